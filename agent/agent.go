@@ -4,8 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
+	"time"
 
 	ai "github.com/ncecere/ai-sdk"
+	"github.com/ncecere/ai-sdk/provider"
 	"github.com/ncecere/ai-sdk/registry"
 )
 
@@ -18,6 +22,20 @@ const (
 	EventTypeToolResult EventType = "tool_result"
 	EventTypeError      EventType = "error"
 	EventTypeDone       EventType = "done"
+	EventTypeCompaction EventType = "compaction"
+	// EventTypeBudgetWarning is emitted before the last tool-loop step
+	// allowed by MaxSteps so UIs can indicate that the run is wrapping up.
+	EventTypeBudgetWarning EventType = "budget_warning"
+	// EventTypeReasoning carries the model's reasoning output for a step
+	// in Content. Reasoning is reported but never added to the history.
+	EventTypeReasoning EventType = "reasoning"
+	// EventTypeApprovalRequired is emitted when a tool call that requires
+	// approval is waiting for a decision. Tool and ToolCallID identify
+	// the call and Content holds its JSON arguments.
+	EventTypeApprovalRequired EventType = "approval_required"
+	// EventTypeApprovalResolved is emitted once a decision for a tool
+	// call has been made (or its approval timed out); see Approval.
+	EventTypeApprovalResolved EventType = "approval_resolved"
 )
 
 // Event represents a single step in an agent run that can be streamed
@@ -33,6 +51,25 @@ type Event struct {
 	Content string `json:"content,omitempty"`
 	// Tool is the name of the tool for tool-related events.
 	Tool string `json:"tool,omitempty"`
+	// ToolCallID identifies the tool call for approval events.
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	// Approval is the decision for approval_resolved events.
+	Approval *ApprovalDecision `json:"approval,omitempty"`
+	// Compaction describes the history compaction for compaction events.
+	Compaction *CompactionInfo `json:"compaction,omitempty"`
+	// TraceID is the run's trace ID, shared by every model call it makes.
+	TraceID string `json:"trace_id,omitempty"`
+}
+
+// CompactionInfo describes a history compaction performed before a
+// model call.
+type CompactionInfo struct {
+	// MessagesBefore is the number of messages before compaction.
+	MessagesBefore int `json:"messages_before"`
+	// MessagesAfter is the number of messages after compaction.
+	MessagesAfter int `json:"messages_after"`
+	// ReclaimedTokens is the estimated number of tokens removed.
+	ReclaimedTokens int `json:"reclaimed_tokens"`
 }
 
 // EventEmitter is a callback used to observe agent events.
@@ -52,7 +89,18 @@ type Tool struct {
 	Parameters json.RawMessage
 	// Execute is invoked when the model calls this tool. The args
 	// parameter contains the raw JSON arguments provided by the model.
+	// The result is JSON-encoded into the tool message, unless it is an
+	// ai.ToolResult, whose parts (text and images) are sent as
+	// multi-part content.
 	Execute func(ctx context.Context, args json.RawMessage) (any, error)
+	// RequiresApproval makes the agent ask Config.Approver before each
+	// call. Denied calls are not executed; the model receives the denial
+	// as the tool result instead.
+	RequiresApproval bool
+	// Group optionally places the tool in a namespace, so that
+	// Config.SelectTools can offer all of the group's tools with a
+	// "<group>.*" pattern (see ExpandToolNames).
+	Group string
 }
 
 // Config contains the static configuration for an agent run.
@@ -66,11 +114,73 @@ type Config struct {
 	// Tools is a map from tool name to tool implementation. The keys
 	// should match the Tool.Name field.
 	Tools map[string]Tool
+	// ToolOrder, if set, lists tool names in the order their definitions
+	// are sent to the model; unlisted tools follow in name order. By
+	// default tools are sent in name order, so the tools block is the
+	// same on every call and provider prompt caches keep hitting.
+	ToolOrder []string
 
 	// MaxSteps controls how many tool-loop iterations the agent may run
 	// before returning an error. If zero or negative, a default of 8 is
 	// used.
 	MaxSteps int
+
+	// ProbeToolSupport, if true, checks once per model (see
+	// ai.ProbeToolSupportCached) that the backend actually returns tool
+	// calls before starting the loop, and fails fast if it does not.
+	// Some OpenAI-compatible backends silently ignore the tools field.
+	ProbeToolSupport bool
+
+	// FinalAnswerOnMaxSteps, if true, makes the agent perform one last
+	// model call with tools disabled when MaxSteps is reached instead of
+	// returning an error. The answer is returned as Result.FinalText
+	// with Result.Truncated set.
+	FinalAnswerOnMaxSteps bool
+	// FinalAnswerPrompt is the system message sent with the final
+	// answer call. If empty, DefaultFinalAnswerPrompt is used.
+	FinalAnswerPrompt string
+
+	// Reasoning, if set, requests reasoning output on every model call.
+	// Reasoning is emitted as EventTypeReasoning events and is not
+	// echoed back to the model in later steps, except for the signed
+	// reasoning blocks of tool-call steps, which Anthropic requires.
+	Reasoning *ai.ReasoningOptions
+
+	// MaxHistoryMessages limits the number of messages sent to the model
+	// on each step. Zero disables the limit.
+	MaxHistoryMessages int
+	// MaxHistoryTokens limits the token count of the messages sent to
+	// the model on each step. Counts are exact when the model implements
+	// provider.TokenCounter (for example Anthropic models) and estimated
+	// otherwise. Zero disables the limit.
+	MaxHistoryTokens int
+	// Compaction is the strategy used to shrink the history when a
+	// history limit is exceeded. If nil, DropOldestCompaction is used.
+	// Leading system messages and the most recent turn (see
+	// HistoryWindow) are always preserved. The compacted history replaces the working history, so
+	// Result.Messages reflects what was last sent to the model.
+	Compaction CompactionStrategy
+
+	// Approver decides whether calls to tools with RequiresApproval set
+	// may run. It must be set if any tool requires approval. Use
+	// ApprovalStore.Approver to wait for a decision made over HTTP.
+	Approver Approver
+
+	// SelectTools, if set, picks the tools offered to the model on each
+	// step, given the zero-based step and the history about to be sent.
+	// Names may use the patterns accepted by ExpandToolNames, such as
+	// "search.*" for every tool in the "search" group. If nil, all
+	// tools are offered on every step.
+	SelectTools func(ctx context.Context, step int, messages []ai.Message) ([]string, error)
+	// AllowUnlistedCalls lets the model call a configured tool that was
+	// not offered on the current step. By default such calls fail the
+	// run like calls to unknown tools.
+	AllowUnlistedCalls bool
+
+	// ToolResultSource, if set, answers tool calls instead of executing
+	// the tools, and approval is not requested. Use NewReplay to re-run
+	// a recorded run without side effects.
+	ToolResultSource ToolResultSource
 }
 
 // Result represents the outcome of an agent run.
@@ -82,8 +192,64 @@ type Result struct {
 	FinalText string
 	// Steps is the number of tool-loop iterations executed.
 	Steps int
+	// Truncated reports that MaxSteps was reached and FinalText was
+	// produced by a forced final answer with tool use disabled.
+	Truncated bool
+	// TraceID is the run's trace ID (see ai.WithTraceID).
+	TraceID string
+	// StepDetails records each model call of the run in order, including
+	// the final answer call, with its tool calls and timings. Unlike
+	// Messages it is never compacted.
+	StepDetails []StepDetail
+	// Usage is the token usage summed over all model calls of the run,
+	// or nil if the provider reported none.
+	Usage *ai.Usage
+}
+
+// StepDetail records one model call of an agent run.
+type StepDetail struct {
+	// Step is the zero-based tool-loop iteration.
+	Step int `json:"step"`
+	// Started is when the model call started.
+	Started time.Time `json:"started"`
+	// ModelDuration is the time spent waiting for the model.
+	ModelDuration time.Duration `json:"model_duration"`
+	// Text is the assistant text returned by the model, if any.
+	Text string `json:"text,omitempty"`
+	// OfferedTools is the number of tools sent with the model call.
+	OfferedTools int `json:"offered_tools"`
+	// ToolCalls lists the tool calls the model requested, in order.
+	ToolCalls []ToolCallDetail `json:"tool_calls,omitempty"`
+	// Usage is the token usage of the model call, if reported. Its
+	// Cache field shows how much of the prompt was served from a cache.
+	Usage *ai.Usage `json:"usage,omitempty"`
+}
+
+// ToolCallDetail records one tool call of an agent run.
+type ToolCallDetail struct {
+	// ID is the provider's tool call ID, or "call_<step>_<index>" when
+	// the provider did not return one.
+	ID string `json:"id"`
+	// Tool is the name of the called tool.
+	Tool string `json:"tool"`
+	// Arguments are the raw JSON arguments provided by the model.
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+	// Result is the content of the tool message sent back to the model.
+	Result string `json:"result"`
+	// Denied reports that the call was denied by Config.Approver and
+	// not executed.
+	Denied bool `json:"denied,omitempty"`
+	// Started is when the call started, including any approval wait.
+	Started time.Time `json:"started"`
+	// Duration is the time taken by the call, including any approval
+	// wait.
+	Duration time.Duration `json:"duration"`
 }
 
+// DefaultFinalAnswerPrompt is the system message used for the final
+// answer call when Config.FinalAnswerOnMaxSteps is set.
+const DefaultFinalAnswerPrompt = "You have run out of tool calls. Do not call any more tools; answer the user's request as well as you can with the information you already have."
+
 func (c *Config) validate() error {
 	if c.Registry == nil {
 		return &ai.InvalidArgumentError{Parameter: "Registry", Value: nil, Message: "must not be nil"}
@@ -91,6 +257,18 @@ func (c *Config) validate() error {
 	if c.ModelName == "" {
 		return &ai.InvalidArgumentError{Parameter: "ModelName", Value: c.ModelName, Message: "must not be empty"}
 	}
+	for _, name := range c.ToolOrder {
+		if _, ok := c.Tools[name]; !ok {
+			return &ai.InvalidArgumentError{Parameter: "ToolOrder", Value: name, Message: fmt.Sprintf("no tool registered with name %q", name)}
+		}
+	}
+	if c.Approver == nil && c.ToolResultSource == nil {
+		for _, name := range slices.Sorted(maps.Keys(c.Tools)) {
+			if c.Tools[name].RequiresApproval {
+				return &ai.InvalidArgumentError{Parameter: "Approver", Value: nil, Message: fmt.Sprintf("must be set because tool %q requires approval", name)}
+			}
+		}
+	}
 	return nil
 }
 
@@ -117,22 +295,43 @@ func Run(ctx context.Context, cfg Config, initialMessages []ai.Message) (*Result
 // each significant step (assistant messages, tool start, tool result,
 // and completion). This is useful for driving streaming UIs such as
 // Server-Sent Events or CLIs that want incremental updates.
+//
+// The run uses the trace ID carried by ctx, or generates one, for all
+// of its model calls; it is stamped on every Event and on the Result.
 func RunWithEvents(ctx context.Context, cfg Config, initialMessages []ai.Message, emit EventEmitter) (*Result, error) {
 	if err := cfg.validate(); err != nil {
 		return nil, err
 	}
 
+	ctx, traceID := provider.EnsureTraceID(ctx)
 	emitEvent := func(e Event) {
 		if emit != nil {
+			e.TraceID = traceID
 			emit(e)
 		}
 	}
 
+	if cfg.ProbeToolSupport && len(cfg.Tools) > 0 {
+		if err := cfg.probeTools(ctx); err != nil {
+			emitEvent(Event{Type: EventTypeError, Content: err.Error()})
+			return nil, err
+		}
+	}
+
 	messages := append([]ai.Message(nil), initialMessages...)
 	steps := 0
 	maxSteps := maxStepsOrDefault(cfg.MaxSteps)
+	var details []StepDetail
 
 	for {
+		if steps >= maxSteps && cfg.FinalAnswerOnMaxSteps {
+			res, err := cfg.finalAnswer(ctx, messages, steps, emitEvent)
+			if res != nil {
+				res.StepDetails = append(details, res.StepDetails...)
+				res.Usage = totalUsage(res.StepDetails)
+			}
+			return res, err
+		}
 		if steps >= maxSteps {
 			err := &ai.UnsupportedFunctionalityError{
 				Feature: "agent.maxSteps",
@@ -142,37 +341,69 @@ func RunWithEvents(ctx context.Context, cfg Config, initialMessages []ai.Message
 			return nil, err
 		}
 
-		// Build tool definitions from the configured tools.
-		var toolDefs []ai.ToolDefinition
-		if len(cfg.Tools) > 0 {
-			toolDefs = make([]ai.ToolDefinition, 0, len(cfg.Tools))
-			for name, t := range cfg.Tools {
-				params := []byte(nil)
-				if len(t.Parameters) > 0 {
-					params = t.Parameters
-				}
-				toolDefs = append(toolDefs, ai.ToolDefinition{
-					Name:        name,
-					Description: t.Description,
-					Parameters:  params,
-				})
-			}
+		if steps == maxSteps-1 {
+			emitEvent(Event{
+				Type:    EventTypeBudgetWarning,
+				Step:    steps,
+				Content: fmt.Sprintf("last tool step (%d of %d)", steps+1, maxSteps),
+			})
+		}
+
+		compacted, info, err := cfg.compactHistory(ctx, messages)
+		if err != nil {
+			emitEvent(Event{Type: EventTypeError, Step: steps, Content: err.Error()})
+			return nil, err
+		}
+		if info != nil {
+			messages = compacted
+			emitEvent(Event{
+				Type:       EventTypeCompaction,
+				Step:       steps,
+				Content:    fmt.Sprintf("history compacted from %d to %d messages (~%d tokens reclaimed)", info.MessagesBefore, info.MessagesAfter, info.ReclaimedTokens),
+				Compaction: info,
+			})
 		}
 
+		offered, err := cfg.offeredTools(ctx, steps, messages)
+		if err != nil {
+			emitEvent(Event{Type: EventTypeError, Step: steps, Content: err.Error()})
+			return nil, err
+		}
+
+		toolDefs := cfg.toolDefinitions(offered)
+
+		detail := StepDetail{Step: steps, Started: time.Now(), OfferedTools: len(toolDefs)}
 		res, err := ai.GenerateTextWithRegistry(ctx, cfg.Registry, cfg.ModelName, ai.GenerateTextRequest{
-			Messages: messages,
-			Tools:    toolDefs,
+			Messages:  messages,
+			Tools:     toolDefs,
+			Reasoning: cfg.Reasoning,
 		})
 		if err != nil {
 			emitEvent(Event{Type: EventTypeError, Step: steps, Content: err.Error()})
 			return nil, err
 		}
+		detail.ModelDuration = time.Since(detail.Started)
+		detail.Text = res.Text
+		detail.Usage = res.Usage
 
+		if res.Reasoning != "" {
+			emitEvent(Event{Type: EventTypeReasoning, Step: steps, Content: res.Reasoning})
+		}
+
+		// Calls without a provider ID get "call_<step>_<index>", shared by
+		// the assistant message and the tool results that answer it.
+		toolCalls := slices.Clone(res.ToolCalls)
+		for i := range toolCalls {
+			if toolCalls[i].ID == "" {
+				toolCalls[i].ID = fmt.Sprintf("call_%d_%d", steps, i)
+			}
+		}
+		if res.Text != "" || len(toolCalls) > 0 {
+			msg := ai.AssistantToolCallMessage(res.Text, toolCalls)
+			msg.ReasoningBlocks = res.ReasoningBlocks
+			messages = append(messages, msg)
+		}
 		if res.Text != "" {
-			messages = append(messages, ai.Message{
-				Role:    ai.RoleAssistant,
-				Content: res.Text,
-			})
 			emitEvent(Event{
 				Type:    EventTypeMessage,
 				Step:    steps,
@@ -181,16 +412,20 @@ func RunWithEvents(ctx context.Context, cfg Config, initialMessages []ai.Message
 			})
 		}
 
-		if len(res.ToolCalls) == 0 {
+		if res.FinishReason != ai.FinishReasonToolCalls {
 			emitEvent(Event{Type: EventTypeDone, Step: steps})
+			details = append(details, detail)
 			return &Result{
-				Messages:  messages,
-				FinalText: res.Text,
-				Steps:     steps,
+				Messages:    messages,
+				FinalText:   res.Text,
+				Steps:       steps,
+				TraceID:     res.TraceID,
+				StepDetails: details,
+				Usage:       totalUsage(details),
 			}, nil
 		}
 
-		for _, tc := range res.ToolCalls {
+		for _, tc := range toolCalls {
 			tool, ok := cfg.Tools[tc.Name]
 			if !ok {
 				err := &ai.UnsupportedFunctionalityError{
@@ -200,33 +435,174 @@ func RunWithEvents(ctx context.Context, cfg Config, initialMessages []ai.Message
 				emitEvent(Event{Type: EventTypeError, Step: steps, Content: err.Error(), Tool: tc.Name})
 				return nil, err
 			}
-
-			emitEvent(Event{Type: EventTypeToolStart, Step: steps, Tool: tool.Name})
+			if _, ok := offered[tc.Name]; !ok && !cfg.AllowUnlistedCalls {
+				err := &ai.UnsupportedFunctionalityError{
+					Feature: "agent.tool",
+					Message: fmt.Sprintf("tool %q was not offered on step %d", tc.Name, steps),
+				}
+				emitEvent(Event{Type: EventTypeError, Step: steps, Content: err.Error(), Tool: tc.Name})
+				return nil, err
+			}
 
 			args := json.RawMessage(tc.RawArguments)
-			result, err := tool.Execute(ctx, args)
-			if err != nil {
-				emitEvent(Event{Type: EventTypeError, Step: steps, Content: err.Error(), Tool: tool.Name})
-				return nil, err
+			call := ToolCallDetail{ID: tc.ID, Tool: tool.Name, Arguments: args, Started: time.Now()}
+			var (
+				result any
+				denied bool
+			)
+			if tool.RequiresApproval && cfg.ToolResultSource == nil {
+				decision, err := cfg.awaitApproval(ctx, ApprovalRequest{ToolCallID: tc.ID, Tool: tool.Name, Arguments: args, Step: steps}, emitEvent)
+				if err != nil {
+					emitEvent(Event{Type: EventTypeError, Step: steps, Content: err.Error(), Tool: tool.Name, ToolCallID: tc.ID})
+					return nil, err
+				}
+				if !decision.Approved {
+					result, denied = deniedResult(decision), true
+				}
 			}
 
-			payload := map[string]any{
-				"tool":   tool.Name,
-				"result": result,
+			if !denied {
+				emitEvent(Event{Type: EventTypeToolStart, Step: steps, Tool: tool.Name})
+				if cfg.ToolResultSource != nil {
+					result, err = cfg.ToolResultSource.ToolResult(ctx, steps, tc)
+				} else {
+					result, err = tool.Execute(ctx, args)
+				}
+				if err != nil {
+					emitEvent(Event{Type: EventTypeError, Step: steps, Content: err.Error(), Tool: tool.Name})
+					return nil, err
+				}
 			}
-			data, err := json.Marshal(payload)
+
+			msg, err := ai.NewToolResultMessage(tc, result)
 			if err != nil {
 				emitEvent(Event{Type: EventTypeError, Step: steps, Content: err.Error(), Tool: tool.Name})
 				return nil, err
 			}
 
-			messages = append(messages, ai.Message{
-				Role:    ai.RoleTool,
-				Content: string(data),
-			})
+			messages = append(messages, msg)
+			call.Result, call.Denied, call.Duration = msg.Content, denied, time.Since(call.Started)
+			detail.ToolCalls = append(detail.ToolCalls, call)
 			emitEvent(Event{Type: EventTypeToolResult, Step: steps, Tool: tool.Name})
 		}
 
+		details = append(details, detail)
 		steps++
 	}
 }
+
+// awaitApproval registers req with the approver, announces it, and
+// blocks until it is decided.
+func (c *Config) awaitApproval(ctx context.Context, req ApprovalRequest, emitEvent EventEmitter) (ApprovalDecision, error) {
+	if err := c.Approver.Request(req); err != nil {
+		return ApprovalDecision{}, err
+	}
+	emitEvent(Event{Type: EventTypeApprovalRequired, Step: req.Step, Tool: req.Tool, ToolCallID: req.ToolCallID, Content: string(req.Arguments)})
+	decision, err := c.Approver.Wait(ctx, req)
+	if err != nil {
+		return ApprovalDecision{}, err
+	}
+	emitEvent(Event{Type: EventTypeApprovalResolved, Step: req.Step, Tool: req.Tool, ToolCallID: req.ToolCallID, Approval: &decision})
+	return decision, nil
+}
+
+// deniedResult is the tool result reported to the model for a denied
+// call.
+func deniedResult(d ApprovalDecision) map[string]string {
+	msg := "the user denied this tool call"
+	if d.Reason != "" {
+		msg += ": " + d.Reason
+	}
+	return map[string]string{"error": msg}
+}
+
+// totalUsage sums the usage of details.
+func totalUsage(details []StepDetail) *ai.Usage {
+	var total *ai.Usage
+	for _, d := range details {
+		total = total.Add(d.Usage)
+	}
+	return total
+}
+
+// finalAnswer makes one last model call with tool use disabled after
+// MaxSteps has been reached, nudging the model to answer with what it
+// knows. The tools are still defined, with ToolChoiceNone, because the
+// history holds tool calls and results, which Anthropic rejects in a
+// request without tools.
+func (c *Config) finalAnswer(ctx context.Context, messages []ai.Message, steps int, emitEvent EventEmitter) (*Result, error) {
+	prompt := c.FinalAnswerPrompt
+	if prompt == "" {
+		prompt = DefaultFinalAnswerPrompt
+	}
+
+	compacted, info, err := c.compactHistory(ctx, messages)
+	if err != nil {
+		emitEvent(Event{Type: EventTypeError, Step: steps, Content: err.Error()})
+		return nil, err
+	}
+	if info != nil {
+		messages = compacted
+	}
+
+	all := make(map[string]struct{}, len(c.Tools))
+	for name := range c.Tools {
+		all[name] = struct{}{}
+	}
+	req := ai.GenerateTextRequest{
+		Messages:  append(append([]ai.Message(nil), messages...), ai.SystemMessage(prompt)),
+		Tools:     c.toolDefinitions(all),
+		Reasoning: c.Reasoning,
+	}
+	if len(req.Tools) > 0 {
+		req.ToolChoice = ai.ToolChoiceNone
+	}
+
+	detail := StepDetail{Step: steps, Started: time.Now()}
+	res, err := ai.GenerateTextWithRegistry(ctx, c.Registry, c.ModelName, req)
+	if err != nil {
+		emitEvent(Event{Type: EventTypeError, Step: steps, Content: err.Error()})
+		return nil, err
+	}
+	detail.ModelDuration = time.Since(detail.Started)
+	detail.Text = res.Text
+	detail.Usage = res.Usage
+
+	if res.Reasoning != "" {
+		emitEvent(Event{Type: EventTypeReasoning, Step: steps, Content: res.Reasoning})
+	}
+
+	if res.Text != "" {
+		messages = append(messages, ai.AssistantMessage(res.Text))
+		emitEvent(Event{Type: EventTypeMessage, Step: steps, Role: ai.RoleAssistant, Content: res.Text})
+	}
+	emitEvent(Event{Type: EventTypeDone, Step: steps})
+	return &Result{
+		Messages:    messages,
+		FinalText:   res.Text,
+		Steps:       steps,
+		Truncated:   true,
+		TraceID:     res.TraceID,
+		StepDetails: []StepDetail{detail},
+	}, nil
+}
+
+// probeTools verifies that the configured model returns tool calls.
+func (c *Config) probeTools(ctx context.Context) error {
+	model, err := c.Registry.LanguageModel(c.ModelName)
+	if err != nil {
+		return err
+	}
+	support, err := ai.ProbeToolSupportCached(ctx, model)
+	if err != nil {
+		return err
+	}
+	if support.Supported {
+		return nil
+	}
+	msg := fmt.Sprintf("model %q did not return a tool call for a forced tool request; the backend may be ignoring the tools field", c.ModelName)
+	if d := support.Diagnostics; d != nil {
+		msg += fmt.Sprintf(" (finish_reason=%q, tool_calls key present=%t)", d.FinishReason, d.ToolCallsKeyPresent)
+	}
+	return &ai.UnsupportedFunctionalityError{Feature: "agent.tools", Message: msg}
+}
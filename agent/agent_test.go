@@ -0,0 +1,396 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	ai "github.com/ncecere/ai-sdk"
+	"github.com/ncecere/ai-sdk/anthropic"
+	"github.com/ncecere/ai-sdk/provider"
+	"github.com/ncecere/ai-sdk/registry"
+)
+
+// loopingModel calls the "search" tool whenever tools are offered and
+// allowed, and answers in text otherwise, so it would loop forever
+// without MaxSteps.
+type loopingModel struct {
+	requests []*provider.LanguageModelRequest
+}
+
+func (m *loopingModel) Generate(ctx context.Context, req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
+	m.requests = append(m.requests, req)
+	if len(req.Tools) > 0 && req.ToolChoice != provider.ToolChoiceNone {
+		return &provider.LanguageModelResponse{ToolCalls: []provider.ToolCall{{ID: "call", Name: "search", RawArguments: []byte(`{}`)}}}, nil
+	}
+	return &provider.LanguageModelResponse{Text: "best effort answer"}, nil
+}
+
+func (m *loopingModel) Stream(ctx context.Context, req *provider.LanguageModelRequest) (provider.LanguageModelStream, error) {
+	return nil, errors.New("loopingModel: streaming not supported")
+}
+
+// testConfig returns a Config that runs model under the name "model"
+// with tools, or with just searchTool when tools is nil. Tests adjust
+// the returned Config for the behaviour they exercise.
+func testConfig(model provider.LanguageModel, tools map[string]Tool) Config {
+	if tools == nil {
+		tools = map[string]Tool{"search": searchTool()}
+	}
+	reg := registry.NewInMemoryRegistry()
+	reg.RegisterLanguageModel("model", model)
+	return Config{Registry: reg, ModelName: "model", Tools: tools}
+}
+
+// searchTool returns a "search" tool that never finds anything.
+func searchTool() Tool {
+	return Tool{
+		Name: "search",
+		Execute: func(ctx context.Context, args json.RawMessage) (any, error) {
+			return "nothing found", nil
+		},
+	}
+}
+
+func newLoopingConfig(model provider.LanguageModel) Config {
+	cfg := testConfig(model, nil)
+	cfg.MaxSteps = 3
+	return cfg
+}
+
+func TestRunWithEvents_FinalAnswerOnMaxSteps(t *testing.T) {
+	model := &loopingModel{}
+	cfg := newLoopingConfig(model)
+	cfg.FinalAnswerOnMaxSteps = true
+
+	var events []Event
+	res, err := RunWithEvents(context.Background(), cfg, []ai.Message{ai.UserMessage("find it")}, func(e Event) {
+		events = append(events, e)
+	})
+	if err != nil {
+		t.Fatalf("RunWithEvents error: %v", err)
+	}
+	if !res.Truncated || res.FinalText != "best effort answer" || res.Steps != 3 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+
+	last := model.requests[len(model.requests)-1]
+	if len(model.requests) != 4 || len(last.Tools) != 1 || last.ToolChoice != provider.ToolChoiceNone {
+		t.Fatalf("expected 3 tool steps and a final call with tool use disabled, got %d requests", len(model.requests))
+	}
+	if nudge := last.Messages[len(last.Messages)-1]; nudge.Role != ai.RoleSystem || nudge.Content != DefaultFinalAnswerPrompt {
+		t.Fatalf("expected final answer nudge, got %+v", nudge)
+	}
+
+	var warnings []int
+	for _, e := range events {
+		if e.Type == EventTypeBudgetWarning {
+			warnings = append(warnings, e.Step)
+		}
+	}
+	if len(warnings) != 1 || warnings[0] != 2 {
+		t.Fatalf("expected one budget warning before the last step, got %v", warnings)
+	}
+	if events[len(events)-1].Type != EventTypeDone {
+		t.Fatalf("expected done event last, got %+v", events[len(events)-1])
+	}
+}
+
+// usageModel reports the same usage for every call of loopingModel.
+type usageModel struct{ loopingModel }
+
+func (m *usageModel) Generate(ctx context.Context, req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
+	res, err := m.loopingModel.Generate(ctx, req)
+	if res != nil {
+		res.Usage = &provider.Usage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12}
+	}
+	return res, err
+}
+
+func TestRun_SumsUsage(t *testing.T) {
+	cfg := newLoopingConfig(&usageModel{})
+	cfg.FinalAnswerOnMaxSteps = true
+
+	res, err := Run(context.Background(), cfg, []ai.Message{ai.UserMessage("find it")})
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if want := (ai.Usage{PromptTokens: 40, CompletionTokens: 8, TotalTokens: 48}); res.Usage == nil || *res.Usage != want {
+		t.Fatalf("Usage = %+v, want %+v", res.Usage, want)
+	}
+	for _, d := range res.StepDetails {
+		if d.Usage == nil || d.Usage.TotalTokens != 12 {
+			t.Fatalf("step %d: unexpected usage %+v", d.Step, d.Usage)
+		}
+	}
+
+	res, err = Run(context.Background(), newSelectConfig(echoToolModel{}), []ai.Message{ai.UserMessage("find it")})
+	if err != nil || res.Usage != nil {
+		t.Fatalf("expected no usage from a model that reports none, got %+v, %v", res, err)
+	}
+}
+
+func TestRunWithEvents_MaxStepsErrorsByDefault(t *testing.T) {
+	cfg := newLoopingConfig(&loopingModel{})
+
+	var unsupported *ai.UnsupportedFunctionalityError
+	if _, err := Run(context.Background(), cfg, []ai.Message{ai.UserMessage("find it")}); !errors.As(err, &unsupported) {
+		t.Fatalf("expected max steps error, got %v", err)
+	}
+}
+
+// toolBlindModel behaves like a backend that drops the tools field.
+type toolBlindModel struct{ calls int }
+
+func (m *toolBlindModel) Generate(ctx context.Context, req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
+	m.calls++
+	return &provider.LanguageModelResponse{
+		Text:            "I would call a tool if I could.",
+		StopReason:      "stop",
+		ToolDiagnostics: &provider.ToolDiagnostics{FinishReason: "stop", TextPresent: true},
+	}, nil
+}
+
+func (m *toolBlindModel) Stream(ctx context.Context, req *provider.LanguageModelRequest) (provider.LanguageModelStream, error) {
+	return nil, errors.New("toolBlindModel: streaming not supported")
+}
+
+func TestRun_ProbeToolSupportFailsFast(t *testing.T) {
+	model := &toolBlindModel{}
+	cfg := newLoopingConfig(model)
+	cfg.ProbeToolSupport = true
+
+	for i := 0; i < 2; i++ {
+		var unsupported *ai.UnsupportedFunctionalityError
+		if _, err := Run(context.Background(), cfg, []ai.Message{ai.UserMessage("find it")}); !errors.As(err, &unsupported) || unsupported.Feature != "agent.tools" {
+			t.Fatalf("expected tool support error, got %v", err)
+		}
+	}
+	if model.calls != 1 {
+		t.Fatalf("expected the probe to run once and be cached, got %d calls", model.calls)
+	}
+
+	looping := &loopingModel{}
+	cfg = newLoopingConfig(looping)
+	cfg.ProbeToolSupport = true
+	cfg.FinalAnswerOnMaxSteps = true
+	if _, err := Run(context.Background(), cfg, []ai.Message{ai.UserMessage("find it")}); err != nil {
+		t.Fatalf("expected probe to pass for a tool-calling model, got %v", err)
+	}
+}
+
+// thinkingModel calls "search" once and then answers, attaching
+// reasoning to every response.
+type thinkingModel struct {
+	requests []*provider.LanguageModelRequest
+}
+
+func (m *thinkingModel) Generate(ctx context.Context, req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
+	m.requests = append(m.requests, req)
+	if len(m.requests) == 1 {
+		return &provider.LanguageModelResponse{
+			Reasoning: "I should search first.",
+			ToolCalls: []provider.ToolCall{{ID: "call", Name: "search", RawArguments: []byte(`{}`)}},
+		}, nil
+	}
+	return &provider.LanguageModelResponse{Reasoning: "Nothing was found.", Text: "no results"}, nil
+}
+
+func (m *thinkingModel) Stream(ctx context.Context, req *provider.LanguageModelRequest) (provider.LanguageModelStream, error) {
+	return nil, errors.New("thinkingModel: streaming not supported")
+}
+
+func TestRunWithEvents_EmitsReasoningWithoutEchoingIt(t *testing.T) {
+	model := &thinkingModel{}
+	cfg := newLoopingConfig(model)
+	cfg.Reasoning = &ai.ReasoningOptions{Effort: "low"}
+
+	var reasoning []Event
+	res, err := RunWithEvents(context.Background(), cfg, []ai.Message{ai.UserMessage("find it")}, func(e Event) {
+		if e.Type == EventTypeReasoning {
+			reasoning = append(reasoning, e)
+		}
+	})
+	if err != nil {
+		t.Fatalf("RunWithEvents error: %v", err)
+	}
+	if res.FinalText != "no results" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if len(reasoning) != 2 || reasoning[0].Content != "I should search first." || reasoning[1].Step != 1 {
+		t.Fatalf("unexpected reasoning events: %+v", reasoning)
+	}
+
+	for _, req := range model.requests {
+		if req.Reasoning == nil || req.Reasoning.Effort != "low" {
+			t.Fatalf("expected reasoning options on every call, got %+v", req.Reasoning)
+		}
+	}
+	for _, msg := range res.Messages {
+		if strings.Contains(msg.Content, "I should search first.") {
+			t.Fatalf("reasoning leaked into history: %+v", msg)
+		}
+	}
+}
+
+func TestRunWithEvents_StampsTraceIDOnEvents(t *testing.T) {
+	model := &thinkingModel{}
+	cfg := newLoopingConfig(model)
+
+	var events []Event
+	ctx := ai.WithTraceID(context.Background(), "run-1")
+	res, err := RunWithEvents(ctx, cfg, []ai.Message{ai.UserMessage("find it")}, func(e Event) {
+		events = append(events, e)
+	})
+	if err != nil {
+		t.Fatalf("RunWithEvents error: %v", err)
+	}
+	if res.TraceID != "run-1" {
+		t.Fatalf("expected result trace ID, got %q", res.TraceID)
+	}
+	for _, e := range events {
+		if e.TraceID != "run-1" {
+			t.Fatalf("event without run trace ID: %+v", e)
+		}
+	}
+}
+
+// preambleModel answers the first call with text and a tool call, as
+// OpenAI does when the model explains itself before calling a tool.
+type preambleModel struct{ calls int }
+
+func (m *preambleModel) Generate(ctx context.Context, req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
+	m.calls++
+	if m.calls == 1 {
+		return &provider.LanguageModelResponse{
+			Text:       "Let me search for that.",
+			StopReason: "stop",
+			ToolCalls:  []provider.ToolCall{{ID: "call", Name: "search", RawArguments: []byte(`{}`)}},
+		}, nil
+	}
+	return &provider.LanguageModelResponse{Text: "no results"}, nil
+}
+
+func (m *preambleModel) Stream(ctx context.Context, req *provider.LanguageModelRequest) (provider.LanguageModelStream, error) {
+	return nil, errors.New("preambleModel: streaming not supported")
+}
+
+func TestRun_TextWithToolCallsIsNotFinal(t *testing.T) {
+	res, err := Run(context.Background(), newLoopingConfig(&preambleModel{}), []ai.Message{ai.UserMessage("find it")})
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if res.FinalText != "no results" || res.Steps != 1 || len(res.StepDetails[0].ToolCalls) != 1 {
+		t.Fatalf("expected the tool call to run before the answer, got %+v", res)
+	}
+	// The preamble stays in the history ahead of the tool result, on the
+	// assistant message carrying the call.
+	if len(res.Messages) != 4 || res.Messages[1].Content != "Let me search for that." || res.Messages[2].Role != ai.RoleTool {
+		t.Fatalf("unexpected history %+v", res.Messages)
+	}
+	if calls := res.Messages[1].ToolCalls; len(calls) != 1 || calls[0].ID != "call" || res.Messages[2].ToolCallID != "call" {
+		t.Fatalf("assistant tool calls not carried: %+v", res.Messages[1:3])
+	}
+}
+
+func TestRun_FinalAnswerOnAnthropicKeepsTools(t *testing.T) {
+	var bodies []map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		w.Header().Set("Content-Type", "application/json")
+		if choice, _ := body["tool_choice"].(map[string]any); choice["type"] == "none" {
+			fmt.Fprint(w, `{"content":[{"type":"text","text":"best effort answer"}],"stop_reason":"end_turn"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"content":[{"type":"tool_use","id":"toolu_%d","name":"search","input":{}}],"stop_reason":"tool_use"}`, len(bodies))
+	}))
+	defer ts.Close()
+
+	client, err := anthropic.NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	cfg := newLoopingConfig(nil)
+	cfg.Registry.(*registry.InMemoryRegistry).RegisterLanguageModel(cfg.ModelName, client.ChatModel("claude-test"))
+	cfg.FinalAnswerOnMaxSteps = true
+
+	res, err := Run(context.Background(), cfg, []ai.Message{ai.UserMessage("find it")})
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if res.FinalText != "best effort answer" || len(bodies) != 4 {
+		t.Fatalf("unexpected result %+v after %d requests", res, len(bodies))
+	}
+
+	// The final request replays tool_use and tool_result blocks, so it
+	// must still define the tools.
+	final := bodies[3]
+	tools, _ := final["tools"].([]any)
+	if len(tools) != 1 || tools[0].(map[string]any)["name"] != "search" {
+		t.Fatalf("final request tools = %v", final["tools"])
+	}
+	if !reflect.DeepEqual(final["tool_choice"], map[string]any{"type": "none"}) {
+		t.Fatalf("final request tool_choice = %v", final["tool_choice"])
+	}
+}
+
+func TestRun_ReplaysAnthropicThinkingInToolLoop(t *testing.T) {
+	var bodies []map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		w.Header().Set("Content-Type", "application/json")
+		if len(bodies) == 1 {
+			fmt.Fprint(w, `{"content":[
+				{"type":"thinking","thinking":"look it up","signature":"sig-1"},
+				{"type":"redacted_thinking","data":"opaque"},
+				{"type":"tool_use","id":"toolu_1","name":"search","input":{}}
+			],"stop_reason":"tool_use"}`)
+			return
+		}
+		fmt.Fprint(w, `{"content":[{"type":"thinking","thinking":"done","signature":"sig-2"},{"type":"text","text":"found it"}],"stop_reason":"end_turn"}`)
+	}))
+	defer ts.Close()
+
+	client, err := anthropic.NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	cfg := newLoopingConfig(nil)
+	cfg.Registry.(*registry.InMemoryRegistry).RegisterLanguageModel(cfg.ModelName, client.ChatModel("claude-test"))
+	cfg.Reasoning = &ai.ReasoningOptions{BudgetTokens: 1024}
+
+	res, err := Run(context.Background(), cfg, []ai.Message{ai.UserMessage("find it")})
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if res.FinalText != "found it" || len(bodies) != 2 {
+		t.Fatalf("unexpected result %+v after %d requests", res, len(bodies))
+	}
+
+	// The assistant turn of step 2 leads with the signed blocks.
+	messages := bodies[1]["messages"].([]any)
+	assistant := messages[1].(map[string]any)["content"].([]any)
+	var types []string
+	for _, b := range assistant {
+		types = append(types, b.(map[string]any)["type"].(string))
+	}
+	if want := []string{"thinking", "redacted_thinking", "tool_use"}; !reflect.DeepEqual(types, want) {
+		t.Fatalf("assistant blocks = %v, want %v", types, want)
+	}
+	if first := assistant[0].(map[string]any); first["thinking"] != "look it up" || first["signature"] != "sig-1" {
+		t.Fatalf("thinking block not replayed unchanged: %v", first)
+	}
+	if data := assistant[1].(map[string]any)["data"]; data != "opaque" {
+		t.Fatalf("redacted block data = %v", data)
+	}
+}
@@ -0,0 +1,248 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnknownApproval is returned by ApprovalStore.Resolve when no
+// approval is pending for the run and tool call, for example because it
+// was already decided or timed out.
+var ErrUnknownApproval = errors.New("agent: no pending approval")
+
+// ApprovalRequest describes a tool call awaiting human approval.
+type ApprovalRequest struct {
+	// ToolCallID identifies the call within the run. It is the
+	// provider's tool call ID, or "call_<step>_<index>" when the provider
+	// did not return one.
+	ToolCallID string
+	// Tool is the name of the tool to be called.
+	Tool string
+	// Arguments are the raw JSON arguments provided by the model.
+	Arguments json.RawMessage
+	// Step is the tool-loop iteration of the call.
+	Step int
+}
+
+// ApprovalDecision is the outcome of an approval request.
+type ApprovalDecision struct {
+	// Approved reports whether the tool call may run.
+	Approved bool `json:"approved"`
+	// Reason optionally explains a denial. It is sent to the model in
+	// the tool result so it can adjust its plan.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Approver decides whether tool calls marked with Tool.RequiresApproval
+// may run. The agent calls Request, emits an EventTypeApprovalRequired
+// event, and then blocks in Wait; registering before the event is
+// emitted ensures decisions made in response to it are not lost.
+type Approver interface {
+	// Request registers req as pending.
+	Request(req ApprovalRequest) error
+	// Wait blocks until req is decided. A non-nil error aborts the run.
+	Wait(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error)
+}
+
+// ApprovalFunc adapts a function that decides synchronously, such as a
+// CLI prompt or a policy check, to the Approver interface.
+type ApprovalFunc func(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error)
+
+// Request implements Approver; it does nothing.
+func (f ApprovalFunc) Request(req ApprovalRequest) error { return nil }
+
+// Wait implements Approver by calling f.
+func (f ApprovalFunc) Wait(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error) {
+	return f(ctx, req)
+}
+
+// DefaultApprovalTimeout is the time an ApprovalStore waits for a
+// decision when ApprovalStoreOptions.Timeout is zero.
+const DefaultApprovalTimeout = 10 * time.Minute
+
+// ApprovalStoreOptions configures an ApprovalStore.
+type ApprovalStoreOptions struct {
+	// Timeout is how long a pending approval waits for a decision
+	// before it is denied. Zero uses DefaultApprovalTimeout.
+	Timeout time.Duration
+	// TimeoutReason is the denial reason given to the model when an
+	// approval times out. Defaults to "approval timed out".
+	TimeoutReason string
+}
+
+// ApprovalStore holds the pending approvals of concurrent runs, keyed
+// by run ID and tool call ID, so that a separate HTTP request (see
+// ApprovalServer) can unblock the run waiting on it. It is safe for
+// concurrent use.
+type ApprovalStore struct {
+	timeout       time.Duration
+	timeoutReason string
+
+	mu      sync.Mutex
+	pending map[approvalKey]chan ApprovalDecision
+}
+
+type approvalKey struct {
+	runID, callID string
+}
+
+// NewApprovalStore creates an empty ApprovalStore.
+func NewApprovalStore(opts ApprovalStoreOptions) *ApprovalStore {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultApprovalTimeout
+	}
+	if opts.TimeoutReason == "" {
+		opts.TimeoutReason = "approval timed out"
+	}
+	return &ApprovalStore{
+		timeout:       opts.Timeout,
+		timeoutReason: opts.TimeoutReason,
+		pending:       make(map[approvalKey]chan ApprovalDecision),
+	}
+}
+
+// Approver returns the Approver for runID, for use as Config.Approver.
+func (s *ApprovalStore) Approver(runID string) Approver {
+	return storeApprover{store: s, runID: runID}
+}
+
+// Resolve delivers decision to the run waiting on callID.
+//
+// Errors:
+//   - ErrUnknownApproval if no approval is pending for runID and callID.
+func (s *ApprovalStore) Resolve(runID, callID string, decision ApprovalDecision) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := approvalKey{runID, callID}
+	ch, ok := s.pending[key]
+	if !ok {
+		return ErrUnknownApproval
+	}
+	delete(s.pending, key)
+	ch <- decision
+	return nil
+}
+
+// Pending returns the tool call IDs awaiting a decision in runID.
+func (s *ApprovalStore) Pending(runID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ids []string
+	for key := range s.pending {
+		if key.runID == runID {
+			ids = append(ids, key.callID)
+		}
+	}
+	return ids
+}
+
+func (s *ApprovalStore) register(key approvalKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.pending[key]; !ok {
+		s.pending[key] = make(chan ApprovalDecision, 1)
+	}
+}
+
+func (s *ApprovalStore) wait(ctx context.Context, key approvalKey) (ApprovalDecision, error) {
+	s.mu.Lock()
+	ch, ok := s.pending[key]
+	s.mu.Unlock()
+	if !ok {
+		return ApprovalDecision{}, ErrUnknownApproval
+	}
+
+	timer := time.NewTimer(s.timeout)
+	defer timer.Stop()
+	select {
+	case d := <-ch:
+		return d, nil
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	// Withdraw the request unless a decision raced the timeout, in
+	// which case Resolve has already removed it and buffered the
+	// decision.
+	s.mu.Lock()
+	if s.pending[key] == ch {
+		delete(s.pending, key)
+	}
+	s.mu.Unlock()
+	select {
+	case d := <-ch:
+		return d, nil
+	default:
+	}
+	if err := ctx.Err(); err != nil {
+		return ApprovalDecision{}, err
+	}
+	return ApprovalDecision{Reason: s.timeoutReason}, nil
+}
+
+type storeApprover struct {
+	store *ApprovalStore
+	runID string
+}
+
+func (a storeApprover) Request(req ApprovalRequest) error {
+	a.store.register(approvalKey{a.runID, req.ToolCallID})
+	return nil
+}
+
+func (a storeApprover) Wait(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error) {
+	return a.store.wait(ctx, approvalKey{a.runID, req.ToolCallID})
+}
+
+// ApprovalServer is an http.Handler that resolves pending approvals in
+// Store. It serves POST <Prefix><runID>/<callID> with a JSON
+// ApprovalDecision body such as {"approved":true} and responds with 204
+// No Content, or 404 Not Found if no approval is pending.
+type ApprovalServer struct {
+	// Store holds the pending approvals.
+	Store *ApprovalStore
+	// Prefix is the path the server is mounted at. Defaults to
+	// "/approvals/".
+	Prefix string
+}
+
+// NewApprovalServer returns an ApprovalServer for store mounted at
+// "/approvals/".
+func NewApprovalServer(store *ApprovalStore) *ApprovalServer {
+	return &ApprovalServer{Store: store}
+}
+
+// ServeHTTP implements http.Handler.
+func (s *ApprovalServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	prefix := s.Prefix
+	if prefix == "" {
+		prefix = "/approvals/"
+	}
+	rest, ok := strings.CutPrefix(r.URL.Path, prefix)
+	runID, callID, found := strings.Cut(rest, "/")
+	if !ok || !found || runID == "" || callID == "" || strings.Contains(callID, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	var decision ApprovalDecision
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&decision); err != nil {
+		http.Error(w, "invalid approval decision", http.StatusBadRequest)
+		return
+	}
+	if err := s.Store.Resolve(runID, callID, decision); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,196 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ai "github.com/ncecere/ai-sdk"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// echoToolModel calls the "search" tool once and then answers with the
+// tool's result.
+type echoToolModel struct{}
+
+func (echoToolModel) Generate(ctx context.Context, req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
+	last := req.Messages[len(req.Messages)-1]
+	if last.Role == ai.RoleTool {
+		return &provider.LanguageModelResponse{Text: last.Content}, nil
+	}
+	return &provider.LanguageModelResponse{ToolCalls: []provider.ToolCall{{ID: "call_1", Name: "search", RawArguments: []byte(`{"q":"x"}`)}}}, nil
+}
+
+func (echoToolModel) Stream(ctx context.Context, req *provider.LanguageModelRequest) (provider.LanguageModelStream, error) {
+	return nil, errors.New("echoToolModel: streaming not supported")
+}
+
+func newApprovalConfig(approver Approver) Config {
+	search := searchTool()
+	search.RequiresApproval = true
+	cfg := testConfig(echoToolModel{}, map[string]Tool{"search": search})
+	cfg.Approver = approver
+	return cfg
+}
+
+// waitForEvent blocks until runID has recorded an event of type typ.
+func waitForEvent(t *testing.T, store EventStore, runID string, typ EventType) Event {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var after int64
+	for {
+		if err := store.Wait(ctx, runID, after); err != nil {
+			t.Fatalf("waiting for %s in %s: %v", typ, runID, err)
+		}
+		events, ids, _, _ := store.Since(runID, after)
+		for i, e := range events {
+			if e.Type == typ {
+				return e
+			}
+			after = ids[i]
+		}
+	}
+}
+
+func postDecision(t *testing.T, url string, d ApprovalDecision) int {
+	t.Helper()
+	body, _ := json.Marshal(d)
+	resp, err := http.Post(url, "application/json", strings.NewReader(string(body)))
+	if err != nil {
+		t.Fatalf("POST %s: %v", url, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestApprovalServer_ConcurrentRuns(t *testing.T) {
+	approvals := NewApprovalStore(ApprovalStoreOptions{})
+	events := NewRingEventStore(0)
+	srv := httptest.NewServer(NewApprovalServer(approvals))
+	t.Cleanup(srv.Close)
+
+	// Both runs use the same tool call ID; only the run ID tells them
+	// apart.
+	outcomes := map[string]<-chan RunOutcome{}
+	for _, runID := range []string{"run-a", "run-b"} {
+		done, err := StartRun(context.Background(), events, runID, newApprovalConfig(approvals.Approver(runID)), []ai.Message{ai.UserMessage("find x")})
+		if err != nil {
+			t.Fatalf("StartRun error: %v", err)
+		}
+		outcomes[runID] = done
+	}
+	for _, runID := range []string{"run-a", "run-b"} {
+		e := waitForEvent(t, events, runID, EventTypeApprovalRequired)
+		if e.ToolCallID != "call_1" || e.Tool != "search" || e.Content != `{"q":"x"}` {
+			t.Fatalf("unexpected approval event %+v", e)
+		}
+	}
+
+	if code := postDecision(t, srv.URL+"/approvals/run-b/call_1", ApprovalDecision{Reason: "not today"}); code != http.StatusNoContent {
+		t.Fatalf("expected 204 for the denial, got %d", code)
+	}
+	out := <-outcomes["run-b"]
+	if out.Err != nil || !strings.Contains(out.Result.FinalText, "denied this tool call: not today") {
+		t.Fatalf("expected the denial reported to the model, got %+v", out)
+	}
+	if pending := approvals.Pending("run-a"); len(pending) != 1 {
+		t.Fatalf("run-a should still be waiting, pending=%v", pending)
+	}
+
+	if code := postDecision(t, srv.URL+"/approvals/run-a/call_1", ApprovalDecision{Approved: true}); code != http.StatusNoContent {
+		t.Fatalf("expected 204 for the approval, got %d", code)
+	}
+	out = <-outcomes["run-a"]
+	if out.Err != nil || !strings.Contains(out.Result.FinalText, "nothing found") {
+		t.Fatalf("expected the tool to run after approval, got %+v", out)
+	}
+	resolved := waitForEvent(t, events, "run-a", EventTypeApprovalResolved)
+	if resolved.Approval == nil || !resolved.Approval.Approved {
+		t.Fatalf("unexpected resolved event %+v", resolved)
+	}
+
+	if code := postDecision(t, srv.URL+"/approvals/run-a/call_1", ApprovalDecision{Approved: true}); code != http.StatusNotFound {
+		t.Fatalf("expected 404 once decided, got %d", code)
+	}
+}
+
+func TestApprovalStore_TimeoutDenies(t *testing.T) {
+	approvals := NewApprovalStore(ApprovalStoreOptions{Timeout: 20 * time.Millisecond})
+	var executed bool
+	cfg := newApprovalConfig(approvals.Approver("run"))
+	search := cfg.Tools["search"]
+	search.Execute = func(ctx context.Context, args json.RawMessage) (any, error) {
+		executed = true
+		return nil, nil
+	}
+	cfg.Tools["search"] = search
+
+	res, err := Run(context.Background(), cfg, []ai.Message{ai.UserMessage("find x")})
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if executed || !strings.Contains(res.FinalText, "approval timed out") {
+		t.Fatalf("expected the call to be denied on timeout, got %q (executed=%t)", res.FinalText, executed)
+	}
+	if len(approvals.Pending("run")) != 0 {
+		t.Fatal("timed out approval should be withdrawn")
+	}
+}
+
+func TestConfig_RequiresApprover(t *testing.T) {
+	_, err := Run(context.Background(), newApprovalConfig(nil), []ai.Message{ai.UserMessage("find x")})
+	var invalid *ai.InvalidArgumentError
+	if !errors.As(err, &invalid) || invalid.Parameter != "Approver" {
+		t.Fatalf("expected InvalidArgumentError for Approver, got %v", err)
+	}
+}
+
+func TestWriteRunAsSSE_HeartbeatsWhileAwaitingApproval(t *testing.T) {
+	defer func(d time.Duration) { SSEHeartbeatInterval = d }(SSEHeartbeatInterval)
+	SSEHeartbeatInterval = 5 * time.Millisecond
+
+	approvals := NewApprovalStore(ApprovalStoreOptions{})
+	mux := http.NewServeMux()
+	mux.Handle("/approvals/", NewApprovalServer(approvals))
+	mux.HandleFunc("/run", func(w http.ResponseWriter, r *http.Request) {
+		WriteRunAsSSE(r.Context(), w, newApprovalConfig(approvals.Approver("run")), []ai.Message{ai.UserMessage("find x")})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	resp, err := http.Get(srv.URL + "/run")
+	if err != nil {
+		t.Fatalf("GET error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	lines := bufio.NewScanner(resp.Body)
+	var sawRequest, approved bool
+	heartbeats := 0
+	for lines.Scan() {
+		line := lines.Text()
+		switch {
+		case strings.Contains(line, `"type":"approval_required"`):
+			sawRequest = true
+		case line == ": heartbeat" && sawRequest:
+			heartbeats++
+		}
+		if heartbeats == 2 && !approved {
+			if code := postDecision(t, srv.URL+"/approvals/run/call_1", ApprovalDecision{Approved: true}); code != http.StatusNoContent {
+				t.Fatalf("expected 204, got %d", code)
+			}
+			approved = true
+		}
+		if strings.Contains(line, `"type":"message"`) && strings.Contains(line, "nothing found") {
+			return
+		}
+	}
+	t.Fatalf("stream ended before the approved answer (request=%t, heartbeats=%d, approved=%t): %v", sawRequest, heartbeats, approved, lines.Err())
+}
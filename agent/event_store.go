@@ -0,0 +1,222 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrUnknownRun is returned by an EventStore for run IDs it has no
+// events for.
+var ErrUnknownRun = errors.New("agent: unknown run")
+
+// EventStore records the events of agent runs so that SSE clients can
+// reconnect and replay the events they missed. Event IDs are assigned
+// per run, start at 1, and increase by one for each appended event.
+// Implementations must be safe for concurrent use.
+type EventStore interface {
+	// Start registers runID so that readers can wait for its events.
+	Start(runID string) error
+	// Append records e for runID and returns the assigned event ID.
+	Append(runID string, e Event) (int64, error)
+	// Finish marks runID as complete; no further events are appended.
+	Finish(runID string) error
+	// Since returns the retained events of runID with IDs greater than
+	// afterID, the IDs of those events, and whether the run has
+	// finished. It returns ErrUnknownRun for unknown runs.
+	Since(runID string, afterID int64) (events []Event, ids []int64, finished bool, err error)
+	// Wait blocks until runID has events after afterID, the run
+	// finishes, or ctx is done.
+	Wait(ctx context.Context, runID string, afterID int64) error
+}
+
+// RingEventStore is an in-memory EventStore that retains the most
+// recent events of each run in a fixed-size ring buffer. Clients that
+// fall further behind than the buffer size miss the evicted events.
+// Finished runs are evicted as configured by RingEventStoreOptions, or
+// removed with Delete; unfinished runs are kept until they finish.
+type RingEventStore struct {
+	opts RingEventStoreOptions
+	now  func() time.Time
+
+	mu   sync.Mutex
+	runs map[string]*ringRun
+	// finished lists the finished runs, oldest first.
+	finished []string
+}
+
+type ringRun struct {
+	events     []Event
+	ids        []int64
+	next       int64
+	finished   bool
+	finishedAt time.Time
+	// changed is closed and replaced whenever the run changes.
+	changed chan struct{}
+}
+
+// RingEventStoreOptions configures a RingEventStore.
+type RingEventStoreOptions struct {
+	// Capacity is the number of events retained per run. Zero or less
+	// defaults to 1024.
+	Capacity int
+	// MaxFinishedRuns is the number of finished runs retained; beyond
+	// it the run that finished first is evicted. Zero defaults to 1000,
+	// and a negative value retains every finished run.
+	MaxFinishedRuns int
+	// TTL evicts finished runs once they have been finished for longer,
+	// checked whenever a run starts or finishes. Zero disables it.
+	TTL time.Duration
+}
+
+// NewRingEventStore creates a RingEventStore retaining up to capacity
+// events per run, with the default eviction of finished runs. A
+// capacity of zero or less defaults to 1024.
+func NewRingEventStore(capacity int) *RingEventStore {
+	return NewRingEventStoreWithOptions(RingEventStoreOptions{Capacity: capacity})
+}
+
+// NewRingEventStoreWithOptions creates a RingEventStore configured by
+// opts.
+func NewRingEventStoreWithOptions(opts RingEventStoreOptions) *RingEventStore {
+	if opts.Capacity <= 0 {
+		opts.Capacity = 1024
+	}
+	if opts.MaxFinishedRuns == 0 {
+		opts.MaxFinishedRuns = 1000
+	}
+	return &RingEventStore{opts: opts, now: time.Now, runs: make(map[string]*ringRun)}
+}
+
+// evict removes the finished runs beyond MaxFinishedRuns or older than
+// TTL. s.mu must be held.
+func (s *RingEventStore) evict() {
+	n := 0
+	if limit := s.opts.MaxFinishedRuns; limit > 0 && len(s.finished) > limit {
+		n = len(s.finished) - limit
+	}
+	if s.opts.TTL > 0 {
+		cutoff := s.now().Add(-s.opts.TTL)
+		for n < len(s.finished) && s.runs[s.finished[n]].finishedAt.Before(cutoff) {
+			n++
+		}
+	}
+	for _, id := range s.finished[:n] {
+		delete(s.runs, id)
+	}
+	s.finished = slices.Delete(s.finished, 0, n)
+}
+
+// Delete removes runID and its events. Readers waiting on it return,
+// and later reads return ErrUnknownRun. Deleting an unknown run is not
+// an error.
+func (s *RingEventStore) Delete(runID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.run(runID, false)
+	if r == nil {
+		return nil
+	}
+	delete(s.runs, runID)
+	if r.finished {
+		s.finished = slices.DeleteFunc(s.finished, func(id string) bool { return id == runID })
+	}
+	close(r.changed)
+	return nil
+}
+
+func (s *RingEventStore) run(runID string, create bool) *ringRun {
+	r, ok := s.runs[runID]
+	if !ok && create {
+		r = &ringRun{next: 1, changed: make(chan struct{})}
+		s.runs[runID] = r
+	}
+	return r
+}
+
+// Start implements EventStore.
+func (s *RingEventStore) Start(runID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evict()
+	s.run(runID, true)
+	return nil
+}
+
+// Append implements EventStore.
+func (s *RingEventStore) Append(runID string, e Event) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.run(runID, true)
+	id := r.next
+	r.next++
+	r.events = append(r.events, e)
+	r.ids = append(r.ids, id)
+	if len(r.events) > s.opts.Capacity {
+		r.events = r.events[1:]
+		r.ids = r.ids[1:]
+	}
+	close(r.changed)
+	r.changed = make(chan struct{})
+	return id, nil
+}
+
+// Finish implements EventStore.
+func (s *RingEventStore) Finish(runID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.run(runID, true)
+	if !r.finished {
+		r.finished = true
+		r.finishedAt = s.now()
+		s.finished = append(s.finished, runID)
+		close(r.changed)
+		r.changed = make(chan struct{})
+	}
+	s.evict()
+	return nil
+}
+
+// Since implements EventStore.
+func (s *RingEventStore) Since(runID string, afterID int64) ([]Event, []int64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.run(runID, false)
+	if r == nil {
+		return nil, nil, false, ErrUnknownRun
+	}
+	var events []Event
+	var ids []int64
+	for i, id := range r.ids {
+		if id > afterID {
+			events = append(events, r.events[i])
+			ids = append(ids, id)
+		}
+	}
+	return events, ids, r.finished, nil
+}
+
+// Wait implements EventStore.
+func (s *RingEventStore) Wait(ctx context.Context, runID string, afterID int64) error {
+	s.mu.Lock()
+	r := s.run(runID, false)
+	if r == nil {
+		s.mu.Unlock()
+		return ErrUnknownRun
+	}
+	if r.finished || r.next-1 > afterID {
+		s.mu.Unlock()
+		return nil
+	}
+	changed := r.changed
+	s.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-changed:
+		return nil
+	}
+}
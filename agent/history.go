@@ -0,0 +1,269 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	ai "github.com/ncecere/ai-sdk"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// HistoryLimits bounds the message history sent to the model on each
+// agent step. Zero values disable the corresponding limit.
+type HistoryLimits struct {
+	// MaxMessages is the maximum number of messages in the history.
+	MaxMessages int
+	// MaxTokens is the maximum estimated token count of the history,
+	// as computed by ai.EstimateMessageTokens.
+	MaxTokens int
+}
+
+func (l HistoryLimits) enabled() bool {
+	return l.MaxMessages > 0 || l.MaxTokens > 0
+}
+
+func (l HistoryLimits) exceeded(messages []ai.Message) bool {
+	if l.MaxMessages > 0 && len(messages) > l.MaxMessages {
+		return true
+	}
+	if l.MaxTokens > 0 && ai.EstimateMessageTokens(messages) > l.MaxTokens {
+		return true
+	}
+	return false
+}
+
+// CompactionStrategy reduces a message history so that it fits within
+// the given limits. Implementations must preserve leading system
+// messages and the most recent turn (the last user message, or the
+// latest assistant message and its tool results); HistoryWindow reports
+// which messages those are.
+type CompactionStrategy interface {
+	Compact(ctx context.Context, messages []ai.Message, limits HistoryLimits) ([]ai.Message, error)
+}
+
+// CompactionStrategyFunc adapts a function to the CompactionStrategy
+// interface.
+type CompactionStrategyFunc func(ctx context.Context, messages []ai.Message, limits HistoryLimits) ([]ai.Message, error)
+
+// Compact implements CompactionStrategy.
+func (f CompactionStrategyFunc) Compact(ctx context.Context, messages []ai.Message, limits HistoryLimits) ([]ai.Message, error) {
+	return f(ctx, messages, limits)
+}
+
+// HistoryWindow splits messages into the protected head (leading system
+// messages), the compactable middle, and the protected tail (the most
+// recent turn: the last message other than a tool result, and the tool
+// results after it). Mid-run, the tail is the latest assistant message
+// and its tool results, so earlier tool steps of a single prompt can be
+// compacted.
+func HistoryWindow(messages []ai.Message) (head, middle, tail []ai.Message) {
+	start := 0
+	for start < len(messages) && messages[start].Role == ai.RoleSystem {
+		start++
+	}
+	end := len(messages)
+	for end > start && messages[end-1].Role == ai.RoleTool {
+		end--
+	}
+	if end > start {
+		end--
+	}
+	return messages[:start], messages[start:end], messages[end:]
+}
+
+// DropOldestCompaction returns a CompactionStrategy that first drops the
+// oldest tool result messages and then the oldest remaining messages
+// from the compactable middle of the history until the limits are met
+// or nothing more can be removed.
+func DropOldestCompaction() CompactionStrategy {
+	return CompactionStrategyFunc(func(ctx context.Context, messages []ai.Message, limits HistoryLimits) ([]ai.Message, error) {
+		return dropOldest(messages, limits), nil
+	})
+}
+
+func dropOldest(messages []ai.Message, limits HistoryLimits) []ai.Message {
+	head, middle, tail := HistoryWindow(messages)
+	middle = append([]ai.Message(nil), middle...)
+
+	assemble := func() []ai.Message {
+		out := make([]ai.Message, 0, len(head)+len(middle)+len(tail))
+		out = append(out, head...)
+		out = append(out, middle...)
+		return append(out, tail...)
+	}
+
+	// Tool results are usually the bulkiest and least useful once the
+	// model has acted on them, so drop those first.
+	for limits.exceeded(assemble()) {
+		idx := -1
+		for i, m := range middle {
+			if m.Role == ai.RoleTool {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			break
+		}
+		id := middle[idx].ToolCallID
+		middle = append(middle[:idx], middle[idx+1:]...)
+		middle = withoutToolCall(middle, id)
+	}
+	// Then drop the oldest messages, keeping the last user message, which
+	// usually states the task, for as long as anything else is left.
+	for limits.exceeded(assemble()) && len(middle) > 0 {
+		idx := 0
+		if last := lastUserIndex(middle); last == 0 && len(middle) > 1 {
+			idx = 1
+		}
+		middle = slices.Delete(middle, idx, idx+1)
+	}
+	return assemble()
+}
+
+// lastUserIndex returns the index of the last user message, or -1.
+func lastUserIndex(messages []ai.Message) int {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == ai.RoleUser {
+			return i
+		}
+	}
+	return -1
+}
+
+// withoutToolCall removes the call with the given ID from the assistant
+// message that made it, since providers reject tool calls sent without
+// their results. A message left with neither content nor calls is
+// removed.
+func withoutToolCall(messages []ai.Message, id string) []ai.Message {
+	if id == "" {
+		return messages
+	}
+	for i, m := range messages {
+		j := slices.IndexFunc(m.ToolCalls, func(tc ai.ToolCall) bool { return tc.ID == id })
+		if m.Role != ai.RoleAssistant || j < 0 {
+			continue
+		}
+		m.ToolCalls = slices.Delete(slices.Clone(m.ToolCalls), j, j+1)
+		if len(m.ToolCalls) == 0 && m.Content == "" && len(m.Parts) == 0 {
+			return slices.Delete(messages, i, i+1)
+		}
+		messages[i] = m
+		return messages
+	}
+	return messages
+}
+
+// SummarizeCompaction returns a CompactionStrategy that drops old tool
+// results first and, if the history is still over the limits, replaces
+// the compactable middle with a single summary message produced by
+// model.
+func SummarizeCompaction(model ai.LanguageModel) CompactionStrategy {
+	return CompactionStrategyFunc(func(ctx context.Context, messages []ai.Message, limits HistoryLimits) ([]ai.Message, error) {
+		head, middle, tail := HistoryWindow(messages)
+		var withoutTools []ai.Message
+		for _, m := range middle {
+			if m.Role == ai.RoleTool {
+				continue
+			}
+			if len(m.ToolCalls) > 0 {
+				if m.Content == "" && len(m.Parts) == 0 {
+					continue
+				}
+				m.ToolCalls = nil
+			}
+			withoutTools = append(withoutTools, m)
+		}
+		candidate := append(append(append([]ai.Message(nil), head...), withoutTools...), tail...)
+		if !limits.exceeded(candidate) || len(withoutTools) == 0 {
+			return dropOldest(candidate, limits), nil
+		}
+
+		var transcript strings.Builder
+		for _, m := range withoutTools {
+			fmt.Fprintf(&transcript, "%s: %s\n", m.Role, m.Content)
+		}
+		summary, err := ai.GenerateText(ctx, ai.GenerateTextRequest{
+			Model: model,
+			Messages: []ai.Message{
+				ai.SystemMessage("Summarize the following conversation excerpt concisely, keeping facts, decisions, and open questions."),
+				ai.UserMessage(transcript.String()),
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("agent: summarizing history: %w", err)
+		}
+
+		out := append([]ai.Message(nil), head...)
+		out = append(out, ai.SystemMessage("Summary of earlier conversation: "+summary.Text))
+		out = append(out, tail...)
+		return dropOldest(out, limits), nil
+	})
+}
+
+// compactHistory applies the configured compaction strategy when the
+// history exceeds the configured limits.
+func (c *Config) compactHistory(ctx context.Context, messages []ai.Message) ([]ai.Message, *CompactionInfo, error) {
+	limits := HistoryLimits{MaxMessages: c.MaxHistoryMessages, MaxTokens: c.MaxHistoryTokens}
+	if !limits.enabled() {
+		return messages, nil, nil
+	}
+	if exact, ok := c.exactTokenCount(ctx, messages); ok {
+		// Prefer the provider's exact count for the trigger, and scale
+		// the token limit handed to the strategy so that its heuristic
+		// estimates land under the exact budget.
+		if exact <= limits.MaxTokens && (limits.MaxMessages <= 0 || len(messages) <= limits.MaxMessages) {
+			return messages, nil, nil
+		}
+		if est := ai.EstimateMessageTokens(messages); exact > est {
+			limits.MaxTokens = max(1, limits.MaxTokens*est/exact)
+		}
+	} else if !limits.exceeded(messages) {
+		return messages, nil, nil
+	}
+
+	strategy := c.Compaction
+	if strategy == nil {
+		strategy = DropOldestCompaction()
+	}
+
+	before := ai.EstimateMessageTokens(messages)
+	compacted, err := strategy.Compact(ctx, messages, limits)
+	if err != nil {
+		return nil, nil, err
+	}
+	after := ai.EstimateMessageTokens(compacted)
+	if len(compacted) == len(messages) && after >= before {
+		// The strategy could not remove anything.
+		return messages, nil, nil
+	}
+
+	return compacted, &CompactionInfo{
+		MessagesBefore:  len(messages),
+		MessagesAfter:   len(compacted),
+		ReclaimedTokens: before - after,
+	}, nil
+}
+
+// exactTokenCount returns the provider's exact input token count for
+// messages when MaxHistoryTokens is set and the model implements
+// provider.TokenCounter. Counting failures fall back to estimates.
+func (c *Config) exactTokenCount(ctx context.Context, messages []ai.Message) (int, bool) {
+	if c.MaxHistoryTokens <= 0 {
+		return 0, false
+	}
+	model, err := c.Registry.LanguageModel(c.ModelName)
+	if err != nil {
+		return 0, false
+	}
+	if _, ok := model.(provider.TokenCounter); !ok {
+		return 0, false
+	}
+	n, _, err := ai.CountTokens(ctx, ai.GenerateTextRequest{Model: model, Messages: messages})
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
@@ -0,0 +1,150 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	ai "github.com/ncecere/ai-sdk"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+func TestDropOldestCompaction_PreservesSystemAndRecentExchange(t *testing.T) {
+	messages := []ai.Message{
+		ai.SystemMessage("system prompt"),
+		ai.UserMessage("first question"),
+		ai.AssistantMessage("calling tool"),
+		{Role: ai.RoleTool, Content: `{"tool":"search","result":"a very long tool result"}`},
+		ai.AssistantMessage("first answer"),
+		ai.UserMessage("second question"),
+		ai.AssistantMessage("calling tool again"),
+		{Role: ai.RoleTool, Content: `{"tool":"search","result":"recent result"}`},
+	}
+
+	out, err := DropOldestCompaction().Compact(context.Background(), messages, HistoryLimits{MaxMessages: 5})
+	if err != nil {
+		t.Fatalf("Compact error: %v", err)
+	}
+	if len(out) != 5 {
+		t.Fatalf("expected 5 messages, got %d: %+v", len(out), out)
+	}
+	if out[0].Content != "system prompt" {
+		t.Fatalf("system prompt not preserved: %+v", out)
+	}
+	for _, m := range out[1:] {
+		if m.Content == `{"tool":"search","result":"a very long tool result"}` {
+			t.Fatalf("expected oldest tool result to be dropped first: %+v", out)
+		}
+	}
+	if got := out[len(out)-3:]; got[0].Content != "second question" || got[2].Role != ai.RoleTool {
+		t.Fatalf("recent exchange not preserved: %+v", got)
+	}
+}
+
+func TestDropOldestCompaction_DropsToolCallsWithTheirResults(t *testing.T) {
+	messages := []ai.Message{
+		ai.UserMessage("first question"),
+		ai.AssistantToolCallMessage("", []ai.ToolCall{{ID: "call_1", Name: "search"}}),
+		{Role: ai.RoleTool, ToolCallID: "call_1", Content: "old result"},
+		ai.AssistantToolCallMessage("let me look", []ai.ToolCall{{ID: "call_2", Name: "search"}, {ID: "call_3", Name: "fetch"}}),
+		{Role: ai.RoleTool, ToolCallID: "call_2", Content: "result"},
+		{Role: ai.RoleTool, ToolCallID: "call_3", Content: "page"},
+		ai.UserMessage("second question"),
+	}
+
+	out, err := DropOldestCompaction().Compact(context.Background(), messages, HistoryLimits{MaxMessages: 4})
+	if err != nil {
+		t.Fatalf("Compact error: %v", err)
+	}
+	// The first call is removed with its result, and the second
+	// assistant message keeps only the call whose result is left.
+	if len(out) != 4 || out[1].Content != "let me look" || len(out[1].ToolCalls) != 1 || out[1].ToolCalls[0].ID != "call_3" || out[2].ToolCallID != "call_3" {
+		t.Fatalf("unexpected compacted history %+v", out)
+	}
+	if len(messages[3].ToolCalls) != 2 {
+		t.Fatalf("compaction modified the caller's messages")
+	}
+}
+
+// countingModel reports an exact token count far above the heuristic
+// estimate, as a tokenizer-dense language would.
+type countingModel struct {
+	loopingModel
+	counts int
+}
+
+func (m *countingModel) CountTokens(ctx context.Context, req *provider.LanguageModelRequest) (int, error) {
+	m.counts++
+	return 100 * len(req.Messages), nil
+}
+
+func TestCompactHistory_PrefersExactTokenCounts(t *testing.T) {
+	model := &countingModel{}
+	cfg := newLoopingConfig(model)
+	cfg.MaxHistoryTokens = 250
+
+	messages := []ai.Message{
+		ai.SystemMessage("s"),
+		ai.UserMessage("a"),
+		ai.AssistantMessage("b"),
+		ai.UserMessage("c"),
+	}
+	compacted, info, err := cfg.compactHistory(context.Background(), messages)
+	if err != nil {
+		t.Fatalf("compactHistory error: %v", err)
+	}
+	if model.counts != 1 {
+		t.Fatalf("expected one exact count, got %d", model.counts)
+	}
+	if info == nil || len(compacted) >= len(messages) {
+		t.Fatalf("expected exact count to trigger compaction, got %d messages", len(compacted))
+	}
+}
+
+func TestRun_CompactsToolStepsOfSinglePrompt(t *testing.T) {
+	model := &loopingModel{}
+	cfg := newLoopingConfig(model)
+	cfg.MaxSteps = 20
+	cfg.FinalAnswerOnMaxSteps = true
+	cfg.MaxHistoryMessages = 10
+
+	var compactions int
+	_, err := RunWithEvents(context.Background(), cfg, []ai.Message{ai.SystemMessage("s"), ai.UserMessage("find it")}, func(e Event) {
+		if e.Type == EventTypeCompaction {
+			compactions++
+			if e.Compaction.MessagesAfter >= e.Compaction.MessagesBefore {
+				t.Errorf("compaction removed nothing: %+v", e.Compaction)
+			}
+		}
+	})
+	if err != nil {
+		t.Fatalf("RunWithEvents error: %v", err)
+	}
+	if compactions == 0 {
+		t.Fatal("expected the tool steps to be compacted")
+	}
+	for i, req := range model.requests[:len(model.requests)-1] {
+		if len(req.Messages) > 10 {
+			t.Fatalf("request %d sent %d messages", i, len(req.Messages))
+		}
+		if req.Messages[1].Content != "find it" {
+			t.Fatalf("request %d lost the prompt: %+v", i, req.Messages[1])
+		}
+		if last := req.Messages[len(req.Messages)-1]; i > 0 && last.Role != ai.RoleTool {
+			t.Fatalf("request %d lost the latest tool result: %+v", i, last)
+		}
+	}
+}
+
+func TestCompactHistory_NothingRemoved(t *testing.T) {
+	cfg := newLoopingConfig(&loopingModel{})
+	cfg.MaxHistoryMessages = 1
+
+	messages := []ai.Message{ai.SystemMessage("s"), ai.UserMessage("a")}
+	compacted, info, err := cfg.compactHistory(context.Background(), messages)
+	if err != nil {
+		t.Fatalf("compactHistory error: %v", err)
+	}
+	if info != nil || len(compacted) != 2 {
+		t.Fatalf("expected no compaction, got %+v with %d messages", info, len(compacted))
+	}
+}
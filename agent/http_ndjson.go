@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	ai "github.com/ncecere/ai-sdk"
+)
+
+// WriteRunAsNDJSON executes an agent run and streams agent events as
+// newline-delimited JSON to the provided ResponseWriter, the
+// counterpart of WriteRunAsSSE for clients that read
+// application/x-ndjson.
+//
+// Each event is encoded as a single JSON object on its own line and
+// flushed, and a final done event is written when the run completes.
+// NDJSON has no comments, so no heartbeats are sent. The function
+// returns when the agent run completes or an error occurs.
+func WriteRunAsNDJSON(ctx context.Context, w http.ResponseWriter, cfg Config, initialMessages []ai.Message) (*Result, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, fmt.Errorf("agent: response writer does not support flushing")
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	encoder := json.NewEncoder(w)
+	emit := func(e Event) {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		if err := encoder.Encode(e); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+
+	res, err := RunWithEvents(ctx, cfg, initialMessages, emit)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send a final done event to ensure clients see completion even if
+	// the agent terminated without emitting an explicit done event.
+	_ = encoder.Encode(Event{Type: EventTypeDone})
+	flusher.Flush()
+
+	return res, nil
+}
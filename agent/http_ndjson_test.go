@@ -0,0 +1,40 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	ai "github.com/ncecere/ai-sdk"
+)
+
+func TestWriteRunAsNDJSON(t *testing.T) {
+	cfg := newApprovalConfig(nil)
+	search := cfg.Tools["search"]
+	search.RequiresApproval = false
+	cfg.Tools["search"] = search
+
+	rec := httptest.NewRecorder()
+	res, err := WriteRunAsNDJSON(context.Background(), rec, cfg, []ai.Message{ai.UserMessage("find x")})
+	if err != nil {
+		t.Fatalf("WriteRunAsNDJSON error: %v", err)
+	}
+	if res.Steps == 0 || rec.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("unexpected result after %d steps, content type %q", res.Steps, rec.Header().Get("Content-Type"))
+	}
+
+	var types []EventType
+	lines := bufio.NewScanner(rec.Body)
+	for lines.Scan() {
+		var e Event
+		if err := json.Unmarshal(lines.Bytes(), &e); err != nil {
+			t.Fatalf("line %q is not an event: %v", lines.Text(), err)
+		}
+		types = append(types, e.Type)
+	}
+	if len(types) < 3 || types[0] != EventTypeToolStart || types[len(types)-1] != EventTypeDone {
+		t.Fatalf("unexpected events %v", types)
+	}
+}
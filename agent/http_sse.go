@@ -5,16 +5,29 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync"
+	"time"
 
 	ai "github.com/ncecere/ai-sdk"
 )
 
+// SSEHeartbeatInterval is how often the SSE writers in this package
+// send a comment line while no events are produced, for example while
+// a tool call waits for approval, so that proxies and browsers keep the
+// connection open. Zero disables heartbeats.
+var SSEHeartbeatInterval = 15 * time.Second
+
+// sseHeartbeat is the SSE comment sent as a heartbeat; clients ignore
+// it.
+const sseHeartbeat = ": heartbeat\n\n"
+
 // WriteRunAsSSE executes an agent run and streams agent events as
 // Server-Sent Events (SSE) to the provided ResponseWriter.
 //
 // Each event is encoded as a single JSON object and sent using the
-// "data: <json>\n\n" framing. The function returns when the agent run
-// completes or an error occurs.
+// "data: <json>\n\n" framing, with heartbeats every
+// SSEHeartbeatInterval while the run is quiet. The function returns
+// when the agent run completes or an error occurs.
 func WriteRunAsSSE(ctx context.Context, w http.ResponseWriter, cfg Config, initialMessages []ai.Message) (*Result, error) {
 	flusher, ok := w.(http.Flusher)
 	if !ok {
@@ -27,6 +40,35 @@ func WriteRunAsSSE(ctx context.Context, w http.ResponseWriter, cfg Config, initi
 
 	encoder := json.NewEncoder(w)
 
+	// Events and heartbeats are written from different goroutines.
+	var mu sync.Mutex
+	if SSEHeartbeatInterval > 0 {
+		stop := make(chan struct{})
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(SSEHeartbeatInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					mu.Lock()
+					if _, err := fmt.Fprint(w, sseHeartbeat); err == nil {
+						flusher.Flush()
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+		defer wg.Wait()
+		defer close(stop)
+	}
+
 	emit := func(e Event) {
 		select {
 		case <-ctx.Done():
@@ -38,6 +80,8 @@ func WriteRunAsSSE(ctx context.Context, w http.ResponseWriter, cfg Config, initi
 		if err != nil {
 			return
 		}
+		mu.Lock()
+		defer mu.Unlock()
 		if _, err := fmt.Fprintf(w, "data: %s\n\n", b); err != nil {
 			return
 		}
@@ -51,6 +95,8 @@ func WriteRunAsSSE(ctx context.Context, w http.ResponseWriter, cfg Config, initi
 
 	// Send a final done event to ensure clients see completion even if
 	// the agent terminated without emitting an explicit done event.
+	mu.Lock()
+	defer mu.Unlock()
 	_ = encoder.Encode(Event{Type: EventTypeDone})
 	flusher.Flush()
 
@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ai "github.com/ncecere/ai-sdk"
+	"github.com/ncecere/ai-sdk/aitest"
+	"github.com/ncecere/ai-sdk/openai"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+func TestLeaks_AgentLoopUnderFaults(t *testing.T) {
+	aitest.VerifyNoLeaks(t)
+	seed := uint64(time.Now().UnixNano())
+	t.Logf("cancellation seed %d", seed)
+	rng := rand.New(rand.NewPCG(seed, seed))
+
+	// Every response asks for the search tool, so a healthy run would
+	// loop until MaxSteps.
+	toolCall := `{"choices":[{"message":{"role":"assistant","tool_calls":[{"id":"call_1","type":"function","function":{"name":"search","arguments":"{}"}}]}}]}`
+	runs := map[string]func(ctx context.Context, cfg Config){
+		"run": func(ctx context.Context, cfg Config) {
+			Run(ctx, cfg, []ai.Message{ai.UserMessage("find it")})
+		},
+		"sse": func(ctx context.Context, cfg Config) {
+			WriteRunAsSSE(ctx, httptest.NewRecorder(), cfg, []ai.Message{ai.UserMessage("find it")})
+		},
+	}
+
+	for _, fault := range aitest.Faults {
+		for name, run := range runs {
+			t.Run(fault.String()+"/"+name, func(t *testing.T) {
+				srv := aitest.NewFaultServer(t, fault, "application/json", toolCall)
+				client, err := openai.NewClient(provider.ClientOptions{BaseURL: srv.URL, APIKey: "test", HTTPClient: srv.Client()})
+				if err != nil {
+					t.Fatalf("NewClient error: %v", err)
+				}
+				for range 3 {
+					ctx, cancel := aitest.CancelAtRandom(context.Background(), rng, 20*time.Millisecond)
+					run(ctx, newLoopingConfig(client.ChatModel("gpt-test")))
+					cancel()
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,692 @@
+// Package openapi builds agent tools from OpenAPI 3 specifications, so
+// that an agent can call internal HTTP services without hand-written
+// tool definitions.
+//
+// Each selected operation becomes an agent.Tool named after its
+// operationId. The tool's parameters schema has one property per path,
+// query, and header parameter and a "body" property for a JSON request
+// body; Execute performs the HTTP call and returns the response JSON as
+// the tool result.
+//
+//	spec, err := openapi.Load("inventory.yaml")
+//	tools, err := spec.Tools(openapi.Options{
+//		Operations: []string{"listItems", "getItem"},
+//		Authorize: func(ctx context.Context, req *http.Request) error {
+//			req.Header.Set("Authorization", "Bearer "+token)
+//			return nil
+//		},
+//	})
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	ai "github.com/ncecere/ai-sdk"
+	"github.com/ncecere/ai-sdk/agent"
+	"github.com/ncecere/ai-sdk/provider"
+	"github.com/ncecere/ai-sdk/providerutil"
+)
+
+// DefaultMaxResponseBytes is the response size limit used when
+// Options.MaxResponseBytes is zero.
+const DefaultMaxResponseBytes = 1 << 20
+
+// bodyProperty is the tool parameter holding the request body.
+const bodyProperty = "body"
+
+// Spec is a parsed OpenAPI 3 document.
+type Spec struct {
+	doc        map[string]any
+	operations []operation
+}
+
+// operation is an OpenAPI operation with its references resolved.
+type operation struct {
+	id          string
+	method      string
+	path        string
+	summary     string
+	description string
+	tags        []string
+	params      []parameter
+	body        any // request body schema, nil without a JSON body
+	bodyReq     bool
+}
+
+type parameter struct {
+	name        string
+	in          string // "path", "query", or "header"
+	description string
+	required    bool
+	schema      any
+}
+
+// Load reads and parses the OpenAPI document at path. See Parse.
+func Load(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("openapi: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse parses an OpenAPI 3 document in JSON or YAML. YAML documents
+// may use block and flow collections, quoted and block scalars, and
+// comments; anchors, aliases, and tags are not supported. Local $ref
+// references ("#/components/...") are resolved; recursive schemas are
+// cut off with an unconstrained schema at the point of recursion.
+//
+// Errors:
+//   - An error if the document does not parse, is not OpenAPI 3, or
+//     contains an external or dangling $ref.
+func Parse(data []byte) (*Spec, error) {
+	var raw any
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &raw); err != nil {
+			return nil, fmt.Errorf("openapi: %w", err)
+		}
+	} else {
+		var err error
+		if raw, err = parseYAML(data); err != nil {
+			return nil, fmt.Errorf("openapi: %w", err)
+		}
+	}
+	doc, ok := raw.(map[string]any)
+	if !ok {
+		return nil, errors.New("openapi: document is not an object")
+	}
+	if v, _ := doc["openapi"].(string); !strings.HasPrefix(v, "3.") {
+		return nil, fmt.Errorf("openapi: unsupported version %q; only OpenAPI 3 is supported", v)
+	}
+
+	s := &Spec{doc: doc}
+	paths, _ := doc["paths"].(map[string]any)
+	for _, path := range sortedKeys(paths) {
+		item, err := s.resolveRef(paths[path])
+		if err != nil {
+			return nil, fmt.Errorf("openapi: path %s: %w", path, err)
+		}
+		itemMap, _ := item.(map[string]any)
+		for _, method := range []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"} {
+			op, ok := itemMap[method].(map[string]any)
+			if !ok {
+				continue
+			}
+			parsed, err := s.operation(method, path, itemMap, op)
+			if err != nil {
+				return nil, fmt.Errorf("openapi: %s %s: %w", strings.ToUpper(method), path, err)
+			}
+			s.operations = append(s.operations, parsed)
+		}
+	}
+	return s, nil
+}
+
+// OperationIDs returns the operation IDs of the document in path and
+// method order. Operations without an operationId are listed under the
+// generated name their tool would have.
+func (s *Spec) OperationIDs() []string {
+	ids := make([]string, len(s.operations))
+	for i, op := range s.operations {
+		ids[i] = op.id
+	}
+	return ids
+}
+
+func (s *Spec) operation(method, path string, item, op map[string]any) (operation, error) {
+	o := operation{
+		method:      strings.ToUpper(method),
+		path:        path,
+		summary:     stringField(op, "summary"),
+		description: stringField(op, "description"),
+	}
+	o.id = stringField(op, "operationId")
+	if o.id == "" {
+		o.id = generatedName(method, path)
+	}
+	for _, t := range sliceField(op, "tags") {
+		if tag, ok := t.(string); ok {
+			o.tags = append(o.tags, tag)
+		}
+	}
+
+	// Operation parameters override path-level ones with the same name
+	// and location.
+	var params []parameter
+	for _, raw := range append(sliceField(item, "parameters"), sliceField(op, "parameters")...) {
+		p, err := s.parameter(raw)
+		if err != nil {
+			return operation{}, err
+		}
+		if p.in == "cookie" {
+			continue
+		}
+		params = slices.DeleteFunc(params, func(q parameter) bool { return q.name == p.name && q.in == p.in })
+		params = append(params, p)
+	}
+	o.params = params
+
+	if raw, ok := op["requestBody"]; ok {
+		rb, err := s.resolveRef(raw)
+		if err != nil {
+			return operation{}, fmt.Errorf("requestBody: %w", err)
+		}
+		rbMap, _ := rb.(map[string]any)
+		content, _ := rbMap["content"].(map[string]any)
+		for _, ct := range sortedKeys(content) {
+			if ct != "application/json" && !strings.HasSuffix(ct, "+json") {
+				continue
+			}
+			media, _ := content[ct].(map[string]any)
+			schema, err := s.schema(media["schema"])
+			if err != nil {
+				return operation{}, fmt.Errorf("requestBody: %w", err)
+			}
+			o.body = schema
+			o.bodyReq, _ = rbMap["required"].(bool)
+			break
+		}
+	}
+	for _, p := range o.params {
+		if p.name == bodyProperty && o.body != nil {
+			return operation{}, fmt.Errorf("parameter %q collides with the request body property", bodyProperty)
+		}
+	}
+	return o, nil
+}
+
+func (s *Spec) parameter(raw any) (parameter, error) {
+	resolved, err := s.resolveRef(raw)
+	if err != nil {
+		return parameter{}, fmt.Errorf("parameter: %w", err)
+	}
+	m, _ := resolved.(map[string]any)
+	p := parameter{
+		name:        stringField(m, "name"),
+		in:          stringField(m, "in"),
+		description: stringField(m, "description"),
+	}
+	p.required, _ = m["required"].(bool)
+	if p.name == "" {
+		return parameter{}, errors.New("parameter without a name")
+	}
+	switch p.in {
+	case "path":
+		p.required = true
+	case "query", "header", "cookie":
+	default:
+		return parameter{}, fmt.Errorf("parameter %q: unsupported location %q", p.name, p.in)
+	}
+	if p.schema, err = s.schema(m["schema"]); err != nil {
+		return parameter{}, fmt.Errorf("parameter %q: %w", p.name, err)
+	}
+	if p.schema == nil {
+		p.schema = map[string]any{"type": "string"}
+	}
+	return p, nil
+}
+
+// Options selects the operations turned into tools and configures
+// their HTTP calls.
+type Options struct {
+	// Operations lists the operationIds to expose. Together with Tags
+	// it forms an allowlist: only operations listed here or tagged with
+	// one of Tags become tools. At least one of them must be set.
+	Operations []string
+	// Tags selects every operation with one of these tags.
+	Tags []string
+	// BaseURL is the URL operation paths are appended to. Empty means
+	// the first server URL of the document, with server variables set
+	// to their defaults.
+	BaseURL string
+	// HTTPClient performs the calls. Nil means http.DefaultClient.
+	HTTPClient provider.HTTPClient
+	// Authorize, if set, is called with every request before it is
+	// sent, for example to set an Authorization header. An error fails
+	// the tool call.
+	Authorize func(ctx context.Context, req *http.Request) error
+	// MaxResponseBytes bounds the response body read per call. Zero
+	// means DefaultMaxResponseBytes.
+	MaxResponseBytes int64
+	// Group is set as the Group of every tool.
+	Group string
+}
+
+// Tools returns a tool for each operation selected by opts, in the
+// order of OperationIDs. Tool arguments are checked strictly: unknown
+// keys fail with an *ai.UnknownFieldsError and missing required
+// parameters with an error naming them.
+//
+// Execute sends the request and returns the response body as a
+// json.RawMessage when it is JSON, as a string otherwise, and nil when
+// it is empty.
+//
+// Errors:
+//   - ai.InvalidArgumentError if opts selects no operation or lists an
+//     unknown operationId.
+//   - An error if no base URL is known.
+//
+// Errors returned by Execute:
+//   - *HTTPError for responses with a non-2xx status.
+//   - *provider.ResponseTooLargeError if the response exceeds
+//     MaxResponseBytes.
+func (s *Spec) Tools(opts Options) ([]agent.Tool, error) {
+	if len(opts.Operations) == 0 && len(opts.Tags) == 0 {
+		return nil, &ai.InvalidArgumentError{Parameter: "Options", Value: nil, Message: "select operations with Operations or Tags"}
+	}
+	known := s.OperationIDs()
+	for _, id := range opts.Operations {
+		if !slices.Contains(known, id) {
+			return nil, &ai.InvalidArgumentError{Parameter: "Operations", Value: id, Message: "no operation with this operationId"}
+		}
+	}
+	baseURL := opts.BaseURL
+	if baseURL == "" {
+		baseURL = s.serverURL()
+	}
+	if u, err := url.Parse(baseURL); err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("openapi: base URL %q is not absolute; set Options.BaseURL", baseURL)
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	if opts.MaxResponseBytes <= 0 {
+		opts.MaxResponseBytes = DefaultMaxResponseBytes
+	}
+
+	var tools []agent.Tool
+	for _, op := range s.operations {
+		if !slices.Contains(opts.Operations, op.id) && !slices.ContainsFunc(op.tags, func(t string) bool { return slices.Contains(opts.Tags, t) }) {
+			continue
+		}
+		schema, err := json.Marshal(op.parametersSchema())
+		if err != nil {
+			return nil, fmt.Errorf("openapi: operation %s: %w", op.id, err)
+		}
+		c := &caller{op: op, baseURL: strings.TrimRight(baseURL, "/"), opts: opts}
+		tools = append(tools, agent.Tool{
+			Name:        toolName(op.id),
+			Description: op.toolDescription(),
+			Parameters:  schema,
+			Execute:     c.execute,
+			Group:       opts.Group,
+		})
+	}
+	return tools, nil
+}
+
+// parametersSchema returns the JSON schema of the tool's arguments.
+func (op operation) parametersSchema() map[string]any {
+	props := map[string]any{}
+	required := []string{}
+	for _, p := range op.params {
+		schema, _ := p.schema.(map[string]any)
+		if p.description != "" && stringField(schema, "description") == "" {
+			schema = withField(schema, "description", p.description)
+		}
+		props[p.name] = schema
+		if p.required {
+			required = append(required, p.name)
+		}
+	}
+	if op.body != nil {
+		props[bodyProperty] = op.body
+		if op.bodyReq {
+			required = append(required, bodyProperty)
+		}
+	}
+	return map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+func (op operation) toolDescription() string {
+	parts := []string{}
+	if op.summary != "" {
+		parts = append(parts, op.summary)
+	}
+	if op.description != "" && op.description != op.summary {
+		parts = append(parts, op.description)
+	}
+	if len(parts) == 0 {
+		return op.method + " " + op.path
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// HTTPError is returned by an OpenAPI tool when the service answers
+// with a non-2xx status.
+type HTTPError struct {
+	// Tool is the name of the tool that made the call.
+	Tool string
+	// StatusCode is the HTTP status code.
+	StatusCode int
+	// Body is the start of the response body.
+	Body string
+}
+
+func (e *HTTPError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	msg := fmt.Sprintf("openapi: tool %s: HTTP %d %s", e.Tool, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+// caller performs the HTTP calls of one operation.
+type caller struct {
+	op      operation
+	baseURL string
+	opts    Options
+}
+
+func (c *caller) execute(ctx context.Context, raw json.RawMessage) (any, error) {
+	name := toolName(c.op.id)
+	args := map[string]json.RawMessage{}
+	if len(bytes.TrimSpace(raw)) > 0 {
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return nil, fmt.Errorf("openapi: tool %s: arguments: %w", name, err)
+		}
+	}
+	if unknown := c.unknownArgs(args); len(unknown) > 0 {
+		return nil, &ai.UnknownFieldsError{Tool: name, Fields: unknown}
+	}
+
+	path := c.op.path
+	query := url.Values{}
+	header := http.Header{}
+	var missing []string
+	for _, p := range c.op.params {
+		v, ok := args[p.name]
+		if !ok || string(v) == "null" {
+			if p.required {
+				missing = append(missing, p.name)
+			}
+			continue
+		}
+		values, err := paramValues(v)
+		if err != nil {
+			return nil, fmt.Errorf("openapi: tool %s: parameter %s: %w", name, p.name, err)
+		}
+		switch p.in {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+p.name+"}", url.PathEscape(strings.Join(values, ",")))
+		case "query":
+			query[p.name] = values
+		case "header":
+			header.Set(p.name, strings.Join(values, ","))
+		}
+	}
+	body, hasBody := args[bodyProperty]
+	if c.op.body != nil && c.op.bodyReq && (!hasBody || string(body) == "null") {
+		missing = append(missing, bodyProperty)
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("openapi: tool %s: missing required arguments: %s", name, strings.Join(missing, ", "))
+	}
+
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if c.op.body != nil && hasBody && string(body) != "null" {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, c.op.method, target, reader)
+	if err != nil {
+		return nil, fmt.Errorf("openapi: tool %s: %w", name, err)
+	}
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+	req.Header.Set("Accept", "application/json")
+	if reader != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.opts.Authorize != nil {
+		if err := c.opts.Authorize(ctx, req); err != nil {
+			return nil, fmt.Errorf("openapi: tool %s: authorize: %w", name, err)
+		}
+	}
+
+	resp, err := c.opts.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("openapi: tool %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, c.opts.MaxResponseBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("openapi: tool %s: reading response: %w", name, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &HTTPError{Tool: name, StatusCode: resp.StatusCode, Body: providerutil.Snippet(bytes.TrimSpace(data), 512)}
+	}
+	if int64(len(data)) > c.opts.MaxResponseBytes {
+		return nil, &provider.ResponseTooLargeError{Provider: "openapi: tool " + name, Limit: c.opts.MaxResponseBytes}
+	}
+	data = bytes.TrimSpace(data)
+	switch {
+	case len(data) == 0:
+		return nil, nil
+	case json.Valid(data):
+		return json.RawMessage(data), nil
+	}
+	return string(data), nil
+}
+
+// unknownArgs returns the sorted argument keys that are neither a
+// parameter nor the request body.
+func (c *caller) unknownArgs(args map[string]json.RawMessage) []string {
+	var unknown []string
+	for k := range args {
+		if k == bodyProperty && c.op.body != nil {
+			continue
+		}
+		if !slices.ContainsFunc(c.op.params, func(p parameter) bool { return p.name == k }) {
+			unknown = append(unknown, k)
+		}
+	}
+	slices.Sort(unknown)
+	return unknown
+}
+
+// paramValues renders a parameter value: strings verbatim, other
+// scalars as JSON, and arrays as one value per element.
+func paramValues(raw json.RawMessage) ([]string, error) {
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	items, isArray := v.([]any)
+	if !isArray {
+		items = []any{v}
+	}
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		switch x := item.(type) {
+		case string:
+			out = append(out, x)
+		case json.Number, bool:
+			out = append(out, fmt.Sprint(x))
+		default:
+			return nil, errors.New("objects are not supported as parameter values")
+		}
+	}
+	return out, nil
+}
+
+// serverURL returns the first server URL with its variables set to
+// their defaults.
+func (s *Spec) serverURL() string {
+	servers := sliceField(s.doc, "servers")
+	if len(servers) == 0 {
+		return ""
+	}
+	server, _ := servers[0].(map[string]any)
+	u := stringField(server, "url")
+	vars, _ := server["variables"].(map[string]any)
+	for name, v := range vars {
+		def := stringField(asMap(v), "default")
+		u = strings.ReplaceAll(u, "{"+name+"}", def)
+	}
+	return u
+}
+
+// resolveRef follows a $ref in node, if any, to the referenced object.
+func (s *Spec) resolveRef(node any) (any, error) {
+	for range 32 {
+		ref, ok := asMap(node)["$ref"].(string)
+		if !ok {
+			return node, nil
+		}
+		target, err := s.pointer(ref)
+		if err != nil {
+			return nil, err
+		}
+		node = target
+	}
+	return nil, errors.New("$ref chain too long")
+}
+
+// schema returns a copy of the schema node with every $ref inlined.
+func (s *Spec) schema(node any) (any, error) {
+	return s.inline(node, nil)
+}
+
+func (s *Spec) inline(node any, active []string) (any, error) {
+	switch n := node.(type) {
+	case map[string]any:
+		if ref, ok := n["$ref"].(string); ok {
+			if slices.Contains(active, ref) {
+				// Recursive schema: stop with an unconstrained schema.
+				return map[string]any{}, nil
+			}
+			target, err := s.pointer(ref)
+			if err != nil {
+				return nil, err
+			}
+			return s.inline(target, append(active, ref))
+		}
+		out := make(map[string]any, len(n))
+		for k, v := range n {
+			c, err := s.inline(v, active)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = c
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(n))
+		for i, v := range n {
+			c, err := s.inline(v, active)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = c
+		}
+		return out, nil
+	}
+	return node, nil
+}
+
+// pointer resolves a local JSON pointer reference such as
+// "#/components/schemas/Item".
+func (s *Spec) pointer(ref string) (any, error) {
+	rest, ok := strings.CutPrefix(ref, "#/")
+	if !ok {
+		return nil, fmt.Errorf("unsupported $ref %q: only local references are supported", ref)
+	}
+	var node any = s.doc
+	for _, token := range strings.Split(rest, "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		m, ok := node.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("dangling $ref %q", ref)
+		}
+		if node, ok = m[token]; !ok {
+			return nil, fmt.Errorf("dangling $ref %q", ref)
+		}
+	}
+	return node, nil
+}
+
+// toolName makes an operation ID a valid tool name: letters, digits,
+// '_' and '-', at most 64 characters.
+func toolName(id string) string {
+	b := []byte(id)
+	for i, c := range b {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_' || c == '-') {
+			b[i] = '_'
+		}
+	}
+	if len(b) > 64 {
+		b = b[:64]
+	}
+	return string(b)
+}
+
+// generatedName names an operation without an operationId, such as
+// "get_items_itemId" for GET /items/{itemId}.
+func generatedName(method, path string) string {
+	words := []string{method}
+	for _, seg := range strings.Split(path, "/") {
+		if seg = strings.Trim(seg, "{}"); seg != "" {
+			words = append(words, seg)
+		}
+	}
+	return strings.Join(words, "_")
+}
+
+func asMap(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	return m
+}
+
+func stringField(m map[string]any, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+func sliceField(m map[string]any, key string) []any {
+	s, _ := m[key].([]any)
+	return s
+}
+
+// withField returns a copy of m with key set to v.
+func withField(m map[string]any, key string, v any) map[string]any {
+	out := make(map[string]any, len(m)+1)
+	for k, x := range m {
+		out[k] = x
+	}
+	out[key] = v
+	return out
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
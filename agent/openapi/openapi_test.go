@@ -0,0 +1,279 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	ai "github.com/ncecere/ai-sdk"
+	"github.com/ncecere/ai-sdk/agent"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+func loadFixture(t *testing.T) *Spec {
+	t.Helper()
+	spec, err := Load("testdata/inventory.yaml")
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	return spec
+}
+
+func toolsByName(tools []agent.Tool) map[string]agent.Tool {
+	out := map[string]agent.Tool{}
+	for _, tool := range tools {
+		out[tool.Name] = tool
+	}
+	return out
+}
+
+func TestParse_YAMLFixture(t *testing.T) {
+	spec := loadFixture(t)
+	want := []string{"get_health", "listItems", "createItem", "getItem", "deleteItem"}
+	if got := spec.OperationIDs(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("OperationIDs = %v, want %v", got, want)
+	}
+
+	tools, err := spec.Tools(Options{Operations: []string{"listItems", "createItem"}})
+	if err != nil {
+		t.Fatalf("Tools error: %v", err)
+	}
+	byName := toolsByName(tools)
+	if byName["listItems"].Description != "List items\n\nReturns items in stock, newest first.\n" {
+		t.Fatalf("unexpected description %q", byName["listItems"].Description)
+	}
+
+	var list struct {
+		Properties map[string]map[string]any `json:"properties"`
+		Required   []string                  `json:"required"`
+	}
+	if err := json.Unmarshal(byName["listItems"].Parameters, &list); err != nil {
+		t.Fatalf("decode schema: %v", err)
+	}
+	if list.Properties["limit"]["description"] != "Maximum number of items." || list.Properties["limit"]["maximum"] != 100.0 {
+		t.Fatalf("unexpected limit schema %v", list.Properties["limit"])
+	}
+	if _, ok := list.Properties["X-Request-ID"]; !ok || len(list.Required) != 0 {
+		t.Fatalf("unexpected listItems schema %s", byName["listItems"].Parameters)
+	}
+
+	// The request body schema is inlined, including the recursive
+	// Category schema, which is cut off at the recursion.
+	var create struct {
+		Properties struct {
+			Body struct {
+				Required   []string `json:"required"`
+				Properties struct {
+					Name     map[string]any `json:"name"`
+					Category struct {
+						Properties struct {
+							Parent map[string]any `json:"parent"`
+						} `json:"properties"`
+					} `json:"category"`
+				} `json:"properties"`
+			} `json:"body"`
+		} `json:"properties"`
+		Required []string `json:"required"`
+	}
+	if err := json.Unmarshal(byName["createItem"].Parameters, &create); err != nil {
+		t.Fatalf("decode schema: %v", err)
+	}
+	body := create.Properties.Body
+	if !reflect.DeepEqual(create.Required, []string{"body"}) || !reflect.DeepEqual(body.Required, []string{"name"}) {
+		t.Fatalf("unexpected createItem schema %s", byName["createItem"].Parameters)
+	}
+	if body.Properties.Name["description"] != "Display name.\nShown in the catalog.\n" || len(body.Properties.Category.Properties.Parent) != 0 {
+		t.Fatalf("unexpected item schema %s", byName["createItem"].Parameters)
+	}
+}
+
+func TestParse_JSONMatchesYAML(t *testing.T) {
+	data, err := os.ReadFile("testdata/inventory.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := parseYAML(data)
+	if err != nil {
+		t.Fatalf("parseYAML error: %v", err)
+	}
+	asJSON, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON, err := Parse(asJSON)
+	if err != nil {
+		t.Fatalf("Parse(JSON) error: %v", err)
+	}
+	opts := Options{Tags: []string{"items", "admin"}}
+	want, err := loadFixture(t).Tools(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := fromJSON.Tools(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 4 || len(got) != len(want) {
+		t.Fatalf("expected 4 tools from both, got %d and %d", len(got), len(want))
+	}
+	for i := range got {
+		if got[i].Name != want[i].Name || string(got[i].Parameters) != string(want[i].Parameters) {
+			t.Fatalf("tool %d differs:\n%s %s\n%s %s", i, got[i].Name, got[i].Parameters, want[i].Name, want[i].Parameters)
+		}
+	}
+}
+
+func TestTools_Selection(t *testing.T) {
+	spec := loadFixture(t)
+	names := func(opts Options) []string {
+		tools, err := spec.Tools(opts)
+		if err != nil {
+			t.Fatalf("Tools(%+v) error: %v", opts, err)
+		}
+		var out []string
+		for _, tool := range tools {
+			out = append(out, tool.Name)
+		}
+		return out
+	}
+	if got := names(Options{Tags: []string{"items"}}); !reflect.DeepEqual(got, []string{"listItems", "createItem", "getItem"}) {
+		t.Fatalf("tag selection = %v", got)
+	}
+	if got := names(Options{Operations: []string{"deleteItem"}, Tags: []string{"write"}}); !reflect.DeepEqual(got, []string{"createItem", "deleteItem"}) {
+		t.Fatalf("mixed selection = %v", got)
+	}
+
+	var invalid *ai.InvalidArgumentError
+	if _, err := spec.Tools(Options{}); !errors.As(err, &invalid) {
+		t.Fatalf("expected InvalidArgumentError without a selection, got %v", err)
+	}
+	if _, err := spec.Tools(Options{Operations: []string{"dropDatabase"}}); !errors.As(err, &invalid) || invalid.Value != "dropDatabase" {
+		t.Fatalf("expected InvalidArgumentError for an unknown operation, got %v", err)
+	}
+}
+
+func TestTools_Execute(t *testing.T) {
+	type request struct {
+		method, path, query, auth, requestID, body string
+	}
+	var got request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = request{r.Method, r.URL.EscapedPath(), r.URL.RawQuery, r.Header.Get("Authorization"), r.Header.Get("X-Request-ID"), string(body)}
+		switch {
+		case r.URL.Path == "/v1/items/missing":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"no such item"}`))
+		case r.URL.Path == "/v1/items/big":
+			w.Write([]byte(`"` + strings.Repeat("x", 100) + `"`))
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"ok":true}`))
+		}
+	}))
+	defer srv.Close()
+
+	tools, err := loadFixture(t).Tools(Options{
+		Tags:    []string{"items", "admin"},
+		BaseURL: srv.URL + "/v1/",
+		Authorize: func(ctx context.Context, req *http.Request) error {
+			req.Header.Set("Authorization", "Bearer secret")
+			return nil
+		},
+		MaxResponseBytes: 64,
+	})
+	if err != nil {
+		t.Fatalf("Tools error: %v", err)
+	}
+	byName := toolsByName(tools)
+	ctx := context.Background()
+
+	res, err := byName["listItems"].Execute(ctx, json.RawMessage(`{"limit":5,"tag":["red","blue"],"X-Request-ID":"r-1"}`))
+	want := request{"GET", "/v1/items", "limit=5&tag=red&tag=blue", "Bearer secret", "r-1", ""}
+	if err != nil || got != want || string(res.(json.RawMessage)) != `{"ok":true}` {
+		t.Fatalf("listItems = %v, %v; request %+v", res, err, got)
+	}
+
+	_, err = byName["createItem"].Execute(ctx, json.RawMessage(`{"body":{"name":"Lamp","quantity":2}}`))
+	if err != nil || got.method != "POST" || got.body != `{"name":"Lamp","quantity":2}` {
+		t.Fatalf("createItem error %v; request %+v", err, got)
+	}
+
+	_, err = byName["getItem"].Execute(ctx, json.RawMessage(`{"itemId":"a/b c"}`))
+	if err != nil || got.path != "/v1/items/a%2Fb%20c" {
+		t.Fatalf("getItem error %v; request %+v", err, got)
+	}
+
+	if res, err := byName["deleteItem"].Execute(ctx, json.RawMessage(`{"itemId":"7"}`)); err != nil || res != nil || got.method != "DELETE" {
+		t.Fatalf("deleteItem = %v, %v; request %+v", res, err, got)
+	}
+
+	var httpErr *HTTPError
+	_, err = byName["getItem"].Execute(ctx, json.RawMessage(`{"itemId":"missing"}`))
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != 404 || httpErr.Body != `{"error":"no such item"}` || httpErr.Tool != "getItem" {
+		t.Fatalf("expected *HTTPError 404, got %v", err)
+	}
+	if want := `openapi: tool getItem: HTTP 404 Not Found: {"error":"no such item"}`; err.Error() != want {
+		t.Fatalf("error = %q, want %q", err, want)
+	}
+
+	var tooLarge *provider.ResponseTooLargeError
+	if _, err := byName["getItem"].Execute(ctx, json.RawMessage(`{"itemId":"big"}`)); !errors.As(err, &tooLarge) || tooLarge.Limit != 64 {
+		t.Fatalf("expected *provider.ResponseTooLargeError, got %v", err)
+	}
+
+	var unknown *ai.UnknownFieldsError
+	if _, err := byName["getItem"].Execute(ctx, json.RawMessage(`{"itemId":"1","verbose":true}`)); !errors.As(err, &unknown) || unknown.Fields[0] != "verbose" {
+		t.Fatalf("expected *ai.UnknownFieldsError, got %v", err)
+	}
+	if _, err := byName["createItem"].Execute(ctx, json.RawMessage(`{}`)); err == nil || !strings.Contains(err.Error(), "missing required arguments: body") {
+		t.Fatalf("expected a missing body error, got %v", err)
+	}
+}
+
+func TestTools_DefaultServerURL(t *testing.T) {
+	tools, err := loadFixture(t).Tools(Options{Operations: []string{"getItem"}, HTTPClient: recordingClient(func(req *http.Request) {
+		if req.URL.String() != "https://eu.inventory.example.com/v1/items/42" {
+			t.Errorf("unexpected URL %s", req.URL)
+		}
+	})})
+	if err != nil {
+		t.Fatalf("Tools error: %v", err)
+	}
+	if _, err := tools[0].Execute(context.Background(), json.RawMessage(`{"itemId":42}`)); err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+}
+
+type recordingClient func(req *http.Request)
+
+func (f recordingClient) Do(req *http.Request) (*http.Response, error) {
+	f(req)
+	return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(`{}`))}, nil
+}
+
+func TestParse_Errors(t *testing.T) {
+	cases := []struct {
+		name, doc, want string
+	}{
+		{"swagger 2", `{"swagger":"2.0"}`, "only OpenAPI 3"},
+		{"dangling ref", "openapi: 3.1.0\npaths:\n  /a:\n    get:\n      parameters:\n        - $ref: '#/components/parameters/Nope'\n", "dangling $ref"},
+		{"external ref", "openapi: 3.1.0\npaths:\n  /a:\n    post:\n      requestBody:\n        content:\n          application/json:\n            schema: {$ref: 'other.yaml#/Item'}\n", "only local references"},
+		{"alias", "openapi: 3.1.0\ninfo: *info\n", "aliases"},
+		{"duplicate key", "openapi: 3.1.0\nopenapi: 3.0.0\n", "duplicate key"},
+	}
+	for _, tc := range cases {
+		if _, err := Parse([]byte(tc.doc)); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected error mentioning %q, got %v", tc.name, tc.want, err)
+		}
+	}
+}
@@ -0,0 +1,536 @@
+package openapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseYAML decodes the subset of YAML that OpenAPI documents use into
+// map[string]any, []any, string, bool, int64, float64, and nil values:
+// block mappings and sequences, flow collections, plain and quoted
+// scalars, literal (|) and folded (>) block scalars, and comments.
+// Anchors, aliases, tags, and multi-document streams are not supported
+// and fail with an error.
+func parseYAML(data []byte) (any, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	text = strings.TrimPrefix(text, "\ufeff")
+	p := &yamlParser{lines: strings.Split(text, "\n")}
+	p.skipBlank()
+	if p.pos < len(p.lines) && strings.TrimSpace(p.lines[p.pos]) == "---" {
+		p.pos++
+		p.skipBlank()
+	}
+	if p.pos == len(p.lines) {
+		return nil, nil
+	}
+	v, err := p.block(p.indent())
+	if err != nil {
+		return nil, err
+	}
+	if p.skipBlank(); p.pos < len(p.lines) {
+		return nil, p.errorf("unexpected content %q", p.text())
+	}
+	return v, nil
+}
+
+type yamlParser struct {
+	lines []string
+	pos   int
+	// sub replaces the current line's content after a sequence dash,
+	// so "- key: value" is parsed as a mapping at the column of "key".
+	sub       string
+	subIndent int
+	subActive bool
+}
+
+func (p *yamlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("yaml: line %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+// skipBlank moves past blank and comment-only lines.
+func (p *yamlParser) skipBlank() {
+	for !p.subActive && p.pos < len(p.lines) {
+		if t := strings.TrimSpace(p.lines[p.pos]); t != "" && !strings.HasPrefix(t, "#") {
+			return
+		}
+		p.pos++
+	}
+}
+
+// indent returns the indentation of the current line.
+func (p *yamlParser) indent() int {
+	if p.subActive {
+		return p.subIndent
+	}
+	line := p.lines[p.pos]
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// text returns the current line without indentation and comment.
+func (p *yamlParser) text() string {
+	if p.subActive {
+		return p.sub
+	}
+	return stripComment(strings.TrimSpace(p.lines[p.pos]))
+}
+
+// next moves to the next structural line.
+func (p *yamlParser) next() {
+	if p.subActive {
+		p.subActive = false
+	}
+	p.pos++
+	p.skipBlank()
+}
+
+func (p *yamlParser) done() bool {
+	return !p.subActive && p.pos >= len(p.lines)
+}
+
+// block parses the node starting at the current line, which must be
+// indented by exactly indent.
+func (p *yamlParser) block(indent int) (any, error) {
+	t := p.text()
+	switch {
+	case strings.HasPrefix(t, "&"), strings.HasPrefix(t, "*"), strings.HasPrefix(t, "!"):
+		return nil, p.errorf("anchors, aliases, and tags are not supported")
+	case isSeqItem(t):
+		return p.sequence(indent)
+	}
+	if _, _, ok := splitKey(t); ok {
+		return p.mapping(indent)
+	}
+	v, err := p.inlineValue(t, indent)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func isSeqItem(t string) bool {
+	return t == "-" || strings.HasPrefix(t, "- ")
+}
+
+func (p *yamlParser) sequence(indent int) ([]any, error) {
+	out := []any{}
+	for !p.done() && p.indent() == indent && isSeqItem(p.text()) {
+		t := p.text()
+		rest := strings.TrimLeft(strings.TrimPrefix(t, "-"), " ")
+		if rest == "" {
+			p.next()
+			if p.done() || p.indent() <= indent {
+				out = append(out, nil)
+				continue
+			}
+			v, err := p.block(p.indent())
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+			continue
+		}
+		p.sub, p.subIndent, p.subActive = rest, p.indent()+len(t)-len(rest), true
+		v, err := p.block(p.subIndent)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	if !p.done() && p.indent() > indent {
+		return nil, p.errorf("bad indentation of a sequence entry")
+	}
+	return out, nil
+}
+
+func (p *yamlParser) mapping(indent int) (map[string]any, error) {
+	out := map[string]any{}
+	for !p.done() && p.indent() == indent && !isSeqItem(p.text()) {
+		key, value, ok := splitKey(p.text())
+		if !ok {
+			return nil, p.errorf("expected a mapping key, got %q", p.text())
+		}
+		if _, dup := out[key]; dup {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+		var (
+			v   any
+			err error
+		)
+		switch {
+		case value == "":
+			p.next()
+			switch {
+			case !p.done() && p.indent() > indent:
+				v, err = p.block(p.indent())
+			case !p.done() && p.indent() == indent && isSeqItem(p.text()):
+				v, err = p.sequence(indent)
+			}
+		case value[0] == '|' || value[0] == '>':
+			v, err = p.blockScalar(value, indent)
+		default:
+			v, err = p.inlineValue(value, indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		out[key] = v
+	}
+	if !p.done() && p.indent() > indent {
+		return nil, p.errorf("bad indentation of a mapping entry")
+	}
+	return out, nil
+}
+
+// inlineValue parses a scalar or flow collection that starts on the
+// current line, joining following lines while a flow collection is
+// open, and moves past it.
+func (p *yamlParser) inlineValue(s string, indent int) (any, error) {
+	if strings.HasPrefix(s, "&") || strings.HasPrefix(s, "*") || strings.HasPrefix(s, "!") {
+		return nil, p.errorf("anchors, aliases, and tags are not supported")
+	}
+	if s[0] == '[' || s[0] == '{' {
+		for !flowClosed(s) {
+			p.next()
+			if p.done() {
+				return nil, p.errorf("unterminated flow collection")
+			}
+			s += " " + p.text()
+		}
+		f := &flowParser{s: s}
+		v, err := f.value()
+		if err == nil {
+			f.space()
+			if f.i < len(f.s) {
+				err = fmt.Errorf("unexpected %q after flow collection", f.s[f.i:])
+			}
+		}
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		p.next()
+		return v, nil
+	}
+	if s[0] == '"' || s[0] == '\'' {
+		v, rest, err := quoted(s)
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		if strings.TrimSpace(rest) != "" {
+			return nil, p.errorf("unexpected %q after quoted scalar", rest)
+		}
+		p.next()
+		return v, nil
+	}
+	// A plain scalar may continue on more indented lines.
+	p.next()
+	for !p.done() && p.indent() > indent && !p.subActive {
+		t := p.text()
+		if _, _, isKey := splitKey(t); isKey || isSeqItem(t) {
+			break
+		}
+		s += " " + t
+		p.next()
+	}
+	return plainScalar(s), nil
+}
+
+// blockScalar parses a literal (|) or folded (>) block scalar whose
+// header is on the current line.
+func (p *yamlParser) blockScalar(header string, indent int) (any, error) {
+	folded := header[0] == '>'
+	chomp := byte(0)
+	for _, c := range []byte(header[1:]) {
+		switch {
+		case c == '-' || c == '+':
+			chomp = c
+		case c >= '1' && c <= '9':
+		default:
+			return nil, p.errorf("unsupported block scalar header %q", header)
+		}
+	}
+	p.subActive = false
+	p.pos++
+
+	var lines []string
+	blockIndent := -1
+	for ; p.pos < len(p.lines); p.pos++ {
+		line := p.lines[p.pos]
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" {
+			lines = append(lines, "")
+			continue
+		}
+		n := len(line) - len(trimmed)
+		if n <= indent {
+			break
+		}
+		if blockIndent < 0 {
+			blockIndent = n
+		}
+		if n < blockIndent {
+			return nil, p.errorf("bad indentation in block scalar")
+		}
+		lines = append(lines, line[blockIndent:])
+	}
+	p.skipBlank()
+
+	content := len(lines)
+	for content > 0 && lines[content-1] == "" {
+		content--
+	}
+	var b strings.Builder
+	for i, l := range lines[:content] {
+		if i > 0 {
+			prev := lines[i-1]
+			switch {
+			case !folded, l == "", strings.HasPrefix(l, " "), strings.HasPrefix(prev, " "):
+				b.WriteByte('\n')
+			case prev == "":
+				// The blank line already broke the line.
+			default:
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(l)
+	}
+	s := b.String()
+	switch {
+	case chomp == '-' || content == 0:
+	case chomp == '+':
+		s += strings.Repeat("\n", len(lines)-content+1)
+	default:
+		s += "\n"
+	}
+	return s, nil
+}
+
+// splitKey splits "key: value" at the first ": " (or a trailing ':')
+// outside quotes. Keys may be quoted.
+func splitKey(t string) (key, value string, ok bool) {
+	if t == "" || t[0] == '[' || t[0] == '{' {
+		return "", "", false
+	}
+	if t[0] == '"' || t[0] == '\'' {
+		k, rest, err := quoted(t)
+		if err != nil || !strings.HasPrefix(rest, ":") {
+			return "", "", false
+		}
+		rest = rest[1:]
+		if rest != "" && rest[0] != ' ' {
+			return "", "", false
+		}
+		return k, strings.TrimSpace(rest), true
+	}
+	for i := 0; i < len(t); i++ {
+		if t[i] == ':' && (i == len(t)-1 || t[i+1] == ' ') {
+			return strings.TrimSpace(t[:i]), strings.TrimSpace(t[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// stripComment removes a " #" comment outside quotes.
+func stripComment(t string) string {
+	var quote byte
+	for i := 0; i < len(t); i++ {
+		c := t[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || strings.IndexByte(" [{,:", t[i-1]) >= 0 {
+				quote = c
+			}
+		case c == '#' && (i == 0 || t[i-1] == ' ' || t[i-1] == '\t'):
+			return strings.TrimSpace(t[:i])
+		}
+	}
+	return t
+}
+
+// quoted parses the quoted scalar at the start of s and returns it with
+// the rest of s.
+func quoted(s string) (string, string, error) {
+	q := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case q == '"' && s[i] == '\\':
+			i++
+		case q == '\'' && s[i] == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == q:
+			if q == '\'' {
+				return strings.ReplaceAll(s[1:i], "''", "'"), s[i+1:], nil
+			}
+			v, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", "", fmt.Errorf("bad double-quoted scalar %s", s[:i+1])
+			}
+			return v, s[i+1:], nil
+		}
+	}
+	return "", "", fmt.Errorf("unterminated quoted scalar")
+}
+
+// plainScalar resolves an unquoted scalar to null, a bool, a number, or
+// a string.
+func plainScalar(s string) any {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	if c := s[0]; c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9') {
+		if f, err := strconv.ParseFloat(s, 64); err == nil && !strings.ContainsAny(s, "xXpP_") {
+			return f
+		}
+	}
+	return s
+}
+
+// flowClosed reports whether every bracket opened in s is closed.
+func flowClosed(s string) bool {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return depth <= 0
+}
+
+// flowParser parses a flow collection such as [a, "b"] or {k: v}.
+type flowParser struct {
+	s string
+	i int
+}
+
+func (f *flowParser) space() {
+	for f.i < len(f.s) && f.s[f.i] == ' ' {
+		f.i++
+	}
+}
+
+func (f *flowParser) value() (any, error) {
+	f.space()
+	if f.i == len(f.s) {
+		return nil, fmt.Errorf("unexpected end of flow collection")
+	}
+	switch c := f.s[f.i]; c {
+	case '[':
+		f.i++
+		out := []any{}
+		for {
+			f.space()
+			if f.i < len(f.s) && f.s[f.i] == ']' {
+				f.i++
+				return out, nil
+			}
+			v, err := f.value()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+			if err := f.separator(']'); err != nil {
+				return nil, err
+			}
+		}
+	case '{':
+		f.i++
+		out := map[string]any{}
+		for {
+			f.space()
+			if f.i < len(f.s) && f.s[f.i] == '}' {
+				f.i++
+				return out, nil
+			}
+			k, err := f.scalar(true)
+			if err != nil {
+				return nil, err
+			}
+			key := fmt.Sprint(k)
+			if k == nil {
+				key = ""
+			}
+			f.space()
+			var v any
+			if f.i < len(f.s) && f.s[f.i] == ':' {
+				f.i++
+				if v, err = f.value(); err != nil {
+					return nil, err
+				}
+			}
+			out[key] = v
+			if err := f.separator('}'); err != nil {
+				return nil, err
+			}
+		}
+	case '"', '\'':
+		v, rest, err := quoted(f.s[f.i:])
+		if err != nil {
+			return nil, err
+		}
+		f.i = len(f.s) - len(rest)
+		return v, nil
+	}
+	return f.scalar(false)
+}
+
+// scalar parses a plain scalar in a flow collection. Keys also end at
+// ':'.
+func (f *flowParser) scalar(key bool) (any, error) {
+	f.space()
+	if f.i < len(f.s) && (f.s[f.i] == '"' || f.s[f.i] == '\'') {
+		v, rest, err := quoted(f.s[f.i:])
+		if err != nil {
+			return nil, err
+		}
+		f.i = len(f.s) - len(rest)
+		return v, nil
+	}
+	start := f.i
+	for f.i < len(f.s) && strings.IndexByte(",]}", f.s[f.i]) < 0 {
+		if key && f.s[f.i] == ':' {
+			break
+		}
+		f.i++
+	}
+	return plainScalar(strings.TrimSpace(f.s[start:f.i])), nil
+}
+
+// separator consumes a ',' or the closing bracket, leaving the latter.
+func (f *flowParser) separator(closer byte) error {
+	f.space()
+	switch {
+	case f.i < len(f.s) && f.s[f.i] == ',':
+		f.i++
+		return nil
+	case f.i < len(f.s) && f.s[f.i] == closer:
+		return nil
+	}
+	return fmt.Errorf("expected ',' or %q in flow collection", closer)
+}
@@ -0,0 +1,52 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	cases := []struct {
+		name string
+		doc  string
+		want any
+	}{
+		{"scalars", "a: 1\nb: 1.5\nc: true\nd: ~\ne: 'it''s'\nf: \"tab\\t\"\ng: 2024-01-01\nh: 0x10\n", map[string]any{
+			"a": int64(1), "b": 1.5, "c": true, "d": nil, "e": "it's", "f": "tab\t", "g": "2024-01-01", "h": "0x10",
+		}},
+		{"sequence at key indent", "tags:\n- a\n- b\nnext: x\n", map[string]any{"tags": []any{"a", "b"}, "next": "x"}},
+		{"sequence of mappings", "- name: a\n  in: query\n-\n  name: b\n- [1, 2]\n", []any{
+			map[string]any{"name": "a", "in": "query"}, map[string]any{"name": "b"}, []any{int64(1), int64(2)},
+		}},
+		{"nested flow", "s: {type: array, items: {type: string}, enum: ['a, b', \"c\"]}\n", map[string]any{
+			"s": map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "enum": []any{"a, b", "c"}},
+		}},
+		{"multi-line flow", "required: [\n  id,\n  name]\n", map[string]any{"required": []any{"id", "name"}}},
+		{"comments", "# head\na: x # note\nb: 'y # kept'\nc: http://h/#frag\n", map[string]any{"a": "x", "b": "y # kept", "c": "http://h/#frag"}},
+		{"literal keep and strip", "a: |+\n  x\n\nb: |-\n  y\n  z\n", map[string]any{"a": "x\n\n", "b": "y\nz"}},
+		{"folded paragraphs", "a: >\n  one\n  two\n\n  three\nb: 1\n", map[string]any{"a": "one two\nthree\n", "b": int64(1)}},
+		{"plain continuation", "a: one\n  two\nb: c\n", map[string]any{"a": "one two", "b": "c"}},
+		{"quoted and colon keys", "\"/a\": 1\n/b/{id}:\n  x: y\n'200': ok\n", map[string]any{"/a": int64(1), "/b/{id}": map[string]any{"x": "y"}, "200": "ok"}},
+		{"empty value", "a:\nb: 2\n", map[string]any{"a": nil, "b": int64(2)}},
+	}
+	for _, tc := range cases {
+		got, err := parseYAML([]byte(tc.doc))
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: parseYAML = %#v, %v; want %#v", tc.name, got, err, tc.want)
+		}
+	}
+}
+
+func TestParseYAML_Errors(t *testing.T) {
+	for _, doc := range []string{
+		"a: 1\n  b: 2\n",
+		"a: [1, 2\n",
+		"a: \"open\n",
+		"a: &x 1\n",
+		"a: !!str 1\n",
+	} {
+		if _, err := parseYAML([]byte(doc)); err == nil {
+			t.Errorf("parseYAML(%q): expected an error", doc)
+		}
+	}
+}
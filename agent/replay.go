@@ -0,0 +1,225 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	ai "github.com/ncecere/ai-sdk"
+)
+
+// ToolResultSource serves tool results in place of executing tools.
+// With Config.ToolResultSource set, the agent never calls Tool.Execute
+// or the Approver; each tool call is answered with the value returned
+// by ToolResult, encoded like a Tool.Execute result. Replay is the
+// implementation for recorded runs.
+type ToolResultSource interface {
+	ToolResult(ctx context.Context, step int, call ai.ToolCall) (any, error)
+}
+
+// ReplayMatch controls how a Replay handles calls that are not in the
+// recording.
+type ReplayMatch string
+
+const (
+	// ReplayStrict fails the run with a *ReplayMismatchError on a call
+	// whose tool and arguments were not recorded.
+	ReplayStrict ReplayMatch = "strict"
+	// ReplayLenient answers a call with unrecorded arguments with a
+	// recorded result of the same tool, and a call to a tool that was
+	// never recorded with an error result, so the run continues.
+	ReplayLenient ReplayMatch = "lenient"
+)
+
+// DivergenceReason describes how a tool call differs from the
+// recording.
+type DivergenceReason string
+
+const (
+	// DivergenceUnrecordedTool means the recording has no call to the
+	// tool at all.
+	DivergenceUnrecordedTool DivergenceReason = "unrecorded_tool"
+	// DivergenceUnrecordedArguments means the tool was recorded, but
+	// not with these arguments.
+	DivergenceUnrecordedArguments DivergenceReason = "unrecorded_arguments"
+)
+
+// Divergence is a tool call of a replayed run that the recording does
+// not contain.
+type Divergence struct {
+	// Step is the zero-based tool-loop iteration of the call.
+	Step int `json:"step"`
+	// Tool is the name of the called tool.
+	Tool string `json:"tool"`
+	// Arguments are the raw JSON arguments provided by the model.
+	Arguments json.RawMessage  `json:"arguments,omitempty"`
+	Reason    DivergenceReason `json:"reason"`
+	// Served reports that a recorded result of the same tool was
+	// returned instead (ReplayLenient only).
+	Served bool `json:"served,omitempty"`
+}
+
+// ReplayMismatchError is returned by a ReplayStrict Replay for a tool
+// call the recording does not contain.
+type ReplayMismatchError struct {
+	Divergence Divergence
+}
+
+func (e *ReplayMismatchError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	d := e.Divergence
+	if d.Reason == DivergenceUnrecordedTool {
+		return fmt.Sprintf("agent: replay: step %d: tool %q was not called in the recording", d.Step, d.Tool)
+	}
+	return fmt.Sprintf("agent: replay: step %d: tool %q was not recorded with arguments %s", d.Step, d.Tool, d.Arguments)
+}
+
+// ReplayOptions configures a Replay.
+type ReplayOptions struct {
+	// Match selects strict or lenient matching. Defaults to
+	// ReplayStrict.
+	Match ReplayMatch
+}
+
+// Replay is a ToolResultSource serving the tool results of a recorded
+// run, so an agent's decisions can be re-run against a new prompt or
+// model without executing expensive or side-effecting tools:
+//
+//	replay := agent.NewReplay(recorded.StepDetails, agent.ReplayOptions{})
+//	cfg.ToolResultSource = replay
+//	res, err := agent.Run(ctx, cfg, messages)
+//	report := replay.Divergences()
+//
+// Calls are matched by tool name and arguments, compared as JSON values
+// so key order and whitespace do not matter. A call recorded several
+// times is answered with its recorded results in order, repeating the
+// last one. A Replay is safe for concurrent use but keeps state, so use
+// a new one per run.
+type Replay struct {
+	match ReplayMatch
+
+	mu          sync.Mutex
+	byCall      map[string]*recordedResults
+	byTool      map[string]*recordedResults
+	divergences []Divergence
+}
+
+// recordedResults serves recorded results in order, repeating the last.
+type recordedResults struct {
+	results []string
+	next    int
+}
+
+func (r *recordedResults) take() string {
+	res := r.results[min(r.next, len(r.results)-1)]
+	r.next++
+	return res
+}
+
+// NewReplay returns a Replay serving the tool calls recorded in
+// details, typically Result.StepDetails of an earlier run.
+func NewReplay(details []StepDetail, opts ReplayOptions) *Replay {
+	r := &Replay{
+		match:  opts.Match,
+		byCall: make(map[string]*recordedResults),
+		byTool: make(map[string]*recordedResults),
+	}
+	if r.match == "" {
+		r.match = ReplayStrict
+	}
+	for _, d := range details {
+		for _, c := range d.ToolCalls {
+			key := callKey(c.Tool, c.Arguments)
+			if r.byCall[key] == nil {
+				r.byCall[key] = &recordedResults{}
+			}
+			r.byCall[key].results = append(r.byCall[key].results, c.Result)
+			if r.byTool[c.Tool] == nil {
+				r.byTool[c.Tool] = &recordedResults{}
+			}
+			r.byTool[c.Tool].results = append(r.byTool[c.Tool].results, c.Result)
+		}
+	}
+	return r
+}
+
+// ToolResult implements ToolResultSource.
+//
+// Errors:
+//   - *ReplayMismatchError for an unrecorded call with ReplayStrict.
+func (r *Replay) ToolResult(ctx context.Context, step int, call ai.ToolCall) (any, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if rec := r.byCall[callKey(call.Name, call.RawArguments)]; rec != nil {
+		return recordedValue(rec.take()), nil
+	}
+
+	d := Divergence{Step: step, Tool: call.Name, Arguments: json.RawMessage(call.RawArguments), Reason: DivergenceUnrecordedArguments}
+	rec := r.byTool[call.Name]
+	if rec == nil {
+		d.Reason = DivergenceUnrecordedTool
+	}
+	if r.match == ReplayStrict {
+		r.divergences = append(r.divergences, d)
+		return nil, &ReplayMismatchError{Divergence: d}
+	}
+	d.Served = rec != nil
+	r.divergences = append(r.divergences, d)
+	if rec == nil {
+		return map[string]string{"error": fmt.Sprintf("no recorded result for tool %q", call.Name)}, nil
+	}
+	return recordedValue(rec.take()), nil
+}
+
+// Divergences returns the calls of the replayed run that the recording
+// does not contain, in order.
+func (r *Replay) Divergences() []Divergence {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Divergence(nil), r.divergences...)
+}
+
+// callKey identifies a call by tool name and a hash of its arguments
+// in canonical JSON form.
+func callKey(tool string, args []byte) string {
+	sum := sha256.Sum256(canonicalArguments(args))
+	return tool + ":" + hex.EncodeToString(sum[:])
+}
+
+// canonicalArguments re-encodes args with sorted keys and no
+// whitespace. Arguments sent as a JSON string holding the JSON object
+// (as the OpenAI client returns them) are unwrapped first. Invalid
+// JSON is compared as-is.
+func canonicalArguments(args []byte) []byte {
+	var s string
+	if json.Unmarshal(args, &s) == nil {
+		args = []byte(s)
+	}
+	var v any
+	if err := json.Unmarshal(args, &v); err != nil {
+		return bytes.TrimSpace(args)
+	}
+	out, _ := json.Marshal(v)
+	return out
+}
+
+// recordedValue returns the result to re-encode for a recorded tool
+// message. Messages built by ai.NewToolResultMessage hold the result
+// next to the original call's name and ID, so only the result is
+// reused; other content is returned as text.
+func recordedValue(content string) any {
+	var payload struct {
+		Tool   string          `json:"tool"`
+		Result json.RawMessage `json:"result"`
+	}
+	if json.Unmarshal([]byte(content), &payload) == nil && payload.Tool != "" && payload.Result != nil {
+		return payload.Result
+	}
+	return content
+}
@@ -0,0 +1,114 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	ai "github.com/ncecere/ai-sdk"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// argsToolModel calls the "search" tool once with args and then answers
+// with the tool's result.
+type argsToolModel struct{ args string }
+
+func (m argsToolModel) Generate(ctx context.Context, req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
+	last := req.Messages[len(req.Messages)-1]
+	if last.Role == ai.RoleTool {
+		return &provider.LanguageModelResponse{Text: last.Content}, nil
+	}
+	return &provider.LanguageModelResponse{ToolCalls: []provider.ToolCall{{ID: "call_new", Name: "search", RawArguments: []byte(m.args)}}}, nil
+}
+
+func (argsToolModel) Stream(ctx context.Context, req *provider.LanguageModelRequest) (provider.LanguageModelStream, error) {
+	return nil, errors.New("argsToolModel: streaming not supported")
+}
+
+func newReplayConfig(args string, executions *int) Config {
+	return testConfig(argsToolModel{args: args}, map[string]Tool{
+		"search": {
+			Name:             "search",
+			RequiresApproval: true,
+			Execute: func(ctx context.Context, args json.RawMessage) (any, error) {
+				*executions++
+				return map[string]int{"hits": *executions}, nil
+			},
+		},
+	})
+}
+
+func TestReplay_ServesRecordedResults(t *testing.T) {
+	var executions int
+	cfg := newReplayConfig(`{"q":"go","limit":3}`, &executions)
+	cfg.Approver = ApprovalFunc(func(ctx context.Context, req ApprovalRequest) (ApprovalDecision, error) {
+		return ApprovalDecision{Approved: true}, nil
+	})
+	recorded, err := Run(context.Background(), cfg, []ai.Message{ai.UserMessage("search")})
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+
+	// The same arguments in another key order and spacing match, and
+	// neither the tool nor the approver is called.
+	replay := NewReplay(recorded.StepDetails, ReplayOptions{})
+	cfg = newReplayConfig(`{ "limit": 3, "q": "go" }`, &executions)
+	cfg.ToolResultSource = replay
+	res, err := Run(context.Background(), cfg, []ai.Message{ai.UserMessage("search")})
+	if err != nil {
+		t.Fatalf("replay Run error: %v", err)
+	}
+	if executions != 1 || len(replay.Divergences()) != 0 {
+		t.Fatalf("expected a pure replay, got %d executions and divergences %+v", executions, replay.Divergences())
+	}
+	var payload struct {
+		ToolCallID string          `json:"tool_call_id"`
+		Result     json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal([]byte(res.FinalText), &payload); err != nil || payload.ToolCallID != "call_new" || string(payload.Result) != `{"hits":1}` {
+		t.Fatalf("unexpected replayed tool message %s", res.FinalText)
+	}
+}
+
+func TestReplay_ReportsDivergences(t *testing.T) {
+	recorded := []StepDetail{{ToolCalls: []ToolCallDetail{{
+		ID: "call_1", Tool: "search", Arguments: json.RawMessage(`{"q":"go"}`),
+		Result: `{"result":{"hits":7},"tool":"search","tool_call_id":"call_1"}`,
+	}}}}
+	var executions int
+	cfg := newReplayConfig(`{"q":"rust"}`, &executions)
+
+	strict := NewReplay(recorded, ReplayOptions{Match: ReplayStrict})
+	cfg.ToolResultSource = strict
+	_, err := Run(context.Background(), cfg, []ai.Message{ai.UserMessage("search")})
+	var mismatch *ReplayMismatchError
+	if !errors.As(err, &mismatch) || mismatch.Divergence.Reason != DivergenceUnrecordedArguments || mismatch.Divergence.Tool != "search" {
+		t.Fatalf("expected a ReplayMismatchError, got %v", err)
+	}
+
+	lenient := NewReplay(recorded, ReplayOptions{Match: ReplayLenient})
+	cfg.ToolResultSource = lenient
+	res, err := Run(context.Background(), cfg, []ai.Message{ai.UserMessage("search")})
+	if err != nil {
+		t.Fatalf("lenient Run error: %v", err)
+	}
+	want := Divergence{Step: 0, Tool: "search", Arguments: json.RawMessage(`{"q":"rust"}`), Reason: DivergenceUnrecordedArguments, Served: true}
+	got := lenient.Divergences()
+	if len(got) != 1 || got[0].Reason != want.Reason || !got[0].Served || string(got[0].Arguments) != string(want.Arguments) {
+		t.Fatalf("unexpected divergences %+v", got)
+	}
+	if executions != 0 || res.FinalText != `{"result":{"hits":7},"tool":"search","tool_call_id":"call_new"}` {
+		t.Fatalf("unexpected lenient result %q after %d executions", res.FinalText, executions)
+	}
+
+	// A tool that was never recorded gets an error result.
+	empty := NewReplay(nil, ReplayOptions{Match: ReplayLenient})
+	cfg.ToolResultSource = empty
+	if _, err := Run(context.Background(), cfg, []ai.Message{ai.UserMessage("search")}); err != nil {
+		t.Fatalf("lenient Run error: %v", err)
+	}
+	if got := empty.Divergences(); len(got) != 1 || got[0].Reason != DivergenceUnrecordedTool || got[0].Served {
+		t.Fatalf("unexpected divergences %+v", got)
+	}
+}
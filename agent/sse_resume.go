@@ -0,0 +1,175 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	ai "github.com/ncecere/ai-sdk"
+)
+
+// StartRun executes an agent run in the background and records its
+// events in store under runID. The run is detached from ctx's
+// cancellation so it keeps going while SSE clients disconnect and
+// reconnect; values carried by ctx remain visible to the run.
+//
+// The returned channel receives the run result (or error) once the run
+// finishes and the store has been marked finished.
+//
+// Errors:
+//   - Any error returned by store.Start.
+func StartRun(ctx context.Context, store EventStore, runID string, cfg Config, initialMessages []ai.Message) (<-chan RunOutcome, error) {
+	if err := store.Start(runID); err != nil {
+		return nil, err
+	}
+	done := make(chan RunOutcome, 1)
+	bg := context.WithoutCancel(ctx)
+	go func() {
+		res, err := RunWithEvents(bg, cfg, initialMessages, func(e Event) {
+			_, _ = store.Append(runID, e)
+		})
+		_ = store.Finish(runID)
+		done <- RunOutcome{Result: res, Err: err}
+	}()
+	return done, nil
+}
+
+// RunOutcome is the final outcome of a run started with StartRun.
+type RunOutcome struct {
+	Result *Result
+	Err    error
+}
+
+// WriteRunAsResumableSSE starts an agent run with StartRun and streams
+// its events as Server-Sent Events with incrementing `id:` fields.
+// If the client disconnects, the run continues and the client can
+// reconnect through ResumeHandler with the Last-Event-ID header to
+// replay missed events and continue live.
+//
+// It returns when the run finishes or ctx is done.
+func WriteRunAsResumableSSE(ctx context.Context, w http.ResponseWriter, store EventStore, runID string, cfg Config, initialMessages []ai.Message) error {
+	if _, err := StartRun(ctx, store, runID, cfg, initialMessages); err != nil {
+		return err
+	}
+	return WriteEventsAsSSE(ctx, w, store, runID, 0)
+}
+
+// WriteEventsAsSSE replays the events of runID with IDs greater than
+// lastEventID from store and then continues streaming live events until
+// the run finishes or ctx is done. Each event is framed as
+// "id: <n>\ndata: <json>\n\n". While no events arrive, a heartbeat
+// comment is sent every SSEHeartbeatInterval.
+//
+// Errors:
+//   - ErrUnknownRun if store has no events for runID.
+//   - ctx.Err() if ctx is done before the run finishes.
+//   - Any error writing to w.
+func WriteEventsAsSSE(ctx context.Context, w http.ResponseWriter, store EventStore, runID string, lastEventID int64) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return fmt.Errorf("agent: response writer does not support flushing")
+	}
+
+	setHeaders := func() {
+		h := w.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Set("Connection", "keep-alive")
+	}
+	headersSent := false
+	for {
+		if err := waitOrHeartbeat(ctx, store, runID, lastEventID); errors.Is(err, errHeartbeatDue) {
+			if !headersSent {
+				setHeaders()
+				headersSent = true
+			}
+			if _, err := fmt.Fprint(w, sseHeartbeat); err != nil {
+				return err
+			}
+			flusher.Flush()
+			continue
+		} else if err != nil {
+			return err
+		}
+		events, ids, finished, err := store.Since(runID, lastEventID)
+		if err != nil {
+			return err
+		}
+		if !headersSent {
+			setHeaders()
+			headersSent = true
+		}
+		for i, e := range events {
+			b, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\ndata: %s\n\n", ids[i], b); err != nil {
+				return err
+			}
+			lastEventID = ids[i]
+		}
+		flusher.Flush()
+		if finished {
+			return nil
+		}
+	}
+}
+
+// errHeartbeatDue reports that a heartbeat should be sent before
+// waiting again.
+var errHeartbeatDue = errors.New("agent: heartbeat due")
+
+// waitOrHeartbeat waits like store.Wait but gives up with
+// errHeartbeatDue after SSEHeartbeatInterval.
+func waitOrHeartbeat(ctx context.Context, store EventStore, runID string, afterID int64) error {
+	if SSEHeartbeatInterval <= 0 {
+		return store.Wait(ctx, runID, afterID)
+	}
+	waitCtx, cancel := context.WithTimeoutCause(ctx, SSEHeartbeatInterval, errHeartbeatDue)
+	defer cancel()
+	err := store.Wait(waitCtx, runID, afterID)
+	if err != nil && ctx.Err() == nil && errors.Is(context.Cause(waitCtx), errHeartbeatDue) {
+		return errHeartbeatDue
+	}
+	return err
+}
+
+// ResumeHandler returns an http.Handler that resumes the SSE stream of
+// a run recorded in store. The run ID is read from the "run_id" query
+// parameter and the position from the Last-Event-ID header (or a
+// "last_event_id" query parameter for clients that cannot set headers).
+// Unknown runs get 404 Not Found.
+func ResumeHandler(store EventStore) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		runID := r.URL.Query().Get("run_id")
+		if runID == "" {
+			http.Error(w, "missing run_id", http.StatusBadRequest)
+			return
+		}
+
+		last := strings.TrimSpace(r.Header.Get("Last-Event-ID"))
+		if last == "" {
+			last = r.URL.Query().Get("last_event_id")
+		}
+		var lastID int64
+		if last != "" {
+			id, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || id < 0 {
+				http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+				return
+			}
+			lastID = id
+		}
+
+		if _, _, _, err := store.Since(runID, lastID); errors.Is(err, ErrUnknownRun) {
+			http.NotFound(w, r)
+			return
+		}
+		_ = WriteEventsAsSSE(r.Context(), w, store, runID, lastID)
+	})
+}
@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ai "github.com/ncecere/ai-sdk"
+)
+
+func TestResumeHandler_ReplaysFromLastEventID(t *testing.T) {
+	store := NewRingEventStore(0)
+	cfg := newLoopingConfig(&loopingModel{})
+	cfg.FinalAnswerOnMaxSteps = true
+
+	done, err := StartRun(context.Background(), store, "run-1", cfg, []ai.Message{ai.UserMessage("find it")})
+	if err != nil {
+		t.Fatalf("StartRun error: %v", err)
+	}
+	if out := <-done; out.Err != nil {
+		t.Fatalf("run error: %v", out.Err)
+	}
+
+	all, ids, finished, err := store.Since("run-1", 0)
+	if err != nil || !finished || len(all) < 4 || ids[0] != 1 {
+		t.Fatalf("unexpected stored events: %d events, ids=%v, finished=%t, err=%v", len(all), ids, finished, err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/resume?run_id=run-1", nil)
+	req.Header.Set("Last-Event-ID", "2")
+	rec := httptest.NewRecorder()
+	ResumeHandler(store).ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	if strings.Contains(body, "id: 2\n") || !strings.HasPrefix(body, "id: 3\n") {
+		t.Fatalf("expected replay to start after event 2, got:\n%s", body)
+	}
+	if got := strings.Count(body, "\nid: ") + 1; got != len(all)-2 {
+		t.Fatalf("expected %d replayed events, got %d", len(all)-2, got)
+	}
+
+	rec = httptest.NewRecorder()
+	ResumeHandler(store).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/resume?run_id=missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown run, got %d", rec.Code)
+	}
+}
+
+func TestWriteEventsAsSSE_ContinuesLive(t *testing.T) {
+	store := NewRingEventStore(2)
+	_ = store.Start("run")
+	_, _ = store.Append("run", Event{Type: EventTypeMessage, Content: "one"})
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_, _ = store.Append("run", Event{Type: EventTypeMessage, Content: "two"})
+		_ = store.Finish("run")
+	}()
+
+	rec := httptest.NewRecorder()
+	if err := WriteEventsAsSSE(context.Background(), rec, store, "run", 0); err != nil {
+		t.Fatalf("WriteEventsAsSSE error: %v", err)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "id: 1\n") || !strings.Contains(body, `"content":"two"`) {
+		t.Fatalf("expected replayed and live events, got:\n%s", body)
+	}
+}
+
+func TestWriteEventsAsSSE_HeartbeatsWhileIdle(t *testing.T) {
+	defer func(d time.Duration) { SSEHeartbeatInterval = d }(SSEHeartbeatInterval)
+	SSEHeartbeatInterval = 5 * time.Millisecond
+
+	store := NewRingEventStore(0)
+	_ = store.Start("run")
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		_, _ = store.Append("run", Event{Type: EventTypeMessage, Content: "late"})
+		_ = store.Finish("run")
+	}()
+
+	rec := httptest.NewRecorder()
+	if err := WriteEventsAsSSE(context.Background(), rec, store, "run", 0); err != nil {
+		t.Fatalf("WriteEventsAsSSE error: %v", err)
+	}
+	body := rec.Body.String()
+	if !strings.HasPrefix(body, ": heartbeat\n\n") || !strings.Contains(body, `"content":"late"`) {
+		t.Fatalf("expected heartbeats before the event, got:\n%s", body)
+	}
+}
+
+func TestRingEventStore_EvictsFinishedRuns(t *testing.T) {
+	now := time.Unix(0, 0)
+	store := NewRingEventStoreWithOptions(RingEventStoreOptions{MaxFinishedRuns: 2, TTL: time.Minute})
+	store.now = func() time.Time { return now }
+	known := func(runID string) bool {
+		_, _, _, err := store.Since(runID, 0)
+		return !errors.Is(err, ErrUnknownRun)
+	}
+
+	for _, id := range []string{"a", "b", "c", "running"} {
+		_ = store.Start(id)
+		_, _ = store.Append(id, Event{Type: EventTypeMessage})
+	}
+	_ = store.Finish("a")
+	_ = store.Finish("b")
+	_ = store.Finish("c")
+	if known("a") || !known("b") || !known("c") || !known("running") {
+		t.Fatal("expected only the oldest finished run to be evicted")
+	}
+
+	now = now.Add(2 * time.Minute)
+	_ = store.Start("d")
+	if known("b") || known("c") || !known("running") {
+		t.Fatal("expected expired finished runs to be evicted and running ones kept")
+	}
+
+	waited := make(chan error, 1)
+	go func() { waited <- store.Wait(context.Background(), "running", 1) }()
+	time.Sleep(10 * time.Millisecond)
+	_ = store.Delete("running")
+	if err := <-waited; err != nil || known("running") {
+		t.Fatalf("expected Delete to remove the run and release waiters, got %v", err)
+	}
+	if err := store.Delete("missing"); err != nil {
+		t.Fatalf("Delete of an unknown run: %v", err)
+	}
+}
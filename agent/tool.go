@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	ai "github.com/ncecere/ai-sdk"
+)
+
+// NewTool builds a Tool whose parameters schema is inferred from Args
+// (see ai.JSONSchemaFromType, including its example and default struct
+// tags) and whose arguments are decoded into Args before fn is called.
+// Use Tool.WithExamples to add whole-call examples.
+//
+// Because the schema is derived from Args, decoding is strict: arguments
+// containing keys unknown to Args fail with an *ai.UnknownFieldsError.
+// Use NewToolWithOptions to change this or to enable UseNumber.
+func NewTool[Args any](name, description string, fn func(ctx context.Context, args Args) (any, error)) (Tool, error) {
+	return NewToolWithOptions(name, description, ai.DecodeOptions{DisallowUnknownFields: true}, fn)
+}
+
+// NewToolWithOptions is like NewTool but decodes arguments with the
+// given options.
+//
+// Errors:
+//   - A wrapped error if a JSON schema cannot be inferred from Args.
+func NewToolWithOptions[Args any](name, description string, opts ai.DecodeOptions, fn func(ctx context.Context, args Args) (any, error)) (Tool, error) {
+	var zero Args
+	schema, err := ai.JSONSchemaFromType(zero)
+	if err != nil {
+		return Tool{}, fmt.Errorf("agent: building JSON schema for tool %q: %w", name, err)
+	}
+
+	return Tool{
+		Name:        name,
+		Description: description,
+		Parameters:  schema,
+		Execute: func(ctx context.Context, raw json.RawMessage) (any, error) {
+			var args Args
+			if len(raw) > 0 {
+				if err := ai.DecodeToolCallArgsWithOptions(ai.ToolCall{Name: name, RawArguments: raw}, &args, opts); err != nil {
+					return nil, err
+				}
+			}
+			return fn(ctx, args)
+		},
+	}, nil
+}
+
+// WithExamples returns a copy of t whose Parameters schema lists
+// examples, typically values of the tool's argument type, under its
+// top-level "examples" keyword (see ai.AddSchemaExamples).
+//
+// Errors:
+//   - A wrapped error if Parameters is not a JSON object or an example
+//     cannot be encoded.
+func (t Tool) WithExamples(examples ...any) (Tool, error) {
+	schema := t.Parameters
+	if len(schema) == 0 {
+		schema = json.RawMessage(`{"type":"object"}`)
+	}
+	schema, err := ai.AddSchemaExamples(schema, examples...)
+	if err != nil {
+		return Tool{}, fmt.Errorf("agent: tool %q: %w", t.Name, err)
+	}
+	t.Parameters = schema
+	return t, nil
+}
@@ -0,0 +1,114 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	ai "github.com/ncecere/ai-sdk"
+)
+
+// ExpandToolNames resolves tool names and patterns against tools and
+// returns the matching tool names without duplicates. A name is either
+// an exact tool name, "<group>.*" for every tool whose Group is group,
+// or "*" for all tools. Tools matched by a pattern are returned in name
+// order, in the position of the pattern.
+//
+// Errors:
+//   - *ai.InvalidArgumentError if a name matches no tool.
+func ExpandToolNames(tools map[string]Tool, names []string) ([]string, error) {
+	var out []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			out = append(out, name)
+		}
+	}
+	for _, name := range names {
+		group, isGroup := strings.CutSuffix(name, ".*")
+		if name != "*" && !isGroup {
+			if _, ok := tools[name]; !ok {
+				return nil, &ai.InvalidArgumentError{Parameter: "names", Value: name, Message: fmt.Sprintf("no tool registered with name %q", name)}
+			}
+			add(name)
+			continue
+		}
+		var matched []string
+		for n, t := range tools {
+			if name == "*" || t.Group == group {
+				matched = append(matched, n)
+			}
+		}
+		if len(matched) == 0 {
+			return nil, &ai.InvalidArgumentError{Parameter: "names", Value: name, Message: fmt.Sprintf("no tools match %q", name)}
+		}
+		slices.Sort(matched)
+		for _, n := range matched {
+			add(n)
+		}
+	}
+	return out, nil
+}
+
+// toolDefinitions returns the definitions of the offered tools in
+// ToolOrder, then in name order.
+func (c *Config) toolDefinitions(offered map[string]struct{}) []ai.ToolDefinition {
+	if len(offered) == 0 {
+		return nil
+	}
+	defs := make([]ai.ToolDefinition, 0, len(offered))
+	add := func(name string) {
+		t := c.Tools[name]
+		var params []byte
+		if len(t.Parameters) > 0 {
+			params = t.Parameters
+		}
+		defs = append(defs, ai.ToolDefinition{Name: name, Description: t.Description, Parameters: params})
+	}
+	listed := make(map[string]bool, len(c.ToolOrder))
+	for _, name := range c.ToolOrder {
+		if _, ok := offered[name]; ok && !listed[name] {
+			listed[name] = true
+			add(name)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(offered)) {
+		if !listed[name] {
+			add(name)
+		}
+	}
+	return defs
+}
+
+// offeredTools returns the set of tools to offer on step, consulting
+// SelectTools when it is set.
+func (c *Config) offeredTools(ctx context.Context, step int, messages []ai.Message) (map[string]struct{}, error) {
+	if c.SelectTools == nil {
+		offered := make(map[string]struct{}, len(c.Tools))
+		for name := range c.Tools {
+			offered[name] = struct{}{}
+		}
+		return offered, nil
+	}
+	names, err := c.SelectTools(ctx, step, messages)
+	if err != nil {
+		return nil, err
+	}
+	expanded, err := ExpandToolNames(c.Tools, names)
+	if err != nil {
+		var argErr *ai.InvalidArgumentError
+		if errors.As(err, &argErr) {
+			return nil, &ai.InvalidArgumentError{Parameter: "SelectTools", Value: names, Message: fmt.Sprintf("step %d: %s", step, argErr.Message)}
+		}
+		return nil, err
+	}
+	offered := make(map[string]struct{}, len(expanded))
+	for _, name := range expanded {
+		offered[name] = struct{}{}
+	}
+	return offered, nil
+}
@@ -0,0 +1,174 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	ai "github.com/ncecere/ai-sdk"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// toolNamesModel wraps echoToolModel and records the tool names sent
+// with each request.
+type toolNamesModel struct {
+	echoToolModel
+	offered [][]string
+}
+
+func (m *toolNamesModel) Generate(ctx context.Context, req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
+	var names []string
+	for _, t := range req.Tools {
+		names = append(names, t.Name)
+	}
+	slices.Sort(names)
+	m.offered = append(m.offered, names)
+	return m.echoToolModel.Generate(ctx, req)
+}
+
+func newSelectConfig(model provider.LanguageModel) Config {
+	execute := searchTool().Execute
+	return testConfig(model, map[string]Tool{
+		"search":      {Name: "search", Group: "search", Execute: execute},
+		"search_docs": {Name: "search_docs", Group: "search", Execute: execute},
+		"send_email":  {Name: "send_email", Group: "mail", Execute: execute},
+	})
+}
+
+func TestExpandToolNames(t *testing.T) {
+	tools := newSelectConfig(echoToolModel{}).Tools
+	got, err := ExpandToolNames(tools, []string{"send_email", "search.*", "search"})
+	if err != nil {
+		t.Fatalf("ExpandToolNames error: %v", err)
+	}
+	if want := []string{"send_email", "search", "search_docs"}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, _ := ExpandToolNames(tools, []string{"*"}); len(got) != 3 {
+		t.Fatalf("expected * to match every tool, got %v", got)
+	}
+
+	var argErr *ai.InvalidArgumentError
+	for _, names := range [][]string{{"serch"}, {"calendar.*"}} {
+		if _, err := ExpandToolNames(tools, names); !errors.As(err, &argErr) {
+			t.Fatalf("%v: expected InvalidArgumentError, got %v", names, err)
+		}
+	}
+}
+
+func TestRun_SelectToolsPerStep(t *testing.T) {
+	model := &toolNamesModel{}
+	cfg := newSelectConfig(model)
+	var steps []int
+	cfg.SelectTools = func(ctx context.Context, step int, messages []ai.Message) ([]string, error) {
+		steps = append(steps, step)
+		if step == 0 {
+			return []string{"search.*"}, nil
+		}
+		return nil, nil
+	}
+
+	res, err := Run(context.Background(), cfg, []ai.Message{ai.UserMessage("find x")})
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if !slices.Equal(steps, []int{0, 1}) {
+		t.Fatalf("SelectTools called for steps %v", steps)
+	}
+	if len(model.offered) != 2 || !slices.Equal(model.offered[0], []string{"search", "search_docs"}) || len(model.offered[1]) != 0 {
+		t.Fatalf("unexpected offered tools %v", model.offered)
+	}
+	if res.StepDetails[0].OfferedTools != 2 || res.StepDetails[1].OfferedTools != 0 {
+		t.Fatalf("unexpected step details %+v", res.StepDetails)
+	}
+}
+
+func TestRun_UnlistedToolCalls(t *testing.T) {
+	cfg := newSelectConfig(echoToolModel{})
+	cfg.SelectTools = func(ctx context.Context, step int, messages []ai.Message) ([]string, error) {
+		return []string{"mail.*"}, nil
+	}
+
+	_, err := Run(context.Background(), cfg, []ai.Message{ai.UserMessage("find x")})
+	var unsupported *ai.UnsupportedFunctionalityError
+	if !errors.As(err, &unsupported) || !strings.Contains(err.Error(), `tool "search" was not offered on step 0`) {
+		t.Fatalf("expected an error for the unlisted call, got %v", err)
+	}
+
+	cfg.AllowUnlistedCalls = true
+	res, err := Run(context.Background(), cfg, []ai.Message{ai.UserMessage("find x")})
+	if err != nil || !strings.Contains(res.FinalText, "nothing found") {
+		t.Fatalf("expected the unlisted call to run, got %+v, %v", res, err)
+	}
+
+	cfg.SelectTools = func(ctx context.Context, step int, messages []ai.Message) ([]string, error) {
+		return []string{"serch"}, nil
+	}
+	var argErr *ai.InvalidArgumentError
+	if _, err := Run(context.Background(), cfg, []ai.Message{ai.UserMessage("find x")}); !errors.As(err, &argErr) || argErr.Parameter != "SelectTools" {
+		t.Fatalf("expected InvalidArgumentError for an unknown selected tool, got %v", err)
+	}
+}
+
+// toolsJSONModel wraps echoToolModel and records the tool definitions
+// sent with each request as JSON.
+type toolsJSONModel struct {
+	echoToolModel
+	tools [][]byte
+}
+
+func (m *toolsJSONModel) Generate(ctx context.Context, req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
+	data, err := json.Marshal(req.Tools)
+	if err != nil {
+		return nil, err
+	}
+	m.tools = append(m.tools, data)
+	return m.echoToolModel.Generate(ctx, req)
+}
+
+func TestRun_ToolDefinitionsAreDeterministic(t *testing.T) {
+	model := &toolsJSONModel{}
+	cfg := newSelectConfig(model)
+	// Enough tools that map iteration order would differ between runs.
+	for _, name := range strings.Fields("alpha bravo charlie delta echo foxtrot golf hotel india juliet") {
+		cfg.Tools[name] = Tool{Name: name, Description: "tool " + name, Parameters: json.RawMessage(`{"type":"object"}`)}
+	}
+
+	for range 2 {
+		if _, err := Run(context.Background(), cfg, []ai.Message{ai.UserMessage("find x")}); err != nil {
+			t.Fatalf("Run error: %v", err)
+		}
+	}
+	if len(model.tools) != 4 {
+		t.Fatalf("expected 4 model calls, got %d", len(model.tools))
+	}
+	for i, tools := range model.tools[1:] {
+		if string(tools) != string(model.tools[0]) {
+			t.Fatalf("call %d sent different tools:\n%s\nvs\n%s", i+1, tools, model.tools[0])
+		}
+	}
+	var defs []provider.ToolDefinition
+	json.Unmarshal(model.tools[0], &defs)
+	if !slices.IsSortedFunc(defs, func(a, b provider.ToolDefinition) int { return strings.Compare(a.Name, b.Name) }) {
+		t.Fatalf("tools not sorted by name: %s", model.tools[0])
+	}
+
+	model.tools = nil
+	cfg.ToolOrder = []string{"search", "juliet"}
+	if _, err := Run(context.Background(), cfg, []ai.Message{ai.UserMessage("find x")}); err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	json.Unmarshal(model.tools[0], &defs)
+	if defs[0].Name != "search" || defs[1].Name != "juliet" || defs[2].Name != "alpha" {
+		t.Fatalf("ToolOrder not applied: %s", model.tools[0])
+	}
+
+	cfg.ToolOrder = []string{"serch"}
+	var argErr *ai.InvalidArgumentError
+	if _, err := Run(context.Background(), cfg, []ai.Message{ai.UserMessage("find x")}); !errors.As(err, &argErr) || argErr.Parameter != "ToolOrder" {
+		t.Fatalf("expected InvalidArgumentError for an unknown ToolOrder name, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	ai "github.com/ncecere/ai-sdk"
+)
+
+func TestNewTool_DecodesStrictly(t *testing.T) {
+	type args struct {
+		OrderID int64 `json:"order_id"`
+	}
+
+	var got int64
+	tool, err := NewTool("order", "Look up an order.", func(ctx context.Context, a args) (any, error) {
+		got = a.OrderID
+		return "ok", nil
+	})
+	if err != nil {
+		t.Fatalf("NewTool error: %v", err)
+	}
+	if len(tool.Parameters) == 0 {
+		t.Fatalf("expected inferred parameters schema")
+	}
+
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"order_id":1234567890123456789}`)); err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	if got != 1234567890123456789 {
+		t.Fatalf("expected exact order ID, got %d", got)
+	}
+
+	var unknown *ai.UnknownFieldsError
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"order_id":1,"customer":"x"}`)); !errors.As(err, &unknown) || unknown.Tool != "order" {
+		t.Fatalf("expected UnknownFieldsError for tool order, got %v", err)
+	}
+}
+
+func TestNewTool_CarriesSchemaExamples(t *testing.T) {
+	type args struct {
+		Query string `json:"query" example:"open invoices"`
+	}
+	tool, err := NewTool("search", "Search records.", func(ctx context.Context, a args) (any, error) {
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("NewTool error: %v", err)
+	}
+	tool, err = tool.WithExamples(args{Query: "orders from May"})
+	if err != nil {
+		t.Fatalf("WithExamples error: %v", err)
+	}
+
+	var schema struct {
+		Examples   []args `json:"examples"`
+		Properties struct {
+			Query struct {
+				Examples []string `json:"examples"`
+			} `json:"query"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(tool.Parameters, &schema); err != nil {
+		t.Fatalf("decode schema: %v", err)
+	}
+	if len(schema.Examples) != 1 || schema.Examples[0].Query != "orders from May" {
+		t.Fatalf("unexpected tool examples %+v", schema.Examples)
+	}
+	if q := schema.Properties.Query.Examples; len(q) != 1 || q[0] != "open invoices" {
+		t.Fatalf("unexpected parameter examples %v", q)
+	}
+}
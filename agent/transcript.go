@@ -0,0 +1,299 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	ai "github.com/ncecere/ai-sdk"
+)
+
+// TranscriptFormat selects the output format of RenderTranscript.
+type TranscriptFormat string
+
+const (
+	// TranscriptMarkdown renders a human-readable Markdown document.
+	TranscriptMarkdown TranscriptFormat = "markdown"
+	// TranscriptJSON renders an indented JSON document.
+	TranscriptJSON TranscriptFormat = "json"
+)
+
+// DefaultTranscriptPayloadBytes is the default limit applied to each
+// tool argument and result payload in a transcript.
+const DefaultTranscriptPayloadBytes = 4096
+
+// TranscriptOptions configures RenderTranscript.
+type TranscriptOptions struct {
+	// Format is the output format. Empty means TranscriptMarkdown.
+	Format TranscriptFormat
+	// IncludeToolPayloads includes tool call arguments and results.
+	// When false only tool names, IDs, and timings are rendered.
+	IncludeToolPayloads bool
+	// Redactor, if set, is applied to every piece of message text and
+	// tool payload before it is rendered, for example to mask secrets
+	// or personal data.
+	Redactor func(string) string
+	// MaxPayloadBytes limits each tool argument and result payload.
+	// Longer payloads are cut at a UTF-8 boundary and marked with the
+	// number of bytes removed. Zero means DefaultTranscriptPayloadBytes;
+	// a negative value disables truncation.
+	MaxPayloadBytes int
+}
+
+// RenderTranscript renders the run recorded in result as a shareable
+// document: the input conversation, each step's assistant text, tool
+// calls with their arguments and results, timings, and the final answer.
+//
+// Output is deterministic for a given result: steps and tool calls are
+// rendered in the order they happened, timestamps are rendered in UTC
+// when recorded, and durations are rounded to milliseconds. Results
+// without StepDetails (such as ones built by hand) are rendered from
+// Messages alone.
+//
+// Errors:
+//   - *ai.InvalidArgumentError if result is nil or Format is unknown.
+func RenderTranscript(result *Result, opts TranscriptOptions) ([]byte, error) {
+	if result == nil {
+		return nil, &ai.InvalidArgumentError{Parameter: "result", Message: "result must not be nil"}
+	}
+	doc := newTranscript(result, opts)
+	switch opts.Format {
+	case "", TranscriptMarkdown:
+		return doc.markdown(), nil
+	case TranscriptJSON:
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	default:
+		return nil, &ai.InvalidArgumentError{Parameter: "Format", Value: opts.Format, Message: "unknown transcript format"}
+	}
+}
+
+// transcript is the format-independent form of a rendered run. Its JSON
+// encoding is the TranscriptJSON format.
+type transcript struct {
+	TraceID   string              `json:"trace_id,omitempty"`
+	Steps     int                 `json:"steps"`
+	Truncated bool                `json:"truncated,omitempty"`
+	Input     []transcriptMessage `json:"input"`
+	StepList  []transcriptStep    `json:"step_details"`
+	Final     string              `json:"final_answer"`
+}
+
+type transcriptMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type transcriptStep struct {
+	Step      int                  `json:"step"`
+	Started   string               `json:"started,omitempty"`
+	ModelMS   int64                `json:"model_ms"`
+	Text      string               `json:"text,omitempty"`
+	ToolCalls []transcriptToolCall `json:"tool_calls,omitempty"`
+}
+
+type transcriptToolCall struct {
+	ID         string `json:"id"`
+	Tool       string `json:"tool"`
+	Denied     bool   `json:"denied,omitempty"`
+	Started    string `json:"started,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+	Arguments  string `json:"arguments,omitempty"`
+	Result     string `json:"result,omitempty"`
+}
+
+func newTranscript(result *Result, opts TranscriptOptions) *transcript {
+	redact := opts.Redactor
+	if redact == nil {
+		redact = func(s string) string { return s }
+	}
+	limit := opts.MaxPayloadBytes
+	if limit == 0 {
+		limit = DefaultTranscriptPayloadBytes
+	}
+	payload := func(s string) string { return truncatePayload(redact(s), limit) }
+
+	doc := &transcript{
+		TraceID:   result.TraceID,
+		Steps:     result.Steps,
+		Truncated: result.Truncated,
+		Input:     []transcriptMessage{},
+		StepList:  []transcriptStep{},
+		Final:     redact(result.FinalText),
+	}
+
+	input := result.Messages
+	if len(result.StepDetails) > 0 {
+		// The run appended one assistant message per step with text or
+		// tool calls and one tool message per tool call; everything
+		// before those is the conversation the run started from.
+		produced := 0
+		for _, s := range result.StepDetails {
+			if s.Text != "" || len(s.ToolCalls) > 0 {
+				produced++
+			}
+			produced += len(s.ToolCalls)
+		}
+		input = input[:max(len(input)-produced, 0)]
+	}
+	for _, m := range input {
+		doc.Input = append(doc.Input, transcriptMessage{Role: m.Role, Content: redact(messageText(m))})
+	}
+
+	for _, s := range result.StepDetails {
+		step := transcriptStep{
+			Step:    s.Step,
+			Started: formatTimestamp(s.Started),
+			ModelMS: s.ModelDuration.Round(time.Millisecond).Milliseconds(),
+			Text:    redact(s.Text),
+		}
+		for _, c := range s.ToolCalls {
+			call := transcriptToolCall{
+				ID:         c.ID,
+				Tool:       c.Tool,
+				Denied:     c.Denied,
+				Started:    formatTimestamp(c.Started),
+				DurationMS: c.Duration.Round(time.Millisecond).Milliseconds(),
+			}
+			if opts.IncludeToolPayloads {
+				call.Arguments = payload(string(c.Arguments))
+				call.Result = payload(c.Result)
+			}
+			step.ToolCalls = append(step.ToolCalls, call)
+		}
+		doc.StepList = append(doc.StepList, step)
+	}
+
+	// Without step details the run's own messages are part of input;
+	// collapse tool payloads there the same way.
+	if len(result.StepDetails) == 0 {
+		for i, m := range input {
+			if m.Role != ai.RoleTool {
+				continue
+			}
+			if opts.IncludeToolPayloads {
+				doc.Input[i].Content = payload(messageText(m))
+			} else {
+				doc.Input[i].Content = "[tool result omitted]"
+			}
+		}
+	}
+	return doc
+}
+
+func (t *transcript) markdown() []byte {
+	var b strings.Builder
+	b.WriteString("# Agent transcript\n\n")
+	if t.TraceID != "" {
+		fmt.Fprintf(&b, "- Trace ID: `%s`\n", t.TraceID)
+	}
+	fmt.Fprintf(&b, "- Steps: %d\n", t.Steps)
+	if t.Truncated {
+		b.WriteString("- Truncated: the step limit was reached before the model finished\n")
+	}
+
+	if len(t.Input) > 0 {
+		b.WriteString("\n## Conversation\n")
+		for _, m := range t.Input {
+			fmt.Fprintf(&b, "\n### %s\n\n%s\n", roleTitle(m.Role), m.Content)
+		}
+	}
+
+	for _, s := range t.StepList {
+		fmt.Fprintf(&b, "\n## Step %d\n\n", s.Step+1)
+		if s.Started != "" {
+			fmt.Fprintf(&b, "- Started: %s\n", s.Started)
+		}
+		fmt.Fprintf(&b, "- Model time: %dms\n", s.ModelMS)
+		if s.Text != "" {
+			fmt.Fprintf(&b, "\n### Assistant\n\n%s\n", s.Text)
+		}
+		for _, c := range s.ToolCalls {
+			fmt.Fprintf(&b, "\n### Tool call `%s` (`%s`)\n\n", c.Tool, c.ID)
+			if c.Started != "" {
+				fmt.Fprintf(&b, "- Started: %s\n", c.Started)
+			}
+			fmt.Fprintf(&b, "- Duration: %dms\n", c.DurationMS)
+			if c.Denied {
+				b.WriteString("- Denied by the approver\n")
+			}
+			if c.Arguments != "" {
+				fmt.Fprintf(&b, "\nArguments:\n\n%s", fence(c.Arguments))
+			}
+			if c.Result != "" {
+				fmt.Fprintf(&b, "\nResult:\n\n%s", fence(c.Result))
+			}
+		}
+	}
+
+	b.WriteString("\n## Final answer\n\n")
+	if t.Final != "" {
+		b.WriteString(t.Final + "\n")
+	} else {
+		b.WriteString("_No final answer._\n")
+	}
+	return []byte(b.String())
+}
+
+// messageText renders m as text, with image parts as placeholders.
+func messageText(m ai.Message) string {
+	if len(m.Parts) == 0 {
+		return m.Content
+	}
+	var parts []string
+	for _, p := range m.Parts {
+		switch p.Type {
+		case ai.ContentPartText:
+			parts = append(parts, p.Text)
+		case ai.ContentPartImage:
+			parts = append(parts, "[image]")
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func roleTitle(role string) string {
+	if role == "" {
+		return "Unknown"
+	}
+	return strings.ToUpper(role[:1]) + role[1:]
+}
+
+// fence wraps s in a Markdown code block, using a fence longer than any
+// backtick run inside s.
+func fence(s string) string {
+	ticks := "```"
+	for strings.Contains(s, ticks) {
+		ticks += "`"
+	}
+	lang := ""
+	if json.Valid([]byte(s)) {
+		lang = "json"
+	}
+	return ticks + lang + "\n" + s + "\n" + ticks + "\n"
+}
+
+func formatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// truncatePayload cuts s to at most limit bytes at a UTF-8 boundary and
+// appends a marker with the number of bytes removed.
+func truncatePayload(s string, limit int) string {
+	if limit < 0 || len(s) <= limit {
+		return s
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s… [truncated %d bytes]", s[:cut], len(s)-cut)
+}
@@ -0,0 +1,168 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	ai "github.com/ncecere/ai-sdk"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// transcriptResult is a hand-built run with fixed timings: a search
+// whose result is too long, a denied delete, and a final answer.
+func transcriptResult() *Result {
+	start := time.Date(2025, 3, 1, 9, 30, 0, 0, time.FixedZone("CET", 3600))
+	return &Result{
+		Messages: []ai.Message{
+			ai.SystemMessage("You are a file assistant."),
+			ai.UserMessage("Find the report and delete the draft. My token is sk-secret."),
+			ai.AssistantMessage("Let me look."),
+			{Role: ai.RoleTool, Content: `{"result":"…","tool":"search","tool_call_id":"call_a"}`},
+			{Role: ai.RoleTool, Content: `{"result":{"error":"the user denied this tool call"},"tool":"delete","tool_call_id":"call_b"}`},
+			ai.AssistantMessage("The report is report.pdf; I did not delete the draft."),
+		},
+		FinalText: "The report is report.pdf; I did not delete the draft.",
+		Steps:     1,
+		TraceID:   "trace-123",
+		StepDetails: []StepDetail{
+			{
+				Step:          0,
+				Started:       start,
+				ModelDuration: 812345 * time.Microsecond,
+				Text:          "Let me look.",
+				ToolCalls: []ToolCallDetail{
+					{
+						ID:        "call_a",
+						Tool:      "search",
+						Arguments: json.RawMessage(`{"query":"report"}`),
+						Result:    `{"result":"` + strings.Repeat("é", 40) + `","tool":"search","tool_call_id":"call_a"}`,
+						Started:   start.Add(time.Second),
+						Duration:  1500 * time.Microsecond,
+					},
+					{
+						ID:        "call_b",
+						Tool:      "delete",
+						Arguments: json.RawMessage(`{"path":"draft.txt"}`),
+						Result:    `{"result":{"error":"the user denied this tool call"},"tool":"delete","tool_call_id":"call_b"}`,
+						Denied:    true,
+						Started:   start.Add(2 * time.Second),
+						Duration:  3 * time.Second,
+					},
+				},
+			},
+			{
+				Step:          1,
+				Started:       start.Add(5 * time.Second),
+				ModelDuration: 400 * time.Millisecond,
+				Text:          "The report is report.pdf; I did not delete the draft.",
+			},
+		},
+	}
+}
+
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("write golden: %v", err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("%s mismatch (run with -update to accept):\n%s", name, got)
+	}
+}
+
+func TestRenderTranscript_Golden(t *testing.T) {
+	opts := TranscriptOptions{
+		IncludeToolPayloads: true,
+		Redactor:            func(s string) string { return strings.ReplaceAll(s, "sk-secret", "[redacted]") },
+		MaxPayloadBytes:     64,
+	}
+	for _, tc := range []struct {
+		format TranscriptFormat
+		golden string
+	}{
+		{TranscriptMarkdown, "transcript.md"},
+		{TranscriptJSON, "transcript.json"},
+	} {
+		t.Run(string(tc.format), func(t *testing.T) {
+			opts.Format = tc.format
+			got, err := RenderTranscript(transcriptResult(), opts)
+			if err != nil {
+				t.Fatalf("RenderTranscript error: %v", err)
+			}
+			checkGolden(t, tc.golden, got)
+			again, _ := RenderTranscript(transcriptResult(), opts)
+			if !bytes.Equal(got, again) {
+				t.Fatalf("output is not deterministic")
+			}
+		})
+	}
+}
+
+func TestRenderTranscript_OmitsPayloads(t *testing.T) {
+	got, err := RenderTranscript(transcriptResult(), TranscriptOptions{})
+	if err != nil {
+		t.Fatalf("RenderTranscript error: %v", err)
+	}
+	if strings.Contains(string(got), "draft.txt") || strings.Contains(string(got), "Arguments:") {
+		t.Fatalf("tool payloads should be omitted:\n%s", got)
+	}
+	if !strings.Contains(string(got), "### Tool call `delete` (`call_b`)") {
+		t.Fatalf("tool calls should still be listed:\n%s", got)
+	}
+}
+
+func TestRenderTranscript_Errors(t *testing.T) {
+	var invalid *ai.InvalidArgumentError
+	if _, err := RenderTranscript(nil, TranscriptOptions{}); !errors.As(err, &invalid) {
+		t.Fatalf("expected InvalidArgumentError for a nil result, got %v", err)
+	}
+	if _, err := RenderTranscript(&Result{}, TranscriptOptions{Format: "html"}); !errors.As(err, &invalid) || invalid.Parameter != "Format" {
+		t.Fatalf("expected InvalidArgumentError on Format, got %v", err)
+	}
+}
+
+func TestRun_RecordsStepDetails(t *testing.T) {
+	cfg := testConfig(echoToolModel{}, nil)
+	res, err := Run(context.Background(), cfg, []ai.Message{ai.UserMessage("find x")})
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if len(res.StepDetails) != 2 {
+		t.Fatalf("expected 2 step details, got %+v", res.StepDetails)
+	}
+	first := res.StepDetails[0]
+	if first.Started.IsZero() || len(first.ToolCalls) != 1 {
+		t.Fatalf("unexpected first step %+v", first)
+	}
+	call := first.ToolCalls[0]
+	if call.ID != "call_1" || call.Tool != "search" || string(call.Arguments) != `{"q":"x"}` || !strings.Contains(call.Result, "nothing found") {
+		t.Fatalf("unexpected tool call detail %+v", call)
+	}
+	if last := res.StepDetails[1]; last.Step != 1 || last.Text != res.FinalText {
+		t.Fatalf("unexpected last step %+v", last)
+	}
+
+	got, err := RenderTranscript(res, TranscriptOptions{IncludeToolPayloads: true})
+	if err != nil {
+		t.Fatalf("RenderTranscript error: %v", err)
+	}
+	if !strings.Contains(string(got), "## Conversation\n\n### User\n\nfind x\n\n## Step 1") {
+		t.Fatalf("expected only the user prompt as input:\n%s", got)
+	}
+}
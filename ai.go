@@ -2,6 +2,7 @@ package ai
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/ncecere/ai-sdk/provider"
 	"github.com/ncecere/ai-sdk/registry"
@@ -25,6 +26,12 @@ type (
 	ToolDefinition = provider.ToolDefinition
 	// ToolCall represents a tool invocation emitted by the model.
 	ToolCall = provider.ToolCall
+	// ToolCallDelta is a fragment of a streamed tool call.
+	ToolCallDelta = provider.ToolCallDelta
+	// ContentPart is a single part (text or image) of a multi-part message.
+	ContentPart = provider.ContentPart
+	// ContentPartType identifies the kind of a ContentPart.
+	ContentPartType = provider.ContentPartType
 
 	// LanguageModel is a provider-agnostic chat-oriented model.
 	LanguageModel = provider.LanguageModel
@@ -38,20 +45,178 @@ type (
 	SpeechModel = provider.SpeechModel
 	// TranscriptionModel is a provider-agnostic speech-to-text model.
 	TranscriptionModel = provider.TranscriptionModel
+	// TranscriptionSegment is a timestamped span of a transcription.
+	TranscriptionSegment = provider.TranscriptionSegment
 	// RerankModel is a provider-agnostic rerank model.
 	RerankModel = provider.RerankModel
 
 	// Image is a generated image returned by image models.
 	Image = provider.Image
+	// ImageCapabilities describes the sizes and image counts an image
+	// model accepts.
+	ImageCapabilities = provider.ImageCapabilities
 	// RerankResult is a single scored document returned by rerank models.
 	RerankResult = provider.RerankResult
 
+	// APIError is returned for non-2xx provider responses, with the
+	// provider's error type, code, message, and offending parameter.
+	APIError = provider.APIError
+	// IncompleteStreamError is returned by a TextStream whose
+	// connection ended before the provider's completion marker.
+	IncompleteStreamError = provider.IncompleteStreamError
+	// ResponseTooLargeError is returned when a response body exceeds
+	// ClientOptions.MaxResponseBytes.
+	ResponseTooLargeError = provider.ResponseTooLargeError
+	// StreamTruncatedError is returned by a TextStream that exceeds
+	// ClientOptions.MaxStreamBytes or MaxStreamDeltas.
+	StreamTruncatedError = provider.StreamTruncatedError
+	// EmptyResponseError is returned when a provider responds with a 2xx
+	// status but without any result in the body.
+	EmptyResponseError = provider.EmptyResponseError
+	// InvalidMetadataError is returned when request metadata exceeds
+	// the provider's limits.
+	InvalidMetadataError = provider.InvalidMetadataError
+	// RemoteImageError is returned when an image referenced by URL
+	// cannot be inlined for ClientOptions.FetchRemoteImages.
+	RemoteImageError = provider.RemoteImageError
+
 	// TextDelta is a single streamed text update.
 	TextDelta = provider.LanguageModelDelta
 	// TextStream is an iterator-style stream of text deltas.
 	TextStream = provider.LanguageModelStream
+
+	// ImageDelta is a single streamed image update.
+	ImageDelta = provider.ImageDelta
+	// PartialImage is an in-progress preview emitted while streaming.
+	PartialImage = provider.PartialImage
+	// ImageStream is an iterator-style stream of image deltas.
+	ImageStream = provider.ImageModelStream
+
+	// ToolChoice controls whether and which tools a model calls.
+	ToolChoice = provider.ToolChoice
+	// ResponseFormat selects the output format of a text generation.
+	ResponseFormat = provider.ResponseFormat
+	// OrphanToolPolicy controls how orphaned tool messages are handled.
+	OrphanToolPolicy = provider.OrphanToolPolicy
+	// ReasoningOptions requests and limits model reasoning output.
+	ReasoningOptions = provider.ReasoningOptions
+	// ReasoningBlock is a signed block of reasoning output.
+	ReasoningBlock = provider.ReasoningBlock
+	// SystemOnlyPolicy controls how requests without a user or
+	// assistant message are handled.
+	SystemOnlyPolicy = provider.SystemOnlyPolicy
+	// NoConversationMessageError is returned when a request contains
+	// only system messages and SystemOnlyError is in effect.
+	NoConversationMessageError = provider.NoConversationMessageError
+	// OrphanToolMessageError is returned when a request contains a tool
+	// message without tool-call context and OrphanToolError is in effect.
+	OrphanToolMessageError = provider.OrphanToolMessageError
+	// ToolDiagnostics describes how a provider handled the tools sent
+	// with a request that produced no tool calls.
+	ToolDiagnostics = provider.ToolDiagnostics
+	// AudioFormat names the audio container or codec of generated speech.
+	AudioFormat = provider.AudioFormat
+	// EmbeddingDType is the numeric type of embedding vectors.
+	EmbeddingDType = provider.EmbeddingDType
+	// SpeechCapabilities describes the output formats a speech model
+	// supports.
+	SpeechCapabilities = provider.SpeechCapabilities
+	// Citation attributes a span of response text to a source.
+	Citation = provider.Citation
+	// SafetyRating is a provider's assessment of one harm category.
+	SafetyRating = provider.SafetyRating
+	// Usage reports the tokens consumed by a language-model call.
+	Usage = provider.Usage
+	// CacheUsage reports prompt-cache activity within a Usage.
+	CacheUsage = provider.CacheUsage
+	// ResponseMetadata describes how a response was produced.
+	ResponseMetadata = provider.ResponseMetadata
+	// RetryStats summarizes the attempts of a retried call.
+	RetryStats = provider.RetryStats
+	// TokenLogprob is the log probability of one generated token.
+	TokenLogprob = provider.TokenLogprob
+	// TokenAlternative is a candidate token and its log probability.
+	TokenAlternative = provider.TokenAlternative
+	// WebSearchOptions configures the provider's server-side web search.
+	WebSearchOptions = provider.WebSearchOptions
+	// FinishReason is the provider-independent reason a model stopped.
+	FinishReason = provider.FinishReason
+	// ContentBlockedError is returned when a provider's safety filters
+	// block the prompt or response.
+	ContentBlockedError = provider.ContentBlockedError
+	// UnsupportedFunctionalityError indicates that a requested feature
+	// is not supported.
+	UnsupportedFunctionalityError = provider.UnsupportedFunctionalityError
+	// DroppedOption is a request option a provider could not honor.
+	DroppedOption = provider.DroppedOption
+)
+
+// Content part types for multi-part messages.
+const (
+	ContentPartText  = provider.ContentPartText
+	ContentPartImage = provider.ContentPartImage
+)
+
+// Orphan tool message policies.
+const (
+	OrphanToolDowngrade = provider.OrphanToolDowngrade
+	OrphanToolError     = provider.OrphanToolError
 )
 
+// Audio formats for SpeechRequest.Format.
+const (
+	FormatMP3  = provider.FormatMP3
+	FormatWAV  = provider.FormatWAV
+	FormatOpus = provider.FormatOpus
+	FormatAAC  = provider.FormatAAC
+	FormatFLAC = provider.FormatFLAC
+	FormatPCM  = provider.FormatPCM
+)
+
+// Embedding types for EmbeddingRequest.OutputDType.
+const (
+	EmbeddingFloat32 = provider.EmbeddingFloat32
+	EmbeddingFloat64 = provider.EmbeddingFloat64
+	EmbeddingInt8    = provider.EmbeddingInt8
+	EmbeddingBinary  = provider.EmbeddingBinary
+)
+
+// MetadataUserID is the request metadata key for an end-user ID. It is
+// the only key Anthropic accepts.
+const MetadataUserID = provider.MetadataUserID
+
+// System-only request policies.
+const (
+	SystemOnlyError      = provider.SystemOnlyError
+	SystemOnlyInsertUser = provider.SystemOnlyInsertUser
+)
+
+// Tool choices for GenerateTextRequest.ToolChoice; see also
+// ToolChoiceTool.
+const (
+	ToolChoiceAuto     = provider.ToolChoiceAuto
+	ToolChoiceNone     = provider.ToolChoiceNone
+	ToolChoiceRequired = provider.ToolChoiceRequired
+)
+
+// Finish reasons for GenerateTextResponse.FinishReason.
+const (
+	FinishReasonStop          = provider.FinishReasonStop
+	FinishReasonLength        = provider.FinishReasonLength
+	FinishReasonToolCalls     = provider.FinishReasonToolCalls
+	FinishReasonContentFilter = provider.FinishReasonContentFilter
+	FinishReasonOther         = provider.FinishReasonOther
+)
+
+// ResponseFormatJSON requests JSON mode without a schema; see
+// GenerateTextRequest.ResponseFormat.
+const ResponseFormatJSON = provider.ResponseFormatJSON
+
+// ToolChoiceTool makes the model call the named tool.
+func ToolChoiceTool(name string) ToolChoice {
+	return provider.ToolChoiceTool(name)
+}
+
 // Tool calling pattern
 //
 // A typical tool-calling loop with this package looks like:
@@ -59,15 +224,21 @@ type (
 //  1. Define tools with JSON schemas using ToolDefinition and, if desired,
 //     JSONSchemaFromType to build the schema from a Go struct.
 //  2. Call GenerateText or StreamText with Tools populated.
-//  3. Inspect the ToolCalls field on the response (or TextDelta) and for
-//     each ToolCall:
+//  3. Inspect the ToolCalls field on the response (or the final
+//     TextDelta). A response may hold text as well as tool calls; if
+//     ToolCalls is non-empty the turn is not finished, whatever the
+//     text says. For each ToolCall:
 //     - Decode ToolCall.RawArguments into a Go struct.
 //     - Execute the corresponding tool in your application.
 //     - Append a new Message with RoleTool whose Content contains the
-//       JSON-encoded tool result and whose name is represented in the
-//       tool metadata (e.g., in the arguments you pass back to the model).
+//       JSON-encoded tool result and whose ToolCallID is the ToolCall's
+//       ID; NewToolResultMessage builds such a message.
 //  4. Call GenerateText again with the extended Messages slice to let the
-//     model continue the conversation with tool results included.
+//     model continue the conversation with tool results included. The
+//     assistant turn goes ahead of the tool messages and must carry the
+//     tool calls; AssistantToolCallMessage(res.Text, res.ToolCalls)
+//     builds it. With reasoning enabled, also set its ReasoningBlocks
+//     to res.ReasoningBlocks.
 //
 // This mirrors the OpenAI function and tool-calling semantics while
 // keeping execution of the tools firmly in your own Go code.
@@ -76,6 +247,10 @@ type (
 type GenerateTextRequest struct {
 	// Model is the language model used to generate the response.
 	Model LanguageModel
+	// System, if non-empty, is sent as a system message ahead of
+	// Messages. Providers with a single system prompt (such as
+	// Anthropic) join it with any system messages in Messages, in order.
+	System string
 	// Messages is the ordered chat history passed to the model.
 	Messages []Message
 	// Temperature controls randomness of the output.
@@ -86,20 +261,237 @@ type GenerateTextRequest struct {
 	MaxTokens *int
 	// Stop contains stop sequences that will truncate the output.
 	Stop []string
+	// FrequencyPenalty, between -2 and 2, penalizes tokens by how often
+	// they already appeared. Providers without penalties (such as
+	// Anthropic) ignore it.
+	FrequencyPenalty *float64
+	// PresencePenalty, between -2 and 2, penalizes tokens that already
+	// appeared at all. Providers without penalties ignore it.
+	PresencePenalty *float64
+	// Logprobs requests per-token log probabilities, returned in
+	// GenerateTextResponse.Logprobs. TopLogprobs also requests that many
+	// alternatives per token and implies Logprobs. Streams do not report
+	// them, and providers without logprobs (such as Anthropic) ignore
+	// both.
+	Logprobs    *bool
+	TopLogprobs *int
 	// JSONSchema, if set, requests a structured JSON response from the model.
 	JSONSchema []byte
+	// JSONSchemaName names JSONSchema for providers that require a name,
+	// such as OpenAI's json_schema.name: up to 64 letters, digits,
+	// underscores, or dashes. Empty means "response".
+	JSONSchemaName string
+	// JSONSchemaStrict requests strict structured outputs (OpenAI's
+	// "strict": true). JSONSchema is rewritten with StrictJSONSchema
+	// first; a schema that is not a JSON object is sent unchanged.
+	// Providers without a strict mode ignore it.
+	JSONSchemaStrict bool
+	// ResponseFormat, if ResponseFormatJSON, requests plain JSON mode
+	// (OpenAI response_format json_object) instead of a json_schema
+	// response, for backends that reject the schema variant. The
+	// messages must ask for JSON. Empty uses JSONSchema as above.
+	ResponseFormat ResponseFormat
 	// Tools defines tools the model may call during generation.
 	Tools []ToolDefinition
+	// ToolChoice controls whether and which of Tools the model calls:
+	// ToolChoiceAuto, ToolChoiceNone, ToolChoiceRequired, or
+	// ToolChoiceTool(name) for a named tool. Empty leaves the provider
+	// default. It is ignored when Tools is empty.
+	ToolChoice ToolChoice
+	// ParallelToolCalls, if set, allows or forbids several tool calls
+	// in one response, for tools with ordering dependencies. Nil leaves
+	// the provider default. It is ignored when Tools is empty and by
+	// providers without the flag.
+	ParallelToolCalls *bool
+	// LogitBias maps token IDs (as decimal strings) to a bias between
+	// -100 and 100. See LogitBiasFor for building it from strings.
+	// Providers without logit bias support (such as Anthropic) ignore it.
+	LogitBias map[string]float64
+	// Betas lists additional provider beta features to enable for this
+	// call, merged with the client's ClientOptions.Betas. See the
+	// provider packages for named constants (e.g. anthropic.BetaOutput128k).
+	Betas []string
+	// OrphanToolMessages controls how providers that require tool-call
+	// context (such as OpenAI) handle RoleTool messages when Tools is
+	// empty: OrphanToolDowngrade (the default) sends them as user
+	// messages, OrphanToolError fails the call.
+	OrphanToolMessages OrphanToolPolicy
+	// SystemOnly controls requests that contain no user or assistant
+	// message: SystemOnlyError (the default) fails the call before it
+	// is sent, SystemOnlyInsertUser appends a minimal user message.
+	SystemOnly SystemOnlyPolicy
+	// Reasoning requests reasoning output on providers that support it:
+	// OpenAI-compatible servers receive Effort as reasoning_effort, and
+	// Anthropic enables extended thinking with BudgetTokens (or a budget
+	// derived from Effort). Nil leaves the provider default.
+	Reasoning *ReasoningOptions
+	// Metadata is request-level metadata sent to providers that support
+	// it (OpenAI metadata, Anthropic metadata.user_id via MetadataUserID).
+	// Message.Metadata is never sent.
+	Metadata map[string]string
+	// UserID is an end-user identifier for abuse tracking, sent to
+	// OpenAI as user and to Anthropic as metadata.user_id. Empty uses
+	// the ID set with WithUserID, then ClientOptions.DefaultUserID.
+	UserID string
+	// Store controls whether the provider retains the request and
+	// response (OpenAI store). Nil uses the client's
+	// ClientOptions.DefaultStore.
+	Store *bool
+	// WebSearch enables the provider's server-side web search tool
+	// (Anthropic web_search). Cited sources are returned in Citations
+	// and all results in Metadata.SearchResults.
+	WebSearch *WebSearchOptions
+	// ProviderOptions holds provider-specific body fields merged into
+	// the top level of the request body. Fields set from the typed
+	// fields above take precedence.
+	ProviderOptions map[string]any
+	// ForwardMetadataKeys names Message.Metadata keys whose values are
+	// copied into the request metadata, with later messages overriding
+	// earlier ones and Metadata overriding both. Values are formatted
+	// with fmt.Sprint.
+	ForwardMetadataKeys []string
 }
 
 // GenerateTextResponse is the result of a non-streaming text generation call.
 type GenerateTextResponse struct {
 	// Text is the concatenated assistant text returned by the model.
 	Text string
-	// StopReason describes why generation stopped (if available).
+	// TraceID is the client-generated ID of this call. It is sent as the
+	// TraceHeader request header and recorded on returned errors.
+	TraceID string
+	// Reasoning is the model's reasoning output, if the provider returned
+	// any. It is kept separate from Text and is not echoed back into the
+	// history: append AssistantMessage(Text) to continue a conversation.
+	Reasoning string
+	// ReasoningBlocks are the signed reasoning blocks behind Reasoning.
+	// When ToolCalls is non-empty, set them on the assistant message
+	// with the calls (Message.ReasoningBlocks): Anthropic rejects the
+	// next step of a tool loop with thinking enabled without them.
+	ReasoningBlocks []ReasoningBlock
+	// StopReason describes why generation stopped (if available), in
+	// the provider's own terms. It is the provider's tool-calls value
+	// whenever ToolCalls is non-empty.
 	StopReason string
-	// ToolCalls contains any tool invocations emitted by the model.
+	// FinishReason is StopReason normalized across providers. It is
+	// FinishReasonToolCalls if and only if ToolCalls is non-empty, and
+	// FinishReasonOther for provider values without a normalized
+	// equivalent.
+	FinishReason FinishReason
+	// ToolCalls contains any tool invocations emitted by the model. They
+	// take precedence over Text: a response with tool calls is not a
+	// final answer, even if Text is non-empty.
 	ToolCalls []ToolCall
+	// Warnings contains non-fatal validation notes reported by the
+	// provider, such as a feature used without the beta it requires.
+	Warnings []string
+	// ToolDiagnostics is set when Tools were sent but no tool calls were
+	// returned. See ProbeToolSupport for an active check.
+	ToolDiagnostics *ToolDiagnostics
+	// Citations lists sources the provider attributed parts of Text to.
+	Citations []Citation
+	// SafetyRatings contains the provider's safety assessment, if any.
+	SafetyRatings []SafetyRating
+	// Usage is the token usage of the call, or nil if the provider did
+	// not report it.
+	Usage *Usage
+	// Metadata describes how the response was produced, such as the
+	// provider's request ID, its rate-limit headers, or the model a
+	// RouteBySize router chose. It may be nil.
+	Metadata *ResponseMetadata
+	// Logprobs holds per-token log probabilities when they were
+	// requested and returned.
+	Logprobs []TokenLogprob
+}
+
+// languageModelRequest maps the high-level request onto the
+// provider-level request shared by all language model calls.
+func (req GenerateTextRequest) languageModelRequest() *provider.LanguageModelRequest {
+	messages := req.Messages
+	if req.System != "" {
+		messages = append([]Message{SystemMessage(req.System)}, req.Messages...)
+	}
+	return &provider.LanguageModelRequest{
+		Messages:           messages,
+		Temperature:        req.Temperature,
+		TopP:               req.TopP,
+		MaxTokens:          req.MaxTokens,
+		Stop:               req.Stop,
+		JSONSchema:         req.jsonSchema(),
+		JSONSchemaName:     req.JSONSchemaName,
+		JSONSchemaStrict:   req.JSONSchemaStrict,
+		ResponseFormat:     req.ResponseFormat,
+		Tools:              req.Tools,
+		ToolChoice:         req.ToolChoice,
+		ParallelToolCalls:  req.ParallelToolCalls,
+		LogitBias:          req.LogitBias,
+		FrequencyPenalty:   req.FrequencyPenalty,
+		PresencePenalty:    req.PresencePenalty,
+		Logprobs:           req.Logprobs,
+		TopLogprobs:        req.TopLogprobs,
+		Betas:              req.Betas,
+		OrphanToolMessages: req.OrphanToolMessages,
+		SystemOnly:         req.SystemOnly,
+		Reasoning:          req.Reasoning,
+		Metadata:           req.requestMetadata(),
+		UserID:             req.UserID,
+		Store:              req.Store,
+		WebSearch:          req.WebSearch,
+		ProviderOptions:    req.ProviderOptions,
+	}
+}
+
+// jsonSchema returns JSONSchema, rewritten with StrictJSONSchema when
+// JSONSchemaStrict is set.
+func (req GenerateTextRequest) jsonSchema() []byte {
+	if !req.JSONSchemaStrict || len(req.JSONSchema) == 0 {
+		return req.JSONSchema
+	}
+	schema, err := StrictJSONSchema(req.JSONSchema)
+	if err != nil {
+		return req.JSONSchema
+	}
+	return schema
+}
+
+// validateToolChoice checks that ToolChoice is a known value naming,
+// for ToolChoiceTool, one of Tools.
+func (req GenerateTextRequest) validateToolChoice() error {
+	switch req.ToolChoice {
+	case "", ToolChoiceAuto, ToolChoiceNone, ToolChoiceRequired:
+		return nil
+	}
+	name, ok := req.ToolChoice.Tool()
+	if !ok {
+		return &InvalidArgumentError{Parameter: "ToolChoice", Value: req.ToolChoice, Message: `must be "auto", "none", "required", or built by ToolChoiceTool`}
+	}
+	for _, t := range req.Tools {
+		if t.Name == name {
+			return nil
+		}
+	}
+	return &InvalidArgumentError{Parameter: "ToolChoice", Value: req.ToolChoice, Message: fmt.Sprintf("tool %q is not in Tools", name)}
+}
+
+// requestMetadata merges forwarded message metadata with req.Metadata.
+func (req GenerateTextRequest) requestMetadata() map[string]string {
+	if len(req.ForwardMetadataKeys) == 0 {
+		return req.Metadata
+	}
+	out := make(map[string]string)
+	for _, msg := range req.Messages {
+		for _, k := range req.ForwardMetadataKeys {
+			if v, ok := msg.Metadata[k]; ok && v != nil {
+				out[k] = fmt.Sprint(v)
+			}
+		}
+	}
+	for k, v := range req.Metadata {
+		out[k] = v
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
 }
 
 // GenerateText calls the underlying LanguageModel.Generate and returns a
@@ -107,34 +499,80 @@ type GenerateTextResponse struct {
 //
 // Errors:
 //   - ErrMissingModel if req.Model is nil.
+//   - *InvalidArgumentError if req.ToolChoice is not a known value or
+//     names a tool that is not in req.Tools, or if the history cannot
+//     be packed into the model's message cap (see PackMessages).
+//   - *EmptyResponseError if the provider returned a successful status
+//     without any result (for example a gateway body without choices).
 //   - Any error returned by the underlying provider implementation. For
 //     the OpenAI provider this includes HTTP and JSON decoding errors
 //     originating from the OpenAI API.
+//
+// Each call has a trace ID, taken from ctx (see WithTraceID) or
+// generated. Provider errors carry it in their TraceID field or are
+// wrapped in a *TracedError; see TraceIDFromError.
+//
+// Models that cap the number of messages per request (see
+// provider.MessageLimiter) receive req.Messages packed with
+// PackMessages.
 func GenerateText(ctx context.Context, req GenerateTextRequest) (GenerateTextResponse, error) {
 	if req.Model == nil {
 		return GenerateTextResponse{}, ErrMissingModel
 	}
-
-	lmReq := &provider.LanguageModelRequest{
-		Messages:    req.Messages,
-		Temperature: req.Temperature,
-		TopP:        req.TopP,
-		MaxTokens:   req.MaxTokens,
-		Stop:        req.Stop,
-		JSONSchema:  req.JSONSchema,
-		Tools:       req.Tools,
+	if err := req.validateToolChoice(); err != nil {
+		return GenerateTextResponse{}, err
 	}
-
-	lmRes, err := req.Model.Generate(ctx, lmReq)
+	req, err := req.fitMessageLimit()
 	if err != nil {
 		return GenerateTextResponse{}, err
 	}
 
+	ctx, traceID := provider.EnsureTraceID(ctx)
+	lmRes, err := req.Model.Generate(ctx, req.languageModelRequest())
+	if err != nil {
+		return GenerateTextResponse{}, traceError(err, traceID)
+	}
+
+	res := newGenerateTextResponse(lmRes)
+	res.TraceID = traceID
+	return res, nil
+}
+
+// newGenerateTextResponse maps a provider-level response onto the
+// high-level response type.
+func newGenerateTextResponse(lmRes *provider.LanguageModelResponse) GenerateTextResponse {
 	return GenerateTextResponse{
-		Text:       lmRes.Text,
-		StopReason: lmRes.StopReason,
-		ToolCalls:  lmRes.ToolCalls,
-	}, nil
+		Text:            lmRes.Text,
+		Reasoning:       lmRes.Reasoning,
+		ReasoningBlocks: lmRes.ReasoningBlocks,
+		StopReason:      lmRes.StopReason,
+		FinishReason:    normalizedFinishReason(lmRes),
+		ToolCalls:       lmRes.ToolCalls,
+		Warnings:        lmRes.Warnings,
+		ToolDiagnostics: lmRes.ToolDiagnostics,
+		Citations:       lmRes.Citations,
+		SafetyRatings:   lmRes.SafetyRatings,
+		Usage:           lmRes.Usage,
+		Metadata:        lmRes.Metadata,
+		Logprobs:        lmRes.Logprobs,
+	}
+}
+
+// normalizedFinishReason returns lmRes.FinishReason, derived from
+// StopReason for models that do not set it, and enforces that
+// FinishReasonToolCalls goes with tool calls.
+func normalizedFinishReason(lmRes *provider.LanguageModelResponse) FinishReason {
+	if len(lmRes.ToolCalls) > 0 {
+		return FinishReasonToolCalls
+	}
+	reason := lmRes.FinishReason
+	if reason == "" {
+		reason = provider.ParseFinishReason(lmRes.StopReason)
+	}
+	if reason == FinishReasonToolCalls {
+		return FinishReasonOther
+	}
+	return reason
 }
 
 // StreamText calls the underlying LanguageModel.Stream and returns a
@@ -142,39 +580,55 @@ func GenerateText(ctx context.Context, req GenerateTextRequest) (GenerateTextRes
 //
 // Errors:
 //   - ErrMissingModel if req.Model is nil.
+//   - *InvalidArgumentError for an invalid req.ToolChoice or a history
+//     that cannot be packed, as for GenerateText.
 //   - Any error returned by the underlying provider implementation when
-//     establishing the stream.
+//     establishing the stream, with the call's trace ID recorded as for
+//     GenerateText. Models that cannot stream return
+//     ErrStreamingUnsupported; see StreamOrSimulate.
 func StreamText(ctx context.Context, req GenerateTextRequest) (TextStream, error) {
 	if req.Model == nil {
 		return nil, ErrMissingModel
 	}
-
-	lmReq := &provider.LanguageModelRequest{
-		Messages:    req.Messages,
-		Temperature: req.Temperature,
-		TopP:        req.TopP,
-		MaxTokens:   req.MaxTokens,
-		Stop:        req.Stop,
-		JSONSchema:  req.JSONSchema,
-		Tools:       req.Tools,
+	if err := req.validateToolChoice(); err != nil {
+		return nil, err
+	}
+	req, err := req.fitMessageLimit()
+	if err != nil {
+		return nil, err
 	}
 
-	return req.Model.Stream(ctx, lmReq)
+	ctx, traceID := provider.EnsureTraceID(ctx)
+	stream, err := req.Model.Stream(ctx, req.languageModelRequest())
+	if err != nil {
+		return nil, traceError(err, traceID)
+	}
+	return stream, nil
 }
 
 // GenerateSimpleText is a convenience helper for the common case of
 // a single user prompt and plain text response.
 //
-// It constructs a GenerateTextRequest with a single user message and
-// returns only the response text.
-func GenerateSimpleText(ctx context.Context, model LanguageModel, prompt string) (string, error) {
-	res, err := GenerateText(ctx, GenerateTextRequest{
-		Model: model,
-		Messages: []Message{{
-			Role:    RoleUser,
-			Content: prompt,
-		}},
-	})
+// It constructs a GenerateTextRequest with prompt as the last user
+// message, applies opts such as WithSystem, WithSettings, or
+// WithRegistryModel, and returns only the response text. Prefer adding
+// a TextOption to adding another helper variant.
+//
+// Errors:
+//   - ErrMissingModel if model is nil and no WithRegistryModel option
+//     is given.
+//   - InvalidArgumentError if WithRegistryModel was given a nil
+//     registry.
+//   - Any error returned by the registry lookup or GenerateText.
+func GenerateSimpleText(ctx context.Context, model LanguageModel, prompt string, opts ...TextOption) (string, error) {
+	o := buildTextRequest(model, prompt, opts)
+	var res GenerateTextResponse
+	var err error
+	if o.useReg {
+		res, err = GenerateTextWithRegistry(ctx, o.reg, o.modelName, o.req)
+	} else {
+		res, err = GenerateText(ctx, o.req)
+	}
 	if err != nil {
 		return "", err
 	}
@@ -183,21 +637,10 @@ func GenerateSimpleText(ctx context.Context, model LanguageModel, prompt string)
 
 // GenerateSimpleTextWithRegistry is a convenience helper for the common
 // case of a single user prompt and plain text response when using a
-// registry to look up the model by name.
-//
-// It constructs a GenerateTextRequest with a single user message and
-// delegates to GenerateTextWithRegistry.
-func GenerateSimpleTextWithRegistry(ctx context.Context, reg registry.Registry, modelName, prompt string) (string, error) {
-	res, err := GenerateTextWithRegistry(ctx, reg, modelName, GenerateTextRequest{
-		Messages: []Message{{
-			Role:    RoleUser,
-			Content: prompt,
-		}},
-	})
-	if err != nil {
-		return "", err
-	}
-	return res.Text, nil
+// registry to look up the model by name. It is GenerateSimpleText with
+// WithRegistryModel(reg, modelName) applied before opts.
+func GenerateSimpleTextWithRegistry(ctx context.Context, reg registry.Registry, modelName, prompt string, opts ...TextOption) (string, error) {
+	return GenerateSimpleText(ctx, nil, prompt, append([]TextOption{WithRegistryModel(reg, modelName)}, opts...)...)
 }
 
 // GenerateTextWithRegistry is a convenience helper that looks up the
@@ -335,12 +778,22 @@ type EmbeddingRequest struct {
 	Input []string
 	// UserID is an optional identifier used for provider-side logging.
 	UserID string
+	// OutputDType selects the vector type of the response. Empty means
+	// EmbeddingFloat32. Providers that produce the type natively are
+	// asked for it; for others the float32 vectors are converted with
+	// QuantizeInt8 or QuantizeBinary.
+	OutputDType EmbeddingDType
 }
 
-// EmbeddingResponse contains embedding vectors.
+// EmbeddingResponse contains embedding vectors, one per input, in the
+// field matching EmbeddingRequest.OutputDType. The other fields are
+// nil.
 type EmbeddingResponse struct {
 	// Embeddings is a slice of embedding vectors, one per input.
 	Embeddings [][]float32
+	Float64    [][]float64
+	Int8       []Int8Vector
+	Binary     []BinaryVector
 }
 
 // GenerateEmbeddings calls the underlying EmbeddingModel.Generate and
@@ -348,23 +801,36 @@ type EmbeddingResponse struct {
 //
 // Errors:
 //   - ErrMissingEmbeddingModel if req.Model is nil.
+//   - *InvalidArgumentError if req.OutputDType is not a known type.
 //   - Any error returned by the underlying provider implementation.
 func GenerateEmbeddings(ctx context.Context, req EmbeddingRequest) (EmbeddingResponse, error) {
 	if req.Model == nil {
 		return EmbeddingResponse{}, ErrMissingEmbeddingModel
 	}
 
+	dtype := req.OutputDType
+	switch dtype {
+	case "":
+		dtype = EmbeddingFloat32
+	case EmbeddingFloat32, EmbeddingFloat64, EmbeddingInt8, EmbeddingBinary:
+	default:
+		return EmbeddingResponse{}, &InvalidArgumentError{Parameter: "OutputDType", Value: req.OutputDType, Message: `must be "float32", "float64", "int8", or "binary"`}
+	}
+
 	embReq := &provider.EmbeddingRequest{
 		Input:  req.Input,
 		UserID: req.UserID,
 	}
+	if dtype != EmbeddingFloat32 {
+		embReq.OutputDType = dtype
+	}
 
 	embRes, err := req.Model.Generate(ctx, embReq)
 	if err != nil {
 		return EmbeddingResponse{}, err
 	}
 
-	return EmbeddingResponse{Embeddings: embRes.Embeddings}, nil
+	return embeddingResponse(embRes, dtype), nil
 }
 
 // GenerateEmbeddingsWithRegistry is a convenience helper that looks up
@@ -404,6 +870,22 @@ type ImageRequest struct {
 	ResponseFormat string
 	// UserID is an optional identifier used for provider-side logging.
 	UserID string
+	// PartialImages is the number of partial (preview) images to emit
+	// when streaming with StreamImage. It is ignored by GenerateImage.
+	PartialImages int
+}
+
+// imageRequest maps the high-level request onto the provider-level
+// request shared by GenerateImage and StreamImage.
+func (req ImageRequest) imageRequest() *provider.ImageRequest {
+	return &provider.ImageRequest{
+		Prompt:         req.Prompt,
+		Size:           req.Size,
+		NumberOfImages: req.NumberOfImages,
+		ResponseFormat: req.ResponseFormat,
+		UserID:         req.UserID,
+		PartialImages:  req.PartialImages,
+	}
 }
 
 // ImageResponse contains generated images.
@@ -414,23 +896,25 @@ type ImageResponse struct {
 
 // GenerateImage calls the underlying ImageModel.Generate and returns generated images.
 //
+// Size and NumberOfImages are validated against the model's
+// capabilities before any HTTP call when the model reports them (see
+// provider.ImageCapabilityReporter); models with unknown limits are
+// not validated.
+//
 // Errors:
 //   - ErrMissingModel if req.Model is nil.
+//   - *InvalidArgumentError if Size or NumberOfImages is not supported
+//     by the model.
 //   - Any error returned by the underlying provider implementation.
 func GenerateImage(ctx context.Context, req ImageRequest) (ImageResponse, error) {
 	if req.Model == nil {
 		return ImageResponse{}, ErrMissingModel
 	}
-
-	imgReq := &provider.ImageRequest{
-		Prompt:         req.Prompt,
-		Size:           req.Size,
-		NumberOfImages: req.NumberOfImages,
-		ResponseFormat: req.ResponseFormat,
-		UserID:         req.UserID,
+	if err := req.validate(); err != nil {
+		return ImageResponse{}, err
 	}
 
-	imgRes, err := req.Model.Generate(ctx, imgReq)
+	imgRes, err := req.Model.Generate(ctx, req.imageRequest())
 	if err != nil {
 		return ImageResponse{}, err
 	}
@@ -469,8 +953,9 @@ type SpeechRequest struct {
 	Input string
 	// Voice is an optional voice selection (provider-specific).
 	Voice string
-	// Format is the desired audio container/codec (e.g. "mp3", "wav").
-	Format string
+	// Format is the desired audio container/codec (e.g. FormatMP3).
+	// Empty means the provider default.
+	Format AudioFormat
 	// Language is an optional BCP-47 language tag.
 	Language string
 	// UserID is an optional identifier used for provider-side logging.
@@ -486,14 +971,22 @@ type SpeechResponse struct {
 }
 
 // GenerateSpeech calls the underlying SpeechModel.Generate and returns synthesized audio.
+// When the provider does not report a content type, MimeType is derived
+// from req.Format.
 //
 // Errors:
 //   - ErrMissingModel if req.Model is nil.
+//   - InvalidArgumentError if req.Format is not supported by the model,
+//     when the model reports its capabilities (see
+//     provider.SpeechCapabilityReporter).
 //   - Any error returned by the underlying provider implementation.
 func GenerateSpeech(ctx context.Context, req SpeechRequest) (SpeechResponse, error) {
 	if req.Model == nil {
 		return SpeechResponse{}, ErrMissingModel
 	}
+	if err := req.validate(); err != nil {
+		return SpeechResponse{}, err
+	}
 
 	spReq := &provider.SpeechRequest{
 		Input:    req.Input,
@@ -508,9 +1001,13 @@ func GenerateSpeech(ctx context.Context, req SpeechRequest) (SpeechResponse, err
 		return SpeechResponse{}, err
 	}
 
+	mimeType := spRes.MimeType
+	if mimeType == "" {
+		mimeType = provider.AudioMimeType(req.Format)
+	}
 	return SpeechResponse{
 		Audio:    spRes.Audio,
-		MimeType: spRes.MimeType,
+		MimeType: mimeType,
 	}, nil
 }
 
@@ -555,12 +1052,26 @@ type TranscriptionRequest struct {
 	Temperature *float64
 	// UserID is an optional identifier used for provider-side logging.
 	UserID string
+	// ExtraFields contains additional form fields passed through as-is,
+	// for backends that accept options beyond the OpenAI API (for
+	// example "vad_filter" on self-hosted Whisper servers).
+	ExtraFields map[string]string
+	// ExtraArrayFields contains additional array-valued form fields.
+	// Each value is sent as a repeated "name[]" field, e.g.
+	// "timestamp_granularities": {"word", "segment"}.
+	ExtraArrayFields map[string][]string
+	// Segments requests segment-level timestamps in the response for
+	// models that support them.
+	Segments bool
 }
 
 // TranscriptionResponse contains the transcription text.
 type TranscriptionResponse struct {
 	// Text is the transcribed text.
 	Text string
+	// Segments holds timestamped segments when they were requested and
+	// the model returned them.
+	Segments []TranscriptionSegment
 }
 
 // Transcribe calls the underlying TranscriptionModel.Generate and returns the transcription text.
@@ -574,13 +1085,16 @@ func Transcribe(ctx context.Context, req TranscriptionRequest) (TranscriptionRes
 	}
 
 	trReq := &provider.TranscriptionRequest{
-		Audio:       req.Audio,
-		FileName:    req.FileName,
-		MimeType:    req.MimeType,
-		Language:    req.Language,
-		Prompt:      req.Prompt,
-		Temperature: req.Temperature,
-		UserID:      req.UserID,
+		Audio:            req.Audio,
+		FileName:         req.FileName,
+		MimeType:         req.MimeType,
+		Language:         req.Language,
+		Prompt:           req.Prompt,
+		Temperature:      req.Temperature,
+		UserID:           req.UserID,
+		ExtraFields:      req.ExtraFields,
+		ExtraArrayFields: req.ExtraArrayFields,
+		Segments:         req.Segments,
 	}
 
 	trRes, err := req.Model.Generate(ctx, trReq)
@@ -589,7 +1103,8 @@ func Transcribe(ctx context.Context, req TranscriptionRequest) (TranscriptionRes
 	}
 
 	return TranscriptionResponse{
-		Text: trRes.Text,
+		Text:     trRes.Text,
+		Segments: trRes.Segments,
 	}, nil
 }
 
@@ -0,0 +1,316 @@
+package aitest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/provider"
+	"github.com/ncecere/ai-sdk/providerutil"
+)
+
+// UpdateGoldenEnv is the environment variable that makes CompareGolden
+// write golden files instead of comparing against them, for example
+// AITEST_UPDATE=1 go test ./...
+const UpdateGoldenEnv = "AITEST_UPDATE"
+
+// CapturedRequest is an HTTP request a CapturingModel recorded for one
+// call.
+type CapturedRequest struct {
+	// Stream reports whether the request was rendered for a Stream
+	// call rather than a Generate call.
+	Stream bool
+	Method string
+	URL    string
+	// Header holds the request headers with credentials redacted by
+	// providerutil.RedactHeaders.
+	Header http.Header
+	Body   []byte
+}
+
+// CapturingModel is a provider.LanguageModel that records the HTTP
+// request the wrapped model sends for each call. Use it to assert the
+// exact payloads an application causes the SDK to send. A
+// CapturingModel is safe for concurrent use.
+//
+// Calls passed to the wrapped model are recorded as they are sent,
+// through provider.WithRequestObserver, so the request is built once
+// and side effects of building it, such as lint hooks or fetching
+// remote images, happen once. With Respond set, the request is rendered
+// through provider.RequestBuilder (and provider.StreamRequestBuilder
+// for streams) instead, and nothing is sent. The built-in OpenAI and
+// Anthropic chat models support both.
+type CapturingModel struct {
+	// Respond, when set, answers each call instead of the wrapped model,
+	// so that nothing is sent. A Stream call replays the response as a
+	// single delta.
+	Respond func(req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error)
+
+	model    provider.LanguageModel
+	mu       sync.Mutex
+	requests []CapturedRequest
+}
+
+// CaptureRequests wraps model in a CapturingModel. Calls fail if model
+// cannot render the request they would send.
+func CaptureRequests(model provider.LanguageModel) *CapturingModel {
+	return &CapturingModel{model: model}
+}
+
+// Requests returns a copy of the requests recorded so far, in call
+// order.
+func (m *CapturingModel) Requests() []CapturedRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.requests)
+}
+
+// Reset discards the recorded requests.
+func (m *CapturingModel) Reset() {
+	m.mu.Lock()
+	m.requests = nil
+	m.mu.Unlock()
+}
+
+// Generate records the request and calls Respond or the wrapped model.
+func (m *CapturingModel) Generate(ctx context.Context, req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
+	if m.Respond == nil {
+		if _, ok := m.model.(provider.RequestBuilder); !ok {
+			return nil, fmt.Errorf("aitest: %T does not implement provider.RequestBuilder", m.model)
+		}
+		return m.model.Generate(m.observe(ctx, false), req)
+	}
+	if err := m.capture(ctx, req, false); err != nil {
+		return nil, err
+	}
+	return m.Respond(req)
+}
+
+// Stream records the request and calls Respond or the wrapped model.
+func (m *CapturingModel) Stream(ctx context.Context, req *provider.LanguageModelRequest) (provider.LanguageModelStream, error) {
+	if m.Respond == nil {
+		if _, ok := m.model.(provider.StreamRequestBuilder); !ok {
+			return nil, fmt.Errorf("aitest: %T does not implement provider.StreamRequestBuilder", m.model)
+		}
+		return m.model.Stream(m.observe(ctx, true), req)
+	}
+	if err := m.capture(ctx, req, true); err != nil {
+		return nil, err
+	}
+	res, err := m.Respond(req)
+	if err != nil {
+		return nil, err
+	}
+	return &responseStream{res: res}, nil
+}
+
+// BuildRequest implements provider.RequestBuilder by delegating to the
+// wrapped model, so dry runs see through the capture.
+func (m *CapturingModel) BuildRequest(ctx context.Context, req *provider.LanguageModelRequest) (*http.Request, []byte, error) {
+	builder, ok := m.model.(provider.RequestBuilder)
+	if !ok {
+		return nil, nil, fmt.Errorf("aitest: %T does not implement provider.RequestBuilder", m.model)
+	}
+	return builder.BuildRequest(ctx, req)
+}
+
+// observe returns ctx with an observer that records the requests the
+// wrapped model sends.
+func (m *CapturingModel) observe(ctx context.Context, stream bool) context.Context {
+	return provider.WithRequestObserver(ctx, func(httpReq *http.Request, body []byte) {
+		m.record(httpReq, body, stream)
+	})
+}
+
+// capture renders the request the wrapped model would send for req and
+// records it.
+func (m *CapturingModel) capture(ctx context.Context, req *provider.LanguageModelRequest, stream bool) error {
+	var httpReq *http.Request
+	var body []byte
+	var err error
+	if stream {
+		builder, ok := m.model.(provider.StreamRequestBuilder)
+		if !ok {
+			return fmt.Errorf("aitest: %T does not implement provider.StreamRequestBuilder", m.model)
+		}
+		httpReq, body, err = builder.BuildStreamRequest(ctx, req)
+	} else {
+		httpReq, body, err = m.BuildRequest(ctx, req)
+	}
+	if err != nil {
+		return err
+	}
+	m.record(httpReq, body, stream)
+	return nil
+}
+
+func (m *CapturingModel) record(httpReq *http.Request, body []byte, stream bool) {
+	m.mu.Lock()
+	m.requests = append(m.requests, CapturedRequest{
+		Stream: stream,
+		Method: httpReq.Method,
+		URL:    httpReq.URL.String(),
+		Header: providerutil.RedactHeaders(httpReq.Header),
+		Body:   slices.Clone(body),
+	})
+	m.mu.Unlock()
+}
+
+// responseStream replays a response as one delta followed by Done.
+type responseStream struct {
+	res  *provider.LanguageModelResponse
+	sent bool
+}
+
+func (s *responseStream) Next(ctx context.Context) (*provider.LanguageModelDelta, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !s.sent && (s.res.Text != "" || s.res.Reasoning != "") {
+		s.sent = true
+		return &provider.LanguageModelDelta{Text: s.res.Text, Reasoning: s.res.Reasoning}, nil
+	}
+	s.sent = true
+	return &provider.LanguageModelDelta{
+		ToolCalls:    s.res.ToolCalls,
+		Usage:        s.res.Usage,
+		StopReason:   s.res.StopReason,
+		FinishReason: s.res.FinishReason,
+		Warnings:     s.res.Warnings,
+		Metadata:     s.res.Metadata,
+		Done:         true,
+	}, nil
+}
+
+func (s *responseStream) Close() error { return nil }
+
+// GoldenOptions controls how FormatRequest renders a request.
+type GoldenOptions struct {
+	// ScrubFields names JSON body fields, at any depth, whose values
+	// change between runs, such as timestamps or request IDs. Their
+	// values are rendered as "<scrubbed>".
+	ScrubFields []string
+	// ScrubHeaders names headers whose values are rendered as
+	// "<scrubbed>". Credentials, including Idempotency-Key, are already
+	// redacted.
+	ScrubHeaders []string
+	// OmitHeaders leaves the headers out entirely.
+	OmitHeaders bool
+	// BaseURL is trimmed from the start of the URL, for test servers
+	// whose address changes between runs.
+	BaseURL string
+}
+
+// FormatRequest renders req in a stable text form for golden files:
+// the method and URL, the headers sorted by name, a blank line, and the
+// body. A JSON body is indented with its object keys sorted; any other
+// body, such as multipart form data, is written as is.
+func FormatRequest(req CapturedRequest, opts GoldenOptions) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s %s\n", req.Method, strings.TrimPrefix(req.URL, opts.BaseURL))
+	if !opts.OmitHeaders {
+		names := make([]string, 0, len(req.Header))
+		for name := range req.Header {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			values := req.Header[name]
+			if slices.ContainsFunc(opts.ScrubHeaders, func(h string) bool { return http.CanonicalHeaderKey(h) == name }) {
+				values = []string{"<scrubbed>"}
+			}
+			for _, v := range values {
+				fmt.Fprintf(&b, "%s: %s\n", name, v)
+			}
+		}
+	}
+	b.WriteByte('\n')
+	b.Write(formatBody(req.Body, opts.ScrubFields))
+	if b.Len() > 0 && b.Bytes()[b.Len()-1] != '\n' {
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+func formatBody(body []byte, scrub []string) []byte {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return body
+	}
+	v = scrubFields(v, scrub)
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return body
+	}
+	return out.Bytes()
+}
+
+func scrubFields(v any, scrub []string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, field := range v {
+			if slices.Contains(scrub, k) {
+				v[k] = "<scrubbed>"
+				continue
+			}
+			v[k] = scrubFields(field, scrub)
+		}
+	case []any:
+		for i := range v {
+			v[i] = scrubFields(v[i], scrub)
+		}
+	}
+	return v
+}
+
+// CompareGolden fails t if got differs from the contents of the golden
+// file at path, reporting the first differing line. When the
+// UpdateGoldenEnv environment variable is set, it writes got to path
+// instead, creating missing directories.
+func CompareGolden(t testing.TB, path string, got []byte) {
+	t.Helper()
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("aitest: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("aitest: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Errorf("aitest: %v (set %s=1 to create it)", err, UpdateGoldenEnv)
+		return
+	}
+	if bytes.Equal(got, want) {
+		return
+	}
+	gotLines, wantLines := strings.Split(string(got), "\n"), strings.Split(string(want), "\n")
+	line := 0
+	for line < len(gotLines) && line < len(wantLines) && gotLines[line] == wantLines[line] {
+		line++
+	}
+	t.Errorf("aitest: %s differs at line %d (set %s=1 to update)\ngot:  %s\nwant: %s\n\nfull output:\n%s",
+		path, line+1, UpdateGoldenEnv, lineAt(gotLines, line), lineAt(wantLines, line), got)
+}
+
+func lineAt(lines []string, i int) string {
+	if i < len(lines) {
+		return lines[i]
+	}
+	return "<end of file>"
+}
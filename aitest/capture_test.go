@@ -0,0 +1,146 @@
+package aitest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/openai"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+func TestCaptureRequests(t *testing.T) {
+	client, err := openai.NewClient(provider.ClientOptions{
+		BaseURL: "https://gateway.example.com/v1",
+		APIKey:  "secret",
+		Headers: http.Header{"X-Request-Time": {"12:00:01"}},
+	})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	model := CaptureRequests(client.ChatModel("gpt-test"))
+	model.Respond = func(req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
+		return &provider.LanguageModelResponse{Text: "ok"}, nil
+	}
+
+	ctx := context.Background()
+	req := &provider.LanguageModelRequest{
+		Messages:        []provider.Message{{Role: "user", Content: "<hi>"}},
+		ProviderOptions: map[string]any{"metadata": map[string]any{"sent_at": 1700000000}},
+	}
+	if res, err := model.Generate(ctx, req); err != nil || res.Text != "ok" {
+		t.Fatalf("Generate = %+v, %v", res, err)
+	}
+	stream, err := model.Stream(ctx, req)
+	if err != nil {
+		t.Fatalf("Stream error: %v", err)
+	}
+	if delta, err := stream.Next(ctx); err != nil || delta.Text != "ok" {
+		t.Fatalf("Next = %+v, %v", delta, err)
+	}
+	if delta, err := stream.Next(ctx); err != nil || !delta.Done {
+		t.Fatalf("Next = %+v, %v; want the final delta", delta, err)
+	}
+
+	reqs := model.Requests()
+	if len(reqs) != 2 || reqs[0].Stream || !reqs[1].Stream {
+		t.Fatalf("unexpected requests %+v", reqs)
+	}
+	opts := GoldenOptions{ScrubFields: []string{"sent_at"}, ScrubHeaders: []string{"x-request-time"}}
+	want := `POST https://gateway.example.com/v1/chat/completions
+Authorization: REDACTED
+Content-Type: application/json
+X-Request-Time: <scrubbed>
+
+{
+  "messages": [
+    {
+      "content": "<hi>",
+      "role": "user"
+    }
+  ],
+  "metadata": {
+    "sent_at": "<scrubbed>"
+  },
+  "model": "gpt-test"
+}
+`
+	if got := string(FormatRequest(reqs[0], opts)); got != want {
+		t.Fatalf("FormatRequest =\n%s\nwant\n%s", got, want)
+	}
+	if got := string(FormatRequest(reqs[1], GoldenOptions{OmitHeaders: true})); !strings.HasPrefix(got, "POST https://gateway.example.com/v1/chat/completions\n\n{") || !strings.Contains(got, `"stream": true`) {
+		t.Fatalf("FormatRequest(stream) =\n%s", got)
+	}
+
+	model.Reset()
+	if len(model.Requests()) != 0 {
+		t.Fatal("Reset kept the requests")
+	}
+}
+
+func TestCaptureRequests_PassThroughBuildsOnce(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"finish_reason":"stop","message":{"role":"assistant","content":"sent"}}]}`)
+	}))
+	defer ts.Close()
+
+	var lints int
+	client, err := openai.NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client(),
+		OnLintWarnings: func(string, []string) { lints++ },
+	})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	model := CaptureRequests(client.ChatModel("gpt-test"))
+	res, err := model.Generate(context.Background(), &provider.LanguageModelRequest{
+		Messages:        []provider.Message{{Role: "user", Content: "hi"}},
+		ProviderOptions: map[string]any{"frobnicate": true},
+	})
+	if err != nil || res.Text != "sent" {
+		t.Fatalf("Generate = %+v, %v", res, err)
+	}
+	if lints != 1 {
+		t.Fatalf("lint hook ran %d times, want once", lints)
+	}
+	reqs := model.Requests()
+	if len(reqs) != 1 || !strings.Contains(string(reqs[0].Body), `"frobnicate":true`) {
+		t.Fatalf("unexpected requests %+v", reqs)
+	}
+}
+
+func TestCaptureRequests_NoBuilder(t *testing.T) {
+	model := CaptureRequests(plainModel{})
+	if _, err := model.Generate(context.Background(), &provider.LanguageModelRequest{}); err == nil || !strings.Contains(err.Error(), "provider.RequestBuilder") {
+		t.Fatalf("expected an error for a model without a request builder, got %v", err)
+	}
+}
+
+type plainModel struct{ provider.LanguageModel }
+
+func TestCompareGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "req.golden")
+	t.Setenv(UpdateGoldenEnv, "1")
+	CompareGolden(t, path, []byte("a\nb\n"))
+	if data, err := os.ReadFile(path); err != nil || string(data) != "a\nb\n" {
+		t.Fatalf("golden file = %q, %v", data, err)
+	}
+
+	t.Setenv(UpdateGoldenEnv, "")
+	CompareGolden(t, path, []byte("a\nb\n"))
+	rec := &recordingTB{TB: t}
+	CompareGolden(rec, path, []byte("a\nc\n"))
+	if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], "line 2") || !strings.Contains(rec.errors[0], "got:  c") {
+		t.Fatalf("unexpected errors %q", rec.errors)
+	}
+	rec = &recordingTB{TB: t}
+	CompareGolden(rec, path+".missing", nil)
+	if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], UpdateGoldenEnv) {
+		t.Fatalf("unexpected errors %q", rec.errors)
+	}
+}
@@ -0,0 +1,107 @@
+// Package aitest provides helpers for testing applications built on
+// the SDK: a goroutine leak check, HTTP servers that misbehave the way
+// real providers and gateways do, and golden files of the requests sent
+// to providers.
+//
+// The SDK's own leak suite is built from these helpers, so applications
+// can run the same checks against their own composition of models,
+// middleware, agents, and SSE handlers.
+package aitest
+
+import (
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// LeakTimeout is how long VerifyNoLeaks waits for goroutines to exit
+// before reporting them.
+var LeakTimeout = 5 * time.Second
+
+// VerifyNoLeaks records the goroutines running now and, when t
+// finishes, fails t if goroutines started since then are still running
+// after LeakTimeout. Call it at the start of a test, before starting
+// servers, so their cleanups run first. Goroutines whose stack
+// contains any of the ignore substrings (for example a function name)
+// are not reported.
+//
+// The check covers every goroutine in the process, so it must not be
+// used in tests that run in parallel with others.
+func VerifyNoLeaks(t testing.TB, ignore ...string) {
+	t.Helper()
+	before := Snapshot()
+	t.Cleanup(func() {
+		if leaked := before.Leaked(LeakTimeout, ignore...); len(leaked) > 0 {
+			t.Errorf("aitest: %d leaked goroutine(s):\n\n%s", len(leaked), strings.Join(leaked, "\n\n"))
+		}
+	})
+}
+
+// GoroutineSnapshot is the set of goroutines running at a point in time.
+type GoroutineSnapshot map[int]bool
+
+// Snapshot records the IDs of the goroutines running now.
+func Snapshot() GoroutineSnapshot {
+	s := GoroutineSnapshot{}
+	for _, g := range goroutines() {
+		s[g.id] = true
+	}
+	return s
+}
+
+// Leaked waits up to timeout for goroutines started after s to exit
+// and returns the stacks of those still running. Garbage collection is
+// forced while waiting so that streams released by finalizers are
+// accounted for.
+func (s GoroutineSnapshot) Leaked(timeout time.Duration, ignore ...string) []string {
+	deadline := time.Now().Add(timeout)
+	for {
+		var leaked []string
+		for _, g := range goroutines() {
+			if s[g.id] || slices.ContainsFunc(ignore, func(sub string) bool { return strings.Contains(g.stack, sub) }) {
+				continue
+			}
+			leaked = append(leaked, g.stack)
+		}
+		if len(leaked) == 0 || time.Now().After(deadline) {
+			return leaked
+		}
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+type goroutine struct {
+	id    int
+	stack string
+}
+
+// goroutines returns the running goroutines, excluding the caller.
+func goroutines() []goroutine {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	var out []goroutine
+	for i, stack := range strings.Split(string(buf), "\n\n") {
+		if i == 0 {
+			continue // the calling goroutine
+		}
+		header, _, _ := strings.Cut(stack, " [")
+		id, err := strconv.Atoi(strings.TrimPrefix(header, "goroutine "))
+		if err != nil {
+			continue
+		}
+		out = append(out, goroutine{id: id, stack: stack})
+	}
+	return out
+}
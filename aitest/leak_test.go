@@ -0,0 +1,78 @@
+package aitest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordingTB captures cleanups and errors instead of failing the test.
+type recordingTB struct {
+	testing.TB
+	cleanups []func()
+	errors   []string
+}
+
+func (r *recordingTB) Helper()          {}
+func (r *recordingTB) Cleanup(f func()) { r.cleanups = append(r.cleanups, f) }
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingTB) finish() {
+	for i := len(r.cleanups) - 1; i >= 0; i-- {
+		r.cleanups[i]()
+	}
+}
+
+func blockedUntil(release chan struct{}) {
+	<-release
+}
+
+func TestVerifyNoLeaks_ReportsNewGoroutines(t *testing.T) {
+	defer func(d time.Duration) { LeakTimeout = d }(LeakTimeout)
+	LeakTimeout = 50 * time.Millisecond
+
+	rec := &recordingTB{TB: t}
+	VerifyNoLeaks(rec)
+	release := make(chan struct{})
+	go blockedUntil(release)
+	rec.finish()
+	if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], "blockedUntil") {
+		t.Fatalf("expected the blocked goroutine to be reported, got %q", rec.errors)
+	}
+
+	rec = &recordingTB{TB: t}
+	VerifyNoLeaks(rec, "aitest.blockedUntil")
+	go blockedUntil(release)
+	rec.finish()
+	if len(rec.errors) != 0 {
+		t.Fatalf("expected ignored goroutine not to be reported, got %q", rec.errors)
+	}
+	close(release)
+}
+
+func TestNewFaultServer_Faults(t *testing.T) {
+	VerifyNoLeaks(t)
+	for _, fault := range Faults {
+		t.Run(fault.String(), func(t *testing.T) {
+			srv := NewFaultServer(t, fault, "application/json", `{"ok":true}`)
+			ctx, cancel := CancelAtRandom(context.Background(), rand.New(rand.NewPCG(1, 2)), 50*time.Millisecond)
+			defer cancel()
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+			resp, err := srv.Client().Do(req)
+			if err == nil {
+				_, err = io.ReadAll(resp.Body)
+				resp.Body.Close()
+			}
+			if err == nil {
+				t.Fatalf("expected %s to fail the request", fault)
+			}
+		})
+	}
+}
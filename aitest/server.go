@@ -0,0 +1,97 @@
+package aitest
+
+import (
+	"context"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Fault selects how a server started with NewFaultServer misbehaves.
+type Fault int
+
+const (
+	// FaultStall sends the response headers and body, then keeps the
+	// response open without finishing it until the client disconnects,
+	// like a provider that stops producing tokens.
+	FaultStall Fault = iota
+	// FaultHalfWrite sends the headers and the first half of the body,
+	// then drops the connection mid-response.
+	FaultHalfWrite
+	// FaultReset reads the request and resets the connection without
+	// sending a response.
+	FaultReset
+)
+
+// Faults lists every Fault, for table-driven tests.
+var Faults = []Fault{FaultStall, FaultHalfWrite, FaultReset}
+
+func (f Fault) String() string {
+	switch f {
+	case FaultStall:
+		return "stall"
+	case FaultHalfWrite:
+		return "half-write"
+	case FaultReset:
+		return "reset"
+	}
+	return "fault(" + strconv.Itoa(int(f)) + ")"
+}
+
+// NewFaultServer starts a server that answers every request with
+// fault. contentType and body describe the response a healthy server
+// would send, for example a provider's JSON or SSE payload. The server
+// and its connections are closed when t finishes.
+func NewFaultServer(t testing.TB, fault Fault, contentType, body string) *httptest.Server {
+	t.Helper()
+	stop := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch fault {
+		case FaultStall:
+			w.Header().Set("Content-Type", contentType)
+			w.Write([]byte(body))
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+			case <-stop:
+			}
+		case FaultHalfWrite:
+			w.Header().Set("Content-Type", contentType)
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			w.Write([]byte(body[:len(body)/2]))
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		case FaultReset:
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				return
+			}
+			if tcp, ok := conn.(*net.TCPConn); ok {
+				tcp.SetLinger(0)
+			}
+			conn.Close()
+		}
+	}))
+	// Silence the server's log of aborted handlers.
+	srv.Config.ErrorLog = discardLogger
+	t.Cleanup(func() {
+		close(stop)
+		srv.Close()
+	})
+	return srv
+}
+
+// CancelAtRandom returns a copy of ctx that is cancelled after a random
+// delay in [0, maxDelay), drawn from r. Use a seeded r and log the seed so
+// failing cancellation points can be reproduced.
+func CancelAtRandom(ctx context.Context, r *rand.Rand, maxDelay time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(r.Int64N(int64(maxDelay))))
+}
+
+var discardLogger = log.New(io.Discard, "", 0)
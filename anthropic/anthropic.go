@@ -1,15 +1,16 @@
 package anthropic
 
 import (
-	"bufio"
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/ncecere/ai-sdk/provider"
 	"github.com/ncecere/ai-sdk/providerutil"
@@ -22,6 +23,31 @@ type Client struct {
 	apiKey     string
 	httpClient provider.HTTPClient
 	headers    http.Header
+	betas      []string
+	systemSep  string
+	systemOnly provider.SystemOnlyPolicy
+	onUnknown  func(source string, fields []string)
+	onDropped  func(source string, dropped []provider.DroppedOption)
+	strict     bool
+	// remoteImages is non-nil when ClientOptions.FetchRemoteImages is
+	// set.
+	remoteImages *provider.RemoteImageOptions
+	limits       providerutil.Limits
+	maxMessages  int
+	defaultUser  string
+	deadlineHint *provider.DeadlineHintOptions
+}
+
+// do sends req, bounding the size of a successful response body by
+// ClientOptions.MaxResponseBytes. Language-model streams are bounded by
+// content instead; see providerutil.Limits.LimitStream.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	c.limits.LimitBody(resp, "anthropic")
+	return resp, nil
 }
 
 // NewClient creates a new Anthropic client.
@@ -30,6 +56,8 @@ type Client struct {
 //   - ANTHROPIC_API_KEY (required if opts.APIKey is empty)
 //   - ANTHROPIC_BASE_URL (optional, defaults to https://api.anthropic.com)
 //   - ANTHROPIC_VERSION (optional, defaults to 2023-06-01)
+//   - ANTHROPIC_EXTRA_HEADERS (read with AI_SDK_DEFAULT_HEADERS if
+//     opts.HeadersFromEnv is set)
 func NewClient(opts provider.ClientOptions) (*Client, error) {
 	apiKey := opts.APIKey
 	if apiKey == "" {
@@ -48,12 +76,15 @@ func NewClient(opts provider.ClientOptions) (*Client, error) {
 	}
 	baseURL = strings.TrimRight(baseURL, "/")
 
-	hc := opts.HTTPClient
-	if hc == nil {
-		hc = providerutil.DefaultHTTPClient()
+	hc, err := providerutil.ClientHTTPClient(opts)
+	if err != nil {
+		return nil, fmt.Errorf("anthropic: %w", err)
 	}
 
-	headers := opts.Headers
+	headers, err := providerutil.ClientHeaders(opts, "ANTHROPIC_EXTRA_HEADERS")
+	if err != nil {
+		return nil, fmt.Errorf("anthropic: %w", err)
+	}
 	if headers == nil {
 		headers = make(http.Header)
 	}
@@ -67,12 +98,29 @@ func NewClient(opts provider.ClientOptions) (*Client, error) {
 		headers.Set("anthropic-version", version)
 	}
 
-	return &Client{
-		baseURL:    baseURL,
-		apiKey:     apiKey,
-		httpClient: hc,
-		headers:    headers,
-	}, nil
+	c := &Client{
+		baseURL:     baseURL,
+		apiKey:      apiKey,
+		httpClient:  hc,
+		headers:     headers,
+		betas:       opts.Betas,
+		systemSep:   opts.SystemSeparator,
+		systemOnly:  opts.SystemOnly,
+		onUnknown:   opts.OnUnknownFields,
+		onDropped:   opts.OnDroppedOptions,
+		strict:      opts.StrictOptions,
+		limits:      providerutil.NewLimits(opts),
+		maxMessages: opts.MaxMessages,
+		defaultUser: opts.DefaultUserID,
+	}
+	if opts.DeadlineHint != nil {
+		hint := *opts.DeadlineHint
+		c.deadlineHint = &hint
+	}
+	if opts.FetchRemoteImages {
+		c.remoteImages = &opts.RemoteImages
+	}
+	return c, nil
 }
 
 func (c *Client) messagesURL() string {
@@ -82,6 +130,34 @@ func (c *Client) messagesURL() string {
 	return c.baseURL + "/v1/messages"
 }
 
+// newRequest creates a JSON POST request to url with the client's
+// custom headers, beta header, and authentication applied. The betas
+// of req, if non-nil, are merged into the beta header.
+func (c *Client) newRequest(ctx context.Context, url string, body []byte, req *provider.LanguageModelRequest) (*http.Request, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range c.headers {
+		for _, v := range vs {
+			if v == "" {
+				continue
+			}
+			httpReq.Header.Add(k, v)
+		}
+	}
+	providerutil.SetContextHeaders(ctx, httpReq.Header, "x-api-key", "anthropic-version", "Content-Type")
+	var reqBetas []string
+	if req != nil {
+		reqBetas = req.Betas
+	}
+	providerutil.SetBetaHeader(httpReq.Header, betaHeader, c.betas, reqBetas)
+	providerutil.SetTraceHeader(ctx, httpReq.Header)
+	httpReq.Header.Set("x-api-key", c.apiKey)
+	httpReq.Header.Set("Content-Type", "application/json")
+	return httpReq, nil
+}
+
 // ChatModel returns a LanguageModel for the given Anthropic model ID.
 func (c *Client) ChatModel(model string) provider.LanguageModel {
 	return &messagesModel{client: c, model: model}
@@ -92,6 +168,15 @@ type messagesModel struct {
 	model  string
 }
 
+// MaxMessages implements provider.MessageLimiter with
+// ClientOptions.MaxMessages.
+func (m *messagesModel) MaxMessages() int {
+	if m.client == nil {
+		return 0
+	}
+	return m.client.maxMessages
+}
+
 const jsonToolName = "json"
 
 type anthropicMessage struct {
@@ -100,17 +185,86 @@ type anthropicMessage struct {
 }
 
 type anthropicContentBlock struct {
-	Type  string          `json:"type"`
-	Text  string          `json:"text,omitempty"`
-	ID    string          `json:"id,omitempty"`
-	Name  string          `json:"name,omitempty"`
-	Input json.RawMessage `json:"input,omitempty"`
+	Type   string                `json:"type"`
+	Text   string                `json:"text,omitempty"`
+	ID     string                `json:"id,omitempty"`
+	Name   string                `json:"name,omitempty"`
+	Input  json.RawMessage       `json:"input,omitempty"`
+	Source *anthropicImageSource `json:"source,omitempty"`
+	// Thinking and Signature hold the text and integrity signature of
+	// a "thinking" block, and Data the content of a
+	// "redacted_thinking" block.
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"`
+	// ToolUseID and Content are set on "tool_result" and
+	// "web_search_tool_result" blocks.
+	ToolUseID string                `json:"tool_use_id,omitempty"`
+	Content   anthropicBlockContent `json:"content,omitempty"`
+	// Citations are set on response "text" blocks supported by sources.
+	Citations []anthropicCitation `json:"citations,omitempty"`
+	// URL and Title are set on "web_search_result" blocks, and
+	// ErrorCode on "web_search_tool_result_error" blocks.
+	URL       string `json:"url,omitempty"`
+	Title     string `json:"title,omitempty"`
+	ErrorCode string `json:"error_code,omitempty"`
+}
+
+// anthropicBlockContent is the content of a tool_result or
+// web_search_tool_result block. A failed web search sends a single
+// error object instead of an array.
+type anthropicBlockContent []anthropicContentBlock
+
+func (c *anthropicBlockContent) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var block anthropicContentBlock
+		if err := json.Unmarshal(trimmed, &block); err != nil {
+			return err
+		}
+		*c = anthropicBlockContent{block}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]anthropicContentBlock)(c))
+}
+
+// anthropicCitation is a citation on a text block, such as a
+// web_search_result_location.
+type anthropicCitation struct {
+	Type      string `json:"type"`
+	URL       string `json:"url,omitempty"`
+	Title     string `json:"title,omitempty"`
+	CitedText string `json:"cited_text,omitempty"`
+}
+
+type anthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
 }
 
 type anthropicTool struct {
+	// Type is set for server tools such as web search.
+	Type        string          `json:"type,omitempty"`
 	Name        string          `json:"name"`
 	Description string          `json:"description,omitempty"`
 	InputSchema json.RawMessage `json:"input_schema,omitempty"`
+	// MaxUses, AllowedDomains, and BlockedDomains configure the web
+	// search server tool.
+	MaxUses        int      `json:"max_uses,omitempty"`
+	AllowedDomains []string `json:"allowed_domains,omitempty"`
+	BlockedDomains []string `json:"blocked_domains,omitempty"`
+}
+
+// webSearchTool returns the web search server tool for opts.
+func webSearchTool(opts *provider.WebSearchOptions) anthropicTool {
+	return anthropicTool{
+		Type:           "web_search_20250305",
+		Name:           "web_search",
+		MaxUses:        opts.MaxUses,
+		AllowedDomains: opts.AllowedDomains,
+		BlockedDomains: opts.BlockedDomains,
+	}
 }
 
 type anthropicMessagesRequest struct {
@@ -123,38 +277,121 @@ type anthropicMessagesRequest struct {
 	StopSequences []string           `json:"stop_sequences,omitempty"`
 	Tools         []anthropicTool    `json:"tools,omitempty"`
 	ToolChoice    any                `json:"tool_choice,omitempty"`
+	Thinking      *anthropicThinking `json:"thinking,omitempty"`
+	Metadata      *anthropicMetadata `json:"metadata,omitempty"`
 	Stream        bool               `json:"stream,omitempty"`
 }
 
+// anthropicMetadata is the request metadata object. Anthropic only
+// accepts an opaque user identifier.
+type anthropicMetadata struct {
+	UserID string `json:"user_id,omitempty"`
+}
+
 type anthropicMessagesResponse struct {
 	Content    []anthropicContentBlock `json:"content"`
 	StopReason string                  `json:"stop_reason"`
+	Usage      *anthropicUsage         `json:"usage"`
 }
 
-func (m *messagesModel) Generate(ctx context.Context, req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
+type anthropicUsage struct {
+	InputTokens              int                     `json:"input_tokens"`
+	OutputTokens             int                     `json:"output_tokens"`
+	CacheCreationInputTokens int                     `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int                     `json:"cache_read_input_tokens"`
+	ServerToolUse            *anthropicServerToolUse `json:"server_tool_use,omitempty"`
+}
+
+// anthropicServerToolUse counts server tool invocations.
+type anthropicServerToolUse struct {
+	WebSearchRequests int `json:"web_search_requests"`
+}
+
+// usage converts the wire usage, or returns nil if it was not reported.
+// Anthropic counts cached input separately from input_tokens; the
+// prompt tokens include both.
+func (u *anthropicUsage) usage() *provider.Usage {
+	if u == nil {
+		return nil
+	}
+	prompt := u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+	out := &provider.Usage{
+		PromptTokens:     prompt,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      prompt + u.OutputTokens,
+		Cache: &provider.CacheUsage{
+			CachedInputTokens: u.CacheReadInputTokens,
+			CacheWriteTokens:  u.CacheCreationInputTokens,
+		},
+	}
+	if u.ServerToolUse != nil {
+		out.WebSearchRequests = u.ServerToolUse.WebSearchRequests
+	}
+	return out
+}
+
+// merge applies the cumulative counts of a message_delta usage. Input
+// counts are only present in newer API versions, so zeros keep the
+// message_start values.
+func (u *anthropicUsage) merge(d *anthropicUsage) {
+	u.OutputTokens = d.OutputTokens
+	if d.InputTokens != 0 {
+		u.InputTokens = d.InputTokens
+	}
+	if d.CacheCreationInputTokens != 0 {
+		u.CacheCreationInputTokens = d.CacheCreationInputTokens
+	}
+	if d.CacheReadInputTokens != 0 {
+		u.CacheReadInputTokens = d.CacheReadInputTokens
+	}
+	if d.ServerToolUse != nil {
+		u.ServerToolUse = d.ServerToolUse
+	}
+}
+
+// buildBody maps a provider-level request onto the Anthropic Messages
+// API wire format. It reports whether the synthetic JSON tool is used
+// to emulate structured output.
+//
+// Anthropic takes a single top-level system prompt, so every system
+// message, wherever it appears, is hoisted into it in order and joined
+// with the client's system separator. Requests without a non-system
+// message are handled according to the system-only policy. With
+// FetchRemoteImages set, images referenced by URL are downloaded and
+// sent as base64.
+func (m *messagesModel) buildBody(ctx context.Context, req *provider.LanguageModelRequest, stream bool) (anthropicMessagesRequest, bool, error) {
+	policy := req.SystemOnly
+	if policy == "" {
+		policy = m.client.systemOnly
+	}
+	msgs, err := providerutil.EnsureConversation("anthropic", req.Messages, policy)
+	if err != nil {
+		return anthropicMessagesRequest{}, false, err
+	}
+	if m.client.remoteImages != nil {
+		msgs, err = providerutil.InlineRemoteImages(ctx, "anthropic", msgs, *m.client.remoteImages)
+		if err != nil {
+			return anthropicMessagesRequest{}, false, err
+		}
+	}
+
 	var systemParts []string
 	var messages []anthropicMessage
-	for _, msg := range req.Messages {
+	for _, msg := range msgs {
 		switch msg.Role {
 		case "system":
 			systemParts = append(systemParts, msg.Content)
 		case "tool":
-			// Anthropic does not support a dedicated tool role; map tool
-			// messages to user messages containing the tool result JSON.
+			messages = appendToolResult(messages, msg)
+		case "assistant":
 			messages = append(messages, anthropicMessage{
-				Role: "user",
-				Content: []anthropicContentBlock{{
-					Type: "text",
-					Text: msg.Content,
-				}},
+				Role:    msg.Role,
+				Content: assistantBlocks(msg),
 			})
 		default:
 			messages = append(messages, anthropicMessage{
-				Role: msg.Role,
-				Content: []anthropicContentBlock{{
-					Type: "text",
-					Text: msg.Content,
-				}},
+				Role:    msg.Role,
+				Content: contentBlocks(msg),
 			})
 		}
 	}
@@ -168,15 +405,31 @@ func (m *messagesModel) Generate(ctx context.Context, req *provider.LanguageMode
 		Model:     m.model,
 		Messages:  messages,
 		MaxTokens: maxTokens,
+		Stream:    stream,
 	}
 	if len(systemParts) > 0 {
-		body.System = strings.Join(systemParts, "\n")
+		body.System = providerutil.JoinSystem(systemParts, m.client.systemSep)
 	}
 	body.Temperature = req.Temperature
 	body.TopP = req.TopP
 	if len(req.Stop) > 0 {
 		body.StopSequences = req.Stop
 	}
+	userID := req.UserID
+	if userID == "" {
+		userID = req.Metadata[provider.MetadataUserID]
+	}
+	if userID = providerutil.UserID(ctx, userID, m.client.defaultUser); userID != "" {
+		body.Metadata = &anthropicMetadata{UserID: userID}
+	}
+	if thinking := thinkingConfig(req.Reasoning); thinking != nil {
+		body.Thinking = thinking
+		// max_tokens must exceed the thinking budget; leave the
+		// requested amount available for the visible answer.
+		if body.MaxTokens <= thinking.BudgetTokens {
+			body.MaxTokens += thinking.BudgetTokens
+		}
+	}
 
 	useJSONTool := false
 	if len(req.Tools) > 0 {
@@ -189,211 +442,388 @@ func (m *messagesModel) Generate(ctx context.Context, req *provider.LanguageMode
 			})
 		}
 		body.Tools = tools
-	} else if len(req.JSONSchema) > 0 {
+		if choice := toolChoice(req.ToolChoice, req.ParallelToolCalls); choice != nil {
+			body.ToolChoice = choice
+		}
+	} else if len(req.JSONSchema) > 0 || req.ResponseFormat == provider.ResponseFormatJSON {
+		// Anthropic has no JSON mode; JSON without a schema uses the
+		// same tool with a schema accepting any object.
+		schema := req.JSONSchema
+		if len(schema) == 0 {
+			schema = []byte(`{"type":"object"}`)
+		}
 		useJSONTool = true
 		body.Tools = []anthropicTool{{
 			Name:        jsonToolName,
 			Description: "Respond with a JSON object that matches the given schema.",
-			InputSchema: json.RawMessage(req.JSONSchema),
+			InputSchema: json.RawMessage(schema),
 		}}
-		body.ToolChoice = map[string]string{
-			"type": "tool",
-			"name": jsonToolName,
+		body.ToolChoice = &anthropicToolChoice{Type: "tool", Name: jsonToolName}
+	}
+	if req.WebSearch != nil {
+		body.Tools = append(body.Tools, webSearchTool(req.WebSearch))
+	}
+
+	return body, useJSONTool, nil
+}
+
+// anthropicToolChoice is the tool_choice object.
+type anthropicToolChoice struct {
+	Type                   string `json:"type"`
+	Name                   string `json:"name,omitempty"`
+	DisableParallelToolUse *bool  `json:"disable_parallel_tool_use,omitempty"`
+}
+
+// toolChoice maps choice and the parallel tool-call flag onto a
+// tool_choice object, or returns nil for the provider default.
+// "required" is Anthropic's "any"; a flag without a choice is sent
+// with type "auto". The flag is meaningless with "none" and dropped.
+func toolChoice(choice provider.ToolChoice, parallel *bool) *anthropicToolChoice {
+	var out *anthropicToolChoice
+	if name, ok := choice.Tool(); ok {
+		out = &anthropicToolChoice{Type: "tool", Name: name}
+	} else {
+		switch choice {
+		case "":
+			if parallel == nil {
+				return nil
+			}
+			out = &anthropicToolChoice{Type: "auto"}
+		case provider.ToolChoiceRequired:
+			out = &anthropicToolChoice{Type: "any"}
+		default:
+			out = &anthropicToolChoice{Type: string(choice)}
 		}
 	}
+	if parallel != nil && out.Type != string(provider.ToolChoiceNone) {
+		disable := !*parallel
+		out.DisableParallelToolUse = &disable
+	}
+	return out
+}
 
+// buildRequest constructs the HTTP request for a Messages API call. It
+// is shared by Generate, Stream, and BuildRequest so that dry runs
+// render exactly what would be sent.
+func (m *messagesModel) buildRequest(ctx context.Context, req *provider.LanguageModelRequest, stream bool) (*http.Request, []byte, bool, error) {
+	if err := providerutil.CheckDroppedOptions("anthropic", droppedOptions(req), m.client.strict); err != nil {
+		return nil, nil, false, err
+	}
+	body, useJSONTool, err := m.buildBody(ctx, req, stream)
+	if err != nil {
+		return nil, nil, false, err
+	}
 	buf, err := json.Marshal(body)
 	if err != nil {
-		return nil, err
+		return nil, nil, false, err
+	}
+	buf, _, err = providerutil.MergeBodyFields(buf, req.ProviderOptions)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	hint, err := providerutil.NewDeadlineHint(ctx, m.client.deadlineHint, time.Now())
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("anthropic: %w", err)
+	}
+	if buf, err = hint.MergeBody(buf); err != nil {
+		return nil, nil, false, err
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, m.client.messagesURL(), bytes.NewReader(buf))
+	httpReq, err := m.client.newRequest(ctx, m.client.messagesURL(), buf, req)
 	if err != nil {
-		return nil, err
+		return nil, nil, false, err
 	}
-	for k, vs := range m.client.headers {
-		for _, v := range vs {
-			if v == "" {
-				continue
-			}
-			httpReq.Header.Add(k, v)
-		}
+	hint.SetHeader(httpReq.Header)
+	if stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
 	}
-	httpReq.Header.Set("x-api-key", m.client.apiKey)
-	httpReq.Header.Set("Content-Type", "application/json")
+	return httpReq, buf, useJSONTool, nil
+}
 
-	resp, err := m.client.httpClient.Do(httpReq)
+// BuildRequest implements provider.RequestBuilder.
+func (m *messagesModel) BuildRequest(ctx context.Context, req *provider.LanguageModelRequest) (*http.Request, []byte, error) {
+	httpReq, buf, _, err := m.buildRequest(ctx, req, false)
+	return httpReq, buf, err
+}
+
+// BuildStreamRequest implements provider.StreamRequestBuilder.
+func (m *messagesModel) BuildStreamRequest(ctx context.Context, req *provider.LanguageModelRequest) (*http.Request, []byte, error) {
+	httpReq, buf, _, err := m.buildRequest(ctx, req, true)
+	return httpReq, buf, err
+}
+
+func (m *messagesModel) Generate(ctx context.Context, req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
+	httpReq, buf, useJSONTool, err := m.buildRequest(ctx, req, false)
 	if err != nil {
 		return nil, err
 	}
+	provider.ObserveRequest(ctx, httpReq, buf)
+	dropped := m.client.reportDropped(req)
 
-	var out anthropicMessagesResponse
-	if err := providerutil.ReadJSON(resp, &out); err != nil {
+	resp, err := m.client.do(httpReq)
+	if err != nil {
 		return nil, err
 	}
 
-	lmRes := &provider.LanguageModelResponse{}
+	var out anthropicMessagesResponse
+	var report func([]string)
+	if m.client.onUnknown != nil {
+		report = func(fields []string) { m.client.onUnknown("anthropic.messages", fields) }
+	}
+	if err := providerutil.ReadJSONReporting(resp, &out, report); err != nil {
+		return nil, providerutil.TagProvider(err, "anthropic")
+	}
+
+	lmRes := &provider.LanguageModelResponse{Metadata: providerutil.ResponseMetadata(resp)}
+	lmRes.Metadata.DroppedOptions = dropped
 	for _, c := range out.Content {
 		switch c.Type {
 		case "text":
+			start := len(lmRes.Text)
 			lmRes.Text += c.Text
+			for _, cit := range c.Citations {
+				lmRes.Citations = append(lmRes.Citations, provider.Citation{
+					URL:        cit.URL,
+					Title:      cit.Title,
+					Text:       cit.CitedText,
+					StartIndex: start,
+					EndIndex:   len(lmRes.Text),
+				})
+			}
+		case "web_search_tool_result":
+			// server_tool_use blocks, the model's search queries, need
+			// no handling: the provider ran them.
+			for _, r := range c.Content {
+				if r.Type == "web_search_result" {
+					lmRes.Metadata.SearchResults = append(lmRes.Metadata.SearchResults, provider.Citation{URL: r.URL, Title: r.Title})
+				}
+			}
+		case "thinking":
+			lmRes.Reasoning += c.Thinking
+			lmRes.ReasoningBlocks = append(lmRes.ReasoningBlocks, provider.ReasoningBlock{Text: c.Thinking, Signature: c.Signature})
+		case "redacted_thinking":
+			lmRes.ReasoningBlocks = append(lmRes.ReasoningBlocks, provider.ReasoningBlock{Redacted: c.Data})
 		case "tool_use":
+			if useJSONTool && c.Name == jsonToolName {
+				// The json tool carries the structured output, which is
+				// the final answer rather than a tool call.
+				if len(c.Input) > 0 && lmRes.Text == "" {
+					lmRes.Text = normalizeJSON(c.Input)
+				}
+				continue
+			}
 			lmRes.ToolCalls = append(lmRes.ToolCalls, provider.ToolCall{
 				ID:           c.ID,
 				Name:         c.Name,
 				RawArguments: c.Input,
 			})
-			if useJSONTool && c.Name == jsonToolName && len(c.Input) > 0 && lmRes.Text == "" {
-				lmRes.Text = normalizeJSON(c.Input)
-			}
 		}
 	}
+	if out.StopReason == "refusal" {
+		return nil, refusalError()
+	}
 	lmRes.StopReason = out.StopReason
+	if len(lmRes.ToolCalls) > 0 {
+		// Tool calls always decide the finish reason, even when the
+		// response was cut off by max_tokens after a tool_use block.
+		lmRes.StopReason = "tool_use"
+	}
+	lmRes.FinishReason = responseFinishReason(lmRes.StopReason, len(lmRes.ToolCalls) > 0, useJSONTool)
+	lmRes.Usage = out.Usage.usage()
+	lmRes.Warnings = betaWarnings(req, httpReq.Header)
+	if len(req.Tools) > 0 && len(lmRes.ToolCalls) == 0 {
+		// Anthropic has no separate tool-calls key; tool use appears as
+		// content blocks and a "tool_use" stop reason.
+		lmRes.ToolDiagnostics = &provider.ToolDiagnostics{
+			FinishReason:        out.StopReason,
+			ToolCallsKeyPresent: out.StopReason == "tool_use",
+			TextPresent:         lmRes.Text != "",
+		}
+	}
 	return lmRes, nil
 }
 
-func (m *messagesModel) Stream(ctx context.Context, req *provider.LanguageModelRequest) (provider.LanguageModelStream, error) {
-	var systemParts []string
-	var messages []anthropicMessage
-	for _, msg := range req.Messages {
-		switch msg.Role {
-		case "system":
-			systemParts = append(systemParts, msg.Content)
-		case "tool":
-			messages = append(messages, anthropicMessage{
-				Role: "user",
-				Content: []anthropicContentBlock{{
-					Type: "text",
-					Text: msg.Content,
-				}},
-			})
-		default:
-			messages = append(messages, anthropicMessage{
-				Role: msg.Role,
-				Content: []anthropicContentBlock{{
-					Type: "text",
-					Text: msg.Content,
-				}},
-			})
+// finishReason maps an Anthropic stop_reason onto a
+// provider.FinishReason. "tool_use" without tool calls counts as other.
+func finishReason(stop string, toolCalls bool) provider.FinishReason {
+	switch stop {
+	case "":
+		return ""
+	case "end_turn", "stop_sequence":
+		return provider.FinishReasonStop
+	case "max_tokens":
+		return provider.FinishReasonLength
+	case "tool_use":
+		if toolCalls {
+			return provider.FinishReasonToolCalls
 		}
 	}
+	return provider.FinishReasonOther
+}
 
-	maxTokens := 1024
-	if req.MaxTokens != nil && *req.MaxTokens > 0 {
-		maxTokens = *req.MaxTokens
-	}
-
-	body := anthropicMessagesRequest{
-		Model:     m.model,
-		Messages:  messages,
-		MaxTokens: maxTokens,
-		Stream:    true,
-	}
-	if len(systemParts) > 0 {
-		body.System = strings.Join(systemParts, "\n")
-	}
-	body.Temperature = req.Temperature
-	body.TopP = req.TopP
-	if len(req.Stop) > 0 {
-		body.StopSequences = req.Stop
-	}
-
-	if len(req.Tools) > 0 {
-		tools := make([]anthropicTool, 0, len(req.Tools))
-		for _, t := range req.Tools {
-			tools = append(tools, anthropicTool{
-				Name:        t.Name,
-				Description: t.Description,
-				InputSchema: json.RawMessage(t.Parameters),
-			})
-		}
-		body.Tools = tools
-	} else if len(req.JSONSchema) > 0 {
-		body.Tools = []anthropicTool{{
-			Name:        jsonToolName,
-			Description: "Respond with a JSON object that matches the given schema.",
-			InputSchema: json.RawMessage(req.JSONSchema),
-		}}
-		body.ToolChoice = map[string]string{
-			"type": "tool",
-			"name": jsonToolName,
-		}
-	}
+// refusalError is returned in place of a response whose stop_reason is
+// "refusal", so refusals are distinguishable from empty answers.
+func refusalError() error {
+	return &provider.ContentBlockedError{Provider: "anthropic", Reason: "refusal"}
+}
 
-	buf, err := json.Marshal(body)
-	if err != nil {
-		return nil, err
+// responseFinishReason is finishReason for a response to a request
+// that forced the json tool when jsonTool is set: the json tool's
+// tool_use then ends a complete answer, like end_turn.
+func responseFinishReason(stop string, toolCalls, jsonTool bool) provider.FinishReason {
+	if jsonTool && stop == "tool_use" && !toolCalls {
+		return provider.FinishReasonStop
 	}
+	return finishReason(stop, toolCalls)
+}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, m.client.messagesURL(), bytes.NewReader(buf))
+// Stream starts a streaming Messages API call. The stream lives until
+// ctx is done or the stream is closed; see provider.LanguageModelStream.
+func (m *messagesModel) Stream(ctx context.Context, req *provider.LanguageModelRequest) (provider.LanguageModelStream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	httpReq, buf, useJSONTool, err := m.buildRequest(ctx, req, true)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
-	for k, vs := range m.client.headers {
-		for _, v := range vs {
-			if v == "" {
-				continue
-			}
-			httpReq.Header.Add(k, v)
-		}
-	}
-	httpReq.Header.Set("x-api-key", m.client.apiKey)
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Accept", "text/event-stream")
+	provider.ObserveRequest(ctx, httpReq, buf)
+	dropped := m.client.reportDropped(req)
 
 	resp, err := m.client.httpClient.Do(httpReq)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
+	if err := providerutil.CheckStatus(resp); err != nil {
+		cancel()
+		return nil, providerutil.TagProvider(err, "anthropic")
+	}
 
-	return newMessagesStream(resp.Body), nil
+	stream := newMessagesStream(ctx, resp.Body, cancel, useJSONTool)
+	stream.metadata = providerutil.ResponseMetadata(resp)
+	stream.metadata.DroppedOptions = dropped
+	stream.warnings = betaWarnings(req, httpReq.Header)
+	return m.client.limits.LimitStream(stream, "anthropic"), nil
 }
 
-// messagesStream implements provider.LanguageModelStream for Anthropic messages.
-
+// messagesStream implements provider.LanguageModelStream for Anthropic
+// messages. tool_use blocks are assembled from their input_json_delta
+// fragments and delivered whole on the final delta, after all text,
+// except that the input of the json tool used for structured output is
+// streamed as Text, as Generate returns it. A body that ends before
+// message_stop fails with a
+// *provider.IncompleteStreamError, an error event such as
+// overloaded_error with a *provider.APIError, and a "refusal" stop with
+// a *provider.ContentBlockedError.
 type messagesStream struct {
-	body    io.ReadCloser
-	scanner *bufio.Scanner
-	done    bool
+	lines *providerutil.LineReader
+	done  bool
+	// err is an in-band error, returned by every later call.
+	err error
+	// toolUse holds the tool_use blocks seen so far, by block index.
+	toolUse map[int]*streamedToolUse
+	order   []int
+	// usage accumulates message_start and message_delta usage.
+	usage *anthropicUsage
+	// stop is the stop_reason of message_delta. metadata and warnings
+	// describe the response.
+	stop     string
+	metadata *provider.ResponseMetadata
+	warnings []string
+	// text and thinking reassemble characters split across events.
+	text, thinking providerutil.UTF8Joiner
+	// jsonTool is set when the request forced the json tool, and
+	// jsonBlock is the index of its tool_use block, or -1.
+	jsonTool  bool
+	jsonBlock int
 }
 
-func newMessagesStream(body io.ReadCloser) provider.LanguageModelStream {
-	scanner := bufio.NewScanner(body)
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 1024*1024)
-	return &messagesStream{
-		body:    body,
-		scanner: scanner,
-	}
+type streamedToolUse struct {
+	id, name string
+	input    strings.Builder
+}
+
+func newMessagesStream(ctx context.Context, body io.ReadCloser, cancel context.CancelFunc, jsonTool bool) *messagesStream {
+	return &messagesStream{lines: providerutil.NewLineReader(ctx, body, cancel, 1024*1024), jsonTool: jsonTool, jsonBlock: -1}
 }
 
 type anthropicStreamEvent struct {
-	Type  string          `json:"type"`
-	Delta *anthropicDelta `json:"delta,omitempty"`
+	Type         string                 `json:"type"`
+	Index        int                    `json:"index"`
+	ContentBlock *anthropicContentBlock `json:"content_block,omitempty"`
+	Delta        *anthropicDelta        `json:"delta,omitempty"`
+	// Message is set on message_start, carrying the input usage.
+	Message *struct {
+		Usage *anthropicUsage `json:"usage"`
+	} `json:"message,omitempty"`
+	// Usage is set on message_delta. Its counts are cumulative.
+	Usage *anthropicUsage `json:"usage,omitempty"`
 }
 
 type anthropicDelta struct {
 	Type string `json:"type"`
-	Text string `json:"text,omitempty"`
+	// Text fields keep invalid UTF-8 so characters split across events
+	// can be reassembled.
+	Text        providerutil.RawString `json:"text,omitempty"`
+	Thinking    providerutil.RawString `json:"thinking,omitempty"`
+	PartialJSON string                 `json:"partial_json,omitempty"`
+	// StopReason is set on message_delta.
+	StopReason string `json:"stop_reason,omitempty"`
+}
+
+// final returns the last delta of the stream, carrying the assembled
+// tool calls in block order and the stop reason.
+func (s *messagesStream) final() *provider.LanguageModelDelta {
+	s.done = true
+	delta := &provider.LanguageModelDelta{
+		Text:       s.text.Flush(),
+		Reasoning:  s.thinking.Flush(),
+		Done:       true,
+		Usage:      s.usage.usage(),
+		StopReason: s.stop,
+		Warnings:   s.warnings,
+		Metadata:   s.metadata,
+	}
+	for _, i := range s.order {
+		t := s.toolUse[i]
+		input := json.RawMessage(t.input.String())
+		if len(input) == 0 {
+			input = json.RawMessage("{}")
+		}
+		delta.ToolCalls = append(delta.ToolCalls, provider.ToolCall{ID: t.id, Name: t.name, RawArguments: input})
+	}
+	s.toolUse, s.order = nil, nil
+	if len(delta.ToolCalls) > 0 {
+		delta.StopReason = "tool_use"
+	}
+	delta.FinishReason = responseFinishReason(delta.StopReason, len(delta.ToolCalls) > 0, s.jsonTool)
+	return delta
+}
+
+// finish ends the stream with the final delta, or with a
+// *provider.ContentBlockedError if the model refused to answer.
+func (s *messagesStream) finish() (*provider.LanguageModelDelta, error) {
+	if s.stop == "refusal" {
+		s.err = refusalError()
+		return nil, s.err
+	}
+	return s.final(), nil
 }
 
 func (s *messagesStream) Next(ctx context.Context) (*provider.LanguageModelDelta, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
 	if s.done {
 		return &provider.LanguageModelDelta{Done: true}, nil
 	}
 
 	for {
-		if err := ctx.Err(); err != nil {
-			return nil, err
+		line, err := s.lines.Next(ctx)
+		if err != nil {
+			return nil, providerutil.IncompleteStream("anthropic", err)
 		}
-		if !s.scanner.Scan() {
-			if err := s.scanner.Err(); err != nil {
-				return nil, err
-			}
-			s.done = true
-			return &provider.LanguageModelDelta{Done: true}, nil
-		}
-		line := strings.TrimSpace(s.scanner.Text())
+		line = strings.TrimSpace(line)
 		if line == "" {
 			continue
 		}
@@ -402,8 +832,7 @@ func (s *messagesStream) Next(ctx context.Context) (*provider.LanguageModelDelta
 		}
 		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
 		if data == "[DONE]" {
-			s.done = true
-			return &provider.LanguageModelDelta{Done: true}, nil
+			return s.finish()
 		}
 
 		var ev anthropicStreamEvent
@@ -412,20 +841,186 @@ func (s *messagesStream) Next(ctx context.Context) (*provider.LanguageModelDelta
 		}
 
 		switch ev.Type {
+		case "content_block_start":
+			if b := ev.ContentBlock; b != nil && b.Type == "tool_use" && s.jsonTool && b.Name == jsonToolName && s.jsonBlock < 0 {
+				s.jsonBlock = ev.Index
+			} else if b != nil && b.Type == "tool_use" {
+				if s.toolUse == nil {
+					s.toolUse = make(map[int]*streamedToolUse)
+				}
+				s.toolUse[ev.Index] = &streamedToolUse{id: b.ID, name: b.Name}
+				s.order = append(s.order, ev.Index)
+			}
 		case "content_block_delta":
-			if ev.Delta != nil && ev.Delta.Type == "text_delta" && ev.Delta.Text != "" {
-				return &provider.LanguageModelDelta{Text: ev.Delta.Text}, nil
+			if ev.Index == s.jsonBlock && ev.Delta != nil && ev.Delta.Type == "input_json_delta" {
+				if ev.Delta.PartialJSON != "" {
+					return &provider.LanguageModelDelta{Text: ev.Delta.PartialJSON}, nil
+				}
+				continue
+			}
+			if t := s.toolUse[ev.Index]; t != nil && ev.Delta != nil && ev.Delta.Type == "input_json_delta" {
+				t.input.WriteString(ev.Delta.PartialJSON)
+				continue
+			}
+			if ev.Delta != nil && ev.Delta.Type == "text_delta" {
+				if text := s.text.Join(string(ev.Delta.Text)); text != "" {
+					return &provider.LanguageModelDelta{Text: text}, nil
+				}
+			}
+			if ev.Delta != nil && ev.Delta.Type == "thinking_delta" {
+				if thinking := s.thinking.Join(string(ev.Delta.Thinking)); thinking != "" {
+					return &provider.LanguageModelDelta{Reasoning: thinking}, nil
+				}
+			}
+		case "message_start":
+			if ev.Message != nil && ev.Message.Usage != nil {
+				s.usage = ev.Message.Usage
+			}
+		case "message_delta":
+			if ev.Delta != nil && ev.Delta.StopReason != "" {
+				s.stop = ev.Delta.StopReason
+			}
+			if u := ev.Usage; u != nil {
+				if s.usage == nil {
+					s.usage = &anthropicUsage{}
+				}
+				s.usage.merge(u)
 			}
 		case "message_stop":
-			s.done = true
-			return &provider.LanguageModelDelta{Done: true}, nil
+			return s.finish()
+		case "error":
+			if apiErr := providerutil.StreamEventError("anthropic", []byte(data)); apiErr != nil {
+				s.err = apiErr
+				return nil, s.err
+			}
 		}
 	}
 }
 
 func (s *messagesStream) Close() error {
 	s.done = true
-	return s.body.Close()
+	return s.lines.Close()
+}
+
+// assistantBlocks maps an assistant message onto its reasoning blocks,
+// content blocks, and a tool_use block per tool call. Reasoning blocks
+// are only sent with tool calls, where thinking requires them first.
+// Empty text is left out, as Anthropic rejects empty text blocks.
+func assistantBlocks(msg provider.Message) []anthropicContentBlock {
+	if len(msg.ToolCalls) == 0 {
+		return contentBlocks(msg)
+	}
+	var blocks []anthropicContentBlock
+	for _, r := range msg.ReasoningBlocks {
+		if r.Redacted != "" {
+			blocks = append(blocks, anthropicContentBlock{Type: "redacted_thinking", Data: r.Redacted})
+			continue
+		}
+		blocks = append(blocks, anthropicContentBlock{Type: "thinking", Thinking: r.Text, Signature: r.Signature})
+	}
+	if msg.Content != "" || len(msg.Parts) > 0 {
+		blocks = append(blocks, contentBlocks(msg)...)
+	}
+	for _, tc := range msg.ToolCalls {
+		blocks = append(blocks, anthropicContentBlock{Type: "tool_use", ID: tc.ID, Name: tc.Name, Input: toolInput(tc.RawArguments)})
+	}
+	return blocks
+}
+
+// toolInput returns tool call arguments as the JSON object Anthropic
+// expects. Arguments held as a JSON string (as the OpenAI client returns
+// them) are unwrapped, and missing arguments become {}.
+func toolInput(args []byte) json.RawMessage {
+	var s string
+	if json.Unmarshal(args, &s) == nil {
+		args = []byte(s)
+	}
+	if len(bytes.TrimSpace(args)) == 0 {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(args)
+}
+
+// appendToolResult appends a tool message to messages. Anthropic has no
+// tool role: results are tool_result blocks in a user message, and all
+// results for one assistant turn must share that message. A message
+// whose ToolCallID names a tool_use block of the preceding assistant
+// message becomes such a block; any other tool message is sent as
+// plain user content, since a tool_result without its tool_use is
+// rejected.
+func appendToolResult(messages []anthropicMessage, msg provider.Message) []anthropicMessage {
+	n := len(messages)
+	assistant := n - 1
+	if n > 0 && messages[n-1].Role == "user" && isToolResults(messages[n-1]) {
+		assistant = n - 2
+	}
+	if msg.ToolCallID == "" || assistant < 0 || messages[assistant].Role != "assistant" || !hasToolUse(messages[assistant], msg.ToolCallID) {
+		return append(messages, anthropicMessage{Role: "user", Content: contentBlocks(msg)})
+	}
+	block := anthropicContentBlock{Type: "tool_result", ToolUseID: msg.ToolCallID, Content: contentBlocks(msg)}
+	if assistant == n-2 {
+		messages[n-1].Content = append(messages[n-1].Content, block)
+		return messages
+	}
+	return append(messages, anthropicMessage{Role: "user", Content: []anthropicContentBlock{block}})
+}
+
+// isToolResults reports whether m consists only of tool_result blocks.
+func isToolResults(m anthropicMessage) bool {
+	for _, b := range m.Content {
+		if b.Type != "tool_result" {
+			return false
+		}
+	}
+	return len(m.Content) > 0
+}
+
+// hasToolUse reports whether m contains a tool_use block with id.
+func hasToolUse(m anthropicMessage, id string) bool {
+	for _, b := range m.Content {
+		if b.Type == "tool_use" && b.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// contentBlocks maps a message's content onto Anthropic content blocks,
+// using Parts when present.
+func contentBlocks(msg provider.Message) []anthropicContentBlock {
+	if len(msg.Parts) == 0 {
+		return []anthropicContentBlock{{Type: "text", Text: msg.Content}}
+	}
+	blocks := make([]anthropicContentBlock, 0, len(msg.Parts))
+	for _, p := range msg.Parts {
+		switch p.Type {
+		case provider.ContentPartText:
+			blocks = append(blocks, anthropicContentBlock{Type: "text", Text: p.Text})
+		case provider.ContentPartImage:
+			blocks = append(blocks, anthropicContentBlock{Type: "image", Source: imageSource(p)})
+		}
+	}
+	if len(blocks) == 0 {
+		blocks = append(blocks, anthropicContentBlock{Type: "text", Text: msg.Content})
+	}
+	return blocks
+}
+
+// imageSource maps an image part onto an Anthropic image source, using
+// base64 data when inline bytes are available.
+func imageSource(p provider.ContentPart) *anthropicImageSource {
+	if len(p.Data) > 0 {
+		mt := p.MimeType
+		if mt == "" {
+			mt = "image/png"
+		}
+		return &anthropicImageSource{
+			Type:      "base64",
+			MediaType: mt,
+			Data:      base64.StdEncoding.EncodeToString(p.Data),
+		}
+	}
+	return &anthropicImageSource{Type: "url", URL: p.ImageURL}
 }
 
 func normalizeJSON(raw json.RawMessage) string {
@@ -443,3 +1038,23 @@ func normalizeJSON(raw json.RawMessage) string {
 	}
 	return string(normalized)
 }
+
+// Factory is a provider.Factory that creates Anthropic clients. Register
+// it with registry.RegisterProviderFactory to resolve
+// "anthropic:<model>" references.
+type Factory struct{}
+
+// Ensure Factory implements provider.Factory.
+var _ provider.Factory = Factory{}
+
+// Name implements provider.Factory.
+func (Factory) Name() string { return "anthropic" }
+
+// NewClient implements provider.Factory.
+func (Factory) NewClient(opts provider.ClientOptions) (provider.Provider, error) {
+	c, err := NewClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,878 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/aitest"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+func TestMessagesModelGenerate_ComposesBetaHeaderAndWarns(t *testing.T) {
+	ctx := context.Background()
+
+	var gotBeta string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBeta = r.Header.Get("anthropic-beta")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn"}`)
+	}))
+	defer ts.Close()
+
+	headers := make(http.Header)
+	headers.Set("anthropic-beta", "custom-beta")
+	client, err := NewClient(provider.ClientOptions{
+		BaseURL:    ts.URL,
+		APIKey:     "test",
+		HTTPClient: ts.Client(),
+		Headers:    headers,
+		Betas:      []string{BetaPromptCaching, "custom-beta"},
+	})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	maxTokens := 100000
+	res, err := client.ChatModel("claude-test").Generate(ctx, &provider.LanguageModelRequest{
+		Messages:  []provider.Message{{Role: "user", Content: "hi"}},
+		MaxTokens: &maxTokens,
+		Betas:     []string{BetaTokenEfficientTools},
+	})
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+
+	want := "custom-beta," + BetaPromptCaching + "," + BetaTokenEfficientTools
+	if gotBeta != want {
+		t.Fatalf("unexpected anthropic-beta header: got %q, want %q", gotBeta, want)
+	}
+	if len(res.Warnings) != 1 || !strings.Contains(res.Warnings[0], BetaOutput128k) {
+		t.Fatalf("expected output beta warning, got %v", res.Warnings)
+	}
+
+	res, err = client.ChatModel("claude-test").Generate(ctx, &provider.LanguageModelRequest{
+		Messages:  []provider.Message{{Role: "user", Content: "hi"}},
+		MaxTokens: &maxTokens,
+		Betas:     []string{BetaOutput128k},
+	})
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	if len(res.Warnings) != 0 {
+		t.Fatalf("expected no warnings with output beta, got %v", res.Warnings)
+	}
+}
+
+func TestMessagesModelGenerate_ParsesAPIErrorParam(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"type":"error","error":{"type":"invalid_request_error","message":"reasonig_effort: Extra inputs are not permitted"}}`, http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	_, err = client.ChatModel("claude-test").Generate(context.Background(), &provider.LanguageModelRequest{
+		Messages: []provider.Message{{Role: "user", Content: "hi"}},
+	})
+	var apiErr *provider.APIError
+	if !errors.As(err, &apiErr) || apiErr.Provider != "anthropic" || apiErr.Param != "reasonig_effort" || apiErr.Type != "invalid_request_error" {
+		t.Fatalf("expected APIError naming the parameter, got %#v", err)
+	}
+	if want := `anthropic: http status 400 (param "reasonig_effort"): reasonig_effort: Extra inputs are not permitted`; err.Error() != want {
+		t.Fatalf("unexpected message %q", err.Error())
+	}
+}
+
+func TestClientCountTokens_MapsSystemAndTools(t *testing.T) {
+	var got anthropicCountTokensRequest
+	var path string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"input_tokens":42}`)
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	n, err := client.CountTokens(context.Background(), "claude-test", &provider.LanguageModelRequest{
+		Messages: []provider.Message{
+			{Role: "system", Content: "be brief"},
+			{Role: "user", Content: "weather?"},
+		},
+		Tools: []provider.ToolDefinition{{Name: "weather", Description: "Get weather", Parameters: []byte(`{"type":"object"}`)}},
+	})
+	if err != nil {
+		t.Fatalf("CountTokens error: %v", err)
+	}
+	if n != 42 || path != "/v1/messages/count_tokens" {
+		t.Fatalf("unexpected result: n=%d path=%s", n, path)
+	}
+	if got.Model != "claude-test" || got.System != "be brief" || len(got.Messages) != 1 || len(got.Tools) != 1 || got.Tools[0].Name != "weather" {
+		t.Fatalf("unexpected count request: %+v", got)
+	}
+}
+
+// pngBytes is the PNG signature followed by padding, enough for content
+// sniffing.
+var pngBytes = append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 24)...)
+
+func TestMessagesModelBuildRequest_FetchesRemoteImages(t *testing.T) {
+	var fetches int
+	images := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		switch r.URL.Path {
+		case "/cat.png":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(pngBytes)
+		case "/page.html":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, "<html></html>")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer images.Close()
+
+	client, err := NewClient(provider.ClientOptions{APIKey: "test", FetchRemoteImages: true})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	model := client.ChatModel("claude-test").(provider.RequestBuilder)
+	image := func(url string) provider.ContentPart {
+		return provider.ContentPart{Type: provider.ContentPartImage, ImageURL: url}
+	}
+
+	req := &provider.LanguageModelRequest{Messages: []provider.Message{{
+		Role:  "user",
+		Parts: []provider.ContentPart{{Type: provider.ContentPartText, Text: "compare"}, image(images.URL + "/cat.png"), image(images.URL + "/cat.png")},
+	}}}
+	_, body, err := model.BuildRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("BuildRequest error: %v", err)
+	}
+	var sent anthropicMessagesRequest
+	if err := json.Unmarshal(body, &sent); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	for _, block := range sent.Messages[0].Content[1:] {
+		if block.Source == nil || block.Source.Type != "base64" || block.Source.MediaType != "image/png" || block.Source.URL != "" {
+			t.Fatalf("expected an inline PNG source, got %+v", block.Source)
+		}
+	}
+	if fetches != 1 {
+		t.Fatalf("expected the repeated URL to be fetched once, got %d fetches", fetches)
+	}
+	if req.Messages[0].Parts[1].Data != nil {
+		t.Fatalf("caller's request was modified")
+	}
+
+	req.Messages = append(req.Messages, provider.Message{Role: "user", Parts: []provider.ContentPart{image(images.URL + "/page.html?X-Amz-Signature=secret")}})
+	_, _, err = model.BuildRequest(context.Background(), req)
+	var imgErr *provider.RemoteImageError
+	if !errors.As(err, &imgErr) || imgErr.Message != 1 || imgErr.Part != 0 || imgErr.URL != images.URL+"/page.html?X-Amz-Signature=secret" {
+		t.Fatalf("expected RemoteImageError for message 1 part 0, got %#v", err)
+	}
+	if strings.Contains(err.Error(), "secret") || !strings.Contains(err.Error(), `content type "text/html" is not allowed`) {
+		t.Fatalf("unexpected message %q", err.Error())
+	}
+
+	client.remoteImages.MaxBytes = 8
+	req.Messages = req.Messages[:1]
+	if _, _, err := model.BuildRequest(context.Background(), req); !errors.As(err, &imgErr) || imgErr.Part != 1 {
+		t.Fatalf("expected RemoteImageError for an oversized image, got %v", err)
+	}
+}
+
+func TestMessagesModelGenerate_ParsesUsage(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn",
+			"usage":{"input_tokens":10,"cache_creation_input_tokens":100,"cache_read_input_tokens":1000,"output_tokens":7}}`)
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	res, err := client.ChatModel("claude-test").Generate(context.Background(), &provider.LanguageModelRequest{
+		Messages: []provider.Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	want := provider.Usage{
+		PromptTokens: 1110, CompletionTokens: 7, TotalTokens: 1117,
+		Cache: &provider.CacheUsage{CachedInputTokens: 1000, CacheWriteTokens: 100},
+	}
+	if !reflect.DeepEqual(res.Usage, &want) {
+		t.Fatalf("Usage = %+v, want %+v", res.Usage, want)
+	}
+}
+
+func TestMessagesModelBuildBody_AssistantToolCalls(t *testing.T) {
+	m := &messagesModel{client: &Client{}, model: "claude-test"}
+	body, _, err := m.buildBody(context.Background(), &provider.LanguageModelRequest{
+		Messages: []provider.Message{
+			{Role: "user", Content: "add 3 and 5"},
+			{Role: "assistant", Content: "Adding.", ToolCalls: []provider.ToolCall{
+				{ID: "toolu_1", Name: "add", RawArguments: []byte(`"{\"a\":3,\"b\":5}"`)},
+			}},
+			{Role: "tool", Content: "8", ToolCallID: "toolu_1"},
+		},
+	}, false)
+	if err != nil {
+		t.Fatalf("buildBody error: %v", err)
+	}
+
+	data, err := json.Marshal(body.Messages[1:])
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	want := `[{"role":"assistant","content":[{"type":"text","text":"Adding."},{"type":"tool_use","id":"toolu_1","name":"add","input":{"a":3,"b":5}}]},` +
+		`{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":[{"type":"text","text":"8"}]}]}]`
+	if string(data) != want {
+		t.Fatalf("unexpected messages:\n got: %s\nwant: %s", data, want)
+	}
+}
+
+func TestAppendToolResult_PairsWithToolUse(t *testing.T) {
+	assistant := anthropicMessage{Role: "assistant", Content: []anthropicContentBlock{
+		{Type: "tool_use", ID: "toolu_1", Name: "add"},
+		{Type: "tool_use", ID: "toolu_2", Name: "add"},
+	}}
+	messages := []anthropicMessage{{Role: "user", Content: []anthropicContentBlock{{Type: "text", Text: "add"}}}, assistant}
+	messages = appendToolResult(messages, provider.Message{Role: "tool", Content: "8", ToolCallID: "toolu_1"})
+	messages = appendToolResult(messages, provider.Message{Role: "tool", Content: "9", ToolCallID: "toolu_2"})
+	messages = appendToolResult(messages, provider.Message{Role: "tool", Content: "orphan", ToolCallID: "toolu_3"})
+
+	data, err := json.Marshal(messages[2:])
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	want := `[{"role":"user","content":[` +
+		`{"type":"tool_result","tool_use_id":"toolu_1","content":[{"type":"text","text":"8"}]},` +
+		`{"type":"tool_result","tool_use_id":"toolu_2","content":[{"type":"text","text":"9"}]}]},` +
+		`{"role":"user","content":[{"type":"text","text":"orphan"}]}]`
+	if string(data) != want {
+		t.Fatalf("unexpected messages:\n got: %s\nwant: %s", data, want)
+	}
+}
+
+func TestMessagesModel_ResponseAndStreamLimits(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Stream bool }
+		json.NewDecoder(r.Body).Decode(&body)
+		if !body.Stream {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"content":[{"type":"text","text":"%s"}],"stop_reason":"end_turn"}`, strings.Repeat("a", 200))
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for range 3 {
+			fmt.Fprint(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"abcd\"}}\n\n")
+		}
+		fmt.Fprint(w, "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client(), MaxResponseBytes: 100, MaxStreamBytes: 10})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	model := client.ChatModel("claude-test")
+	req := &provider.LanguageModelRequest{Messages: []provider.Message{{Role: "user", Content: "hi"}}}
+
+	_, err = model.Generate(context.Background(), req)
+	var tooLarge *provider.ResponseTooLargeError
+	if !errors.As(err, &tooLarge) || tooLarge.Provider != "anthropic" || tooLarge.Limit != 100 {
+		t.Fatalf("expected ResponseTooLargeError, got %T: %v", err, err)
+	}
+
+	// Streams are bounded by content, not by MaxResponseBytes.
+	stream, err := model.Stream(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Stream error: %v", err)
+	}
+	defer stream.Close()
+	for {
+		delta, err := stream.Next(context.Background())
+		if err != nil {
+			var truncated *provider.StreamTruncatedError
+			if !errors.As(err, &truncated) || truncated.Provider != "anthropic" || truncated.Limit != "bytes" || truncated.Text != "abcdabcd" {
+				t.Fatalf("expected StreamTruncatedError after 8 bytes, got %v", err)
+			}
+			break
+		}
+		if delta.Done {
+			t.Fatalf("expected the stream to be truncated")
+		}
+	}
+}
+
+func TestMessagesModelBuildBody_ToolChoice(t *testing.T) {
+	m := &messagesModel{client: &Client{}, model: "claude-test"}
+	tools := []provider.ToolDefinition{{Name: "weather", Parameters: []byte(`{"type":"object"}`)}}
+	for _, tc := range []struct {
+		choice provider.ToolChoice
+		want   string
+	}{
+		{provider.ToolChoiceAuto, `"tool_choice":{"type":"auto"}`},
+		{provider.ToolChoiceNone, `"tool_choice":{"type":"none"}`},
+		{provider.ToolChoiceRequired, `"tool_choice":{"type":"any"}`},
+		{provider.ToolChoiceTool("weather"), `"tool_choice":{"type":"tool","name":"weather"}`},
+	} {
+		t.Run(string(tc.choice), func(t *testing.T) {
+			body, _, err := m.buildBody(context.Background(), &provider.LanguageModelRequest{
+				Messages:   []provider.Message{{Role: "user", Content: "hi"}},
+				Tools:      tools,
+				ToolChoice: tc.choice,
+			}, false)
+			if err != nil {
+				t.Fatalf("buildBody error: %v", err)
+			}
+			raw, _ := json.Marshal(body)
+			if !strings.Contains(string(raw), tc.want) {
+				t.Fatalf("expected %s in %s", tc.want, raw)
+			}
+		})
+	}
+
+	// Without tools the JSON-schema emulation keeps forcing its own tool.
+	body, useJSONTool, err := m.buildBody(context.Background(), &provider.LanguageModelRequest{
+		Messages:   []provider.Message{{Role: "user", Content: "hi"}},
+		JSONSchema: []byte(`{"type":"object"}`),
+		ToolChoice: provider.ToolChoiceNone,
+	}, false)
+	if err != nil {
+		t.Fatalf("buildBody error: %v", err)
+	}
+	raw, _ := json.Marshal(body)
+	if !useJSONTool || !strings.Contains(string(raw), `"tool_choice":{"type":"tool","name":"`+jsonToolName+`"}`) {
+		t.Fatalf("expected the JSON tool to be forced, got %s", raw)
+	}
+}
+
+func TestToolChoice_DisableParallelToolUse(t *testing.T) {
+	enabled, disabled := true, false
+	for _, tc := range []struct {
+		choice   provider.ToolChoice
+		parallel *bool
+		want     string
+	}{
+		{"", &disabled, `{"type":"auto","disable_parallel_tool_use":true}`},
+		{"", &enabled, `{"type":"auto","disable_parallel_tool_use":false}`},
+		{provider.ToolChoiceRequired, &disabled, `{"type":"any","disable_parallel_tool_use":true}`},
+		{provider.ToolChoiceTool("step"), &disabled, `{"type":"tool","name":"step","disable_parallel_tool_use":true}`},
+		{provider.ToolChoiceNone, &disabled, `{"type":"none"}`},
+		{"", nil, `null`},
+	} {
+		raw, _ := json.Marshal(toolChoice(tc.choice, tc.parallel))
+		if string(raw) != tc.want {
+			t.Fatalf("toolChoice(%q, %v) = %s, want %s", tc.choice, tc.parallel, raw, tc.want)
+		}
+	}
+}
+
+func TestMessagesModelStream_ReportsUsage(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `event: message_start
+data: {"type":"message_start","message":{"usage":{"input_tokens":10,"cache_creation_input_tokens":100,"cache_read_input_tokens":1000,"output_tokens":1}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"ok"}}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":7}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`)
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	stream, err := client.ChatModel("claude-test").Stream(context.Background(), &provider.LanguageModelRequest{
+		Messages: []provider.Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("Stream error: %v", err)
+	}
+	defer stream.Close()
+	for {
+		delta, err := stream.Next(context.Background())
+		if err != nil {
+			t.Fatalf("Next error: %v", err)
+		}
+		if !delta.Done {
+			continue
+		}
+		want := &provider.Usage{
+			PromptTokens: 1110, CompletionTokens: 7, TotalTokens: 1117,
+			Cache: &provider.CacheUsage{CachedInputTokens: 1000, CacheWriteTokens: 100},
+		}
+		if !reflect.DeepEqual(delta.Usage, want) {
+			t.Fatalf("Usage = %+v, want %+v", delta.Usage, want)
+		}
+		if delta.StopReason != "end_turn" || delta.FinishReason != provider.FinishReasonStop || delta.Metadata == nil {
+			t.Fatalf("final delta = %+v, want the stop reason and metadata", delta)
+		}
+		return
+	}
+}
+
+func TestMessagesModel_JSONToolIsNotAToolCall(t *testing.T) {
+	var stop string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") == "text/event-stream" {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, `data: {"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"%s","input":{}}}
+
+data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"a\":1}"}}
+
+data: {"type":"message_delta","delta":{"stop_reason":"%s"}}
+
+data: {"type":"message_stop"}
+
+`, jsonToolName, stop)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"content":[{"type":"tool_use","id":"toolu_1","name":"%s","input":{"a":1}}],"stop_reason":"%s"}`, jsonToolName, stop)
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	model := client.ChatModel("claude-test")
+	req := &provider.LanguageModelRequest{
+		Messages:   []provider.Message{{Role: "user", Content: "hi"}},
+		JSONSchema: []byte(`{"type":"object"}`),
+	}
+	for _, tc := range []struct {
+		stop string
+		want provider.FinishReason
+	}{
+		{"tool_use", provider.FinishReasonStop},
+		{"end_turn", provider.FinishReasonStop},
+		{"max_tokens", provider.FinishReasonLength},
+	} {
+		stop = tc.stop
+		res, err := model.Generate(context.Background(), req)
+		if err != nil {
+			t.Fatalf("%s: Generate error: %v", tc.stop, err)
+		}
+		if res.Text != `{"a":1}` || len(res.ToolCalls) != 0 || res.FinishReason != tc.want {
+			t.Fatalf("%s: Generate = text %q, %d tool calls, finish %q; want finish %q", tc.stop, res.Text, len(res.ToolCalls), res.FinishReason, tc.want)
+		}
+
+		stream, err := model.Stream(context.Background(), req)
+		if err != nil {
+			t.Fatalf("%s: Stream error: %v", tc.stop, err)
+		}
+		var text string
+		for {
+			delta, err := stream.Next(context.Background())
+			if err != nil {
+				t.Fatalf("%s: Next error: %v", tc.stop, err)
+			}
+			text += delta.Text
+			if delta.Done {
+				if text != `{"a":1}` || len(delta.ToolCalls) != 0 || delta.FinishReason != tc.want {
+					t.Fatalf("%s: Stream = text %q, %d tool calls, finish %q; want finish %q", tc.stop, text, len(delta.ToolCalls), delta.FinishReason, tc.want)
+				}
+				break
+			}
+		}
+		stream.Close()
+	}
+}
+
+func TestMessagesModel_RefusalIsContentBlocked(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") == "text/event-stream" {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, `data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"I can"}}
+
+data: {"type":"message_delta","delta":{"stop_reason":"refusal"}}
+
+data: {"type":"message_stop"}
+
+`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"content":[{"type":"text","text":"I can"}],"stop_reason":"refusal"}`)
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	model := client.ChatModel("claude-test")
+	req := &provider.LanguageModelRequest{Messages: []provider.Message{{Role: "user", Content: "hi"}}}
+
+	var blocked *provider.ContentBlockedError
+	if _, err := model.Generate(context.Background(), req); !errors.As(err, &blocked) || blocked.Provider != "anthropic" || blocked.Reason != "refusal" {
+		t.Fatalf("Generate error = %v, want ContentBlockedError with reason refusal", err)
+	}
+
+	stream, err := model.Stream(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Stream error: %v", err)
+	}
+	defer stream.Close()
+	if delta, err := stream.Next(context.Background()); err != nil || delta.Text != "I can" {
+		t.Fatalf("first Next = %+v, %v; want the text delta", delta, err)
+	}
+	blocked = nil
+	if _, err := stream.Next(context.Background()); !errors.As(err, &blocked) || blocked.Reason != "refusal" {
+		t.Fatalf("final Next error = %v, want ContentBlockedError with reason refusal", err)
+	}
+	if _, err := stream.Next(context.Background()); !errors.As(err, &blocked) {
+		t.Fatalf("Next after refusal = %v, want the same ContentBlockedError", err)
+	}
+}
+
+func TestMessagesModelBuildBody_JSONModeWithoutSchema(t *testing.T) {
+	m := &messagesModel{client: &Client{}, model: "claude-test"}
+	body, useJSONTool, err := m.buildBody(context.Background(), &provider.LanguageModelRequest{
+		Messages:       []provider.Message{{Role: "user", Content: "hi"}},
+		ResponseFormat: provider.ResponseFormatJSON,
+	}, false)
+	if err != nil {
+		t.Fatalf("buildBody error: %v", err)
+	}
+	if !useJSONTool || len(body.Tools) != 1 || string(body.Tools[0].InputSchema) != `{"type":"object"}` {
+		t.Fatalf("expected the JSON tool with a permissive schema, got %+v", body.Tools)
+	}
+}
+
+func TestMessagesModel_ProviderOptions(t *testing.T) {
+	client, err := NewClient(provider.ClientOptions{BaseURL: "https://api.anthropic.test", APIKey: "test"})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	model := aitest.CaptureRequests(client.ChatModel("claude-test"))
+	model.Respond = func(*provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
+		return &provider.LanguageModelResponse{Text: "ok"}, nil
+	}
+	req := &provider.LanguageModelRequest{
+		Messages: []provider.Message{{Role: "user", Content: "hi"}},
+		ProviderOptions: map[string]any{
+			"container":  "container_1",
+			"max_tokens": 1,
+		},
+	}
+	if _, err := model.Generate(context.Background(), req); err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	stream, err := model.Stream(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Stream error: %v", err)
+	}
+	for {
+		delta, err := stream.Next(context.Background())
+		if err != nil {
+			t.Fatalf("Next error: %v", err)
+		}
+		if delta.Done {
+			break
+		}
+	}
+	stream.Close()
+
+	// The provider option adds container but does not overwrite
+	// max_tokens.
+	reqs := model.Requests()
+	if len(reqs) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(reqs))
+	}
+	aitest.CompareGolden(t, "testdata/provider_options_generate.golden", aitest.FormatRequest(reqs[0], aitest.GoldenOptions{}))
+	aitest.CompareGolden(t, "testdata/provider_options_stream.golden", aitest.FormatRequest(reqs[1], aitest.GoldenOptions{}))
+}
+
+func TestMessagesModel_ContextHeaders(t *testing.T) {
+	var got http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn"}`)
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	ctx := provider.WithHeaders(context.Background(), http.Header{"X-Tenant-ID": {"acme"}})
+	ctx = provider.WithHeaders(ctx, http.Header{"anthropic-version": {"1999-01-01"}, "X-Api-Key": {"other"}})
+	if _, err := client.ChatModel("claude-test").Generate(ctx, &provider.LanguageModelRequest{
+		Messages: []provider.Message{{Role: "user", Content: "hi"}},
+	}); err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	if got.Get("X-Tenant-ID") != "acme" {
+		t.Fatalf("X-Tenant-ID = %q, want acme", got.Get("X-Tenant-ID"))
+	}
+	if got.Get("anthropic-version") != "2023-06-01" || got.Get("x-api-key") != "test" {
+		t.Fatalf("required headers were replaced: %v", got)
+	}
+}
+
+func TestMessagesModel_WebSearch(t *testing.T) {
+	var body map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{
+			"content": [
+				{"type":"text","text":"Let me search."},
+				{"type":"server_tool_use","id":"srvtoolu_1","name":"web_search","input":{"query":"go release"}},
+				{"type":"web_search_tool_result","tool_use_id":"srvtoolu_1","content":[
+					{"type":"web_search_result","url":"https://go.dev/doc/devel/release","title":"Release History","encrypted_content":"abc","page_age":"1 day"},
+					{"type":"web_search_result","url":"https://go.dev/blog","title":"The Go Blog","encrypted_content":"def"}
+				]},
+				{"type":"text","text":" Go 1.25 is out.","citations":[
+					{"type":"web_search_result_location","url":"https://go.dev/doc/devel/release","title":"Release History","encrypted_index":"xyz","cited_text":"go1.25 (released 2025-08-12)"}
+				]}
+			],
+			"stop_reason":"end_turn",
+			"usage":{"input_tokens":100,"output_tokens":20,"server_tool_use":{"web_search_requests":1}}
+		}`)
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	res, err := client.ChatModel("claude-test").Generate(context.Background(), &provider.LanguageModelRequest{
+		Messages:  []provider.Message{{Role: "user", Content: "Latest Go release?"}},
+		WebSearch: &provider.WebSearchOptions{MaxUses: 3, AllowedDomains: []string{"go.dev"}},
+	})
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+
+	tools, _ := body["tools"].([]any)
+	tool, _ := tools[0].(map[string]any)
+	if len(tools) != 1 || tool["type"] != "web_search_20250305" || tool["name"] != "web_search" || tool["max_uses"] != 3.0 {
+		t.Fatalf("unexpected tools %v", body["tools"])
+	}
+	if domains, _ := tool["allowed_domains"].([]any); len(domains) != 1 || domains[0] != "go.dev" || tool["blocked_domains"] != nil {
+		t.Fatalf("unexpected domains in %v", tool)
+	}
+
+	if res.Text != "Let me search. Go 1.25 is out." || len(res.ToolCalls) != 0 {
+		t.Fatalf("unexpected response %+v", res)
+	}
+	wantCitations := []provider.Citation{{
+		URL: "https://go.dev/doc/devel/release", Title: "Release History", Text: "go1.25 (released 2025-08-12)",
+		StartIndex: len("Let me search."), EndIndex: len(res.Text),
+	}}
+	if !reflect.DeepEqual(res.Citations, wantCitations) {
+		t.Fatalf("Citations = %+v, want %+v", res.Citations, wantCitations)
+	}
+	wantResults := []provider.Citation{
+		{URL: "https://go.dev/doc/devel/release", Title: "Release History"},
+		{URL: "https://go.dev/blog", Title: "The Go Blog"},
+	}
+	if !reflect.DeepEqual(res.Metadata.SearchResults, wantResults) {
+		t.Fatalf("SearchResults = %+v, want %+v", res.Metadata.SearchResults, wantResults)
+	}
+	if res.Usage.WebSearchRequests != 1 {
+		t.Fatalf("WebSearchRequests = %d, want 1", res.Usage.WebSearchRequests)
+	}
+}
+
+func TestMessagesModelStream_WebSearchBlocks(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `event: message_start
+data: {"type":"message_start","message":{"usage":{"input_tokens":10,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"server_tool_use","id":"srvtoolu_1","name":"web_search","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"query\":\"go\"}"}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"web_search_tool_result","tool_use_id":"srvtoolu_1","content":{"type":"web_search_tool_result_error","error_code":"max_uses_exceeded"}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":2,"content_block":{"type":"text","text":"","citations":[]}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":2,"delta":{"type":"citations_delta","citation":{"type":"web_search_result_location","url":"https://go.dev","cited_text":"Go"}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":2,"delta":{"type":"text_delta","text":"Go is great."}}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":5,"server_tool_use":{"web_search_requests":1}}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`)
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	stream, err := client.ChatModel("claude-test").Stream(context.Background(), &provider.LanguageModelRequest{
+		Messages:  []provider.Message{{Role: "user", Content: "hi"}},
+		WebSearch: &provider.WebSearchOptions{},
+	})
+	if err != nil {
+		t.Fatalf("Stream error: %v", err)
+	}
+	defer stream.Close()
+	var text string
+	for {
+		delta, err := stream.Next(context.Background())
+		if err != nil {
+			t.Fatalf("Next error: %v", err)
+		}
+		text += delta.Text
+		if delta.Done {
+			if len(delta.ToolCalls) != 0 {
+				t.Fatalf("server tool use reported as a tool call: %+v", delta.ToolCalls)
+			}
+			if delta.Usage == nil || delta.Usage.WebSearchRequests != 1 {
+				t.Fatalf("unexpected usage %+v", delta.Usage)
+			}
+			break
+		}
+	}
+	if text != "Go is great." {
+		t.Fatalf("unexpected text %q", text)
+	}
+}
+
+func TestMessagesModel_DroppedOptions(t *testing.T) {
+	var bodies []map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		if r.Header.Get("Accept") == "text/event-stream" {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn"}`)
+	}))
+	defer ts.Close()
+
+	type report struct {
+		source  string
+		dropped []provider.DroppedOption
+	}
+	var reports []report
+	opts := provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client(),
+		OnDroppedOptions: func(source string, dropped []provider.DroppedOption) {
+			reports = append(reports, report{source, dropped})
+		},
+	}
+	client, err := NewClient(opts)
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	model := client.ChatModel("claude-test")
+
+	penalty, logprobs, store, temp := 0.5, true, false, 0.2
+	req := &provider.LanguageModelRequest{
+		Messages:         []provider.Message{{Role: "user", Content: "hi"}},
+		Temperature:      &temp,
+		LogitBias:        map[string]float64{"50256": -100},
+		FrequencyPenalty: &penalty,
+		Logprobs:         &logprobs,
+		Store:            &store,
+		JSONSchemaStrict: true,
+		Metadata:         map[string]string{provider.MetadataUserID: "u1", "team": "a"},
+		Reasoning:        &provider.ReasoningOptions{Effort: "extreme"},
+	}
+	want := []string{"LogitBias", "FrequencyPenalty", "Logprobs", "Store", "JSONSchemaStrict", "Metadata[team]", "Reasoning.Effort"}
+	fields := func(dropped []provider.DroppedOption) []string {
+		var out []string
+		for _, d := range dropped {
+			if d.Reason == "" {
+				t.Fatalf("%s dropped without a reason", d.Field)
+			}
+			out = append(out, d.Field)
+		}
+		return out
+	}
+
+	res, err := model.Generate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	if got := fields(res.Metadata.DroppedOptions); !reflect.DeepEqual(got, want) {
+		t.Fatalf("DroppedOptions = %v, want %v", got, want)
+	}
+	stream, err := model.Stream(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Stream error: %v", err)
+	}
+	stream.Close()
+	if len(reports) != 2 || reports[0].source != "anthropic.messages" || !reflect.DeepEqual(fields(reports[1].dropped), want) {
+		t.Fatalf("unexpected reports %+v", reports)
+	}
+	if bodies[0]["temperature"] != 0.2 || bodies[0]["metadata"].(map[string]any)["user_id"] != "u1" {
+		t.Fatalf("expected the supported options to be sent, got %v", bodies[0])
+	}
+
+	// Requests without dropped options report nothing.
+	reports = nil
+	res, err = model.Generate(context.Background(), &provider.LanguageModelRequest{Messages: req.Messages, Temperature: &temp})
+	if err != nil || res.Metadata.DroppedOptions != nil || reports != nil {
+		t.Fatalf("unexpected report %v, %+v (err %v)", res.Metadata.DroppedOptions, reports, err)
+	}
+
+	// Strict clients reject the request before sending it.
+	opts.StrictOptions = true
+	strict, err := NewClient(opts)
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	sent := len(bodies)
+	_, err = strict.ChatModel("claude-test").Generate(context.Background(), req)
+	var unsupported *provider.UnsupportedFunctionalityError
+	if !errors.As(err, &unsupported) || !reflect.DeepEqual(fields(unsupported.Dropped), want) || !strings.Contains(err.Error(), "FrequencyPenalty") {
+		t.Fatalf("expected *UnsupportedFunctionalityError, got %v", err)
+	}
+	if len(bodies) != sent {
+		t.Fatal("strict client sent the request")
+	}
+}
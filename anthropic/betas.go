@@ -0,0 +1,51 @@
+package anthropic
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// betaHeader is the header Anthropic uses to opt in to beta features.
+const betaHeader = "anthropic-beta"
+
+// Known Anthropic beta feature names for use with ClientOptions.Betas
+// or LanguageModelRequest.Betas.
+const (
+	// BetaOutput128k raises the output limit to 128k tokens on models
+	// that support extended output.
+	BetaOutput128k = "output-128k-2025-02-19"
+	// BetaPromptCaching enables prompt caching on older API versions.
+	BetaPromptCaching = "prompt-caching-2024-07-31"
+	// BetaTokenCounting enables the count_tokens endpoint.
+	BetaTokenCounting = "token-counting-2024-11-01"
+	// BetaTokenEfficientTools reduces output tokens for tool use.
+	BetaTokenEfficientTools = "token-efficient-tools-2025-02-19"
+)
+
+// maxStandardOutputTokens is the largest max_tokens value accepted
+// without the extended output beta.
+const maxStandardOutputTokens = 64000
+
+// betaWarnings reports features requested by req whose required beta
+// is missing from the outgoing headers h.
+func betaWarnings(req *provider.LanguageModelRequest, h http.Header) []string {
+	var warnings []string
+	if req.MaxTokens != nil && *req.MaxTokens > maxStandardOutputTokens && !hasBeta(h, BetaOutput128k) {
+		warnings = append(warnings, fmt.Sprintf("anthropic: max_tokens %d exceeds %d without the %q beta", *req.MaxTokens, maxStandardOutputTokens, BetaOutput128k))
+	}
+	return warnings
+}
+
+func hasBeta(h http.Header, beta string) bool {
+	for _, v := range h.Values(betaHeader) {
+		for _, b := range strings.Split(v, ",") {
+			if strings.TrimSpace(b) == beta {
+				return true
+			}
+		}
+	}
+	return false
+}
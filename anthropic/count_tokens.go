@@ -0,0 +1,68 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ncecere/ai-sdk/provider"
+	"github.com/ncecere/ai-sdk/providerutil"
+)
+
+var _ provider.TokenCounter = (*messagesModel)(nil)
+
+type anthropicCountTokensRequest struct {
+	Model      string             `json:"model"`
+	System     string             `json:"system,omitempty"`
+	Messages   []anthropicMessage `json:"messages"`
+	Tools      []anthropicTool    `json:"tools,omitempty"`
+	ToolChoice any                `json:"tool_choice,omitempty"`
+}
+
+type anthropicCountTokensResponse struct {
+	InputTokens int `json:"input_tokens"`
+}
+
+func (c *Client) countTokensURL() string {
+	return c.messagesURL() + "/count_tokens"
+}
+
+// CountTokens returns the exact number of input tokens req would use
+// with the given model, including the system prompt and tools, using
+// the Messages count_tokens endpoint.
+func (c *Client) CountTokens(ctx context.Context, model string, req *provider.LanguageModelRequest) (int, error) {
+	return (&messagesModel{client: c, model: model}).CountTokens(ctx, req)
+}
+
+// CountTokens implements provider.TokenCounter.
+func (m *messagesModel) CountTokens(ctx context.Context, req *provider.LanguageModelRequest) (int, error) {
+	body, _, err := m.buildBody(ctx, req, false)
+	if err != nil {
+		return 0, err
+	}
+	buf, err := json.Marshal(anthropicCountTokensRequest{
+		Model:      body.Model,
+		System:     body.System,
+		Messages:   body.Messages,
+		Tools:      body.Tools,
+		ToolChoice: body.ToolChoice,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	httpReq, err := m.client.newRequest(ctx, m.client.countTokensURL(), buf, req)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := m.client.do(httpReq)
+	if err != nil {
+		return 0, err
+	}
+
+	var out anthropicCountTokensResponse
+	if err := providerutil.ReadJSON(resp, &out); err != nil {
+		return 0, providerutil.TagProvider(err, "anthropic")
+	}
+	return out.InputTokens, nil
+}
@@ -0,0 +1,63 @@
+package anthropic
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// droppedOptions returns the options of req that the Messages API
+// cannot honor and buildBody leaves out. buildBody consumes every other
+// LanguageModelRequest field; keep the two in step.
+func droppedOptions(req *provider.LanguageModelRequest) []provider.DroppedOption {
+	var dropped []provider.DroppedOption
+	drop := func(field, reason string) {
+		dropped = append(dropped, provider.DroppedOption{Field: field, Reason: reason})
+	}
+	if len(req.LogitBias) > 0 {
+		drop("LogitBias", "Anthropic has no logit bias")
+	}
+	if req.FrequencyPenalty != nil {
+		drop("FrequencyPenalty", "Anthropic has no repetition penalties")
+	}
+	if req.PresencePenalty != nil {
+		drop("PresencePenalty", "Anthropic has no repetition penalties")
+	}
+	if req.Logprobs != nil && *req.Logprobs {
+		drop("Logprobs", "Anthropic does not return log probabilities")
+	}
+	if req.TopLogprobs != nil {
+		drop("TopLogprobs", "Anthropic does not return log probabilities")
+	}
+	if req.Store != nil {
+		drop("Store", "Anthropic has no retention controls")
+	}
+	if req.JSONSchemaStrict {
+		drop("JSONSchemaStrict", "Anthropic has no strict structured outputs; JSONSchema is sent as a tool input schema")
+	}
+	var keys []string
+	for k := range req.Metadata {
+		if k != provider.MetadataUserID {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		drop("Metadata["+k+"]", "Anthropic only accepts metadata."+provider.MetadataUserID)
+	}
+	if r := req.Reasoning; r != nil && r.BudgetTokens <= 0 && r.Effort != "" && thinkingBudgets[r.Effort] == 0 {
+		drop("Reasoning.Effort", fmt.Sprintf("unknown effort %q; Anthropic maps low, medium, and high onto a thinking budget", r.Effort))
+	}
+	return dropped
+}
+
+// reportDropped passes the options of req that the Messages API cannot
+// honor to ClientOptions.OnDroppedOptions and returns them.
+func (c *Client) reportDropped(req *provider.LanguageModelRequest) []provider.DroppedOption {
+	dropped := droppedOptions(req)
+	if len(dropped) > 0 && c.onDropped != nil {
+		c.onDropped("anthropic.messages", dropped)
+	}
+	return dropped
+}
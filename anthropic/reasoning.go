@@ -0,0 +1,39 @@
+package anthropic
+
+import "github.com/ncecere/ai-sdk/provider"
+
+// anthropicThinking is the extended thinking request block.
+type anthropicThinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+// minThinkingBudget is the smallest budget_tokens Anthropic accepts.
+const minThinkingBudget = 1024
+
+// thinkingBudgets maps a coarse reasoning effort onto a thinking budget
+// when no explicit budget is given.
+var thinkingBudgets = map[string]int{
+	"low":    minThinkingBudget,
+	"medium": 4096,
+	"high":   16384,
+}
+
+// thinkingConfig returns the thinking block for r, or nil when r does
+// not request reasoning.
+func thinkingConfig(r *provider.ReasoningOptions) *anthropicThinking {
+	if r == nil {
+		return nil
+	}
+	budget := r.BudgetTokens
+	if budget <= 0 {
+		budget = thinkingBudgets[r.Effort]
+	}
+	if budget <= 0 {
+		return nil
+	}
+	if budget < minThinkingBudget {
+		budget = minThinkingBudget
+	}
+	return &anthropicThinking{Type: "enabled", BudgetTokens: budget}
+}
@@ -0,0 +1,274 @@
+package ai
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+)
+
+// audioTrack is a parsed WAV or MP3 file that can cut standalone
+// sub-ranges for chunked transcription.
+type audioTrack interface {
+	// Duration returns the length of the audio in seconds.
+	Duration() float64
+	// BytesPerSecond returns the average encoded size of one second.
+	BytesPerSecond() float64
+	// Slice returns a standalone file holding the audio in [start, end)
+	// seconds, and the actual start time after snapping to a frame.
+	Slice(start, end float64) ([]byte, float64)
+	// Quietest returns the center of the quietest short window in
+	// [from, to], or false if the format does not allow measuring
+	// energy.
+	Quietest(from, to float64) (float64, bool)
+	// MimeType returns the content type of slices.
+	MimeType() string
+	// Ext returns the file extension of slices, including the dot.
+	Ext() string
+}
+
+// detectAudioFormat returns "wav" or "mp3" based on the file's magic
+// bytes, or "" if the format is not recognized.
+func detectAudioFormat(b []byte) string {
+	switch {
+	case len(b) >= 12 && string(b[0:4]) == "RIFF" && string(b[8:12]) == "WAVE":
+		return "wav"
+	case len(b) >= 3 && string(b[0:3]) == "ID3":
+		return "mp3"
+	case len(b) >= 2 && b[0] == 0xFF && b[1]&0xE0 == 0xE0:
+		return "mp3"
+	}
+	return ""
+}
+
+// parseAudioTrack parses b as format, detecting it when empty.
+func parseAudioTrack(b []byte, format string) (audioTrack, error) {
+	if format == "" {
+		format = detectAudioFormat(b)
+	}
+	switch format {
+	case "wav":
+		return parseWAV(b)
+	case "mp3":
+		return parseMP3(b)
+	}
+	return nil, errors.New("unsupported audio format; expected wav or mp3")
+}
+
+// wavTrack is a RIFF/WAVE file split on sample frames.
+type wavTrack struct {
+	fmtChunk      []byte
+	data          []byte
+	audioFormat   int
+	channels      int
+	sampleRate    int
+	blockAlign    int
+	bitsPerSample int
+}
+
+func parseWAV(b []byte) (*wavTrack, error) {
+	if len(b) < 12 || string(b[0:4]) != "RIFF" || string(b[8:12]) != "WAVE" {
+		return nil, errors.New("not a RIFF/WAVE file")
+	}
+	t := &wavTrack{}
+	for off := 12; off+8 <= len(b); {
+		id := string(b[off : off+4])
+		size := int(binary.LittleEndian.Uint32(b[off+4 : off+8]))
+		body := off + 8
+		end := body + size
+		// Streaming encoders leave the data size unset (0 or
+		// 0xFFFFFFFF); treat the rest of the file as the chunk.
+		if end > len(b) || (id == "data" && size == 0) {
+			end = len(b)
+		}
+		switch id {
+		case "fmt ":
+			t.fmtChunk = b[body:end]
+		case "data":
+			t.data = b[body:end]
+		}
+		off = end + (end-body)&1
+	}
+	if len(t.fmtChunk) < 16 {
+		return nil, errors.New("wav file has no fmt chunk")
+	}
+	if t.data == nil {
+		return nil, errors.New("wav file has no data chunk")
+	}
+	t.audioFormat = int(binary.LittleEndian.Uint16(t.fmtChunk[0:2]))
+	t.channels = int(binary.LittleEndian.Uint16(t.fmtChunk[2:4]))
+	t.sampleRate = int(binary.LittleEndian.Uint32(t.fmtChunk[4:8]))
+	t.blockAlign = int(binary.LittleEndian.Uint16(t.fmtChunk[12:14]))
+	t.bitsPerSample = int(binary.LittleEndian.Uint16(t.fmtChunk[14:16]))
+	if t.sampleRate <= 0 || t.blockAlign <= 0 || t.channels <= 0 {
+		return nil, errors.New("wav file has an invalid fmt chunk")
+	}
+	t.data = t.data[:len(t.data)/t.blockAlign*t.blockAlign]
+	return t, nil
+}
+
+func (t *wavTrack) frames() int { return len(t.data) / t.blockAlign }
+
+func (t *wavTrack) Duration() float64 { return float64(t.frames()) / float64(t.sampleRate) }
+
+func (t *wavTrack) BytesPerSecond() float64 { return float64(t.sampleRate * t.blockAlign) }
+
+func (t *wavTrack) MimeType() string { return "audio/wav" }
+
+func (t *wavTrack) Ext() string { return ".wav" }
+
+func (t *wavTrack) frameAt(sec float64) int {
+	return min(max(int(math.Round(sec*float64(t.sampleRate))), 0), t.frames())
+}
+
+func (t *wavTrack) Slice(start, end float64) ([]byte, float64) {
+	from, to := t.frameAt(start), t.frameAt(end)
+	pcm := t.data[from*t.blockAlign : to*t.blockAlign]
+	fmtLen := len(t.fmtChunk) + len(t.fmtChunk)&1
+
+	out := make([]byte, 0, 20+fmtLen+8+len(pcm))
+	out = append(out, "RIFF"...)
+	out = binary.LittleEndian.AppendUint32(out, uint32(4+8+fmtLen+8+len(pcm)))
+	out = append(out, "WAVEfmt "...)
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(t.fmtChunk)))
+	out = append(out, t.fmtChunk...)
+	if len(t.fmtChunk)&1 == 1 {
+		out = append(out, 0)
+	}
+	out = append(out, "data"...)
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(pcm)))
+	out = append(out, pcm...)
+	return out, float64(from) / float64(t.sampleRate)
+}
+
+// Quietest measures energy over 20ms windows. Only 16-bit PCM is
+// supported.
+func (t *wavTrack) Quietest(from, to float64) (float64, bool) {
+	if t.audioFormat != 1 || t.bitsPerSample != 16 {
+		return 0, false
+	}
+	window := max(t.sampleRate/50, 1)
+	first, last := t.frameAt(from), t.frameAt(to)
+	best, bestEnergy := -1, math.Inf(1)
+	for f := first; f+window <= last; f += window {
+		var energy float64
+		for i := f * t.blockAlign; i < (f+window)*t.blockAlign; i += 2 {
+			s := float64(int16(binary.LittleEndian.Uint16(t.data[i:])))
+			energy += s * s
+		}
+		if energy < bestEnergy {
+			best, bestEnergy = f, energy
+		}
+	}
+	if best < 0 {
+		return 0, false
+	}
+	return float64(best+window/2) / float64(t.sampleRate), true
+}
+
+// mp3Frame is the position of one MPEG audio frame.
+type mp3Frame struct {
+	offset int
+	size   int
+	start  float64
+}
+
+// mp3Track is an MPEG-1/2/2.5 Layer III file split on frame boundaries.
+// Slices drop ID3 tags; decoders do not need them.
+type mp3Track struct {
+	data     []byte
+	frames   []mp3Frame
+	duration float64
+	bytes    int
+}
+
+var (
+	mp3Bitrates1    = [16]int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0}
+	mp3Bitrates2    = [16]int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0}
+	mp3SampleRates1 = [4]int{44100, 48000, 32000, 0}
+)
+
+// mp3FrameHeader decodes a Layer III frame header, returning the frame
+// size in bytes and its duration in seconds.
+func mp3FrameHeader(h []byte) (size int, dur float64, ok bool) {
+	if h[0] != 0xFF || h[1]&0xE0 != 0xE0 {
+		return 0, 0, false
+	}
+	version := (h[1] >> 3) & 3 // 0: MPEG-2.5, 2: MPEG-2, 3: MPEG-1
+	layer := (h[1] >> 1) & 3   // 1: Layer III
+	bitrateIdx := h[2] >> 4
+	rateIdx := (h[2] >> 2) & 3
+	padding := int(h[2]>>1) & 1
+	if version == 1 || layer != 1 || bitrateIdx == 0 || bitrateIdx == 15 || rateIdx == 3 {
+		return 0, 0, false
+	}
+
+	rate := mp3SampleRates1[rateIdx]
+	kbps, samples, coef := mp3Bitrates1[bitrateIdx], 1152, 144
+	if version != 3 {
+		kbps, samples, coef = mp3Bitrates2[bitrateIdx], 576, 72
+		rate /= 2
+		if version == 0 {
+			rate /= 2
+		}
+	}
+	return coef*kbps*1000/rate + padding, float64(samples) / float64(rate), true
+}
+
+func parseMP3(b []byte) (*mp3Track, error) {
+	off := 0
+	if len(b) >= 10 && string(b[0:3]) == "ID3" {
+		off = 10 + (int(b[6]&0x7F)<<21 | int(b[7]&0x7F)<<14 | int(b[8]&0x7F)<<7 | int(b[9]&0x7F))
+		if b[5]&0x10 != 0 {
+			off += 10
+		}
+	}
+
+	t := &mp3Track{data: b}
+	for off+4 <= len(b) {
+		size, dur, ok := mp3FrameHeader(b[off : off+4])
+		if !ok || off+size > len(b) {
+			// Skip trailing tags, padding, and truncated frames by
+			// resynchronizing on the next frame header.
+			off++
+			continue
+		}
+		t.frames = append(t.frames, mp3Frame{offset: off, size: size, start: t.duration})
+		t.duration += dur
+		t.bytes += size
+		off += size
+	}
+	if len(t.frames) == 0 {
+		return nil, errors.New("mp3 file has no Layer III audio frames")
+	}
+	return t, nil
+}
+
+func (t *mp3Track) Duration() float64 { return t.duration }
+
+func (t *mp3Track) BytesPerSecond() float64 { return float64(t.bytes) / t.duration }
+
+func (t *mp3Track) MimeType() string { return "audio/mpeg" }
+
+func (t *mp3Track) Ext() string { return ".mp3" }
+
+// frameAt returns the index of the first frame starting at or after sec.
+func (t *mp3Track) frameAt(sec float64) int {
+	return sort.Search(len(t.frames), func(i int) bool { return t.frames[i].start >= sec-1e-9 })
+}
+
+func (t *mp3Track) Slice(start, end float64) ([]byte, float64) {
+	from, to := t.frameAt(start), t.frameAt(end)
+	var out []byte
+	for _, f := range t.frames[from:to] {
+		out = append(out, t.data[f.offset:f.offset+f.size]...)
+	}
+	if from == len(t.frames) {
+		return out, t.duration
+	}
+	return out, t.frames[from].start
+}
+
+// Quietest is not supported for MP3: measuring energy would require
+// decoding the audio, so chunks are cut on frame boundaries.
+func (t *mp3Track) Quietest(from, to float64) (float64, bool) { return 0, false }
@@ -15,6 +15,10 @@ type CallSettings struct {
 	MaxTokens *int
 	// Stop contains stop sequences that will truncate the output.
 	Stop []string
+	// FrequencyPenalty and PresencePenalty, between -2 and 2,
+	// discourage repetition.
+	FrequencyPenalty *float64
+	PresencePenalty  *float64
 }
 
 // ApplyTo copies the non-nil/non-zero fields from the CallSettings
@@ -35,6 +39,12 @@ func (s *CallSettings) ApplyTo(req *GenerateTextRequest) {
 	if len(s.Stop) > 0 {
 		req.Stop = s.Stop
 	}
+	if s.FrequencyPenalty != nil {
+		req.FrequencyPenalty = s.FrequencyPenalty
+	}
+	if s.PresencePenalty != nil {
+		req.PresencePenalty = s.PresencePenalty
+	}
 }
 
 // NewGenerateTextRequest constructs a GenerateTextRequest from the
@@ -64,7 +74,24 @@ func UserMessage(content string) Message {
 	return Message{Role: RoleUser, Content: content}
 }
 
+// UserMessageWithImage creates a user message asking about an image,
+// for vision-capable models. The image is sent inline as img with the
+// given MIME type, e.g. "image/png", after the text; Content holds the
+// text for providers without multi-part content. Use Parts with
+// ImageURLPart to reference an image by URL instead.
+func UserMessageWithImage(text string, img []byte, mimeType string) Message {
+	return Message{Role: RoleUser, Content: text, Parts: []ContentPart{TextPart(text), ImagePart(img, mimeType)}}
+}
+
 // AssistantMessage creates an assistant message with the given content.
 func AssistantMessage(content string) Message {
 	return Message{Role: RoleAssistant, Content: content}
 }
+
+// AssistantToolCallMessage creates an assistant message with the given
+// content (which may be empty) and the tool calls the model made. It is
+// the message to append before the tool results when continuing a tool
+// loop.
+func AssistantToolCallMessage(content string, calls []ToolCall) Message {
+	return Message{Role: RoleAssistant, Content: content, ToolCalls: calls}
+}
@@ -0,0 +1,40 @@
+package ai
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCallSettingsValidate_Penalties(t *testing.T) {
+	float := func(v float64) *float64 { return &v }
+	cases := []struct {
+		name      string
+		settings  CallSettings
+		parameter string
+	}{
+		{"in range", CallSettings{FrequencyPenalty: float(-2), PresencePenalty: float(2)}, ""},
+		{"frequency too low", CallSettings{FrequencyPenalty: float(-2.1)}, "frequencyPenalty"},
+		{"presence too high", CallSettings{PresencePenalty: float(2.5)}, "presencePenalty"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.settings.Validate()
+			if tc.parameter == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var invalid *InvalidArgumentError
+			if !errors.As(err, &invalid) || invalid.Parameter != tc.parameter {
+				t.Fatalf("expected *InvalidArgumentError for %s, got %v", tc.parameter, err)
+			}
+		})
+	}
+
+	req := GenerateTextRequest{}
+	(&CallSettings{FrequencyPenalty: float(1), PresencePenalty: float(-1)}).ApplyTo(&req)
+	if req.FrequencyPenalty == nil || *req.FrequencyPenalty != 1 || req.PresencePenalty == nil || *req.PresencePenalty != -1 {
+		t.Fatalf("ApplyTo did not copy penalties: %+v", req)
+	}
+}
@@ -7,44 +7,78 @@ import "fmt"
 // InvalidArgumentError for values that are clearly out of range.
 //
 // This helper is optional: callers can still construct CallSettings
-// directly when they prefer not to perform validation.
+// directly when they prefer not to perform validation. Settings not
+// covered by the parameters, such as the penalties, can be set on the
+// result and checked with Validate.
 func NewCallSettings(temperature *float64, topP *float64, maxTokens *int, stop []string) (*CallSettings, error) {
-	if temperature != nil {
-		if *temperature < 0 || *temperature > 2 {
-			return nil, &InvalidArgumentError{
+	cs := &CallSettings{
+		Temperature: temperature,
+		TopP:        topP,
+		MaxTokens:   maxTokens,
+		Stop:        stop,
+	}
+	if err := cs.Validate(); err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+// Validate returns an InvalidArgumentError for the first setting that
+// is clearly out of range.
+func (s *CallSettings) Validate() error {
+	if s == nil {
+		return nil
+	}
+	if s.Temperature != nil {
+		if *s.Temperature < 0 || *s.Temperature > 2 {
+			return &InvalidArgumentError{
 				Parameter: "temperature",
-				Value:     *temperature,
+				Value:     *s.Temperature,
 				Message:   "must be between 0 and 2",
 			}
 		}
 	}
-	if topP != nil {
-		if *topP <= 0 || *topP > 1 {
-			return nil, &InvalidArgumentError{
+	if s.TopP != nil {
+		if *s.TopP <= 0 || *s.TopP > 1 {
+			return &InvalidArgumentError{
 				Parameter: "topP",
-				Value:     *topP,
+				Value:     *s.TopP,
 				Message:   "must be in the range (0, 1]",
 			}
 		}
 	}
-	if maxTokens != nil {
-		if *maxTokens <= 0 {
-			return nil, &InvalidArgumentError{
+	if s.MaxTokens != nil {
+		if *s.MaxTokens <= 0 {
+			return &InvalidArgumentError{
 				Parameter: "maxTokens",
-				Value:     *maxTokens,
+				Value:     *s.MaxTokens,
 				Message:   "must be greater than 0",
 			}
 		}
 	}
+	if err := validatePenalty("frequencyPenalty", s.FrequencyPenalty); err != nil {
+		return err
+	}
+	if err := validatePenalty("presencePenalty", s.PresencePenalty); err != nil {
+		return err
+	}
 
 	// No validation for stop sequences; providers may impose limits.
 
-	return &CallSettings{
-		Temperature: temperature,
-		TopP:        topP,
-		MaxTokens:   maxTokens,
-		Stop:        stop,
-	}, nil
+	return nil
+}
+
+// validatePenalty checks that a frequency or presence penalty is
+// between -2 and 2.
+func validatePenalty(name string, penalty *float64) error {
+	if penalty != nil && (*penalty < -2 || *penalty > 2) {
+		return &InvalidArgumentError{
+			Parameter: name,
+			Value:     *penalty,
+			Message:   "must be between -2 and 2",
+		}
+	}
+	return nil
 }
 
 // MustNewCallSettings constructs CallSettings and panics if validation
@@ -0,0 +1,251 @@
+package chatcodec
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	ai "github.com/ncecere/ai-sdk"
+)
+
+// anthropicRecord is one line of an Anthropic Messages dataset, as used
+// for Claude fine-tuning on Amazon Bedrock.
+type anthropicRecord struct {
+	System   json.RawMessage    `json:"system,omitempty"`
+	Messages []anthropicMessage `json:"messages"`
+}
+
+type anthropicMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+type anthropicBlock struct {
+	Type   string           `json:"type"`
+	Text   string           `json:"text,omitempty"`
+	Source *anthropicSource `json:"source,omitempty"`
+	// ID, Name, and Input are set on tool_use blocks.
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+	// ToolUseID and Content are set on tool_result blocks.
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"`
+}
+
+type anthropicSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// anthropicSystemSep joins system messages hoisted into the top-level
+// system prompt.
+const anthropicSystemSep = "\n\n"
+
+func (c converter) marshalAnthropic(msgs []ai.Message) ([]byte, error) {
+	var (
+		rec    = anthropicRecord{Messages: make([]anthropicMessage, 0, len(msgs))}
+		system []string
+	)
+	// results is the index in rec.Messages of the user message holding
+	// the tool_result blocks being added, or -1.
+	results := -1
+	for i, m := range msgs {
+		if m.Role != ai.RoleTool || m.ToolCallID == "" {
+			results = -1
+		}
+		switch m.Role {
+		case ai.RoleSystem:
+			if len(rec.Messages) > 0 {
+				if err := c.loss(i, "system message after the conversation start is hoisted to the system prompt"); err != nil {
+					return nil, err
+				}
+			}
+			system = append(system, textOf(m))
+			continue
+		case ai.RoleUser, ai.RoleAssistant:
+		case ai.RoleTool:
+			if m.ToolCallID != "" {
+				// Consecutive results share one user message, as
+				// Anthropic requires after a turn with several calls.
+				block := anthropicBlock{Type: "tool_result", ToolUseID: m.ToolCallID, Content: mustJSON(textOf(m))}
+				if results < 0 {
+					results = len(rec.Messages)
+					rec.Messages = append(rec.Messages, anthropicMessage{Role: ai.RoleUser, Content: mustJSON([]anthropicBlock{block})})
+					continue
+				}
+				var blocks []anthropicBlock
+				json.Unmarshal(rec.Messages[results].Content, &blocks)
+				rec.Messages[results].Content = mustJSON(append(blocks, block))
+				continue
+			}
+			// tool_result blocks must reference a tool_use block.
+			if err := c.loss(i, "tool message without a tool_use block"); err != nil {
+				return nil, err
+			}
+			rec.Messages = append(rec.Messages, anthropicMessage{Role: ai.RoleUser, Content: mustJSON(toolResultPrefix + textOf(m))})
+			continue
+		default:
+			if err := c.loss(i, fmt.Sprintf("unknown role %q", m.Role)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		am := anthropicMessage{Role: m.Role}
+		if len(m.Parts) == 0 && len(m.ToolCalls) == 0 {
+			am.Content = mustJSON(m.Content)
+			rec.Messages = append(rec.Messages, am)
+			continue
+		}
+		blocks := make([]anthropicBlock, 0, len(m.Parts)+len(m.ToolCalls))
+		if len(m.Parts) == 0 && m.Content != "" {
+			blocks = append(blocks, anthropicBlock{Type: "text", Text: m.Content})
+		}
+		for _, p := range m.Parts {
+			switch p.Type {
+			case ai.ContentPartText:
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: p.Text})
+			case ai.ContentPartImage:
+				src := &anthropicSource{Type: "url", URL: p.ImageURL}
+				if len(p.Data) > 0 {
+					src = &anthropicSource{Type: "base64", MediaType: p.MimeType, Data: base64.StdEncoding.EncodeToString(p.Data)}
+				} else if ip := imagePartFromURL(p.ImageURL); len(ip.Data) > 0 {
+					src = &anthropicSource{Type: "base64", MediaType: ip.MimeType, Data: base64.StdEncoding.EncodeToString(ip.Data)}
+				}
+				blocks = append(blocks, anthropicBlock{Type: "image", Source: src})
+			default:
+				if err := c.loss(i, fmt.Sprintf("unsupported content part %q", p.Type)); err != nil {
+					return nil, err
+				}
+			}
+		}
+		for _, tc := range m.ToolCalls {
+			blocks = append(blocks, anthropicBlock{Type: "tool_use", ID: tc.ID, Name: tc.Name, Input: toolUseInput(tc.RawArguments)})
+		}
+		am.Content = mustJSON(blocks)
+		rec.Messages = append(rec.Messages, am)
+	}
+	if len(system) > 0 {
+		rec.System = mustJSON(strings.Join(system, anthropicSystemSep))
+	}
+	return json.Marshal(rec)
+}
+
+func (c converter) unmarshalAnthropic(data []byte) ([]ai.Message, error) {
+	var rec anthropicRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("chatcodec: decoding anthropic record: %w", err)
+	}
+	msgs := make([]ai.Message, 0, len(rec.Messages)+1)
+	if hasJSON(rec.System) {
+		m := ai.Message{Role: ai.RoleSystem}
+		if _, err := c.decodeAnthropicContent(-1, rec.System, &m); err != nil {
+			return nil, err
+		}
+		// The system prompt is plain text; keep it in Content only.
+		msgs = append(msgs, ai.Message{Role: ai.RoleSystem, Content: textOf(m)})
+	}
+	for i, am := range rec.Messages {
+		if am.Role != ai.RoleUser && am.Role != ai.RoleAssistant {
+			if err := c.loss(i, fmt.Sprintf("unknown role %q", am.Role)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		m := ai.Message{Role: am.Role}
+		results, err := c.decodeAnthropicContent(i, am.Content, &m)
+		if err != nil {
+			return nil, err
+		}
+		// tool_result blocks become tool messages ahead of the rest of
+		// the user message.
+		msgs = append(msgs, results...)
+		if m.Content == "" && len(m.Parts) == 0 && len(m.ToolCalls) == 0 && hasJSON(am.Content) && am.Content[0] == '[' {
+			// Only tool results, or blocks that were dropped.
+			continue
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, nil
+}
+
+// toolUseInput returns tool call arguments as the JSON object a
+// tool_use block holds. Arguments held as a JSON string (as the OpenAI
+// client returns them) are unwrapped, and missing arguments become {}.
+func toolUseInput(args []byte) json.RawMessage {
+	var s string
+	if json.Unmarshal(args, &s) == nil {
+		args = []byte(s)
+	}
+	if len(strings.TrimSpace(string(args))) == 0 {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(args)
+}
+
+// decodeAnthropicContent decodes a string or content-block array into
+// m, with tool_use blocks as m.ToolCalls, and returns the tool_result
+// blocks as tool messages. Other unsupported blocks are dropped, unless
+// the conversion is strict.
+func (c converter) decodeAnthropicContent(i int, raw json.RawMessage, m *ai.Message) ([]ai.Message, error) {
+	if !hasJSON(raw) {
+		return nil, nil
+	}
+	if raw[0] == '"' {
+		return nil, json.Unmarshal(raw, &m.Content)
+	}
+	var blocks []anthropicBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return nil, fmt.Errorf("chatcodec: decoding anthropic message %d content: %w", i, err)
+	}
+	var (
+		parts   = make([]ai.ContentPart, 0, len(blocks))
+		calls   []ai.ToolCall
+		results []ai.Message
+	)
+	for _, b := range blocks {
+		switch {
+		case b.Type == "text":
+			parts = append(parts, ai.TextPart(b.Text))
+		case b.Type == "image" && b.Source != nil:
+			switch b.Source.Type {
+			case "base64":
+				data, err := base64.StdEncoding.DecodeString(b.Source.Data)
+				if err != nil {
+					return nil, fmt.Errorf("chatcodec: decoding anthropic message %d image: %w", i, err)
+				}
+				parts = append(parts, ai.ImagePart(data, b.Source.MediaType))
+			case "url":
+				parts = append(parts, ai.ImageURLPart(b.Source.URL))
+			default:
+				if err := c.loss(i, fmt.Sprintf("unsupported image source %q", b.Source.Type)); err != nil {
+					return nil, err
+				}
+			}
+		case b.Type == "tool_use" && m.Role == ai.RoleAssistant:
+			calls = append(calls, ai.ToolCall{ID: b.ID, Name: b.Name, RawArguments: toolUseInput(b.Input)})
+		case b.Type == "tool_result" && m.Role == ai.RoleUser:
+			result := ai.Message{Role: ai.RoleTool, ToolCallID: b.ToolUseID}
+			if hasJSON(b.Content) {
+				var content ai.Message
+				if _, err := c.decodeAnthropicContent(i, b.Content, &content); err != nil {
+					return nil, err
+				}
+				result.Content = textOf(content)
+			}
+			results = append(results, result)
+		default:
+			if err := c.loss(i, fmt.Sprintf("%s blocks are not carried by messages", b.Type)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(parts) > 0 {
+		*m = partsMessage(m.Role, parts)
+	}
+	m.ToolCalls = calls
+	return results, nil
+}
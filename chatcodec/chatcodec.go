@@ -0,0 +1,184 @@
+// Package chatcodec converts conversations to and from the JSONL
+// dataset formats used for fine-tuning and evaluation: the OpenAI chat
+// format, ShareGPT, and the Anthropic Messages format.
+//
+// Each record holds one conversation. Marshal and Unmarshal convert a
+// single record; Writer and Reader stream records for large datasets.
+//
+// Formats do not all express the same things. The OpenAI and Anthropic
+// formats carry tool calls and results (as tool_use and tool_result
+// blocks in the Anthropic format); ShareGPT has no tool role or images,
+// and the Anthropic format has a single system prompt. By default
+// such differences are resolved the way the SDK's providers resolve
+// them: system prompts are hoisted, tool results without a call are
+// downgraded to user messages prefixed with "[tool result]", and parts
+// a format cannot hold are dropped. Options.Strict turns every such
+// conversion into a *LossError instead.
+package chatcodec
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	ai "github.com/ncecere/ai-sdk"
+)
+
+// Format identifies a dataset format.
+type Format string
+
+const (
+	// FormatOpenAI is the OpenAI chat fine-tuning format:
+	// {"messages":[{"role":"user","content":"..."}, ...]}.
+	FormatOpenAI Format = "openai"
+	// FormatShareGPT is the ShareGPT format:
+	// {"conversations":[{"from":"human","value":"..."}, ...]}.
+	FormatShareGPT Format = "sharegpt"
+	// FormatAnthropic is the Anthropic Messages format:
+	// {"system":"...","messages":[{"role":"user","content":"..."}, ...]}.
+	FormatAnthropic Format = "anthropic"
+)
+
+// Options controls conversions.
+type Options struct {
+	// Strict rejects conversions that would lose or rewrite information
+	// with a *LossError instead of resolving them.
+	Strict bool
+}
+
+// LossError is returned in strict mode when a conversion would lose or
+// rewrite information.
+type LossError struct {
+	// Format is the format being converted to or from.
+	Format Format
+	// Message is the index of the offending message, or -1 for
+	// record-level fields.
+	Message int
+	// Reason describes what would be lost.
+	Reason string
+}
+
+func (e *LossError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	if e.Message < 0 {
+		return "chatcodec: " + string(e.Format) + ": " + e.Reason
+	}
+	return "chatcodec: " + string(e.Format) + ": message " + strconv.Itoa(e.Message) + ": " + e.Reason
+}
+
+// toolResultPrefix marks tool results downgraded to user messages; it
+// matches the prefix used by the providers' orphan tool handling.
+const toolResultPrefix = "[tool result] "
+
+// Marshal encodes msgs as a single record in format f, without a
+// trailing newline.
+//
+// Errors:
+//   - *LossError in strict mode if msgs cannot be represented exactly.
+//   - An error if f is not a known Format.
+func Marshal(f Format, msgs []ai.Message, opts Options) ([]byte, error) {
+	c := converter{format: f, strict: opts.Strict}
+	switch f {
+	case FormatOpenAI:
+		return c.marshalOpenAI(msgs)
+	case FormatShareGPT:
+		return c.marshalShareGPT(msgs)
+	case FormatAnthropic:
+		return c.marshalAnthropic(msgs)
+	}
+	return nil, fmt.Errorf("chatcodec: unknown format %q", f)
+}
+
+// Unmarshal decodes a single record in format f.
+//
+// Errors:
+//   - *LossError in strict mode if the record holds information that
+//     ai.Message cannot carry, such as tool definitions.
+//   - Any JSON decoding error.
+//   - An error if f is not a known Format.
+func Unmarshal(f Format, data []byte, opts Options) ([]ai.Message, error) {
+	c := converter{format: f, strict: opts.Strict}
+	switch f {
+	case FormatOpenAI:
+		return c.unmarshalOpenAI(data)
+	case FormatShareGPT:
+		return c.unmarshalShareGPT(data)
+	case FormatAnthropic:
+		return c.unmarshalAnthropic(data)
+	}
+	return nil, fmt.Errorf("chatcodec: unknown format %q", f)
+}
+
+// converter carries the format and strictness of one conversion.
+type converter struct {
+	format Format
+	strict bool
+}
+
+// loss returns a *LossError in strict mode and nil otherwise, in which
+// case the caller resolves the difference.
+func (c converter) loss(msg int, reason string) error {
+	if !c.strict {
+		return nil
+	}
+	return &LossError{Format: c.format, Message: msg, Reason: reason}
+}
+
+// hasImages reports whether m carries image parts.
+func hasImages(m ai.Message) bool {
+	for _, p := range m.Parts {
+		if p.Type == ai.ContentPartImage {
+			return true
+		}
+	}
+	return false
+}
+
+// textOf returns the text of m, joining text parts when Content is
+// empty.
+func textOf(m ai.Message) string {
+	if m.Content != "" || len(m.Parts) == 0 {
+		return m.Content
+	}
+	var text []string
+	for _, p := range m.Parts {
+		if p.Type == ai.ContentPartText && p.Text != "" {
+			text = append(text, p.Text)
+		}
+	}
+	return strings.Join(text, "\n")
+}
+
+// partsMessage builds a message from parts, rendering the text parts
+// into Content for providers without multi-part support.
+func partsMessage(role string, parts []ai.ContentPart) ai.Message {
+	m := ai.Message{Role: role, Parts: parts}
+	m.Content = textOf(m)
+	return m
+}
+
+// dataURI encodes inline image data as a data URI.
+func dataURI(p ai.ContentPart) string {
+	mt := p.MimeType
+	if mt == "" {
+		mt = "image/png"
+	}
+	return "data:" + mt + ";base64," + base64.StdEncoding.EncodeToString(p.Data)
+}
+
+// imagePartFromURL returns an inline part for base64 data URIs and a
+// URL part otherwise.
+func imagePartFromURL(url string) ai.ContentPart {
+	if rest, ok := strings.CutPrefix(url, "data:"); ok {
+		meta, payload, found := strings.Cut(rest, ",")
+		if mt, isBase64 := strings.CutSuffix(meta, ";base64"); found && isBase64 {
+			if data, err := base64.StdEncoding.DecodeString(payload); err == nil {
+				return ai.ImagePart(data, mt)
+			}
+		}
+	}
+	return ai.ImageURLPart(url)
+}
@@ -0,0 +1,288 @@
+package chatcodec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	ai "github.com/ncecere/ai-sdk"
+)
+
+// readLines returns the non-blank lines of a testdata file.
+func readLines(t *testing.T, name string) [][]byte {
+	t.Helper()
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var lines [][]byte
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if line := bytes.TrimSpace(sc.Bytes()); len(line) > 0 {
+			lines = append(lines, append([]byte(nil), line...))
+		}
+	}
+	return lines
+}
+
+func assertSameJSON(t *testing.T, want, got []byte) {
+	t.Helper()
+	var w, g any
+	if err := json.Unmarshal(want, &w); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("invalid JSON %s: %v", got, err)
+	}
+	if !reflect.DeepEqual(w, g) {
+		t.Fatalf("round trip mismatch:\nwant %s\n got %s", want, got)
+	}
+}
+
+func TestRoundTrip_PublishedExamples(t *testing.T) {
+	for _, tc := range []struct {
+		format Format
+		file   string
+	}{
+		{FormatOpenAI, "openai.jsonl"},
+		{FormatShareGPT, "sharegpt.jsonl"},
+		{FormatAnthropic, "anthropic.jsonl"},
+	} {
+		t.Run(string(tc.format), func(t *testing.T) {
+			for _, line := range readLines(t, tc.file) {
+				msgs, err := Unmarshal(tc.format, line, Options{Strict: true})
+				if err != nil {
+					t.Fatalf("Unmarshal error: %v", err)
+				}
+				out, err := Marshal(tc.format, msgs, Options{Strict: true})
+				if err != nil {
+					t.Fatalf("Marshal error: %v", err)
+				}
+				assertSameJSON(t, line, out)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_OpenAIMapsPartsAndMetadata(t *testing.T) {
+	lines := readLines(t, "openai.jsonl")
+	msgs, err := Unmarshal(FormatOpenAI, lines[1], Options{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := msgs[2].Metadata[MetadataWeight]; w != 0.0 {
+		t.Fatalf("expected weight 0 on the first answer, got %v", w)
+	}
+
+	msgs, err = Unmarshal(FormatOpenAI, lines[3], Options{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := msgs[0].Parts[1]
+	if img.MimeType != "image/png" || !bytes.HasPrefix(img.Data, []byte("\x89PNG")) {
+		t.Fatalf("expected the data URI decoded inline, got %+v", img)
+	}
+	if msgs[0].Content != "What is in this image?" {
+		t.Fatalf("expected a plain-text rendering in Content, got %q", msgs[0].Content)
+	}
+	if msgs[1].Metadata[MetadataName] != "inspector" {
+		t.Fatalf("expected the name in metadata, got %v", msgs[1].Metadata)
+	}
+}
+
+func TestUnmarshal_OpenAIToolCalls(t *testing.T) {
+	line := readLines(t, "openai_tools.jsonl")[0]
+
+	_, err := Unmarshal(FormatOpenAI, line, Options{Strict: true})
+	var loss *LossError
+	if !errors.As(err, &loss) || loss.Message != -1 {
+		t.Fatalf("expected a record-level LossError for tools, got %v", err)
+	}
+
+	msgs, err := Unmarshal(FormatOpenAI, line, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roles := make([]string, len(msgs))
+	for i, m := range msgs {
+		roles[i] = m.Role
+	}
+	if want := []string{ai.RoleUser, ai.RoleAssistant, ai.RoleTool, ai.RoleAssistant}; !reflect.DeepEqual(roles, want) {
+		t.Fatalf("unexpected roles %v", roles)
+	}
+	call := msgs[1].ToolCalls
+	if len(call) != 1 || call[0].ID != "call_id" || call[0].Name != "get_current_weather" || string(call[0].RawArguments) != `{"location": "San Francisco, USA", "format": "celsius"}` || msgs[2].ToolCallID != "call_id" {
+		t.Fatalf("tool call not carried: %+v", msgs[1:3])
+	}
+
+	// Tool calls and results round-trip without loss.
+	out, err := Marshal(FormatOpenAI, msgs, Options{Strict: true})
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	assertSameJSON(t, []byte(`{"messages": [
+		{"role": "user", "content": "What is the weather in San Francisco?"},
+		{"role": "assistant", "tool_calls": [{"id": "call_id", "type": "function", "function": {"name": "get_current_weather", "arguments": "{\"location\": \"San Francisco, USA\", \"format\": \"celsius\"}"}}]},
+		{"role": "tool", "tool_call_id": "call_id", "content": "{\"temperature\": 18}"},
+		{"role": "assistant", "content": "It is 18°C in San Francisco."}]}`), out)
+}
+
+func TestMarshal_LossyConversions(t *testing.T) {
+	msgs := []ai.Message{
+		ai.UserMessage("Look at this"),
+		{Role: ai.RoleUser, Parts: []ai.ContentPart{ai.TextPart("and this"), ai.ImageURLPart("https://example.com/a.png")}, Content: "and this"},
+		{Role: ai.RoleTool, Content: `{"temperature":18}`},
+		ai.SystemMessage("Answer briefly."),
+		ai.AssistantMessage("Done."),
+	}
+
+	for _, f := range []Format{FormatOpenAI, FormatShareGPT, FormatAnthropic} {
+		_, err := Marshal(f, msgs, Options{Strict: true})
+		var loss *LossError
+		if !errors.As(err, &loss) || loss.Format != f {
+			t.Fatalf("%s: expected LossError in strict mode, got %v", f, err)
+		}
+	}
+
+	out, err := Marshal(FormatShareGPT, msgs, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameJSON(t, []byte(`{"conversations":[
+		{"from":"human","value":"Look at this"},
+		{"from":"human","value":"and this"},
+		{"from":"human","value":"[tool result] {\"temperature\":18}"},
+		{"from":"system","value":"Answer briefly."},
+		{"from":"gpt","value":"Done."}]}`), out)
+
+	out, err = Marshal(FormatAnthropic, msgs, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameJSON(t, []byte(`{"system":"Answer briefly.","messages":[
+		{"role":"user","content":"Look at this"},
+		{"role":"user","content":[{"type":"text","text":"and this"},{"type":"image","source":{"type":"url","url":"https://example.com/a.png"}}]},
+		{"role":"user","content":"[tool result] {\"temperature\":18}"},
+		{"role":"assistant","content":"Done."}]}`), out)
+}
+
+func TestAnthropicToolCalls(t *testing.T) {
+	line := []byte(`{"messages":[
+		{"role":"user","content":"Weather in Paris and Rome?"},
+		{"role":"assistant","content":[{"type":"text","text":"Checking."},{"type":"tool_use","id":"toolu_1","name":"weather","input":{"city":"Paris"}},{"type":"tool_use","id":"toolu_2","name":"weather","input":{"city":"Rome"}}]},
+		{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"18C"},{"type":"tool_result","tool_use_id":"toolu_2","content":"24C"}]},
+		{"role":"assistant","content":"18C in Paris, 24C in Rome."}]}`)
+
+	msgs, err := Unmarshal(FormatAnthropic, line, Options{Strict: true})
+	if err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	roles := make([]string, len(msgs))
+	for i, m := range msgs {
+		roles[i] = m.Role
+	}
+	if want := []string{ai.RoleUser, ai.RoleAssistant, ai.RoleTool, ai.RoleTool, ai.RoleAssistant}; !reflect.DeepEqual(roles, want) {
+		t.Fatalf("unexpected roles %v", roles)
+	}
+	calls := msgs[1].ToolCalls
+	if msgs[1].Content != "Checking." || len(calls) != 2 || calls[1].ID != "toolu_2" || calls[1].Name != "weather" || string(calls[1].RawArguments) != `{"city":"Rome"}` {
+		t.Fatalf("tool calls not carried: %+v", msgs[1])
+	}
+	if msgs[2].ToolCallID != "toolu_1" || msgs[2].Content != "18C" || msgs[3].ToolCallID != "toolu_2" {
+		t.Fatalf("tool results not carried: %+v", msgs[2:4])
+	}
+
+	// Consecutive results are sent back in one user message.
+	out, err := Marshal(FormatAnthropic, msgs, Options{Strict: true})
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	assertSameJSON(t, line, out)
+
+	// OpenAI tool calls, with arguments as a JSON string, convert too.
+	openAI, err := Unmarshal(FormatOpenAI, readLines(t, "openai_tools.jsonl")[0], Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err = Marshal(FormatAnthropic, openAI, Options{Strict: true})
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	assertSameJSON(t, []byte(`{"messages": [
+		{"role": "user", "content": "What is the weather in San Francisco?"},
+		{"role": "assistant", "content": [{"type": "tool_use", "id": "call_id", "name": "get_current_weather", "input": {"location": "San Francisco, USA", "format": "celsius"}}]},
+		{"role": "user", "content": [{"type": "tool_result", "tool_use_id": "call_id", "content": "{\"temperature\": 18}"}]},
+		{"role": "assistant", "content": "It is 18°C in San Francisco."}]}`), out)
+}
+
+func TestUnmarshal_ShareGPTUnknownSpeaker(t *testing.T) {
+	line := []byte(`{"conversations":[{"from":"human","value":"hi"},{"from":"observation","value":"{}"},{"from":"gpt","value":"hello"}]}`)
+	if _, err := Unmarshal(FormatShareGPT, line, Options{Strict: true}); err == nil {
+		t.Fatal("expected LossError in strict mode")
+	}
+	msgs, err := Unmarshal(FormatShareGPT, line, Options{})
+	if err != nil || len(msgs) != 2 {
+		t.Fatalf("expected the unknown turn skipped, got %+v, %v", msgs, err)
+	}
+}
+
+func TestWriterReader_ConvertsDatasets(t *testing.T) {
+	src := strings.Join([]string{
+		string(readLines(t, "sharegpt.jsonl")[0]),
+		"",
+		string(readLines(t, "sharegpt.jsonl")[1]),
+	}, "\n")
+
+	var buf bytes.Buffer
+	r := NewReader(strings.NewReader(src), FormatShareGPT, Options{Strict: true})
+	w := NewWriter(&buf, FormatOpenAI, Options{Strict: true})
+	var n int
+	for {
+		msgs, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Write(msgs); err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || strings.Count(buf.String(), "\n") != 2 {
+		t.Fatalf("expected 2 records, got %d:\n%s", n, buf.String())
+	}
+
+	back := NewReader(&buf, FormatOpenAI, Options{Strict: true})
+	for _, want := range readLines(t, "sharegpt.jsonl") {
+		msgs, err := back.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := Marshal(FormatShareGPT, msgs, Options{Strict: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertSameJSON(t, want, out)
+	}
+	if _, err := back.Read(); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+
+	bad := NewReader(strings.NewReader("{}\nnot json\n"), FormatOpenAI, Options{})
+	bad.Read()
+	if _, err := bad.Read(); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("expected an error naming line 2, got %v", err)
+	}
+}
@@ -0,0 +1,249 @@
+package chatcodec
+
+import (
+	"encoding/json"
+	"fmt"
+
+	ai "github.com/ncecere/ai-sdk"
+)
+
+// openAIRecord is one line of an OpenAI chat fine-tuning dataset.
+type openAIRecord struct {
+	Messages          []openAIMessage `json:"messages"`
+	Tools             json.RawMessage `json:"tools,omitempty"`
+	ParallelToolCalls json.RawMessage `json:"parallel_tool_calls,omitempty"`
+}
+
+type openAIMessage struct {
+	Role       string          `json:"role"`
+	Content    json.RawMessage `json:"content,omitempty"`
+	Name       string          `json:"name,omitempty"`
+	Weight     *float64        `json:"weight,omitempty"`
+	ToolCalls  json.RawMessage `json:"tool_calls,omitempty"`
+	ToolCallID string          `json:"tool_call_id,omitempty"`
+}
+
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAIPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+}
+
+type openAIImageURL struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Message.Metadata keys mapped to the OpenAI message fields of the same
+// name. Weight is a float64; name is a string.
+const (
+	MetadataName   = "name"
+	MetadataWeight = "weight"
+)
+
+func (c converter) marshalOpenAI(msgs []ai.Message) ([]byte, error) {
+	rec := openAIRecord{Messages: make([]openAIMessage, 0, len(msgs))}
+	for i, m := range msgs {
+		om := openAIMessage{Role: m.Role}
+		if name, ok := m.Metadata[MetadataName].(string); ok {
+			om.Name = name
+		}
+		if w, ok := metadataFloat(m.Metadata[MetadataWeight]); ok {
+			om.Weight = &w
+		}
+		if m.Role == ai.RoleTool && m.ToolCallID == "" {
+			// The format requires tool_call_id.
+			if err := c.loss(i, "tool message without a tool_call_id"); err != nil {
+				return nil, err
+			}
+			om.Role = ai.RoleUser
+			om.Content = mustJSON(toolResultPrefix + textOf(m))
+			rec.Messages = append(rec.Messages, om)
+			continue
+		}
+		if m.Role == ai.RoleTool {
+			om.ToolCallID = m.ToolCallID
+		}
+		if len(m.ToolCalls) > 0 {
+			om.ToolCalls = mustJSON(toOpenAIToolCalls(m.ToolCalls))
+			if m.Content == "" && len(m.Parts) == 0 {
+				rec.Messages = append(rec.Messages, om)
+				continue
+			}
+		}
+		if len(m.Parts) == 0 {
+			om.Content = mustJSON(m.Content)
+			rec.Messages = append(rec.Messages, om)
+			continue
+		}
+		parts := make([]openAIPart, 0, len(m.Parts))
+		for _, p := range m.Parts {
+			switch p.Type {
+			case ai.ContentPartText:
+				parts = append(parts, openAIPart{Type: "text", Text: p.Text})
+			case ai.ContentPartImage:
+				url := p.ImageURL
+				if len(p.Data) > 0 {
+					url = dataURI(p)
+				}
+				parts = append(parts, openAIPart{Type: "image_url", ImageURL: &openAIImageURL{URL: url}})
+			default:
+				if err := c.loss(i, fmt.Sprintf("unsupported content part %q", p.Type)); err != nil {
+					return nil, err
+				}
+			}
+		}
+		om.Content = mustJSON(parts)
+		rec.Messages = append(rec.Messages, om)
+	}
+	return json.Marshal(rec)
+}
+
+func (c converter) unmarshalOpenAI(data []byte) ([]ai.Message, error) {
+	var rec openAIRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("chatcodec: decoding openai record: %w", err)
+	}
+	if len(rec.Tools) > 0 {
+		if err := c.loss(-1, "tool definitions are not carried by messages"); err != nil {
+			return nil, err
+		}
+	}
+	msgs := make([]ai.Message, 0, len(rec.Messages))
+	for i, om := range rec.Messages {
+		m := ai.Message{Role: om.Role}
+		switch om.Role {
+		case ai.RoleSystem, ai.RoleUser, ai.RoleAssistant, ai.RoleTool:
+		case "developer":
+			m.Role = ai.RoleSystem
+		default:
+			if err := c.loss(i, fmt.Sprintf("unknown role %q", om.Role)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if om.ToolCallID != "" && m.Role != ai.RoleTool {
+			if err := c.loss(i, "tool_call_id is only carried by tool messages"); err != nil {
+				return nil, err
+			}
+		}
+		if err := c.decodeOpenAIContent(i, om.Content, &m); err != nil {
+			return nil, err
+		}
+		if m.Role == ai.RoleTool {
+			m.ToolCallID = om.ToolCallID
+		}
+		if hasJSON(om.ToolCalls) {
+			var calls []openAIToolCall
+			if err := json.Unmarshal(om.ToolCalls, &calls); err != nil {
+				return nil, fmt.Errorf("chatcodec: decoding openai message %d tool calls: %w", i, err)
+			}
+			for _, tc := range calls {
+				m.ToolCalls = append(m.ToolCalls, ai.ToolCall{ID: tc.ID, Name: tc.Function.Name, RawArguments: []byte(tc.Function.Arguments)})
+			}
+		}
+		if om.Name != "" {
+			setMetadata(&m, MetadataName, om.Name)
+		}
+		if om.Weight != nil {
+			setMetadata(&m, MetadataWeight, *om.Weight)
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, nil
+}
+
+// toOpenAIToolCalls renders calls with their arguments as a JSON string.
+// Arguments that already are a JSON string (as returned by the OpenAI
+// client) are not quoted again.
+func toOpenAIToolCalls(calls []ai.ToolCall) []openAIToolCall {
+	out := make([]openAIToolCall, 0, len(calls))
+	for _, tc := range calls {
+		wire := openAIToolCall{ID: tc.ID, Type: "function"}
+		wire.Function.Name = tc.Name
+		wire.Function.Arguments = string(tc.RawArguments)
+		var s string
+		if json.Unmarshal(tc.RawArguments, &s) == nil {
+			wire.Function.Arguments = s
+		}
+		out = append(out, wire)
+	}
+	return out
+}
+
+// decodeOpenAIContent decodes a string, null, or content-part array into m.
+func (c converter) decodeOpenAIContent(i int, raw json.RawMessage, m *ai.Message) error {
+	if !hasJSON(raw) {
+		return nil
+	}
+	if raw[0] == '"' {
+		return json.Unmarshal(raw, &m.Content)
+	}
+	var parts []openAIPart
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return fmt.Errorf("chatcodec: decoding openai message %d content: %w", i, err)
+	}
+	out := make([]ai.ContentPart, 0, len(parts))
+	for _, p := range parts {
+		switch {
+		case p.Type == "text":
+			out = append(out, ai.TextPart(p.Text))
+		case p.Type == "image_url" && p.ImageURL != nil:
+			out = append(out, imagePartFromURL(p.ImageURL.URL))
+		default:
+			if err := c.loss(i, fmt.Sprintf("unsupported content part %q", p.Type)); err != nil {
+				return err
+			}
+		}
+	}
+	*m = partsMessage(m.Role, out)
+	return nil
+}
+
+// hasJSON reports whether raw holds a value other than null.
+func hasJSON(raw json.RawMessage) bool {
+	return len(raw) > 0 && string(raw) != "null"
+}
+
+func mustJSON(v any) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func setMetadata(m *ai.Message, key string, value any) {
+	if m.Metadata == nil {
+		m.Metadata = map[string]any{}
+	}
+	m.Metadata[key] = value
+}
+
+// metadataFloat accepts the numeric types a weight may have been stored
+// as by application code or decoded as from JSON.
+func metadataFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,95 @@
+package chatcodec
+
+import (
+	"encoding/json"
+	"fmt"
+
+	ai "github.com/ncecere/ai-sdk"
+)
+
+// shareGPTRecord is one line of a ShareGPT dataset.
+type shareGPTRecord struct {
+	Conversations []shareGPTTurn `json:"conversations"`
+}
+
+type shareGPTTurn struct {
+	From   string   `json:"from"`
+	Value  string   `json:"value"`
+	Weight *float64 `json:"weight,omitempty"`
+}
+
+// shareGPTRoles maps ShareGPT speakers to message roles.
+var shareGPTRoles = map[string]string{
+	"system":  ai.RoleSystem,
+	"human":   ai.RoleUser,
+	"user":    ai.RoleUser,
+	"gpt":     ai.RoleAssistant,
+	"chatgpt": ai.RoleAssistant,
+}
+
+func (c converter) marshalShareGPT(msgs []ai.Message) ([]byte, error) {
+	rec := shareGPTRecord{Conversations: make([]shareGPTTurn, 0, len(msgs))}
+	for i, m := range msgs {
+		turn := shareGPTTurn{Value: textOf(m)}
+		switch m.Role {
+		case ai.RoleSystem:
+			turn.From = "system"
+		case ai.RoleUser:
+			turn.From = "human"
+		case ai.RoleAssistant:
+			turn.From = "gpt"
+			if len(m.ToolCalls) > 0 {
+				if err := c.loss(i, "ShareGPT cannot hold tool calls"); err != nil {
+					return nil, err
+				}
+				if turn.Value == "" {
+					continue
+				}
+			}
+		case ai.RoleTool:
+			if err := c.loss(i, "ShareGPT has no tool role"); err != nil {
+				return nil, err
+			}
+			turn.From = "human"
+			turn.Value = toolResultPrefix + turn.Value
+		default:
+			if err := c.loss(i, fmt.Sprintf("unknown role %q", m.Role)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if hasImages(m) {
+			if err := c.loss(i, "ShareGPT cannot hold images"); err != nil {
+				return nil, err
+			}
+		}
+		if w, ok := metadataFloat(m.Metadata[MetadataWeight]); ok {
+			turn.Weight = &w
+		}
+		rec.Conversations = append(rec.Conversations, turn)
+	}
+	return json.Marshal(rec)
+}
+
+func (c converter) unmarshalShareGPT(data []byte) ([]ai.Message, error) {
+	var rec shareGPTRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("chatcodec: decoding sharegpt record: %w", err)
+	}
+	msgs := make([]ai.Message, 0, len(rec.Conversations))
+	for i, turn := range rec.Conversations {
+		role, ok := shareGPTRoles[turn.From]
+		if !ok {
+			if err := c.loss(i, fmt.Sprintf("unknown speaker %q", turn.From)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		m := ai.Message{Role: role, Content: turn.Value}
+		if turn.Weight != nil {
+			setMetadata(&m, MetadataWeight, *turn.Weight)
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, nil
+}
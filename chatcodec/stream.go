@@ -0,0 +1,88 @@
+package chatcodec
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	ai "github.com/ncecere/ai-sdk"
+)
+
+// Writer writes conversations as JSONL records, one per line. Output is
+// buffered; call Flush when done.
+type Writer struct {
+	w      *bufio.Writer
+	format Format
+	opts   Options
+}
+
+// NewWriter returns a Writer that encodes conversations in format f.
+func NewWriter(w io.Writer, f Format, opts Options) *Writer {
+	return &Writer{w: bufio.NewWriter(w), format: f, opts: opts}
+}
+
+// Write encodes msgs as one record.
+//
+// Errors:
+//   - *LossError in strict mode; nothing is written for the record.
+//   - Any error from the underlying writer.
+func (w *Writer) Write(msgs []ai.Message) error {
+	data, err := Marshal(w.format, msgs, w.opts)
+	if err != nil {
+		return err
+	}
+	if _, err := w.w.Write(data); err != nil {
+		return err
+	}
+	return w.w.WriteByte('\n')
+}
+
+// Flush writes buffered records to the underlying writer.
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+// Reader reads conversations from JSONL records. Records may be of any
+// length; blank lines are skipped.
+type Reader struct {
+	r      *bufio.Reader
+	format Format
+	opts   Options
+	line   int
+}
+
+// NewReader returns a Reader that decodes conversations in format f.
+func NewReader(r io.Reader, f Format, opts Options) *Reader {
+	return &Reader{r: bufio.NewReader(r), format: f, opts: opts}
+}
+
+// Read returns the next conversation, or io.EOF after the last one.
+//
+// Errors:
+//   - io.EOF when there are no more records.
+//   - A decoding error or *LossError, wrapped with the line number.
+//     Reading may continue with the next record.
+//   - Any error from the underlying reader.
+func (r *Reader) Read() ([]ai.Message, error) {
+	for {
+		line, err := r.r.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			return nil, err
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		r.line++
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		msgs, derr := Unmarshal(r.format, line, r.opts)
+		if derr != nil {
+			return nil, fmt.Errorf("line %d: %w", r.line, derr)
+		}
+		return msgs, nil
+	}
+}
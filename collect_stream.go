@@ -0,0 +1,76 @@
+package ai
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// CollectStream drains stream into a single response shaped like the
+// one GenerateText returns: the concatenated text and reasoning, the
+// tool calls assembled by a ToolCallAccumulator, and the usage of the
+// final delta. Streams do not report
+// the provider's stop reason, so StopReason is empty and FinishReason
+// is FinishReasonToolCalls when the model called tools. The stream is
+// closed when CollectStream returns.
+//
+// Errors:
+//   - The error of ctx or of stream.Next if the stream fails before any
+//     content was received.
+//   - *PartialResponseError if it fails after content was received,
+//     holding the text and reasoning received so far, and any tool
+//     calls, whose arguments may be cut off. It unwraps to the failure,
+//     such as context.Canceled.
+func CollectStream(ctx context.Context, stream TextStream) (GenerateTextResponse, error) {
+	return TeeStream(ctx, stream, nil)
+}
+
+// TeeStream is like CollectStream but also passes each delta to
+// onDelta before collecting it, for example to stream it to a client
+// while keeping the full response for logging. onDelta runs on the
+// calling goroutine and must not retain the delta. If it returns an
+// error, TeeStream stops and returns that error as is. A nil onDelta
+// makes TeeStream equivalent to CollectStream.
+//
+// Errors:
+//   - Any error returned by onDelta.
+//   - The errors of CollectStream.
+func TeeStream(ctx context.Context, stream TextStream, onDelta func(*TextDelta) error) (GenerateTextResponse, error) {
+	defer stream.Close()
+
+	var text, reasoning strings.Builder
+	var calls ToolCallAccumulator
+	for {
+		delta, err := stream.Next(ctx)
+		if err != nil {
+			if text.Len() == 0 && reasoning.Len() == 0 {
+				return GenerateTextResponse{}, err
+			}
+			return GenerateTextResponse{}, &PartialResponseError{
+				Partial: GenerateTextResponse{Text: text.String(), Reasoning: reasoning.String(), ToolCalls: calls.Calls()},
+				Err:     err,
+			}
+		}
+		if onDelta != nil {
+			if err := onDelta(delta); err != nil {
+				return GenerateTextResponse{}, err
+			}
+		}
+		text.WriteString(delta.Text)
+		reasoning.WriteString(delta.Reasoning)
+		calls.Add(*delta)
+		if delta.Done {
+			return newGenerateTextResponse(&provider.LanguageModelResponse{
+				Text:         text.String(),
+				Reasoning:    reasoning.String(),
+				ToolCalls:    calls.Calls(),
+				Usage:        delta.Usage,
+				StopReason:   delta.StopReason,
+				FinishReason: delta.FinishReason,
+				Warnings:     delta.Warnings,
+				Metadata:     delta.Metadata,
+			}), nil
+		}
+	}
+}
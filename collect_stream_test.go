@@ -0,0 +1,87 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+func TestCollectStream_ToolCalls(t *testing.T) {
+	for _, tc := range mixedCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var bodies []map[string]any
+			ts := fixtureServer(t, "toolcalls/"+tc.name+"_stream.sse", &bodies)
+			defer ts.Close()
+			model, err := tc.newModel(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+			if err != nil {
+				t.Fatalf("NewClient error: %v", err)
+			}
+			stream, err := StreamText(context.Background(), GenerateTextRequest{
+				Model:    model,
+				Messages: []Message{UserMessage("Weather in Paris?")},
+				Tools:    []ToolDefinition{{Name: "weather", Parameters: []byte(`{"type":"object"}`)}},
+			})
+			if err != nil {
+				t.Fatalf("StreamText error: %v", err)
+			}
+
+			var deltas int
+			res, err := TeeStream(context.Background(), stream, func(delta *TextDelta) error {
+				deltas++
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("TeeStream error: %v", err)
+			}
+			if res.Text != "Let me check the weather." || res.FinishReason != FinishReasonToolCalls || deltas < 2 {
+				t.Fatalf("unexpected response %+v after %d deltas", res, deltas)
+			}
+			checkWeatherCall(t, res.ToolCalls)
+		})
+	}
+}
+
+func TestCollectStream_Errors(t *testing.T) {
+	// A failure after content keeps what was received.
+	source := &scriptStream{texts: []string{"Hello", " wor"}, err: io.ErrUnexpectedEOF}
+	_, err := CollectStream(context.Background(), source)
+	var partial *PartialResponseError
+	if !errors.As(err, &partial) || partial.Partial.Text != "Hello wor" || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected *PartialResponseError, got %v", err)
+	}
+	if !source.closed.Load() {
+		t.Fatalf("stream not closed")
+	}
+
+	// Cancellation mid-stream is propagated.
+	ctx, cancel := context.WithCancel(context.Background())
+	source = &scriptStream{texts: []string{"a"}, forever: true}
+	_, err = TeeStream(ctx, source, func(delta *TextDelta) error {
+		if source.n == 3 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) || !errors.As(err, &partial) || partial.Partial.Text != "aaa" {
+		t.Fatalf("expected a cancelled partial response, got %v", err)
+	}
+
+	// An onDelta error stops collection and is returned as is.
+	stop := errors.New("client went away")
+	source = &scriptStream{texts: []string{"a", "b"}}
+	if _, err := TeeStream(context.Background(), source, func(*TextDelta) error { return stop }); err != stop || !source.closed.Load() {
+		t.Fatalf("expected the callback error, got %v", err)
+	}
+
+	// A failure before any content is returned unwrapped.
+	if _, err := CollectStream(context.Background(), &scriptStream{err: io.ErrUnexpectedEOF}); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected the stream error, got %v", err)
+	}
+	if res, err := CollectStream(context.Background(), &scriptStream{texts: strings.Fields("x y z")}); err != nil || res.Text != "xyz" {
+		t.Fatalf("CollectStream = %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,77 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// TextPart creates a text content part.
+func TextPart(text string) ContentPart {
+	return ContentPart{Type: ContentPartText, Text: text}
+}
+
+// ImagePart creates an inline image content part from raw bytes.
+func ImagePart(data []byte, mimeType string) ContentPart {
+	return ContentPart{Type: ContentPartImage, Data: data, MimeType: mimeType}
+}
+
+// ImageURLPart creates an image content part referencing a remote URL.
+func ImageURLPart(url string) ContentPart {
+	return ContentPart{Type: ContentPartImage, ImageURL: url}
+}
+
+// ToolResult is a multi-part tool result. Tools may return a ToolResult
+// (or *ToolResult) instead of a JSON-encodable value when the result
+// includes images, for example a browser tool returning a screenshot.
+type ToolResult struct {
+	// Parts is the ordered content of the result.
+	Parts []ContentPart
+}
+
+// NewToolResultMessage builds a RoleTool message carrying the result of
+// call.
+//
+// The message's ToolCallID is call.ID. Plain values are JSON-encoded
+// together with the tool name (and call ID when present) into Content.
+// A ToolResult is carried in Parts, with the text parts concatenated
+// into Content for providers that do not support multi-part tool
+// results.
+func NewToolResultMessage(call ToolCall, result any) (Message, error) {
+	switch r := result.(type) {
+	case ToolResult:
+		return toolResultPartsMessage(call, r), nil
+	case *ToolResult:
+		if r != nil {
+			return toolResultPartsMessage(call, *r), nil
+		}
+	}
+
+	payload := map[string]any{
+		"tool":   call.Name,
+		"result": result,
+	}
+	if call.ID != "" {
+		payload["tool_call_id"] = call.ID
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Message{}, fmt.Errorf("ai: encoding tool result: %w", err)
+	}
+	return Message{Role: RoleTool, Content: string(data), ToolCallID: call.ID}, nil
+}
+
+func toolResultPartsMessage(call ToolCall, r ToolResult) Message {
+	var text []string
+	for _, p := range r.Parts {
+		if p.Type == ContentPartText && p.Text != "" {
+			text = append(text, p.Text)
+		}
+	}
+	return Message{
+		Role:       RoleTool,
+		Content:    strings.Join(text, "\n"),
+		Parts:      append([]ContentPart(nil), r.Parts...),
+		ToolCallID: call.ID,
+	}
+}
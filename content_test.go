@@ -0,0 +1,52 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/anthropic"
+	"github.com/ncecere/ai-sdk/openai"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+func TestNewToolResultMessage_SetsToolCallID(t *testing.T) {
+	call := ToolCall{ID: "call_1", Name: "add"}
+	msg, err := NewToolResultMessage(call, 8)
+	if err != nil {
+		t.Fatalf("NewToolResultMessage error: %v", err)
+	}
+	if msg.Role != RoleTool || msg.ToolCallID != "call_1" || msg.Content != `{"result":8,"tool":"add","tool_call_id":"call_1"}` {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+	msg, _ = NewToolResultMessage(call, ToolResult{Parts: []ContentPart{{Type: ContentPartText, Text: "done"}}})
+	if msg.ToolCallID != "call_1" || msg.Content != "done" {
+		t.Fatalf("unexpected parts message %+v", msg)
+	}
+}
+
+func TestUserMessageWithImage_ProviderBodies(t *testing.T) {
+	msg := UserMessageWithImage("What is this?", []byte{0x89, 'P', 'N', 'G'}, "image/png")
+	if msg.Role != RoleUser || msg.Content != "What is this?" || len(msg.Parts) != 2 {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+	req := &provider.LanguageModelRequest{Messages: []Message{msg}}
+
+	oc, _ := openai.NewClient(provider.ClientOptions{APIKey: "test"})
+	_, body, err := oc.ChatModel("gpt-4o").(provider.RequestBuilder).BuildRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("openai BuildRequest error: %v", err)
+	}
+	if want := `"content":[{"type":"text","text":"What is this?"},{"type":"image_url","image_url":{"url":"data:image/png;base64,iVBORw=="}}]`; !strings.Contains(string(body), want) {
+		t.Fatalf("unexpected openai body %s", body)
+	}
+
+	ac, _ := anthropic.NewClient(provider.ClientOptions{APIKey: "test"})
+	_, body, err = ac.ChatModel("claude-test").(provider.RequestBuilder).BuildRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("anthropic BuildRequest error: %v", err)
+	}
+	if want := `{"type":"image","source":{"type":"base64","media_type":"image/png","data":"iVBORw=="}}`; !strings.Contains(string(body), want) {
+		t.Fatalf("unexpected anthropic body %s", body)
+	}
+}
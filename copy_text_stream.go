@@ -0,0 +1,57 @@
+package ai
+
+import (
+	"context"
+	"io"
+	"strings"
+)
+
+// CopyTextStream writes the text of each delta of stream to w as it
+// arrives and returns the number of bytes written. Reasoning and tool
+// calls are not written. After each write w is flushed if it implements
+// StreamFlusher, as *bufio.Writer does, or http.Flusher, so a CLI or a
+// chunked HTTP response shows the text as it is generated. It returns
+// after the final (Done) delta and closes the stream before returning.
+//
+// Errors:
+//   - The error of ctx or of stream.Next if the stream fails before any
+//     text was written.
+//   - *PartialResponseError if it fails after text was written, holding
+//     that text. It unwraps to the failure, such as context.Canceled.
+//   - The write or flush error of w, wrapped the same way.
+func CopyTextStream(ctx context.Context, w io.Writer, stream TextStream) (int64, error) {
+	defer stream.Close()
+
+	var text strings.Builder
+	var n int64
+	fail := func(err error) (int64, error) {
+		if text.Len() == 0 {
+			return n, err
+		}
+		return n, &PartialResponseError{Partial: GenerateTextResponse{Text: text.String()}, Err: err}
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return fail(err)
+		}
+		delta, err := stream.Next(ctx)
+		if err != nil {
+			return fail(err)
+		}
+		if delta.Text != "" {
+			written, err := io.WriteString(w, delta.Text)
+			n += int64(written)
+			text.WriteString(delta.Text[:written])
+			if err != nil {
+				return fail(err)
+			}
+			if err := flushStream(w); err != nil {
+				return fail(err)
+			}
+		}
+		if delta.Done {
+			return n, nil
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+)
+
+// cancelWriter cancels its context after the given number of writes.
+type cancelWriter struct {
+	buf    bytes.Buffer
+	writes int
+	after  int
+	cancel context.CancelFunc
+}
+
+func (w *cancelWriter) Write(p []byte) (int, error) {
+	w.writes++
+	if w.writes == w.after {
+		w.cancel()
+	}
+	return w.buf.Write(p)
+}
+
+type failingWriter struct{ err error }
+
+func (w failingWriter) Write(p []byte) (int, error) { return 0, w.err }
+
+func TestCopyTextStream(t *testing.T) {
+	// A *bufio.Writer is flushed after every delta.
+	var out bytes.Buffer
+	buf := bufio.NewWriter(&out)
+	source := &scriptStream{texts: []string{"Hello", "", ", world"}}
+	n, err := CopyTextStream(context.Background(), buf, source)
+	if err != nil || n != 12 || out.String() != "Hello, world" || !source.closed.Load() {
+		t.Fatalf("CopyTextStream = %d, %v; wrote %q", n, err, out.String())
+	}
+
+	rec := httptest.NewRecorder()
+	if _, err := CopyTextStream(context.Background(), rec, &scriptStream{texts: []string{"a", "b"}}); err != nil || rec.Body.String() != "ab" || !rec.Flushed {
+		t.Fatalf("CopyTextStream to a ResponseWriter: %v, body %q, flushed %v", err, rec.Body.String(), rec.Flushed)
+	}
+}
+
+func TestCopyTextStream_Errors(t *testing.T) {
+	// Cancellation mid-stream returns the context error and what was
+	// written before it.
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &cancelWriter{after: 2, cancel: cancel}
+	source := &scriptStream{texts: []string{"ab"}, forever: true}
+	n, err := CopyTextStream(ctx, w, source)
+	var partial *PartialResponseError
+	if !errors.Is(err, context.Canceled) || !errors.As(err, &partial) || partial.Partial.Text != "abab" || n != 4 || !source.closed.Load() {
+		t.Fatalf("CopyTextStream = %d, %v", n, err)
+	}
+
+	// A failure before any text is returned unwrapped.
+	if n, err := CopyTextStream(context.Background(), io.Discard, &scriptStream{err: io.ErrUnexpectedEOF}); err != io.ErrUnexpectedEOF || n != 0 {
+		t.Fatalf("CopyTextStream = %d, %v; want the stream error", n, err)
+	}
+
+	broken := errors.New("broken pipe")
+	if _, err := CopyTextStream(context.Background(), failingWriter{broken}, &scriptStream{texts: []string{"a"}}); err != broken {
+		t.Fatalf("CopyTextStream = %v; want the write error", err)
+	}
+}
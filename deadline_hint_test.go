@@ -0,0 +1,123 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ncecere/ai-sdk/provider"
+	"github.com/ncecere/ai-sdk/providerutil"
+)
+
+func TestNewDeadlineHint(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	hint := &provider.DeadlineHintOptions{Header: "X-Request-Timeout"}
+	cases := []struct {
+		name     string
+		opts     *provider.DeadlineHintOptions
+		deadline time.Duration // from now; 0 means none
+		want     string        // "" means no hint
+		expired  bool
+	}{
+		{"not configured", nil, 30 * time.Second, "", false},
+		{"no deadline", hint, 0, "", false},
+		{"default margin", hint, 30 * time.Second, "29", false},
+		{"custom margin", &provider.DeadlineHintOptions{Margin: 250 * time.Millisecond}, 10500 * time.Millisecond, "10.25", false},
+		{"no margin", &provider.DeadlineHintOptions{Margin: -1}, 1234567 * time.Microsecond, "1.234", false},
+		{"within margin", hint, 800 * time.Millisecond, "", true},
+		{"under a millisecond left", hint, time.Second + 400*time.Microsecond, "", true},
+		{"already expired", hint, -time.Second, "", true},
+	}
+	for _, tc := range cases {
+		ctx := context.Background()
+		if tc.deadline != 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, now.Add(tc.deadline))
+			defer cancel()
+		}
+		h, err := providerutil.NewDeadlineHint(ctx, tc.opts, now)
+		if tc.expired {
+			if !errors.Is(err, context.DeadlineExceeded) || h != nil {
+				t.Errorf("%s: expected a DeadlineExceeded error, got %v, %v", tc.name, h, err)
+			}
+			continue
+		}
+		if err != nil || h.Seconds() != tc.want || (h == nil) != (tc.want == "") {
+			t.Errorf("%s: NewDeadlineHint = %q, %v; want %q", tc.name, h.Seconds(), err, tc.want)
+		}
+	}
+}
+
+func TestDeadlineHint_Providers(t *testing.T) {
+	for _, mc := range mixedCases {
+		t.Run(mc.name, func(t *testing.T) {
+			fixture, err := os.ReadFile("testdata/toolcalls/" + mc.name + "_generate.json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var headers []string
+			var bodies []map[string]any
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]any
+				json.NewDecoder(r.Body).Decode(&body)
+				headers = append(headers, r.Header.Get("X-Request-Timeout"))
+				bodies = append(bodies, body)
+				w.Header().Set("Content-Type", "application/json")
+				w.Write(fixture)
+			}))
+			defer ts.Close()
+			model, err := mc.newModel(provider.ClientOptions{
+				BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client(),
+				DeadlineHint: &provider.DeadlineHintOptions{Header: "X-Request-Timeout", BodyField: "timeout"},
+			})
+			if err != nil {
+				t.Fatalf("NewClient error: %v", err)
+			}
+			req := GenerateTextRequest{Model: model, Messages: []Message{UserMessage("Hi")}}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if _, err := GenerateText(ctx, req); err != nil {
+				t.Fatalf("GenerateText error: %v", err)
+			}
+			header, _ := strconv.ParseFloat(headers[0], 64)
+			field, _ := bodies[0]["timeout"].(float64)
+			if header < 28 || header > 29 || field != header {
+				t.Fatalf("hint header %q and body field %v, want about 29 seconds", headers[0], bodies[0]["timeout"])
+			}
+
+			// No deadline, no hint.
+			if _, err := GenerateText(context.Background(), req); err != nil {
+				t.Fatalf("GenerateText error: %v", err)
+			}
+			if _, ok := bodies[1]["timeout"]; ok || headers[1] != "" {
+				t.Fatalf("unexpected hint without a deadline: %q %v", headers[1], bodies[1]["timeout"])
+			}
+
+			// ProviderOptions take precedence over the body field.
+			req.ProviderOptions = map[string]any{"timeout": 5}
+			if _, err := GenerateText(ctx, req); err != nil || bodies[2]["timeout"] != 5.0 {
+				t.Fatalf("GenerateText = %v; timeout %v", err, bodies[2]["timeout"])
+			}
+
+			// A deadline within the margin fails without a request.
+			short, cancelShort := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancelShort()
+			if _, err := GenerateText(short, req); !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("expected DeadlineExceeded, got %v", err)
+			}
+			if _, err := StreamText(short, req); !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("expected DeadlineExceeded from StreamText, got %v", err)
+			}
+			if len(bodies) != 3 {
+				t.Fatalf("expired requests reached the server: %d requests", len(bodies))
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ncecere/ai-sdk/provider"
+	"github.com/ncecere/ai-sdk/providerutil"
+)
+
+// DryRunResult describes the HTTP request a provider would send for a
+// GenerateTextRequest. Credential headers are redacted with
+// providerutil.RedactHeaders.
+type DryRunResult struct {
+	// Method is the HTTP method, e.g. "POST".
+	Method string
+	// URL is the full request URL.
+	URL string
+	// Headers contains the request headers with credentials redacted.
+	Headers http.Header
+	// Body is the serialized request body.
+	Body []byte
+}
+
+// DryRunText renders the request that model would send for req
+// without performing the call. It is useful for debugging and for
+// generating curl reproductions.
+//
+// The model must implement provider.RequestBuilder. The built-in
+// OpenAI and Anthropic chat models do; models wrapped by middleware
+// generally do not.
+//
+// Errors:
+//   - ErrMissingModel if model is nil.
+//   - UnsupportedFunctionalityError if model does not implement
+//     provider.RequestBuilder.
+//   - Any error returned while building the request.
+func DryRunText(ctx context.Context, model LanguageModel, req GenerateTextRequest) (DryRunResult, error) {
+	if model == nil {
+		return DryRunResult{}, ErrMissingModel
+	}
+	builder, ok := model.(provider.RequestBuilder)
+	if !ok {
+		return DryRunResult{}, &UnsupportedFunctionalityError{
+			Feature: "dry run",
+			Message: "model does not implement provider.RequestBuilder",
+		}
+	}
+
+	httpReq, body, err := builder.BuildRequest(ctx, req.languageModelRequest())
+	if err != nil {
+		return DryRunResult{}, err
+	}
+
+	return DryRunResult{
+		Method:  httpReq.Method,
+		URL:     httpReq.URL.String(),
+		Headers: providerutil.RedactHeaders(httpReq.Header),
+		Body:    body,
+	}, nil
+}
@@ -0,0 +1,49 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/openai"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+func TestDryRunText_RendersOpenAIRequest(t *testing.T) {
+	client, err := openai.NewClient(provider.ClientOptions{
+		BaseURL: "https://example.test/v1",
+		APIKey:  "sk-secret",
+	})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	res, err := DryRunText(context.Background(), client.ChatModel("gpt-test"), GenerateTextRequest{
+		Messages: []Message{UserMessage("hi")},
+	})
+	if err != nil {
+		t.Fatalf("DryRunText error: %v", err)
+	}
+
+	if res.Method != "POST" || res.URL != "https://example.test/v1/chat/completions" {
+		t.Fatalf("unexpected method/url: %s %s", res.Method, res.URL)
+	}
+	if got := res.Headers.Get("Authorization"); got != "REDACTED" {
+		t.Fatalf("expected redacted auth header, got %q", got)
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(res.Body, &body); err != nil {
+		t.Fatalf("invalid body: %v", err)
+	}
+	if body["model"] != "gpt-test" {
+		t.Fatalf("unexpected body: %s", res.Body)
+	}
+}
+
+func TestDryRunText_UnsupportedModel(t *testing.T) {
+	_, err := DryRunText(context.Background(), &scriptedModel{}, GenerateTextRequest{})
+	if _, ok := err.(*UnsupportedFunctionalityError); !ok {
+		t.Fatalf("expected UnsupportedFunctionalityError, got %v", err)
+	}
+}
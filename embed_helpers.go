@@ -1,6 +1,10 @@
 package ai
 
-import "context"
+import (
+	"context"
+
+	"github.com/ncecere/ai-sdk/textsplit"
+)
 
 // Embed is a convenience helper for generating an embedding vector for
 // a single input string using the given embedding model.
@@ -33,3 +37,77 @@ func EmbedMany(ctx context.Context, model EmbeddingModel, inputs []string) ([][]
 	}
 	return res.Embeddings, nil
 }
+
+// EmbedDocumentOptions configures EmbedDocument.
+type EmbedDocumentOptions struct {
+	// MaxTokens is the maximum chunk size in tokens. Defaults to 512.
+	MaxTokens int
+	// Overlap is the number of tokens of trailing context repeated at
+	// the start of the next chunk.
+	Overlap int
+	// Split splits the document into chunks. Defaults to
+	// textsplit.SplitByTokens; textsplit.SplitBySentences and
+	// textsplit.SplitMarkdown can be used directly.
+	Split func(text string, maxTokens, overlap int, est textsplit.Estimator) []textsplit.Chunk
+	// Estimator counts tokens. Defaults to EstimateTokens.
+	Estimator textsplit.Estimator
+	// BatchSize limits how many chunks are sent per embedding request.
+	// Zero sends all chunks in a single request.
+	BatchSize int
+}
+
+// ChunkEmbedding is the embedding of one chunk of a document, with the
+// chunk's byte offsets into the original text.
+type ChunkEmbedding struct {
+	textsplit.Chunk
+	Embedding []float32 `json:"embedding"`
+}
+
+// EmbedDocument splits text into chunks and embeds them with EmbedMany,
+// returning one ChunkEmbedding per chunk in document order.
+//
+// Errors:
+//   - ErrNoEmbeddingGenerated if the model returns fewer vectors than
+//     chunks.
+//   - Any error returned by EmbedMany.
+func EmbedDocument(ctx context.Context, model EmbeddingModel, text string, opts EmbedDocumentOptions) ([]ChunkEmbedding, error) {
+	if opts.MaxTokens <= 0 {
+		opts.MaxTokens = 512
+	}
+	if opts.Split == nil {
+		opts.Split = textsplit.SplitByTokens
+	}
+	if opts.Estimator == nil {
+		opts.Estimator = EstimateTokens
+	}
+
+	chunks := opts.Split(text, opts.MaxTokens, opts.Overlap, opts.Estimator)
+	if len(chunks) == 0 {
+		return nil, nil
+	}
+
+	batch := opts.BatchSize
+	if batch <= 0 {
+		batch = len(chunks)
+	}
+
+	out := make([]ChunkEmbedding, 0, len(chunks))
+	for start := 0; start < len(chunks); start += batch {
+		end := min(start+batch, len(chunks))
+		inputs := make([]string, 0, end-start)
+		for _, c := range chunks[start:end] {
+			inputs = append(inputs, c.Text)
+		}
+		vectors, err := EmbedMany(ctx, model, inputs)
+		if err != nil {
+			return nil, err
+		}
+		if len(vectors) < len(inputs) {
+			return nil, ErrNoEmbeddingGenerated
+		}
+		for i, c := range chunks[start:end] {
+			out = append(out, ChunkEmbedding{Chunk: c, Embedding: vectors[i]})
+		}
+	}
+	return out, nil
+}
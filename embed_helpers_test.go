@@ -0,0 +1,44 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+type countingEmbeddingModel struct {
+	batches [][]string
+}
+
+func (m *countingEmbeddingModel) Generate(ctx context.Context, req *provider.EmbeddingRequest) (*provider.EmbeddingResponse, error) {
+	m.batches = append(m.batches, req.Input)
+	res := &provider.EmbeddingResponse{}
+	for _, in := range req.Input {
+		res.Embeddings = append(res.Embeddings, []float32{float32(len(in))})
+	}
+	return res, nil
+}
+
+func TestEmbedDocument_BatchesChunksWithOffsets(t *testing.T) {
+	text := "alpha beta gamma delta epsilon zeta eta theta"
+	model := &countingEmbeddingModel{}
+
+	res, err := EmbedDocument(context.Background(), model, text, EmbedDocumentOptions{
+		MaxTokens: 3,
+		BatchSize: 2,
+		Estimator: func(s string) int { return len(strings.Fields(s)) },
+	})
+	if err != nil {
+		t.Fatalf("EmbedDocument error: %v", err)
+	}
+	if len(res) != 3 || len(model.batches) != 2 {
+		t.Fatalf("expected 3 chunks in 2 batches, got %d chunks in %d batches", len(res), len(model.batches))
+	}
+	for _, c := range res {
+		if text[c.Start:c.End] != c.Text || c.Embedding[0] != float32(len(c.Text)) {
+			t.Fatalf("chunk embedding mismatch: %+v", c)
+		}
+	}
+}
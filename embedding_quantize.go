@@ -0,0 +1,213 @@
+package ai
+
+import (
+	"math"
+	"math/bits"
+	"slices"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// Int8Vector is a scalar-quantized embedding: component i is
+// approximately Values[i] * Scale. QuantizeInt8 picks the scale per
+// vector. Vectors returned natively by a provider have Scale 1: their
+// values are in the provider's own units, which is enough for
+// Int8CosineSimilarity.
+type Int8Vector struct {
+	Values []int8  `json:"values"`
+	Scale  float32 `json:"scale"`
+}
+
+// BinaryVector is a sign-quantized embedding: bit i is set when
+// component i is positive. Bits are packed most significant bit first.
+type BinaryVector struct {
+	Bits []byte `json:"bits"`
+	// Dims is the number of dimensions; the last byte may be padded.
+	Dims int `json:"dims"`
+}
+
+// QuantizeInt8 quantizes v to int8 with a per-vector scale, mapping the
+// largest absolute component to ±127. It uses a quarter of the memory
+// of v, with an error of at most Scale/2 per component.
+func QuantizeInt8(v []float32) Int8Vector {
+	var maxAbs float64
+	for _, x := range v {
+		maxAbs = max(maxAbs, math.Abs(float64(x)))
+	}
+	out := Int8Vector{Values: make([]int8, len(v))}
+	if maxAbs == 0 {
+		return out
+	}
+	scale := maxAbs / 127
+	for i, x := range v {
+		out.Values[i] = int8(max(-127, min(127, math.Round(float64(x)/scale))))
+	}
+	out.Scale = float32(scale)
+	return out
+}
+
+// Dequantize returns the approximate float32 components of v.
+func (v Int8Vector) Dequantize() []float32 {
+	out := make([]float32, len(v.Values))
+	for i, x := range v.Values {
+		out[i] = float32(x) * v.Scale
+	}
+	return out
+}
+
+// QuantizeBinary quantizes v to one bit per dimension, set for positive
+// components. It uses a thirty-second of the memory of v.
+func QuantizeBinary(v []float32) BinaryVector {
+	out := BinaryVector{Bits: make([]byte, (len(v)+7)/8), Dims: len(v)}
+	for i, x := range v {
+		if x > 0 {
+			out.Bits[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return out
+}
+
+// Dequantize returns v as a vector of +1 and -1 components.
+func (v BinaryVector) Dequantize() []float32 {
+	out := make([]float32, v.Dims)
+	for i := range out {
+		out[i] = -1
+		if v.Bits[i/8]&(0x80>>(i%8)) != 0 {
+			out[i] = 1
+		}
+	}
+	return out
+}
+
+// CosineSimilarity returns the cosine similarity of a and b, in
+// [-1, 1]. It is 0 if either vector is zero or their lengths differ.
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		x, y := float64(a[i]), float64(b[i])
+		dot += x * y
+		na += x * x
+		nb += y * y
+	}
+	return cosine(dot, na, nb)
+}
+
+// Int8CosineSimilarity returns the cosine similarity of two quantized
+// vectors, computed on the int8 values; the scales cancel out. It is 0
+// if either vector is zero or their lengths differ.
+func Int8CosineSimilarity(a, b Int8Vector) float64 {
+	if len(a.Values) != len(b.Values) {
+		return 0
+	}
+	var dot, na, nb int64
+	for i := range a.Values {
+		x, y := int64(a.Values[i]), int64(b.Values[i])
+		dot += x * y
+		na += x * x
+		nb += y * y
+	}
+	return cosine(float64(dot), float64(na), float64(nb))
+}
+
+// HammingSimilarity returns 1 - 2*h/Dims for the Hamming distance h of
+// a and b, in [-1, 1]. It equals the cosine similarity of the ±1
+// vectors Dequantize returns. It is 0 if the dimensions differ.
+func HammingSimilarity(a, b BinaryVector) float64 {
+	if a.Dims != b.Dims || a.Dims == 0 {
+		return 0
+	}
+	h := 0
+	for i := range a.Bits {
+		h += bits.OnesCount8(a.Bits[i] ^ b.Bits[i])
+	}
+	return 1 - 2*float64(h)/float64(a.Dims)
+}
+
+func cosine(dot, na, nb float64) float64 {
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// Match is a corpus entry ranked by TopK.
+type Match struct {
+	// Index is the position of the entry in the corpus.
+	Index int
+	// Score is the entry's similarity to the query.
+	Score float64
+}
+
+// TopK returns the k entries of corpus most similar to query, best
+// first, scored by similarity, such as CosineSimilarity for float32
+// vectors or Int8CosineSimilarity and HammingSimilarity for quantized
+// ones. Entries with equal scores keep their corpus order. k <= 0 or
+// larger than the corpus returns every entry.
+func TopK[V any](query V, corpus []V, k int, similarity func(a, b V) float64) []Match {
+	matches := make([]Match, len(corpus))
+	for i, v := range corpus {
+		matches[i] = Match{Index: i, Score: similarity(query, v)}
+	}
+	slices.SortStableFunc(matches, func(a, b Match) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		}
+		return 0
+	})
+	if k > 0 && k < len(matches) {
+		matches = matches[:k]
+	}
+	return matches
+}
+
+// embeddingResponse returns the vectors of res as dtype, converting the
+// float32 vectors when the provider did not produce dtype natively.
+func embeddingResponse(res *provider.EmbeddingResponse, dtype EmbeddingDType) EmbeddingResponse {
+	var out EmbeddingResponse
+	switch dtype {
+	case EmbeddingFloat64:
+		out.Float64 = res.Float64
+		if out.Float64 == nil && res.Embeddings != nil {
+			out.Float64 = make([][]float64, len(res.Embeddings))
+			for i, v := range res.Embeddings {
+				out.Float64[i] = make([]float64, len(v))
+				for j, x := range v {
+					out.Float64[i][j] = float64(x)
+				}
+			}
+		}
+	case EmbeddingInt8:
+		if res.Int8 != nil {
+			out.Int8 = make([]Int8Vector, len(res.Int8))
+			for i, v := range res.Int8 {
+				out.Int8[i] = Int8Vector{Values: v, Scale: 1}
+			}
+		} else if res.Embeddings != nil {
+			out.Int8 = make([]Int8Vector, len(res.Embeddings))
+			for i, v := range res.Embeddings {
+				out.Int8[i] = QuantizeInt8(v)
+			}
+		}
+	case EmbeddingBinary:
+		if res.Binary != nil {
+			out.Binary = make([]BinaryVector, len(res.Binary))
+			for i, v := range res.Binary {
+				out.Binary[i] = BinaryVector{Bits: v, Dims: 8 * len(v)}
+			}
+		} else if res.Embeddings != nil {
+			out.Binary = make([]BinaryVector, len(res.Embeddings))
+			for i, v := range res.Embeddings {
+				out.Binary[i] = QuantizeBinary(v)
+			}
+		}
+	default:
+		out.Embeddings = res.Embeddings
+	}
+	return out
+}
@@ -0,0 +1,122 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+func TestQuantizeInt8(t *testing.T) {
+	v := []float32{0.5, -1, 0.25, 0}
+	q := QuantizeInt8(v)
+	if !reflect.DeepEqual(q.Values, []int8{64, -127, 32, 0}) || q.Scale != float32(1.0/127) {
+		t.Fatalf("QuantizeInt8 = %+v", q)
+	}
+	for i, x := range q.Dequantize() {
+		if math.Abs(float64(x-v[i])) > float64(q.Scale)/2 {
+			t.Fatalf("component %d: dequantized %v, want %v", i, x, v[i])
+		}
+	}
+	if zero := QuantizeInt8([]float32{0, 0}); zero.Scale != 0 || !reflect.DeepEqual(zero.Dequantize(), []float32{0, 0}) {
+		t.Fatalf("QuantizeInt8(zero) = %+v", zero)
+	}
+}
+
+func TestQuantizeBinary(t *testing.T) {
+	v := []float32{0.1, -0.2, 0, 3, -1, 1, 1, 1, 2}
+	q := QuantizeBinary(v)
+	if !reflect.DeepEqual(q.Bits, []byte{0x97, 0x80}) || q.Dims != 9 {
+		t.Fatalf("QuantizeBinary = %+v", q)
+	}
+	want := []float32{1, -1, -1, 1, -1, 1, 1, 1, 1}
+	if got := q.Dequantize(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Dequantize = %v, want %v", got, want)
+	}
+	other := QuantizeBinary([]float32{1, 1, 1, 1, 1, 1, 1, 1, -1})
+	if got, want := HammingSimilarity(q, other), CosineSimilarity(q.Dequantize(), other.Dequantize()); math.Abs(got-want) > 1e-9 || math.Abs(got-(1-2*4.0/9)) > 1e-9 {
+		t.Fatalf("HammingSimilarity = %v, want %v", got, want)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	if got := CosineSimilarity([]float32{1, 0}, []float32{1, 1}); math.Abs(got-1/math.Sqrt2) > 1e-9 {
+		t.Fatalf("CosineSimilarity = %v", got)
+	}
+	if CosineSimilarity([]float32{1}, []float32{1, 0}) != 0 || CosineSimilarity([]float32{0, 0}, []float32{1, 0}) != 0 {
+		t.Fatalf("expected 0 for mismatched or zero vectors")
+	}
+
+	query := []float32{0.9, 0.1, -0.3, 0.2}
+	corpus := [][]float32{
+		{-0.5, 0.8, 0.1, 0.0},
+		{0.8, 0.2, -0.2, 0.3},
+		{0.1, 0.1, 0.9, -0.4},
+		{0.7, -0.1, -0.5, 0.1},
+	}
+	want := TopK(query, corpus, 3, CosineSimilarity)
+	if want[0].Index != 1 || want[1].Index != 3 || len(want) != 3 {
+		t.Fatalf("TopK = %+v", want)
+	}
+
+	// Quantized corpora rank the same, with scores close to float32.
+	q := QuantizeInt8(query)
+	qc := make([]Int8Vector, len(corpus))
+	for i, v := range corpus {
+		qc[i] = QuantizeInt8(v)
+	}
+	got := TopK(q, qc, 3, Int8CosineSimilarity)
+	for i := range want {
+		if got[i].Index != want[i].Index || math.Abs(got[i].Score-want[i].Score) > 0.01 {
+			t.Fatalf("int8 TopK = %+v, want %+v", got, want)
+		}
+	}
+	if all := TopK(q, qc, 0, Int8CosineSimilarity); len(all) != len(corpus) {
+		t.Fatalf("TopK with k=0 returned %d matches", len(all))
+	}
+}
+
+// nativeEmbeddingModel returns int8 vectors natively.
+type nativeEmbeddingModel struct {
+	req *provider.EmbeddingRequest
+}
+
+func (m *nativeEmbeddingModel) Generate(ctx context.Context, req *provider.EmbeddingRequest) (*provider.EmbeddingResponse, error) {
+	m.req = req
+	if req.OutputDType == provider.EmbeddingInt8 {
+		return &provider.EmbeddingResponse{Int8: [][]int8{{5, -3}}}, nil
+	}
+	return &provider.EmbeddingResponse{Embeddings: [][]float32{{0.5, -0.25}}}, nil
+}
+
+func TestGenerateEmbeddings_OutputDType(t *testing.T) {
+	ctx := context.Background()
+	model := &nativeEmbeddingModel{}
+
+	res, err := GenerateEmbeddings(ctx, EmbeddingRequest{Model: model, Input: []string{"a"}, OutputDType: EmbeddingInt8})
+	if err != nil || !reflect.DeepEqual(res.Int8, []Int8Vector{{Values: []int8{5, -3}, Scale: 1}}) || res.Embeddings != nil {
+		t.Fatalf("native int8 = %+v, %v", res, err)
+	}
+
+	// Float32-only providers are converted client-side.
+	res, err = GenerateEmbeddings(ctx, EmbeddingRequest{Model: model, Input: []string{"a"}, OutputDType: EmbeddingBinary})
+	if err != nil || !reflect.DeepEqual(res.Binary, []BinaryVector{{Bits: []byte{0x80}, Dims: 2}}) {
+		t.Fatalf("binary = %+v, %v", res, err)
+	}
+	res, err = GenerateEmbeddings(ctx, EmbeddingRequest{Model: model, Input: []string{"a"}, OutputDType: EmbeddingFloat64})
+	if err != nil || !reflect.DeepEqual(res.Float64, [][]float64{{0.5, -0.25}}) || model.req.OutputDType != EmbeddingFloat64 {
+		t.Fatalf("float64 = %+v, %v", res, err)
+	}
+	res, err = GenerateEmbeddings(ctx, EmbeddingRequest{Model: model, Input: []string{"a"}})
+	if err != nil || len(res.Embeddings) != 1 || model.req.OutputDType != "" {
+		t.Fatalf("default = %+v, %v; requested %q", res, err, model.req.OutputDType)
+	}
+
+	var argErr *InvalidArgumentError
+	if _, err := GenerateEmbeddings(ctx, EmbeddingRequest{Model: model, OutputDType: "uint4"}); !errors.As(err, &argErr) || argErr.Parameter != "OutputDType" {
+		t.Fatalf("expected InvalidArgumentError, got %v", err)
+	}
+}
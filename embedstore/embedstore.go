@@ -0,0 +1,218 @@
+// Package embedstore persists embedding vectors in a compact binary
+// file and searches them without loading the file into memory, for RAG
+// projects that do not need a vector database.
+//
+// A store holds entries of one dimension: an ID, a vector, and optional
+// JSON metadata. Writer streams entries to any io.Writer, and Append
+// adds to an existing file. Reader reads entries on demand through an
+// io.ReaderAt such as an *os.File, so the data lives in the operating
+// system's page cache rather than the Go heap, and Reader.TopK scans
+// every entry with memory proportional to k. WriteJSONL and ReadJSONL
+// convert stores to and from JSONL for other tools.
+//
+// # Format
+//
+// Integers are little-endian. A store is a 32-byte header, the entries
+// in order, and a footer:
+//
+//	header  magic "AIEB", version uint16 (1), dtype uint8, reserved
+//	        uint8, dimension uint32, reserved [16]byte, CRC-32C of the
+//	        preceding 28 bytes uint32
+//	entry   ID length uint32, metadata length uint32, ID, metadata,
+//	        vector, CRC-32C of the preceding bytes uint32
+//	footer  entry offsets [count]uint64, count uint64, CRC-32C of the
+//	        offsets and count uint32, magic "AIEBEND\x00"
+//
+// The dtype selects how vectors are stored: dimension float32 values
+// (1, ai.EmbeddingFloat32), a float32 scale and dimension int8 values
+// (2, ai.EmbeddingInt8; see ai.QuantizeInt8), or packed sign bits (3,
+// ai.EmbeddingBinary; see ai.QuantizeBinary). Quantized vectors are
+// dequantized when read.
+//
+// Every read verifies the checksums involved, so damage is reported as
+// a *CorruptError instead of returning wrong vectors.
+package embedstore
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"math"
+
+	ai "github.com/ncecere/ai-sdk"
+)
+
+// Entry is one stored embedding.
+type Entry struct {
+	ID     string    `json:"id"`
+	Vector []float32 `json:"vector"`
+	// Metadata is optional JSON stored with the vector, such as the
+	// source document and chunk offsets.
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+}
+
+// CorruptError reports a store that fails a checksum or is otherwise
+// malformed, such as a file cut off while it was written.
+type CorruptError struct {
+	// Offset is the byte offset of the damaged part of the store.
+	Offset int64
+	// Reason describes the damage.
+	Reason string
+}
+
+func (e *CorruptError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("embedstore: corrupt store at offset %d: %s", e.Offset, e.Reason)
+}
+
+const (
+	version     = 1
+	headerSize  = 32
+	headerMagic = "AIEB"
+	footerMagic = "AIEBEND\x00"
+	// footerTrailer is the size of the footer after the offsets: the
+	// count, the checksum, and the magic.
+	footerTrailer = 8 + 4 + 8
+	// entryOverhead is the size of an entry without its ID, metadata,
+	// and vector: the two lengths and the checksum.
+	entryOverhead = 4 + 4 + 4
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// codec encodes the vectors of one store.
+type codec struct {
+	dtype ai.EmbeddingDType
+	code  uint8
+	dims  int
+	// size is the encoded size of a vector.
+	size int
+}
+
+func newCodec(dtype ai.EmbeddingDType, dims int) (codec, error) {
+	if dims <= 0 || uint64(dims) > math.MaxUint32 {
+		return codec{}, fmt.Errorf("embedstore: invalid dimension %d", dims)
+	}
+	c := codec{dtype: dtype, dims: dims}
+	switch dtype {
+	case "", ai.EmbeddingFloat32:
+		c.dtype, c.code, c.size = ai.EmbeddingFloat32, 1, 4*dims
+	case ai.EmbeddingInt8:
+		c.code, c.size = 2, 4+dims
+	case ai.EmbeddingBinary:
+		c.code, c.size = 3, (dims+7)/8
+	default:
+		return codec{}, fmt.Errorf("embedstore: unsupported dtype %q", dtype)
+	}
+	return c, nil
+}
+
+func codecFor(code uint8, dims int) (codec, error) {
+	dtypes := map[uint8]ai.EmbeddingDType{1: ai.EmbeddingFloat32, 2: ai.EmbeddingInt8, 3: ai.EmbeddingBinary}
+	dtype, ok := dtypes[code]
+	if !ok {
+		return codec{}, &CorruptError{Offset: 6, Reason: fmt.Sprintf("unknown dtype %d", code)}
+	}
+	return newCodec(dtype, dims)
+}
+
+// appendVector appends the encoding of v to dst.
+func (c codec) appendVector(dst []byte, v []float32) []byte {
+	switch c.code {
+	case 1:
+		for _, x := range v {
+			dst = binary.LittleEndian.AppendUint32(dst, math.Float32bits(x))
+		}
+	case 2:
+		q := ai.QuantizeInt8(v)
+		dst = binary.LittleEndian.AppendUint32(dst, math.Float32bits(q.Scale))
+		for _, x := range q.Values {
+			dst = append(dst, byte(x))
+		}
+	case 3:
+		dst = append(dst, ai.QuantizeBinary(v).Bits...)
+	}
+	return dst
+}
+
+// decodeVector decodes src, of length c.size, into dst, of length
+// c.dims.
+func (c codec) decodeVector(dst []float32, src []byte) {
+	switch c.code {
+	case 1:
+		for i := range dst {
+			dst[i] = math.Float32frombits(binary.LittleEndian.Uint32(src[4*i:]))
+		}
+	case 2:
+		scale := math.Float32frombits(binary.LittleEndian.Uint32(src))
+		for i := range dst {
+			dst[i] = float32(int8(src[4+i])) * scale
+		}
+	case 3:
+		for i := range dst {
+			dst[i] = -1
+			if src[i/8]&(0x80>>(i%8)) != 0 {
+				dst[i] = 1
+			}
+		}
+	}
+}
+
+// header returns the encoded store header.
+func (c codec) header() []byte {
+	h := make([]byte, 0, headerSize)
+	h = append(h, headerMagic...)
+	h = binary.LittleEndian.AppendUint16(h, version)
+	h = append(h, c.code, 0)
+	h = binary.LittleEndian.AppendUint32(h, uint32(c.dims))
+	h = append(h, make([]byte, 16)...)
+	return binary.LittleEndian.AppendUint32(h, crc32.Checksum(h, castagnoli))
+}
+
+// parseHeader returns the codec of a store from its header.
+func parseHeader(h []byte) (codec, error) {
+	if len(h) < headerSize || string(h[:4]) != headerMagic {
+		return codec{}, &CorruptError{Reason: "not an embedding store"}
+	}
+	if crc32.Checksum(h[:28], castagnoli) != binary.LittleEndian.Uint32(h[28:]) {
+		return codec{}, &CorruptError{Reason: "header checksum mismatch"}
+	}
+	if v := binary.LittleEndian.Uint16(h[4:]); v != version {
+		return codec{}, fmt.Errorf("embedstore: unsupported format version %d", v)
+	}
+	return codecFor(h[6], int(binary.LittleEndian.Uint32(h[8:])))
+}
+
+// appendEntry appends the encoding of e to dst.
+func (c codec) appendEntry(dst []byte, e Entry) []byte {
+	start := len(dst)
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(len(e.ID)))
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(len(e.Metadata)))
+	dst = append(dst, e.ID...)
+	dst = append(dst, e.Metadata...)
+	dst = c.appendVector(dst, e.Vector)
+	return binary.LittleEndian.AppendUint32(dst, crc32.Checksum(dst[start:], castagnoli))
+}
+
+// parseEntry splits the encoded entry rec, found at offset, into its
+// ID, metadata, and vector after verifying its checksum.
+func (c codec) parseEntry(rec []byte, offset int64) (id, meta, vec []byte, err error) {
+	if len(rec) < entryOverhead+c.size {
+		return nil, nil, nil, &CorruptError{Offset: offset, Reason: "entry too short"}
+	}
+	body := rec[:len(rec)-4]
+	if crc32.Checksum(body, castagnoli) != binary.LittleEndian.Uint32(rec[len(body):]) {
+		return nil, nil, nil, &CorruptError{Offset: offset, Reason: "entry checksum mismatch"}
+	}
+	idLen := int64(binary.LittleEndian.Uint32(body))
+	metaLen := int64(binary.LittleEndian.Uint32(body[4:]))
+	if 8+idLen+metaLen+int64(c.size) != int64(len(body)) {
+		return nil, nil, nil, &CorruptError{Offset: offset, Reason: "entry lengths do not match its size"}
+	}
+	id = body[8 : 8+idLen]
+	meta = body[8+idLen : 8+idLen+metaLen]
+	return id, meta, body[8+idLen+metaLen:], nil
+}
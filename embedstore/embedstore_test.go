@@ -0,0 +1,380 @@
+package embedstore
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	ai "github.com/ncecere/ai-sdk"
+)
+
+// testEntries returns n entries of dims-dimensional random vectors,
+// with metadata on every other one.
+func testEntries(n, dims int) []Entry {
+	rng := rand.New(rand.NewSource(1))
+	entries := make([]Entry, n)
+	for i := range entries {
+		v := make([]float32, dims)
+		for j := range v {
+			v[j] = rng.Float32()*2 - 1
+		}
+		entries[i] = Entry{ID: fmt.Sprintf("doc-%d", i), Vector: v}
+		if i%2 == 0 {
+			entries[i].Metadata = json.RawMessage(fmt.Sprintf(`{"chunk":%d}`, i))
+		}
+	}
+	return entries
+}
+
+// writeStore creates a store at a temporary path holding entries.
+func writeStore(t testing.TB, entries []Entry, opts Options) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "vectors.aieb")
+	w, err := Create(path, len(entries[0].Vector), opts)
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	for _, e := range entries {
+		if err := w.Add(e); err != nil {
+			t.Fatalf("Add error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	return path
+}
+
+func TestStore_RoundTrip(t *testing.T) {
+	entries := testEntries(50, 12)
+	for _, dtype := range []ai.EmbeddingDType{ai.EmbeddingFloat32, ai.EmbeddingInt8, ai.EmbeddingBinary} {
+		t.Run(string(dtype), func(t *testing.T) {
+			r, err := Open(writeStore(t, entries, Options{DType: dtype}))
+			if err != nil {
+				t.Fatalf("Open error: %v", err)
+			}
+			defer r.Close()
+			if r.Len() != len(entries) || r.Dims() != 12 || r.DType() != dtype {
+				t.Fatalf("Len %d, Dims %d, DType %q", r.Len(), r.Dims(), r.DType())
+			}
+			if err := r.Verify(); err != nil {
+				t.Fatalf("Verify error: %v", err)
+			}
+
+			var want []float32
+			i := 0
+			for got, err := range r.Entries() {
+				if err != nil {
+					t.Fatalf("Entries error: %v", err)
+				}
+				switch dtype {
+				case ai.EmbeddingInt8:
+					want = ai.QuantizeInt8(entries[i].Vector).Dequantize()
+				case ai.EmbeddingBinary:
+					want = ai.QuantizeBinary(entries[i].Vector).Dequantize()
+				default:
+					want = entries[i].Vector
+				}
+				if got.ID != entries[i].ID || !bytes.Equal(got.Metadata, entries[i].Metadata) || !reflect.DeepEqual(got.Vector, want) {
+					t.Fatalf("entry %d = %+v, want %+v with vector %v", i, got, entries[i], want)
+				}
+				if byIndex, err := r.Entry(i); err != nil || !reflect.DeepEqual(byIndex, got) {
+					t.Fatalf("Entry(%d) = %+v, %v", i, byIndex, err)
+				}
+				i++
+			}
+			if i != len(entries) {
+				t.Fatalf("Entries yielded %d entries", i)
+			}
+		})
+	}
+}
+
+func TestReader_TopK(t *testing.T) {
+	entries := testEntries(200, 16)
+	// A duplicate vector ties with its original, which comes first.
+	entries[150].Vector = entries[40].Vector
+	r, err := Open(writeStore(t, entries, Options{}))
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	defer r.Close()
+
+	corpus := make([][]float32, len(entries))
+	for i, e := range entries {
+		corpus[i] = e.Vector
+	}
+	query := entries[40].Vector
+	for _, k := range []int{1, 5, 0, 500} {
+		got, err := r.TopK(query, k, nil)
+		if err != nil {
+			t.Fatalf("TopK error: %v", err)
+		}
+		if want := ai.TopK(query, corpus, k, ai.CosineSimilarity); !reflect.DeepEqual(got, want) {
+			t.Fatalf("TopK(k=%d) = %v, want %v", k, got, want)
+		}
+	}
+	if got, _ := r.TopK(query, 2, nil); got[0].Index != 40 || got[1].Index != 150 {
+		t.Fatalf("expected the tie in store order, got %v", got)
+	}
+	if _, err := r.TopK(query[:3], 5, nil); err == nil {
+		t.Fatal("expected a dimension mismatch error")
+	}
+}
+
+func TestWriter_Add(t *testing.T) {
+	w, err := NewWriter(&bytes.Buffer{}, 3, Options{})
+	if err != nil {
+		t.Fatalf("NewWriter error: %v", err)
+	}
+	if err := w.Add(Entry{ID: "a", Vector: []float32{1, 2}}); err == nil {
+		t.Fatal("expected a dimension mismatch error")
+	}
+	if err := w.Add(Entry{ID: "a", Vector: []float32{1, 2, 3}, Metadata: json.RawMessage(`{"x":`)}); err == nil {
+		t.Fatal("expected invalid metadata to be rejected")
+	}
+	if w.Len() != 0 {
+		t.Fatalf("rejected entries were counted: %d", w.Len())
+	}
+	if _, err := NewWriter(&bytes.Buffer{}, 3, Options{DType: ai.EmbeddingFloat64}); err == nil {
+		t.Fatal("expected float64 to be unsupported")
+	}
+}
+
+func TestAppend(t *testing.T) {
+	entries := testEntries(8, 4)
+	path := writeStore(t, entries[:3], Options{DType: ai.EmbeddingInt8})
+
+	w, err := Append(path)
+	if err != nil {
+		t.Fatalf("Append error: %v", err)
+	}
+	for _, e := range entries[3:5] {
+		if err := w.Add(e); err != nil {
+			t.Fatalf("Add error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	// A writer that stops without Close, midway through an entry.
+	w, err = Append(path)
+	if err != nil {
+		t.Fatalf("Append error: %v", err)
+	}
+	w.Add(entries[5])
+	w.Add(entries[6])
+	w.Flush()
+	w.file.Close()
+	info, _ := os.Stat(path)
+	os.Truncate(path, info.Size()-3)
+	if _, err := Open(path); !isCorrupt(err) {
+		t.Fatalf("expected an unclosed store to be reported as corrupt, got %v", err)
+	}
+
+	w, err = Append(path)
+	if err != nil {
+		t.Fatalf("Append error: %v", err)
+	}
+	if w.Len() != 6 {
+		t.Fatalf("recovered %d entries, want 6", w.Len())
+	}
+	w.Add(entries[7])
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	defer r.Close()
+	var ids []string
+	for e, err := range r.Entries() {
+		if err != nil {
+			t.Fatalf("Entries error: %v", err)
+		}
+		ids = append(ids, e.ID)
+	}
+	want := []string{"doc-0", "doc-1", "doc-2", "doc-3", "doc-4", "doc-5", "doc-7"}
+	if r.DType() != ai.EmbeddingInt8 || !reflect.DeepEqual(ids, want) {
+		t.Fatalf("DType %q, IDs %v, want %v", r.DType(), ids, want)
+	}
+}
+
+func TestAppend_CorruptEntry(t *testing.T) {
+	entries := testEntries(100, 4)
+	path := filepath.Join(t.TempDir(), "vectors.aieb")
+	w, err := Create(path, 4, Options{})
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	for _, e := range entries {
+		w.Add(e)
+	}
+	w.Flush()
+	w.file.Close()
+
+	// Damage the vector of entry 5 in a store without a footer.
+	data, _ := os.ReadFile(path)
+	off := int(w.offsets[5]) + 8 + len("doc-5") + 1
+	data[off] ^= 0x01
+	os.WriteFile(path, data, 0o644)
+
+	if _, err := Append(path); !isCorrupt(err) {
+		t.Fatalf("expected *CorruptError, got %v", err)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, data) {
+		t.Fatalf("Append changed the damaged store: %d bytes, want %d", len(after), len(data))
+	}
+}
+
+func TestCorruption(t *testing.T) {
+	entries := testEntries(5, 8)
+	data, err := os.ReadFile(writeStore(t, entries, Options{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := func(data []byte) (*Reader, error) {
+		return NewReader(bytes.NewReader(data), int64(len(data)))
+	}
+	flip := func(offset int) []byte {
+		damaged := bytes.Clone(data)
+		damaged[offset] ^= 0x01
+		return damaged
+	}
+	r, err := open(data)
+	if err != nil {
+		t.Fatalf("NewReader error: %v", err)
+	}
+
+	// A bit flip in the vector of entry 2 is caught by every read of it.
+	start, _ := r.bounds(2)
+	r, err = open(flip(int(start) + 8 + len("doc-2") + len(`{"chunk":2}`) + 1))
+	if err != nil {
+		t.Fatalf("NewReader error: %v", err)
+	}
+	if _, err := r.Entry(1); err != nil {
+		t.Fatalf("Entry(1) error: %v", err)
+	}
+	if _, err := r.Entry(2); !isCorrupt(err) {
+		t.Fatalf("Entry(2): expected *CorruptError, got %v", err)
+	}
+	if err := r.Verify(); !isCorrupt(err) {
+		t.Fatalf("Verify: expected *CorruptError, got %v", err)
+	}
+	if _, err := r.TopK(entries[0].Vector, 3, nil); !isCorrupt(err) {
+		t.Fatalf("TopK: expected *CorruptError, got %v", err)
+	}
+
+	for name, damaged := range map[string][]byte{
+		"header":    flip(9),
+		"footer":    flip(len(data) - footerTrailer - 3),
+		"truncated": data[:len(data)-1],
+		"not store": []byte(strings.Repeat("x", 64)),
+	} {
+		if _, err := open(damaged); !isCorrupt(err) {
+			t.Fatalf("%s: expected *CorruptError, got %v", name, err)
+		}
+	}
+}
+
+func isCorrupt(err error) bool {
+	var corrupt *CorruptError
+	return errors.As(err, &corrupt)
+}
+
+func TestJSONL(t *testing.T) {
+	entries := testEntries(10, 6)
+	r, err := Open(writeStore(t, entries, Options{}))
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	defer r.Close()
+
+	var jsonl bytes.Buffer
+	if err := WriteJSONL(&jsonl, r); err != nil {
+		t.Fatalf("WriteJSONL error: %v", err)
+	}
+	if lines := strings.Count(jsonl.String(), "\n"); lines != len(entries) {
+		t.Fatalf("wrote %d lines", lines)
+	}
+
+	var store bytes.Buffer
+	w, err := NewWriter(&store, 6, Options{})
+	if err != nil {
+		t.Fatalf("NewWriter error: %v", err)
+	}
+	if n, err := ReadJSONL(strings.NewReader(jsonl.String()+"\n"), w); err != nil || n != len(entries) {
+		t.Fatalf("ReadJSONL = %d, %v", n, err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	back, err := NewReader(bytes.NewReader(store.Bytes()), int64(store.Len()))
+	if err != nil {
+		t.Fatalf("NewReader error: %v", err)
+	}
+	for i, want := range entries {
+		if got, err := back.Entry(i); err != nil || !reflect.DeepEqual(got, want) {
+			t.Fatalf("entry %d = %+v, %v; want %+v", i, got, err, want)
+		}
+	}
+
+	w, _ = NewWriter(&bytes.Buffer{}, 6, Options{})
+	_, err = ReadJSONL(strings.NewReader(`{"id":"a","vector":[1,2,3,4,5,6]}`+"\n\n"+`{"id":"b","vector":[1]}`), w)
+	if err == nil || !strings.HasPrefix(err.Error(), "line 3:") {
+		t.Fatalf("expected an error on line 3, got %v", err)
+	}
+}
+
+func BenchmarkWriter_Add(b *testing.B) {
+	entries := testEntries(1000, 768)
+	w, err := NewWriter(discard{}, 768, Options{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(4 * 768)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := w.Add(entries[i%len(entries)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReader_TopK(b *testing.B) {
+	entries := testEntries(20000, 256)
+	for _, dtype := range []ai.EmbeddingDType{ai.EmbeddingFloat32, ai.EmbeddingInt8, ai.EmbeddingBinary} {
+		b.Run(string(dtype), func(b *testing.B) {
+			r, err := Open(writeStore(b, entries, Options{DType: dtype}))
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer r.Close()
+			query := entries[7].Vector
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				matches, err := r.TopK(query, 10, nil)
+				if err != nil || math.IsNaN(matches[0].Score) {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// discard is io.Discard without the ReaderFrom fast path.
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) { return len(p), nil }
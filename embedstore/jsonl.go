@@ -0,0 +1,66 @@
+package embedstore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// WriteJSONL writes the entries of r to w as JSONL, one Entry per line:
+//
+//	{"id":"doc-1#0","vector":[0.12,-0.03],"metadata":{"source":"doc-1"}}
+//
+// Quantized stores are written with their dequantized vectors.
+func WriteJSONL(w io.Writer, r *Reader) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for e, err := range r.Entries() {
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadJSONL adds the entries of JSONL records in r, in the format of
+// WriteJSONL, to w and returns the number added. Blank lines are
+// skipped. Records may be of any length.
+//
+// Errors:
+//   - A decoding error or an error from Writer.Add, wrapped with the
+//     line number. The entries before it have been added.
+//   - Any error from r.
+func ReadJSONL(r io.Reader, w *Writer) (int, error) {
+	br := bufio.NewReader(r)
+	n := 0
+	for lineNo := 1; ; lineNo++ {
+		line, err := br.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			if errors.Is(err, io.EOF) {
+				return n, nil
+			}
+			return n, err
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return n, err
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return n, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if err := w.Add(e); err != nil {
+			return n, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		n++
+	}
+}
@@ -0,0 +1,338 @@
+package embedstore
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"iter"
+	"os"
+	"slices"
+
+	ai "github.com/ncecere/ai-sdk"
+)
+
+// errNoFooter reports a store without a footer, as left by a Writer
+// that was not closed.
+var errNoFooter = errors.New("embedstore: missing footer")
+
+// Reader reads a store. Entries are read on demand; only their offsets,
+// eight bytes per entry, are held in memory. A Reader is safe for
+// concurrent use if its io.ReaderAt is, as *os.File is.
+type Reader struct {
+	r       io.ReaderAt
+	file    *os.File
+	codec   codec
+	offsets []uint64
+	// end is the offset of the footer, just past the last entry.
+	end int64
+}
+
+// Open opens the store at path. Close closes the file.
+func Open(path string) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r, err := NewReader(f, info.Size())
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.file = f
+	return r, nil
+}
+
+// NewReader returns a Reader for the store of size bytes in r. It
+// reads the header and the footer; entries are read when requested.
+//
+// Errors:
+//   - *CorruptError if the header or footer is damaged, including a
+//     store whose Writer was not closed (Append recovers it).
+//   - Any error from r.
+func NewReader(r io.ReaderAt, size int64) (*Reader, error) {
+	h := make([]byte, headerSize)
+	if _, err := r.ReadAt(h, 0); err != nil {
+		return nil, &CorruptError{Reason: "truncated header"}
+	}
+	c, err := parseHeader(h)
+	if err != nil {
+		return nil, err
+	}
+	offsets, end, err := readFooter(r, size)
+	if errors.Is(err, errNoFooter) {
+		return nil, &CorruptError{Offset: size, Reason: "missing footer; the store was not closed"}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{r: r, codec: c, offsets: offsets, end: end}, nil
+}
+
+// readFooter returns the entry offsets of the store of size bytes in r
+// and the offset of its footer. It returns errNoFooter if the store
+// does not end with a footer.
+func readFooter(r io.ReaderAt, size int64) ([]uint64, int64, error) {
+	if size < headerSize+footerTrailer {
+		return nil, 0, errNoFooter
+	}
+	trailer := make([]byte, footerTrailer)
+	if _, err := r.ReadAt(trailer, size-footerTrailer); err != nil {
+		return nil, 0, err
+	}
+	if string(trailer[12:]) != footerMagic {
+		return nil, 0, errNoFooter
+	}
+	count := binary.LittleEndian.Uint64(trailer)
+	if count > uint64(size-headerSize-footerTrailer)/8 {
+		return nil, 0, &CorruptError{Offset: size - footerTrailer, Reason: "entry count exceeds the store size"}
+	}
+	end := size - footerTrailer - 8*int64(count)
+	footer := make([]byte, 8*count+8)
+	if _, err := r.ReadAt(footer, end); err != nil {
+		return nil, 0, err
+	}
+	if crc32.Checksum(footer, castagnoli) != binary.LittleEndian.Uint32(trailer[8:]) {
+		return nil, 0, &CorruptError{Offset: end, Reason: "footer checksum mismatch"}
+	}
+	offsets := make([]uint64, count)
+	for i := range offsets {
+		offsets[i] = binary.LittleEndian.Uint64(footer[8*i:])
+		// Entries are contiguous, from the header to the footer.
+		if i == 0 && offsets[i] != headerSize || i > 0 && offsets[i] <= offsets[i-1] || offsets[i] >= uint64(end) {
+			return nil, 0, &CorruptError{Offset: end + 8*int64(i), Reason: "invalid entry offset"}
+		}
+	}
+	if count == 0 && end != headerSize {
+		return nil, 0, &CorruptError{Offset: headerSize, Reason: "data after the header of an empty store"}
+	}
+	return offsets, end, nil
+}
+
+// scanEntries returns the offsets of the entries of the store of size
+// bytes in r, and the offset just past the last of them, for recovering
+// a store without a footer. Only a torn tail, an entry that runs past
+// the end of the store, is dropped; a damaged entry returns a
+// *CorruptError.
+func scanEntries(r io.ReaderAt, c codec, size int64) ([]uint64, int64, error) {
+	br := bufio.NewReaderSize(io.NewSectionReader(r, headerSize, size-headerSize), 1<<16)
+	var offsets []uint64
+	var rec []byte
+	off := int64(headerSize)
+	for {
+		var lens [8]byte
+		if _, err := io.ReadFull(br, lens[:]); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return offsets, off, nil
+			}
+			return nil, 0, err
+		}
+		n := int64(entryOverhead) + int64(binary.LittleEndian.Uint32(lens[:])) + int64(binary.LittleEndian.Uint32(lens[4:])) + int64(c.size)
+		if off+n > size {
+			return offsets, off, nil
+		}
+		rec = append(slices.Grow(rec[:0], int(n)), lens[:]...)[:n]
+		if _, err := io.ReadFull(br, rec[8:]); err != nil {
+			return nil, 0, err
+		}
+		if _, _, _, err := c.parseEntry(rec, off); err != nil {
+			return nil, 0, err
+		}
+		offsets = append(offsets, uint64(off))
+		off += n
+	}
+}
+
+// Len returns the number of entries.
+func (r *Reader) Len() int {
+	return len(r.offsets)
+}
+
+// Dims returns the dimension of the vectors.
+func (r *Reader) Dims() int {
+	return r.codec.dims
+}
+
+// DType returns the stored vector type.
+func (r *Reader) DType() ai.EmbeddingDType {
+	return r.codec.dtype
+}
+
+// bounds returns the offsets of the start and end of entry i.
+func (r *Reader) bounds(i int) (int64, int64) {
+	if i+1 < len(r.offsets) {
+		return int64(r.offsets[i]), int64(r.offsets[i+1])
+	}
+	return int64(r.offsets[i]), r.end
+}
+
+// Entry reads entry i.
+//
+// Errors:
+//   - *CorruptError if the entry is damaged.
+//   - An error if i is out of range, or any error from the underlying
+//     reader.
+func (r *Reader) Entry(i int) (Entry, error) {
+	if i < 0 || i >= len(r.offsets) {
+		return Entry{}, fmt.Errorf("embedstore: entry %d out of range [0, %d)", i, len(r.offsets))
+	}
+	start, end := r.bounds(i)
+	rec := make([]byte, end-start)
+	if _, err := r.r.ReadAt(rec, start); err != nil {
+		return Entry{}, err
+	}
+	id, meta, vec, err := r.codec.parseEntry(rec, start)
+	if err != nil {
+		return Entry{}, err
+	}
+	return r.entry(id, meta, vec), nil
+}
+
+// entry decodes an entry into newly allocated memory.
+func (r *Reader) entry(id, meta, vec []byte) Entry {
+	e := Entry{ID: string(id), Vector: make([]float32, r.codec.dims)}
+	if len(meta) > 0 {
+		e.Metadata = slices.Clone(meta)
+	}
+	r.codec.decodeVector(e.Vector, vec)
+	return e
+}
+
+// Entries returns an iterator over the entries in order, reading the
+// store sequentially. Iteration stops after the first error.
+func (r *Reader) Entries() iter.Seq2[Entry, error] {
+	return func(yield func(Entry, error) bool) {
+		stopped := errors.New("stopped")
+		err := r.scan(func(_ int, id, meta, vec []byte) error {
+			if !yield(r.entry(id, meta, vec), nil) {
+				return stopped
+			}
+			return nil
+		})
+		if err != nil && err != stopped {
+			yield(Entry{}, err)
+		}
+	}
+}
+
+// Verify reads every entry and checks its checksum.
+//
+// Errors:
+//   - *CorruptError for the first damaged entry.
+//   - Any error from the underlying reader.
+func (r *Reader) Verify() error {
+	return r.scan(func(int, []byte, []byte, []byte) error { return nil })
+}
+
+// scan reads the entries in order and calls fn with the parts of each.
+// The slices are only valid during the call.
+func (r *Reader) scan(fn func(i int, id, meta, vec []byte) error) error {
+	br := bufio.NewReaderSize(io.NewSectionReader(r.r, headerSize, r.end-headerSize), 1<<16)
+	var rec []byte
+	for i := range r.offsets {
+		start, end := r.bounds(i)
+		rec = slices.Grow(rec[:0], int(end-start))[:end-start]
+		if _, err := io.ReadFull(br, rec); err != nil {
+			return err
+		}
+		id, meta, vec, err := r.codec.parseEntry(rec, start)
+		if err != nil {
+			return err
+		}
+		if err := fn(i, id, meta, vec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TopK returns the k entries most similar to query, best first, like
+// ai.TopK over the stored vectors. It reads the store sequentially and
+// keeps only k matches in memory. similarity defaults to
+// ai.CosineSimilarity; it must not retain its arguments, as the entry
+// vector is reused. Entries with equal scores keep their store order.
+// k <= 0 or larger than the store returns every entry.
+//
+// Errors:
+//   - An error if query does not have the store's dimension.
+//   - *CorruptError if an entry is damaged.
+//   - Any error from the underlying reader.
+func (r *Reader) TopK(query []float32, k int, similarity func(a, b []float32) float64) ([]ai.Match, error) {
+	if len(query) != r.codec.dims {
+		return nil, fmt.Errorf("embedstore: query has %d dimensions, want %d", len(query), r.codec.dims)
+	}
+	if similarity == nil {
+		similarity = ai.CosineSimilarity
+	}
+	if k <= 0 || k > len(r.offsets) {
+		k = len(r.offsets)
+	}
+	h := make(matchHeap, 0, k)
+	vec := make([]float32, r.codec.dims)
+	err := r.scan(func(i int, _, _, v []byte) error {
+		r.codec.decodeVector(vec, v)
+		m := ai.Match{Index: i, Score: similarity(query, vec)}
+		switch {
+		case len(h) < k:
+			heap.Push(&h, m)
+		case k > 0 && worse(h[0], m):
+			h[0] = m
+			heap.Fix(&h, 0)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(h, func(a, b ai.Match) int {
+		switch {
+		case worse(b, a):
+			return -1
+		case worse(a, b):
+			return 1
+		}
+		return 0
+	})
+	return h, nil
+}
+
+// worse reports whether a ranks below b: it has a lower score, or an
+// equal score and a later index.
+func worse(a, b ai.Match) bool {
+	if a.Score != b.Score {
+		return a.Score < b.Score
+	}
+	return a.Index > b.Index
+}
+
+// matchHeap holds the best matches so far with the worst on top.
+type matchHeap []ai.Match
+
+func (h matchHeap) Len() int           { return len(h) }
+func (h matchHeap) Less(i, j int) bool { return worse(h[i], h[j]) }
+func (h matchHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *matchHeap) Push(x any)        { *h = append(*h, x.(ai.Match)) }
+func (h *matchHeap) Pop() any {
+	old := *h
+	m := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return m
+}
+
+// Close closes the file opened by Open.
+func (r *Reader) Close() error {
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
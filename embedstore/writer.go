@@ -0,0 +1,199 @@
+package embedstore
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+
+	ai "github.com/ncecere/ai-sdk"
+)
+
+// Options configures a new store.
+type Options struct {
+	// DType is the stored vector type: ai.EmbeddingFloat32 (the
+	// default), ai.EmbeddingInt8, a quarter of the size, or
+	// ai.EmbeddingBinary, a thirty-second.
+	DType ai.EmbeddingDType
+}
+
+// Writer adds entries to a store. Output is buffered; Close writes the
+// footer, after which the store can be read.
+type Writer struct {
+	w     *bufio.Writer
+	file  *os.File
+	codec codec
+	// offset is the position of the next entry in the store.
+	offset  int64
+	offsets []uint64
+	buf     []byte
+	// err is the first write error; the store is unusable after it.
+	err error
+}
+
+// NewWriter writes the header of a store of dims-dimensional vectors to
+// w and returns a Writer for its entries. Close does not close w.
+func NewWriter(w io.Writer, dims int, opts Options) (*Writer, error) {
+	c, err := newCodec(opts.DType, dims)
+	if err != nil {
+		return nil, err
+	}
+	sw := &Writer{w: bufio.NewWriter(w), codec: c, offset: headerSize}
+	if _, err := sw.w.Write(c.header()); err != nil {
+		return nil, err
+	}
+	return sw, nil
+}
+
+// Create creates or truncates the file at path and returns a Writer for
+// a new store in it. Close closes the file.
+func Create(path string, dims int, opts Options) (*Writer, error) {
+	if _, err := newCodec(opts.DType, dims); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewWriter(f, dims, opts)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	w.file = f
+	return w, nil
+}
+
+// Append returns a Writer that adds entries to the store at path, with
+// the dimension and dtype it was created with. Close closes the file.
+//
+// A store whose Writer never reached Close has no footer; Append
+// recovers it, keeping the entries that were written completely and
+// dropping a partially written last entry.
+//
+// Errors:
+//   - *CorruptError if the header, an existing footer, or an entry of
+//     a store without a footer is damaged. The file is left unchanged.
+//   - Any error opening or reading the file.
+func Append(path string) (*Writer, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	w, err := appendTo(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func appendTo(f *os.File) (*Writer, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	h := make([]byte, headerSize)
+	if _, err := f.ReadAt(h, 0); err != nil {
+		return nil, &CorruptError{Reason: "truncated header"}
+	}
+	c, err := parseHeader(h)
+	if err != nil {
+		return nil, err
+	}
+	offsets, end, err := readFooter(f, info.Size())
+	if errors.Is(err, errNoFooter) {
+		offsets, end, err = scanEntries(f, c, info.Size())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Truncate(end); err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(end, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return &Writer{w: bufio.NewWriter(f), file: f, codec: c, offset: end, offsets: offsets}, nil
+}
+
+// Add appends e to the store. The vector must have the store's
+// dimension, and Metadata, if set, must be valid JSON.
+func (w *Writer) Add(e Entry) error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(e.Vector) != w.codec.dims {
+		return fmt.Errorf("embedstore: vector of %q has %d dimensions, want %d", e.ID, len(e.Vector), w.codec.dims)
+	}
+	if len(e.Metadata) > 0 && !json.Valid(e.Metadata) {
+		return fmt.Errorf("embedstore: metadata of %q is not valid JSON", e.ID)
+	}
+	if uint64(len(e.ID)) > math.MaxUint32 || uint64(len(e.Metadata)) > math.MaxUint32 {
+		return fmt.Errorf("embedstore: entry %q is too large", e.ID)
+	}
+	w.buf = w.codec.appendEntry(w.buf[:0], e)
+	if _, err := w.w.Write(w.buf); err != nil {
+		w.err = err
+		return err
+	}
+	w.offsets = append(w.offsets, uint64(w.offset))
+	w.offset += int64(len(w.buf))
+	return nil
+}
+
+// Len returns the number of entries in the store.
+func (w *Writer) Len() int {
+	return len(w.offsets)
+}
+
+// Flush writes buffered entries to the underlying writer. A store
+// becomes readable only after Close; Append recovers flushed entries
+// if the process stops before that.
+func (w *Writer) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.w.Flush(); err != nil {
+		w.err = err
+	}
+	return w.err
+}
+
+// Close writes the footer, flushes, and closes the file opened by
+// Create or Append. The Writer cannot be used afterwards.
+func (w *Writer) Close() error {
+	err := w.writeFooter()
+	if w.file != nil {
+		if cerr := w.file.Close(); err == nil {
+			err = cerr
+		}
+		w.file = nil
+	}
+	if w.err == nil {
+		w.err = errors.New("embedstore: writer is closed")
+	}
+	return err
+}
+
+func (w *Writer) writeFooter() error {
+	if w.err != nil {
+		return w.err
+	}
+	footer := make([]byte, 0, 8*len(w.offsets)+footerTrailer)
+	for _, off := range w.offsets {
+		footer = binary.LittleEndian.AppendUint64(footer, off)
+	}
+	footer = binary.LittleEndian.AppendUint64(footer, uint64(len(w.offsets)))
+	footer = binary.LittleEndian.AppendUint32(footer, crc32.Checksum(footer, castagnoli))
+	footer = append(footer, footerMagic...)
+	if _, err := w.w.Write(footer); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
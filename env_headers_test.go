@@ -0,0 +1,147 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/anthropic"
+	"github.com/ncecere/ai-sdk/groq"
+	"github.com/ncecere/ai-sdk/openai"
+	"github.com/ncecere/ai-sdk/provider"
+	"github.com/ncecere/ai-sdk/providerutil"
+)
+
+// dryRunHeaders returns the headers model would send, unredacted.
+func dryRunHeaders(t *testing.T, model LanguageModel) http.Header {
+	t.Helper()
+	builder := model.(provider.RequestBuilder)
+	httpReq, _, err := builder.BuildRequest(context.Background(), &provider.LanguageModelRequest{
+		Messages: []Message{UserMessage("hi")},
+	})
+	if err != nil {
+		t.Fatalf("BuildRequest error: %v", err)
+	}
+	return httpReq.Header
+}
+
+func TestHeadersFromEnv_Precedence(t *testing.T) {
+	t.Setenv("AI_SDK_DEFAULT_HEADERS", `Proxy-Authorization=Basic dXNlcg==, X-Egress-Token="tok,en=1", X-Team=global`)
+	t.Setenv("OPENAI_EXTRA_HEADERS", "X-Team=openai,X-Route=fast")
+	t.Setenv("ANTHROPIC_EXTRA_HEADERS", "X-Team=anthropic")
+	t.Setenv("GROQ_EXTRA_HEADERS", "X-Route=groq")
+
+	oc, err := openai.NewClient(provider.ClientOptions{
+		APIKey: "sk-test", HeadersFromEnv: true,
+		Headers: http.Header{"X-Route": {"explicit"}},
+	})
+	if err != nil {
+		t.Fatalf("openai.NewClient error: %v", err)
+	}
+	ac, err := anthropic.NewClient(provider.ClientOptions{APIKey: "sk-test", HeadersFromEnv: true})
+	if err != nil {
+		t.Fatalf("anthropic.NewClient error: %v", err)
+	}
+	gc, err := groq.NewClient(provider.ClientOptions{APIKey: "sk-test", HeadersFromEnv: true})
+	if err != nil {
+		t.Fatalf("groq.NewClient error: %v", err)
+	}
+
+	cases := []struct {
+		name        string
+		model       LanguageModel
+		team, route string
+	}{
+		{"openai", oc.ChatModel("gpt-test"), "openai", "explicit"},
+		{"anthropic", ac.ChatModel("claude-test"), "anthropic", ""},
+		{"groq", gc.ChatModel("llama-test"), "global", "groq"},
+	}
+	for _, tc := range cases {
+		h := dryRunHeaders(t, tc.model)
+		if got := h.Get("Proxy-Authorization"); got != "Basic dXNlcg==" {
+			t.Errorf("%s: Proxy-Authorization = %q", tc.name, got)
+		}
+		if got := h.Get("X-Egress-Token"); got != "tok,en=1" {
+			t.Errorf("%s: X-Egress-Token = %q", tc.name, got)
+		}
+		if got := h.Get("X-Team"); got != tc.team {
+			t.Errorf("%s: X-Team = %q, want %q", tc.name, got, tc.team)
+		}
+		if got := h.Get("X-Route"); got != tc.route {
+			t.Errorf("%s: X-Route = %q, want %q", tc.name, got, tc.route)
+		}
+	}
+
+	res, err := DryRunText(context.Background(), oc.ChatModel("gpt-test"), GenerateTextRequest{Messages: []Message{UserMessage("hi")}})
+	if err != nil {
+		t.Fatalf("DryRunText error: %v", err)
+	}
+	for _, name := range []string{"Proxy-Authorization", "X-Egress-Token", "Authorization"} {
+		if got := res.Headers.Get(name); got != "REDACTED" {
+			t.Errorf("dry run %s = %q, want REDACTED", name, got)
+		}
+	}
+	if got := res.Headers.Get("X-Team"); got != "openai" {
+		t.Errorf("dry run X-Team = %q", got)
+	}
+}
+
+func TestHeadersFromEnv_OptIn(t *testing.T) {
+	t.Setenv("AI_SDK_DEFAULT_HEADERS", "X-Egress-Token=secret")
+	client, err := openai.NewClient(provider.ClientOptions{APIKey: "sk-test"})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	if got := dryRunHeaders(t, client.ChatModel("gpt-test")).Get("X-Egress-Token"); got != "" {
+		t.Fatalf("env header sent without HeadersFromEnv: %q", got)
+	}
+
+	t.Setenv("OPENAI_EXTRA_HEADERS", "X-Broken")
+	_, err = openai.NewClient(provider.ClientOptions{APIKey: "sk-test", HeadersFromEnv: true})
+	if err == nil || !strings.Contains(err.Error(), "OPENAI_EXTRA_HEADERS") {
+		t.Fatalf("expected error naming OPENAI_EXTRA_HEADERS, got %v", err)
+	}
+}
+
+func TestParseHeaderList(t *testing.T) {
+	valid := []struct {
+		in   string
+		want http.Header
+	}{
+		{"", http.Header{}},
+		{"a=1", http.Header{"A": {"1"}}},
+		{" x-token = abc== , X-Token=def,", http.Header{"X-Token": {"abc==", "def"}}},
+		{`X-List="a, b", X-Eq="k=v"`, http.Header{"X-List": {"a, b"}, "X-Eq": {"k=v"}}},
+		{`X-Quote="say \"hi\" \\o/",X-Empty=""`, http.Header{"X-Quote": {`say "hi" \o/`}, "X-Empty": {""}}},
+		{`X-Space=" padded "`, http.Header{"X-Space": {" padded "}}},
+	}
+	for _, tc := range valid {
+		got, err := providerutil.ParseHeaderList(tc.in)
+		if err != nil {
+			t.Fatalf("ParseHeaderList(%q) error: %v", tc.in, err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("ParseHeaderList(%q) = %v, want %v", tc.in, got, tc.want)
+		}
+		back, err := providerutil.ParseHeaderList(providerutil.FormatHeaderList(got))
+		if err != nil || !reflect.DeepEqual(back, got) {
+			t.Fatalf("round trip of %v via %q = %v, %v", got, providerutil.FormatHeaderList(got), back, err)
+		}
+	}
+
+	for _, in := range []string{
+		"novalue",
+		"a,b=1",
+		"=1",
+		"bad name=1",
+		`a="unterminated`,
+		`a="x"y`,
+		`a="line` + "\n" + `break"`,
+	} {
+		if _, err := providerutil.ParseHeaderList(in); err == nil {
+			t.Fatalf("ParseHeaderList(%q) succeeded", in)
+		}
+	}
+}
@@ -1,6 +1,12 @@
 package ai
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
 
 // Package-level error values and types returned by the ai package.
 var (
@@ -24,8 +30,255 @@ var (
 	// ErrNoEmbeddingGenerated is returned when an embedding request
 	// completes successfully but does not return any vectors.
 	ErrNoEmbeddingGenerated = errors.New("ai: no embedding generated")
+
+	// ErrPendingToolCalls is returned by Session when a new model call
+	// is attempted while tool calls are still awaiting results.
+	ErrPendingToolCalls = errors.New("ai: session has pending tool calls without results")
+
+	// ErrJobNotFound is returned by a JobStore when no job with the
+	// requested ID exists.
+	ErrJobNotFound = errors.New("ai: job not found")
+
+	// ErrJobRunnerClosed is returned by JobRunner.SubmitText after the
+	// runner has been closed.
+	ErrJobRunnerClosed = errors.New("ai: job runner closed")
+
+	// ErrStreamingUnsupported is returned by StreamText when the model
+	// cannot stream. StreamOrSimulate falls back to GenerateText
+	// instead.
+	ErrStreamingUnsupported = provider.ErrStreamingUnsupported
 )
 
+// RetryBudgetExhaustedError is returned by retrying components that
+// skipped a retry because the shared retry budget was exhausted.
+type RetryBudgetExhaustedError = provider.RetryBudgetExhaustedError
+
+// BudgetExceededError is returned by middleware.Budget when a call
+// would exceed a per-user or per-key limit.
+type BudgetExceededError = provider.BudgetExceededError
+
+// UnknownFieldsError is returned by strict JSON decoding when the input
+// contains keys that have no corresponding field in the target type.
+type UnknownFieldsError struct {
+	// Tool is the name of the tool whose arguments were decoded, if any.
+	Tool string
+	// Fields lists the unknown keys as dotted paths, e.g. "filter.extra".
+	Fields []string
+}
+
+func (e *UnknownFieldsError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	msg := "ai: unknown fields " + strings.Join(e.Fields, ", ")
+	if e.Tool != "" {
+		msg += " in arguments for tool " + e.Tool
+	}
+	return msg
+}
+
+// ObjectRepairError is returned by GenerateObject with
+// GenerateObjectOptions.Repair when the output still cannot be decoded
+// after the repair calls. errors.Is and errors.As see both decode
+// errors, so errors.Is(err, ErrInvalidObjectJSON) still holds.
+type ObjectRepairError struct {
+	// Attempts is the number of repair calls made.
+	Attempts int
+	// First is the decode error of the original output.
+	First error
+	// Last is the decode error of the last repair output.
+	Last error
+	// Text is the last repair output.
+	Text string
+}
+
+func (e *ObjectRepairError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("ai: object still invalid after %d repair attempts: %v (original: %v)", e.Attempts, e.Last, e.First)
+}
+
+// Unwrap returns the original and the last decode error.
+func (e *ObjectRepairError) Unwrap() []error {
+	if e == nil {
+		return nil
+	}
+	return []error{e.First, e.Last}
+}
+
+// EnumValueError is returned by GenerateEnum when the model keeps
+// answering with a value outside the allowed set.
+type EnumValueError struct {
+	// Value is the last value the model returned.
+	Value string
+	// Allowed lists the allowed values.
+	Allowed []string
+}
+
+func (e *EnumValueError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("ai: model returned %q, want one of %s", e.Value, quoteList(e.Allowed))
+}
+
+// PartialResponseError is returned when a text generation fails after
+// the model already produced content, for example when a stream is cut
+// mid-response. Partial holds what was received before the failure so
+// callers can show it as an interrupted response and reconcile usage
+// against it.
+//
+// It is returned by WriteTextStreamAsSSE and WriteTextStreamToWriter
+// when the stream, the context, or the writer fails after any text or
+// reasoning was received; Partial then holds the Text and Reasoning
+// read from the stream so far. Errors before any content are returned
+// unwrapped.
+type PartialResponseError struct {
+	// Partial is the response content received before the failure.
+	Partial GenerateTextResponse
+	// Err is the error that interrupted the response.
+	Err error
+}
+
+func (e *PartialResponseError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("ai: response interrupted after %d bytes of text: %v", len(e.Partial.Text), e.Err)
+}
+
+// Unwrap returns the error that interrupted the response.
+func (e *PartialResponseError) Unwrap() error {
+	if e == nil {
+		return nil
+	}
+	return e.Err
+}
+
+// PartialRerankError is returned by RerankLarge together with the
+// results that were scored when some rerank calls failed.
+type PartialRerankError struct {
+	// Shards is the total number of shards the documents were split into.
+	Shards int
+	// Failed lists the shards whose rerank call failed. Their documents
+	// are missing from the results.
+	Failed []RerankShardError
+	// SecondPass is the error of the second-pass rerank, if it failed.
+	// The results are then in merged first-pass order.
+	SecondPass error
+}
+
+func (e *PartialRerankError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	msg := fmt.Sprintf("ai: rerank partially failed: %d of %d shards failed", len(e.Failed), e.Shards)
+	if len(e.Failed) > 0 {
+		msg += " (first: " + e.Failed[0].Err.Error() + ")"
+	}
+	if e.SecondPass != nil {
+		msg += "; second pass: " + e.SecondPass.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the underlying shard and second-pass errors.
+func (e *PartialRerankError) Unwrap() []error {
+	if e == nil {
+		return nil
+	}
+	errs := make([]error, 0, len(e.Failed)+1)
+	for _, f := range e.Failed {
+		errs = append(errs, f.Err)
+	}
+	if e.SecondPass != nil {
+		errs = append(errs, e.SecondPass)
+	}
+	return errs
+}
+
+// RerankShardError describes a failed RerankLarge shard covering
+// documents [Start, End).
+type RerankShardError struct {
+	Start int
+	End   int
+	Err   error
+}
+
+// TranscribeChunkError is returned by TranscribeLong together with the
+// text of the preceding chunks when a chunk's transcription fails.
+type TranscribeChunkError struct {
+	// Chunk is the zero-based index of the failed chunk.
+	Chunk int
+	// Start and End are the chunk's boundaries in seconds.
+	Start float64
+	End   float64
+	// Err is the transcription error.
+	Err error
+}
+
+func (e *TranscribeChunkError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("ai: transcription of chunk %d (%.1fs-%.1fs) failed: %v", e.Chunk, e.Start, e.End, e.Err)
+}
+
+// Unwrap returns the underlying transcription error.
+func (e *TranscribeChunkError) Unwrap() error {
+	if e == nil {
+		return nil
+	}
+	return e.Err
+}
+
+// TracedError wraps an error from a model call that has no TraceID
+// field of its own with the call's trace ID. Use TraceIDFromError to
+// read the ID from any error returned by GenerateText or StreamText.
+type TracedError struct {
+	// TraceID is the client-generated trace ID of the failed call.
+	TraceID string
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *TracedError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	return e.Err.Error() + " (trace_id=" + e.TraceID + ")"
+}
+
+// Unwrap returns the underlying error.
+func (e *TracedError) Unwrap() error {
+	if e == nil {
+		return nil
+	}
+	return e.Err
+}
+
+// JSONPathError is returned by GetPath and the typed Get helpers when
+// a path cannot be resolved or the value has the wrong type.
+type JSONPathError struct {
+	// Path is the full path that was requested.
+	Path string
+	// At is the prefix of Path where resolution failed.
+	At string
+	// Message describes the failure, e.g. "missing key".
+	Message string
+}
+
+func (e *JSONPathError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	if e.At == "" || e.At == e.Path {
+		return fmt.Sprintf("ai: json path %q: %s", e.Path, e.Message)
+	}
+	return fmt.Sprintf("ai: json path %q: %s at %q", e.Path, e.Message, e.At)
+}
+
 // InvalidArgumentError indicates that a function argument is invalid.
 // It is intended for validation of ai package helper arguments, such
 // as call settings or prompt construction helpers.
@@ -44,22 +297,3 @@ func (e *InvalidArgumentError) Error() string {
 	}
 	return "ai: invalid argument for parameter " + e.Parameter + ": " + e.Message
 }
-
-// UnsupportedFunctionalityError indicates that a requested feature is
-// not supported by the current implementation.
-type UnsupportedFunctionalityError struct {
-	// Feature describes the unsupported feature, e.g. "image generation".
-	Feature string
-	// Message is an optional explanatory message.
-	Message string
-}
-
-func (e *UnsupportedFunctionalityError) Error() string {
-	if e == nil {
-		return "<nil>"
-	}
-	if e.Message != "" {
-		return "ai: unsupported functionality (" + e.Feature + "): " + e.Message
-	}
-	return "ai: unsupported functionality (" + e.Feature + ")"
-}
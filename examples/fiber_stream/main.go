@@ -2,20 +2,22 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"log"
 	"os"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	ai "github.com/ncecere/ai-sdk"
+	"github.com/ncecere/ai-sdk/fiberadapter"
 	"github.com/ncecere/ai-sdk/openai"
 	"github.com/ncecere/ai-sdk/provider"
 )
 
 // This example demonstrates integrating ai-sdk with the Fiber web
 // framework, streaming chat completions to the client using an
-// SSE-style response.
+// SSE-style response. fiberadapter flushes each event as it arrives;
+// writing to c.Context().Response.BodyWriter() would buffer the whole
+// response until the handler returns.
 //
 // It expects:
 //
@@ -38,8 +40,10 @@ func main() {
 	app.Get("/stream", func(c *fiber.Ctx) error {
 		prompt := c.Query("prompt", "Stream a response from Fiber.")
 
-		ctx, cancel := context.WithTimeout(c.Context(), 2*time.Minute)
-		defer cancel()
+		// The body is streamed after the handler returns, so the
+		// timeout must not be tied to the handler's lifetime; the
+		// adapter calls cancel once the stream has been written.
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 
 		stream, err := ai.StreamText(ctx, ai.GenerateTextRequest{
 			Model: model,
@@ -49,38 +53,16 @@ func main() {
 			}},
 		})
 		if err != nil {
+			cancel()
 			return fiber.NewError(fiber.StatusInternalServerError, err.Error())
 		}
-		defer stream.Close()
 
-		c.Set("Content-Type", "text/event-stream")
-		c.Set("Cache-Control", "no-cache")
-		c.Set("Connection", "keep-alive")
-
-		writer := c.Context().Response.BodyWriter()
-
-		for {
-			if err := ctx.Err(); err != nil {
-				return err
-			}
-			delta, err := stream.Next(ctx)
+		return fiberadapter.SendTextStream(ctx, c, stream, func(err error) {
+			cancel()
 			if err != nil {
-				return err
-			}
-			if delta.Done {
-				break
-			}
-			if delta.Text == "" {
-				continue
+				log.Printf("stream error: %v", err)
 			}
-			if _, err := fmt.Fprintf(writer, "data: %s\n\n", delta.Text); err != nil {
-				return err
-			}
-		}
-		if _, err := fmt.Fprint(writer, "data: [DONE]\n\n"); err != nil {
-			return err
-		}
-		return nil
+		})
 	})
 
 	log.Println("Fiber SSE-style chat streaming on :8081/stream?prompt=...")
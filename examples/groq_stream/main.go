@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"fmt"
 	"log"
@@ -46,22 +45,9 @@ func main() {
 	if err != nil {
 		log.Fatalf("stream error: %v", err)
 	}
-	defer stream.Close()
 
-	writer := bufio.NewWriter(os.Stdout)
-	defer writer.Flush()
-
-	for {
-		delta, err := stream.Next(ctx)
-		if err != nil {
-			log.Fatalf("stream next error: %v", err)
-		}
-		if delta.Done {
-			break
-		}
-		fmt.Fprint(writer, delta.Text)
-		writer.Flush()
+	if _, err := ai.CopyTextStream(ctx, os.Stdout, stream); err != nil {
+		log.Fatalf("stream error: %v", err)
 	}
-
-	fmt.Fprintln(writer)
+	fmt.Println()
 }
@@ -89,8 +89,8 @@ func main() {
 		Model: model,
 		Messages: []ai.Message{
 			{Role: ai.RoleUser, Content: "Use the add tool to add 3 and 5."},
-			{Role: ai.RoleAssistant, Content: res.Text},
-			{Role: ai.RoleTool, Content: string(payload)},
+			ai.AssistantToolCallMessage(res.Text, res.ToolCalls),
+			{Role: ai.RoleTool, Content: string(payload), ToolCallID: tc.ID},
 		},
 	})
 	if err != nil {
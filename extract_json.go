@@ -0,0 +1,99 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ExtractJSON returns the first top-level JSON object or array in
+// model output. Compatible backends, and some models even with a JSON
+// schema set, wrap the value in a markdown code fence or surround it
+// with prose such as "Here is the JSON:". The contents of fenced blocks
+// are searched first, then the whole text; text before and after the
+// value is ignored. GenerateObject and GenerateJSON use it, and it
+// works as well on tool results.
+//
+// A bracket in prose that does not start valid JSON is skipped, but a
+// value cut short by the end of the text is an error rather than a
+// reason to return a nested value from inside it.
+//
+// Errors:
+//   - ErrInvalidObjectJSON (wrapped) if the text holds no object or
+//     array, with the parse error of the first candidate if there was
+//     one.
+func ExtractJSON(text string) ([]byte, error) {
+	var firstErr error
+	for _, block := range append(fencedBlocks(text), text) {
+		raw, err := firstJSONValue(block)
+		if err == nil {
+			return raw, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, fmt.Errorf("%w: %v", ErrInvalidObjectJSON, firstErr)
+}
+
+// errNoJSONValue reports text without an object or array.
+var errNoJSONValue = errors.New("no JSON object or array found")
+
+// firstJSONValue returns the first object or array in s that parses.
+// After a candidate with a syntax error, the search resumes past the
+// error, so values nested inside invalid JSON are not returned.
+func firstJSONValue(s string) ([]byte, error) {
+	var firstErr error
+	for from := 0; from < len(s); {
+		i := strings.IndexAny(s[from:], "{[")
+		if i < 0 {
+			break
+		}
+		start := from + i
+		var raw json.RawMessage
+		err := json.NewDecoder(strings.NewReader(s[start:])).Decode(&raw)
+		if err == nil {
+			return raw, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		var syntaxErr *json.SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			// Truncated: every later candidate is nested inside it.
+			return nil, firstErr
+		}
+		from = start + max(int(syntaxErr.Offset), 1)
+	}
+	if firstErr == nil {
+		firstErr = errNoJSONValue
+	}
+	return nil, firstErr
+}
+
+// fencedBlocks returns the contents of the markdown code fences in
+// text, without their info strings (such as "json"). An unclosed fence
+// runs to the end of the text.
+func fencedBlocks(text string) []string {
+	var blocks []string
+	for {
+		start := strings.Index(text, "```")
+		if start < 0 {
+			return blocks
+		}
+		text = text[start+3:]
+		// Drop the info string, e.g. "json".
+		if nl := strings.IndexByte(text, '\n'); nl >= 0 {
+			text = text[nl+1:]
+		} else {
+			text = ""
+		}
+		end := strings.Index(text, "```")
+		if end < 0 {
+			return append(blocks, text)
+		}
+		blocks = append(blocks, text[:end])
+		text = text[end+3:]
+	}
+}
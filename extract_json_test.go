@@ -0,0 +1,52 @@
+package ai
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExtractJSON(t *testing.T) {
+	cases := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"clean object", `{"name":"Ada"}`, `{"name":"Ada"}`},
+		{"clean array", " [1, 2]\n", `[1, 2]`},
+		{"fenced", "```json\n{\"name\": \"Ada\"}\n```", `{"name": "Ada"}`},
+		{"fenced without info string", "```\n[{\"a\":1}]\n```", `[{"a":1}]`},
+		{"prefixed", `Here is the JSON: {"name":"Ada"}`, `{"name":"Ada"}`},
+		{"prose around fence", "Sure! Here it is:\n```json\n{\"ok\":true}\n```\nLet me know [if] you need more.", `{"ok":true}`},
+		{"trailing prose with braces", `{"a":1} Note: {b} is unused.`, `{"a":1}`},
+		{"brackets in leading prose", `Options [see below]: {"a":[1]}`, `{"a":[1]}`},
+		{"braces in strings", `{"text":"use } and ] freely"} done`, `{"text":"use } and ] freely"}`},
+		{"first of two values", `{"a":1}{"b":2}`, `{"a":1}`},
+		{"unclosed fence", "```json\n{\"a\":1}", `{"a":1}`},
+	}
+	for _, tc := range cases {
+		got, err := ExtractJSON(tc.text)
+		if err != nil || string(got) != tc.want {
+			t.Errorf("%s: ExtractJSON = %s, %v; want %s", tc.name, got, err, tc.want)
+		}
+	}
+}
+
+func TestExtractJSON_Errors(t *testing.T) {
+	cases := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"no JSON", "I cannot help with that.", "no JSON object or array"},
+		{"truncated", `{"name":{"first":"Ada"}`, "unexpected EOF"},
+		{"trailing comma", `{"name":{"first":"Ada"},}`, "invalid character '}'"},
+		{"single quotes", `Result: {'a': 1}`, `invalid character '\''`},
+	}
+	for _, tc := range cases {
+		got, err := ExtractJSON(tc.text)
+		if !errors.Is(err, ErrInvalidObjectJSON) || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: ExtractJSON = %s, %v; want error mentioning %q", tc.name, got, err, tc.want)
+		}
+	}
+}
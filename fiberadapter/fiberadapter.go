@@ -0,0 +1,67 @@
+// Package fiberadapter streams ai-sdk results through Fiber handlers.
+//
+// Fiber is built on fasthttp, which buffers everything written to
+// Response.BodyWriter until the handler returns. The helpers in this
+// package use fasthttp's SetBodyStreamWriter instead so each event is
+// flushed to the client as soon as it is produced.
+package fiberadapter
+
+import (
+	"bufio"
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+	ai "github.com/ncecere/ai-sdk"
+)
+
+// SendTextStream sets the SSE headers on c and streams stream to the
+// client in the same format as ai.WriteTextStreamAsSSE.
+//
+// The body is written after the handler returns, so ctx must outlive
+// the handler and the adapter takes ownership of stream, closing it
+// when writing finishes. done, if non-nil, is called with the result
+// of writing the stream (nil on success); use it to release resources
+// tied to ctx, such as a timeout's cancel func, and to observe errors
+// that occur after the response has started.
+func SendTextStream(ctx context.Context, c *fiber.Ctx, stream ai.TextStream, done func(error)) error {
+	setSSEHeaders(c)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		err := ai.WriteTextStreamToWriter(ctx, w, stream)
+		if done != nil {
+			done(err)
+		}
+	})
+	return nil
+}
+
+// SendTextStreamWithOptions is SendTextStream in the format of
+// ai.WriteTextStreamAsSSEWithOptions.
+func SendTextStreamWithOptions(ctx context.Context, c *fiber.Ctx, stream ai.TextStream, opts ai.SSEOptions, done func(error)) error {
+	setSSEHeaders(c)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		err := ai.WriteTextStreamToWriterWithOptions(ctx, w, stream, opts)
+		if done != nil {
+			done(err)
+		}
+	})
+	return nil
+}
+
+// SendImageStream is the image counterpart of SendTextStream, writing
+// events in the format of ai.WriteImageStreamAsSSE.
+func SendImageStream(ctx context.Context, c *fiber.Ctx, stream ai.ImageStream, done func(error)) error {
+	setSSEHeaders(c)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		err := ai.WriteImageStreamToWriter(ctx, w, stream)
+		if done != nil {
+			done(err)
+		}
+	})
+	return nil
+}
+
+func setSSEHeaders(c *fiber.Ctx) {
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+}
@@ -0,0 +1,97 @@
+package fiberadapter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	ai "github.com/ncecere/ai-sdk"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+// gatedStream yields each delta only after it is sent on next.
+type gatedStream struct {
+	next   chan ai.TextDelta
+	closed chan struct{}
+}
+
+func (s *gatedStream) Next(ctx context.Context) (*ai.TextDelta, error) {
+	select {
+	case d := <-s.next:
+		return &d, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *gatedStream) Close() error {
+	close(s.closed)
+	return nil
+}
+
+func TestSendTextStream_DeliversIncrementally(t *testing.T) {
+	stream := &gatedStream{next: make(chan ai.TextDelta), closed: make(chan struct{})}
+	done := make(chan error, 1)
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/stream", func(c *fiber.Ctx) error {
+		return SendTextStream(context.Background(), c, stream, func(err error) { done <- err })
+	})
+	ln := fasthttputil.NewInmemoryListener()
+	go app.Listener(ln)
+	defer app.Shutdown()
+
+	conn, err := ln.Dial()
+	if err != nil {
+		t.Fatalf("Dial error: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, "GET /stream HTTP/1.1\r\nHost: test\r\n\r\n")
+
+	var received strings.Builder
+	readUntil := func(want string) {
+		t.Helper()
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		buf := make([]byte, 1024)
+		for !strings.Contains(received.String(), want) {
+			n, err := conn.Read(buf)
+			if err != nil {
+				t.Fatalf("waiting for %q: %v (got %q)", want, err, received.String())
+			}
+			received.Write(buf[:n])
+		}
+	}
+
+	stream.next <- ai.TextDelta{Text: "one"}
+	// The second delta is held back, so "one" can only arrive if the
+	// adapter flushes each event instead of buffering the body.
+	readUntil("data: one\n\n")
+	if !strings.Contains(received.String(), "Content-Type: text/event-stream") {
+		t.Fatalf("missing SSE headers: %q", received.String())
+	}
+	if strings.Contains(received.String(), "[DONE]") {
+		t.Fatalf("stream finished early: %q", received.String())
+	}
+
+	stream.next <- ai.TextDelta{Text: "two"}
+	readUntil("data: two\n\n")
+	stream.next <- ai.TextDelta{Done: true}
+	readUntil("data: [DONE]\n\n")
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("done called with error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("done was not called")
+	}
+	select {
+	case <-stream.closed:
+	default:
+		t.Fatalf("stream was not closed")
+	}
+}
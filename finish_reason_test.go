@@ -0,0 +1,98 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+func TestGenerateText_FinishReason(t *testing.T) {
+	cases := []struct {
+		provider string
+		stop     string
+		want     FinishReason
+	}{
+		{"openai", "length", FinishReasonLength},
+		{"openai", "insufficient_system_resource", FinishReasonOther},
+		{"anthropic", "max_tokens", FinishReasonLength},
+		{"anthropic", "pause_turn", FinishReasonOther},
+	}
+	newModel := map[string]func(provider.ClientOptions) (LanguageModel, error){}
+	for _, mc := range mixedCases {
+		newModel[mc.name] = mc.newModel
+	}
+	for _, tc := range cases {
+		t.Run(tc.provider+"/"+tc.stop, func(t *testing.T) {
+			var bodies []map[string]any
+			ts := fixtureServer(t, "finishreason/"+tc.provider+"_"+tc.stop+".json", &bodies)
+			defer ts.Close()
+			model, err := newModel[tc.provider](provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+			if err != nil {
+				t.Fatalf("NewClient error: %v", err)
+			}
+
+			res, err := GenerateText(context.Background(), GenerateTextRequest{
+				Model:    model,
+				Messages: []Message{UserMessage("Weather in Paris?")},
+			})
+			if err != nil {
+				t.Fatalf("GenerateText error: %v", err)
+			}
+			if res.FinishReason != tc.want || res.StopReason != tc.stop {
+				t.Fatalf("finish=%q stop=%q, want %q and %q", res.FinishReason, res.StopReason, tc.want, tc.stop)
+			}
+		})
+	}
+}
+
+func TestGenerateText_ContentBlocked(t *testing.T) {
+	cases := []struct{ provider, stop string }{
+		{"openai", "content_filter"},
+		{"anthropic", "refusal"},
+	}
+	newModel := map[string]func(provider.ClientOptions) (LanguageModel, error){}
+	for _, mc := range mixedCases {
+		newModel[mc.name] = mc.newModel
+	}
+	for _, tc := range cases {
+		t.Run(tc.provider+"/"+tc.stop, func(t *testing.T) {
+			var bodies []map[string]any
+			ts := fixtureServer(t, "finishreason/"+tc.provider+"_"+tc.stop+".json", &bodies)
+			defer ts.Close()
+			model, err := newModel[tc.provider](provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+			if err != nil {
+				t.Fatalf("NewClient error: %v", err)
+			}
+
+			_, err = GenerateText(context.Background(), GenerateTextRequest{
+				Model:    model,
+				Messages: []Message{UserMessage("Weather in Paris?")},
+			})
+			var blocked *ContentBlockedError
+			if !errors.As(err, &blocked) || blocked.Provider != tc.provider || blocked.Reason != tc.stop || blocked.TraceID == "" {
+				t.Fatalf("GenerateText error = %v, want a traced ContentBlockedError with reason %q", err, tc.stop)
+			}
+		})
+	}
+}
+
+func TestGenerateText_FinishReasonFromStopReason(t *testing.T) {
+	model := &scriptedModel{responses: []*provider.LanguageModelResponse{
+		{Text: "done", StopReason: "end_turn"},
+		{StopReason: "tool_use"},
+	}}
+	for _, want := range []FinishReason{FinishReasonStop, FinishReasonOther} {
+		res, err := GenerateText(context.Background(), GenerateTextRequest{
+			Model:    model,
+			Messages: []Message{UserMessage("hi")},
+		})
+		if err != nil {
+			t.Fatalf("GenerateText error: %v", err)
+		}
+		if res.FinishReason != want {
+			t.Fatalf("finish=%q for stop %q, want %q", res.FinishReason, res.StopReason, want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// enumChoice is the object GenerateEnum asks the model for. Structured
+// outputs must be objects, so the label is wrapped in one.
+type enumChoice struct {
+	Value string `json:"value"`
+}
+
+// GenerateEnum asks the model to pick one of the allowed values, such
+// as a sentiment label, and returns it. The request carries a JSON
+// schema whose only property is constrained to the allowed values. A
+// returned value that differs from an allowed one only in case or
+// surrounding whitespace counts as that value. Any other value is sent
+// back to the model once, with the allowed values, before giving up.
+//
+// Errors:
+//   - *InvalidArgumentError if allowed is empty.
+//   - *EnumValueError if the value is still not allowed after the retry.
+//   - ErrNoObjectGenerated or ErrInvalidObjectJSON (wrapped) if the
+//     output does not decode.
+//   - Any error returned by GenerateText.
+func GenerateEnum(ctx context.Context, model LanguageModel, messages []Message, allowed []string) (string, error) {
+	if len(allowed) == 0 {
+		return "", &InvalidArgumentError{Parameter: "allowed", Value: allowed, Message: "must list at least one value"}
+	}
+	schema, err := enumSchema(allowed)
+	if err != nil {
+		return "", err
+	}
+	req := GenerateTextRequest{Model: model, Messages: messages, JSONSchema: schema, JSONSchemaName: "choice"}
+
+	got, err := generateObject[enumChoice](ctx, req, DecodeOptions{}, 0)
+	if err != nil {
+		return "", err
+	}
+	if value, ok := matchEnum(got.Value, allowed); ok {
+		return value, nil
+	}
+
+	previous, err := json.Marshal(got)
+	if err != nil {
+		return "", err
+	}
+	req.Messages = append(slices.Clone(messages), AssistantMessage(string(previous)), UserMessage(fmt.Sprintf(
+		"%q is not an allowed value. Respond again with \"value\" set to exactly one of: %s.", got.Value, quoteList(allowed))))
+	got, err = generateObject[enumChoice](ctx, req, DecodeOptions{}, 0)
+	if err != nil {
+		return "", err
+	}
+	if value, ok := matchEnum(got.Value, allowed); ok {
+		return value, nil
+	}
+	return "", &EnumValueError{Value: got.Value, Allowed: slices.Clone(allowed)}
+}
+
+// enumSchema builds the schema of an enumChoice restricted to allowed.
+func enumSchema(allowed []string) ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"value": map[string]any{"type": "string", "enum": allowed},
+		},
+		"required":             []string{"value"},
+		"additionalProperties": false,
+	})
+}
+
+// matchEnum returns the allowed value that value names: an exact match
+// first, then one that differs only in case and surrounding whitespace.
+func matchEnum(value string, allowed []string) (string, bool) {
+	if slices.Contains(allowed, value) {
+		return value, true
+	}
+	value = strings.TrimSpace(value)
+	for _, a := range allowed {
+		if strings.EqualFold(a, value) {
+			return a, true
+		}
+	}
+	return "", false
+}
+
+// quoteList formats values as a comma-separated list of quoted strings.
+func quoteList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = fmt.Sprintf("%q", v)
+	}
+	return strings.Join(quoted, ", ")
+}
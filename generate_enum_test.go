@@ -0,0 +1,60 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+func TestGenerateEnum(t *testing.T) {
+	allowed := []string{"positive", "negative", "neutral"}
+	messages := []Message{UserMessage("Classify: I love it.")}
+
+	model := &scriptedModel{responses: []*provider.LanguageModelResponse{{Text: `{"value":" Positive"}`}}}
+	got, err := GenerateEnum(context.Background(), model, messages, allowed)
+	if err != nil || got != "positive" {
+		t.Fatalf("GenerateEnum = %q, %v", got, err)
+	}
+	req := model.requests[0]
+	if !strings.Contains(string(req.JSONSchema), `"enum":["positive","negative","neutral"]`) || req.JSONSchemaName != "choice" {
+		t.Fatalf("expected an enum schema, got %q %s", req.JSONSchemaName, req.JSONSchema)
+	}
+
+	// A miss is retried once with the allowed values.
+	model = &scriptedModel{responses: []*provider.LanguageModelResponse{{Text: `{"value":"happy"}`}, {Text: `{"value":"neutral"}`}}}
+	if got, err := GenerateEnum(context.Background(), model, messages, allowed); err != nil || got != "neutral" {
+		t.Fatalf("GenerateEnum after retry = %q, %v", got, err)
+	}
+	retry := model.requests[1].Messages
+	if len(retry) != 3 || retry[1].Content != `{"value":"happy"}` || !strings.Contains(retry[2].Content, `"positive", "negative", "neutral"`) {
+		t.Fatalf("unexpected retry messages %+v", retry)
+	}
+	if len(messages) != 1 {
+		t.Fatalf("caller's messages were modified")
+	}
+}
+
+func TestGenerateEnum_Errors(t *testing.T) {
+	allowed := []string{"yes", "no"}
+	model := &scriptedModel{responses: []*provider.LanguageModelResponse{{Text: `{"value":"maybe"}`}, {Text: `{"value":"perhaps"}`}}}
+	_, err := GenerateEnum(context.Background(), model, nil, allowed)
+	var enumErr *EnumValueError
+	if !errors.As(err, &enumErr) || enumErr.Value != "perhaps" || !reflect.DeepEqual(enumErr.Allowed, allowed) {
+		t.Fatalf("expected *EnumValueError, got %v", err)
+	}
+	if want := `ai: model returned "perhaps", want one of "yes", "no"`; err.Error() != want {
+		t.Fatalf("error = %q, want %q", err, want)
+	}
+	if len(model.requests) != 2 {
+		t.Fatalf("expected one retry, got %d calls", len(model.requests))
+	}
+
+	var argErr *InvalidArgumentError
+	if _, err := GenerateEnum(context.Background(), model, nil, nil); !errors.As(err, &argErr) || argErr.Parameter != "allowed" {
+		t.Fatalf("expected InvalidArgumentError, got %v", err)
+	}
+}
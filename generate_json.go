@@ -0,0 +1,68 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// permissiveObjectSchema accepts any JSON object.
+var permissiveObjectSchema = []byte(`{"type":"object"}`)
+
+// GenerateJSONOptions configures GenerateJSON.
+type GenerateJSONOptions struct {
+	// Schema optionally constrains the output. If nil, a permissive
+	// object schema is used so any JSON object is accepted.
+	Schema []byte
+	// UseNumber decodes numbers as json.Number instead of float64,
+	// preserving large integers. The Get helpers accept both.
+	UseNumber bool
+	// Temperature controls randomness of the output.
+	Temperature *float64
+	// MaxTokens limits the number of tokens generated.
+	MaxTokens *int
+}
+
+// GenerateJSON generates a JSON object without a Go target type and
+// returns it as a map. Use GetString, GetInt, and the other Get helpers
+// to read fields by path. Like GenerateObject, it extracts the object
+// from code fences and surrounding prose with ExtractJSON.
+//
+// Errors:
+//   - ErrNoObjectGenerated if the model produced an empty result.
+//   - ErrInvalidObjectJSON (wrapped) if the output is not a JSON object.
+//   - Any error returned by GenerateText.
+func GenerateJSON(ctx context.Context, model LanguageModel, messages []Message, opts GenerateJSONOptions) (map[string]any, error) {
+	schema := opts.Schema
+	if len(schema) == 0 {
+		schema = permissiveObjectSchema
+	}
+
+	res, err := GenerateText(ctx, GenerateTextRequest{
+		Model:       model,
+		Messages:    messages,
+		JSONSchema:  schema,
+		Temperature: opts.Temperature,
+		MaxTokens:   opts.MaxTokens,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(res.Text) == "" {
+		return nil, ErrNoObjectGenerated
+	}
+	raw, err := ExtractJSON(res.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	var out map[string]any
+	if err := DecodeJSON(raw, &out, DecodeOptions{UseNumber: opts.UseNumber}); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidObjectJSON, err)
+	}
+	if out == nil {
+		return nil, fmt.Errorf("%w: output is not a JSON object", ErrInvalidObjectJSON)
+	}
+	return out, nil
+}
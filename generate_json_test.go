@@ -0,0 +1,60 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+func TestGenerateJSON_StripsFencesAndQueriesPaths(t *testing.T) {
+	model := &scriptedModel{responses: []*provider.LanguageModelResponse{{
+		Text: "Here you go:\n```json\n{\"user\":{\"name\":\"Ada\",\"id\":1234567890123456789,\"tags\":[\"a\",{\"score\":0.5}],\"active\":true}}\n```",
+	}}}
+
+	doc, err := GenerateJSON(context.Background(), model, []Message{UserMessage("who?")}, GenerateJSONOptions{UseNumber: true})
+	if err != nil {
+		t.Fatalf("GenerateJSON error: %v", err)
+	}
+	if got := string(model.requests[0].JSONSchema); got != `{"type":"object"}` {
+		t.Fatalf("expected permissive schema, got %s", got)
+	}
+
+	if name, err := GetString(doc, "user.name"); err != nil || name != "Ada" {
+		t.Fatalf("GetString = %q, %v", name, err)
+	}
+	if id, err := GetInt(doc, "user.id"); err != nil || id != 1234567890123456789 {
+		t.Fatalf("GetInt = %d, %v", id, err)
+	}
+	if score, err := GetFloat(doc, "user.tags[1].score"); err != nil || score != 0.5 {
+		t.Fatalf("GetFloat = %v, %v", score, err)
+	}
+	if active, err := GetBool(doc, "user.active"); err != nil || !active {
+		t.Fatalf("GetBool = %v, %v", active, err)
+	}
+
+	var pathErr *JSONPathError
+	if _, err := GetString(doc, "user.tags[5]"); !errors.As(err, &pathErr) || pathErr.At != "user.tags[5]" || pathErr.Message != "index out of range" {
+		t.Fatalf("expected out of range error, got %v", err)
+	}
+	if _, err := GetString(doc, "user.email.domain"); !errors.As(err, &pathErr) || pathErr.At != "user.email" || pathErr.Message != "missing key" {
+		t.Fatalf("expected missing key error, got %v", err)
+	}
+	if _, err := GetInt(doc, "user.name"); !errors.As(err, &pathErr) {
+		t.Fatalf("expected type error, got %v", err)
+	}
+	if _, err := GetPath(doc, "user..name"); !errors.As(err, &pathErr) {
+		t.Fatalf("expected malformed path error, got %v", err)
+	}
+}
+
+func TestGenerateObject_AcceptsFencedOutput(t *testing.T) {
+	model := &scriptedModel{responses: []*provider.LanguageModelResponse{{Text: "```\n{\"city\":\"Paris\"}\n```"}}}
+	out, err := GenerateObject[struct {
+		City string `json:"city"`
+	}](context.Background(), model, []Message{UserMessage("where?")})
+	if err != nil || out.City != "Paris" {
+		t.Fatalf("GenerateObject = %+v, %v", out, err)
+	}
+}
@@ -1,11 +1,17 @@
 package ai
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
+	"slices"
 	"strings"
+
+	"github.com/ncecere/ai-sdk/providerutil"
+	"github.com/ncecere/ai-sdk/registry"
 )
 
 // GenerateObject generates a structured object using a language model
@@ -15,46 +21,529 @@ import (
 //
 // This helper is built on top of GenerateText and the provider's
 // JSON schema / JSON mode support.
-func GenerateObject[T any](ctx context.Context, model LanguageModel, messages []Message) (T, error) {
+//
+// ErrNoObjectGenerated is returned only when the model produced an
+// empty result. Malformed provider responses surface as the underlying
+// *EmptyResponseError instead so the real cause is not masked.
+//
+// opts optionally supplies a hand-written schema, a schema name, call
+// settings, decode options, and repair calls for invalid output; see
+// GenerateObjectOptions.
+func GenerateObject[T any](ctx context.Context, model LanguageModel, messages []Message, opts ...GenerateObjectOptions) (T, error) {
+	o := objectOptions(opts)
+	repairs := 0
+	if o.Repair {
+		repairs = max(o.MaxRepairs, 1)
+	}
+	return generateObject[T](ctx, o.request(model, messages), o.Decode, repairs)
+}
+
+// GenerateObjectWithRegistry is a convenience helper that looks up the
+// language model by name in the provided registry and then delegates
+// to GenerateObject.
+//
+// Errors:
+//   - InvalidArgumentError if reg is nil.
+//   - Any error returned by reg.LanguageModel, such as
+//     *registry.NoSuchModelError, unchanged.
+//   - Any error returned by GenerateObject.
+func GenerateObjectWithRegistry[T any](ctx context.Context, reg registry.Registry, modelName string, messages []Message, opts ...GenerateObjectOptions) (T, error) {
 	var zero T
+	if reg == nil {
+		return zero, &InvalidArgumentError{Parameter: "reg", Value: nil, Message: "registry must not be nil"}
+	}
 
-	schema, err := JSONSchemaFromType(zero)
+	lm, err := reg.LanguageModel(modelName)
 	if err != nil {
-		return zero, fmt.Errorf("ai: building JSON schema for object: %w", err)
+		return zero, err
 	}
+	return GenerateObject[T](ctx, lm, messages, opts...)
+}
 
-	res, err := GenerateText(ctx, GenerateTextRequest{
-		Model:      model,
-		Messages:   messages,
-		JSONSchema: schema,
-	})
+// GenerateObjectOptions customizes GenerateObject and StreamObject.
+type GenerateObjectOptions struct {
+	// Schema is a hand-written JSON schema for T, for when the schema
+	// inferred by JSONSchemaFromType is too loose. When set, no schema
+	// is inferred.
+	Schema []byte
+	// SchemaName names the schema for providers that require a name;
+	// see GenerateTextRequest.JSONSchemaName.
+	SchemaName string
+	// Settings are optional call settings such as Temperature and
+	// MaxTokens.
+	Settings *CallSettings
+	// Decode controls how the output is decoded into T, for example
+	// KeyMatchTolerant for models that mix naming conventions.
+	Decode DecodeOptions
+	// Repair enables repair calls: when the output cannot be decoded
+	// into T, the invalid output and the decode error are appended to
+	// the conversation and the model is asked for corrected JSON.
+	// Failing that, GenerateObject returns an *ObjectRepairError.
+	// StreamObject ignores Repair.
+	Repair bool
+	// MaxRepairs is the number of repair calls made with Repair.
+	// Values <= 0 mean 1.
+	MaxRepairs int
+	// Strict requests strict structured outputs; see
+	// GenerateTextRequest.JSONSchemaStrict. Fields that are not
+	// required, such as pointer fields, are then sent as nullable and
+	// decode to nil when the model returns null.
+	Strict bool
+}
+
+// objectOptions merges opts; non-zero fields of later options take
+// precedence.
+func objectOptions(opts []GenerateObjectOptions) GenerateObjectOptions {
+	var merged GenerateObjectOptions
+	for _, o := range opts {
+		if len(o.Schema) > 0 {
+			merged.Schema = o.Schema
+		}
+		if o.SchemaName != "" {
+			merged.SchemaName = o.SchemaName
+		}
+		if o.Settings != nil {
+			merged.Settings = o.Settings
+		}
+		if o.Decode != (DecodeOptions{}) {
+			merged.Decode = o.Decode
+		}
+		if o.Repair {
+			merged.Repair = true
+		}
+		if o.MaxRepairs != 0 {
+			merged.MaxRepairs = o.MaxRepairs
+		}
+		if o.Strict {
+			merged.Strict = true
+		}
+	}
+	return merged
+}
+
+// request builds the GenerateTextRequest for model and messages.
+func (o GenerateObjectOptions) request(model LanguageModel, messages []Message) GenerateTextRequest {
+	req := NewGenerateTextRequest(model, messages, o.Settings)
+	req.JSONSchema = o.Schema
+	req.JSONSchemaName = o.SchemaName
+	req.JSONSchemaStrict = o.Strict
+	return req
+}
+
+// GenerateObjectWithRequest is like GenerateObject but takes a full
+// GenerateTextRequest, for settings such as Temperature or MaxTokens,
+// and decodes the output with opts. If req.JSONSchema is empty, the
+// schema is inferred from T.
+//
+// Set req.ResponseFormat to ResponseFormatJSON for backends that
+// support JSON mode but reject json_schema. The schema is then not
+// enforced by the provider; it is described in the system prompt
+// instead.
+//
+// By default, numbers decoded into any-typed fields of T (including
+// map[string]any and []any values) become float64, which corrupts
+// integers beyond 2^53 such as 64-bit IDs. With opts.UseNumber set,
+// such numbers are integers of type int64 when they fit and json.Number
+// otherwise, so re-encoding the object reproduces them exactly. Fields
+// with concrete numeric types are unaffected.
+//
+// Errors:
+//   - InvalidArgumentError if req.JSONSchemaName is not a valid schema
+//     name.
+//   - ErrNoObjectGenerated if the model produced an empty result.
+//   - ErrInvalidObjectJSON (wrapped) if the output cannot be decoded
+//     into T.
+//   - *UnknownFieldsError if opts.DisallowUnknownFields is set and the
+//     output contains keys unknown to T.
+//   - Any error returned by GenerateText.
+func GenerateObjectWithRequest[T any](ctx context.Context, req GenerateTextRequest, opts DecodeOptions) (T, error) {
+	return generateObject[T](ctx, req, opts, 0)
+}
+
+// generateObject is GenerateObjectWithRequest with up to repairs
+// repair calls after a decode failure.
+func generateObject[T any](ctx context.Context, req GenerateTextRequest, opts DecodeOptions, repairs int) (T, error) {
+	var zero T
+
+	req, err := objectRequest[T](req)
+	if err != nil {
+		return zero, err
+	}
+	res, err := GenerateText(ctx, req)
 	if err != nil {
 		return zero, err
 	}
+	out, decodeErr := decodeObject[T](res.Text, opts)
+	if decodeErr == nil || repairs == 0 || !repairable(decodeErr) {
+		return out, decodeErr
+	}
+
+	first, text := decodeErr, res.Text
+	req.Messages = slices.Clone(req.Messages)
+	for attempt := 1; attempt <= repairs; attempt++ {
+		req.Messages = append(req.Messages, AssistantMessage(text), UserMessage(repairPrompt(decodeErr)))
+		res, err := GenerateText(ctx, req)
+		if err != nil {
+			return zero, err
+		}
+		text = res.Text
+		out, decodeErr = decodeObject[T](text, opts)
+		if decodeErr == nil {
+			return out, nil
+		}
+		if !repairable(decodeErr) || attempt == repairs {
+			return zero, &ObjectRepairError{Attempts: attempt, First: first, Last: decodeErr, Text: text}
+		}
+	}
+	return zero, decodeErr
+}
+
+// repairable reports whether err is a decode failure a repair call can
+// fix.
+func repairable(err error) bool {
+	var unknown *UnknownFieldsError
+	return errors.Is(err, ErrInvalidObjectJSON) || errors.As(err, &unknown)
+}
+
+// repairPrompt asks the model to correct output that failed to decode
+// with err.
+func repairPrompt(err error) string {
+	return "Your previous response could not be parsed: " + err.Error() +
+		"\nRespond again with only the corrected JSON, without prose or code fences."
+}
 
-	text := strings.TrimSpace(res.Text)
-	if text == "" {
+// objectRequest prepares req for structured output of type T: it infers
+// the schema when req.JSONSchema is empty, wraps an array schema for a
+// list target in the list envelope and, in JSON mode, describes the
+// schema in the system prompt. Caller schemas of any other type are
+// sent unchanged.
+func objectRequest[T any](req GenerateTextRequest) (GenerateTextRequest, error) {
+	if !validSchemaName(req.JSONSchemaName) {
+		return req, &InvalidArgumentError{Parameter: "JSONSchemaName", Value: req.JSONSchemaName, Message: "must be at most 64 letters, digits, underscores, or dashes"}
+	}
+	if len(req.JSONSchema) == 0 {
+		var zero T
+		schema, err := JSONSchemaFromType(zero)
+		if err != nil {
+			return req, fmt.Errorf("ai: building JSON schema for object: %w", err)
+		}
+		req.JSONSchema = schema
+	}
+	wrap := isListTarget[T]() && isArraySchema(req.JSONSchema)
+	if wrap {
+		schema, err := listEnvelopeSchema(req.JSONSchema)
+		if err != nil {
+			return req, err
+		}
+		req.JSONSchema = schema
+	}
+	if req.ResponseFormat == ResponseFormatJSON {
+		// The instruction also satisfies OpenAI's requirement that JSON
+		// mode prompts mention JSON.
+		instruction := "Respond only with a JSON object that matches this JSON schema:\n" + string(req.JSONSchema)
+		if wrap {
+			instruction += "\nPut the list in the \"" + listEnvelopeKey + "\" array."
+		}
+		if req.System != "" {
+			instruction = req.System + "\n\n" + instruction
+		}
+		req.System = instruction
+	}
+	return req, nil
+}
+
+// listEnvelopeKey is the property of the envelope object that holds
+// the array when T is a slice or array type.
+const listEnvelopeKey = "items"
+
+// isListTarget reports whether T decodes from a JSON array. Structured
+// outputs (OpenAI json_schema, Anthropic tool input) require an object
+// at the top level, so such targets are requested wrapped in an
+// envelope object {"items": [...]} and unwrapped before decoding.
+// []byte is not a list: it decodes from a base64 string.
+func isListTarget[T any]() bool {
+	t := reflect.TypeFor[T]()
+	switch t.Kind() {
+	case reflect.Slice:
+		return t.Elem().Kind() != reflect.Uint8
+	case reflect.Array:
+		return true
+	}
+	return false
+}
+
+// isScalarTarget reports whether T decodes from a JSON string, number,
+// or boolean, which ExtractJSON does not look for. []byte decodes from
+// a base64 string.
+func isScalarTarget[T any]() bool {
+	t := reflect.TypeFor[T]()
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.Uint8
+	}
+	return false
+}
+
+// scalarText returns model output holding a single value without
+// surrounding whitespace or, if it has one, the code fence around it.
+func scalarText(text string) string {
+	if blocks := fencedBlocks(text); len(blocks) > 0 {
+		text = blocks[0]
+	}
+	return strings.TrimSpace(text)
+}
+
+// isArraySchema reports whether schema has the top-level type "array".
+func isArraySchema(schema []byte) bool {
+	var s struct {
+		Type any `json:"type"`
+	}
+	return json.Unmarshal(schema, &s) == nil && s.Type == "array"
+}
+
+// listEnvelopeSchema wraps the array schema in an envelope object.
+func listEnvelopeSchema(schema []byte) ([]byte, error) {
+	var items map[string]any
+	if err := json.Unmarshal(schema, &items); err != nil {
+		return nil, fmt.Errorf("ai: JSON schema for list: %w", err)
+	}
+	if _, ok := items["description"]; !ok {
+		items["description"] = "All items of the response, in order."
+	}
+	return json.Marshal(map[string]any{
+		"type":                 "object",
+		"properties":           map[string]any{listEnvelopeKey: items},
+		"required":             []string{listEnvelopeKey},
+		"additionalProperties": false,
+	})
+}
+
+// unwrapList returns the array held by a list envelope. Anything else,
+// such as a bare array from a model that ignored the envelope, is
+// returned unchanged.
+func unwrapList(raw []byte) []byte {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || raw[0] != '{' {
+		return raw
+	}
+	var envelope map[string]json.RawMessage
+	if json.Unmarshal(raw, &envelope) != nil {
+		return raw
+	}
+	if items, ok := envelope[listEnvelopeKey]; ok && len(envelope) == 1 {
+		return items
+	}
+	return raw
+}
+
+// validSchemaName reports whether name is empty or matches the
+// pattern OpenAI requires, ^[a-zA-Z0-9_-]{1,64}$.
+func validSchemaName(name string) bool {
+	if len(name) > 64 {
+		return false
+	}
+	for _, c := range name {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '_', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// decodeObject decodes the model output text into a T, with the errors
+// documented on GenerateObjectWithRequest.
+func decodeObject[T any](text string, opts DecodeOptions) (T, error) {
+	var zero T
+	if strings.TrimSpace(text) == "" {
 		return zero, ErrNoObjectGenerated
 	}
+	var raw []byte
+	if isScalarTarget[T]() {
+		raw = []byte(scalarText(text))
+	} else if extracted, err := ExtractJSON(text); err == nil {
+		raw = extracted
+	} else {
+		// No object or array: the output may be a bare value for an
+		// interface target, or invalid JSON to report as such.
+		raw = []byte(scalarText(text))
+	}
+	if isListTarget[T]() {
+		raw = unwrapList(raw)
+	}
 
 	var out T
-	if err := json.Unmarshal([]byte(text), &out); err != nil {
+	if err := DecodeJSON(raw, &out, opts); err != nil {
+		var unknown *UnknownFieldsError
+		if errors.As(err, &unknown) {
+			return zero, err
+		}
 		// Wrap JSON errors in a typed error for callers that want to
 		// distinguish parsing failures from model failures.
 		return zero, fmt.Errorf("%w: %v", ErrInvalidObjectJSON, err)
 	}
+	if opts.UseNumber {
+		narrowNumbers(reflect.ValueOf(&out).Elem())
+	}
 
 	return out, nil
 }
 
+var (
+	jsonNumberType = reflect.TypeFor[json.Number]()
+	int64Type      = reflect.TypeFor[int64]()
+)
+
+// narrowNumbers replaces json.Number values held in interface-typed
+// locations reachable from v with int64 when they are integers that
+// fit. Other numbers keep their json.Number form.
+func narrowNumbers(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			narrowNumbers(v.Elem())
+		}
+	case reflect.Struct:
+		for i := range v.NumField() {
+			if v.Type().Field(i).IsExported() {
+				narrowNumbers(v.Field(i))
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			narrowNumbers(v.Index(i))
+		}
+	case reflect.Map:
+		// Map values are not addressable; narrow a copy and store it back.
+		iter := v.MapRange()
+		for iter.Next() {
+			val := reflect.New(v.Type().Elem()).Elem()
+			val.Set(iter.Value())
+			narrowNumbers(val)
+			v.SetMapIndex(iter.Key(), val)
+		}
+	case reflect.Interface:
+		if v.IsNil() || !v.CanSet() {
+			return
+		}
+		elem := v.Elem()
+		if elem.Type() == jsonNumberType {
+			if n, err := elem.Interface().(json.Number).Int64(); err == nil && int64Type.AssignableTo(v.Type()) {
+				v.Set(reflect.ValueOf(n))
+			}
+			return
+		}
+		val := reflect.New(elem.Type()).Elem()
+		val.Set(elem)
+		narrowNumbers(val)
+		v.Set(val)
+	}
+}
+
 // DecodeToolCallArgs decodes the JSON arguments of a ToolCall into v.
 // It is a small convenience helper around json.Unmarshal.
+//
+// Numbers decoded into interface values become float64, which loses
+// precision for large integer IDs; use DecodeToolCallArgsWithOptions
+// with UseNumber for such tools.
 func DecodeToolCallArgs[T any](tc ToolCall, v *T) error {
+	return DecodeToolCallArgsWithOptions(tc, v, DecodeOptions{})
+}
+
+// DecodeOptions controls how JSON tool arguments are decoded.
+type DecodeOptions struct {
+	// UseNumber decodes numbers into interface values as json.Number
+	// instead of float64, preserving integers beyond 2^53 such as
+	// 19-digit IDs.
+	UseNumber bool
+	// DisallowUnknownFields rejects arguments containing keys that have
+	// no corresponding field in the target type, returning an
+	// *UnknownFieldsError listing all of them.
+	DisallowUnknownFields bool
+	// KeyMatching controls how keys are matched to field names. The
+	// zero value follows encoding/json.
+	KeyMatching KeyMatching
+}
+
+// KeyMatching selects how DecodeJSON matches JSON keys to the field
+// names of the target type.
+type KeyMatching string
+
+const (
+	// KeyMatchDefault matches keys as encoding/json does: exactly, then
+	// case-insensitively.
+	KeyMatchDefault KeyMatching = ""
+	// KeyMatchTolerant also accepts keys in another naming convention,
+	// for models that answer with snake_case keys for camelCase tags or
+	// vice versa. Keys are matched exactly, then case-insensitively,
+	// then by snake_case, camelCase, and kebab-case equivalence, in
+	// nested objects and arrays too. Two keys matching the same field
+	// are an error rather than merged.
+	KeyMatchTolerant KeyMatching = "tolerant"
+	// KeyMatchStrict requires every key to match a field name exactly
+	// and returns an *UnknownFieldsError listing the keys that do not.
+	KeyMatchStrict KeyMatching = "strict"
+)
+
+// DecodeToolCallArgsWithOptions is like DecodeToolCallArgs but applies
+// the given decode options.
+//
+// Errors:
+//   - *UnknownFieldsError if opts.DisallowUnknownFields is set and the
+//     arguments contain keys unknown to T.
+//   - A wrapped decoding error if the arguments are not valid for T.
+func DecodeToolCallArgsWithOptions[T any](tc ToolCall, v *T, opts DecodeOptions) error {
 	if len(tc.RawArguments) == 0 {
 		return errors.New("ai: tool call has no arguments")
 	}
-	if err := json.Unmarshal(tc.RawArguments, v); err != nil {
+	if err := DecodeJSON(tc.RawArguments, v, opts); err != nil {
+		var unknown *UnknownFieldsError
+		if errors.As(err, &unknown) {
+			unknown.Tool = tc.Name
+			return unknown
+		}
 		return fmt.Errorf("ai: decoding tool call arguments: %w", err)
 	}
 	return nil
 }
+
+// DecodeJSON decodes data into v according to opts. It is the decoding
+// path shared by DecodeToolCallArgsWithOptions and agent tools.
+//
+// Errors:
+//   - *UnknownFieldsError if opts.DisallowUnknownFields is set and data
+//     contains keys unknown to v's type, or if opts.KeyMatching is
+//     KeyMatchStrict and keys do not match field names exactly.
+//   - An error naming the keys if opts.KeyMatching is KeyMatchTolerant
+//     and two keys match the same field.
+//   - Any error returned by encoding/json.
+func DecodeJSON(data []byte, v any, opts DecodeOptions) error {
+	switch opts.KeyMatching {
+	case KeyMatchTolerant:
+		normalized, err := providerutil.NormalizeKeys(data, v)
+		if err != nil {
+			return err
+		}
+		data = normalized
+	case KeyMatchStrict:
+		if fields := providerutil.UnmatchedKeys(data, v); len(fields) > 0 {
+			return &UnknownFieldsError{Fields: fields}
+		}
+	}
+	if opts.DisallowUnknownFields {
+		if fields := providerutil.UnknownFields(data, v); len(fields) > 0 {
+			return &UnknownFieldsError{Fields: fields}
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if opts.UseNumber {
+		dec.UseNumber()
+	}
+	return dec.Decode(v)
+}
@@ -0,0 +1,517 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/provider"
+	"github.com/ncecere/ai-sdk/registry"
+)
+
+func TestDecodeToolCallArgsWithOptions_UseNumberPreservesLargeIDs(t *testing.T) {
+	tc := ToolCall{Name: "lookup", RawArguments: []byte(`{"id":1234567890123456789}`)}
+
+	var lossy map[string]any
+	if err := DecodeToolCallArgs(tc, &lossy); err != nil {
+		t.Fatalf("DecodeToolCallArgs error: %v", err)
+	}
+	if f, _ := lossy["id"].(float64); int64(f) == 1234567890123456789 {
+		t.Fatalf("expected default decoding to lose precision")
+	}
+
+	var exact map[string]any
+	if err := DecodeToolCallArgsWithOptions(tc, &exact, DecodeOptions{UseNumber: true}); err != nil {
+		t.Fatalf("DecodeToolCallArgsWithOptions error: %v", err)
+	}
+	if n, _ := exact["id"].(json.Number); n.String() != "1234567890123456789" {
+		t.Fatalf("expected exact json.Number, got %#v", exact["id"])
+	}
+}
+
+func TestDecodeToolCallArgsWithOptions_DisallowUnknownFields(t *testing.T) {
+	type args struct {
+		City   string `json:"city"`
+		Filter struct {
+			Units string `json:"units"`
+		} `json:"filter"`
+	}
+	tc := ToolCall{Name: "weather", RawArguments: []byte(`{"city":"Paris","country":"FR","filter":{"units":"c","extra":1}}`)}
+
+	var lenient args
+	if err := DecodeToolCallArgs(tc, &lenient); err != nil || lenient.City != "Paris" {
+		t.Fatalf("expected lenient decode to succeed, got %+v, err=%v", lenient, err)
+	}
+
+	var strict args
+	err := DecodeToolCallArgsWithOptions(tc, &strict, DecodeOptions{DisallowUnknownFields: true})
+	var unknown *UnknownFieldsError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("expected UnknownFieldsError, got %v", err)
+	}
+	if unknown.Tool != "weather" || len(unknown.Fields) != 2 || unknown.Fields[0] != "country" || unknown.Fields[1] != "filter.extra" {
+		t.Fatalf("unexpected unknown fields error: %+v", unknown)
+	}
+}
+
+func TestGenerateObjectWithRequest_UseNumberRoundTripsIDs(t *testing.T) {
+	type record struct {
+		ID     any            `json:"id"`
+		Count  int            `json:"count"`
+		Attrs  map[string]any `json:"attrs"`
+		Events []any          `json:"events"`
+		Owner  struct {
+			Ref any `json:"ref"`
+		} `json:"owner"`
+	}
+	output := `{"id":1234567890123456789,"count":3,"attrs":{"nested":{"ids":[9223372036854775807,-9223372036854775808]},"parent":9007199254740993,"ratio":0.25},"events":[{"at":1700000000123456789},18446744073709551615,1e3],"owner":{"ref":4611686018427387905}}`
+	req := func(model LanguageModel) GenerateTextRequest {
+		return GenerateTextRequest{Model: model, Messages: []Message{UserMessage("load")}}
+	}
+
+	lossyModel := &scriptedModel{responses: []*provider.LanguageModelResponse{{Text: output}}}
+	lossy, err := GenerateObjectWithRequest[record](context.Background(), req(lossyModel), DecodeOptions{})
+	if err != nil {
+		t.Fatalf("GenerateObjectWithRequest error: %v", err)
+	}
+	if b, _ := json.Marshal(lossy); string(b) == output {
+		t.Fatal("expected float64 decoding to corrupt the IDs")
+	}
+
+	model := &scriptedModel{responses: []*provider.LanguageModelResponse{{Text: "```json\n" + output + "\n```"}}}
+	exact, err := GenerateObjectWithRequest[record](context.Background(), req(model), DecodeOptions{UseNumber: true})
+	if err != nil {
+		t.Fatalf("GenerateObjectWithRequest error: %v", err)
+	}
+	if len(model.requests[0].JSONSchema) == 0 {
+		t.Fatal("expected the schema to be inferred from T")
+	}
+	if b, err := json.Marshal(exact); err != nil || string(b) != output {
+		t.Fatalf("round trip changed the object:\n got %s\nwant %s", b, output)
+	}
+
+	if id, ok := exact.ID.(int64); !ok || id != 1234567890123456789 {
+		t.Fatalf("expected int64 ID, got %#v", exact.ID)
+	}
+	nested := exact.Attrs["nested"].(map[string]any)["ids"].([]any)
+	if nested[0] != int64(math.MaxInt64) || nested[1] != int64(math.MinInt64) {
+		t.Fatalf("expected int64 bounds in nested any values, got %#v", nested)
+	}
+	if _, ok := exact.Events[1].(json.Number); !ok {
+		t.Fatalf("expected integers beyond int64 to stay json.Number, got %#v", exact.Events[1])
+	}
+	if _, ok := exact.Attrs["ratio"].(json.Number); !ok {
+		t.Fatalf("expected fractions to stay json.Number, got %#v", exact.Attrs["ratio"])
+	}
+}
+
+func TestGenerateObjectWithRequest_JSONMode(t *testing.T) {
+	type person struct {
+		Name string `json:"name"`
+	}
+	model := &scriptedModel{responses: []*provider.LanguageModelResponse{{Text: `{"name":"Ada"}`}}}
+	got, err := GenerateObjectWithRequest[person](context.Background(), GenerateTextRequest{
+		Model:          model,
+		System:         "Be accurate.",
+		Messages:       []Message{UserMessage("Who wrote the first program?")},
+		ResponseFormat: ResponseFormatJSON,
+	}, DecodeOptions{})
+	if err != nil {
+		t.Fatalf("GenerateObjectWithRequest error: %v", err)
+	}
+	if got.Name != "Ada" {
+		t.Fatalf("unexpected object %+v", got)
+	}
+
+	req := model.requests[0]
+	if req.ResponseFormat != ResponseFormatJSON {
+		t.Fatalf("expected JSON mode, got %q", req.ResponseFormat)
+	}
+	system := req.Messages[0]
+	if system.Role != RoleSystem || !strings.HasPrefix(system.Content, "Be accurate.\n\n") || !strings.Contains(system.Content, string(req.JSONSchema)) {
+		t.Fatalf("expected the schema in the system prompt, got %+v", system)
+	}
+}
+
+func TestGenerateObject_Options(t *testing.T) {
+	type person struct {
+		Name string `json:"name"`
+	}
+	schema := []byte(`{"type":"object","properties":{"name":{"type":"string","enum":["Ada","Grace"]}},"required":["name"]}`)
+	temperature := 0.0
+	maxTokens := 50
+	model := &scriptedModel{responses: []*provider.LanguageModelResponse{{Text: `{"name":"Ada"}`}, {Text: `{"name":"Grace"}`}}}
+
+	got, err := GenerateObject[person](context.Background(), model, []Message{UserMessage("Who?")}, GenerateObjectOptions{
+		Schema:     schema,
+		SchemaName: "person",
+		Settings:   &CallSettings{Temperature: &temperature, MaxTokens: &maxTokens},
+	})
+	if err != nil || got.Name != "Ada" {
+		t.Fatalf("GenerateObject = %+v, %v", got, err)
+	}
+	req := model.requests[0]
+	if string(req.JSONSchema) != string(schema) || req.JSONSchemaName != "person" {
+		t.Fatalf("expected the custom schema and name, got %q %s", req.JSONSchemaName, req.JSONSchema)
+	}
+	if req.Temperature == nil || *req.Temperature != 0 || req.MaxTokens == nil || *req.MaxTokens != 50 {
+		t.Fatalf("settings not applied: %+v", req)
+	}
+
+	// The two-argument form still infers the schema.
+	if _, err := GenerateObject[person](context.Background(), model, []Message{UserMessage("Who?")}); err != nil {
+		t.Fatalf("GenerateObject error: %v", err)
+	}
+	if req := model.requests[1]; !strings.Contains(string(req.JSONSchema), `"name"`) || req.JSONSchemaName != "" || req.Temperature != nil {
+		t.Fatalf("unexpected inferred request %+v", req)
+	}
+
+	_, err = GenerateObject[person](context.Background(), model, nil, GenerateObjectOptions{SchemaName: "a person"})
+	var argErr *InvalidArgumentError
+	if !errors.As(err, &argErr) || argErr.Parameter != "JSONSchemaName" {
+		t.Fatalf("expected InvalidArgumentError for the schema name, got %v", err)
+	}
+	if len(model.requests) != 2 {
+		t.Fatalf("invalid name reached the model")
+	}
+}
+
+type keyedAddress struct {
+	StreetName string `json:"streetName"`
+	ZipCode    string `json:"zip_code"`
+}
+
+type keyedProfile struct {
+	UserName  string                  `json:"userName"`
+	HomeAddr  *keyedAddress           `json:"homeAddress"`
+	Past      []keyedAddress          `json:"pastAddresses"`
+	ByLabel   map[string]keyedAddress `json:"byLabel"`
+	MaxItems  int                     `json:"max-items"`
+	CreatedAt int64
+}
+
+// mixedKeys mixes snake_case, camelCase, kebab-case, and PascalCase
+// keys at every level. Map keys ("Work_Place") are data and must stay.
+const mixedKeys = `{
+	"user_name": "ada",
+	"HomeAddress": {"street_name": "Main", "zipCode": "123"},
+	"past-addresses": [{"STREETNAME": "Elm", "zip-code": "456"}],
+	"by_label": {"Work_Place": {"Street-Name": "Oak", "ZipCode": "789"}},
+	"maxItems": 3,
+	"created_at": 9007199254740993,
+	"extra_key": true
+}`
+
+func TestDecodeJSON_KeyMatching(t *testing.T) {
+	var loose keyedProfile
+	if err := DecodeJSON([]byte(mixedKeys), &loose, DecodeOptions{}); err != nil {
+		t.Fatalf("DecodeJSON error: %v", err)
+	}
+	if loose.UserName != "" || loose.MaxItems != 0 {
+		t.Fatalf("default matching unexpectedly matched convention variants: %+v", loose)
+	}
+
+	var got keyedProfile
+	if err := DecodeJSON([]byte(mixedKeys), &got, DecodeOptions{KeyMatching: KeyMatchTolerant}); err != nil {
+		t.Fatalf("tolerant DecodeJSON error: %v", err)
+	}
+	want := keyedProfile{
+		UserName:  "ada",
+		HomeAddr:  &keyedAddress{StreetName: "Main", ZipCode: "123"},
+		Past:      []keyedAddress{{StreetName: "Elm", ZipCode: "456"}},
+		ByLabel:   map[string]keyedAddress{"Work_Place": {StreetName: "Oak", ZipCode: "789"}},
+		MaxItems:  3,
+		CreatedAt: 9007199254740993,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("tolerant decode = %+v, want %+v", got, want)
+	}
+
+	// Unknown keys still fail DisallowUnknownFields after normalization.
+	err := DecodeJSON([]byte(mixedKeys), &got, DecodeOptions{KeyMatching: KeyMatchTolerant, DisallowUnknownFields: true})
+	var unknown *UnknownFieldsError
+	if !errors.As(err, &unknown) || !reflect.DeepEqual(unknown.Fields, []string{"extra_key"}) {
+		t.Fatalf("expected extra_key to be unknown, got %v", err)
+	}
+}
+
+func TestDecodeJSON_KeyMatchingNeverMerges(t *testing.T) {
+	for _, data := range []string{
+		`{"userName": "a", "user_name": "b"}`,
+		`{"past_addresses": [{"zip_code": "1", "zipCode": "2"}]}`,
+	} {
+		var got keyedProfile
+		err := DecodeJSON([]byte(data), &got, DecodeOptions{KeyMatching: KeyMatchTolerant})
+		if err == nil || !strings.Contains(err.Error(), "both match field") {
+			t.Fatalf("DecodeJSON(%s) = %+v, %v; want a conflict error", data, got, err)
+		}
+	}
+}
+
+func TestDecodeJSON_KeyMatchStrict(t *testing.T) {
+	var got keyedProfile
+	err := DecodeJSON([]byte(mixedKeys), &got, DecodeOptions{KeyMatching: KeyMatchStrict})
+	var unknown *UnknownFieldsError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("expected UnknownFieldsError, got %v", err)
+	}
+	want := []string{"HomeAddress", "by_label", "created_at", "extra_key", "maxItems", "past-addresses", "user_name"}
+	if !reflect.DeepEqual(unknown.Fields, want) {
+		t.Fatalf("unmatched keys = %v, want %v", unknown.Fields, want)
+	}
+	if err := DecodeJSON([]byte(`{"userName":"ada","homeAddress":{"zip_code":"1"}}`), &got, DecodeOptions{KeyMatching: KeyMatchStrict}); err != nil {
+		t.Fatalf("exact keys rejected: %v", err)
+	}
+}
+
+func TestGenerateObject_TolerantKeys(t *testing.T) {
+	model := &scriptedModel{responses: []*provider.LanguageModelResponse{{Text: `{"user_name":"ada","max_items":2}`}}}
+	got, err := GenerateObject[keyedProfile](context.Background(), model, nil, GenerateObjectOptions{
+		Decode: DecodeOptions{KeyMatching: KeyMatchTolerant},
+	})
+	if err != nil || got.UserName != "ada" || got.MaxItems != 2 {
+		t.Fatalf("GenerateObject = %+v, %v", got, err)
+	}
+}
+
+func TestGenerateObjectWithRegistry(t *testing.T) {
+	type person struct {
+		Name string `json:"name"`
+	}
+	model := &scriptedModel{responses: []*provider.LanguageModelResponse{{Text: `{"name":"Ada"}`}}}
+	reg := registry.NewInMemoryRegistry()
+	reg.RegisterLanguageModel("chat", model)
+	ctx := context.Background()
+
+	temperature := 0.1
+	got, err := GenerateObjectWithRegistry[person](ctx, reg, "chat", []Message{UserMessage("Who?")}, GenerateObjectOptions{
+		SchemaName: "person",
+		Settings:   &CallSettings{Temperature: &temperature},
+	})
+	if err != nil || got.Name != "Ada" {
+		t.Fatalf("GenerateObjectWithRegistry = %+v, %v", got, err)
+	}
+	if req := model.requests[0]; req.JSONSchemaName != "person" || req.Temperature == nil || *req.Temperature != 0.1 {
+		t.Fatalf("options not applied: %+v", req)
+	}
+
+	_, wantErr := reg.LanguageModel("missing")
+	_, err = GenerateObjectWithRegistry[person](ctx, reg, "missing", nil)
+	var noModel *registry.NoSuchModelError
+	if !errors.As(err, &noModel) || err.Error() != wantErr.Error() {
+		t.Fatalf("expected the registry's *NoSuchModelError unchanged, got %v", err)
+	}
+	var invalid *InvalidArgumentError
+	if _, err := GenerateObjectWithRegistry[person](ctx, nil, "chat", nil); !errors.As(err, &invalid) || invalid.Parameter != "reg" {
+		t.Fatalf("expected *InvalidArgumentError for a nil registry, got %v", err)
+	}
+}
+
+func TestGenerateObject_Repair(t *testing.T) {
+	type person struct {
+		Name string `json:"name"`
+	}
+	ctx := context.Background()
+	messages := []Message{UserMessage("Who?")}
+
+	model := &scriptedModel{responses: []*provider.LanguageModelResponse{
+		{Text: `{"name": "Ada",}`},
+		{Text: `{"name":"Ada"}`},
+	}}
+	got, err := GenerateObject[person](ctx, model, messages, GenerateObjectOptions{Repair: true})
+	if err != nil || got.Name != "Ada" {
+		t.Fatalf("GenerateObject = %+v, %v", got, err)
+	}
+	if len(model.requests) != 2 {
+		t.Fatalf("expected one repair call, got %d calls", len(model.requests))
+	}
+	repair := model.requests[1].Messages
+	if len(repair) != 3 || repair[1].Role != RoleAssistant || repair[1].Content != `{"name": "Ada",}` ||
+		repair[2].Role != RoleUser || !strings.Contains(repair[2].Content, "invalid character") {
+		t.Fatalf("unexpected repair messages: %+v", repair)
+	}
+	if len(messages) != 1 {
+		t.Fatalf("caller's messages modified: %+v", messages)
+	}
+
+	// Without Repair, the first decode error is returned.
+	model = &scriptedModel{responses: []*provider.LanguageModelResponse{{Text: `not json`}}}
+	if _, err := GenerateObject[person](ctx, model, messages); !errors.Is(err, ErrInvalidObjectJSON) || len(model.requests) != 1 {
+		t.Fatalf("expected ErrInvalidObjectJSON after one call, got %v (%d calls)", err, len(model.requests))
+	}
+
+	// Exhausted repairs wrap the original and the final error.
+	model = &scriptedModel{responses: []*provider.LanguageModelResponse{
+		{Text: `not json`},
+		{Text: `{"name":`},
+		{Text: `{"name": "Ada", "age": 36}`},
+	}}
+	_, err = GenerateObject[person](ctx, model, messages, GenerateObjectOptions{
+		Repair:     true,
+		MaxRepairs: 2,
+		Decode:     DecodeOptions{DisallowUnknownFields: true},
+	})
+	var repairErr *ObjectRepairError
+	var unknown *UnknownFieldsError
+	if !errors.As(err, &repairErr) || repairErr.Attempts != 2 || !errors.Is(err, ErrInvalidObjectJSON) || !errors.As(err, &unknown) {
+		t.Fatalf("expected *ObjectRepairError wrapping both errors, got %v", err)
+	}
+	if !strings.Contains(repairErr.First.Error(), "invalid character 'o'") || repairErr.Text != `{"name": "Ada", "age": 36}` {
+		t.Fatalf("unexpected repair error: %+v", repairErr)
+	}
+	if n := len(model.requests[2].Messages); n != 5 {
+		t.Fatalf("expected the second repair to see 5 messages, got %d", n)
+	}
+}
+
+type weatherReport struct {
+	City    string `json:"city"`
+	Celsius int    `json:"celsius"`
+}
+
+func TestGenerateObject_Slice(t *testing.T) {
+	want := []weatherReport{{City: "Paris", Celsius: 18}, {City: "Oslo", Celsius: 7}}
+	for _, tc := range mixedCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var bodies []map[string]any
+			ts := fixtureServer(t, "objects/"+tc.name+"_list.json", &bodies)
+			defer ts.Close()
+			model, err := tc.newModel(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+			if err != nil {
+				t.Fatalf("NewClient error: %v", err)
+			}
+
+			got, err := GenerateObject[[]weatherReport](context.Background(), model, []Message{UserMessage("Weather in Paris and Oslo?")})
+			if err != nil || !reflect.DeepEqual(got, want) {
+				t.Fatalf("GenerateObject = %+v, %v", got, err)
+			}
+
+			// Both providers require an object at the top level, so the
+			// array schema is sent inside an envelope.
+			var schema any
+			if tc.name == "openai" {
+				schema = bodies[0]["response_format"].(map[string]any)["json_schema"].(map[string]any)["schema"]
+			} else {
+				schema = bodies[0]["tools"].([]any)[0].(map[string]any)["input_schema"]
+			}
+			envelope, _ := schema.(map[string]any)
+			items, _ := envelope["properties"].(map[string]any)["items"].(map[string]any)
+			if envelope["type"] != "object" || items["type"] != "array" || !reflect.DeepEqual(envelope["required"], []any{"items"}) {
+				t.Fatalf("expected an envelope schema, got %v", schema)
+			}
+		})
+	}
+}
+
+func TestGenerateObject_SliceUnwrap(t *testing.T) {
+	model := &scriptedModel{responses: []*provider.LanguageModelResponse{
+		{Text: "```json\n[{\"city\":\"Paris\",\"celsius\":18}]\n```"},
+		{Text: `{"items":[]}`},
+	}}
+	got, err := GenerateObject[[1]weatherReport](context.Background(), model, nil)
+	if err != nil || got[0].City != "Paris" {
+		t.Fatalf("bare array: GenerateObject = %+v, %v", got, err)
+	}
+	empty, err := GenerateObject[[]weatherReport](context.Background(), model, nil)
+	if err != nil || empty == nil || len(empty) != 0 {
+		t.Fatalf("empty envelope: GenerateObject = %#v, %v", empty, err)
+	}
+}
+
+func TestGenerateObject_SliceCallerSchema(t *testing.T) {
+	model := &scriptedModel{responses: []*provider.LanguageModelResponse{
+		{Text: `{"items":[{"city":"Paris","celsius":18}]}`},
+		{Text: `{"items":[{"city":"Oslo","celsius":7}]}`},
+	}}
+
+	// An object schema is the caller's own envelope and is sent as is.
+	object := `{"type":"object","properties":{"items":{"type":"array"}},"required":["items"]}`
+	got, err := GenerateObject[[]weatherReport](context.Background(), model, nil, GenerateObjectOptions{Schema: []byte(object)})
+	if err != nil || len(got) != 1 || got[0].City != "Paris" {
+		t.Fatalf("object schema: GenerateObject = %+v, %v", got, err)
+	}
+	if string(model.requests[0].JSONSchema) != object {
+		t.Fatalf("object schema was rewritten: %s", model.requests[0].JSONSchema)
+	}
+
+	// An array schema still needs the envelope.
+	got, err = GenerateObject[[]weatherReport](context.Background(), model, nil, GenerateObjectOptions{Schema: []byte(`{"type":"array"}`)})
+	if err != nil || len(got) != 1 || got[0].City != "Oslo" {
+		t.Fatalf("array schema: GenerateObject = %+v, %v", got, err)
+	}
+	var envelope map[string]any
+	if err := json.Unmarshal(model.requests[1].JSONSchema, &envelope); err != nil || envelope["type"] != "object" {
+		t.Fatalf("array schema was not wrapped: %s", model.requests[1].JSONSchema)
+	}
+}
+
+func TestGenerateObject_Scalars(t *testing.T) {
+	model := &scriptedModel{responses: []*provider.LanguageModelResponse{
+		{Text: "42"},
+		{Text: `"hi"`},
+		{Text: "```json\n\"[not a list]\"\n```"},
+		{Text: " true\n"},
+		{Text: "3.5"},
+	}}
+	if n, err := GenerateObject[int](context.Background(), model, nil); err != nil || n != 42 {
+		t.Fatalf("int: GenerateObject = %v, %v", n, err)
+	}
+	if s, err := GenerateObject[string](context.Background(), model, nil); err != nil || s != "hi" {
+		t.Fatalf("string: GenerateObject = %q, %v", s, err)
+	}
+	if s, err := GenerateObject[string](context.Background(), model, nil); err != nil || s != "[not a list]" {
+		t.Fatalf("fenced string: GenerateObject = %q, %v", s, err)
+	}
+	if b, err := GenerateObject[bool](context.Background(), model, nil); err != nil || !b {
+		t.Fatalf("bool: GenerateObject = %v, %v", b, err)
+	}
+	if f, err := GenerateObject[float64](context.Background(), model, nil); err != nil || f != 3.5 {
+		t.Fatalf("float64: GenerateObject = %v, %v", f, err)
+	}
+}
+
+func TestGenerateObject_Strict(t *testing.T) {
+	type birth struct {
+		Year  int     `json:"year"`
+		Place *string `json:"place"`
+	}
+	type person struct {
+		Name      string   `json:"name"`
+		Nickname  *string  `json:"nickname,omitempty"`
+		Born      birth    `json:"born"`
+		Languages []string `json:"languages"`
+	}
+	var bodies []map[string]any
+	ts := fixtureServer(t, "objects/openai_strict.json", &bodies)
+	defer ts.Close()
+	model, err := mixedCases[0].newModel(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	got, err := GenerateObject[person](context.Background(), model, []Message{UserMessage("Who was Ada Lovelace?")}, GenerateObjectOptions{
+		SchemaName: "person",
+		Strict:     true,
+	})
+	if err != nil || got.Name != "Ada" || got.Nickname != nil || got.Born.Year != 1815 || got.Born.Place != nil || got.Languages != nil {
+		t.Fatalf("GenerateObject = %+v, %v", got, err)
+	}
+
+	// Strict mode requires "strict": true, every property in "required",
+	// and "additionalProperties": false on every object. Optional fields
+	// are nullable instead.
+	format, _ := json.Marshal(bodies[0]["response_format"])
+	want := `{"json_schema":{"name":"person","schema":{"additionalProperties":false,"properties":{` +
+		`"born":{"additionalProperties":false,"properties":{"place":{"type":["string","null"]},"year":{"type":"integer"}},"required":["place","year"],"type":"object"},` +
+		`"languages":{"items":{"type":"string"},"type":["array","null"]},` +
+		`"name":{"type":"string"},` +
+		`"nickname":{"type":["string","null"]}},` +
+		`"required":["born","languages","name","nickname"],"type":"object"},"strict":true},"type":"json_schema"}`
+	if string(format) != want {
+		t.Fatalf("response_format =\n%s\nwant\n%s", format, want)
+	}
+}
@@ -7,8 +7,13 @@ import (
 
 	"github.com/ncecere/ai-sdk/openai"
 	"github.com/ncecere/ai-sdk/provider"
+	"github.com/ncecere/ai-sdk/providerutil"
 )
 
+// DefaultUnsupportedOptions names the LanguageModelRequest fields that
+// the Groq API does not accept.
+var DefaultUnsupportedOptions = []string{"LogitBias", "Logprobs", "TopLogprobs"}
+
 // NewClient creates a new Groq client by configuring the existing OpenAI
 // client with Groq-specific defaults.
 //
@@ -19,6 +24,12 @@ import (
 // Environment variables:
 //   - GROQ_API_KEY  (used if opts.APIKey is empty)
 //   - GROQ_BASE_URL (optional, defaults to https://api.groq.com/openai/v1)
+//   - GROQ_EXTRA_HEADERS (read with AI_SDK_DEFAULT_HEADERS if
+//     opts.HeadersFromEnv is set)
+//
+// Groq rejects logit_bias, logprobs, and top_logprobs, so a nil
+// opts.UnsupportedOptions defaults to DefaultUnsupportedOptions and
+// those fields are reported as dropped instead of failing the request.
 func NewClient(opts provider.ClientOptions) (*openai.Client, error) {
 	if opts.APIKey == "" {
 		opts.APIKey = os.Getenv("GROQ_API_KEY")
@@ -37,5 +48,36 @@ func NewClient(opts provider.ClientOptions) (*openai.Client, error) {
 		opts.BaseURL = strings.TrimRight(baseURL, "/")
 	}
 
+	// Read GROQ_EXTRA_HEADERS here so the OpenAI client does not read
+	// OPENAI_EXTRA_HEADERS.
+	headers, err := providerutil.ClientHeaders(opts, "GROQ_EXTRA_HEADERS")
+	if err != nil {
+		return nil, fmt.Errorf("groq: %w", err)
+	}
+	opts.Headers, opts.HeadersFromEnv = headers, false
+	if opts.UnsupportedOptions == nil {
+		opts.UnsupportedOptions = DefaultUnsupportedOptions
+	}
+
 	return openai.NewClient(opts)
 }
+
+// Factory is a provider.Factory that creates Groq clients. Register it
+// with registry.RegisterProviderFactory to resolve "groq:<model>"
+// references.
+type Factory struct{}
+
+// Ensure Factory implements provider.Factory.
+var _ provider.Factory = Factory{}
+
+// Name implements provider.Factory.
+func (Factory) Name() string { return "groq" }
+
+// NewClient implements provider.Factory.
+func (Factory) NewClient(opts provider.ClientOptions) (provider.Provider, error) {
+	c, err := NewClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,54 @@
+package groq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+func TestNewClient_DropsUnsupportedOptions(t *testing.T) {
+	var body map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"finish_reason":"stop","message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	defer ts.Close()
+
+	var dropped []string
+	client, err := NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client(),
+		OnDroppedOptions: func(source string, options []provider.DroppedOption) {
+			for _, d := range options {
+				dropped = append(dropped, d.Field)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	logprobs, top := true, 3
+	_, err = client.ChatModel("llama-test").Generate(context.Background(), &provider.LanguageModelRequest{
+		Messages:    []provider.Message{{Role: "user", Content: "hi"}},
+		LogitBias:   map[string]float64{"50256": -100},
+		Logprobs:    &logprobs,
+		TopLogprobs: &top,
+	})
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	for _, key := range []string{"logit_bias", "logprobs", "top_logprobs"} {
+		if _, ok := body[key]; ok {
+			t.Errorf("request body sets %s: %v", key, body)
+		}
+	}
+	if want := []string{"LogitBias", "Logprobs", "TopLogprobs"}; !reflect.DeepEqual(dropped, want) {
+		t.Fatalf("dropped = %v, want %v", dropped, want)
+	}
+}
@@ -0,0 +1,17 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// WithHeaders returns a context carrying extra HTTP headers for every
+// provider request made with it: chat, embeddings, images, speech, and
+// transcription. They are added to ClientOptions.Headers, replacing
+// values of the same name, but never replace headers the provider
+// requires, such as Authorization or anthropic-version.
+func WithHeaders(ctx context.Context, h http.Header) context.Context {
+	return provider.WithHeaders(ctx, h)
+}
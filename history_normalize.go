@@ -0,0 +1,152 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// maxToolCallIDLength is the longest tool call ID OpenAI accepts.
+const maxToolCallIDLength = 40
+
+// NormalizeHistoryFor prepares a history recorded with one provider to
+// be sent to another, for example when a conversation switches from an
+// OpenAI model to a Claude model mid-session. providerName is the
+// registry name of the target provider: "anthropic" selects Anthropic's
+// rules, and any other name, such as "openai" or "groq", the rules of
+// OpenAI-compatible backends. messages is not modified.
+//
+// Both APIs reject tool calls that are not answered right away, so
+// NormalizeHistoryFor:
+//   - Moves the tool messages answering an assistant message's tool
+//     calls directly after it, ahead of any other message of that turn.
+//   - Drops tool calls that are never answered, and the assistant
+//     message if nothing else is left, unless it is the last message
+//     and its calls are still pending.
+//   - Replaces tool call IDs that are empty, repeated, too long, or not
+//     made of letters, digits, '_' and '-', as some compatible backends
+//     return them, and updates the tool messages answering them. New
+//     IDs are derived from the message position, so normalizing a
+//     growing history yields the same IDs every time.
+//
+// For Anthropic it also sends tool call arguments as JSON objects,
+// replacing arguments that are not a JSON object with {}, and leaves
+// out user and assistant messages without any content, which Anthropic
+// rejects.
+//
+// Tool messages that answer no tool call are kept as they are, for the
+// provider's OrphanToolMessages policy to handle.
+func NormalizeHistoryFor(providerName string, messages []Message) []Message {
+	anthropic := providerName == "anthropic"
+	prefix := "call_"
+	if anthropic {
+		prefix = "toolu_"
+	}
+
+	used := map[string]bool{}
+	moved := make([]bool, len(messages))
+	out := make([]Message, 0, len(messages))
+	for i, msg := range messages {
+		if moved[i] {
+			continue
+		}
+		if msg.Role != RoleAssistant || len(msg.ToolCalls) == 0 {
+			if !anthropic || !emptyMessage(msg) {
+				out = append(out, msg)
+			}
+			continue
+		}
+
+		// The turn's tool messages come before the next assistant
+		// message. Each call is answered by the first tool message
+		// with its ID.
+		end := i + 1
+		for end < len(messages) && messages[end].Role != RoleAssistant {
+			end++
+		}
+		answers := make([]int, len(msg.ToolCalls))
+		for j := range answers {
+			answers[j] = -1
+		}
+		for k := i + 1; k < end; k++ {
+			if messages[k].Role != RoleTool {
+				continue
+			}
+			for j, call := range msg.ToolCalls {
+				if answers[j] < 0 && messages[k].ToolCallID == call.ID {
+					answers[j] = k
+					break
+				}
+			}
+		}
+
+		pending := i == len(messages)-1
+		var calls []ToolCall
+		var results []Message
+		for j, call := range msg.ToolCalls {
+			if answers[j] < 0 && !pending {
+				continue
+			}
+			if !validToolCallID(call.ID) || used[call.ID] {
+				call.ID = fmt.Sprintf("%s%d_%d", prefix, i, j)
+				for used[call.ID] {
+					call.ID += "_"
+				}
+			}
+			used[call.ID] = true
+			if anthropic {
+				call.RawArguments = objectArguments(call.RawArguments)
+			}
+			calls = append(calls, call)
+			if k := answers[j]; k >= 0 {
+				result := messages[k]
+				result.ToolCallID = call.ID
+				results = append(results, result)
+				moved[k] = true
+			}
+		}
+		msg.ToolCalls = calls
+		if len(calls) == 0 && emptyMessage(msg) {
+			continue
+		}
+		out = append(out, msg)
+		out = append(out, results...)
+	}
+	return out
+}
+
+// validToolCallID reports whether both OpenAI and Anthropic accept id.
+func validToolCallID(id string) bool {
+	if id == "" || len(id) > maxToolCallIDLength {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// objectArguments returns args as a JSON object. Arguments held as a
+// JSON string (as the OpenAI client returns them) are unwrapped, and
+// anything that is not an object becomes {}.
+func objectArguments(args []byte) []byte {
+	var s string
+	if json.Unmarshal(args, &s) == nil {
+		args = []byte(s)
+	}
+	var obj map[string]json.RawMessage
+	if trimmed := bytes.TrimSpace(args); len(trimmed) == 0 || trimmed[0] != '{' || json.Unmarshal(trimmed, &obj) != nil {
+		return []byte("{}")
+	}
+	return args
+}
+
+// emptyMessage reports whether a user or assistant message has no text,
+// parts, or tool calls.
+func emptyMessage(msg Message) bool {
+	return (msg.Role == RoleUser || msg.Role == RoleAssistant) &&
+		strings.TrimSpace(msg.Content) == "" && len(msg.Parts) == 0 && len(msg.ToolCalls) == 0
+}
@@ -0,0 +1,189 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/anthropic"
+	"github.com/ncecere/ai-sdk/openai"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// compatHistory is an OpenAI-shaped history as some compatible
+// backends record it: IDs Anthropic rejects, arguments as JSON strings
+// (one cut off), a user message between the calls and their results,
+// and a tool call that was never answered.
+func compatHistory() []Message {
+	return []Message{
+		SystemMessage("Be brief."),
+		UserMessage("Weather in Paris and Rome?"),
+		{Role: RoleAssistant, ToolCalls: []ToolCall{
+			{ID: "functions.weather:0", Name: "weather", RawArguments: []byte(`"{\"city\":\"Paris\"}"`)},
+			{ID: "functions.weather:1", Name: "weather", RawArguments: []byte(`"{\"city\":\"Ro"`)},
+		}},
+		UserMessage("Quickly, please."),
+		{Role: RoleTool, ToolCallID: "functions.weather:0", Content: "sunny"},
+		{Role: RoleTool, ToolCallID: "functions.weather:1", Content: "rain"},
+		AssistantMessage("Sunny in Paris, rain in Rome."),
+		{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "call_9", Name: "time", RawArguments: []byte(`"{}"`)}}},
+		UserMessage(""),
+		UserMessage("Thanks!"),
+	}
+}
+
+func TestNormalizeHistoryFor_OpenAIToAnthropic(t *testing.T) {
+	history := compatHistory()
+	got := NormalizeHistoryFor("anthropic", history)
+	if !reflect.DeepEqual(history, compatHistory()) {
+		t.Fatal("NormalizeHistoryFor modified its input")
+	}
+	want := []Message{
+		SystemMessage("Be brief."),
+		UserMessage("Weather in Paris and Rome?"),
+		{Role: RoleAssistant, ToolCalls: []ToolCall{
+			{ID: "toolu_2_0", Name: "weather", RawArguments: []byte(`{"city":"Paris"}`)},
+			{ID: "toolu_2_1", Name: "weather", RawArguments: []byte(`{}`)},
+		}},
+		{Role: RoleTool, ToolCallID: "toolu_2_0", Content: "sunny"},
+		{Role: RoleTool, ToolCallID: "toolu_2_1", Content: "rain"},
+		UserMessage("Quickly, please."),
+		AssistantMessage("Sunny in Paris, rain in Rome."),
+		UserMessage("Thanks!"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("NormalizeHistoryFor =\n%+v\nwant\n%+v", got, want)
+	}
+	if again := NormalizeHistoryFor("anthropic", got); !reflect.DeepEqual(again, got) {
+		t.Fatalf("normalizing twice changed the history:\n%+v", again)
+	}
+
+	// The Anthropic request pairs every tool_use with a tool_result in
+	// the next message.
+	client, err := anthropic.NewClient(provider.ClientOptions{APIKey: "test"})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	res, err := DryRunText(context.Background(), client.ChatModel("claude-test"), GenerateTextRequest{Messages: got})
+	if err != nil {
+		t.Fatalf("DryRunText error: %v", err)
+	}
+	var body struct {
+		Messages []struct {
+			Role    string
+			Content []struct {
+				Type      string
+				ID        string
+				ToolUseID string `json:"tool_use_id"`
+				Input     json.RawMessage
+			}
+		}
+	}
+	if err := json.Unmarshal(res.Body, &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	var shape []string
+	for _, m := range body.Messages {
+		var blocks []string
+		for _, b := range m.Content {
+			blocks = append(blocks, b.Type+b.ID+b.ToolUseID+string(b.Input))
+		}
+		shape = append(shape, m.Role+"["+strings.Join(blocks, " ")+"]")
+	}
+	wantShape := []string{
+		"user[text]",
+		`assistant[tool_usetoolu_2_0{"city":"Paris"} tool_usetoolu_2_1{}]`,
+		"user[tool_resulttoolu_2_0 tool_resulttoolu_2_1]",
+		"user[text]",
+		"assistant[text]",
+		"user[text]",
+	}
+	if !reflect.DeepEqual(shape, wantShape) {
+		t.Fatalf("Anthropic messages =\n%s\nwant\n%s", strings.Join(shape, "\n"), strings.Join(wantShape, "\n"))
+	}
+
+	// Back to OpenAI: the Anthropic-ready history is already valid, and
+	// each call is followed by its tool messages.
+	back := NormalizeHistoryFor("openai", got)
+	if !reflect.DeepEqual(back, got) {
+		t.Fatalf("NormalizeHistoryFor(openai) =\n%+v\nwant\n%+v", back, got)
+	}
+	checkOpenAIToolPairs(t, back, []string{"toolu_2_0", "toolu_2_1"})
+}
+
+func TestNormalizeHistoryFor_AnthropicToOpenAI(t *testing.T) {
+	long := "toolu_" + strings.Repeat("x", 40)
+	history := []Message{
+		UserMessage("Weather?"),
+		{Role: RoleAssistant, Content: "Checking.", ToolCalls: []ToolCall{{ID: "toolu_01A", Name: "weather", RawArguments: []byte(`{"city":"Paris"}`)}}},
+		{Role: RoleTool, ToolCallID: "toolu_01A", Content: "sunny"},
+		// A replayed turn reuses the ID, and one is too long for OpenAI.
+		{Role: RoleAssistant, ToolCalls: []ToolCall{
+			{ID: "toolu_01A", Name: "weather", RawArguments: []byte(`{"city":"Rome"}`)},
+			{ID: long, Name: "time", RawArguments: []byte(`{}`)},
+		}},
+		{Role: RoleTool, ToolCallID: long, Content: "noon"},
+		{Role: RoleTool, ToolCallID: "toolu_01A", Content: "rain"},
+		// Calls still pending at the end of the history are kept.
+		{Role: RoleAssistant, ToolCalls: []ToolCall{{Name: "weather", RawArguments: []byte(`{"city":"Oslo"}`)}}},
+	}
+	got := NormalizeHistoryFor("openai", history)
+	var ids, answered []string
+	for _, m := range got {
+		for _, call := range m.ToolCalls {
+			ids = append(ids, call.ID)
+		}
+		if m.Role == RoleTool {
+			answered = append(answered, m.ToolCallID+"="+m.Content)
+		}
+	}
+	if want := []string{"toolu_01A", "call_3_0", "call_3_1", "call_6_0"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("tool call IDs = %v, want %v", ids, want)
+	}
+	if want := []string{"toolu_01A=sunny", "call_3_0=rain", "call_3_1=noon"}; !reflect.DeepEqual(answered, want) {
+		t.Fatalf("tool messages = %v, want %v", answered, want)
+	}
+	checkOpenAIToolPairs(t, got[:len(got)-1], []string{"toolu_01A", "call_3_0", "call_3_1"})
+}
+
+// checkOpenAIToolPairs renders messages as an OpenAI request and checks
+// that each assistant tool_calls message is followed by the tool
+// messages answering it.
+func checkOpenAIToolPairs(t *testing.T, messages []Message, wantIDs []string) {
+	t.Helper()
+	client, err := openai.NewClient(provider.ClientOptions{APIKey: "test"})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	res, err := DryRunText(context.Background(), client.ChatModel("gpt-test"), GenerateTextRequest{
+		Messages: messages,
+		Tools:    []ToolDefinition{{Name: "weather", Parameters: []byte(`{"type":"object"}`)}},
+	})
+	if err != nil {
+		t.Fatalf("DryRunText error: %v", err)
+	}
+	var body struct {
+		Messages []struct {
+			Role       string
+			ToolCallID string                `json:"tool_call_id"`
+			ToolCalls  []struct{ ID string } `json:"tool_calls"`
+		}
+	}
+	if err := json.Unmarshal(res.Body, &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	var ids []string
+	for i, m := range body.Messages {
+		for j, call := range m.ToolCalls {
+			ids = append(ids, call.ID)
+			if next := body.Messages[i+1+j]; next.Role != "tool" || next.ToolCallID != call.ID {
+				t.Fatalf("call %s is followed by %+v", call.ID, next)
+			}
+		}
+	}
+	if !reflect.DeepEqual(ids, wantIDs) {
+		t.Fatalf("OpenAI tool call IDs = %v, want %v", ids, wantIDs)
+	}
+}
@@ -0,0 +1,160 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// NDJSONLine is one line written by WriteTextStreamAsNDJSON.
+type NDJSONLine struct {
+	// Text and Reasoning are the delta's output.
+	Text      string `json:"text,omitempty"`
+	Reasoning string `json:"reasoning,omitempty"`
+	// ToolCallDeltas are tool call fragments as they arrive, for
+	// showing tool activity before the calls are complete.
+	ToolCallDeltas []NDJSONToolCall `json:"toolCallDeltas,omitempty"`
+	// Done is true on the last line only.
+	Done bool `json:"done"`
+	// FinishReason, ToolCalls, and Usage are set on the last line of a
+	// successful stream. FinishReason is "tool_calls" when the model
+	// called tools and "stop" otherwise.
+	FinishReason FinishReason     `json:"finishReason,omitempty"`
+	ToolCalls    []NDJSONToolCall `json:"toolCalls,omitempty"`
+	Usage        *Usage           `json:"usage,omitempty"`
+	// Error is set on the last line when the stream failed.
+	Error *StreamErrorEvent `json:"error,omitempty"`
+}
+
+// NDJSONToolCall is a tool call, or a fragment of one, in an
+// NDJSONLine.
+type NDJSONToolCall struct {
+	// Index is the position of the call in the response; it is only
+	// set on fragments.
+	Index *int   `json:"index,omitempty"`
+	ID    string `json:"id,omitempty"`
+	Name  string `json:"name,omitempty"`
+	// Arguments is the JSON arguments object of a complete call, or the
+	// raw text of a fragment.
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+// WriteTextStreamAsNDJSON writes a TextStream to an http.ResponseWriter
+// as newline-delimited JSON, one NDJSONLine per delta:
+//
+//	{"text":"Hel","done":false}
+//	{"text":"lo","done":false}
+//	{"done":true,"finishReason":"stop"}
+//
+// It sets Content-Type to application/x-ndjson and flushes w after each
+// line. Deltas without text, reasoning, or tool call fragments are not
+// written. The final line carries the finish reason and the complete
+// tool calls. The stream is closed before returning.
+//
+// When stream.Next fails, a last line is written with Done set and
+// Error describing the failure, as in WriteTextStreamToWriter's
+// `event: error`. Nothing is written if ctx is done, since the client
+// is gone.
+//
+// Errors:
+//   - *PartialResponseError wrapping the failure if it occurs after
+//     any text or reasoning was received.
+//   - Otherwise the context, stream, or write error.
+func WriteTextStreamAsNDJSON(ctx context.Context, w http.ResponseWriter, stream TextStream) error {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	return writeTextStreamNDJSON(ctx, w, stream)
+}
+
+func writeTextStreamNDJSON(ctx context.Context, w io.Writer, stream TextStream) error {
+	defer stream.Close()
+
+	var text, reasoning strings.Builder
+	var calls ToolCallAccumulator
+	fail := func(err error) error {
+		if text.Len() == 0 && reasoning.Len() == 0 {
+			return err
+		}
+		return &PartialResponseError{
+			Partial: GenerateTextResponse{Text: text.String(), Reasoning: reasoning.String(), ToolCalls: calls.Calls()},
+			Err:     err,
+		}
+	}
+	enc := json.NewEncoder(w)
+	write := func(line NDJSONLine) error {
+		if err := enc.Encode(line); err != nil {
+			return err
+		}
+		return flushStream(w)
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return fail(err)
+		}
+		delta, err := stream.Next(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				ev := streamErrorEvent(err)
+				write(NDJSONLine{Done: true, Error: &ev})
+			}
+			return fail(err)
+		}
+		text.WriteString(delta.Text)
+		reasoning.WriteString(delta.Reasoning)
+		calls.Add(*delta)
+
+		line := NDJSONLine{Text: delta.Text, Reasoning: delta.Reasoning, Done: delta.Done}
+		for _, f := range delta.ToolCallDeltas {
+			index := f.Index
+			fragment := NDJSONToolCall{Index: &index, ID: f.ID, Name: f.Name}
+			if f.Arguments != "" {
+				fragment.Arguments = jsonString(f.Arguments)
+			}
+			line.ToolCallDeltas = append(line.ToolCallDeltas, fragment)
+		}
+		if delta.Done {
+			line.FinishReason = FinishReasonStop
+			for _, call := range calls.Calls() {
+				line.ToolCalls = append(line.ToolCalls, NDJSONToolCall{ID: call.ID, Name: call.Name, Arguments: ndjsonArguments(call.RawArguments)})
+			}
+			if len(line.ToolCalls) > 0 {
+				line.FinishReason = FinishReasonToolCalls
+			}
+			line.Usage = delta.Usage
+		} else if line.Text == "" && line.Reasoning == "" && len(line.ToolCallDeltas) == 0 {
+			continue
+		}
+		if err := write(line); err != nil {
+			return fail(err)
+		}
+		if delta.Done {
+			return nil
+		}
+	}
+}
+
+// ndjsonArguments returns tool call arguments as a JSON value.
+// Arguments held as a JSON string (as the OpenAI client returns them)
+// are unwrapped, and arguments that are not valid JSON are sent as a
+// string.
+func ndjsonArguments(args []byte) json.RawMessage {
+	var s string
+	if json.Unmarshal(args, &s) == nil {
+		args = []byte(s)
+	}
+	if len(args) == 0 {
+		return json.RawMessage("{}")
+	}
+	if !json.Valid(args) {
+		return jsonString(string(args))
+	}
+	return json.RawMessage(args)
+}
+
+func jsonString(s string) json.RawMessage {
+	data, _ := json.Marshal(s)
+	return data
+}
@@ -0,0 +1,84 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/openai"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+func TestWriteTextStreamAsNDJSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if err := WriteTextStreamAsNDJSON(context.Background(), rec, &scriptStream{texts: []string{"Hel", "", "lo\n"}}); err != nil {
+		t.Fatalf("WriteTextStreamAsNDJSON error: %v", err)
+	}
+	want := `{"text":"Hel","done":false}` + "\n" + `{"text":"lo\n","done":false}` + "\n" + `{"done":true,"finishReason":"stop"}` + "\n"
+	if rec.Body.String() != want || rec.Header().Get("Content-Type") != "application/x-ndjson" || !rec.Flushed {
+		t.Fatalf("unexpected output %q (content type %q)", rec.Body.String(), rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestWriteTextStreamAsNDJSON_ToolCalls(t *testing.T) {
+	var bodies []map[string]any
+	ts := fixtureServer(t, "toolcalls/openai_stream.sse", &bodies)
+	defer ts.Close()
+	client, err := openai.NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	stream, err := StreamText(context.Background(), GenerateTextRequest{
+		Model:    client.ChatModel("gpt-test"),
+		Messages: []Message{UserMessage("Weather in Paris?")},
+		Tools:    []ToolDefinition{{Name: "weather", Parameters: []byte(`{"type":"object"}`)}},
+	})
+	if err != nil {
+		t.Fatalf("StreamText error: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	if err := WriteTextStreamAsNDJSON(context.Background(), rec, stream); err != nil {
+		t.Fatalf("WriteTextStreamAsNDJSON error: %v", err)
+	}
+
+	var text, args string
+	var last NDJSONLine
+	for _, raw := range strings.Split(strings.TrimSpace(rec.Body.String()), "\n") {
+		last = NDJSONLine{}
+		if err := json.Unmarshal([]byte(raw), &last); err != nil {
+			t.Fatalf("invalid line %q: %v", raw, err)
+		}
+		text += last.Text
+		for _, f := range last.ToolCallDeltas {
+			var s string
+			json.Unmarshal(f.Arguments, &s)
+			args += s
+		}
+	}
+	if text != "Let me check the weather." || args != `{"city":"Paris"}` {
+		t.Fatalf("text %q, streamed arguments %q", text, args)
+	}
+	if !last.Done || last.FinishReason != FinishReasonToolCalls || len(last.ToolCalls) != 1 ||
+		last.ToolCalls[0].ID != "call_1" || string(last.ToolCalls[0].Arguments) != `{"city":"Paris"}` {
+		t.Fatalf("unexpected last line %+v", last)
+	}
+}
+
+func TestWriteTextStreamAsNDJSON_Error(t *testing.T) {
+	rec := httptest.NewRecorder()
+	failed := &IncompleteStreamError{Provider: "openai", Err: io.ErrUnexpectedEOF}
+	err := WriteTextStreamAsNDJSON(context.Background(), rec, &scriptStream{texts: []string{"Hel"}, err: failed})
+	var partial *PartialResponseError
+	if !errors.As(err, &partial) || partial.Partial.Text != "Hel" {
+		t.Fatalf("expected *PartialResponseError, got %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	var last NDJSONLine
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &last); err != nil || !last.Done || last.Error == nil || last.Error.Reason != StreamErrorInterrupted {
+		t.Fatalf("unexpected last line %q", lines[len(lines)-1])
+	}
+}
@@ -2,58 +2,292 @@ package ai
 
 import (
 	"context"
-	"fmt"
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 )
 
+// StreamFlusher is implemented by writers that buffer output and can
+// push it to the client on demand. *bufio.Writer, as handed out by
+// fasthttp's SetBodyStreamWriter, satisfies it.
+type StreamFlusher interface {
+	Flush() error
+}
+
+// SSEOptions configures WriteTextStreamAsSSEWithOptions and
+// WriteTextStreamToWriterWithOptions. The zero value sends text as
+// unnamed events with raw data, and no error events or heartbeats.
+type SSEOptions struct {
+	// EventName names the events carrying text, such as "delta", for
+	// clients listening with addEventListener. Empty sends unnamed
+	// events, which EventSource delivers to onmessage.
+	EventName string
+	// EncodeJSON sends the data of text and reasoning events as JSON
+	// objects, {"text":"..."} and {"reasoning":"..."}. Otherwise the
+	// raw text is sent, one data: line per line of text.
+	EncodeJSON bool
+	// SendErrors ends a failed stream with an `event: error` whose data
+	// is a StreamErrorEvent. Without it the response just ends, and
+	// EventSource clients reconnect as if the connection dropped.
+	SendErrors bool
+	// HeartbeatInterval, if positive, sends a ": keepalive" comment
+	// whenever nothing was sent for that long, so that proxies keep a
+	// quiet connection open. Clients ignore comments.
+	HeartbeatInterval time.Duration
+}
+
+// defaultSSEOptions are the options of WriteTextStreamAsSSE and
+// WriteTextStreamToWriter.
+var defaultSSEOptions = SSEOptions{SendErrors: true}
+
+// sseKeepalive is the SSE comment sent as a heartbeat.
+const sseKeepalive = ": keepalive\n\n"
+
 // WriteTextStreamAsSSE writes a TextStream to an http.ResponseWriter
 // using the Server-Sent Events (SSE) format.
 //
 // It sets the standard SSE headers and then sends each non-empty
-// TextDelta.Text value as a separate `data:` event line.
-// The stream terminates when a delta with Done=true is received or
-// when the context is canceled.
+// TextDelta.Text value as a separate unnamed event, one `data:` line
+// per line of text. Reasoning deltas are sent as named
+// `event: reasoning` events, which clients that only listen for default
+// messages ignore.
+// The stream terminates when a delta with Done=true is received, which
+// is followed by "data: [DONE]", or when the context is canceled. If
+// the stream fails, a named `event: error` is sent instead of [DONE];
+// see WriteTextStreamToWriter. Failures after content was sent are
+// returned as a *PartialResponseError.
+//
+// It is WriteTextStreamAsSSEWithOptions with SSEOptions.SendErrors set.
 func WriteTextStreamAsSSE(ctx context.Context, w http.ResponseWriter, stream TextStream) error {
-	defer stream.Close()
+	return WriteTextStreamAsSSEWithOptions(ctx, w, stream, defaultSSEOptions)
+}
 
-	h := w.Header()
-	h.Set("Content-Type", "text/event-stream")
-	h.Set("Cache-Control", "no-cache")
-	h.Set("Connection", "keep-alive")
+// WriteTextStreamAsSSEWithOptions is WriteTextStreamAsSSE with
+// configurable event names, data encoding, error events, and
+// heartbeats. For example, with
+//
+//	SSEOptions{EventName: "delta", EncodeJSON: true, SendErrors: true}
+//
+// each text delta is sent as
+//
+//	event: delta
+//	data: {"text":"Hello"}
+func WriteTextStreamAsSSEWithOptions(ctx context.Context, w http.ResponseWriter, stream TextStream, opts SSEOptions) error {
+	setSSEHeaders(w.Header())
+	return WriteTextStreamToWriterWithOptions(ctx, w, stream, opts)
+}
 
-	flusher, _ := w.(http.Flusher)
+// WriteTextStreamToWriter writes a TextStream to w in the same SSE
+// format as WriteTextStreamAsSSE without touching any headers, for
+// frameworks that are not built on net/http. The caller is responsible
+// for setting the SSE headers.
+//
+// After each event w is flushed if it implements StreamFlusher or
+// http.Flusher; writers that implement neither are written to as-is,
+// so they must not buffer the whole response if incremental delivery
+// is expected. The stream is closed before returning.
+//
+// When stream.Next fails, an `event: error` is written whose data is a
+// StreamErrorEvent, so clients can show "response interrupted" for a
+// cut connection (*IncompleteStreamError) rather than treating the
+// response as done. Nothing is written if ctx is done, since the
+// client is gone.
+//
+// Errors:
+//   - *PartialResponseError wrapping the failure if it occurs after
+//     any text or reasoning was received.
+//   - Otherwise the context, stream, or write error.
+func WriteTextStreamToWriter(ctx context.Context, w io.Writer, stream TextStream) error {
+	return WriteTextStreamToWriterWithOptions(ctx, w, stream, defaultSSEOptions)
+}
+
+// WriteTextStreamToWriterWithOptions is WriteTextStreamToWriter with
+// the options of WriteTextStreamAsSSEWithOptions. Heartbeats are
+// written from another goroutine, which stops before it returns.
+func WriteTextStreamToWriterWithOptions(ctx context.Context, w io.Writer, stream TextStream, opts SSEOptions) error {
+	defer stream.Close()
+
+	var text, reasoning strings.Builder
+	fail := func(err error) error {
+		if text.Len() == 0 && reasoning.Len() == 0 {
+			return err
+		}
+		return &PartialResponseError{
+			Partial: GenerateTextResponse{Text: text.String(), Reasoning: reasoning.String()},
+			Err:     err,
+		}
+	}
+	sw := &sseWriter{w: w, last: time.Now()}
+	if opts.HeartbeatInterval > 0 {
+		defer sw.heartbeat(ctx, opts.HeartbeatInterval)()
+	}
+	data := func(key, value string) string {
+		if !opts.EncodeJSON {
+			return value
+		}
+		b, _ := json.Marshal(map[string]string{key: value})
+		return string(b)
+	}
 
 	for {
 		if err := ctx.Err(); err != nil {
-			return err
+			return fail(err)
 		}
 
 		delta, err := stream.Next(ctx)
 		if err != nil {
-			return err
+			if ctx.Err() == nil && opts.SendErrors {
+				// Write errors are ignored; the stream error is what
+				// the caller reports.
+				ev, _ := json.Marshal(streamErrorEvent(err))
+				sw.event("error", string(ev))
+			}
+			return fail(err)
 		}
+		text.WriteString(delta.Text)
+		reasoning.WriteString(delta.Reasoning)
 		if delta.Done {
 			break
 		}
-		if delta.Text == "" {
-			continue
-		}
 
-		if _, err := fmt.Fprintf(w, "data: %s\n\n", delta.Text); err != nil {
-			return err
+		if delta.Reasoning != "" {
+			if err := sw.event("reasoning", data("reasoning", delta.Reasoning)); err != nil {
+				return fail(err)
+			}
 		}
-		if flusher != nil {
-			flusher.Flush()
+		if delta.Text != "" {
+			if err := sw.event(opts.EventName, data("text", delta.Text)); err != nil {
+				return fail(err)
+			}
 		}
 	}
 
 	// Send a final [DONE] marker for convenience.
-	if _, err := fmt.Fprint(w, "data: [DONE]\n\n"); err != nil {
+	if err := sw.write("data: [DONE]\n\n"); err != nil {
+		return fail(err)
+	}
+	return nil
+}
+
+// sseWriter writes SSE frames to w, flushing after each. Heartbeats
+// are written from another goroutine, so writes are serialized.
+type sseWriter struct {
+	w    io.Writer
+	mu   sync.Mutex
+	last time.Time
+}
+
+// event writes an event named name (unnamed if empty) with data,
+// split into one data: line per line. The SSE spec ends lines at CR,
+// LF, or CRLF, and clients join the data lines of an event with LF, so
+// multi-line text arrives intact.
+func (s *sseWriter) event(name, data string) error {
+	var b strings.Builder
+	if name != "" {
+		b.WriteString("event: " + name + "\n")
+	}
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\r", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	return s.write(b.String())
+}
+
+func (s *sseWriter) write(frame string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.last = time.Now()
+	if _, err := io.WriteString(s.w, frame); err != nil {
 		return err
 	}
-	if flusher != nil {
-		flusher.Flush()
+	return flushStream(s.w)
+}
+
+// heartbeat sends sseKeepalive whenever nothing was written for
+// interval, until ctx is done or the returned stop func is called.
+// stop waits for the goroutine to exit.
+func (s *sseWriter) heartbeat(ctx context.Context, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		timer := time.NewTimer(interval)
+		defer timer.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+			s.mu.Lock()
+			wait := interval - time.Since(s.last)
+			if wait <= 0 {
+				if _, err := io.WriteString(s.w, sseKeepalive); err == nil {
+					flushStream(s.w)
+				}
+				s.last = time.Now()
+				wait = interval
+			}
+			s.mu.Unlock()
+			timer.Reset(wait)
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// StreamErrorEvent is the data of the `event: error` that
+// WriteTextStreamToWriter sends when the stream fails.
+type StreamErrorEvent struct {
+	// Reason is StreamErrorInterrupted when the provider connection
+	// ended before completion and StreamErrorFailed otherwise.
+	Reason string `json:"reason"`
+	// Message is the error message.
+	Message string `json:"message"`
+}
+
+// StreamErrorEvent reasons.
+const (
+	StreamErrorInterrupted = "interrupted"
+	StreamErrorFailed      = "error"
+)
+
+// streamErrorEvent describes err for an `event: error`.
+func streamErrorEvent(err error) StreamErrorEvent {
+	ev := StreamErrorEvent{Reason: StreamErrorFailed, Message: err.Error()}
+	var incomplete *IncompleteStreamError
+	if errors.As(err, &incomplete) {
+		ev.Reason = StreamErrorInterrupted
 	}
+	return ev
+}
 
+// setSSEHeaders sets the standard Server-Sent Events response headers.
+func setSSEHeaders(h http.Header) {
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+}
+
+// flushStream pushes buffered output in w to the client when w supports
+// it. Flush errors from StreamFlusher usually mean the client went away.
+func flushStream(w io.Writer) error {
+	switch f := w.(type) {
+	case StreamFlusher:
+		return f.Flush()
+	case http.Flusher:
+		f.Flush()
+	}
 	return nil
 }
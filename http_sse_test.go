@@ -0,0 +1,105 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ncecere/ai-sdk/aitest"
+)
+
+func TestWriteTextStreamAsSSE_MultiLineText(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if err := WriteTextStreamAsSSE(context.Background(), rec, &scriptStream{texts: []string{"one\ntwo\r\n", "three"}}); err != nil {
+		t.Fatalf("WriteTextStreamAsSSE error: %v", err)
+	}
+	want := "data: one\ndata: two\ndata: \n\ndata: three\n\ndata: [DONE]\n\n"
+	if rec.Body.String() != want {
+		t.Fatalf("body = %q, want %q", rec.Body.String(), want)
+	}
+}
+
+func TestWriteTextStreamAsSSEWithOptions(t *testing.T) {
+	failed := &IncompleteStreamError{Provider: "openai", Err: io.ErrUnexpectedEOF}
+	cases := []struct {
+		name   string
+		opts   SSEOptions
+		stream *scriptStream
+		want   string
+	}{
+		{
+			name:   "json",
+			opts:   SSEOptions{EventName: "delta", EncodeJSON: true},
+			stream: &scriptStream{texts: []string{"a\nb"}},
+			want:   "event: delta\ndata: {\"text\":\"a\\nb\"}\n\ndata: [DONE]\n\n",
+		},
+		{
+			name:   "named raw",
+			opts:   SSEOptions{EventName: "delta"},
+			stream: &scriptStream{texts: []string{"a\nb"}},
+			want:   "event: delta\ndata: a\ndata: b\n\ndata: [DONE]\n\n",
+		},
+		{
+			name:   "errors",
+			opts:   SSEOptions{SendErrors: true},
+			stream: &scriptStream{texts: []string{"a"}, err: failed},
+			want:   "data: a\n\nevent: error\ndata: " + mustJSON(t, streamErrorEvent(failed)) + "\n\n",
+		},
+		{
+			name:   "no errors",
+			stream: &scriptStream{texts: []string{"a"}, err: failed},
+			want:   "data: a\n\n",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			err := WriteTextStreamAsSSEWithOptions(context.Background(), rec, tc.stream, tc.opts)
+			if tc.stream.err != nil {
+				var partial *PartialResponseError
+				if !errors.As(err, &partial) || !errors.Is(err, failed) {
+					t.Fatalf("expected *PartialResponseError, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("WriteTextStreamAsSSEWithOptions error: %v", err)
+			}
+			if rec.Body.String() != tc.want {
+				t.Fatalf("body = %q, want %q", rec.Body.String(), tc.want)
+			}
+			if rec.Header().Get("Content-Type") != "text/event-stream" || !tc.stream.closed.Load() {
+				t.Fatalf("missing SSE headers or stream left open")
+			}
+		})
+	}
+}
+
+func TestWriteTextStreamAsSSEWithOptions_Heartbeat(t *testing.T) {
+	before := aitest.Snapshot()
+	rec := httptest.NewRecorder()
+	stream := &scriptStream{texts: []string{"a", "b"}, delay: 60 * time.Millisecond}
+	if err := WriteTextStreamAsSSEWithOptions(context.Background(), rec, stream, SSEOptions{HeartbeatInterval: 10 * time.Millisecond}); err != nil {
+		t.Fatalf("WriteTextStreamAsSSEWithOptions error: %v", err)
+	}
+	body := rec.Body.String()
+	a, b := strings.Index(body, "data: a\n\n"), strings.Index(body, "data: b\n\n")
+	if a < 0 || b < 0 || !strings.Contains(body[a:b], ": keepalive\n\n") || !strings.HasPrefix(body, ": keepalive\n\n") {
+		t.Fatalf("expected keepalives before and between the events:\n%s", body)
+	}
+	if leaked := before.Leaked(aitest.LeakTimeout); len(leaked) > 0 {
+		t.Fatalf("%d leaked goroutine(s):\n\n%s", len(leaked), strings.Join(leaked, "\n\n"))
+	}
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
@@ -0,0 +1,102 @@
+package ai
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// StreamImage starts a streaming image generation that yields partial
+// preview images followed by the complete images. Set
+// req.PartialImages to control how many previews are emitted.
+//
+// Errors:
+//   - ErrMissingModel if req.Model is nil.
+//   - *InvalidArgumentError if Size or NumberOfImages is not supported
+//     by the model.
+//   - *UnsupportedFunctionalityError if the model does not support
+//     streaming image generation.
+//   - Any error returned by the underlying provider implementation when
+//     establishing the stream.
+func StreamImage(ctx context.Context, req ImageRequest) (ImageStream, error) {
+	if req.Model == nil {
+		return nil, ErrMissingModel
+	}
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+
+	streamer, ok := req.Model.(provider.ImageStreamer)
+	if !ok {
+		return nil, &UnsupportedFunctionalityError{Feature: "image streaming"}
+	}
+	return streamer.Stream(ctx, req.imageRequest())
+}
+
+// WriteImageStreamAsSSE writes an ImageStream to an http.ResponseWriter
+// using the Server-Sent Events (SSE) format.
+//
+// Partial images are sent as `partial_image` events and complete
+// images as `image` events. Inline image data is encoded as a data URI
+// so it can be assigned directly to an <img> src attribute; images
+// returned by URL are forwarded as-is. The stream terminates with a
+// final [DONE] marker.
+func WriteImageStreamAsSSE(ctx context.Context, w http.ResponseWriter, stream ImageStream) error {
+	setSSEHeaders(w.Header())
+	return WriteImageStreamToWriter(ctx, w, stream)
+}
+
+// WriteImageStreamToWriter writes an ImageStream to w in the same SSE
+// format as WriteImageStreamAsSSE without touching any headers. Like
+// WriteTextStreamToWriter, w is flushed after each event when it
+// implements StreamFlusher or http.Flusher.
+func WriteImageStreamToWriter(ctx context.Context, w io.Writer, stream ImageStream) error {
+	defer stream.Close()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		delta, err := stream.Next(ctx)
+		if err != nil {
+			return err
+		}
+
+		var event, data string
+		switch {
+		case delta.Partial != nil:
+			event, data = "partial_image", imageDataURI(delta.Partial.Data)
+		case delta.Image != nil && delta.Image.URL != "":
+			event, data = "image", delta.Image.URL
+		case delta.Image != nil:
+			event, data = "image", imageDataURI(delta.Image.Data)
+		}
+		if event != "" {
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+				return err
+			}
+			if err := flushStream(w); err != nil {
+				return err
+			}
+		}
+		if delta.Done {
+			break
+		}
+	}
+
+	if _, err := fmt.Fprint(w, "data: [DONE]\n\n"); err != nil {
+		return err
+	}
+	return flushStream(w)
+}
+
+// imageDataURI encodes image bytes as a base64 data URI, sniffing the
+// content type from the data.
+func imageDataURI(data []byte) string {
+	return "data:" + http.DetectContentType(data) + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
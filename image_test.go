@@ -0,0 +1,72 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+type fakeImageModel struct {
+	caps  *provider.ImageCapabilities
+	calls int
+}
+
+func (m *fakeImageModel) Generate(ctx context.Context, req *provider.ImageRequest) (*provider.ImageResponse, error) {
+	m.calls++
+	return &provider.ImageResponse{Images: []provider.Image{{URL: "https://example.com/a.png"}}}, nil
+}
+
+func (m *fakeImageModel) ImageCapabilities() (provider.ImageCapabilities, bool) {
+	if m.caps == nil {
+		return provider.ImageCapabilities{}, false
+	}
+	return *m.caps, true
+}
+
+func TestGenerateImage_ValidatesAgainstCapabilities(t *testing.T) {
+	ctx := context.Background()
+	model := &fakeImageModel{caps: &provider.ImageCapabilities{
+		Sizes:     []string{"1024x1024", "1792x1024"},
+		MaxImages: 1,
+	}}
+
+	cases := []struct {
+		name  string
+		req   ImageRequest
+		param string
+	}{
+		{"unicode size", ImageRequest{Size: "1024×1024"}, "Size"},
+		{"unsupported size", ImageRequest{Size: "512x512"}, "Size"},
+		{"too many images", ImageRequest{NumberOfImages: 2}, "NumberOfImages"},
+	}
+	for _, tc := range cases {
+		tc.req.Model = model
+		_, err := GenerateImage(ctx, tc.req)
+		var argErr *InvalidArgumentError
+		if !errors.As(err, &argErr) || argErr.Parameter != tc.param {
+			t.Fatalf("%s: expected InvalidArgumentError for %s, got %v", tc.name, tc.param, err)
+		}
+	}
+	if model.calls != 0 {
+		t.Fatalf("expected no provider calls for invalid requests, got %d", model.calls)
+	}
+
+	if _, err := GenerateImage(ctx, ImageRequest{Model: model, Size: "1792x1024", NumberOfImages: 1}); err != nil {
+		t.Fatalf("expected valid request to succeed, got %v", err)
+	}
+
+	unknown := &fakeImageModel{}
+	if _, err := GenerateImage(ctx, ImageRequest{Model: unknown, Size: "1024×1024", NumberOfImages: 4}); err != nil {
+		t.Fatalf("expected unknown model to skip validation, got %v", err)
+	}
+}
+
+func TestStreamImage_UnsupportedModel(t *testing.T) {
+	_, err := StreamImage(context.Background(), ImageRequest{Model: &fakeImageModel{}})
+	var unsupported *UnsupportedFunctionalityError
+	if !errors.As(err, &unsupported) {
+		t.Fatalf("expected UnsupportedFunctionalityError, got %v", err)
+	}
+}
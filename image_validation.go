@@ -0,0 +1,40 @@
+package ai
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// validate checks Size and NumberOfImages against the capabilities
+// reported by the model, if any.
+func (req ImageRequest) validate() error {
+	if req.NumberOfImages < 0 {
+		return &InvalidArgumentError{Parameter: "NumberOfImages", Value: req.NumberOfImages, Message: "must not be negative"}
+	}
+	reporter, ok := req.Model.(provider.ImageCapabilityReporter)
+	if !ok {
+		return nil
+	}
+	caps, ok := reporter.ImageCapabilities()
+	if !ok {
+		return nil
+	}
+	if req.Size != "" && len(caps.Sizes) > 0 && !slices.Contains(caps.Sizes, req.Size) {
+		return &InvalidArgumentError{
+			Parameter: "Size",
+			Value:     req.Size,
+			Message:   fmt.Sprintf("unsupported size %q; supported sizes: %s", req.Size, strings.Join(caps.Sizes, ", ")),
+		}
+	}
+	if caps.MaxImages > 0 && req.NumberOfImages > caps.MaxImages {
+		return &InvalidArgumentError{
+			Parameter: "NumberOfImages",
+			Value:     req.NumberOfImages,
+			Message:   fmt.Sprintf("model supports at most %d image(s) per request", caps.MaxImages),
+		}
+	}
+	return nil
+}
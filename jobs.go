@@ -0,0 +1,384 @@
+package ai
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// JobStatus describes the state of a submitted job.
+type JobStatus string
+
+const (
+	// JobPending means the job is still running.
+	JobPending JobStatus = "pending"
+	// JobSucceeded means the job finished and Response is set.
+	JobSucceeded JobStatus = "succeeded"
+	// JobFailed means the job finished with an error.
+	JobFailed JobStatus = "failed"
+)
+
+// JobResult is the persisted state of a job.
+type JobResult struct {
+	// ID is the job identifier returned to callers.
+	ID string `json:"id"`
+	// Status is the current job status.
+	Status JobStatus `json:"status"`
+	// Response is the generation result once the job has succeeded.
+	Response *GenerateTextResponse `json:"response,omitempty"`
+	// Error is the error message once the job has failed.
+	Error string `json:"error,omitempty"`
+}
+
+// JobStore persists job results so they can be retrieved later, for
+// example from a different HTTP request than the one that submitted
+// the job. Implementations must be safe for concurrent use.
+type JobStore interface {
+	Save(ctx context.Context, job JobResult) error
+	// Load returns ErrJobNotFound if no job with id exists.
+	Load(ctx context.Context, id string) (JobResult, error)
+}
+
+// MemoryJobStore is an in-process JobStore. Results are lost when the
+// process exits; use a persistent JobStore for multi-instance servers.
+// Finished jobs are kept until Delete removes them or, with a TTL, until
+// they expire.
+type MemoryJobStore struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu   sync.RWMutex
+	jobs map[string]memoryJob
+}
+
+type memoryJob struct {
+	JobResult
+	// finishedAt is when the job was saved as succeeded or failed.
+	finishedAt time.Time
+}
+
+// defaultJobTTL is how long the MemoryJobStore a JobRunner creates
+// keeps finished jobs.
+const defaultJobTTL = time.Hour
+
+// NewMemoryJobStore creates an empty MemoryJobStore that keeps jobs
+// until they are deleted.
+func NewMemoryJobStore() *MemoryJobStore {
+	return NewMemoryJobStoreWithTTL(0)
+}
+
+// NewMemoryJobStoreWithTTL creates an empty MemoryJobStore that removes
+// finished jobs ttl after they finish. Pending jobs never expire. A ttl
+// of zero or less keeps jobs until they are deleted.
+func NewMemoryJobStoreWithTTL(ttl time.Duration) *MemoryJobStore {
+	return &MemoryJobStore{ttl: ttl, now: time.Now, jobs: make(map[string]memoryJob)}
+}
+
+func (s *MemoryJobStore) expired(job memoryJob, now time.Time) bool {
+	return s.ttl > 0 && job.Status != JobPending && now.Sub(job.finishedAt) > s.ttl
+}
+
+// Save implements JobStore. It also removes expired jobs.
+func (s *MemoryJobStore) Save(ctx context.Context, job JobResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for id, j := range s.jobs {
+		if s.expired(j, now) {
+			delete(s.jobs, id)
+		}
+	}
+	s.jobs[job.ID] = memoryJob{JobResult: job, finishedAt: now}
+	return nil
+}
+
+// Load implements JobStore.
+func (s *MemoryJobStore) Load(ctx context.Context, id string) (JobResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	job, ok := s.jobs[id]
+	if !ok || s.expired(job, s.now()) {
+		return JobResult{}, ErrJobNotFound
+	}
+	return job.JobResult, nil
+}
+
+// Delete removes the job with id, for example once its result has been
+// delivered. Deleting an unknown job is not an error.
+func (s *MemoryJobStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, id)
+	return nil
+}
+
+// JobRunner submits long-running generations in the background and
+// records their results in Store.
+//
+// Models implementing provider.DeferredLanguageModel, such as the
+// OpenAI client's BatchModel, are submitted to the provider and polled
+// every PollInterval; other models are called
+// in a goroutine. In both cases the work outlives the submitting
+// request's context, so a web handler can return 202 with the job ID
+// and a second endpoint can retrieve the result with Job. Call Close
+// on shutdown to stop jobs that are still running.
+type JobRunner struct {
+	// Store persists job results. If nil, a MemoryJobStore that keeps
+	// finished jobs for an hour is created on first use.
+	Store JobStore
+	// PollInterval controls how often provider-side deferred jobs are
+	// polled and how often Wait re-checks the store. Defaults to one
+	// second.
+	PollInterval time.Duration
+
+	once     sync.Once
+	lifetime context.Context
+	shutdown context.CancelFunc
+	mu       sync.Mutex
+	closed   bool
+	running  sync.WaitGroup
+}
+
+var defaultJobRunner = &JobRunner{}
+
+// SubmitText starts req in the background using a process-wide
+// JobRunner backed by a MemoryJobStore, which keeps finished jobs for
+// an hour. See JobRunner.SubmitText.
+func SubmitText(ctx context.Context, req GenerateTextRequest) (*JobHandle, error) {
+	return defaultJobRunner.SubmitText(ctx, req)
+}
+
+// Job returns a handle for a job submitted with SubmitText.
+func Job(id string) *JobHandle {
+	return defaultJobRunner.Job(id)
+}
+
+func (r *JobRunner) init() {
+	r.once.Do(func() {
+		if r.Store == nil {
+			r.Store = NewMemoryJobStoreWithTTL(defaultJobTTL)
+		}
+		r.lifetime, r.shutdown = context.WithCancel(context.Background())
+	})
+}
+
+func (r *JobRunner) store() JobStore {
+	r.init()
+	return r.Store
+}
+
+// Close cancels the jobs this runner is still running, which are
+// recorded as failed, and waits for their goroutines to exit. Jobs
+// submitted after Close fail with ErrJobRunnerClosed; results already
+// in the store remain retrievable.
+func (r *JobRunner) Close() error {
+	r.init()
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+	r.shutdown()
+	r.running.Wait()
+	return nil
+}
+
+// startJob registers a background job and returns the context it runs
+// with: ctx's values without its cancellation, cancelled by Close.
+// release must be called when the job finishes.
+func (r *JobRunner) startJob(ctx context.Context) (jobCtx context.Context, release func(), err error) {
+	r.init()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil, nil, ErrJobRunnerClosed
+	}
+	r.running.Add(1)
+	jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(r.lifetime, cancel)
+	return jobCtx, func() {
+		stop()
+		cancel()
+		r.running.Done()
+	}, nil
+}
+
+func (r *JobRunner) pollInterval() time.Duration {
+	if r.PollInterval <= 0 {
+		return time.Second
+	}
+	return r.PollInterval
+}
+
+// SubmitText records a pending job, starts req in the background, and
+// returns a handle immediately. Cancelling ctx does not cancel the job;
+// values carried by ctx remain visible to the model call.
+//
+// Errors:
+//   - ErrMissingModel if req.Model is nil.
+//   - ErrJobRunnerClosed if Close has been called.
+//   - Any error returned by the store when recording the job.
+//   - Any error returned by the provider when submitting a deferred job.
+func (r *JobRunner) SubmitText(ctx context.Context, req GenerateTextRequest) (*JobHandle, error) {
+	if req.Model == nil {
+		return nil, ErrMissingModel
+	}
+
+	jobCtx, release, err := r.startJob(ctx)
+	if err != nil {
+		return nil, err
+	}
+	store := r.store()
+	id, err := newJobID()
+	if err != nil {
+		release()
+		return nil, err
+	}
+	if err := store.Save(ctx, JobResult{ID: id, Status: JobPending}); err != nil {
+		release()
+		return nil, err
+	}
+
+	// Results are saved even when the job was cancelled by Close.
+	bg := context.WithoutCancel(jobCtx)
+	done := make(chan struct{})
+
+	if deferred, ok := req.Model.(provider.DeferredLanguageModel); ok {
+		jobID, err := deferred.SubmitDeferred(ctx, req.languageModelRequest())
+		if err != nil {
+			_ = store.Save(bg, JobResult{ID: id, Status: JobFailed, Error: err.Error()})
+			release()
+			return nil, err
+		}
+		go func() {
+			defer close(done)
+			defer release()
+			res, err := r.waitDeferred(jobCtx, deferred, jobID)
+			r.finish(bg, id, res, err)
+		}()
+	} else {
+		go func() {
+			defer close(done)
+			defer release()
+			res, err := GenerateText(jobCtx, req)
+			r.finish(bg, id, &res, err)
+		}()
+	}
+
+	return &JobHandle{ID: id, runner: r, done: done}, nil
+}
+
+// waitDeferred polls a provider-side deferred job until it completes.
+func (r *JobRunner) waitDeferred(ctx context.Context, m provider.DeferredLanguageModel, jobID string) (*GenerateTextResponse, error) {
+	ticker := time.NewTicker(r.pollInterval())
+	defer ticker.Stop()
+	for {
+		lmRes, done, err := m.RetrieveDeferred(ctx, jobID)
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			res := newGenerateTextResponse(lmRes)
+			return &res, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *JobRunner) finish(ctx context.Context, id string, res *GenerateTextResponse, err error) {
+	job := JobResult{ID: id, Status: JobSucceeded, Response: res}
+	if err != nil {
+		job = JobResult{ID: id, Status: JobFailed, Error: err.Error()}
+	}
+	_ = r.store().Save(ctx, job)
+}
+
+// Job returns a handle for an existing job ID. The job is not looked
+// up until Poll or Wait is called.
+func (r *JobRunner) Job(id string) *JobHandle {
+	return &JobHandle{ID: id, runner: r}
+}
+
+// JobHandle refers to a submitted job.
+type JobHandle struct {
+	// ID is the job identifier, suitable for returning to clients.
+	ID string
+
+	runner *JobRunner
+	done   <-chan struct{}
+}
+
+// Poll returns the current state of the job without blocking.
+//
+// Errors:
+//   - ErrJobNotFound if the store has no job with this ID.
+func (h *JobHandle) Poll(ctx context.Context) (JobResult, error) {
+	return h.runner.store().Load(ctx, h.ID)
+}
+
+// Wait blocks until the job finishes or ctx is done and returns the
+// generation result.
+//
+// Errors:
+//   - ErrJobNotFound if the store has no job with this ID.
+//   - *JobFailedError if the job finished with an error.
+//   - ctx.Err() if ctx is done first.
+func (h *JobHandle) Wait(ctx context.Context) (GenerateTextResponse, error) {
+	// Handles returned by SubmitText wake up as soon as the local
+	// goroutine finishes; other handles poll the store.
+	done := h.done
+	ticker := time.NewTicker(h.runner.pollInterval())
+	defer ticker.Stop()
+	for {
+		job, err := h.Poll(ctx)
+		if err != nil {
+			return GenerateTextResponse{}, err
+		}
+		switch job.Status {
+		case JobSucceeded:
+			if job.Response == nil {
+				return GenerateTextResponse{}, nil
+			}
+			return *job.Response, nil
+		case JobFailed:
+			return GenerateTextResponse{}, &JobFailedError{ID: job.ID, Message: job.Error}
+		}
+
+		select {
+		case <-ctx.Done():
+			return GenerateTextResponse{}, ctx.Err()
+		case <-done:
+			done = nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// JobFailedError is returned by JobHandle.Wait when the job finished
+// with an error. Message is the original error text, which is all that
+// survives persistence.
+type JobFailedError struct {
+	ID      string
+	Message string
+}
+
+func (e *JobFailedError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	return "ai: job " + e.ID + " failed: " + e.Message
+}
+
+func newJobID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return "job_" + hex.EncodeToString(b[:]), nil
+}
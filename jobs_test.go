@@ -0,0 +1,140 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ncecere/ai-sdk/aitest"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+type deferredModel struct {
+	scriptedModel
+	polls int
+}
+
+func (m *deferredModel) SubmitDeferred(ctx context.Context, req *provider.LanguageModelRequest) (string, error) {
+	return "remote-1", nil
+}
+
+func (m *deferredModel) RetrieveDeferred(ctx context.Context, jobID string) (*provider.LanguageModelResponse, bool, error) {
+	m.polls++
+	if m.polls < 3 {
+		return nil, false, nil
+	}
+	return &provider.LanguageModelResponse{Text: "deferred " + jobID}, true, nil
+}
+
+func TestJobRunner_SubmitAndRetrieve(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	runner := &JobRunner{PollInterval: time.Millisecond}
+	model := &scriptedModel{responses: []*provider.LanguageModelResponse{{Text: "done"}}}
+
+	h, err := runner.SubmitText(ctx, GenerateTextRequest{Model: model, Messages: []Message{UserMessage("hi")}})
+	if err != nil {
+		t.Fatalf("SubmitText error: %v", err)
+	}
+	// The job must survive cancellation of the submitting request.
+	cancel()
+
+	res, err := runner.Job(h.ID).Wait(context.Background())
+	if err != nil || res.Text != "done" {
+		t.Fatalf("unexpected result: %+v, err=%v", res, err)
+	}
+	job, err := h.Poll(context.Background())
+	if err != nil || job.Status != JobSucceeded {
+		t.Fatalf("unexpected job state: %+v, err=%v", job, err)
+	}
+
+	if _, err := runner.Job("missing").Poll(context.Background()); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("expected ErrJobNotFound, got %v", err)
+	}
+}
+
+func TestJobRunner_FailedJob(t *testing.T) {
+	runner := &JobRunner{PollInterval: time.Millisecond}
+	h, err := runner.SubmitText(context.Background(), GenerateTextRequest{Model: &scriptedModel{}})
+	if err != nil {
+		t.Fatalf("SubmitText error: %v", err)
+	}
+	var failed *JobFailedError
+	if _, err := h.Wait(context.Background()); !errors.As(err, &failed) {
+		t.Fatalf("expected JobFailedError, got %v", err)
+	}
+}
+
+func TestJobRunner_UsesProviderDeferral(t *testing.T) {
+	runner := &JobRunner{PollInterval: time.Millisecond}
+	model := &deferredModel{}
+	h, err := runner.SubmitText(context.Background(), GenerateTextRequest{Model: model})
+	if err != nil {
+		t.Fatalf("SubmitText error: %v", err)
+	}
+	res, err := h.Wait(context.Background())
+	if err != nil || res.Text != "deferred remote-1" {
+		t.Fatalf("unexpected result: %+v, err=%v", res, err)
+	}
+	if len(model.requests) != 0 {
+		t.Fatalf("expected Generate not to be called for deferred models")
+	}
+}
+
+// pendingModel is a deferred model whose jobs never complete.
+type pendingModel struct {
+	deferredModel
+}
+
+func (m *pendingModel) RetrieveDeferred(ctx context.Context, jobID string) (*provider.LanguageModelResponse, bool, error) {
+	return nil, false, nil
+}
+
+func TestJobRunner_CloseStopsPollers(t *testing.T) {
+	aitest.VerifyNoLeaks(t)
+	runner := &JobRunner{PollInterval: time.Millisecond}
+	h, err := runner.SubmitText(context.Background(), GenerateTextRequest{Model: &pendingModel{}})
+	if err != nil {
+		t.Fatalf("SubmitText error: %v", err)
+	}
+
+	if err := runner.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	job, err := h.Poll(context.Background())
+	if err != nil || job.Status != JobFailed {
+		t.Fatalf("expected the running job to be recorded as failed, got %+v, err=%v", job, err)
+	}
+	if _, err := runner.SubmitText(context.Background(), GenerateTextRequest{Model: &pendingModel{}}); !errors.Is(err, ErrJobRunnerClosed) {
+		t.Fatalf("expected ErrJobRunnerClosed, got %v", err)
+	}
+}
+
+func TestMemoryJobStore_ExpiresFinishedJobs(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(0, 0)
+	store := NewMemoryJobStoreWithTTL(time.Minute)
+	store.now = func() time.Time { return now }
+
+	_ = store.Save(ctx, JobResult{ID: "done", Status: JobSucceeded})
+	_ = store.Save(ctx, JobResult{ID: "running", Status: JobPending})
+	_ = store.Save(ctx, JobResult{ID: "delivered", Status: JobFailed})
+	if err := store.Delete(ctx, "delivered"); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if _, err := store.Load(ctx, "delivered"); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("expected a deleted job to be gone, got %v", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := store.Load(ctx, "done"); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("expected an expired job to be gone, got %v", err)
+	}
+	_ = store.Save(ctx, JobResult{ID: "next", Status: JobPending})
+	if _, ok := store.jobs["done"]; ok || len(store.jobs) != 2 {
+		t.Fatalf("expected Save to remove expired jobs, have %d", len(store.jobs))
+	}
+	if job, err := store.Load(ctx, "running"); err != nil || job.Status != JobPending {
+		t.Fatalf("expected pending jobs never to expire, got %+v, %v", job, err)
+	}
+}
@@ -0,0 +1,199 @@
+package ai
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// GetPath returns the value at path in a decoded JSON document such as
+// the map returned by GenerateJSON. Paths are dot-separated object keys
+// with optional array indexes, e.g. "a.b[2].c" or "[0].name".
+//
+// Errors:
+//   - *JSONPathError if the path is malformed, a key or index is
+//     missing, or an intermediate value has the wrong type.
+func GetPath(doc any, path string) (any, error) {
+	segs, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	cur := doc
+	for i, seg := range segs {
+		at := jsonPathPrefix(segs[:i+1])
+		if seg.key != "" {
+			obj, ok := cur.(map[string]any)
+			if !ok {
+				return nil, &JSONPathError{Path: path, At: at, Message: "not an object"}
+			}
+			v, ok := obj[seg.key]
+			if !ok {
+				return nil, &JSONPathError{Path: path, At: at, Message: "missing key"}
+			}
+			cur = v
+			continue
+		}
+		arr, ok := cur.([]any)
+		if !ok {
+			return nil, &JSONPathError{Path: path, At: at, Message: "not an array"}
+		}
+		if seg.index >= len(arr) {
+			return nil, &JSONPathError{Path: path, At: at, Message: "index out of range"}
+		}
+		cur = arr[seg.index]
+	}
+	return cur, nil
+}
+
+// GetString returns the string at path.
+func GetString(doc any, path string) (string, error) {
+	v, err := GetPath(doc, path)
+	if err != nil {
+		return "", err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", &JSONPathError{Path: path, At: path, Message: "not a string"}
+	}
+	return s, nil
+}
+
+// GetFloat returns the number at path as a float64. Both float64 and
+// json.Number values are accepted.
+func GetFloat(doc any, path string) (float64, error) {
+	v, err := GetPath(doc, path)
+	if err != nil {
+		return 0, err
+	}
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case json.Number:
+		if f, err := n.Float64(); err == nil {
+			return f, nil
+		}
+	}
+	return 0, &JSONPathError{Path: path, At: path, Message: "not a number"}
+}
+
+// GetInt returns the integer at path. Numbers with a fractional part
+// are rejected. json.Number values keep full int64 precision.
+func GetInt(doc any, path string) (int64, error) {
+	v, err := GetPath(doc, path)
+	if err != nil {
+		return 0, err
+	}
+	switch n := v.(type) {
+	case float64:
+		if n == math.Trunc(n) && n >= math.MinInt64 && n < math.MaxInt64 {
+			return int64(n), nil
+		}
+	case json.Number:
+		if i, err := n.Int64(); err == nil {
+			return i, nil
+		}
+	}
+	return 0, &JSONPathError{Path: path, At: path, Message: "not an integer"}
+}
+
+// GetBool returns the boolean at path.
+func GetBool(doc any, path string) (bool, error) {
+	v, err := GetPath(doc, path)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, &JSONPathError{Path: path, At: path, Message: "not a boolean"}
+	}
+	return b, nil
+}
+
+// GetObject returns the object at path.
+func GetObject(doc any, path string) (map[string]any, error) {
+	v, err := GetPath(doc, path)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, &JSONPathError{Path: path, At: path, Message: "not an object"}
+	}
+	return m, nil
+}
+
+// GetArray returns the array at path.
+func GetArray(doc any, path string) ([]any, error) {
+	v, err := GetPath(doc, path)
+	if err != nil {
+		return nil, err
+	}
+	a, ok := v.([]any)
+	if !ok {
+		return nil, &JSONPathError{Path: path, At: path, Message: "not an array"}
+	}
+	return a, nil
+}
+
+// jsonPathSegment is an object key or, when key is empty, an array
+// index.
+type jsonPathSegment struct {
+	key   string
+	index int
+}
+
+func parseJSONPath(path string) ([]jsonPathSegment, error) {
+	var segs []jsonPathSegment
+	rest := path
+	for rest != "" {
+		if rest[0] == '[' {
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, &JSONPathError{Path: path, At: path, Message: "unclosed ["}
+			}
+			idx, err := strconv.Atoi(rest[1:end])
+			if err != nil || idx < 0 {
+				return nil, &JSONPathError{Path: path, At: path, Message: "invalid index " + strconv.Quote(rest[1:end])}
+			}
+			segs = append(segs, jsonPathSegment{index: idx})
+			rest = rest[end+1:]
+		} else {
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, &JSONPathError{Path: path, At: path, Message: "empty key"}
+			}
+			segs = append(segs, jsonPathSegment{key: rest[:end]})
+			rest = rest[end:]
+		}
+		if strings.HasPrefix(rest, ".") {
+			rest = rest[1:]
+			if rest == "" {
+				return nil, &JSONPathError{Path: path, At: path, Message: "empty key"}
+			}
+		}
+	}
+	if len(segs) == 0 {
+		return nil, &JSONPathError{Path: path, Message: "empty path"}
+	}
+	return segs, nil
+}
+
+// jsonPathPrefix renders segs back into path syntax.
+func jsonPathPrefix(segs []jsonPathSegment) string {
+	var b strings.Builder
+	for _, s := range segs {
+		if s.key == "" {
+			b.WriteString("[" + strconv.Itoa(s.index) + "]")
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(s.key)
+	}
+	return b.String()
+}
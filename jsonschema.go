@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"slices"
+	"sort"
 )
 
 // JSONSchemaFromType builds a simple JSON Schema document for the
@@ -23,6 +25,13 @@ import (
 //   - Maps become `{"type":"object","additionalProperties":...}`
 //     where the value schema is derived from the map element type.
 //   - Unsupported or unknown kinds default to `{ "type": "string" }`.
+//   - An `example:"..."` struct tag adds its value to the property's
+//     "examples" and a `default:"..."` tag sets its "default". For
+//     string fields the tag is used verbatim; for other fields it must
+//     be JSON, such as `example:"42"` or `example:"[\"a\",\"b\"]"`.
+//
+// Errors:
+//   - If example is nil or an example or default tag is not valid JSON.
 func JSONSchemaFromType(example any) ([]byte, error) {
 	t := reflect.TypeOf(example)
 	if t == nil {
@@ -32,7 +41,10 @@ func JSONSchemaFromType(example any) ([]byte, error) {
 		t = t.Elem()
 	}
 
-	schema := schemaForType(t)
+	schema, err := schemaForType(t)
+	if err != nil {
+		return nil, err
+	}
 	data, err := json.Marshal(schema)
 	if err != nil {
 		return nil, err
@@ -40,27 +52,35 @@ func JSONSchemaFromType(example any) ([]byte, error) {
 	return data, nil
 }
 
-func schemaForType(t reflect.Type) map[string]any {
+func schemaForType(t reflect.Type) (map[string]any, error) {
 	switch t.Kind() {
 	case reflect.Bool:
-		return map[string]any{"type": "boolean"}
+		return map[string]any{"type": "boolean"}, nil
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
 		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return map[string]any{"type": "integer"}
+		return map[string]any{"type": "integer"}, nil
 	case reflect.Float32, reflect.Float64:
-		return map[string]any{"type": "number"}
+		return map[string]any{"type": "number"}, nil
 	case reflect.String:
-		return map[string]any{"type": "string"}
+		return map[string]any{"type": "string"}, nil
 	case reflect.Slice, reflect.Array:
+		items, err := schemaForType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
 		return map[string]any{
 			"type":  "array",
-			"items": schemaForType(t.Elem()),
-		}
+			"items": items,
+		}, nil
 	case reflect.Map:
+		values, err := schemaForType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
 		return map[string]any{
 			"type":                 "object",
-			"additionalProperties": schemaForType(t.Elem()),
-		}
+			"additionalProperties": values,
+		}, nil
 	case reflect.Struct:
 		props := make(map[string]any)
 		var required []string
@@ -73,7 +93,15 @@ func schemaForType(t reflect.Type) map[string]any {
 			if name == "" {
 				continue
 			}
-			props[name] = schemaForType(indirectType(f.Type))
+			ft := indirectType(f.Type)
+			prop, err := schemaForType(ft)
+			if err != nil {
+				return nil, err
+			}
+			if err := applyExampleTags(prop, f, ft); err != nil {
+				return nil, err
+			}
+			props[name] = prop
 			if !omit && !isOptionalKind(f.Type.Kind()) {
 				required = append(required, name)
 			}
@@ -85,11 +113,192 @@ func schemaForType(t reflect.Type) map[string]any {
 		if len(required) > 0 {
 			m["required"] = required
 		}
-		return m
+		return m, nil
 	default:
 		// Fallback for unsupported kinds.
-		return map[string]any{"type": "string"}
+		return map[string]any{"type": "string"}, nil
+	}
+}
+
+// applyExampleTags sets the "examples" and "default" keywords of prop
+// from the example and default tags of field f, whose type (without
+// pointers) is t.
+func applyExampleTags(prop map[string]any, f reflect.StructField, t reflect.Type) error {
+	for _, tag := range []string{"example", "default"} {
+		raw, ok := f.Tag.Lookup(tag)
+		if !ok {
+			continue
+		}
+		var v any = raw
+		if t.Kind() != reflect.String {
+			if err := json.Unmarshal([]byte(raw), &v); err != nil {
+				return fmt.Errorf("jsonschema: field %s: invalid %s tag %q: %w", f.Name, tag, raw, err)
+			}
+		}
+		if tag == "example" {
+			prop["examples"] = []any{v}
+		} else {
+			prop["default"] = v
+		}
+	}
+	return nil
+}
+
+// StrictJSONSchema returns schema rewritten for strict structured
+// outputs, such as OpenAI's json_schema with "strict": true, which
+// require every object to list all of its properties in "required" and
+// to set "additionalProperties" to false. Properties that were not
+// required, such as those of pointer fields, become nullable instead:
+// "type": "string" becomes "type": ["string","null"], a schema without
+// "type" is wrapped in "anyOf" with {"type":"null"}, and an "enum"
+// gains null. The decoded field is then nil or zero when the model
+// omits the value, as without strict mode.
+//
+// Nested schemas under "properties", "items", "anyOf", "$defs", and
+// "definitions" are rewritten too. Objects without "properties", such
+// as those of map fields, are left unchanged; strict providers
+// typically reject them. The rewrite is idempotent.
+//
+// Errors:
+//   - If schema is not a JSON object.
+func StrictJSONSchema(schema []byte) ([]byte, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(schema, &doc); err != nil {
+		return nil, fmt.Errorf("jsonschema: schema is not a JSON object: %w", err)
+	}
+	strictSchema(doc)
+	return json.Marshal(doc)
+}
+
+// strictSchema rewrites s and its nested schemas in place.
+func strictSchema(s map[string]any) {
+	if props, ok := s["properties"].(map[string]any); ok {
+		required := map[string]bool{}
+		if list, ok := s["required"].([]any); ok {
+			for _, name := range list {
+				if name, ok := name.(string); ok {
+					required[name] = true
+				}
+			}
+		}
+		names := make([]string, 0, len(props))
+		for name, prop := range props {
+			names = append(names, name)
+			prop, ok := prop.(map[string]any)
+			if !ok {
+				continue
+			}
+			strictSchema(prop)
+			if !required[name] {
+				props[name] = nullableSchema(prop)
+			}
+		}
+		sort.Strings(names)
+		s["required"] = names
+		s["additionalProperties"] = false
+	}
+	if items, ok := s["items"].(map[string]any); ok {
+		strictSchema(items)
+	}
+	if anyOf, ok := s["anyOf"].([]any); ok {
+		for _, sub := range anyOf {
+			if sub, ok := sub.(map[string]any); ok {
+				strictSchema(sub)
+			}
+		}
+	}
+	for _, key := range []string{"$defs", "definitions"} {
+		if defs, ok := s[key].(map[string]any); ok {
+			for _, def := range defs {
+				if def, ok := def.(map[string]any); ok {
+					strictSchema(def)
+				}
+			}
+		}
+	}
+}
+
+// nullableSchema returns s extended to also accept null.
+func nullableSchema(s map[string]any) map[string]any {
+	switch t := s["type"].(type) {
+	case string:
+		if t != "null" {
+			s["type"] = []any{t, "null"}
+		}
+	case []any:
+		if !slices.Contains(t, any("null")) {
+			s["type"] = append(t, "null")
+		}
+	default:
+		return map[string]any{"anyOf": []any{s, map[string]any{"type": "null"}}}
+	}
+	if enum, ok := s["enum"].([]any); ok && !slices.Contains(enum, nil) {
+		s["enum"] = append(enum, nil)
+	}
+	return s
+}
+
+// AddSchemaExamples returns schema with examples appended to its
+// top-level "examples" keyword. Each example is JSON-encoded, so it is
+// typically a value of the Go type the schema was built from. Schema is
+// returned unchanged when there are no examples.
+//
+// Errors:
+//   - If schema is not a JSON object or an example cannot be encoded.
+func AddSchemaExamples(schema []byte, examples ...any) ([]byte, error) {
+	if len(examples) == 0 {
+		return schema, nil
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(schema, &doc); err != nil {
+		return nil, fmt.Errorf("jsonschema: schema is not a JSON object: %w", err)
+	}
+	var all []json.RawMessage
+	if existing, ok := doc["examples"]; ok {
+		if err := json.Unmarshal(existing, &all); err != nil {
+			return nil, fmt.Errorf("jsonschema: existing examples are not an array: %w", err)
+		}
+	}
+	for i, ex := range examples {
+		b, err := json.Marshal(ex)
+		if err != nil {
+			return nil, fmt.Errorf("jsonschema: encoding example %d: %w", i, err)
+		}
+		all = append(all, b)
+	}
+	b, err := json.Marshal(all)
+	if err != nil {
+		return nil, err
+	}
+	doc["examples"] = b
+	return json.Marshal(doc)
+}
+
+// NewToolDefinition builds a ToolDefinition whose parameters schema is
+// inferred from Args as by JSONSchemaFromType, including any example and
+// default struct tags, with examples, complete argument values, listed
+// under the schema's top-level "examples" keyword. Models use examples
+// as guidance for filling in the arguments; they are not validated
+// against the schema.
+//
+// Errors:
+//   - A wrapped error if the schema cannot be inferred from Args or an
+//     example cannot be encoded.
+func NewToolDefinition[Args any](name, description string, examples ...Args) (ToolDefinition, error) {
+	var zero Args
+	schema, err := JSONSchemaFromType(zero)
+	if err != nil {
+		return ToolDefinition{}, fmt.Errorf("ai: building JSON schema for tool %q: %w", name, err)
+	}
+	anyExamples := make([]any, len(examples))
+	for i, ex := range examples {
+		anyExamples[i] = ex
+	}
+	schema, err = AddSchemaExamples(schema, anyExamples...)
+	if err != nil {
+		return ToolDefinition{}, fmt.Errorf("ai: tool %q: %w", name, err)
 	}
+	return ToolDefinition{Name: name, Description: description, Parameters: schema}, nil
 }
 
 func jsonFieldName(f reflect.StructField) (name string, omit bool) {
@@ -0,0 +1,103 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type weatherArgs struct {
+	City  string   `json:"city" example:"Paris"`
+	Units *string  `json:"units,omitempty" example:"celsius" default:"celsius"`
+	Days  int      `json:"days" example:"3" default:"1"`
+	Hours []string `json:"hours,omitempty" example:"[\"09:00\",\"18:00\"]"`
+}
+
+func TestNewToolDefinition_GoldenSchema(t *testing.T) {
+	units := "fahrenheit"
+	def, err := NewToolDefinition("get_weather", "Get the weather forecast for a city.",
+		weatherArgs{City: "Tokyo", Days: 2},
+		weatherArgs{City: "Boston", Units: &units, Days: 1, Hours: []string{"12:00"}},
+	)
+	if err != nil {
+		t.Fatalf("NewToolDefinition error: %v", err)
+	}
+	if def.Name != "get_weather" || def.Description != "Get the weather forecast for a city." {
+		t.Fatalf("unexpected definition %+v", def)
+	}
+
+	var got bytes.Buffer
+	if err := json.Indent(&got, def.Parameters, "", "  "); err != nil {
+		t.Fatalf("indent: %v", err)
+	}
+	want, err := os.ReadFile(filepath.Join("testdata", "jsonschema", "get_weather.json"))
+	if err != nil {
+		t.Fatalf("read golden: %v", err)
+	}
+	if got.String() != strings.TrimSpace(string(want)) {
+		t.Fatalf("schema does not match testdata/jsonschema/get_weather.json:\n%s", got.String())
+	}
+}
+
+func TestJSONSchemaFromType_InvalidExampleTag(t *testing.T) {
+	type args struct {
+		Days int `json:"days" example:"three"`
+	}
+	_, err := JSONSchemaFromType(args{})
+	if err == nil || !strings.Contains(err.Error(), "Days") {
+		t.Fatalf("expected an error naming the field, got %v", err)
+	}
+}
+
+func TestAddSchemaExamples_AppendsToExisting(t *testing.T) {
+	schema, err := AddSchemaExamples([]byte(`{"type":"object","examples":[{"a":1}]}`), map[string]int{"a": 2})
+	if err != nil {
+		t.Fatalf("AddSchemaExamples error: %v", err)
+	}
+	if string(schema) != `{"examples":[{"a":1},{"a":2}],"type":"object"}` {
+		t.Fatalf("unexpected schema %s", schema)
+	}
+	if _, err := AddSchemaExamples([]byte(`[]`), 1); err == nil {
+		t.Fatalf("expected an error for a non-object schema")
+	}
+}
+
+func TestStrictJSONSchema(t *testing.T) {
+	schema := []byte(`{
+		"type": "object",
+		"properties": {
+			"id": {"type": "integer"},
+			"mood": {"enum": ["happy", "sad"], "type": "string"},
+			"tags": {"type": ["array"], "items": {"type": "object", "properties": {"k": {"type": "string"}}}},
+			"extra": {"anyOf": [{"type": "string"}, {"type": "integer"}]},
+			"labels": {"type": "object", "additionalProperties": {"type": "string"}}
+		},
+		"required": ["id"],
+		"$defs": {"node": {"type": "object", "properties": {"next": {"$ref": "#/$defs/node"}}}}
+	}`)
+	got, err := StrictJSONSchema(schema)
+	if err != nil {
+		t.Fatalf("StrictJSONSchema error: %v", err)
+	}
+	want := `{"$defs":{"node":{"additionalProperties":false,"properties":{"next":{"anyOf":[{"$ref":"#/$defs/node"},{"type":"null"}]}},"required":["next"],"type":"object"}},` +
+		`"additionalProperties":false,"properties":{` +
+		`"extra":{"anyOf":[{"anyOf":[{"type":"string"},{"type":"integer"}]},{"type":"null"}]},` +
+		`"id":{"type":"integer"},` +
+		`"labels":{"additionalProperties":{"type":"string"},"type":["object","null"]},` +
+		`"mood":{"enum":["happy","sad",null],"type":["string","null"]},` +
+		`"tags":{"items":{"additionalProperties":false,"properties":{"k":{"type":["string","null"]}},"required":["k"],"type":"object"},"type":["array","null"]}},` +
+		`"required":["extra","id","labels","mood","tags"],"type":"object"}`
+	if string(got) != want {
+		t.Fatalf("StrictJSONSchema =\n%s\nwant\n%s", got, want)
+	}
+	again, err := StrictJSONSchema(got)
+	if err != nil || string(again) != string(got) {
+		t.Fatalf("StrictJSONSchema is not idempotent:\n%s", again)
+	}
+	if _, err := StrictJSONSchema([]byte(`[]`)); err == nil {
+		t.Fatalf("expected an error for a non-object schema")
+	}
+}
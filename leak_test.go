@@ -0,0 +1,121 @@
+package ai
+
+import (
+	"context"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ncecere/ai-sdk/aitest"
+	"github.com/ncecere/ai-sdk/middleware"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// leakOps exercises one SDK entry point against model until it fails
+// or ctx is cancelled.
+var leakOps = []struct {
+	name string
+	run  func(ctx context.Context, model LanguageModel)
+}{
+	{"generate", func(ctx context.Context, model LanguageModel) {
+		GenerateText(ctx, GenerateTextRequest{Model: model, Messages: []Message{UserMessage("hi")}})
+	}},
+	{"stream", func(ctx context.Context, model LanguageModel) {
+		stream, err := StreamText(ctx, GenerateTextRequest{Model: model, Messages: []Message{UserMessage("hi")}})
+		if err != nil {
+			return
+		}
+		defer stream.Close()
+		for {
+			delta, err := stream.Next(ctx)
+			if err != nil || delta.Done {
+				return
+			}
+		}
+	}},
+	{"stream-abandoned", func(ctx context.Context, model LanguageModel) {
+		// The stream outlives the call's cancellation and is never
+		// closed, like a handler that returns early and forgets it.
+		stream, err := StreamText(context.WithoutCancel(ctx), GenerateTextRequest{Model: model, Messages: []Message{UserMessage("hi")}})
+		if err != nil {
+			return
+		}
+		stream.Next(ctx)
+	}},
+	{"sse", func(ctx context.Context, model LanguageModel) {
+		stream, err := StreamText(ctx, GenerateTextRequest{Model: model, Messages: []Message{UserMessage("hi")}})
+		if err != nil {
+			return
+		}
+		WriteTextStreamAsSSE(ctx, httptest.NewRecorder(), stream)
+	}},
+}
+
+// leakStacks wraps a model in each middleware stack under test.
+var leakStacks = []struct {
+	name string
+	wrap func(LanguageModel) LanguageModel
+}{
+	{"bare", func(m LanguageModel) LanguageModel { return m }},
+	{"middleware", func(m LanguageModel) LanguageModel {
+		return middleware.WrapLanguageModel(m,
+			middleware.LoggingLanguageModel(middleware.LoggingOptions{Logger: log.New(io.Discard, "", 0)}),
+			middleware.RetryLanguageModel(middleware.RetryOptions{MaxAttempts: 2, InitialBackoff: time.Millisecond, ShouldRetry: func(error) bool { return true }}),
+			middleware.TelemetryLanguageModel(middleware.TelemetryHooks{}),
+		)
+	}},
+}
+
+func TestLeaks_StreamErrorStatus(t *testing.T) {
+	aitest.VerifyNoLeaks(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"overloaded"}}`, http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(srv.Close)
+
+	for _, p := range streamProviders {
+		model, err := p.newModel(provider.ClientOptions{BaseURL: srv.URL, APIKey: "test", HTTPClient: srv.Client()})
+		if err != nil {
+			t.Fatalf("NewClient error: %v", err)
+		}
+		_, err = StreamText(context.Background(), GenerateTextRequest{Model: model, Messages: []Message{UserMessage("hi")}})
+		if err == nil || !strings.Contains(err.Error(), "503") {
+			t.Fatalf("%s: expected the status error, got %v", p.name, err)
+		}
+	}
+}
+
+func TestLeaks_ModelCallsUnderFaults(t *testing.T) {
+	aitest.VerifyNoLeaks(t)
+	seed := uint64(time.Now().UnixNano())
+	t.Logf("cancellation seed %d", seed)
+	rng := rand.New(rand.NewPCG(seed, seed))
+
+	for _, p := range streamProviders {
+		for _, fault := range aitest.Faults {
+			for _, op := range leakOps {
+				t.Run(p.name+"/"+fault.String()+"/"+op.name, func(t *testing.T) {
+					contentType, body := "text/event-stream", p.chunk("hel")+p.chunk("lo")+p.done
+					if op.name == "generate" {
+						contentType, body = "application/json", p.generate
+					}
+					srv := aitest.NewFaultServer(t, fault, contentType, body)
+					base, err := p.newModel(provider.ClientOptions{BaseURL: srv.URL, APIKey: "test", HTTPClient: srv.Client()})
+					if err != nil {
+						t.Fatalf("NewClient error: %v", err)
+					}
+					for _, stack := range leakStacks {
+						ctx, cancel := aitest.CancelAtRandom(context.Background(), rng, 20*time.Millisecond)
+						op.run(ctx, stack.wrap(base))
+						cancel()
+					}
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package ai
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/openai"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+func TestGenerateText_Logprobs(t *testing.T) {
+	var bodies []map[string]any
+	ts := fixtureServer(t, "logprobs/openai.json", &bodies)
+	defer ts.Close()
+
+	client, err := openai.NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	top := 2
+	res, err := GenerateText(context.Background(), GenerateTextRequest{
+		Model:       client.ChatModel("gpt-4o-mini"),
+		Messages:    []Message{UserMessage("Is the sky blue? Answer yes or no.")},
+		TopLogprobs: &top,
+	})
+	if err != nil {
+		t.Fatalf("GenerateText error: %v", err)
+	}
+
+	if bodies[0]["logprobs"] != true || bodies[0]["top_logprobs"] != float64(2) {
+		t.Fatalf("expected logprobs and top_logprobs in the body, got %v", bodies[0])
+	}
+	want := []TokenLogprob{
+		{Token: "Yes", Logprob: -0.0012, TopAlternatives: []TokenAlternative{{Token: "Yes", Logprob: -0.0012}, {Token: "No", Logprob: -6.75}}},
+		{Token: ".", Logprob: -0.25, TopAlternatives: []TokenAlternative{{Token: ".", Logprob: -0.25}, {Token: "!", Logprob: -1.5}}},
+	}
+	if !reflect.DeepEqual(res.Logprobs, want) {
+		t.Fatalf("unexpected logprobs:\n got %+v\nwant %+v", res.Logprobs, want)
+	}
+}
+
+func TestGenerateText_LogprobsOmittedByDefault(t *testing.T) {
+	var bodies []map[string]any
+	ts := fixtureServer(t, "logprobs/openai.json", &bodies)
+	defer ts.Close()
+
+	client, err := openai.NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	if _, err := GenerateText(context.Background(), GenerateTextRequest{
+		Model:    client.ChatModel("gpt-4o-mini"),
+		Messages: []Message{UserMessage("Is the sky blue?")},
+	}); err != nil {
+		t.Fatalf("GenerateText error: %v", err)
+	}
+	for _, key := range []string{"logprobs", "top_logprobs"} {
+		if v, ok := bodies[0][key]; ok {
+			t.Fatalf("expected %s to be omitted, got %v", key, v)
+		}
+	}
+}
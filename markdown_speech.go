@@ -0,0 +1,347 @@
+package ai
+
+import (
+	"bytes"
+	"strings"
+)
+
+// codeOmitted is spoken in place of a fenced code block.
+const codeOmitted = "code omitted"
+
+// MarkdownToSpeechText rewrites markdown as plain text for speech
+// synthesis. It is the one-shot form of NewMarkdownSpeechTransform and
+// returns the same text.
+func MarkdownToSpeechText(text string) string {
+	t := NewMarkdownSpeechTransform()
+	return t.Transform(text) + t.Flush()
+}
+
+// NewMarkdownSpeechTransform returns a TextTransform that rewrites
+// markdown as plain text for speech synthesis, for example when
+// streamed text is passed on to GenerateSpeech:
+//   - Fenced code blocks become "code omitted".
+//   - Links and images read their text; the URL is dropped.
+//   - Emphasis, strikethrough, and inline code markers are dropped.
+//     Intraword underscores, as in snake_case, and a '*' between
+//     spaces are kept.
+//   - Heading, block quote, and bullet markers and horizontal rules
+//     are dropped.
+//
+// The output does not depend on how the input is split: a marker split
+// across deltas is held back until the text after it decides what it
+// is. Use it with TransformStream:
+//
+//	stream = ai.TransformStream(stream, ai.NewMarkdownSpeechTransform())
+func NewMarkdownSpeechTransform() TextTransform {
+	return &markdownSpeech{lineStart: true, prev: '\n'}
+}
+
+// markdownSpeech is an incremental markdown parser. Input is appended
+// to buf and consumed by step, which waits for more input rather than
+// decide on a partial construct, so any split gives the same output.
+type markdownSpeech struct {
+	buf []byte
+	out []byte
+
+	lineStart bool   // at the start of a line
+	fence     string // the opening fence while in a code block
+	codeSpan  int    // backtick count of the open inline code span
+	link      int    // open link text brackets on this line
+	url       int    // open parentheses of a skipped link destination
+	prev      byte   // the last input byte consumed outside code blocks
+}
+
+func (m *markdownSpeech) Transform(text string) string {
+	m.buf = append(m.buf, text...)
+	return m.drain(false)
+}
+
+func (m *markdownSpeech) Flush() string {
+	return m.drain(true)
+}
+
+// drain consumes buf as far as it can be decided and returns the
+// output. With eof set, the end of buf is the end of the input.
+func (m *markdownSpeech) drain(eof bool) string {
+	for len(m.buf) > 0 {
+		n, ok := m.step(eof)
+		if !ok {
+			break
+		}
+		m.buf = m.buf[n:]
+	}
+	out := string(m.out)
+	m.out = m.out[:0]
+	return out
+}
+
+// step consumes the next construct in buf and returns the number of
+// bytes consumed, which is zero when only the state changed. ok is
+// false if more input is needed to decide.
+func (m *markdownSpeech) step(eof bool) (n int, ok bool) {
+	switch {
+	case m.fence != "":
+		return m.stepFence(eof)
+	case m.lineStart:
+		return m.stepLineStart(eof)
+	case m.url > 0:
+		return m.stepURL(), true
+	case m.codeSpan > 0:
+		return m.stepCodeSpan(eof)
+	}
+	return m.stepInline(eof)
+}
+
+// stepFence drops the lines of a code block up to its closing fence.
+// The code block's text was already replaced when it opened.
+func (m *markdownSpeech) stepFence(eof bool) (int, bool) {
+	i := bytes.IndexByte(m.buf, '\n')
+	if !m.lineStart {
+		if i < 0 {
+			return len(m.buf), true
+		}
+		m.lineStart = true
+		return i + 1, true
+	}
+	line := m.buf
+	if i >= 0 {
+		line = m.buf[:i]
+	} else if !eof {
+		if closesFence(line, m.fence, true) {
+			return 0, false
+		}
+		m.lineStart = false
+		return 0, true
+	}
+	if closesFence(line, m.fence, false) {
+		m.fence = ""
+		m.prev = '\n'
+		if i >= 0 {
+			m.out = append(m.out, '\n')
+		}
+	}
+	if i < 0 {
+		return len(line), true
+	}
+	return i + 1, true
+}
+
+// closesFence reports whether line closes a code block opened by
+// fence. With partial set, line is the start of a line, and it reports
+// whether the complete line still could.
+func closesFence(line []byte, fence string, partial bool) bool {
+	rest := bytes.TrimLeft(line, " \t")
+	run := markerRun(rest, fence[0])
+	rest = rest[run:]
+	if partial && len(rest) == 0 {
+		return true
+	}
+	return run >= len(fence) && len(bytes.Trim(rest, " \t\r")) == 0
+}
+
+// linePrefixBytes can make up the block markers at the start of a line.
+const linePrefixBytes = " \t#>*+-_=`~0123456789.)"
+
+// stepLineStart drops the block markers at the start of a line: code
+// fences, horizontal rules, block quotes, headings, and bullets. What
+// remains of the line is inline text.
+func (m *markdownSpeech) stepLineStart(eof bool) (int, bool) {
+	j := 0
+	for j < len(m.buf) && strings.IndexByte(linePrefixBytes, m.buf[j]) >= 0 {
+		j++
+	}
+	if j == len(m.buf) && !eof {
+		return 0, false
+	}
+	prefix := m.buf[:j]
+	endOfLine := j == len(m.buf) || m.buf[j] == '\n' || m.buf[j] == '\r'
+	m.lineStart = false
+
+	indent := len(prefix) - len(bytes.TrimLeft(prefix, " \t"))
+	t := prefix[indent:]
+	if len(t) > 0 && (t[0] == '`' || t[0] == '~') && markerRun(t, t[0]) >= 3 {
+		m.fence = string(t[:markerRun(t, t[0])])
+		m.out = append(m.out, codeOmitted...)
+		return j, true
+	}
+	if endOfLine && isRule(t) {
+		return j, true
+	}
+
+	i := indent
+	for i < len(prefix) && prefix[i] == '>' {
+		i++
+		i += len(prefix[i:]) - len(bytes.TrimLeft(prefix[i:], " \t"))
+	}
+	t = prefix[i:]
+	if h := markerRun(t, '#'); h >= 1 && h <= 6 && (h < len(t) && isBlank(t[h]) || h == len(t) && endOfLine) {
+		i += len(t) - len(bytes.TrimLeft(t[h:], " \t"))
+	} else if len(t) >= 2 && strings.IndexByte("-*+", t[0]) >= 0 && isBlank(t[1]) {
+		i += len(t) - len(bytes.TrimLeft(t[1:], " \t"))
+	}
+	return i, true
+}
+
+// isRule reports whether line is a horizontal rule or a setext heading
+// underline: three or more of one of "-*_=", optionally spaced.
+func isRule(line []byte) bool {
+	marks := bytes.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' {
+			return -1
+		}
+		return r
+	}, line)
+	return len(marks) >= 3 && strings.IndexByte("-*_=", marks[0]) >= 0 && markerRun(marks, marks[0]) == len(marks)
+}
+
+// stepInline handles inline markup: emphasis, code spans, links,
+// images, and backslash escapes.
+func (m *markdownSpeech) stepInline(eof bool) (int, bool) {
+	b := m.buf[0]
+	switch b {
+	case '\n':
+		m.out = append(m.out, b)
+		m.lineStart, m.link, m.prev = true, 0, b
+		return 1, true
+	case '\\':
+		if len(m.buf) < 2 && !eof {
+			return 0, false
+		}
+		if len(m.buf) >= 2 && isASCIIPunct(m.buf[1]) {
+			m.out = append(m.out, m.buf[1])
+			m.prev = m.buf[1]
+			return 2, true
+		}
+	case '`', '*', '_', '~':
+		r := markerRun(m.buf, b)
+		if r == len(m.buf) && !eof {
+			return 0, false
+		}
+		next := byte(' ')
+		if r < len(m.buf) {
+			next = m.buf[r]
+		}
+		if b == '`' {
+			m.codeSpan = r
+		} else if keepMarker(b, r, m.prev, next) {
+			m.out = append(m.out, m.buf[:r]...)
+		}
+		m.prev = b
+		return r, true
+	case '!':
+		if len(m.buf) < 2 && !eof {
+			return 0, false
+		}
+		if len(m.buf) >= 2 && m.buf[1] == '[' {
+			return 1, true
+		}
+	case '[':
+		m.link++
+		m.prev = b
+		return 1, true
+	case ']':
+		if m.link == 0 {
+			break
+		}
+		if len(m.buf) < 2 && !eof {
+			return 0, false
+		}
+		m.link--
+		m.prev = b
+		if len(m.buf) >= 2 && m.buf[1] == '(' {
+			m.url = 1
+			return 2, true
+		}
+		return 1, true
+	}
+
+	n := 1
+	for n < len(m.buf) && !strings.ContainsRune("\n\\`*_~![]", rune(m.buf[n])) {
+		n++
+	}
+	m.out = append(m.out, m.buf[:n]...)
+	m.prev = m.buf[n-1]
+	return n, true
+}
+
+// keepMarker reports whether a run of r marker bytes c between prev
+// and next is literal text rather than emphasis or strikethrough.
+func keepMarker(c byte, r int, prev, next byte) bool {
+	switch c {
+	case '~':
+		return r != 2
+	case '_':
+		return isWordByte(prev) && isWordByte(next)
+	}
+	return isBlank(prev) && isBlank(next)
+}
+
+// stepCodeSpan emits the text of an inline code span. A span left
+// open ends with its line, so a stray backtick cannot swallow the rest
+// of the text.
+func (m *markdownSpeech) stepCodeSpan(eof bool) (int, bool) {
+	switch m.buf[0] {
+	case '\n':
+		m.codeSpan = 0
+		return 0, true
+	case '`':
+		r := markerRun(m.buf, '`')
+		if r == len(m.buf) && !eof {
+			return 0, false
+		}
+		if r == m.codeSpan {
+			m.codeSpan = 0
+		}
+		m.prev = '`'
+		return r, true
+	}
+	n := 1
+	for n < len(m.buf) && m.buf[n] != '\n' && m.buf[n] != '`' {
+		n++
+	}
+	m.out = append(m.out, m.buf[:n]...)
+	m.prev = m.buf[n-1]
+	return n, true
+}
+
+// stepURL drops a link destination up to its closing parenthesis or
+// the end of the line.
+func (m *markdownSpeech) stepURL() int {
+	for i, b := range m.buf {
+		switch b {
+		case '(':
+			m.url++
+		case ')':
+			if m.url--; m.url == 0 {
+				return i + 1
+			}
+		case '\n':
+			m.url = 0
+			return i
+		}
+	}
+	return len(m.buf)
+}
+
+// markerRun returns the number of leading c bytes in b.
+func markerRun(b []byte, c byte) int {
+	n := 0
+	for n < len(b) && b[n] == c {
+		n++
+	}
+	return n
+}
+
+func isBlank(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+// isWordByte reports whether b is part of a word. Bytes of multi-byte
+// characters count as word bytes.
+func isWordByte(b byte) bool {
+	return b >= 0x80 || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+func isASCIIPunct(b byte) bool {
+	return b >= '!' && b <= '/' || b >= ':' && b <= '@' || b >= '[' && b <= '`' || b >= '{' && b <= '~'
+}
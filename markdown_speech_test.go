@@ -0,0 +1,113 @@
+package ai
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestMarkdownToSpeechText(t *testing.T) {
+	cases := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"plain", "Hello, world.", "Hello, world."},
+		{"emphasis", "This is **very** _important_ and ~~wrong~~.", "This is very important and wrong."},
+		{"kept markers", "Use snake_case, 2 * 3 and ~5 minutes.", "Use snake_case, 2 * 3 and ~5 minutes."},
+		{"inline code", "Run `go test` now, or ``a`b``.", "Run go test now, or ab."},
+		{"code span markers are literal", "`**x**`", "**x**"},
+		{"link", "See [the docs](https://example.com/a_(b)) today.", "See the docs today."},
+		{"image", "![a chart](chart.png)!", "a chart!"},
+		{"brackets without link", "Pick [one] or a]b.", "Pick one or a]b."},
+		{"escapes", `\*not emphasis\* \_x\_ C:\dir`, `*not emphasis* _x_ C:\dir`},
+		{"headings", "# Title\n## Sub heading ##\n#hashtag", "Title\nSub heading ##\n#hashtag"},
+		{"lists", "- one\n* **two**\n+ three\n1. four", "one\ntwo\nthree\n1. four"},
+		{"quote", "> > quoted *text*", "quoted text"},
+		{"rules", "a\n---\n* * *\nb\n===", "a\n\n\nb\n"},
+		{"fence", "Here:\n```go\nfmt.Println(\"*x*\")\n```\nDone.", "Here:\ncode omitted\nDone."},
+		{"indented tilde fence", "  ~~~~\n  x\n  ~~~\n  ~~~~\nafter", "code omitted\nafter"},
+		{"unclosed fence", "Text\n```\ncode", "Text\ncode omitted"},
+		{"unclosed code span ends with line", "a `b\n# c", "a b\nc"},
+		{"numbers", "2024 was 1.5x better", "2024 was 1.5x better"},
+		{"unicode", "**Café** _naïve_ über_alles", "Café naïve über_alles"},
+	}
+	for _, tc := range cases {
+		if got := MarkdownToSpeechText(tc.text); got != tc.want {
+			t.Errorf("%s: MarkdownToSpeechText(%q) = %q, want %q", tc.name, tc.text, got, tc.want)
+		}
+	}
+}
+
+// speechDocs are split in every way by the streaming tests.
+var speechDocs = []string{
+	"# Report\n\nThe **build** is _green_; see [logs](https://ci.example.com/run(1)).\n\n```sh\ngo test ./...\n```\n- done ~~not~~\n> note: `x*y`\n",
+	"Intro ``code ` span`` and \\*escaped\\* text.\n***\n![img](a.png) 3 * 4 = snake_case_name\n~~~\nfence ``` inside\n~~~~\ntail",
+	"```\nunclosed\n``\n```` \n",
+	"##\n#\n* \n-\n1) x\n  *emph*_x_**\n[a [b]](c) ]] !!\\",
+}
+
+func TestMarkdownSpeechTransform_Splits(t *testing.T) {
+	stream := func(pieces []string) string {
+		m := NewMarkdownSpeechTransform()
+		var sb strings.Builder
+		for _, p := range pieces {
+			sb.WriteString(m.Transform(p))
+		}
+		sb.WriteString(m.Flush())
+		return sb.String()
+	}
+	rng := rand.New(rand.NewSource(1))
+	for _, doc := range speechDocs {
+		want := MarkdownToSpeechText(doc)
+
+		// Every single split point.
+		for i := 0; i <= len(doc); i++ {
+			if got := stream([]string{doc[:i], doc[i:]}); got != want {
+				t.Fatalf("split at %d of %q:\n got %q\nwant %q", i, doc, got, want)
+			}
+		}
+		// Byte by byte.
+		if got := stream(strings.Split(doc, "")); got != want {
+			t.Fatalf("byte by byte %q:\n got %q\nwant %q", doc, got, want)
+		}
+		// Random splits, including empty pieces.
+		for range 200 {
+			var pieces []string
+			for rest := doc; rest != ""; {
+				n := min(rng.Intn(6), len(rest))
+				pieces = append(pieces, rest[:n])
+				rest = rest[n:]
+			}
+			if got := stream(pieces); got != want {
+				t.Fatalf("pieces %q:\n got %q\nwant %q", pieces, got, want)
+			}
+		}
+	}
+}
+
+func TestTransformStream(t *testing.T) {
+	source := &scriptStream{texts: []string{"Say **hel", "lo", "**", " to [Ada](u", "rl)", " x~"}}
+	stream := TransformStream(source, NewMarkdownSpeechTransform())
+	var deltas []string
+	for {
+		delta, err := stream.Next(context.Background())
+		if err != nil {
+			t.Fatalf("Next error: %v", err)
+		}
+		deltas = append(deltas, delta.Text)
+		if delta.Done {
+			break
+		}
+	}
+	// Deltas held back entirely are skipped; the Done delta carries
+	// the held-back text.
+	want := []string{"Say hel", "lo", " to Ada", " x", "~"}
+	if strings.Join(deltas, "|") != strings.Join(want, "|") {
+		t.Fatalf("deltas = %q, want %q", deltas, want)
+	}
+	if err := stream.Close(); err != nil || !source.closed.Load() {
+		t.Fatalf("Close did not close the source: %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// MergedDelta is a single update from a MergedStream.
+type MergedDelta struct {
+	// Source is the key of the stream the update came from. It is empty
+	// on the final delta.
+	Source string
+	// Delta is the source's delta; Delta.Done marks the end of that
+	// source. It is nil when Err is set.
+	Delta *TextDelta
+	// Err is set when the source failed. The source yields nothing
+	// further, and the other sources are unaffected.
+	Err error
+	// Done is true on the final delta, once every source has finished
+	// or failed.
+	Done bool
+}
+
+// MergedStream is an iterator-style stream interleaving the deltas of
+// several TextStreams. Next only returns an error when its ctx is done
+// or the stream was closed; source failures are delivered as deltas.
+type MergedStream interface {
+	Next(ctx context.Context) (*MergedDelta, error)
+	Close() error
+}
+
+// ErrStreamClosed is returned by MergedStream.Next after Close.
+var ErrStreamClosed = errors.New("ai: stream closed")
+
+// MergeStreams interleaves streams, keyed by a caller-chosen name such
+// as the model, into one MergedStream, for example to run a prompt
+// against several models side by side. Deltas are delivered in the
+// order the sources produce them, and each source has at most one
+// delta pending, so a fast source cannot starve a slow one.
+//
+// Each source is closed when it finishes or fails. Closing the merged
+// stream closes every source that is still running.
+func MergeStreams(streams map[string]TextStream) MergedStream {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &mergedStream{
+		deltas:  make(chan *MergedDelta),
+		cancel:  cancel,
+		closed:  make(chan struct{}),
+		pending: len(streams),
+	}
+	m.wg.Add(len(streams))
+	for source, stream := range streams {
+		go m.pump(ctx, source, stream)
+	}
+	return m
+}
+
+type mergedStream struct {
+	deltas chan *MergedDelta
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	closeOnce sync.Once
+	closed    chan struct{}
+
+	// pending counts the sources that have not finished or failed. It
+	// is only accessed by Next.
+	pending int
+}
+
+// pump forwards the deltas of one source until it finishes, fails, or
+// the merged stream is closed.
+func (m *mergedStream) pump(ctx context.Context, source string, stream TextStream) {
+	defer m.wg.Done()
+	defer stream.Close()
+	for {
+		delta, err := stream.Next(ctx)
+		out := &MergedDelta{Source: source, Delta: delta, Err: err}
+		if err != nil {
+			out.Delta = nil
+		}
+		select {
+		case m.deltas <- out:
+		case <-ctx.Done():
+			return
+		}
+		if err != nil || delta.Done {
+			return
+		}
+	}
+}
+
+func (m *mergedStream) Next(ctx context.Context) (*MergedDelta, error) {
+	if m.pending == 0 {
+		select {
+		case <-m.closed:
+			return nil, ErrStreamClosed
+		default:
+			return &MergedDelta{Done: true}, nil
+		}
+	}
+	select {
+	case delta := <-m.deltas:
+		if delta.Err != nil || delta.Delta.Done {
+			m.pending--
+		}
+		return delta, nil
+	case <-m.closed:
+		return nil, ErrStreamClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (m *mergedStream) Close() error {
+	m.closeOnce.Do(func() {
+		close(m.closed)
+		// Canceling ends each source's pending Next; the pumps then
+		// close their sources.
+		m.cancel()
+		m.wg.Wait()
+	})
+	return nil
+}
+
+// MergedStreamEvent is the data of the events WriteMergedStreamToWriter
+// sends. Error events embed the source's StreamErrorEvent.
+type MergedStreamEvent struct {
+	// Model is the key of the source stream.
+	Model     string `json:"model"`
+	Text      string `json:"text,omitempty"`
+	Reasoning string `json:"reasoning,omitempty"`
+	*StreamErrorEvent
+}
+
+// WriteMergedStreamAsSSE writes a MergedStream to an
+// http.ResponseWriter using the Server-Sent Events (SSE) format; see
+// WriteMergedStreamToWriter.
+func WriteMergedStreamAsSSE(ctx context.Context, w http.ResponseWriter, stream MergedStream) error {
+	setSSEHeaders(w.Header())
+	return WriteMergedStreamToWriter(ctx, w, stream)
+}
+
+// WriteMergedStreamToWriter writes a MergedStream to w without touching
+// any headers. Every event's data is a MergedStreamEvent naming its
+// source:
+//
+//   - `event: delta` carries text and reasoning.
+//   - `event: done` marks the end of a source.
+//   - `event: error` reports a failed source, with the reason and
+//     message of WriteTextStreamToWriter's error event. The other
+//     sources keep streaming.
+//
+// A final "data: [DONE]" follows once every source has ended. Like
+// WriteTextStreamToWriter, w is flushed after each event and the stream
+// is closed before returning, which closes all sources.
+//
+// Source failures are reported as events, not returned.
+//
+// Errors:
+//   - The context or write error.
+func WriteMergedStreamToWriter(ctx context.Context, w io.Writer, stream MergedStream) error {
+	defer stream.Close()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		delta, err := stream.Next(ctx)
+		if err != nil {
+			return err
+		}
+		if delta.Done {
+			break
+		}
+
+		var werr error
+		if delta.Err != nil {
+			errEv := streamErrorEvent(delta.Err)
+			werr = writeMergedEvent(w, "error", MergedStreamEvent{Model: delta.Source, StreamErrorEvent: &errEv})
+		} else {
+			d := delta.Delta
+			if d.Text != "" || d.Reasoning != "" {
+				werr = writeMergedEvent(w, "delta", MergedStreamEvent{Model: delta.Source, Text: d.Text, Reasoning: d.Reasoning})
+			}
+			if werr == nil && d.Done {
+				werr = writeMergedEvent(w, "done", MergedStreamEvent{Model: delta.Source})
+			}
+		}
+		if werr != nil {
+			return werr
+		}
+	}
+
+	if _, err := fmt.Fprint(w, "data: [DONE]\n\n"); err != nil {
+		return err
+	}
+	return flushStream(w)
+}
+
+// writeMergedEvent sends ev as a named event and flushes w.
+func writeMergedEvent(w io.Writer, event string, ev MergedStreamEvent) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+		return err
+	}
+	return flushStream(w)
+}
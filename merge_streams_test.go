@@ -0,0 +1,184 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// scriptStream yields texts, then a Done delta or err. With forever set
+// it repeats texts without end. Each Next waits delay, honoring ctx.
+type scriptStream struct {
+	texts   []string
+	err     error
+	forever bool
+	delay   time.Duration
+
+	n      int
+	closed atomic.Bool
+}
+
+func (s *scriptStream) Next(ctx context.Context) (*TextDelta, error) {
+	if s.delay > 0 {
+		select {
+		case <-time.After(s.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	} else if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if s.forever {
+		s.n++
+		return &TextDelta{Text: s.texts[s.n%len(s.texts)]}, nil
+	}
+	if s.n < len(s.texts) {
+		s.n++
+		return &TextDelta{Text: s.texts[s.n-1]}, nil
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &TextDelta{Done: true}, nil
+}
+
+func (s *scriptStream) Close() error {
+	s.closed.Store(true)
+	return nil
+}
+
+func TestMergeStreams_SSE(t *testing.T) {
+	failed := &IncompleteStreamError{Provider: "openai", Err: io.ErrUnexpectedEOF}
+	sources := map[string]*scriptStream{
+		"a": {texts: []string{"Hello", " A"}},
+		"b": {texts: []string{"Hi", " B"}, delay: time.Millisecond},
+		"c": {texts: []string{"Hey"}, err: failed},
+	}
+	streams := map[string]TextStream{}
+	for k, s := range sources {
+		streams[k] = s
+	}
+
+	rec := httptest.NewRecorder()
+	if err := WriteMergedStreamAsSSE(context.Background(), rec, MergeStreams(streams)); err != nil {
+		t.Fatalf("WriteMergedStreamAsSSE error: %v", err)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected Content-Type %q", ct)
+	}
+
+	body := rec.Body.String()
+	if !strings.HasSuffix(body, "data: [DONE]\n\n") {
+		t.Fatalf("expected a final [DONE]:\n%s", body)
+	}
+	text := map[string]string{}
+	ended := map[string]string{}
+	for _, block := range strings.Split(strings.TrimSuffix(body, "data: [DONE]\n\n"), "\n\n") {
+		if block == "" {
+			continue
+		}
+		event, data, ok := strings.Cut(block, "\ndata: ")
+		if !ok {
+			t.Fatalf("malformed event %q", block)
+		}
+		var ev MergedStreamEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			t.Fatalf("decode %q: %v", data, err)
+		}
+		if prev, ok := ended[ev.Model]; ok {
+			t.Fatalf("%s after %s for %q", event, prev, ev.Model)
+		}
+		switch event {
+		case "event: delta":
+			text[ev.Model] += ev.Text
+		case "event: done":
+			ended[ev.Model] = "done"
+		case "event: error":
+			if ev.StreamErrorEvent == nil || ev.Reason != StreamErrorInterrupted || ev.Message != failed.Error() {
+				t.Fatalf("unexpected error event %s", data)
+			}
+			ended[ev.Model] = "error"
+		default:
+			t.Fatalf("unexpected event %q", event)
+		}
+	}
+
+	wantText := map[string]string{"a": "Hello A", "b": "Hi B", "c": "Hey"}
+	wantEnded := map[string]string{"a": "done", "b": "done", "c": "error"}
+	for k := range sources {
+		if text[k] != wantText[k] || ended[k] != wantEnded[k] {
+			t.Fatalf("source %q: text %q ended %q, want %q ended %q", k, text[k], ended[k], wantText[k], wantEnded[k])
+		}
+		if !sources[k].closed.Load() {
+			t.Fatalf("source %q was not closed", k)
+		}
+	}
+}
+
+func TestMergeStreams_SlowSourceIsNotStarved(t *testing.T) {
+	fast := &scriptStream{texts: []string{"x"}, forever: true}
+	slow := &scriptStream{texts: []string{"1", "2", "3"}, delay: time.Millisecond}
+	merged := MergeStreams(map[string]TextStream{"fast": fast, "slow": slow})
+	defer merged.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var slowText string
+	for {
+		delta, err := merged.Next(ctx)
+		if err != nil {
+			t.Fatalf("slow source was starved: got %q before %v", slowText, err)
+		}
+		if delta.Source != "slow" {
+			continue
+		}
+		slowText += delta.Delta.Text
+		if delta.Delta.Done {
+			break
+		}
+	}
+	if slowText != "123" {
+		t.Fatalf("unexpected slow text %q", slowText)
+	}
+}
+
+func TestMergeStreams_CloseClosesSources(t *testing.T) {
+	sources := []*scriptStream{
+		{texts: []string{"x"}, forever: true},
+		{texts: []string{"y"}, forever: true, delay: time.Hour},
+	}
+	merged := MergeStreams(map[string]TextStream{"a": sources[0], "b": sources[1]})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	err := WriteMergedStreamToWriter(ctx, io.Discard, merged)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	for i, s := range sources {
+		if !s.closed.Load() {
+			t.Fatalf("source %d was not closed", i)
+		}
+	}
+	if _, err := merged.Next(context.Background()); !errors.Is(err, ErrStreamClosed) {
+		t.Fatalf("expected ErrStreamClosed after Close, got %v", err)
+	}
+}
+
+func TestMergeStreams_Empty(t *testing.T) {
+	merged := MergeStreams(nil)
+	defer merged.Close()
+	delta, err := merged.Next(context.Background())
+	if err != nil || !delta.Done {
+		t.Fatalf("expected a final delta, got %+v, %v", delta, err)
+	}
+}
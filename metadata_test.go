@@ -0,0 +1,81 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/anthropic"
+	"github.com/ncecere/ai-sdk/openai"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+func TestMessageMetadata_SurvivesSessionStateRoundTrip(t *testing.T) {
+	msg := UserMessage("hi")
+	msg.Metadata = map[string]any{"message_id": "m-1", "sources": []any{"doc-7"}}
+
+	raw, err := json.Marshal(SessionState{Messages: []Message{SystemMessage("sys"), msg}})
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	if strings.Count(string(raw), "Metadata") != 1 {
+		t.Fatalf("expected metadata only on the annotated message: %s", raw)
+	}
+
+	var state SessionState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	restored := RestoreSession(nil, state).Messages()
+	if restored[1].Metadata["message_id"] != "m-1" {
+		t.Fatalf("metadata lost: %+v", restored[1])
+	}
+}
+
+func TestMessageMetadata_StrippedAndForwardedPerProvider(t *testing.T) {
+	first := UserMessage("hi")
+	first.Metadata = map[string]any{"message_id": "m-1", "user_id": "u-1", "secret": "do-not-send"}
+	second := UserMessage("again")
+	second.Metadata = map[string]any{"message_id": "m-2"}
+	req := GenerateTextRequest{
+		Messages:            []Message{first, AssistantMessage("hello"), second},
+		ForwardMetadataKeys: []string{"message_id", MetadataUserID},
+		Metadata:            map[string]string{"app": "demo"},
+	}
+
+	opts := provider.ClientOptions{BaseURL: "https://example.test", APIKey: "test"}
+	oc, _ := openai.NewClient(opts)
+	ac, _ := anthropic.NewClient(opts)
+
+	res, err := DryRunText(context.Background(), oc.ChatModel("gpt-test"), req)
+	if err != nil {
+		t.Fatalf("DryRunText error: %v", err)
+	}
+	var openaiBody struct {
+		Metadata map[string]string `json:"metadata"`
+	}
+	json.Unmarshal(res.Body, &openaiBody)
+	want := map[string]string{"message_id": "m-2", "user_id": "u-1", "app": "demo"}
+	if len(openaiBody.Metadata) != len(want) || openaiBody.Metadata["message_id"] != "m-2" || openaiBody.Metadata["app"] != "demo" {
+		t.Fatalf("unexpected openai metadata: %v", openaiBody.Metadata)
+	}
+	if strings.Contains(string(res.Body), "do-not-send") {
+		t.Fatalf("message metadata leaked to openai: %s", res.Body)
+	}
+
+	res, err = DryRunText(context.Background(), ac.ChatModel("claude-test"), req)
+	if err != nil {
+		t.Fatalf("DryRunText error: %v", err)
+	}
+	var anthropicBody struct {
+		Metadata map[string]string `json:"metadata"`
+	}
+	json.Unmarshal(res.Body, &anthropicBody)
+	if len(anthropicBody.Metadata) != 1 || anthropicBody.Metadata["user_id"] != "u-1" {
+		t.Fatalf("unexpected anthropic metadata: %v", anthropicBody.Metadata)
+	}
+	if strings.Contains(string(res.Body), "m-1") {
+		t.Fatalf("message metadata leaked to anthropic: %s", res.Body)
+	}
+}
@@ -0,0 +1,663 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// BudgetLimits are the daily limits of one budget key. Zero fields are
+// unlimited.
+type BudgetLimits struct {
+	RequestsPerDay int64
+	TokensPerDay   int64
+	// DollarsPerDay is charged using BudgetOptions.Pricing.
+	DollarsPerDay float64
+}
+
+// ModelPrice is the price of a model used to charge dollar budgets.
+type ModelPrice struct {
+	// InputPerMillion is the price of one million input tokens.
+	InputPerMillion float64
+	// OutputPerMillion is the price of one million output tokens.
+	OutputPerMillion float64
+	// CachedInputPerMillion is the price of one million input tokens
+	// read from a prompt cache. Zero charges them as InputPerMillion.
+	CachedInputPerMillion float64
+	// CacheWritePerMillion is the price of one million input tokens
+	// written to a prompt cache. Zero charges them as InputPerMillion.
+	CacheWritePerMillion float64
+	// PerRequest is a fixed price per call.
+	PerRequest float64
+	// PerImage is the price of one generated image.
+	PerImage float64
+	// PerWebSearch is the price of one server-side web search, charged
+	// from Usage.WebSearchRequests (Anthropic bills $10 per 1,000).
+	PerWebSearch float64
+}
+
+// cost returns the price of a call with the given token counts.
+func (p ModelPrice) cost(input, output, images int) float64 {
+	return p.PerRequest + p.PerImage*float64(images) +
+		(p.InputPerMillion*float64(input)+p.OutputPerMillion*float64(output))/1e6
+}
+
+// usageCost returns the price of a call with the reported usage u,
+// charging its cached and cache-write input tokens at their own
+// prices, and its web searches.
+func (p ModelPrice) usageCost(u *provider.Usage) float64 {
+	return p.cost(u.PromptTokens, u.CompletionTokens, 0) - p.cacheSavings(u.Cache) +
+		p.PerWebSearch*float64(u.WebSearchRequests)
+}
+
+// cacheSavings returns what c saved compared with charging every input
+// token at InputPerMillion. Cache writes priced above the input price
+// make it smaller, or negative.
+func (p ModelPrice) cacheSavings(c *provider.CacheUsage) float64 {
+	if c == nil {
+		return 0
+	}
+	read, write := p.CachedInputPerMillion, p.CacheWritePerMillion
+	if read == 0 {
+		read = p.InputPerMillion
+	}
+	if write == 0 {
+		write = p.InputPerMillion
+	}
+	return (float64(c.CachedInputTokens)*(p.InputPerMillion-read) - float64(c.CacheWriteTokens)*(write-p.InputPerMillion)) / 1e6
+}
+
+// BudgetUsage is the usage recorded for a key within one window.
+type BudgetUsage struct {
+	Requests int64
+	Tokens   int64
+	Dollars  float64
+}
+
+func (u BudgetUsage) add(d BudgetUsage) BudgetUsage {
+	return BudgetUsage{Requests: u.Requests + d.Requests, Tokens: u.Tokens + d.Tokens, Dollars: u.Dollars + d.Dollars}
+}
+
+func (u BudgetUsage) sub(d BudgetUsage) BudgetUsage {
+	return BudgetUsage{Requests: u.Requests - d.Requests, Tokens: u.Tokens - d.Tokens, Dollars: u.Dollars - d.Dollars}
+}
+
+// BudgetStore persists budget counters. Keys passed to the store
+// already include the window, so a store only needs atomic increments
+// and expiry. Implementations must be safe for concurrent use.
+type BudgetStore interface {
+	// Usage returns the usage recorded for key. Unknown keys have zero
+	// usage.
+	Usage(ctx context.Context, key string) (BudgetUsage, error)
+	// Add adds delta to the usage of key and returns the new total.
+	// delta is negative when an estimate is reconciled downwards. The
+	// counter may be discarded after expires.
+	Add(ctx context.Context, key string, delta BudgetUsage, expires time.Time) (BudgetUsage, error)
+}
+
+// MemoryBudgetStore is an in-process BudgetStore. Counters are lost on
+// restart and are not shared between processes; use RedisBudgetStore
+// for multi-instance deployments.
+type MemoryBudgetStore struct {
+	mu       sync.Mutex
+	counters map[string]memoryCounter
+}
+
+type memoryCounter struct {
+	usage   BudgetUsage
+	expires time.Time
+}
+
+// NewMemoryBudgetStore returns an empty MemoryBudgetStore.
+func NewMemoryBudgetStore() *MemoryBudgetStore {
+	return &MemoryBudgetStore{counters: make(map[string]memoryCounter)}
+}
+
+// Usage implements BudgetStore.
+func (s *MemoryBudgetStore) Usage(ctx context.Context, key string) (BudgetUsage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.counters[key]
+	if !ok || time.Now().After(c.expires) {
+		return BudgetUsage{}, nil
+	}
+	return c.usage, nil
+}
+
+// Add implements BudgetStore. Expired counters are dropped on write.
+func (s *MemoryBudgetStore) Add(ctx context.Context, key string, delta BudgetUsage, expires time.Time) (BudgetUsage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for k, c := range s.counters {
+		if now.After(c.expires) {
+			delete(s.counters, k)
+		}
+	}
+	c := s.counters[key]
+	c.usage = c.usage.add(delta)
+	c.expires = expires
+	s.counters[key] = c
+	return c.usage, nil
+}
+
+// RedisClient is the subset of a Redis client used by
+// RedisBudgetStore. It is small enough to adapt any Redis library in a
+// few lines, so this package does not depend on one.
+type RedisClient interface {
+	HIncrBy(ctx context.Context, key, field string, incr int64) (int64, error)
+	HIncrByFloat(ctx context.Context, key, field string, incr float64) (float64, error)
+	HGetAll(ctx context.Context, key string) (map[string]string, error)
+	ExpireAt(ctx context.Context, key string, at time.Time) error
+}
+
+// RedisBudgetStore stores counters as Redis hashes with the fields
+// "requests", "tokens", and "dollars". Each field is incremented
+// atomically, so the store can be shared by many server instances.
+type RedisBudgetStore struct {
+	Client RedisClient
+	// Prefix is prepended to every Redis key, e.g. "budget:".
+	Prefix string
+}
+
+// NewRedisBudgetStore returns a RedisBudgetStore using client with keys
+// prefixed by prefix.
+func NewRedisBudgetStore(client RedisClient, prefix string) *RedisBudgetStore {
+	return &RedisBudgetStore{Client: client, Prefix: prefix}
+}
+
+// Usage implements BudgetStore.
+func (s *RedisBudgetStore) Usage(ctx context.Context, key string) (BudgetUsage, error) {
+	fields, err := s.Client.HGetAll(ctx, s.Prefix+key)
+	if err != nil {
+		return BudgetUsage{}, err
+	}
+	var u BudgetUsage
+	if v := fields["requests"]; v != "" {
+		if u.Requests, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return BudgetUsage{}, err
+		}
+	}
+	if v := fields["tokens"]; v != "" {
+		if u.Tokens, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return BudgetUsage{}, err
+		}
+	}
+	if v := fields["dollars"]; v != "" {
+		if u.Dollars, err = strconv.ParseFloat(v, 64); err != nil {
+			return BudgetUsage{}, err
+		}
+	}
+	return u, nil
+}
+
+// Add implements BudgetStore.
+func (s *RedisBudgetStore) Add(ctx context.Context, key string, delta BudgetUsage, expires time.Time) (BudgetUsage, error) {
+	key = s.Prefix + key
+	var u BudgetUsage
+	var err error
+	if u.Requests, err = s.Client.HIncrBy(ctx, key, "requests", delta.Requests); err != nil {
+		return BudgetUsage{}, err
+	}
+	if u.Tokens, err = s.Client.HIncrBy(ctx, key, "tokens", delta.Tokens); err != nil {
+		return BudgetUsage{}, err
+	}
+	if u.Dollars, err = s.Client.HIncrByFloat(ctx, key, "dollars", delta.Dollars); err != nil {
+		return BudgetUsage{}, err
+	}
+	if err := s.Client.ExpireAt(ctx, key, expires); err != nil {
+		return BudgetUsage{}, err
+	}
+	return u, nil
+}
+
+// BudgetOptions configures a Budget.
+type BudgetOptions struct {
+	// Store holds the counters. Defaults to a new MemoryBudgetStore.
+	Store BudgetStore
+	// Default is the limit for keys that are not listed in Limits.
+	Default BudgetLimits
+	// Limits overrides Default for individual keys.
+	Limits map[string]BudgetLimits
+	// Pricing maps model names to prices for DollarsPerDay. Calls to
+	// models without a price cost nothing.
+	Pricing map[string]ModelPrice
+	// Key returns the budget key of a call from its context and the
+	// request's user ID (UserID, or the provider.MetadataUserID metadata
+	// of language-model requests). Calls with an empty key are not
+	// metered. Defaults to the key set with WithBudgetKey, falling back
+	// to userID.
+	Key func(ctx context.Context, userID string) string
+	// EstimateTokens estimates the tokens of a text. Defaults to about
+	// four characters per token.
+	EstimateTokens func(text string) int
+}
+
+type budgetKeyContextKey struct{}
+
+// WithBudgetKey returns a context whose calls are charged to key,
+// overriding the request's user ID. Servers use it to meter per API
+// key rather than per end user.
+func WithBudgetKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, budgetKeyContextKey{}, key)
+}
+
+// BudgetKeyFromContext returns the key set with WithBudgetKey, if any.
+func BudgetKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(budgetKeyContextKey{}).(string)
+	return key
+}
+
+// Budget enforces daily per-key limits on requests, tokens, and dollars
+// across model kinds. Wrap every model that should share the budget
+// with the method for its kind.
+//
+// Each call is charged an estimate before it is sent: input tokens are
+// estimated from the request text and output tokens from MaxTokens when
+// set. A call whose estimate would exceed a limit fails with a
+// *provider.BudgetExceededError without reaching the model. After the
+// call the charge is reconciled with the actual response: the
+// provider's reported Usage when present, with cached input charged at
+// the ModelPrice cache prices, otherwise the estimated tokens of the
+// response text. Streams are reconciled once they finish or are closed,
+// using the usage on the final delta if the provider reported it.
+// Failed calls are refunded. Usage resets at midnight UTC.
+type Budget struct {
+	opts BudgetOptions
+}
+
+// NewBudget returns a Budget configured by opts.
+func NewBudget(opts BudgetOptions) *Budget {
+	if opts.Store == nil {
+		opts.Store = NewMemoryBudgetStore()
+	}
+	if opts.Key == nil {
+		opts.Key = func(ctx context.Context, userID string) string {
+			if key := BudgetKeyFromContext(ctx); key != "" {
+				return key
+			}
+			return userID
+		}
+	}
+	if opts.EstimateTokens == nil {
+		opts.EstimateTokens = func(text string) int { return (utf8.RuneCountInString(text) + 3) / 4 }
+	}
+	return &Budget{opts: opts}
+}
+
+// Usage returns the usage of key in the current window and when the
+// window resets.
+func (b *Budget) Usage(ctx context.Context, key string) (BudgetUsage, time.Time, error) {
+	storeKey, reset := b.window(key)
+	u, err := b.opts.Store.Usage(ctx, storeKey)
+	return u, reset, err
+}
+
+// window returns the store key of key for the current day and the time
+// the day ends.
+func (b *Budget) window(key string) (string, time.Time) {
+	now := time.Now().UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return key + ":" + day.Format(time.DateOnly), day.AddDate(0, 0, 1)
+}
+
+func (b *Budget) limits(key string) BudgetLimits {
+	if l, ok := b.opts.Limits[key]; ok {
+		return l
+	}
+	return b.opts.Default
+}
+
+func (b *Budget) price(name, requestModel string) ModelPrice {
+	if name == "" {
+		name = requestModel
+	}
+	return b.opts.Pricing[name]
+}
+
+// reservation is the estimated charge of an in-flight call.
+type reservation struct {
+	b        *Budget
+	storeKey string
+	expires  time.Time
+	charged  BudgetUsage
+	once     sync.Once
+}
+
+// reserve charges est to the key of the call. It returns a nil
+// reservation for unmetered calls.
+func (b *Budget) reserve(ctx context.Context, userID string, est BudgetUsage) (*reservation, error) {
+	key := b.opts.Key(ctx, userID)
+	if key == "" {
+		return nil, nil
+	}
+	storeKey, reset := b.window(key)
+	// Charging first and refunding on rejection keeps concurrent calls
+	// from all passing a check against the same stale total.
+	total, err := b.opts.Store.Add(ctx, storeKey, est, reset)
+	if err != nil {
+		return nil, err
+	}
+	if err := exceeded(key, b.limits(key), total, est, reset); err != nil {
+		b.opts.Store.Add(context.WithoutCancel(ctx), storeKey, BudgetUsage{}.sub(est), reset)
+		return nil, err
+	}
+	return &reservation{b: b, storeKey: storeKey, expires: reset, charged: est}, nil
+}
+
+// exceeded reports the first limit that total exceeds among the
+// resources est uses.
+func exceeded(key string, l BudgetLimits, total, est BudgetUsage, reset time.Time) error {
+	check := func(resource string, limit, total, est float64) error {
+		if limit <= 0 || est <= 0 || total <= limit {
+			return nil
+		}
+		return &provider.BudgetExceededError{Key: key, Resource: resource, Limit: limit, Used: total - est, Requested: est, ResetAt: reset}
+	}
+	if err := check("requests", float64(l.RequestsPerDay), float64(total.Requests), float64(est.Requests)); err != nil {
+		return err
+	}
+	if err := check("tokens", float64(l.TokensPerDay), float64(total.Tokens), float64(est.Tokens)); err != nil {
+		return err
+	}
+	return check("dollars", l.DollarsPerDay, total.Dollars, est.Dollars)
+}
+
+// settle replaces the reserved charge with actual. Only the first call
+// has an effect. Store errors are dropped, leaving the estimate
+// charged.
+func (r *reservation) settle(ctx context.Context, actual BudgetUsage) {
+	if r == nil {
+		return
+	}
+	r.once.Do(func() {
+		delta := actual.sub(r.charged)
+		if delta != (BudgetUsage{}) {
+			r.b.opts.Store.Add(context.WithoutCancel(ctx), r.storeKey, delta, r.expires)
+		}
+	})
+}
+
+// refund removes the reserved charge of a failed call.
+func (r *reservation) refund(ctx context.Context) {
+	r.settle(ctx, BudgetUsage{})
+}
+
+// usage returns the charge of one call.
+func (b *Budget) usage(price ModelPrice, input, output, images int) BudgetUsage {
+	return BudgetUsage{Requests: 1, Tokens: int64(input + output), Dollars: price.cost(input, output, images)}
+}
+
+// reportedUsage returns the charge of a language-model call from the
+// usage the provider reported.
+func (b *Budget) reportedUsage(price ModelPrice, u *provider.Usage) BudgetUsage {
+	return BudgetUsage{Requests: 1, Tokens: int64(u.PromptTokens + u.CompletionTokens), Dollars: price.usageCost(u)}
+}
+
+// LanguageModel wraps next with the budget. name is the Pricing entry
+// of the model; if empty, the request's Model is used. The user ID of
+// a request is its provider.MetadataUserID metadata.
+func (b *Budget) LanguageModel(name string, next provider.LanguageModel) provider.LanguageModel {
+	return &budgetLanguageModel{b: b, name: name, next: next}
+}
+
+// Middleware returns b.LanguageModel as a LanguageModelMiddleware.
+func (b *Budget) Middleware(name string) LanguageModelMiddleware {
+	return func(next provider.LanguageModel) provider.LanguageModel {
+		return b.LanguageModel(name, next)
+	}
+}
+
+type budgetLanguageModel struct {
+	b    *Budget
+	name string
+	next provider.LanguageModel
+}
+
+// estimate returns the estimated input and output tokens of req.
+func (m *budgetLanguageModel) estimate(req *provider.LanguageModelRequest) (input, output int) {
+	est := m.b.opts.EstimateTokens
+	const perMessageOverhead = 4
+	for _, msg := range req.Messages {
+		input += perMessageOverhead + est(msg.Content)
+	}
+	for _, t := range req.Tools {
+		input += est(t.Name) + est(t.Description) + est(string(t.Parameters))
+	}
+	if req.MaxTokens != nil {
+		output = *req.MaxTokens
+	}
+	return input, output
+}
+
+func (m *budgetLanguageModel) Generate(ctx context.Context, req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
+	price := m.b.price(m.name, req.Model)
+	input, output := m.estimate(req)
+	r, err := m.b.reserve(ctx, req.Metadata[provider.MetadataUserID], m.b.usage(price, input, output, 0))
+	if err != nil {
+		return nil, err
+	}
+	res, err := m.next.Generate(ctx, req)
+	if err != nil {
+		r.refund(ctx)
+		return nil, err
+	}
+	if u := res.Usage; u != nil {
+		r.settle(ctx, m.b.reportedUsage(price, u))
+		return res, nil
+	}
+	var out strings.Builder
+	out.WriteString(res.Text)
+	out.WriteString(res.Reasoning)
+	for _, tc := range res.ToolCalls {
+		out.WriteString(tc.Name)
+		out.Write(tc.RawArguments)
+	}
+	r.settle(ctx, m.b.usage(price, input, m.b.opts.EstimateTokens(out.String()), 0))
+	return res, nil
+}
+
+func (m *budgetLanguageModel) Stream(ctx context.Context, req *provider.LanguageModelRequest) (provider.LanguageModelStream, error) {
+	price := m.b.price(m.name, req.Model)
+	input, output := m.estimate(req)
+	r, err := m.b.reserve(ctx, req.Metadata[provider.MetadataUserID], m.b.usage(price, input, output, 0))
+	if err != nil {
+		return nil, err
+	}
+	stream, err := m.next.Stream(ctx, req)
+	if err != nil {
+		r.refund(ctx)
+		return nil, err
+	}
+	return &budgetStream{LanguageModelStream: stream, ctx: ctx, b: m.b, r: r, price: price, input: input}, nil
+}
+
+// budgetStream reconciles the reservation of a stream with the output
+// seen once the stream ends, fails, or is closed.
+type budgetStream struct {
+	provider.LanguageModelStream
+	ctx   context.Context
+	b     *Budget
+	r     *reservation
+	price ModelPrice
+	input int
+	out   strings.Builder
+}
+
+func (s *budgetStream) Next(ctx context.Context) (*provider.LanguageModelDelta, error) {
+	d, err := s.LanguageModelStream.Next(ctx)
+	if err != nil {
+		s.settle()
+		return d, err
+	}
+	s.out.WriteString(d.Text)
+	s.out.WriteString(d.Reasoning)
+	for _, tc := range d.ToolCalls {
+		s.out.WriteString(tc.Name)
+		s.out.Write(tc.RawArguments)
+	}
+	if d.Done {
+		if d.Usage != nil {
+			s.r.settle(s.ctx, s.b.reportedUsage(s.price, d.Usage))
+		}
+		s.settle()
+	}
+	return d, nil
+}
+
+func (s *budgetStream) Close() error {
+	s.settle()
+	return s.LanguageModelStream.Close()
+}
+
+func (s *budgetStream) settle() {
+	s.r.settle(s.ctx, s.b.usage(s.price, s.input, s.b.opts.EstimateTokens(s.out.String()), 0))
+}
+
+// EmbeddingModel wraps next with the budget, charging the input
+// tokens. name is the Pricing entry of the model; if empty, the
+// request's Model is used.
+func (b *Budget) EmbeddingModel(name string, next provider.EmbeddingModel) provider.EmbeddingModel {
+	return budgetEmbeddingModel{b: b, name: name, next: next}
+}
+
+type budgetEmbeddingModel struct {
+	b    *Budget
+	name string
+	next provider.EmbeddingModel
+}
+
+func (m budgetEmbeddingModel) Generate(ctx context.Context, req *provider.EmbeddingRequest) (*provider.EmbeddingResponse, error) {
+	input := 0
+	for _, s := range req.Input {
+		input += m.b.opts.EstimateTokens(s)
+	}
+	r, err := m.b.reserve(ctx, req.UserID, m.b.usage(m.b.price(m.name, req.Model), input, 0, 0))
+	if err != nil {
+		return nil, err
+	}
+	res, err := m.next.Generate(ctx, req)
+	if err != nil {
+		r.refund(ctx)
+	}
+	return res, err
+}
+
+// ImageModel wraps next with the budget, charging the prompt tokens and
+// PerImage for each image. The estimate assumes NumberOfImages (at
+// least one) and is reconciled with the images returned. name is the
+// Pricing entry of the model; if empty, the request's Model is used.
+func (b *Budget) ImageModel(name string, next provider.ImageModel) provider.ImageModel {
+	return budgetImageModel{b: b, name: name, next: next}
+}
+
+type budgetImageModel struct {
+	b    *Budget
+	name string
+	next provider.ImageModel
+}
+
+func (m budgetImageModel) Generate(ctx context.Context, req *provider.ImageRequest) (*provider.ImageResponse, error) {
+	price := m.b.price(m.name, req.Model)
+	input := m.b.opts.EstimateTokens(req.Prompt)
+	r, err := m.b.reserve(ctx, req.UserID, m.b.usage(price, input, 0, max(req.NumberOfImages, 1)))
+	if err != nil {
+		return nil, err
+	}
+	res, err := m.next.Generate(ctx, req)
+	if err != nil {
+		r.refund(ctx)
+		return nil, err
+	}
+	r.settle(ctx, m.b.usage(price, input, 0, len(res.Images)))
+	return res, nil
+}
+
+// SpeechModel wraps next with the budget, charging the input text as
+// input tokens. name is the Pricing entry of the model; if empty, the
+// request's Model is used.
+func (b *Budget) SpeechModel(name string, next provider.SpeechModel) provider.SpeechModel {
+	return budgetSpeechModel{b: b, name: name, next: next}
+}
+
+type budgetSpeechModel struct {
+	b    *Budget
+	name string
+	next provider.SpeechModel
+}
+
+func (m budgetSpeechModel) Generate(ctx context.Context, req *provider.SpeechRequest) (*provider.SpeechResponse, error) {
+	input := m.b.opts.EstimateTokens(req.Input)
+	r, err := m.b.reserve(ctx, req.UserID, m.b.usage(m.b.price(m.name, req.Model), input, 0, 0))
+	if err != nil {
+		return nil, err
+	}
+	res, err := m.next.Generate(ctx, req)
+	if err != nil {
+		r.refund(ctx)
+	}
+	return res, err
+}
+
+// TranscriptionModel wraps next with the budget. Only the request is
+// charged up front; the transcript is reconciled as output tokens.
+// name is the Pricing entry of the model; if empty, the request's Model
+// is used.
+func (b *Budget) TranscriptionModel(name string, next provider.TranscriptionModel) provider.TranscriptionModel {
+	return budgetTranscriptionModel{b: b, name: name, next: next}
+}
+
+type budgetTranscriptionModel struct {
+	b    *Budget
+	name string
+	next provider.TranscriptionModel
+}
+
+func (m budgetTranscriptionModel) Generate(ctx context.Context, req *provider.TranscriptionRequest) (*provider.TranscriptionResponse, error) {
+	price := m.b.price(m.name, req.Model)
+	r, err := m.b.reserve(ctx, req.UserID, m.b.usage(price, 0, 0, 0))
+	if err != nil {
+		return nil, err
+	}
+	res, err := m.next.Generate(ctx, req)
+	if err != nil {
+		r.refund(ctx)
+		return nil, err
+	}
+	r.settle(ctx, m.b.usage(price, 0, m.b.opts.EstimateTokens(res.Text), 0))
+	return res, nil
+}
+
+// RerankModel wraps next with the budget, charging the query and
+// documents as input tokens. name is the Pricing entry of the model; if
+// empty, the request's Model is used.
+func (b *Budget) RerankModel(name string, next provider.RerankModel) provider.RerankModel {
+	return budgetRerankModel{b: b, name: name, next: next}
+}
+
+type budgetRerankModel struct {
+	b    *Budget
+	name string
+	next provider.RerankModel
+}
+
+func (m budgetRerankModel) Generate(ctx context.Context, req *provider.RerankRequest) (*provider.RerankResponse, error) {
+	input := m.b.opts.EstimateTokens(req.Query)
+	for _, d := range req.Documents {
+		input += m.b.opts.EstimateTokens(d)
+	}
+	r, err := m.b.reserve(ctx, req.UserID, m.b.usage(m.b.price(m.name, req.Model), input, 0, 0))
+	if err != nil {
+		return nil, err
+	}
+	res, err := m.next.Generate(ctx, req)
+	if err != nil {
+		r.refund(ctx)
+	}
+	return res, err
+}
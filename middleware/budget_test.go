@@ -0,0 +1,321 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// textModel answers every call with text, streamed as one delta.
+type textModel struct {
+	text  string
+	err   error
+	calls int
+}
+
+func (m *textModel) Generate(ctx context.Context, req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
+	m.calls++
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &provider.LanguageModelResponse{Text: m.text}, nil
+}
+
+func (m *textModel) Stream(ctx context.Context, req *provider.LanguageModelRequest) (provider.LanguageModelStream, error) {
+	m.calls++
+	return &textStream{deltas: []provider.LanguageModelDelta{{Text: m.text}, {Done: true}}}, nil
+}
+
+type textStream struct{ deltas []provider.LanguageModelDelta }
+
+func (s *textStream) Next(ctx context.Context) (*provider.LanguageModelDelta, error) {
+	d := s.deltas[0]
+	s.deltas = s.deltas[1:]
+	return &d, nil
+}
+
+func (s *textStream) Close() error { return nil }
+
+func userRequest(user, content string) *provider.LanguageModelRequest {
+	return &provider.LanguageModelRequest{
+		Messages: []provider.Message{{Role: "user", Content: content}},
+		Metadata: map[string]string{provider.MetadataUserID: user},
+	}
+}
+
+func TestBudget_RejectsCallsOverTheRequestLimit(t *testing.T) {
+	budget := NewBudget(BudgetOptions{Default: BudgetLimits{RequestsPerDay: 2}})
+	base := &textModel{text: "ok"}
+	model := WrapLanguageModel(base, budget.Middleware("gpt"))
+
+	ctx := context.Background()
+	for range 2 {
+		if _, err := model.Generate(ctx, userRequest("alice", "hi")); err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+	}
+	_, err := model.Generate(ctx, userRequest("alice", "hi"))
+	var budgetErr *provider.BudgetExceededError
+	if !errors.As(err, &budgetErr) {
+		t.Fatalf("expected BudgetExceededError, got %v", err)
+	}
+	_, reset, _ := budget.Usage(ctx, "alice")
+	want := provider.BudgetExceededError{Key: "alice", Resource: "requests", Limit: 2, Used: 2, Requested: 1, ResetAt: reset}
+	if *budgetErr != want {
+		t.Fatalf("unexpected error %+v", budgetErr)
+	}
+	if base.calls != 2 {
+		t.Fatalf("rejected call reached the model: %d calls", base.calls)
+	}
+
+	// Other users have their own budget, and calls without a user are
+	// not metered.
+	if _, err := model.Generate(ctx, userRequest("bob", "hi")); err != nil {
+		t.Fatalf("Generate for another user: %v", err)
+	}
+	if _, err := model.Generate(ctx, &provider.LanguageModelRequest{}); err != nil {
+		t.Fatalf("Generate without a user: %v", err)
+	}
+	usage, _, _ := budget.Usage(ctx, "alice")
+	if usage.Requests != 2 {
+		t.Fatalf("rejected call left a charge: %+v", usage)
+	}
+}
+
+func TestBudget_ReconcilesEstimatesWithTheResponse(t *testing.T) {
+	budget := NewBudget(BudgetOptions{
+		Default: BudgetLimits{TokensPerDay: 1000, DollarsPerDay: 1},
+		Pricing: map[string]ModelPrice{"gpt": {InputPerMillion: 1000, OutputPerMillion: 2000}},
+	})
+	ctx := context.Background()
+	model := budget.LanguageModel("gpt", &textModel{text: "12345678"})
+
+	// 5 tokens of input ("hi" plus message overhead), 100 reserved for
+	// MaxTokens, then 2 actual output tokens.
+	maxTokens := 100
+	req := userRequest("alice", "hi")
+	req.MaxTokens = &maxTokens
+	if _, err := model.Generate(ctx, req); err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	usage, _, _ := budget.Usage(ctx, "alice")
+	if usage.Tokens != 7 || math.Abs(usage.Dollars-0.009) > 1e-12 {
+		t.Fatalf("unexpected usage after Generate %+v", usage)
+	}
+
+	stream, err := model.Stream(ctx, userRequest("alice", "hi"))
+	if err != nil {
+		t.Fatalf("Stream error: %v", err)
+	}
+	for {
+		d, err := stream.Next(ctx)
+		if err != nil {
+			t.Fatalf("Next error: %v", err)
+		}
+		if d.Done {
+			break
+		}
+	}
+	stream.Close()
+	usage, _, _ = budget.Usage(ctx, "alice")
+	if usage.Requests != 2 || usage.Tokens != 14 {
+		t.Fatalf("unexpected usage after Stream %+v", usage)
+	}
+
+	// A call whose reserved output would exceed the token limit is
+	// rejected up front.
+	maxTokens = 1000
+	_, err = model.Generate(ctx, req)
+	var budgetErr *provider.BudgetExceededError
+	if !errors.As(err, &budgetErr) || budgetErr.Resource != "tokens" || budgetErr.Used != 14 || budgetErr.Requested != 1005 {
+		t.Fatalf("expected a tokens BudgetExceededError, got %v", err)
+	}
+}
+
+// usageModel reports fixed usage for every call.
+type usageModel struct{ textModel }
+
+func (m *usageModel) Generate(ctx context.Context, req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
+	return &provider.LanguageModelResponse{Text: "ok", Usage: &provider.Usage{PromptTokens: 300, CompletionTokens: 50, TotalTokens: 350}}, nil
+}
+
+func TestBudget_PrefersReportedUsage(t *testing.T) {
+	budget := NewBudget(BudgetOptions{})
+	ctx := context.Background()
+	if _, err := budget.LanguageModel("", &usageModel{}).Generate(ctx, userRequest("alice", "hi")); err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	if usage, _, _ := budget.Usage(ctx, "alice"); usage.Tokens != 350 {
+		t.Fatalf("expected the reported 350 tokens, got %+v", usage)
+	}
+}
+
+func TestBudget_PricesCachedInputFromStreamUsage(t *testing.T) {
+	usage := &provider.Usage{
+		PromptTokens: 1_000_000, CompletionTokens: 0, TotalTokens: 1_000_000,
+		Cache: &provider.CacheUsage{CachedInputTokens: 600_000, CacheWriteTokens: 100_000},
+	}
+	price := ModelPrice{InputPerMillion: 3, CachedInputPerMillion: 0.3, CacheWritePerMillion: 3.75}
+	budget := NewBudget(BudgetOptions{Pricing: map[string]ModelPrice{"claude": price}})
+	base := &textModel{text: "ok"}
+	model := budget.LanguageModel("claude", &streamUsageModel{textModel: base, usage: usage})
+
+	ctx := context.Background()
+	stream, err := model.Stream(ctx, userRequest("alice", "hi"))
+	if err != nil {
+		t.Fatalf("Stream error: %v", err)
+	}
+	for {
+		d, err := stream.Next(ctx)
+		if err != nil {
+			t.Fatalf("Next error: %v", err)
+		}
+		if d.Done {
+			break
+		}
+	}
+	stream.Close()
+
+	// 300k uncached tokens at $3, 600k cache reads at $0.30, and 100k
+	// cache writes at $3.75 per million.
+	want := 0.9 + 0.18 + 0.375
+	got, _, _ := budget.Usage(ctx, "alice")
+	if got.Tokens != 1_000_000 || math.Abs(got.Dollars-want) > 1e-9 {
+		t.Fatalf("expected 1M tokens costing $%.3f, got %+v", want, got)
+	}
+}
+
+// streamUsageModel streams text and reports usage on the final delta.
+type streamUsageModel struct {
+	*textModel
+	usage *provider.Usage
+}
+
+func (m *streamUsageModel) Stream(ctx context.Context, req *provider.LanguageModelRequest) (provider.LanguageModelStream, error) {
+	return &textStream{deltas: []provider.LanguageModelDelta{{Text: m.text}, {Done: true, Usage: m.usage}}}, nil
+}
+
+func TestCacheReport(t *testing.T) {
+	price := ModelPrice{InputPerMillion: 3, CachedInputPerMillion: 0.3}
+	report := NewCacheReport([]*provider.Usage{
+		{PromptTokens: 500_000, Cache: &provider.CacheUsage{CachedInputTokens: 400_000}},
+		{PromptTokens: 500_000, Cache: &provider.CacheUsage{CachedInputTokens: 220_000}},
+		nil,
+	}, price)
+	if report.Calls != 2 || report.InputTokens != 1_000_000 || report.CachedInputTokens != 620_000 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if got, want := report.String(), "62% of input tokens served from cache, saving $1.67"; got != want {
+		t.Fatalf("String() = %q, want %q", got, want)
+	}
+
+	writes := NewCacheReport([]*provider.Usage{
+		{PromptTokens: 1_000_000, Cache: &provider.CacheUsage{CacheWriteTokens: 1_000_000}},
+	}, ModelPrice{InputPerMillion: 3, CacheWritePerMillion: 3.75})
+	if got, want := writes.String(), "0% of input tokens served from cache, costing $0.75 extra"; got != want {
+		t.Fatalf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestBudget_RefundsFailedCalls(t *testing.T) {
+	budget := NewBudget(BudgetOptions{Default: BudgetLimits{RequestsPerDay: 1}})
+	ctx := context.Background()
+	failing := budget.LanguageModel("", &textModel{err: errors.New("upstream down")})
+	if _, err := failing.Generate(ctx, userRequest("alice", "hi")); err == nil {
+		t.Fatal("expected the upstream error")
+	}
+	if _, err := budget.LanguageModel("", &textModel{}).Generate(ctx, userRequest("alice", "hi")); err != nil {
+		t.Fatalf("failed call was charged: %v", err)
+	}
+}
+
+type imageModel struct{}
+
+func (imageModel) Generate(ctx context.Context, req *provider.ImageRequest) (*provider.ImageResponse, error) {
+	return &provider.ImageResponse{Images: make([]provider.Image, 1)}, nil
+}
+
+func TestBudget_SharesKeysAcrossModelKinds(t *testing.T) {
+	budget := NewBudget(BudgetOptions{
+		Limits:  map[string]BudgetLimits{"key-1": {DollarsPerDay: 0.1}},
+		Pricing: map[string]ModelPrice{"dall-e": {PerImage: 0.04}},
+	})
+	images := budget.ImageModel("dall-e", imageModel{})
+	ctx := WithBudgetKey(context.Background(), "key-1")
+
+	// Two images are reserved but one is returned.
+	if _, err := images.Generate(ctx, &provider.ImageRequest{NumberOfImages: 2, UserID: "alice"}); err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	if _, err := images.Generate(ctx, &provider.ImageRequest{NumberOfImages: 1}); err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	_, err := images.Generate(ctx, &provider.ImageRequest{NumberOfImages: 1})
+	var budgetErr *provider.BudgetExceededError
+	if !errors.As(err, &budgetErr) || budgetErr.Key != "key-1" || budgetErr.Resource != "dollars" {
+		t.Fatalf("expected a dollars BudgetExceededError, got %v", err)
+	}
+}
+
+// mapRedis is an in-memory RedisClient.
+type mapRedis map[string]map[string]string
+
+func (r mapRedis) HIncrBy(ctx context.Context, key, field string, incr int64) (int64, error) {
+	n, _ := strconv.ParseInt(r.hash(key)[field], 10, 64)
+	n += incr
+	r[key][field] = strconv.FormatInt(n, 10)
+	return n, nil
+}
+
+func (r mapRedis) HIncrByFloat(ctx context.Context, key, field string, incr float64) (float64, error) {
+	f, _ := strconv.ParseFloat(r.hash(key)[field], 64)
+	f += incr
+	r[key][field] = strconv.FormatFloat(f, 'f', -1, 64)
+	return f, nil
+}
+
+func (r mapRedis) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	return r[key], nil
+}
+
+func (r mapRedis) ExpireAt(ctx context.Context, key string, at time.Time) error { return nil }
+
+func (r mapRedis) hash(key string) map[string]string {
+	if r[key] == nil {
+		r[key] = make(map[string]string)
+	}
+	return r[key]
+}
+
+func TestRedisBudgetStore(t *testing.T) {
+	redis := mapRedis{}
+	store := NewRedisBudgetStore(redis, "budget:")
+	ctx := context.Background()
+	if _, err := store.Add(ctx, "alice", BudgetUsage{Requests: 1, Tokens: 10, Dollars: 0.5}, time.Now()); err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	total, err := store.Add(ctx, "alice", BudgetUsage{Tokens: -4}, time.Now())
+	if err != nil || total != (BudgetUsage{Requests: 1, Tokens: 6, Dollars: 0.5}) {
+		t.Fatalf("unexpected total %+v, %v", total, err)
+	}
+	usage, err := store.Usage(ctx, "alice")
+	if err != nil || usage != total {
+		t.Fatalf("Usage = %+v, %v; want %+v", usage, err, total)
+	}
+	if redis["budget:alice"]["tokens"] != "6" {
+		t.Fatalf("unexpected redis hash %v", redis)
+	}
+}
+
+func TestModelPrice_ChargesWebSearches(t *testing.T) {
+	price := ModelPrice{InputPerMillion: 3, PerWebSearch: 0.01}
+	got := price.usageCost(&provider.Usage{PromptTokens: 1_000_000, TotalTokens: 1_000_000, WebSearchRequests: 2})
+	if want := 3.02; math.Abs(got-want) > 1e-9 {
+		t.Fatalf("usageCost = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// CacheReport summarizes prompt-cache efficiency over a batch of
+// language-model calls. See NewCacheReport.
+type CacheReport struct {
+	// Calls is the number of calls with reported usage.
+	Calls int
+	// InputTokens is the total of Usage.PromptTokens.
+	InputTokens int
+	// CachedInputTokens and CacheWriteTokens total the calls'
+	// CacheUsage.
+	CachedInputTokens int
+	CacheWriteTokens  int
+	// Savings is the dollars saved at the report's ModelPrice compared
+	// with charging every input token at InputPerMillion, net of any
+	// cache-write premium. It is negative when writes cost more than
+	// reads saved.
+	Savings float64
+}
+
+// NewCacheReport summarizes the cache usage of calls to a model with
+// the given price. Nil usages, from calls whose provider did not report
+// usage, are skipped.
+func NewCacheReport(usages []*provider.Usage, price ModelPrice) CacheReport {
+	var r CacheReport
+	for _, u := range usages {
+		if u == nil {
+			continue
+		}
+		r.Calls++
+		r.InputTokens += u.PromptTokens
+		if u.Cache != nil {
+			r.CachedInputTokens += u.Cache.CachedInputTokens
+			r.CacheWriteTokens += u.Cache.CacheWriteTokens
+		}
+		r.Savings += price.cacheSavings(u.Cache)
+	}
+	return r
+}
+
+// HitRate returns the fraction of input tokens served from the cache.
+func (r CacheReport) HitRate() float64 {
+	if r.InputTokens == 0 {
+		return 0
+	}
+	return float64(r.CachedInputTokens) / float64(r.InputTokens)
+}
+
+// String returns a one-line summary such as "62% of input tokens served
+// from cache, saving $3.40".
+func (r CacheReport) String() string {
+	s := fmt.Sprintf("%.0f%% of input tokens served from cache", r.HitRate()*100)
+	switch {
+	case r.Savings >= 0.005:
+		s += fmt.Sprintf(", saving $%.2f", r.Savings)
+	case r.Savings <= -0.005:
+		s += fmt.Sprintf(", costing $%.2f extra", math.Abs(r.Savings))
+	}
+	return s
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"log"
 	"net"
+	"strconv"
 	"time"
 
 	"github.com/ncecere/ai-sdk/provider"
@@ -87,31 +88,38 @@ type loggingLanguageModel struct {
 func (l *loggingLanguageModel) Generate(ctx context.Context, req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
 	start := time.Now()
 	if l.opts.LogRequest {
-		l.logFn("lm.generate start model=%s", req.Model)
+		l.logFn("lm.generate start model=%s%s", req.Model, traceField(ctx))
 	}
 
+	ctx, record := provider.WithAttemptRecord(ctx)
 	res, err := l.next.Generate(ctx, req)
 	dur := time.Since(start)
 
 	if err != nil {
 		if l.opts.LogErrors {
+			fields := traceField(ctx) + requestIDField(errorRequestID(err)) + attemptsField(record)
 			if l.opts.LogDuration {
-				l.logFn("lm.generate error model=%s duration=%s err=%v", req.Model, dur, err)
+				l.logFn("lm.generate error model=%s duration=%s err=%v%s", req.Model, dur, err, fields)
 			} else {
-				l.logFn("lm.generate error model=%s err=%v", req.Model, err)
+				l.logFn("lm.generate error model=%s err=%v%s", req.Model, err, fields)
 			}
 		}
 		return nil, err
 	}
 
+	fields := traceField(ctx)
+	if res.Metadata != nil {
+		fields += requestIDField(res.Metadata.RequestID)
+	}
+	fields += attemptsField(record)
 	if l.opts.LogResponse {
 		if l.opts.LogDuration {
-			l.logFn("lm.generate success model=%s duration=%s", req.Model, dur)
+			l.logFn("lm.generate success model=%s duration=%s%s", req.Model, dur, fields)
 		} else {
-			l.logFn("lm.generate success model=%s", req.Model)
+			l.logFn("lm.generate success model=%s%s", req.Model, fields)
 		}
 	} else if l.opts.LogDuration {
-		l.logFn("lm.generate done model=%s duration=%s", req.Model, dur)
+		l.logFn("lm.generate done model=%s duration=%s%s", req.Model, dur, fields)
 	}
 
 	return res, nil
@@ -119,24 +127,60 @@ func (l *loggingLanguageModel) Generate(ctx context.Context, req *provider.Langu
 
 func (l *loggingLanguageModel) Stream(ctx context.Context, req *provider.LanguageModelRequest) (provider.LanguageModelStream, error) {
 	if l.opts.LogRequest {
-		l.logFn("lm.stream start model=%s", req.Model)
+		l.logFn("lm.stream start model=%s%s", req.Model, traceField(ctx))
 	}
 
+	ctx, record := provider.WithAttemptRecord(ctx)
 	stream, err := l.next.Stream(ctx, req)
 	if err != nil {
 		if l.opts.LogErrors {
-			l.logFn("lm.stream error model=%s err=%v", req.Model, err)
+			l.logFn("lm.stream error model=%s err=%v%s%s%s", req.Model, err, traceField(ctx), requestIDField(errorRequestID(err)), attemptsField(record))
 		}
 		return nil, err
 	}
 
 	if l.opts.LogResponse {
-		l.logFn("lm.stream established model=%s", req.Model)
+		l.logFn("lm.stream established model=%s%s%s", req.Model, traceField(ctx), attemptsField(record))
 	}
 
 	return stream, nil
 }
 
+// traceField formats the trace ID carried by ctx as a log field.
+func traceField(ctx context.Context) string {
+	if id := provider.TraceIDFromContext(ctx); id != "" {
+		return " trace_id=" + id
+	}
+	return ""
+}
+
+// requestIDField formats a provider request ID as a log field.
+func requestIDField(id string) string {
+	if id != "" {
+		return " request_id=" + id
+	}
+	return ""
+}
+
+// attemptsField formats the attempts recorded so far as a log field
+// when the call was retried.
+func attemptsField(record *provider.AttemptRecord) string {
+	if n := record.Stats().Attempts; n > 1 {
+		return " attempts=" + strconv.Itoa(n)
+	}
+	return ""
+}
+
+// errorRequestID returns the provider request ID of the
+// *provider.APIError in err's chain, or "".
+func errorRequestID(err error) string {
+	var apiErr *provider.APIError
+	if errors.As(err, &apiErr) && apiErr != nil {
+		return apiErr.RequestID
+	}
+	return ""
+}
+
 // RetryOptions configures the retry middleware for language-model calls.
 type RetryOptions struct {
 	// MaxAttempts is the maximum number of attempts, including the first
@@ -171,6 +215,22 @@ func defaultRetryOptions(opts RetryOptions) RetryOptions {
 // Generate and Stream calls when ShouldRetry returns true for the
 // encountered error. Retries respect the provided context for
 // cancellation.
+//
+// If the context carries a provider.RetryBudget, each retry consumes
+// one unit of it. When the budget is exhausted the retry is skipped and
+// a *provider.RetryBudgetExhaustedError wrapping the last error is
+// returned.
+//
+// Only establishing a stream is retried; an error after the stream has
+// produced content is returned from Next unchanged, since retrying
+// would repeat text the caller has already seen. Consumers such as
+// ai.WriteTextStreamAsSSE report that content in an
+// ai.PartialResponseError.
+//
+// Attempts are recorded in the context's provider.AttemptRecord, which
+// is created when the context has none and shared by stacked layers.
+// A Generate response that needed retries reports them in
+// Metadata.Retry.
 func RetryLanguageModel(opts RetryOptions) LanguageModelMiddleware {
 	opts = defaultRetryOptions(opts)
 
@@ -190,18 +250,26 @@ type retryLanguageModel struct {
 func (r *retryLanguageModel) Generate(ctx context.Context, req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
 	var lastErr error
 
+	ctx, record := provider.WithAttemptRecord(ctx)
+	budget := provider.RetryBudgetFromContext(ctx)
 	backoff := r.opt.InitialBackoff
 	for attempt := 1; attempt <= r.opt.MaxAttempts; attempt++ {
 		if attempt > 1 {
+			if !budget.TryAcquire() {
+				return nil, &provider.RetryBudgetExhaustedError{Err: lastErr}
+			}
 			if err := sleepWithContext(ctx, backoff); err != nil {
 				return nil, err
 			}
+			record.RecordRetry(lastErr, backoff)
 			backoff = nextBackoff(backoff, r.opt.MaxBackoff)
 		}
 
+		record.StartAttempt()
 		res, err := r.next.Generate(ctx, req)
+		record.EndAttempt()
 		if err == nil {
-			return res, nil
+			return withRetryStats(res, record), nil
 		}
 		// Do not retry on context cancellation.
 		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
@@ -223,16 +291,24 @@ func (r *retryLanguageModel) Stream(ctx context.Context, req *provider.LanguageM
 	var stream provider.LanguageModelStream
 	var lastErr error
 
+	ctx, record := provider.WithAttemptRecord(ctx)
+	budget := provider.RetryBudgetFromContext(ctx)
 	backoff := r.opt.InitialBackoff
 	for attempt := 1; attempt <= r.opt.MaxAttempts; attempt++ {
 		if attempt > 1 {
+			if !budget.TryAcquire() {
+				return nil, &provider.RetryBudgetExhaustedError{Err: lastErr}
+			}
 			if err := sleepWithContext(ctx, backoff); err != nil {
 				return nil, err
 			}
+			record.RecordRetry(lastErr, backoff)
 			backoff = nextBackoff(backoff, r.opt.MaxBackoff)
 		}
 
+		record.StartAttempt()
 		res, err := r.next.Stream(ctx, req)
+		record.EndAttempt()
 		if err == nil {
 			stream = res
 			break
@@ -257,6 +333,23 @@ func (r *retryLanguageModel) Stream(ctx context.Context, req *provider.LanguageM
 	return stream, nil
 }
 
+// withRetryStats returns res with the stats of record attached to its
+// metadata when the call was retried. res is copied, not modified.
+func withRetryStats(res *provider.LanguageModelResponse, record *provider.AttemptRecord) *provider.LanguageModelResponse {
+	stats := retryStats(record)
+	if res == nil || stats == nil {
+		return res
+	}
+	out := *res
+	md := provider.ResponseMetadata{}
+	if res.Metadata != nil {
+		md = *res.Metadata
+	}
+	md.Retry = stats
+	out.Metadata = &md
+	return &out
+}
+
 // sleepWithContext sleeps for the given duration or returns early if
 // the context is cancelled.
 func sleepWithContext(ctx context.Context, d time.Duration) error {
@@ -305,11 +398,18 @@ const (
 // LanguageModelCallInfo contains high-level metadata about a
 // language-model call that can be used for metrics or tracing.
 type LanguageModelCallInfo struct {
-	Kind      LanguageModelCallKind
-	Model     string
+	Kind  LanguageModelCallKind
+	Model string
+	// TraceID is the client-generated trace ID of the call, if any.
+	TraceID   string
 	StartTime time.Time
 	EndTime   time.Time
 	Err       error
+	// Retry reports the attempts made by retrying layers inside the
+	// telemetry middleware, such as RetryLanguageModel. It is nil when
+	// the call was not retried. The hook's context also carries the
+	// provider.AttemptRecord.
+	Retry *provider.RetryStats
 }
 
 // TelemetryHooks defines callbacks that are invoked around language
@@ -331,6 +431,16 @@ func TelemetryLanguageModel(hooks TelemetryHooks) LanguageModelMiddleware {
 	}
 }
 
+// retryStats returns the stats of record, or nil when the call was not
+// retried.
+func retryStats(record *provider.AttemptRecord) *provider.RetryStats {
+	stats := record.Stats()
+	if stats.Attempts <= 1 {
+		return nil
+	}
+	return &stats
+}
+
 type telemetryLanguageModel struct {
 	next  provider.LanguageModel
 	hooks TelemetryHooks
@@ -338,14 +448,17 @@ type telemetryLanguageModel struct {
 
 func (t *telemetryLanguageModel) Generate(ctx context.Context, req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
 	start := time.Now()
+	ctx, record := provider.WithAttemptRecord(ctx)
 	res, err := t.next.Generate(ctx, req)
 	if t.hooks.OnLanguageModelCall != nil {
 		t.hooks.OnLanguageModelCall(ctx, LanguageModelCallInfo{
 			Kind:      LanguageModelCallGenerate,
 			Model:     req.Model,
+			TraceID:   provider.TraceIDFromContext(ctx),
 			StartTime: start,
 			EndTime:   time.Now(),
 			Err:       err,
+			Retry:     retryStats(record),
 		})
 	}
 	return res, err
@@ -353,14 +466,17 @@ func (t *telemetryLanguageModel) Generate(ctx context.Context, req *provider.Lan
 
 func (t *telemetryLanguageModel) Stream(ctx context.Context, req *provider.LanguageModelRequest) (provider.LanguageModelStream, error) {
 	start := time.Now()
+	ctx, record := provider.WithAttemptRecord(ctx)
 	stream, err := t.next.Stream(ctx, req)
 	if t.hooks.OnLanguageModelCall != nil {
 		t.hooks.OnLanguageModelCall(ctx, LanguageModelCallInfo{
 			Kind:      LanguageModelCallStream,
 			Model:     req.Model,
+			TraceID:   provider.TraceIDFromContext(ctx),
 			StartTime: start,
 			EndTime:   time.Now(),
 			Err:       err,
+			Retry:     retryStats(record),
 		})
 	}
 	return stream, err
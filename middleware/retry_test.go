@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+type failingModel struct{ calls int }
+
+func (m *failingModel) Generate(ctx context.Context, req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
+	m.calls++
+	return nil, timeoutError{}
+}
+
+func (m *failingModel) Stream(ctx context.Context, req *provider.LanguageModelRequest) (provider.LanguageModelStream, error) {
+	m.calls++
+	return nil, timeoutError{}
+}
+
+func TestRetryLanguageModel_SharedBudgetCapsStackedRetries(t *testing.T) {
+	base := &failingModel{}
+	retry := RetryLanguageModel(RetryOptions{MaxAttempts: 3, InitialBackoff: time.Microsecond})
+	model := WrapLanguageModel(base, retry, retry)
+
+	ctx := provider.WithRetryBudget(context.Background(), 2)
+	_, err := model.Generate(ctx, &provider.LanguageModelRequest{})
+
+	var budgetErr *provider.RetryBudgetExhaustedError
+	if !errors.As(err, &budgetErr) {
+		t.Fatalf("expected RetryBudgetExhaustedError, got %v", err)
+	}
+	if !errors.As(err, new(timeoutError)) {
+		t.Fatalf("expected budget error to wrap the last provider error, got %v", err)
+	}
+	if base.calls != 3 {
+		t.Fatalf("expected 1 call plus 2 budgeted retries, got %d calls", base.calls)
+	}
+	if b := provider.RetryBudgetFromContext(ctx); b.Remaining() != 0 || b.Skipped() != 1 {
+		t.Fatalf("unexpected budget state: remaining=%d skipped=%d", b.Remaining(), b.Skipped())
+	}
+}
+
+func TestRetryLanguageModel_NoBudgetRetriesAllAttempts(t *testing.T) {
+	base := &failingModel{}
+	model := RetryLanguageModel(RetryOptions{MaxAttempts: 3, InitialBackoff: time.Microsecond})(base)
+
+	if _, err := model.Generate(context.Background(), &provider.LanguageModelRequest{}); err == nil {
+		t.Fatalf("expected error")
+	}
+	if base.calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", base.calls)
+	}
+}
+
+// flakyModel fails its first failures calls with a timeout and then
+// succeeds after delay.
+type flakyModel struct {
+	failures int
+	delay    time.Duration
+	calls    int
+}
+
+func (m *flakyModel) Generate(ctx context.Context, req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
+	m.calls++
+	if m.calls <= m.failures {
+		return nil, timeoutError{}
+	}
+	time.Sleep(m.delay)
+	return &provider.LanguageModelResponse{Text: "ok", Metadata: &provider.ResponseMetadata{RequestID: "req_1"}}, nil
+}
+
+func (m *flakyModel) Stream(ctx context.Context, req *provider.LanguageModelRequest) (provider.LanguageModelStream, error) {
+	m.calls++
+	if m.calls <= m.failures {
+		return nil, timeoutError{}
+	}
+	return &textStream{}, nil
+}
+
+type lineLogger struct{ lines []string }
+
+func (l *lineLogger) Printf(format string, v ...any) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func TestRetryLanguageModel_ReportsAttemptsInMetadata(t *testing.T) {
+	base := &flakyModel{failures: 2, delay: 5 * time.Millisecond}
+	model := RetryLanguageModel(RetryOptions{MaxAttempts: 3, InitialBackoff: time.Millisecond})(base)
+
+	res, err := model.Generate(context.Background(), &provider.LanguageModelRequest{})
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	retry := res.Metadata.Retry
+	if retry == nil || retry.Attempts != 3 || len(retry.Errors) != 2 || res.Metadata.RequestID != "req_1" {
+		t.Fatalf("unexpected metadata: %+v", res.Metadata)
+	}
+	if retry.Wait != 3*time.Millisecond || retry.LastAttempt < base.delay {
+		t.Fatalf("unexpected timings: wait=%s last=%s", retry.Wait, retry.LastAttempt)
+	}
+
+	base = &flakyModel{}
+	res, err = RetryLanguageModel(RetryOptions{})(base).Generate(context.Background(), &provider.LanguageModelRequest{})
+	if err != nil || res.Metadata.Retry != nil {
+		t.Fatalf("expected no retry stats without retries, got %+v, %v", res.Metadata, err)
+	}
+}
+
+func TestRetryLanguageModel_StackedLayersShareTheRecord(t *testing.T) {
+	// The inner retry gives up after two failures, the outer one
+	// retries, and the inner one succeeds on its second attempt: four
+	// calls in all.
+	base := &flakyModel{failures: 3}
+	logger := &lineLogger{}
+	var info LanguageModelCallInfo
+	var hookRecord *provider.AttemptRecord
+	retry := RetryLanguageModel(RetryOptions{MaxAttempts: 2, InitialBackoff: time.Microsecond})
+	model := WrapLanguageModel(base,
+		LoggingLanguageModel(LoggingOptions{Logger: logger, LogDuration: true}),
+		TelemetryLanguageModel(TelemetryHooks{OnLanguageModelCall: func(ctx context.Context, i LanguageModelCallInfo) {
+			info, hookRecord = i, provider.AttemptRecordFromContext(ctx)
+		}}),
+		retry,
+		retry,
+	)
+
+	ctx, record := provider.WithAttemptRecord(context.Background())
+	res, err := model.Generate(ctx, &provider.LanguageModelRequest{Model: "m"})
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	if base.calls != 4 || res.Metadata.Retry.Attempts != 4 || len(res.Metadata.Retry.Errors) != 3 {
+		t.Fatalf("expected 4 attempts, got %d calls and %+v", base.calls, res.Metadata.Retry)
+	}
+	if record.Stats().Attempts != 4 || hookRecord != record {
+		t.Fatalf("expected the caller's record to be shared, got %+v", record.Stats())
+	}
+	if info.Retry == nil || info.Retry.Attempts != 4 {
+		t.Fatalf("telemetry did not see the attempts: %+v", info.Retry)
+	}
+	if len(logger.lines) != 1 || !strings.HasSuffix(logger.lines[0], " request_id=req_1 attempts=4") {
+		t.Fatalf("unexpected log lines: %q", logger.lines)
+	}
+}
+
+func TestLoggingLanguageModel_InsideRetryLogsEachAttempt(t *testing.T) {
+	base := &flakyModel{failures: 2}
+	logger := &lineLogger{}
+	model := WrapLanguageModel(base,
+		RetryLanguageModel(RetryOptions{MaxAttempts: 3, InitialBackoff: time.Microsecond}),
+		LoggingLanguageModel(LoggingOptions{Logger: logger, LogErrors: true, LogResponse: true}),
+	)
+
+	if _, err := model.Stream(context.Background(), &provider.LanguageModelRequest{Model: "m"}); err != nil {
+		t.Fatalf("Stream error: %v", err)
+	}
+	want := []string{"lm.stream error model=m err=timeout", "lm.stream error model=m err=timeout attempts=2", "lm.stream established model=m attempts=3"}
+	if !reflect.DeepEqual(logger.lines, want) {
+		t.Fatalf("log lines = %q, want %q", logger.lines, want)
+	}
+}
@@ -0,0 +1,230 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ncecere/ai-sdk/provider"
+	"github.com/ncecere/ai-sdk/providerutil"
+)
+
+var _ provider.DeferredLanguageModel = (*batchModel)(nil)
+
+// BatchModel returns a LanguageModel for the given chat model ID that
+// implements provider.DeferredLanguageModel with the Batch API, so that
+// ai.JobRunner and ai.SubmitText run its requests as batch jobs: at a
+// lower price, but completed within 24 hours rather than immediately.
+// Generate and Stream call the chat completions API as ChatModel does.
+func (c *Client) BatchModel(model string) provider.LanguageModel {
+	return &batchModel{chatModel{client: c, model: model}}
+}
+
+type batchModel struct {
+	chatModel
+}
+
+// apiURL returns the URL of the API path, such as "/batches".
+func (c *Client) apiURL(path string) string {
+	if strings.HasSuffix(c.baseURL, "/v1") {
+		return c.baseURL + path
+	}
+	return c.baseURL + "/v1" + path
+}
+
+// batchCustomID identifies the single request of a batch.
+const batchCustomID = "request-1"
+
+type openAIBatchInput struct {
+	CustomID string          `json:"custom_id"`
+	Method   string          `json:"method"`
+	URL      string          `json:"url"`
+	Body     json.RawMessage `json:"body"`
+}
+
+type openAIBatch struct {
+	ID           string `json:"id"`
+	Status       string `json:"status"`
+	OutputFileID string `json:"output_file_id"`
+	ErrorFileID  string `json:"error_file_id"`
+	Errors       *struct {
+		Data []openAIBatchError `json:"data"`
+	} `json:"errors"`
+}
+
+type openAIBatchError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type openAIBatchOutput struct {
+	CustomID string `json:"custom_id"`
+	Response *struct {
+		StatusCode int             `json:"status_code"`
+		Body       json.RawMessage `json:"body"`
+	} `json:"response"`
+	Error *openAIBatchError `json:"error"`
+}
+
+// SubmitDeferred implements provider.DeferredLanguageModel: it uploads
+// req as a one-line batch input file and creates a batch for it,
+// returning the batch ID.
+func (m *batchModel) SubmitDeferred(ctx context.Context, req *provider.LanguageModelRequest) (string, error) {
+	_, body, dropped, err := m.buildRequest(ctx, req, false)
+	if err != nil {
+		return "", err
+	}
+	m.client.reportDropped(dropped)
+
+	line, err := json.Marshal(openAIBatchInput{CustomID: batchCustomID, Method: http.MethodPost, URL: "/v1/chat/completions", Body: body})
+	if err != nil {
+		return "", err
+	}
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	if err := writer.WriteField("purpose", "batch"); err != nil {
+		return "", err
+	}
+	part, err := writer.CreateFormFile("file", "batch.jsonl")
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write(append(line, '\n')); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+	var file struct {
+		ID string `json:"id"`
+	}
+	if err := m.client.sendJSON(ctx, http.MethodPost, m.client.apiURL("/files"), &form, writer.FormDataContentType(), &file); err != nil {
+		return "", err
+	}
+
+	create, err := json.Marshal(map[string]string{
+		"input_file_id":     file.ID,
+		"endpoint":          "/v1/chat/completions",
+		"completion_window": "24h",
+	})
+	if err != nil {
+		return "", err
+	}
+	var batch openAIBatch
+	if err := m.client.sendJSON(ctx, http.MethodPost, m.client.apiURL("/batches"), bytes.NewReader(create), "application/json", &batch); err != nil {
+		return "", err
+	}
+	return batch.ID, nil
+}
+
+// RetrieveDeferred implements provider.DeferredLanguageModel. done is
+// false while the batch is validating, in progress, or finalizing.
+//
+// Errors:
+//   - An error naming the status if the batch failed, expired, or was
+//     cancelled.
+//   - *provider.APIError if the request in the batch failed.
+//   - Any error retrieving the batch or its output file.
+func (m *batchModel) RetrieveDeferred(ctx context.Context, jobID string) (*provider.LanguageModelResponse, bool, error) {
+	var batch openAIBatch
+	if err := m.client.sendJSON(ctx, http.MethodGet, m.client.apiURL("/batches/"+url.PathEscape(jobID)), nil, "", &batch); err != nil {
+		return nil, false, err
+	}
+	switch batch.Status {
+	case "validating", "in_progress", "finalizing":
+		return nil, false, nil
+	case "completed":
+	default:
+		msg := batch.Status
+		if batch.Errors != nil && len(batch.Errors.Data) > 0 {
+			msg += ": " + batch.Errors.Data[0].Message
+		}
+		return nil, false, fmt.Errorf("openai: batch %s %s", jobID, msg)
+	}
+
+	fileID := batch.OutputFileID
+	if fileID == "" {
+		// The request failed; its error is in the error file.
+		fileID = batch.ErrorFileID
+	}
+	if fileID == "" {
+		return nil, false, fmt.Errorf("openai: batch %s completed without an output file", jobID)
+	}
+	out, err := m.batchOutput(ctx, fileID)
+	if err != nil {
+		return nil, false, err
+	}
+	if out.Error != nil {
+		return nil, false, fmt.Errorf("openai: batch %s request failed: %s", jobID, out.Error.Message)
+	}
+	if out.Response == nil {
+		return nil, false, fmt.Errorf("openai: batch %s output has no response", jobID)
+	}
+	if out.Response.StatusCode < 200 || out.Response.StatusCode >= 300 {
+		return nil, false, providerutil.TagProvider(providerutil.ParseAPIError(out.Response.StatusCode, out.Response.Body), "openai")
+	}
+	var res openAIChatResponse
+	if err := json.Unmarshal(out.Response.Body, &res); err != nil {
+		return nil, false, err
+	}
+	lmResp, err := m.chatResponse(&provider.LanguageModelRequest{}, &res, out.Response.Body, out.Response.StatusCode, &provider.ResponseMetadata{})
+	if err != nil {
+		return nil, false, err
+	}
+	return lmResp, true, nil
+}
+
+// batchOutput reads the output line of the batch request from fileID.
+func (m *batchModel) batchOutput(ctx context.Context, fileID string) (*openAIBatchOutput, error) {
+	httpReq, err := m.client.newMethodRequest(ctx, http.MethodGet, m.client.apiURL("/files/"+url.PathEscape(fileID)+"/content"), nil, "")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.client.do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := providerutil.CheckStatus(resp); err != nil {
+		return nil, providerutil.TagProvider(err, "openai")
+	}
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(nil, 64*1024*1024)
+	for sc.Scan() {
+		var out openAIBatchOutput
+		if err := json.Unmarshal(sc.Bytes(), &out); err != nil {
+			return nil, fmt.Errorf("openai: decoding batch output: %w", err)
+		}
+		if out.CustomID == batchCustomID {
+			return &out, nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("openai: batch output file %s has no result", fileID)
+}
+
+// sendJSON sends a request with body and decodes the JSON response into
+// v.
+func (c *Client) sendJSON(ctx context.Context, method, url string, body io.Reader, contentType string, v any) error {
+	httpReq, err := c.newMethodRequest(ctx, method, url, body, contentType)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(httpReq)
+	if err != nil {
+		return err
+	}
+	if err := providerutil.ReadJSON(resp, v); err != nil {
+		return providerutil.TagProvider(err, "openai")
+	}
+	return nil
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"net/http"
 	"strings"
 
 	"github.com/ncecere/ai-sdk/provider"
@@ -56,7 +55,7 @@ func (m *completionModel) Generate(ctx context.Context, req *provider.Completion
 		TopP:        req.TopP,
 		MaxTokens:   req.MaxTokens,
 		Stop:        req.Stop,
-		User:        req.UserID,
+		User:        providerutil.UserID(ctx, req.UserID, m.client.defaultUser),
 	}
 
 	buf, err := json.Marshal(body)
@@ -64,32 +63,28 @@ func (m *completionModel) Generate(ctx context.Context, req *provider.Completion
 		return nil, err
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, m.client.completionsURL(), bytes.NewReader(buf))
+	httpReq, err := m.client.newRequest(ctx, m.client.completionsURL(), bytes.NewReader(buf), "application/json")
 	if err != nil {
 		return nil, err
 	}
-	for k, vs := range m.client.headers {
-		for _, v := range vs {
-			if v == "" {
-				continue
-			}
-			httpReq.Header.Add(k, v)
-		}
-	}
-	httpReq.Header.Set("Authorization", "Bearer "+m.client.apiKey)
-	httpReq.Header.Set("Content-Type", "application/json")
 
-	resp, err := m.client.httpClient.Do(httpReq)
+	resp, err := m.client.do(httpReq)
 	if err != nil {
 		return nil, err
 	}
 
 	var out openAICompletionResponse
-	if err := providerutil.ReadJSON(resp, &out); err != nil {
-		return nil, err
+	raw, err := providerutil.ReadJSONWithBody(resp, &out)
+	if err != nil {
+		return nil, providerutil.TagProvider(err, "openai")
 	}
+	providerutil.ReportUnknownFields(raw, &out, m.client.reportUnknown("openai.completions"))
 	if len(out.Choices) == 0 {
-		return &provider.CompletionResponse{}, nil
+		return nil, &provider.EmptyResponseError{
+			Provider:   "openai",
+			StatusCode: resp.StatusCode,
+			Body:       providerutil.Snippet(raw, 512),
+		}
 	}
 
 	choice := out.Choices[0]
@@ -0,0 +1,64 @@
+package openai
+
+import (
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// unsupportedOptions maps the LanguageModelRequest field names accepted
+// in ClientOptions.UnsupportedOptions onto the chat request fields they
+// set. Each function clears its fields and reports whether any was set.
+var unsupportedOptions = map[string]func(body *openAIChatRequest) bool{
+	"Temperature":       func(b *openAIChatRequest) bool { return clearField(&b.Temperature, b.Temperature != nil) },
+	"TopP":              func(b *openAIChatRequest) bool { return clearField(&b.TopP, b.TopP != nil) },
+	"Stop":              func(b *openAIChatRequest) bool { return clearField(&b.Stop, len(b.Stop) > 0) },
+	"ResponseFormat":    func(b *openAIChatRequest) bool { return clearField(&b.ResponseFormat, b.ResponseFormat != nil) },
+	"ToolChoice":        func(b *openAIChatRequest) bool { return clearField(&b.ToolChoice, b.ToolChoice != nil) },
+	"ParallelToolCalls": func(b *openAIChatRequest) bool { return clearField(&b.ParallelToolCalls, b.ParallelToolCalls != nil) },
+	"LogitBias":         func(b *openAIChatRequest) bool { return clearField(&b.LogitBias, len(b.LogitBias) > 0) },
+	"FrequencyPenalty":  func(b *openAIChatRequest) bool { return clearField(&b.FrequencyPenalty, b.FrequencyPenalty != nil) },
+	"PresencePenalty":   func(b *openAIChatRequest) bool { return clearField(&b.PresencePenalty, b.PresencePenalty != nil) },
+	"Logprobs":          func(b *openAIChatRequest) bool { return clearField(&b.Logprobs, b.Logprobs != nil) },
+	"TopLogprobs":       func(b *openAIChatRequest) bool { return clearField(&b.TopLogprobs, b.TopLogprobs != nil) },
+	"Reasoning":         func(b *openAIChatRequest) bool { return clearField(&b.ReasoningEffort, b.ReasoningEffort != "") },
+	"Metadata":          func(b *openAIChatRequest) bool { return clearField(&b.Metadata, len(b.Metadata) > 0) },
+	"UserID":            func(b *openAIChatRequest) bool { return clearField(&b.User, b.User != "") },
+	"Store":             func(b *openAIChatRequest) bool { return clearField(&b.Store, b.Store != nil) },
+}
+
+// clearField zeroes *p and returns set.
+func clearField[T any](p *T, set bool) bool {
+	var zero T
+	*p = zero
+	return set
+}
+
+// dropOptions leaves the options of req that the chat completions API
+// cannot honor, and the fields the backend does not accept (see
+// ClientOptions.UnsupportedOptions), out of body and returns them.
+// buildBody consumes every other LanguageModelRequest field; keep the
+// two in step.
+func (m *chatModel) dropOptions(req *provider.LanguageModelRequest, body *openAIChatRequest) []provider.DroppedOption {
+	var dropped []provider.DroppedOption
+	drop := func(field, reason string) {
+		dropped = append(dropped, provider.DroppedOption{Field: field, Reason: reason})
+	}
+	if req.WebSearch != nil {
+		drop("WebSearch", "the chat completions API has no server-side web search")
+	}
+	if req.Reasoning != nil && req.Reasoning.BudgetTokens > 0 {
+		drop("Reasoning.BudgetTokens", "OpenAI takes a reasoning effort, not a token budget")
+	}
+	for _, name := range m.client.unsupported {
+		if unsupportedOptions[name](body) {
+			drop(name, "not supported by this backend (ClientOptions.UnsupportedOptions)")
+		}
+	}
+	return dropped
+}
+
+// reportDropped passes dropped to ClientOptions.OnDroppedOptions.
+func (c *Client) reportDropped(dropped []provider.DroppedOption) {
+	if len(dropped) > 0 && c.onDropped != nil {
+		c.onDropped("openai.chat", dropped)
+	}
+}
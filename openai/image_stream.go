@@ -0,0 +1,104 @@
+package openai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/ncecere/ai-sdk/provider"
+	"github.com/ncecere/ai-sdk/providerutil"
+)
+
+var _ provider.ImageStreamer = (*imageModel)(nil)
+
+// Stream starts a streaming image generation. Models such as
+// gpt-image-1 emit req.PartialImages preview images before the final
+// image.
+func (m *imageModel) Stream(ctx context.Context, req *provider.ImageRequest) (provider.ImageModelStream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	httpReq, err := m.buildRequest(ctx, req, true)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	resp, err := m.client.do(httpReq)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if err := providerutil.CheckStatus(resp); err != nil {
+		cancel()
+		return nil, providerutil.TagProvider(err, "openai")
+	}
+
+	return newImageStream(ctx, resp.Body, cancel), nil
+}
+
+type openAIImageStreamEvent struct {
+	Type              string `json:"type"`
+	B64JSON           string `json:"b64_json"`
+	PartialImageIndex int    `json:"partial_image_index"`
+}
+
+type imageStream struct {
+	lines *providerutil.LineReader
+	done  bool
+}
+
+func newImageStream(ctx context.Context, body io.ReadCloser, cancel context.CancelFunc) provider.ImageModelStream {
+	// Image events carry whole base64 images on a single line.
+	return &imageStream{lines: providerutil.NewLineReader(ctx, body, cancel, 64*1024*1024)}
+}
+
+func (s *imageStream) Next(ctx context.Context) (*provider.ImageDelta, error) {
+	if s.done {
+		return &provider.ImageDelta{Done: true}, nil
+	}
+
+	for {
+		line, err := s.lines.Next(ctx)
+		if err == io.EOF {
+			s.done = true
+			return &provider.ImageDelta{Done: true}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			s.done = true
+			return &provider.ImageDelta{Done: true}, nil
+		}
+
+		var ev openAIImageStreamEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return nil, err
+		}
+		switch ev.Type {
+		case "image_generation.partial_image":
+			img, err := base64.StdEncoding.DecodeString(ev.B64JSON)
+			if err != nil {
+				return nil, err
+			}
+			return &provider.ImageDelta{Partial: &provider.PartialImage{Index: ev.PartialImageIndex, Data: img}}, nil
+		case "image_generation.completed":
+			img, err := base64.StdEncoding.DecodeString(ev.B64JSON)
+			if err != nil {
+				return nil, err
+			}
+			return &provider.ImageDelta{Image: &provider.Image{Data: img}}, nil
+		}
+	}
+}
+
+func (s *imageStream) Close() error {
+	s.done = true
+	return s.lines.Close()
+}
@@ -1,19 +1,25 @@
 package openai
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"os"
+	"path"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/ncecere/ai-sdk/provider"
 	"github.com/ncecere/ai-sdk/providerutil"
@@ -29,6 +35,44 @@ type Client struct {
 	apiKey     string
 	httpClient provider.HTTPClient
 	headers    http.Header
+	betas      []string
+	orphanTool provider.OrphanToolPolicy
+	systemOnly provider.SystemOnlyPolicy
+	onUnknown  func(source string, fields []string)
+	onLint     func(source string, warnings []string)
+	onDropped  func(source string, dropped []provider.DroppedOption)
+	strict     bool
+	// unsupported holds ClientOptions.UnsupportedOptions.
+	unsupported []string
+	// remoteImages is non-nil when ClientOptions.FetchRemoteImages is
+	// set.
+	remoteImages *provider.RemoteImageOptions
+	limits       providerutil.Limits
+	defaultStore *bool
+	defaultUser  string
+	deadlineHint *provider.DeadlineHintOptions
+	maxMessages  int
+}
+
+// do sends req, bounding the size of a successful response body by
+// ClientOptions.MaxResponseBytes. Language-model streams are bounded by
+// content instead; see providerutil.Limits.LimitStream.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	c.limits.LimitBody(resp, "openai")
+	return resp, nil
+}
+
+// reportUnknown returns the unknown-field reporter for source, or nil
+// when no OnUnknownFields hook is configured.
+func (c *Client) reportUnknown(source string) func([]string) {
+	if c.onUnknown == nil {
+		return nil
+	}
+	return func(fields []string) { c.onUnknown(source, fields) }
 }
 
 func (c *Client) chatCompletionsURL() string {
@@ -66,6 +110,41 @@ func (c *Client) audioTranscriptionsURL() string {
 	return c.baseURL + "/v1/audio/transcriptions"
 }
 
+// newRequest creates a POST request to url with the client's custom
+// headers applied first and the required authentication and content
+// type headers enforced afterwards.
+func (c *Client) newRequest(ctx context.Context, url string, body io.Reader, contentType string) (*http.Request, error) {
+	return c.newMethodRequest(ctx, http.MethodPost, url, body, contentType)
+}
+
+// newMethodRequest is newRequest for any method. An empty contentType
+// sets no Content-Type header.
+func (c *Client) newMethodRequest(ctx context.Context, method, url string, body io.Reader, contentType string) (*http.Request, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range c.headers {
+		for _, v := range vs {
+			if v == "" {
+				continue
+			}
+			httpReq.Header.Add(k, v)
+		}
+	}
+	providerutil.SetContextHeaders(ctx, httpReq.Header, "Authorization", "Content-Type")
+	providerutil.SetBetaHeader(httpReq.Header, betaHeader, c.betas)
+	providerutil.SetTraceHeader(ctx, httpReq.Header)
+	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if contentType != "" {
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+	return httpReq, nil
+}
+
+// betaHeader is the header OpenAI uses to opt in to beta features.
+const betaHeader = "OpenAI-Beta"
+
 // NewClient creates a new OpenAI client.
 // It follows Vercel's pattern of reading configuration from environment
 // variables by default.
@@ -73,7 +152,20 @@ func (c *Client) audioTranscriptionsURL() string {
 // Environment variables:
 //   - OPENAI_API_KEY (required if opts.APIKey is empty)
 //   - OPENAI_BASE_URL (optional, defaults to https://api.openai.com)
+//   - OPENAI_EXTRA_HEADERS (read with AI_SDK_DEFAULT_HEADERS if
+//     opts.HeadersFromEnv is set)
+//
+// opts.UnsupportedOptions accepts the LanguageModelRequest field names
+// Temperature, TopP, Stop, ResponseFormat (which covers JSONSchema),
+// ToolChoice, ParallelToolCalls, LogitBias, FrequencyPenalty,
+// PresencePenalty, Logprobs, TopLogprobs, Reasoning, Metadata, UserID,
+// and Store; other names fail client construction.
 func NewClient(opts provider.ClientOptions) (*Client, error) {
+	for _, name := range opts.UnsupportedOptions {
+		if unsupportedOptions[name] == nil {
+			return nil, fmt.Errorf("openai: unknown UnsupportedOptions field %q", name)
+		}
+	}
 	apiKey := opts.APIKey
 	if apiKey == "" {
 		apiKey = os.Getenv("OPENAI_API_KEY")
@@ -91,17 +183,45 @@ func NewClient(opts provider.ClientOptions) (*Client, error) {
 	}
 	baseURL = strings.TrimRight(baseURL, "/")
 
-	hc := opts.HTTPClient
-	if hc == nil {
-		hc = providerutil.DefaultHTTPClient()
+	hc, err := providerutil.ClientHTTPClient(opts)
+	if err != nil {
+		return nil, fmt.Errorf("openai: %w", err)
 	}
 
-	return &Client{
-		baseURL:    baseURL,
-		apiKey:     apiKey,
-		httpClient: hc,
-		headers:    opts.Headers,
-	}, nil
+	headers, err := providerutil.ClientHeaders(opts, "OPENAI_EXTRA_HEADERS")
+	if err != nil {
+		return nil, fmt.Errorf("openai: %w", err)
+	}
+
+	c := &Client{
+		baseURL:     baseURL,
+		apiKey:      apiKey,
+		httpClient:  hc,
+		headers:     headers,
+		betas:       opts.Betas,
+		orphanTool:  opts.OrphanToolMessages,
+		systemOnly:  opts.SystemOnly,
+		onUnknown:   opts.OnUnknownFields,
+		onLint:      providerutil.LintReporter(opts.Debug, opts.OnLintWarnings),
+		onDropped:   opts.OnDroppedOptions,
+		strict:      opts.StrictOptions,
+		unsupported: slices.Clone(opts.UnsupportedOptions),
+		limits:      providerutil.NewLimits(opts),
+		maxMessages: opts.MaxMessages,
+		defaultUser: opts.DefaultUserID,
+	}
+	if opts.DeadlineHint != nil {
+		hint := *opts.DeadlineHint
+		c.deadlineHint = &hint
+	}
+	if opts.DefaultStore != nil {
+		store := *opts.DefaultStore
+		c.defaultStore = &store
+	}
+	if opts.FetchRemoteImages {
+		c.remoteImages = &opts.RemoteImages
+	}
+	return c, nil
 }
 
 // ChatModel returns a LanguageModel for the given chat model ID.
@@ -134,9 +254,59 @@ type chatModel struct {
 	model  string
 }
 
+// MaxMessages implements provider.MessageLimiter with
+// ClientOptions.MaxMessages.
+func (m *chatModel) MaxMessages() int {
+	if m.client == nil {
+		return 0
+	}
+	return m.client.maxMessages
+}
+
 type openAIChatMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role string `json:"role"`
+	// Content is either a plain string or a slice of openAIContentPart,
+	// or nil for an assistant message with only tool calls.
+	Content    any              `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// toOpenAIToolCalls renders calls in the wire format, with arguments as
+// a JSON string. Arguments that already are a JSON string (as returned
+// by Generate) are not quoted again.
+func toOpenAIToolCalls(calls []provider.ToolCall) []openAIToolCall {
+	out := make([]openAIToolCall, 0, len(calls))
+	for _, tc := range calls {
+		wire := openAIToolCall{ID: tc.ID, Type: "function"}
+		wire.Function.Name = tc.Name
+		wire.Function.Arguments = string(tc.RawArguments)
+		var s string
+		if json.Unmarshal(tc.RawArguments, &s) == nil {
+			wire.Function.Arguments = s
+		}
+		out = append(out, wire)
+	}
+	return out
+}
+
+type openAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+}
+
+type openAIImageURL struct {
+	URL string `json:"url"`
 }
 
 type openAIChatTool struct {
@@ -160,7 +330,25 @@ type openAIChatRequest struct {
 	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
 	Tools          []openAIChatTool      `json:"tools,omitempty"`
 	ToolChoice     any                   `json:"tool_choice,omitempty"`
-	Stream         bool                  `json:"stream,omitempty"`
+	// ParallelToolCalls is only sent with tools; OpenAI rejects it
+	// otherwise.
+	ParallelToolCalls *bool              `json:"parallel_tool_calls,omitempty"`
+	LogitBias         map[string]float64 `json:"logit_bias,omitempty"`
+	FrequencyPenalty  *float64           `json:"frequency_penalty,omitempty"`
+	PresencePenalty   *float64           `json:"presence_penalty,omitempty"`
+	Logprobs          *bool              `json:"logprobs,omitempty"`
+	TopLogprobs       *int               `json:"top_logprobs,omitempty"`
+	ReasoningEffort   string             `json:"reasoning_effort,omitempty"`
+	Metadata          map[string]string  `json:"metadata,omitempty"`
+	User              string             `json:"user,omitempty"`
+	Store             *bool              `json:"store,omitempty"`
+	Stream            bool               `json:"stream,omitempty"`
+	// StreamOptions requests a final usage chunk on streams.
+	StreamOptions *openAIStreamOptions `json:"stream_options,omitempty"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 type openAIResponseFormat struct {
@@ -170,6 +358,7 @@ type openAIResponseFormat struct {
 
 type openAIJSONSchema struct {
 	Name   string          `json:"name"`
+	Strict bool            `json:"strict,omitempty"`
 	Schema json.RawMessage `json:"schema"`
 }
 
@@ -177,9 +366,13 @@ type openAIChatResponse struct {
 	Choices []struct {
 		FinishReason string `json:"finish_reason"`
 		Message      struct {
-			Role      string `json:"role"`
-			Content   string `json:"content"`
-			ToolCalls []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+			// ReasoningContent (DeepSeek, vLLM) and Reasoning (OpenRouter,
+			// Groq) carry reasoning output on compatible servers.
+			ReasoningContent string `json:"reasoning_content"`
+			Reasoning        string `json:"reasoning"`
+			ToolCalls        []struct {
 				ID       string `json:"id"`
 				Type     string `json:"type"`
 				Function struct {
@@ -188,14 +381,77 @@ type openAIChatResponse struct {
 				} `json:"function"`
 			} `json:"tool_calls"`
 		} `json:"message"`
+		Logprobs *openAILogprobs `json:"logprobs"`
 	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+}
+
+type openAILogprobs struct {
+	Content []struct {
+		Token       string  `json:"token"`
+		Logprob     float64 `json:"logprob"`
+		TopLogprobs []struct {
+			Token   string  `json:"token"`
+			Logprob float64 `json:"logprob"`
+		} `json:"top_logprobs"`
+	} `json:"content"`
+}
+
+// logprobs converts the logprobs of a choice, which is nil unless they
+// were requested.
+func (l *openAILogprobs) logprobs() []provider.TokenLogprob {
+	if l == nil || len(l.Content) == 0 {
+		return nil
+	}
+	out := make([]provider.TokenLogprob, len(l.Content))
+	for i, c := range l.Content {
+		out[i] = provider.TokenLogprob{Token: c.Token, Logprob: c.Logprob}
+		for _, alt := range c.TopLogprobs {
+			out[i].TopAlternatives = append(out[i].TopAlternatives, provider.TokenAlternative{Token: alt.Token, Logprob: alt.Logprob})
+		}
+	}
+	return out
+}
+
+type openAIUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	TotalTokens         int `json:"total_tokens"`
+	PromptTokensDetails *struct {
+		CachedTokens *int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+}
+
+// usage converts the wire usage, or returns nil if it was not reported.
+// Cache usage is set when the backend reports cached_tokens, which
+// OpenAI does for every model with automatic prompt caching.
+func (u *openAIUsage) usage() *provider.Usage {
+	if u == nil {
+		return nil
+	}
+	total := u.TotalTokens
+	if total == 0 {
+		total = u.PromptTokens + u.CompletionTokens
+	}
+	usage := &provider.Usage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens, TotalTokens: total}
+	if d := u.PromptTokensDetails; d != nil && d.CachedTokens != nil {
+		usage.Cache = &provider.CacheUsage{CachedInputTokens: *d.CachedTokens}
+	}
+	return usage
 }
 
 type openAIChatStreamChunk struct {
+	// Error is set on in-band error chunks.
+	Error   json.RawMessage `json:"error"`
 	Choices []struct {
 		Delta struct {
-			Content   string `json:"content"`
-			ToolCalls []struct {
+			// Text fields keep invalid UTF-8 so characters split
+			// across chunks can be reassembled.
+			Content          providerutil.RawString `json:"content"`
+			ReasoningContent providerutil.RawString `json:"reasoning_content"`
+			Reasoning        providerutil.RawString `json:"reasoning"`
+			ToolCalls        []struct {
+				Index    *int   `json:"index"`
 				ID       string `json:"id"`
 				Type     string `json:"type"`
 				Function struct {
@@ -206,28 +462,61 @@ type openAIChatStreamChunk struct {
 		} `json:"delta"`
 		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
+	// Usage is sent in a chunk without choices after the last one when
+	// stream_options.include_usage is set.
+	Usage *openAIUsage `json:"usage"`
 }
 
-func (m *chatModel) Generate(ctx context.Context, req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
+// buildBody maps a provider-level request onto the OpenAI chat
+// completions wire format.
+func (m *chatModel) buildBody(req *provider.LanguageModelRequest, stream bool) openAIChatRequest {
 	body := openAIChatRequest{
-		Model: m.model,
+		Model:  m.model,
+		Stream: stream,
 	}
-	for _, msg := range req.Messages {
-		body.Messages = append(body.Messages, openAIChatMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		})
+	if stream {
+		body.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
 	}
+	body.Messages = toOpenAIMessages(req.Messages)
 	body.Temperature = req.Temperature
 	body.TopP = req.TopP
 	body.MaxTokens = req.MaxTokens
 	body.Stop = req.Stop
-
-	if len(req.JSONSchema) > 0 {
+	body.LogitBias = req.LogitBias
+	body.FrequencyPenalty = req.FrequencyPenalty
+	body.PresencePenalty = req.PresencePenalty
+	body.Logprobs = req.Logprobs
+	if req.TopLogprobs != nil {
+		// OpenAI rejects top_logprobs unless logprobs is true.
+		enabled := true
+		body.Logprobs = &enabled
+		body.TopLogprobs = req.TopLogprobs
+	}
+	if req.Reasoning != nil {
+		body.ReasoningEffort = req.Reasoning.Effort
+	}
+	if len(req.Metadata) > 0 {
+		body.Metadata = req.Metadata
+	}
+	body.User = req.UserID
+	body.Store = req.Store
+	if body.Store == nil && m.client != nil {
+		body.Store = m.client.defaultStore
+	}
+
+	switch {
+	case req.ResponseFormat == provider.ResponseFormatJSON:
+		body.ResponseFormat = &openAIResponseFormat{Type: "json_object"}
+	case len(req.JSONSchema) > 0:
+		name := req.JSONSchemaName
+		if name == "" {
+			name = "response"
+		}
 		body.ResponseFormat = &openAIResponseFormat{
 			Type: "json_schema",
 			JSONSchema: &openAIJSONSchema{
-				Name:   "response",
+				Name:   name,
+				Strict: req.JSONSchemaStrict,
 				Schema: json.RawMessage(req.JSONSchema),
 			},
 		}
@@ -244,46 +533,347 @@ func (m *chatModel) Generate(ctx context.Context, req *provider.LanguageModelReq
 				},
 			})
 		}
+		body.ToolChoice = openAIToolChoice(req.ToolChoice)
+		body.ParallelToolCalls = req.ParallelToolCalls
 	}
 
-	buf, err := json.Marshal(body)
+	return body
+}
+
+// openAIToolChoice maps choice onto tool_choice: a string mode, or a
+// function object for a named tool. Empty choices are omitted.
+func openAIToolChoice(choice provider.ToolChoice) any {
+	if name, ok := choice.Tool(); ok {
+		return map[string]any{"type": "function", "function": map[string]string{"name": name}}
+	}
+	if choice == "" {
+		return nil
+	}
+	return string(choice)
+}
+
+// orphanToolPrefix is prepended to tool messages downgraded to user
+// messages.
+const orphanToolPrefix = "[tool result] "
+
+// prepareToolMessages applies the orphan tool message policy. Tool
+// messages in a request that declares no tools are rejected by OpenAI,
+// so they are either rewritten as user messages or reported as an
+// *provider.OrphanToolMessageError. The caller's request is not
+// modified.
+func (m *chatModel) prepareToolMessages(req *provider.LanguageModelRequest) (*provider.LanguageModelRequest, error) {
+	if len(req.Tools) > 0 {
+		return req, nil
+	}
+
+	policy := req.OrphanToolMessages
+	if policy == "" {
+		policy = m.client.orphanTool
+	}
+
+	var msgs []provider.Message
+	for i, msg := range req.Messages {
+		if msg.Role == "assistant" && len(msg.ToolCalls) > 0 && policy != provider.OrphanToolError {
+			// The tool messages answering these calls are downgraded
+			// below, and OpenAI rejects tool_calls that are not followed
+			// by their tool messages.
+			if msgs == nil {
+				msgs = append([]provider.Message(nil), req.Messages...)
+			}
+			msgs[i].ToolCalls = nil
+			continue
+		}
+		if msg.Role != "tool" {
+			continue
+		}
+		if policy == provider.OrphanToolError {
+			return nil, &provider.OrphanToolMessageError{Provider: "openai", Index: i}
+		}
+		if msgs == nil {
+			msgs = append([]provider.Message(nil), req.Messages...)
+		}
+		msg.Role = "user"
+		msg.Content = orphanToolPrefix + msg.Content
+		if len(msg.Parts) > 0 {
+			parts := make([]provider.ContentPart, 0, len(msg.Parts)+1)
+			parts = append(parts, provider.ContentPart{Type: provider.ContentPartText, Text: strings.TrimSpace(orphanToolPrefix)})
+			msg.Parts = append(parts, msg.Parts...)
+		}
+		msgs[i] = msg
+	}
+	if msgs == nil {
+		return req, nil
+	}
+
+	out := *req
+	out.Messages = msgs
+	return &out, nil
+}
+
+// prepareSystemMessages applies the system-only policy. OpenAI accepts
+// any number of system messages at any position, so they are sent in
+// place and in order; only requests without a non-system message are
+// rejected or completed with a user message. The caller's request is
+// not modified.
+func (m *chatModel) prepareSystemMessages(req *provider.LanguageModelRequest) (*provider.LanguageModelRequest, error) {
+	policy := req.SystemOnly
+	if policy == "" {
+		policy = m.client.systemOnly
+	}
+	msgs, err := providerutil.EnsureConversation("openai", req.Messages, policy)
 	if err != nil {
 		return nil, err
 	}
+	if len(msgs) == len(req.Messages) {
+		return req, nil
+	}
+	out := *req
+	out.Messages = msgs
+	return &out, nil
+}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, m.client.chatCompletionsURL(), bytes.NewReader(buf))
+// prepareImages inlines images referenced by URL when FetchRemoteImages
+// is set and providerutil.ImageURLSupport lists OpenAI as not accepting
+// URLs; by default it leaves the request unchanged. The caller's
+// request is not modified.
+func (m *chatModel) prepareImages(ctx context.Context, req *provider.LanguageModelRequest) (*provider.LanguageModelRequest, error) {
+	if m.client.remoteImages == nil {
+		return req, nil
+	}
+	msgs, err := providerutil.InlineRemoteImages(ctx, "openai", req.Messages, *m.client.remoteImages)
 	if err != nil {
 		return nil, err
 	}
-	// Attach any custom headers first, then enforce required headers.
-	for k, vs := range m.client.headers {
-		for _, v := range vs {
-			if v == "" {
-				continue
+	out := *req
+	out.Messages = msgs
+	return &out, nil
+}
+
+// toOpenAIMessages maps provider messages onto chat messages. Messages
+// with Parts are sent as content arrays, and tool messages carry their
+// ToolCallID as tool_call_id. OpenAI does not accept images
+// in tool messages, so image parts of tool results are forwarded in one
+// user message after the last of the consecutive tool messages: every
+// tool message must directly follow the assistant tool calls.
+func toOpenAIMessages(msgs []provider.Message) []openAIChatMessage {
+	out := make([]openAIChatMessage, 0, len(msgs))
+	var pending []openAIContentPart
+	for _, msg := range msgs {
+		var toolCallID string
+		if msg.Role == "tool" {
+			toolCallID = msg.ToolCallID
+		} else if len(pending) > 0 {
+			out = append(out, openAIChatMessage{Role: "user", Content: pending})
+			pending = nil
+		}
+		if msg.Role == "assistant" && len(msg.ToolCalls) > 0 {
+			wire := openAIChatMessage{Role: msg.Role, ToolCalls: toOpenAIToolCalls(msg.ToolCalls)}
+			if msg.Content != "" {
+				wire.Content = msg.Content
 			}
-			httpReq.Header.Add(k, v)
+			out = append(out, wire)
+			continue
+		}
+		if len(msg.Parts) == 0 {
+			out = append(out, openAIChatMessage{Role: msg.Role, Content: msg.Content, ToolCallID: toolCallID})
+			continue
 		}
+
+		var parts, images []openAIContentPart
+		for _, p := range msg.Parts {
+			switch p.Type {
+			case provider.ContentPartText:
+				parts = append(parts, openAIContentPart{Type: "text", Text: p.Text})
+			case provider.ContentPartImage:
+				img := openAIContentPart{Type: "image_url", ImageURL: &openAIImageURL{URL: imageURL(p)}}
+				if msg.Role == "tool" {
+					images = append(images, img)
+				} else {
+					parts = append(parts, img)
+				}
+			}
+		}
+		if len(parts) == 0 {
+			parts = append(parts, openAIContentPart{Type: "text", Text: msg.Content})
+		}
+		out = append(out, openAIChatMessage{Role: msg.Role, Content: parts, ToolCallID: toolCallID})
+		pending = append(pending, images...)
+	}
+	if len(pending) > 0 {
+		out = append(out, openAIChatMessage{Role: "user", Content: pending})
 	}
-	httpReq.Header.Set("Authorization", "Bearer "+m.client.apiKey)
-	httpReq.Header.Set("Content-Type", "application/json")
+	return out
+}
 
-	resp, err := m.client.httpClient.Do(httpReq)
+// imageURL returns the URL for an image part, encoding inline data as a
+// base64 data URI.
+func imageURL(p provider.ContentPart) string {
+	if len(p.Data) == 0 {
+		return p.ImageURL
+	}
+	mt := p.MimeType
+	if mt == "" {
+		mt = "image/png"
+	}
+	return "data:" + mt + ";base64," + base64.StdEncoding.EncodeToString(p.Data)
+}
+
+// OpenAI's limits on request metadata.
+const (
+	maxMetadataPairs    = 16
+	maxMetadataKeyLen   = 64
+	maxMetadataValueLen = 512
+)
+
+// validateMetadata checks metadata against OpenAI's limits, which the API
+// otherwise reports as an opaque 400. Lengths count characters.
+func validateMetadata(metadata map[string]string) error {
+	if len(metadata) > maxMetadataPairs {
+		return &provider.InvalidMetadataError{Provider: "openai", Message: fmt.Sprintf("%d pairs exceed the limit of %d", len(metadata), maxMetadataPairs)}
+	}
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if n := utf8.RuneCountInString(k); n > maxMetadataKeyLen {
+			return &provider.InvalidMetadataError{Provider: "openai", Key: k, Message: fmt.Sprintf("key has %d characters, the limit is %d", n, maxMetadataKeyLen)}
+		}
+		if n := utf8.RuneCountInString(metadata[k]); n > maxMetadataValueLen {
+			return &provider.InvalidMetadataError{Provider: "openai", Key: k, Message: fmt.Sprintf("value has %d characters, the limit is %d", n, maxMetadataValueLen)}
+		}
+	}
+	return nil
+}
+
+// buildRequest constructs the HTTP request for a chat call. It is the
+// single code path used by Generate, Stream, and BuildRequest so that
+// dry runs render exactly what would be sent. It also returns the
+// request options left out of the body.
+func (m *chatModel) buildRequest(ctx context.Context, req *provider.LanguageModelRequest, stream bool) (*http.Request, []byte, []provider.DroppedOption, error) {
+	req, err := m.prepareToolMessages(req)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	req, err = m.prepareSystemMessages(req)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	req, err = m.prepareImages(ctx, req)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := validateMetadata(req.Metadata); err != nil {
+		return nil, nil, nil, err
+	}
+	if userID := providerutil.UserID(ctx, req.UserID, m.client.defaultUser); userID != req.UserID {
+		withUser := *req
+		withUser.UserID = userID
+		req = &withUser
+	}
+
+	body := m.buildBody(req, stream)
+	dropped := m.dropOptions(req, &body)
+	if err := providerutil.CheckDroppedOptions("openai", dropped, m.client.strict); err != nil {
+		return nil, nil, nil, err
+	}
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	buf, collisions, err := providerutil.MergeBodyFields(buf, req.ProviderOptions)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if m.client != nil && m.client.onLint != nil {
+		if w := lintProviderOptions(req.ProviderOptions, collisions); len(w) > 0 {
+			m.client.onLint("openai.chat", w)
+		}
+	}
+	hint, err := providerutil.NewDeadlineHint(ctx, m.client.deadlineHint, time.Now())
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("openai: %w", err)
+	}
+	if buf, err = hint.MergeBody(buf); err != nil {
+		return nil, nil, nil, err
+	}
+
+	httpReq, err := m.client.newRequest(ctx, m.client.chatCompletionsURL(), bytes.NewReader(buf), "application/json")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	hint.SetHeader(httpReq.Header)
+	providerutil.SetBetaHeader(httpReq.Header, betaHeader, req.Betas)
+	if stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
+	return httpReq, buf, dropped, nil
+}
+
+// BuildRequest implements provider.RequestBuilder.
+func (m *chatModel) BuildRequest(ctx context.Context, req *provider.LanguageModelRequest) (*http.Request, []byte, error) {
+	httpReq, buf, _, err := m.buildRequest(ctx, req, false)
+	return httpReq, buf, err
+}
+
+// BuildStreamRequest implements provider.StreamRequestBuilder.
+func (m *chatModel) BuildStreamRequest(ctx context.Context, req *provider.LanguageModelRequest) (*http.Request, []byte, error) {
+	httpReq, buf, _, err := m.buildRequest(ctx, req, true)
+	return httpReq, buf, err
+}
+
+func (m *chatModel) Generate(ctx context.Context, req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
+	httpReq, buf, dropped, err := m.buildRequest(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+	provider.ObserveRequest(ctx, httpReq, buf)
+	m.client.reportDropped(dropped)
+
+	resp, err := m.client.do(httpReq)
 	if err != nil {
 		return nil, err
 	}
 
 	var out openAIChatResponse
-	if err := providerutil.ReadJSON(resp, &out); err != nil {
+	raw, err := providerutil.ReadJSONWithBody(resp, &out)
+	if err != nil {
+		return nil, providerutil.TagProvider(err, "openai")
+	}
+	lmResp, err := m.chatResponse(req, &out, raw, resp.StatusCode, providerutil.ResponseMetadata(resp))
+	if err != nil {
 		return nil, err
 	}
+	lmResp.Metadata.DroppedOptions = dropped
+	return lmResp, nil
+}
+
+// chatResponse maps a decoded chat completion, with its raw body and
+// status code, onto a LanguageModelResponse for req.
+func (m *chatModel) chatResponse(req *provider.LanguageModelRequest, out *openAIChatResponse, raw []byte, statusCode int, metadata *provider.ResponseMetadata) (*provider.LanguageModelResponse, error) {
+	providerutil.ReportUnknownFields(raw, out, m.client.reportUnknown("openai.chat"))
 	if len(out.Choices) == 0 {
-		return &provider.LanguageModelResponse{}, nil
+		// A 2xx response without choices is a malformed body (commonly
+		// produced by gateways under failure), not an empty answer.
+		return nil, &provider.EmptyResponseError{
+			Provider:   "openai",
+			StatusCode: statusCode,
+			Body:       providerutil.Snippet(raw, 512),
+		}
 	}
 
 	choice := out.Choices[0]
+	if choice.FinishReason == "content_filter" {
+		return nil, contentFilterError()
+	}
 	lmResp := &provider.LanguageModelResponse{
 		Text:       choice.Message.Content,
+		Reasoning:  firstNonEmpty(choice.Message.ReasoningContent, choice.Message.Reasoning),
 		StopReason: choice.FinishReason,
+		Usage:      out.Usage.usage(),
+		Logprobs:   choice.Logprobs.logprobs(),
+		Metadata:   metadata,
 	}
 	for _, tc := range choice.Message.ToolCalls {
 		if tc.Type != "function" {
@@ -295,112 +885,226 @@ func (m *chatModel) Generate(ctx context.Context, req *provider.LanguageModelReq
 			RawArguments: []byte(tc.Function.Arguments),
 		})
 	}
+	if len(lmResp.ToolCalls) > 0 {
+		// Some compatible backends report "stop" alongside tool calls;
+		// tool calls always decide the finish reason.
+		lmResp.StopReason = "tool_calls"
+	}
+	lmResp.FinishReason = finishReason(lmResp.StopReason, len(lmResp.ToolCalls) > 0)
+	if len(req.Tools) > 0 && len(lmResp.ToolCalls) == 0 {
+		lmResp.ToolDiagnostics = &provider.ToolDiagnostics{
+			FinishReason:        choice.FinishReason,
+			ToolCallsKeyPresent: hasToolCallsKey(raw),
+			TextPresent:         lmResp.Text != "",
+		}
+	}
 
 	return lmResp, nil
 }
 
-func (m *chatModel) Stream(ctx context.Context, req *provider.LanguageModelRequest) (provider.LanguageModelStream, error) {
-	body := openAIChatRequest{
-		Model:  m.model,
-		Stream: true,
-	}
-	for _, msg := range req.Messages {
-		body.Messages = append(body.Messages, openAIChatMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		})
-	}
-	body.Temperature = req.Temperature
-	body.TopP = req.TopP
-	body.MaxTokens = req.MaxTokens
-	body.Stop = req.Stop
-
-	if len(req.JSONSchema) > 0 {
-		body.ResponseFormat = &openAIResponseFormat{
-			Type: "json_schema",
-			JSONSchema: &openAIJSONSchema{
-				Name:   "response",
-				Schema: json.RawMessage(req.JSONSchema),
-			},
+// finishReason maps an OpenAI finish_reason onto a
+// provider.FinishReason. "tool_calls" without parsed tool calls, such
+// as calls of a type this client skips, counts as other.
+func finishReason(stop string, toolCalls bool) provider.FinishReason {
+	switch stop {
+	case "":
+		return ""
+	case "stop":
+		return provider.FinishReasonStop
+	case "length":
+		return provider.FinishReasonLength
+	case "tool_calls", "function_call":
+		if toolCalls {
+			return provider.FinishReasonToolCalls
 		}
 	}
+	return provider.FinishReasonOther
+}
 
-	if len(req.Tools) > 0 {
-		for _, t := range req.Tools {
-			body.Tools = append(body.Tools, openAIChatTool{
-				Type: "function",
-				Function: openAIFunctionTool{
-					Name:        t.Name,
-					Description: t.Description,
-					Parameters:  json.RawMessage(t.Parameters),
-				},
-			})
-		}
+// hasToolCallsKey reports whether the first choice's message in a raw
+// chat response contains a "tool_calls" key.
+func hasToolCallsKey(raw []byte) bool {
+	var probe struct {
+		Choices []struct {
+			Message map[string]json.RawMessage `json:"message"`
+		} `json:"choices"`
 	}
+	if err := json.Unmarshal(raw, &probe); err != nil || len(probe.Choices) == 0 {
+		return false
+	}
+	_, ok := probe.Choices[0].Message["tool_calls"]
+	return ok
+}
 
-	buf, err := json.Marshal(body)
-	if err != nil {
-		return nil, err
+// firstNonEmpty returns the first non-empty string in vals.
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
 	}
+	return ""
+}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, m.client.chatCompletionsURL(), bytes.NewReader(buf))
+// Stream starts a streaming chat completion. The stream lives until
+// ctx is done or the stream is closed; see provider.LanguageModelStream.
+func (m *chatModel) Stream(ctx context.Context, req *provider.LanguageModelRequest) (provider.LanguageModelStream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	httpReq, buf, dropped, err := m.buildRequest(ctx, req, true)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
-	for k, vs := range m.client.headers {
-		for _, v := range vs {
-			if v == "" {
-				continue
-			}
-			httpReq.Header.Add(k, v)
-		}
-	}
-	httpReq.Header.Set("Authorization", "Bearer "+m.client.apiKey)
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Accept", "text/event-stream")
+	provider.ObserveRequest(ctx, httpReq, buf)
+	m.client.reportDropped(dropped)
 
 	resp, err := m.client.httpClient.Do(httpReq)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
+	if err := providerutil.CheckStatus(resp); err != nil {
+		cancel()
+		return nil, providerutil.TagProvider(err, "openai")
+	}
 
-	return newChatStream(resp.Body), nil
+	stream := newChatStream(ctx, resp.Body, cancel)
+	stream.metadata = providerutil.ResponseMetadata(resp)
+	stream.metadata.DroppedOptions = dropped
+	return m.client.limits.LimitStream(stream, "openai"), nil
 }
 
+// chatStream implements provider.LanguageModelStream for chat
+// completions. Tool-call fragments are passed on as ToolCallDeltas as
+// they arrive, and also assembled by index and delivered whole on the
+// final delta, after all text. A body that ends before
+// "data: [DONE]" or a finish_reason fails with a
+// *provider.IncompleteStreamError, an in-band error chunk with a
+// *provider.APIError, and a "content_filter" finish with a
+// *provider.ContentBlockedError.
 type chatStream struct {
-	body    io.ReadCloser
-	scanner *bufio.Scanner
-	done    bool
+	lines *providerutil.LineReader
+	done  bool
+	// err is an in-band error, returned by every later call.
+	err error
+	// finished is set once a finish_reason was seen. The final delta
+	// waits for [DONE], which follows the usage chunk, but a backend
+	// that closes the connection instead still ends the stream cleanly.
+	finished bool
+	calls    []streamedToolCall
+	usage    *provider.Usage
+	// stop is the finish_reason, and metadata describes the response.
+	stop     string
+	metadata *provider.ResponseMetadata
+	// text and reasoning reassemble characters split across chunks.
+	text, reasoning providerutil.UTF8Joiner
+}
+
+// streamedToolCall accumulates the fragments of one streamed tool call.
+type streamedToolCall struct {
+	id, name string
+	args     strings.Builder
+	// rawArgs is set when a fragment was not a JSON string, as sent by
+	// some compatible backends; args then holds raw JSON.
+	rawArgs bool
+}
+
+// addToolCall adds a fragment at index, or after the last call if the
+// backend sent no index, and returns it as a provider.ToolCallDelta.
+func (s *chatStream) addToolCall(index *int, id, name string, args json.RawMessage) provider.ToolCallDelta {
+	i := len(s.calls) - 1
+	switch {
+	case index != nil:
+		i = *index
+	case id != "" || i < 0:
+		i = len(s.calls)
+	}
+	for len(s.calls) <= i {
+		s.calls = append(s.calls, streamedToolCall{})
+	}
+	c := &s.calls[i]
+	if id != "" {
+		c.id = id
+	}
+	if name != "" {
+		c.name = name
+	}
+	var fragment string
+	if len(args) > 0 && json.Unmarshal(args, &fragment) != nil {
+		c.rawArgs = true
+		fragment = string(args)
+	}
+	c.args.WriteString(fragment)
+	return provider.ToolCallDelta{Index: i, ID: id, Name: name, Arguments: fragment}
 }
 
-func newChatStream(body io.ReadCloser) provider.LanguageModelStream {
-	scanner := bufio.NewScanner(body)
-	// Increase buffer for long lines
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 1024*1024)
-	return &chatStream{
-		body:    body,
-		scanner: scanner,
+// final returns the last delta of the stream, carrying the assembled
+// tool calls and the finish reason. Arguments keep the JSON string form
+// returned by Generate.
+func (s *chatStream) final() *provider.LanguageModelDelta {
+	s.done = true
+	delta := &provider.LanguageModelDelta{
+		Text:       s.text.Flush(),
+		Reasoning:  s.reasoning.Flush(),
+		Done:       true,
+		Usage:      s.usage,
+		StopReason: s.stop,
+		Metadata:   s.metadata,
+	}
+	for i := range s.calls {
+		c := &s.calls[i]
+		args := []byte(c.args.String())
+		if !c.rawArgs {
+			args, _ = json.Marshal(c.args.String())
+		}
+		delta.ToolCalls = append(delta.ToolCalls, provider.ToolCall{ID: c.id, Name: c.name, RawArguments: args})
 	}
+	s.calls = nil
+	if len(delta.ToolCalls) > 0 {
+		delta.StopReason = "tool_calls"
+	}
+	delta.FinishReason = finishReason(delta.StopReason, len(delta.ToolCalls) > 0)
+	return delta
+}
+
+// finish ends the stream with the final delta, or with a
+// *provider.ContentBlockedError if the content filter stopped it.
+func (s *chatStream) finish() (*provider.LanguageModelDelta, error) {
+	if s.stop == "content_filter" {
+		s.err = contentFilterError()
+		return nil, s.err
+	}
+	return s.final(), nil
+}
+
+// contentFilterError is returned in place of a response whose
+// finish_reason is "content_filter", so filtered responses are
+// distinguishable from empty answers.
+func contentFilterError() error {
+	return &provider.ContentBlockedError{Provider: "openai", Reason: "content_filter"}
+}
+
+func newChatStream(ctx context.Context, body io.ReadCloser, cancel context.CancelFunc) *chatStream {
+	return &chatStream{lines: providerutil.NewLineReader(ctx, body, cancel, 1024*1024)}
 }
 
 func (s *chatStream) Next(ctx context.Context) (*provider.LanguageModelDelta, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
 	if s.done {
 		return &provider.LanguageModelDelta{Done: true}, nil
 	}
 
 	for {
-		if err := ctx.Err(); err != nil {
-			return nil, err
-		}
-		if !s.scanner.Scan() {
-			if err := s.scanner.Err(); err != nil {
-				return nil, err
+		line, err := s.lines.Next(ctx)
+		if err != nil {
+			if s.finished && (errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)) {
+				return s.finish()
 			}
-			s.done = true
-			return &provider.LanguageModelDelta{Done: true}, nil
+			return nil, providerutil.IncompleteStream("openai", err)
 		}
-		line := strings.TrimSpace(s.scanner.Text())
+		line = strings.TrimSpace(line)
 		if line == "" {
 			continue
 		}
@@ -409,34 +1113,43 @@ func (s *chatStream) Next(ctx context.Context) (*provider.LanguageModelDelta, er
 		}
 		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
 		if data == "[DONE]" {
-			s.done = true
-			return &provider.LanguageModelDelta{Done: true}, nil
+			return s.finish()
 		}
 
 		var chunk openAIChatStreamChunk
 		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
 			return nil, err
 		}
+		if len(chunk.Error) > 0 {
+			if apiErr := providerutil.StreamEventError("openai", []byte(data)); apiErr != nil {
+				s.err = apiErr
+				return nil, s.err
+			}
+		}
+		if chunk.Usage != nil {
+			s.usage = chunk.Usage.usage()
+		}
 		if len(chunk.Choices) == 0 {
 			continue
 		}
 		choice := chunk.Choices[0]
 		delta := &provider.LanguageModelDelta{
-			Text: choice.Delta.Content,
+			Text:      s.text.Join(string(choice.Delta.Content)),
+			Reasoning: s.reasoning.Join(firstNonEmpty(string(choice.Delta.ReasoningContent), string(choice.Delta.Reasoning))),
 		}
 		for _, tc := range choice.Delta.ToolCalls {
-			if tc.Type != "function" {
+			// Continuation fragments carry no type.
+			if tc.Type != "" && tc.Type != "function" {
 				continue
 			}
-			delta.ToolCalls = append(delta.ToolCalls, provider.ToolCall{
-				ID:           tc.ID,
-				Name:         tc.Function.Name,
-				RawArguments: []byte(tc.Function.Arguments),
-			})
+			delta.ToolCallDeltas = append(delta.ToolCallDeltas, s.addToolCall(tc.Index, tc.ID, tc.Function.Name, tc.Function.Arguments))
 		}
 		if choice.FinishReason != "" {
-			delta.Done = true
-			s.done = true
+			s.finished = true
+			s.stop = choice.FinishReason
+		}
+		if delta.Text == "" && delta.Reasoning == "" && len(delta.ToolCallDeltas) == 0 {
+			continue
 		}
 		return delta, nil
 	}
@@ -444,7 +1157,7 @@ func (s *chatStream) Next(ctx context.Context) (*provider.LanguageModelDelta, er
 
 func (s *chatStream) Close() error {
 	s.done = true
-	return s.body.Close()
+	return s.lines.Close()
 }
 
 type embeddingModel struct {
@@ -468,7 +1181,7 @@ func (m *embeddingModel) Generate(ctx context.Context, req *provider.EmbeddingRe
 	body := openAIEmbeddingRequest{
 		Model: m.model,
 		Input: req.Input,
-		User:  req.UserID,
+		User:  providerutil.UserID(ctx, req.UserID, m.client.defaultUser),
 	}
 
 	buf, err := json.Marshal(body)
@@ -476,29 +1189,19 @@ func (m *embeddingModel) Generate(ctx context.Context, req *provider.EmbeddingRe
 		return nil, err
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, m.client.embeddingsURL(), bytes.NewReader(buf))
+	httpReq, err := m.client.newRequest(ctx, m.client.embeddingsURL(), bytes.NewReader(buf), "application/json")
 	if err != nil {
 		return nil, err
 	}
-	for k, vs := range m.client.headers {
-		for _, v := range vs {
-			if v == "" {
-				continue
-			}
-			httpReq.Header.Add(k, v)
-		}
-	}
-	httpReq.Header.Set("Authorization", "Bearer "+m.client.apiKey)
-	httpReq.Header.Set("Content-Type", "application/json")
 
-	resp, err := m.client.httpClient.Do(httpReq)
+	resp, err := m.client.do(httpReq)
 	if err != nil {
 		return nil, err
 	}
 
 	var out openAIEmbeddingResponse
-	if err := providerutil.ReadJSON(resp, &out); err != nil {
-		return nil, err
+	if err := providerutil.ReadJSONReporting(resp, &out, m.client.reportUnknown("openai.embeddings")); err != nil {
+		return nil, providerutil.TagProvider(err, "openai")
 	}
 
 	res := &provider.EmbeddingResponse{}
@@ -513,6 +1216,31 @@ type imageModel struct {
 	model  string
 }
 
+// ImageModelCapabilities lists the sizes and per-request image counts
+// supported by known OpenAI image models. Entries may be added or
+// overridden before creating clients; models not listed here skip
+// validation in ai.GenerateImage.
+var ImageModelCapabilities = map[string]provider.ImageCapabilities{
+	"dall-e-2": {
+		Sizes:     []string{"256x256", "512x512", "1024x1024"},
+		MaxImages: 10,
+	},
+	"dall-e-3": {
+		Sizes:     []string{"1024x1024", "1792x1024", "1024x1792"},
+		MaxImages: 1,
+	},
+	"gpt-image-1": {
+		Sizes:     []string{"auto", "1024x1024", "1536x1024", "1024x1536"},
+		MaxImages: 10,
+	},
+}
+
+// ImageCapabilities implements provider.ImageCapabilityReporter.
+func (m *imageModel) ImageCapabilities() (provider.ImageCapabilities, bool) {
+	caps, ok := ImageModelCapabilities[m.model]
+	return caps, ok
+}
+
 type openAIImageRequest struct {
 	Prompt         string `json:"prompt"`
 	Model          string `json:"model"`
@@ -520,6 +1248,8 @@ type openAIImageRequest struct {
 	N              int    `json:"n,omitempty"`
 	ResponseFormat string `json:"response_format,omitempty"`
 	User           string `json:"user,omitempty"`
+	Stream         bool   `json:"stream,omitempty"`
+	PartialImages  int    `json:"partial_images,omitempty"`
 }
 
 type openAIImageResponse struct {
@@ -529,7 +1259,9 @@ type openAIImageResponse struct {
 	} `json:"data"`
 }
 
-func (m *imageModel) Generate(ctx context.Context, req *provider.ImageRequest) (*provider.ImageResponse, error) {
+// buildRequest constructs the HTTP request for an image generation
+// call. It is shared by Generate and Stream.
+func (m *imageModel) buildRequest(ctx context.Context, req *provider.ImageRequest, stream bool) (*http.Request, error) {
 	body := openAIImageRequest{
 		Model:  m.model,
 		Prompt: req.Prompt,
@@ -543,8 +1275,10 @@ func (m *imageModel) Generate(ctx context.Context, req *provider.ImageRequest) (
 	if req.ResponseFormat != "" {
 		body.ResponseFormat = req.ResponseFormat
 	}
-	if req.UserID != "" {
-		body.User = req.UserID
+	body.User = providerutil.UserID(ctx, req.UserID, m.client.defaultUser)
+	if stream {
+		body.Stream = true
+		body.PartialImages = req.PartialImages
 	}
 
 	buf, err := json.Marshal(body)
@@ -552,31 +1286,32 @@ func (m *imageModel) Generate(ctx context.Context, req *provider.ImageRequest) (
 		return nil, err
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, m.client.imagesURL(), bytes.NewReader(buf))
+	httpReq, err := m.client.newRequest(ctx, m.client.imagesURL(), bytes.NewReader(buf), "application/json")
 	if err != nil {
 		return nil, err
 	}
-	for k, vs := range m.client.headers {
-		for _, v := range vs {
-			if v == "" {
-				continue
-			}
-			httpReq.Header.Add(k, v)
-		}
+	if stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
 	}
-	httpReq.Header.Set("Authorization", "Bearer "+m.client.apiKey)
-	httpReq.Header.Set("Content-Type", "application/json")
+	return httpReq, nil
+}
 
-	resp, err := m.client.httpClient.Do(httpReq)
+func (m *imageModel) Generate(ctx context.Context, req *provider.ImageRequest) (*provider.ImageResponse, error) {
+	httpReq, err := m.buildRequest(ctx, req, false)
 	if err != nil {
 		return nil, err
 	}
 
-	var out openAIImageResponse
-	if err := providerutil.ReadJSON(resp, &out); err != nil {
+	resp, err := m.client.do(httpReq)
+	if err != nil {
 		return nil, err
 	}
 
+	var out openAIImageResponse
+	if err := providerutil.ReadJSONReporting(resp, &out, m.client.reportUnknown("openai.images")); err != nil {
+		return nil, providerutil.TagProvider(err, "openai")
+	}
+
 	res := &provider.ImageResponse{}
 	for _, d := range out.Data {
 		var img provider.Image
@@ -601,11 +1336,42 @@ type speechModel struct {
 	model  string
 }
 
+// openAISpeechFormats are the response formats of the audio/speech
+// endpoint.
+var openAISpeechFormats = []provider.AudioFormat{
+	provider.FormatMP3, provider.FormatOpus, provider.FormatAAC,
+	provider.FormatFLAC, provider.FormatWAV, provider.FormatPCM,
+}
+
+// groqSpeechFormats are the response formats of Groq's compatible
+// audio/speech endpoint.
+var groqSpeechFormats = []provider.AudioFormat{
+	provider.FormatWAV, provider.FormatMP3, provider.FormatFLAC, "ogg", "mulaw",
+}
+
+// SpeechModelCapabilities lists the output formats supported by known
+// speech models, including the OpenAI-compatible models served by
+// Groq. Entries may be added or overridden before creating clients;
+// models not listed here skip validation in ai.GenerateSpeech.
+var SpeechModelCapabilities = map[string]provider.SpeechCapabilities{
+	"tts-1":             {Formats: openAISpeechFormats, DefaultFormat: provider.FormatMP3},
+	"tts-1-hd":          {Formats: openAISpeechFormats, DefaultFormat: provider.FormatMP3},
+	"gpt-4o-mini-tts":   {Formats: openAISpeechFormats, DefaultFormat: provider.FormatMP3},
+	"playai-tts":        {Formats: groqSpeechFormats, DefaultFormat: provider.FormatWAV},
+	"playai-tts-arabic": {Formats: groqSpeechFormats, DefaultFormat: provider.FormatWAV},
+}
+
+// SpeechCapabilities implements provider.SpeechCapabilityReporter.
+func (m *speechModel) SpeechCapabilities() (provider.SpeechCapabilities, bool) {
+	caps, ok := SpeechModelCapabilities[m.model]
+	return caps, ok
+}
+
 type openAISpeechRequest struct {
-	Model          string `json:"model"`
-	Input          string `json:"input"`
-	Voice          string `json:"voice,omitempty"`
-	ResponseFormat string `json:"response_format,omitempty"`
+	Model          string               `json:"model"`
+	Input          string               `json:"input"`
+	Voice          string               `json:"voice,omitempty"`
+	ResponseFormat provider.AudioFormat `json:"response_format,omitempty"`
 }
 
 func (m *speechModel) Generate(ctx context.Context, req *provider.SpeechRequest) (*provider.SpeechResponse, error) {
@@ -625,40 +1391,42 @@ func (m *speechModel) Generate(ctx context.Context, req *provider.SpeechRequest)
 		return nil, err
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, m.client.audioSpeechURL(), bytes.NewReader(buf))
+	httpReq, err := m.client.newRequest(ctx, m.client.audioSpeechURL(), bytes.NewReader(buf), "application/json")
 	if err != nil {
 		return nil, err
 	}
-	for k, vs := range m.client.headers {
-		for _, v := range vs {
-			if v == "" {
-				continue
-			}
-			httpReq.Header.Add(k, v)
-		}
-	}
-	httpReq.Header.Set("Authorization", "Bearer "+m.client.apiKey)
-	httpReq.Header.Set("Content-Type", "application/json")
 
-	resp, err := m.client.httpClient.Do(httpReq)
+	resp, err := m.client.do(httpReq)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		b, _ := io.ReadAll(io.LimitReader(resp.Body, 8*1024))
-		return nil, fmt.Errorf("provider: http status %d: %s", resp.StatusCode, string(b))
+	if err := providerutil.CheckStatus(resp); err != nil {
+		return nil, providerutil.TagProvider(err, "openai")
 	}
+	defer resp.Body.Close()
 
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
 
+	// Some compatible TTS servers omit Content-Type; fall back to the
+	// requested format, or the model's default format.
+	mimeType := resp.Header.Get("Content-Type")
+	if mimeType == "" {
+		format := req.Format
+		if format == "" {
+			format = provider.FormatMP3
+			if caps, ok := m.SpeechCapabilities(); ok && caps.DefaultFormat != "" {
+				format = caps.DefaultFormat
+			}
+		}
+		mimeType = provider.AudioMimeType(format)
+	}
+
 	return &provider.SpeechResponse{
 		Audio:    data,
-		MimeType: resp.Header.Get("Content-Type"),
+		MimeType: mimeType,
 	}, nil
 }
 
@@ -668,19 +1436,31 @@ type transcriptionModel struct {
 }
 
 type openAITranscriptionResponse struct {
-	Text string `json:"text"`
+	Text     string                       `json:"text"`
+	Task     string                       `json:"task,omitempty"`
+	Language string                       `json:"language,omitempty"`
+	Duration float64                      `json:"duration,omitempty"`
+	Segments []openAITranscriptionSegment `json:"segments,omitempty"`
+}
+
+type openAITranscriptionSegment struct {
+	ID               int     `json:"id"`
+	Seek             int     `json:"seek"`
+	Start            float64 `json:"start"`
+	End              float64 `json:"end"`
+	Text             string  `json:"text"`
+	Tokens           []int   `json:"tokens"`
+	Temperature      float64 `json:"temperature"`
+	AvgLogprob       float64 `json:"avg_logprob"`
+	CompressionRatio float64 `json:"compression_ratio"`
+	NoSpeechProb     float64 `json:"no_speech_prob"`
 }
 
 func (m *transcriptionModel) Generate(ctx context.Context, req *provider.TranscriptionRequest) (*provider.TranscriptionResponse, error) {
 	var buf bytes.Buffer
 	writer := multipart.NewWriter(&buf)
 
-	filename := req.FileName
-	if filename == "" {
-		filename = "audio"
-	}
-
-	filePart, err := writer.CreateFormFile("file", filename)
+	filePart, err := createAudioPart(writer, transcriptionFileName(req.FileName, req.MimeType), req.MimeType)
 	if err != nil {
 		return nil, err
 	}
@@ -706,44 +1486,215 @@ func (m *transcriptionModel) Generate(ctx context.Context, req *provider.Transcr
 			return nil, err
 		}
 	}
-	if req.UserID != "" {
-		if err := writer.WriteField("user", req.UserID); err != nil {
+	if userID := providerutil.UserID(ctx, req.UserID, m.client.defaultUser); userID != "" {
+		if err := writer.WriteField("user", userID); err != nil {
 			return nil, err
 		}
 	}
+	if req.Segments {
+		if err := writer.WriteField("response_format", "verbose_json"); err != nil {
+			return nil, err
+		}
+		if err := writer.WriteField("timestamp_granularities[]", "segment"); err != nil {
+			return nil, err
+		}
+	}
+	m.client.lintExtraFields(req)
+	if err := writeExtraFields(writer, req.ExtraFields, req.ExtraArrayFields); err != nil {
+		return nil, err
+	}
 
 	if err := writer.Close(); err != nil {
 		return nil, err
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, m.client.audioTranscriptionsURL(), &buf)
+	httpReq, err := m.client.newRequest(ctx, m.client.audioTranscriptionsURL(), &buf, writer.FormDataContentType())
 	if err != nil {
 		return nil, err
 	}
-	for k, vs := range m.client.headers {
-		for _, v := range vs {
-			if v == "" {
-				continue
-			}
-			httpReq.Header.Add(k, v)
-		}
-	}
-	httpReq.Header.Set("Authorization", "Bearer "+m.client.apiKey)
-	httpReq.Header.Set("Content-Type", writer.FormDataContentType())
 
-	resp, err := m.client.httpClient.Do(httpReq)
+	resp, err := m.client.do(httpReq)
 	if err != nil {
 		return nil, err
 	}
 
 	var out openAITranscriptionResponse
-	if err := providerutil.ReadJSON(resp, &out); err != nil {
-		return nil, err
+	if err := providerutil.ReadJSONReporting(resp, &out, m.client.reportUnknown("openai.transcription")); err != nil {
+		return nil, providerutil.TagProvider(err, "openai")
 	}
 
-	return &provider.TranscriptionResponse{
+	res := &provider.TranscriptionResponse{
 		Text: out.Text,
-	}, nil
+	}
+	for _, seg := range out.Segments {
+		res.Segments = append(res.Segments, provider.TranscriptionSegment{Start: seg.Start, End: seg.End, Text: seg.Text})
+	}
+	return res, nil
+}
+
+// audioExtensions maps common audio MIME types to file extensions.
+// Self-hosted Whisper servers infer the decoder from the file name and
+// reject uploads without a recognizable extension.
+var audioExtensions = map[string]string{
+	"audio/mpeg":   ".mp3",
+	"audio/mp3":    ".mp3",
+	"audio/wav":    ".wav",
+	"audio/x-wav":  ".wav",
+	"audio/wave":   ".wav",
+	"audio/webm":   ".webm",
+	"audio/ogg":    ".ogg",
+	"audio/opus":   ".opus",
+	"audio/flac":   ".flac",
+	"audio/x-flac": ".flac",
+	"audio/mp4":    ".m4a",
+	"audio/m4a":    ".m4a",
+	"audio/x-m4a":  ".m4a",
+	"video/mp4":    ".mp4",
+}
+
+// transcriptionFileName returns the file name sent with the audio
+// part, deriving an extension from mimeType when name lacks one.
+func transcriptionFileName(name, mimeType string) string {
+	if name == "" {
+		name = "audio"
+	}
+	if path.Ext(name) != "" {
+		return name
+	}
+	mt := strings.ToLower(strings.TrimSpace(strings.SplitN(mimeType, ";", 2)[0]))
+	return name + audioExtensions[mt]
+}
+
+// createAudioPart creates the "file" form part, using mimeType as the
+// part content type when provided.
+func createAudioPart(writer *multipart.Writer, filename, mimeType string) (io.Writer, error) {
+	if mimeType == "" {
+		return writer.CreateFormFile("file", filename)
+	}
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, filename))
+	h.Set("Content-Type", mimeType)
+	return writer.CreatePart(h)
+}
+
+// collisionWarnings describes ProviderOptions keys that were dropped
+// because the request already sets them.
+func collisionWarnings(keys []string) []string {
+	warnings := make([]string, len(keys))
+	for i, k := range keys {
+		warnings[i] = fmt.Sprintf("provider option %q is ignored: the request already sets it", k)
+	}
+	return warnings
+}
+
+// chatKnownFields lists the chat completions request fields that
+// ProviderOptions may set.
+var chatKnownFields = []string{
+	"audio", "frequency_penalty", "function_call", "functions", "logit_bias",
+	"logprobs", "max_completion_tokens", "max_tokens", "messages", "metadata",
+	"modalities", "model", "n", "parallel_tool_calls", "prediction",
+	"presence_penalty", "prompt_cache_key", "reasoning_effort",
+	"response_format", "safety_identifier", "seed", "service_tier", "stop",
+	"store", "stream", "stream_options", "temperature", "tool_choice", "tools",
+	"top_logprobs", "top_p", "user", "verbosity", "web_search_options",
+}
+
+// lintProviderOptions describes ProviderOptions keys that collide with
+// fields the request already sets, look like misspellings of chat
+// completions fields, or are not chat completions fields at all.
+func lintProviderOptions(options map[string]any, collisions []string) []string {
+	warnings := collisionWarnings(collisions)
+	keys := make([]string, 0, len(options))
+	for k := range options {
+		if !slices.Contains(collisions, k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if w := providerutil.LintExtraKeys([]string{k}, nil, chatKnownFields); len(w) > 0 {
+			warnings = append(warnings, w...)
+		} else if !slices.Contains(chatKnownFields, k) {
+			warnings = append(warnings, fmt.Sprintf("provider option %q is not a known chat completions field", k))
+		}
+	}
+	return warnings
+}
+
+// transcriptionTypedFields maps the form fields set from typed
+// TranscriptionRequest fields to those fields.
+var transcriptionTypedFields = map[string]string{
+	"file":                    "Audio",
+	"model":                   "Model",
+	"prompt":                  "Prompt",
+	"language":                "Language",
+	"temperature":             "Temperature",
+	"user":                    "UserID",
+	"response_format":         "Segments",
+	"timestamp_granularities": "Segments",
+}
+
+// transcriptionKnownFields lists other form fields accepted by OpenAI
+// and common self-hosted Whisper servers.
+var transcriptionKnownFields = []string{"response_format", "timestamp_granularities", "chunking_strategy", "include", "stream", "known_speaker_names", "known_speaker_references", "vad_filter"}
+
+// lintExtraFields reports extra transcription form fields that collide
+// with typed fields or look misspelled, when linting is enabled.
+func (c *Client) lintExtraFields(req *provider.TranscriptionRequest) {
+	if c.onLint == nil || len(req.ExtraFields)+len(req.ExtraArrayFields) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(req.ExtraFields)+len(req.ExtraArrayFields))
+	for k := range req.ExtraFields {
+		keys = append(keys, k)
+	}
+	for k := range req.ExtraArrayFields {
+		keys = append(keys, k)
+	}
+	typed := transcriptionTypedFields
+	if !req.Segments {
+		// Without Segments, response_format and timestamp_granularities
+		// are legitimately passed through.
+		typed = maps.Clone(typed)
+		delete(typed, "response_format")
+		delete(typed, "timestamp_granularities")
+	}
+	if w := providerutil.LintExtraKeys(keys, typed, transcriptionKnownFields); len(w) > 0 {
+		c.onLint("openai.transcription", w)
+	}
+}
+
+// writeExtraFields writes pass-through form fields in sorted key order.
+// Array-valued fields are written as repeated "name[]" fields.
+func writeExtraFields(writer *multipart.Writer, fields map[string]string, arrayFields map[string][]string) error {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := writer.WriteField(k, fields[k]); err != nil {
+			return err
+		}
+	}
+
+	keys = keys[:0]
+	for k := range arrayFields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		name := k
+		if !strings.HasSuffix(name, "[]") {
+			name += "[]"
+		}
+		for _, v := range arrayFields[k] {
+			if err := writer.WriteField(name, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
 // CompatibleClient returns a new Client configured for an OpenAI-compatible endpoint.
@@ -775,3 +1726,23 @@ func CompatibleClient() (*Client, error) {
 func WithHTTPTimeout(d time.Duration) provider.HTTPClient {
 	return &http.Client{Timeout: d}
 }
+
+// Factory is a provider.Factory that creates OpenAI clients. Register
+// it with registry.RegisterProviderFactory to resolve "openai:<model>"
+// references.
+type Factory struct{}
+
+// Ensure Factory implements provider.Factory.
+var _ provider.Factory = Factory{}
+
+// Name implements provider.Factory.
+func (Factory) Name() string { return "openai" }
+
+// NewClient implements provider.Factory.
+func (Factory) NewClient(opts provider.ClientOptions) (provider.Provider, error) {
+	c, err := NewClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
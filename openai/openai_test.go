@@ -3,13 +3,16 @@ package openai
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/ncecere/ai-sdk/aitest"
 	"github.com/ncecere/ai-sdk/provider"
 )
 
@@ -18,23 +21,10 @@ func float64Ptr(v float64) *float64 { return &v }
 func TestChatModelGenerate_MapsRequestAndResponse(t *testing.T) {
 	ctx := context.Background()
 
-	var recordedReq openAIChatRequest
-
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			t.Fatalf("unexpected method: %s", r.Method)
-		}
-		if got := r.URL.Path; got != "/v1/chat/completions" {
-			t.Fatalf("unexpected path: %s", got)
-		}
-		if auth := r.Header.Get("Authorization"); !strings.HasPrefix(auth, "Bearer ") {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer test-key" {
 			t.Fatalf("missing bearer auth header: %q", auth)
 		}
-
-		if err := json.NewDecoder(r.Body).Decode(&recordedReq); err != nil {
-			t.Fatalf("failed to decode request: %v", err)
-		}
-
 		w.Header().Set("Content-Type", "application/json")
 		fmt.Fprint(w, `{
 			"choices": [
@@ -69,12 +59,13 @@ func TestChatModelGenerate_MapsRequestAndResponse(t *testing.T) {
 		t.Fatalf("NewClient error: %v", err)
 	}
 
-	model := client.ChatModel("test-model")
+	model := aitest.CaptureRequests(client.ChatModel("test-model"))
 
 	temp := float64Ptr(0.5)
 	res, err := model.Generate(ctx, &provider.LanguageModelRequest{
 		Messages:    []provider.Message{{Role: "user", Content: "hi"}},
 		Temperature: temp,
+		LogitBias:   map[string]float64{"1734": -100},
 		JSONSchema:  []byte(`{"type":"object"}`),
 		Tools: []provider.ToolDefinition{{
 			Name:        "testTool",
@@ -87,27 +78,18 @@ func TestChatModelGenerate_MapsRequestAndResponse(t *testing.T) {
 	}
 
 	// Check request mapping
-	if recordedReq.Model != "test-model" {
-		t.Fatalf("expected model 'test-model', got %q", recordedReq.Model)
-	}
-	if len(recordedReq.Messages) != 1 || recordedReq.Messages[0].Role != "user" || recordedReq.Messages[0].Content != "hi" {
-		t.Fatalf("unexpected messages: %+v", recordedReq.Messages)
-	}
-	if recordedReq.Temperature == nil || *recordedReq.Temperature != *temp {
-		t.Fatalf("temperature not propagated: %+v", recordedReq.Temperature)
-	}
-	if recordedReq.ResponseFormat == nil || recordedReq.ResponseFormat.Type != "json_schema" {
-		t.Fatalf("expected json_schema response format, got %+v", recordedReq.ResponseFormat)
-	}
-	if len(recordedReq.Tools) != 1 || recordedReq.Tools[0].Function.Name != "testTool" {
-		t.Fatalf("tools not propagated: %+v", recordedReq.Tools)
+	reqs := model.Requests()
+	if len(reqs) != 1 {
+		t.Fatalf("expected one request, got %d", len(reqs))
 	}
+	aitest.CompareGolden(t, "testdata/chat_generate.golden", aitest.FormatRequest(reqs[0], aitest.GoldenOptions{BaseURL: ts.URL}))
 
 	// Check response mapping
 	if res.Text != "hello from test" {
 		t.Fatalf("unexpected text: %q", res.Text)
 	}
-	if res.StopReason != "stop" {
+	// Tool calls take precedence over the reported "stop".
+	if res.StopReason != "tool_calls" {
 		t.Fatalf("unexpected stop reason: %q", res.StopReason)
 	}
 	if len(res.ToolCalls) != 1 || res.ToolCalls[0].Name != "testTool" {
@@ -115,6 +97,50 @@ func TestChatModelGenerate_MapsRequestAndResponse(t *testing.T) {
 	}
 }
 
+func TestChatModel_ContentFilterIsContentBlocked(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Stream bool `json:"stream"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Once\"}}]}\n\n")
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"content_filter\"}]}\n\n")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":""},"finish_reason":"content_filter"}]}`)
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test-key", HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	model := client.ChatModel("gpt-test")
+	req := &provider.LanguageModelRequest{Messages: []provider.Message{{Role: "user", Content: "hi"}}}
+
+	var blocked *provider.ContentBlockedError
+	if _, err := model.Generate(context.Background(), req); !errors.As(err, &blocked) || blocked.Provider != "openai" || blocked.Reason != "content_filter" {
+		t.Fatalf("Generate error = %v, want ContentBlockedError with reason content_filter", err)
+	}
+
+	stream, err := model.Stream(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Stream error: %v", err)
+	}
+	defer stream.Close()
+	if delta, err := stream.Next(context.Background()); err != nil || delta.Text != "Once" {
+		t.Fatalf("first Next = %+v, %v; want the text delta", delta, err)
+	}
+	blocked = nil
+	if _, err := stream.Next(context.Background()); !errors.As(err, &blocked) || blocked.Reason != "content_filter" {
+		t.Fatalf("final Next error = %v, want ContentBlockedError with reason content_filter", err)
+	}
+}
+
 func TestChatModelStream_ParsesSSEChunks(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -245,3 +271,1361 @@ func TestChatModelGenerate_PropagatesHTTPError(t *testing.T) {
 		t.Fatalf("expected http status 500 in error, got %v", err)
 	}
 }
+
+func TestChatModelGenerate_ParsesAPIErrorParam(t *testing.T) {
+	for _, tc := range []struct {
+		name, body, param, code string
+	}{
+		{"param field", `{"error":{"message":"Unknown parameter: 'reasonig_effort'.","type":"invalid_request_error","param":"reasonig_effort","code":"unknown_parameter"}}`, "reasonig_effort", "unknown_parameter"},
+		{"message only", `{"error":{"message":"Unrecognized request argument supplied: reasonig_effort","type":"invalid_request_error","param":null,"code":null}}`, "reasonig_effort", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, tc.body, http.StatusBadRequest)
+			}))
+			defer ts.Close()
+
+			client, err := NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+			if err != nil {
+				t.Fatalf("NewClient error: %v", err)
+			}
+			_, err = client.ChatModel("test-model").Generate(context.Background(), &provider.LanguageModelRequest{
+				Messages: []provider.Message{{Role: "user", Content: "hi"}},
+			})
+			var apiErr *provider.APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected APIError, got %v", err)
+			}
+			if apiErr.Provider != "openai" || apiErr.StatusCode != 400 || apiErr.Param != tc.param || apiErr.Code != tc.code || apiErr.Type != "invalid_request_error" {
+				t.Fatalf("unexpected APIError %+v", apiErr)
+			}
+			if !strings.HasPrefix(err.Error(), `openai: http status 400 (param "reasonig_effort"): `) {
+				t.Fatalf("expected the provider and param in the message, got %q", err.Error())
+			}
+		})
+	}
+}
+
+func TestTranscriptionModelGenerate_LintsExtraFields(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"text":"ok"}`)
+	}))
+	defer ts.Close()
+
+	var got []string
+	client, err := NewClient(provider.ClientOptions{
+		BaseURL:    ts.URL,
+		APIKey:     "test",
+		HTTPClient: ts.Client(),
+		OnLintWarnings: func(source string, warnings []string) {
+			for _, w := range warnings {
+				got = append(got, source+": "+w)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	_, err = client.TranscriptionModel("whisper-1").Generate(context.Background(), &provider.TranscriptionRequest{
+		Audio:            []byte("RIFF"),
+		Segments:         true,
+		ExtraFields:      map[string]string{"langauge": "en", "prompt": "x", "vad_filter": "true", "chunking_strategi": "auto"},
+		ExtraArrayFields: map[string][]string{"timestamp_granularities": {"word"}},
+	})
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	want := []string{
+		`openai.transcription: extra key "chunking_strategi" looks like a misspelling of "chunking_strategy"`,
+		`openai.transcription: extra key "langauge" looks like a misspelling of "language"`,
+		`openai.transcription: extra key "prompt" collides with the typed field Prompt`,
+		`openai.transcription: extra key "timestamp_granularities" collides with the typed field Segments`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected lint warnings:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestChatModelGenerate_MissingChoicesReturnsEmptyResponseError(t *testing.T) {
+	ctx := context.Background()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"gw-1","object":"chat.completion"}`)
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(provider.ClientOptions{
+		BaseURL:    ts.URL + "/v1",
+		APIKey:     "test-key",
+		HTTPClient: ts.Client(),
+	})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	_, err = client.ChatModel("test-model").Generate(ctx, &provider.LanguageModelRequest{
+		Messages: []provider.Message{{Role: "user", Content: "hi"}},
+	})
+	var emptyErr *provider.EmptyResponseError
+	if !errors.As(err, &emptyErr) {
+		t.Fatalf("expected EmptyResponseError, got %v", err)
+	}
+	if emptyErr.StatusCode != http.StatusOK || !strings.Contains(emptyErr.Body, "gw-1") {
+		t.Fatalf("unexpected error details: %+v", emptyErr)
+	}
+}
+
+func TestChatModelGenerate_EmptyMessageWithFinishReasonIsNotAnError(t *testing.T) {
+	ctx := context.Background()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"finish_reason":"stop","message":{"role":"assistant","content":""}}]}`)
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(provider.ClientOptions{
+		BaseURL:    ts.URL + "/v1",
+		APIKey:     "test-key",
+		HTTPClient: ts.Client(),
+	})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	res, err := client.ChatModel("test-model").Generate(ctx, &provider.LanguageModelRequest{
+		Messages: []provider.Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	if res.Text != "" || res.StopReason != "stop" {
+		t.Fatalf("unexpected response: %+v", res)
+	}
+}
+
+func TestTranscriptionModelGenerate_ExtraFieldsAndFileName(t *testing.T) {
+	ctx := context.Background()
+
+	var (
+		fileName    string
+		fileType    string
+		vadFilter   string
+		granularity []string
+	)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/transcriptions" {
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatalf("failed to parse multipart form: %v", err)
+		}
+		fh := r.MultipartForm.File["file"][0]
+		fileName = fh.Filename
+		fileType = fh.Header.Get("Content-Type")
+		vadFilter = r.FormValue("vad_filter")
+		granularity = r.MultipartForm.Value["timestamp_granularities[]"]
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"text":"hello"}`)
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(provider.ClientOptions{
+		BaseURL:    ts.URL + "/v1",
+		APIKey:     "test-key",
+		HTTPClient: ts.Client(),
+	})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	res, err := client.TranscriptionModel("whisper-1").Generate(ctx, &provider.TranscriptionRequest{
+		Audio:            []byte("RIFF"),
+		FileName:         "recording",
+		MimeType:         "audio/wav",
+		ExtraFields:      map[string]string{"vad_filter": "true"},
+		ExtraArrayFields: map[string][]string{"timestamp_granularities": {"word", "segment"}},
+	})
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	if res.Text != "hello" {
+		t.Fatalf("unexpected text: %q", res.Text)
+	}
+	if fileName != "recording.wav" || fileType != "audio/wav" {
+		t.Fatalf("unexpected file part: name=%q type=%q", fileName, fileType)
+	}
+	if vadFilter != "true" {
+		t.Fatalf("extra field not sent: %q", vadFilter)
+	}
+	if len(granularity) != 2 || granularity[0] != "word" || granularity[1] != "segment" {
+		t.Fatalf("array field not sent correctly: %v", granularity)
+	}
+}
+
+func TestChatModelBuildBody_ToolResultWithImageParts(t *testing.T) {
+	m := &chatModel{model: "test-model"}
+	body := m.buildBody(&provider.LanguageModelRequest{
+		Messages: []provider.Message{{
+			Role:       "tool",
+			Content:    "screenshot taken",
+			ToolCallID: "call_1",
+			Parts: []provider.ContentPart{
+				{Type: provider.ContentPartText, Text: "screenshot taken"},
+				{Type: provider.ContentPartImage, Data: []byte{0x89, 'P', 'N', 'G'}, MimeType: "image/png"},
+			},
+		}},
+	}, false)
+
+	data, err := json.Marshal(body.Messages)
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	want := `[{"role":"tool","content":[{"type":"text","text":"screenshot taken"}],"tool_call_id":"call_1"},` +
+		`{"role":"user","content":[{"type":"image_url","image_url":{"url":"data:image/png;base64,iVBORw=="}}]}]`
+	if string(data) != want {
+		t.Fatalf("unexpected messages:\n got: %s\nwant: %s", data, want)
+	}
+}
+
+func TestChatModelBuildBody_ParallelToolResultsWithImageParts(t *testing.T) {
+	m := &chatModel{model: "test-model"}
+	screenshot := func(id string, data byte) provider.Message {
+		return provider.Message{Role: "tool", Content: "shot", ToolCallID: id, Parts: []provider.ContentPart{
+			{Type: provider.ContentPartText, Text: "shot"},
+			{Type: provider.ContentPartImage, Data: []byte{data}, MimeType: "image/png"},
+		}}
+	}
+	body := m.buildBody(&provider.LanguageModelRequest{
+		Messages: []provider.Message{
+			{Role: "assistant", ToolCalls: []provider.ToolCall{
+				{ID: "call_1", Name: "screenshot", RawArguments: []byte(`{}`)},
+				{ID: "call_2", Name: "screenshot", RawArguments: []byte(`{}`)},
+			}},
+			screenshot("call_1", 1),
+			screenshot("call_2", 2),
+			{Role: "user", Content: "compare them"},
+		},
+	}, false)
+
+	var roles []string
+	for _, msg := range body.Messages {
+		roles = append(roles, msg.Role)
+	}
+	if want := []string{"assistant", "tool", "tool", "user", "user"}; !reflect.DeepEqual(roles, want) {
+		t.Fatalf("roles = %v, want %v", roles, want)
+	}
+	images, _ := body.Messages[3].Content.([]openAIContentPart)
+	if len(images) != 2 || images[0].ImageURL.URL != "data:image/png;base64,AQ==" || images[1].ImageURL.URL != "data:image/png;base64,Ag==" {
+		t.Fatalf("expected both images in one user message, got %+v", body.Messages[3])
+	}
+}
+
+func TestChatModelBuildBody_ToolCallID(t *testing.T) {
+	m := &chatModel{model: "test-model"}
+	body := m.buildBody(&provider.LanguageModelRequest{
+		Messages: []provider.Message{
+			{Role: "user", Content: "add 3 and 5", ToolCallID: "ignored"},
+			{Role: "assistant", Content: ""},
+			{Role: "tool", Content: `{"result":8}`, ToolCallID: "call_1"},
+		},
+	}, false)
+
+	data, err := json.Marshal(body.Messages)
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	want := `[{"role":"user","content":"add 3 and 5"},{"role":"assistant","content":""},{"role":"tool","content":"{\"result\":8}","tool_call_id":"call_1"}]`
+	if string(data) != want {
+		t.Fatalf("unexpected messages:\n got: %s\nwant: %s", data, want)
+	}
+}
+
+func TestChatModelBuildBody_AssistantToolCalls(t *testing.T) {
+	m := &chatModel{model: "test-model"}
+	body := m.buildBody(&provider.LanguageModelRequest{
+		Messages: []provider.Message{
+			{Role: "user", Content: "add 3 and 5"},
+			// Arguments as returned by Generate (a JSON string) and as
+			// a plain JSON object.
+			{Role: "assistant", ToolCalls: []provider.ToolCall{
+				{ID: "call_1", Name: "add", RawArguments: []byte(`"{\"a\":3,\"b\":5}"`)},
+				{ID: "call_2", Name: "add", RawArguments: []byte(`{"a":1,"b":1}`)},
+			}},
+			{Role: "tool", Content: "8", ToolCallID: "call_1"},
+			{Role: "tool", Content: "2", ToolCallID: "call_2"},
+		},
+	}, false)
+
+	data, err := json.Marshal(body.Messages[1])
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	want := `{"role":"assistant","content":null,"tool_calls":[` +
+		`{"id":"call_1","type":"function","function":{"name":"add","arguments":"{\"a\":3,\"b\":5}"}},` +
+		`{"id":"call_2","type":"function","function":{"name":"add","arguments":"{\"a\":1,\"b\":1}"}}]}`
+	if string(data) != want {
+		t.Fatalf("unexpected assistant message:\n got: %s\nwant: %s", data, want)
+	}
+}
+
+func TestImageModelStream_PartialAndFinalImages(t *testing.T) {
+	ctx := context.Background()
+
+	var recorded openAIImageRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&recorded); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: image_generation.partial_image\n")
+		fmt.Fprint(w, `data: {"type":"image_generation.partial_image","b64_json":"cGFydGlhbA==","partial_image_index":0}`+"\n\n")
+		fmt.Fprint(w, "event: image_generation.completed\n")
+		fmt.Fprint(w, `data: {"type":"image_generation.completed","b64_json":"ZmluYWw="}`+"\n\n")
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	streamer, ok := client.ImageModel("gpt-image-1").(provider.ImageStreamer)
+	if !ok {
+		t.Fatalf("image model does not implement provider.ImageStreamer")
+	}
+	stream, err := streamer.Stream(ctx, &provider.ImageRequest{Prompt: "a cat", PartialImages: 1})
+	if err != nil {
+		t.Fatalf("Stream error: %v", err)
+	}
+	defer stream.Close()
+
+	if !recorded.Stream || recorded.PartialImages != 1 {
+		t.Fatalf("expected stream=true and partial_images=1, got %+v", recorded)
+	}
+
+	d, err := stream.Next(ctx)
+	if err != nil || d.Partial == nil || string(d.Partial.Data) != "partial" {
+		t.Fatalf("unexpected first delta: %+v, err=%v", d, err)
+	}
+	d, err = stream.Next(ctx)
+	if err != nil || d.Image == nil || string(d.Image.Data) != "final" {
+		t.Fatalf("unexpected second delta: %+v, err=%v", d, err)
+	}
+	d, err = stream.Next(ctx)
+	if err != nil || !d.Done {
+		t.Fatalf("expected done delta, got %+v, err=%v", d, err)
+	}
+}
+
+func TestChatModelBuildRequest_OrphanToolMessages(t *testing.T) {
+	ctx := context.Background()
+	msgs := []provider.Message{
+		{Role: "user", Content: "weather?"},
+		{Role: "assistant", Content: "", ToolCalls: []provider.ToolCall{{ID: "call_1", Name: "weather", RawArguments: []byte(`{}`)}}},
+		{Role: "tool", Content: `{"temp":20}`},
+	}
+
+	client, err := NewClient(provider.ClientOptions{APIKey: "test", BaseURL: "http://example.invalid"})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	m := client.ChatModel("gpt-test").(*chatModel)
+
+	req := &provider.LanguageModelRequest{Messages: msgs}
+	_, buf, _, err := m.buildRequest(ctx, req, false)
+	if err != nil {
+		t.Fatalf("buildRequest error: %v", err)
+	}
+	var body struct {
+		Messages []struct {
+			Role      string          `json:"role"`
+			Content   string          `json:"content"`
+			ToolCalls json.RawMessage `json:"tool_calls"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(buf, &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if body.Messages[1].ToolCalls != nil {
+		t.Fatalf("expected the assistant tool calls to be dropped with their results, got %s", body.Messages[1].ToolCalls)
+	}
+	last := body.Messages[2]
+	if last.Role != "user" || last.Content != `[tool result] {"temp":20}` {
+		t.Fatalf("expected downgraded tool message, got %+v", last)
+	}
+	if req.Messages[2].Role != "tool" || len(req.Messages[1].ToolCalls) != 1 {
+		t.Fatalf("caller request was modified")
+	}
+
+	req.OrphanToolMessages = provider.OrphanToolError
+	_, _, _, err = m.buildRequest(ctx, req, false)
+	var orphanErr *provider.OrphanToolMessageError
+	if !errors.As(err, &orphanErr) || orphanErr.Index != 2 {
+		t.Fatalf("expected OrphanToolMessageError at index 2, got %v", err)
+	}
+
+	req.Tools = []provider.ToolDefinition{{Name: "weather"}}
+	if _, _, _, err := m.buildRequest(ctx, req, false); err != nil {
+		t.Fatalf("expected tool messages to pass through when tools are declared, got %v", err)
+	}
+}
+
+func TestChatModelGenerate_ReportsUnknownFields(t *testing.T) {
+	ctx := context.Background()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{
+			"id": "chatcmpl-1",
+			"choices": [
+				{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": "hi", "annotations": []}},
+				{"index": 1, "finish_reason": "stop", "message": {"role": "assistant", "content": "hi", "annotations": []}}
+			]
+		}`)
+	}))
+	defer ts.Close()
+
+	var gotSource string
+	var gotFields []string
+	client, err := NewClient(provider.ClientOptions{
+		BaseURL:    ts.URL,
+		APIKey:     "test",
+		HTTPClient: ts.Client(),
+		OnUnknownFields: func(source string, fields []string) {
+			gotSource, gotFields = source, fields
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	if _, err := client.ChatModel("gpt-test").Generate(ctx, &provider.LanguageModelRequest{
+		Messages: []provider.Message{{Role: "user", Content: "hi"}},
+	}); err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+
+	want := []string{"choices[].index", "choices[].message.annotations", "id"}
+	if gotSource != "openai.chat" || strings.Join(gotFields, ",") != strings.Join(want, ",") {
+		t.Fatalf("unexpected unknown fields report: source=%q fields=%v", gotSource, gotFields)
+	}
+}
+
+func TestChatModelGenerate_ToolDiagnosticsWhenToolsIgnored(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"finish_reason":"stop","message":{"role":"assistant","content":""}}]}`)
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	res, err := client.ChatModel("gpt-test").Generate(context.Background(), &provider.LanguageModelRequest{
+		Messages: []provider.Message{{Role: "user", Content: "call the tool"}},
+		Tools:    []provider.ToolDefinition{{Name: "probe"}},
+	})
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	d := res.ToolDiagnostics
+	if d == nil || d.FinishReason != "stop" || d.ToolCallsKeyPresent || d.TextPresent {
+		t.Fatalf("unexpected tool diagnostics: %+v", d)
+	}
+}
+
+func TestChatModelBuildRequest_FetchRemoteImagesKeepsURLs(t *testing.T) {
+	client, err := NewClient(provider.ClientOptions{APIKey: "test", FetchRemoteImages: true})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	// OpenAI accepts image URLs, so nothing is fetched from this
+	// unreachable host.
+	_, body, err := client.ChatModel("gpt-test").(provider.RequestBuilder).BuildRequest(context.Background(), &provider.LanguageModelRequest{
+		Messages: []provider.Message{{
+			Role:  "user",
+			Parts: []provider.ContentPart{{Type: provider.ContentPartImage, ImageURL: "https://images.invalid/cat.png"}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("BuildRequest error: %v", err)
+	}
+	if !strings.Contains(string(body), `"url":"https://images.invalid/cat.png"`) {
+		t.Fatalf("expected the image URL to be sent as is, got %s", body)
+	}
+}
+
+func TestChatModelGenerate_ParsesUsage(t *testing.T) {
+	for _, tc := range []struct {
+		name, usage string
+		want        *provider.Usage
+	}{
+		{"reported", `,"usage":{"prompt_tokens":12,"completion_tokens":5,"total_tokens":17}`, &provider.Usage{PromptTokens: 12, CompletionTokens: 5, TotalTokens: 17}},
+		{"without total", `,"usage":{"prompt_tokens":12,"completion_tokens":5}`, &provider.Usage{PromptTokens: 12, CompletionTokens: 5, TotalTokens: 17}},
+		{"zero", `,"usage":{"prompt_tokens":0,"completion_tokens":0,"total_tokens":0}`, &provider.Usage{}},
+		{"not reported", ``, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprintf(w, `{"choices":[{"finish_reason":"stop","message":{"role":"assistant","content":"hi"}}]%s}`, tc.usage)
+			}))
+			defer ts.Close()
+
+			client, err := NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+			if err != nil {
+				t.Fatalf("NewClient error: %v", err)
+			}
+			res, err := client.ChatModel("gpt-test").Generate(context.Background(), &provider.LanguageModelRequest{
+				Messages: []provider.Message{{Role: "user", Content: "hi"}},
+			})
+			if err != nil {
+				t.Fatalf("Generate error: %v", err)
+			}
+			if (res.Usage == nil) != (tc.want == nil) || (tc.want != nil && *res.Usage != *tc.want) {
+				t.Fatalf("Usage = %+v, want %+v", res.Usage, tc.want)
+			}
+		})
+	}
+}
+
+func TestClient_MaxResponseBytes(t *testing.T) {
+	chat := `{"choices":[{"finish_reason":"stop","message":{"role":"assistant","content":"` + strings.Repeat("a", 200) + `"}}]}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/chat/completions":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, chat)
+		case "/v1/embeddings":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"data":[{"embedding":[%s0]}]}`, strings.Repeat("0.5,", 100))
+		case "/v1/audio/speech":
+			w.Header().Set("Content-Type", "audio/mpeg")
+			w.Write(make([]byte, 1000))
+		}
+	}))
+	defer ts.Close()
+
+	newClient := func(limit int64) *Client {
+		c, err := NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client(), MaxResponseBytes: limit})
+		if err != nil {
+			t.Fatalf("NewClient error: %v", err)
+		}
+		return c
+	}
+	calls := map[string]func(c *Client) error{
+		"chat": func(c *Client) error {
+			_, err := c.ChatModel("gpt-test").Generate(context.Background(), &provider.LanguageModelRequest{Messages: []provider.Message{{Role: "user", Content: "hi"}}})
+			return err
+		},
+		"embeddings": func(c *Client) error {
+			_, err := c.EmbeddingModel("emb").Generate(context.Background(), &provider.EmbeddingRequest{Input: []string{"hi"}})
+			return err
+		},
+		"speech": func(c *Client) error {
+			_, err := c.SpeechModel("tts-1").Generate(context.Background(), &provider.SpeechRequest{Input: "hi", Voice: "alloy"})
+			return err
+		},
+	}
+	for name, call := range calls {
+		t.Run(name, func(t *testing.T) {
+			var tooLarge *provider.ResponseTooLargeError
+			if err := call(newClient(100)); !errors.As(err, &tooLarge) || tooLarge.Provider != "openai" || tooLarge.Limit != 100 {
+				t.Fatalf("expected ResponseTooLargeError, got %T: %v", err, err)
+			}
+			if err := call(newClient(-1)); err != nil {
+				t.Fatalf("expected no limit, got %v", err)
+			}
+			if err := call(newClient(0)); err != nil {
+				t.Fatalf("expected the default limit to pass, got %v", err)
+			}
+		})
+	}
+
+	// A body of exactly the limit is accepted.
+	if err := calls["chat"](newClient(int64(len(chat)))); err != nil {
+		t.Fatalf("expected a body at the limit to pass, got %v", err)
+	}
+}
+
+func TestChatModelStream_MaxStreamBytesAndDeltas(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for range 5 {
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"abc\"}}]}\n\n")
+		}
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n")
+	}))
+	defer ts.Close()
+
+	for _, tc := range []struct {
+		name     string
+		opts     provider.ClientOptions
+		limit    string
+		wantText string
+	}{
+		{"deltas", provider.ClientOptions{MaxStreamDeltas: 2}, "deltas", "abcabc"},
+		{"bytes", provider.ClientOptions{MaxStreamBytes: 10}, "bytes", "abcabcabc"},
+		{"exact", provider.ClientOptions{MaxStreamBytes: 15, MaxStreamDeltas: 5}, "", "abcabcabcabcabc"},
+		{"disabled", provider.ClientOptions{MaxStreamBytes: -1, MaxStreamDeltas: -1}, "", "abcabcabcabcabc"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := tc.opts
+			opts.BaseURL, opts.APIKey, opts.HTTPClient = ts.URL, "test", ts.Client()
+			client, err := NewClient(opts)
+			if err != nil {
+				t.Fatalf("NewClient error: %v", err)
+			}
+			stream, err := client.ChatModel("gpt-test").Stream(context.Background(), &provider.LanguageModelRequest{Messages: []provider.Message{{Role: "user", Content: "hi"}}})
+			if err != nil {
+				t.Fatalf("Stream error: %v", err)
+			}
+			defer stream.Close()
+
+			var text strings.Builder
+			for {
+				delta, err := stream.Next(context.Background())
+				if err != nil {
+					var truncated *provider.StreamTruncatedError
+					if tc.limit == "" || !errors.As(err, &truncated) || truncated.Limit != tc.limit || truncated.Text != tc.wantText {
+						t.Fatalf("unexpected error %v", err)
+					}
+					break
+				}
+				text.WriteString(delta.Text)
+				if delta.Done {
+					if tc.limit != "" {
+						t.Fatalf("expected the stream to be truncated")
+					}
+					break
+				}
+			}
+			if text.String() != tc.wantText {
+				t.Fatalf("got text %q, want %q", text.String(), tc.wantText)
+			}
+		})
+	}
+}
+
+func TestChatModelBuildBody_ToolChoice(t *testing.T) {
+	m := &chatModel{client: &Client{}, model: "gpt-test"}
+	tools := []provider.ToolDefinition{{Name: "weather", Parameters: []byte(`{"type":"object"}`)}}
+	for _, tc := range []struct {
+		choice provider.ToolChoice
+		tools  []provider.ToolDefinition
+		want   string
+	}{
+		{provider.ToolChoiceAuto, tools, `"tool_choice":"auto"`},
+		{provider.ToolChoiceNone, tools, `"tool_choice":"none"`},
+		{provider.ToolChoiceRequired, tools, `"tool_choice":"required"`},
+		{provider.ToolChoiceTool("weather"), tools, `"tool_choice":{"function":{"name":"weather"},"type":"function"}`},
+		{"", tools, ``},
+		{provider.ToolChoiceRequired, nil, ``},
+	} {
+		t.Run(string(tc.choice), func(t *testing.T) {
+			body, err := json.Marshal(m.buildBody(&provider.LanguageModelRequest{Tools: tc.tools, ToolChoice: tc.choice}, false))
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			if tc.want == "" {
+				if strings.Contains(string(body), "tool_choice") {
+					t.Fatalf("expected no tool_choice, got %s", body)
+				}
+				return
+			}
+			if !strings.Contains(string(body), tc.want) {
+				t.Fatalf("expected %s in %s", tc.want, body)
+			}
+		})
+	}
+}
+
+func TestChatModel_ParallelToolCallsOnlyWhenSet(t *testing.T) {
+	var bodies []map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		if body["stream"] == true {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"finish_reason":"stop","message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	model := client.ChatModel("gpt-test")
+	tools := []provider.ToolDefinition{{Name: "step", Parameters: []byte(`{"type":"object"}`)}}
+	disabled := false
+	reqs := []*provider.LanguageModelRequest{
+		{Tools: tools, ParallelToolCalls: &disabled},
+		{Tools: tools},
+		{ParallelToolCalls: &disabled},
+	}
+	for _, req := range reqs {
+		req.Messages = []provider.Message{{Role: "user", Content: "go"}}
+		if _, err := model.Generate(context.Background(), req); err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+		stream, err := model.Stream(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Stream error: %v", err)
+		}
+		for {
+			delta, err := stream.Next(context.Background())
+			if err != nil {
+				t.Fatalf("Next error: %v", err)
+			}
+			if delta.Done {
+				break
+			}
+		}
+		stream.Close()
+	}
+
+	for i, body := range bodies {
+		v, ok := body["parallel_tool_calls"]
+		if want := i < 2; ok != want || (ok && v != false) {
+			t.Fatalf("request %d: parallel_tool_calls = %v (present %v), want present %v", i, v, ok, want)
+		}
+	}
+}
+
+func TestChatModel_StoreAndMetadata(t *testing.T) {
+	var bodies []map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		if body["stream"] == true {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"finish_reason":"stop","message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	defer ts.Close()
+
+	disabled, enabled := false, true
+	client, err := NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client(), DefaultStore: &disabled})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	model := client.ChatModel("gpt-test")
+	reqs := []*provider.LanguageModelRequest{
+		{Metadata: map[string]string{"tenant": "acme"}},
+		{Store: &enabled},
+	}
+	for _, req := range reqs {
+		req.Messages = []provider.Message{{Role: "user", Content: "go"}}
+		if _, err := model.Generate(context.Background(), req); err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+		stream, err := model.Stream(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Stream error: %v", err)
+		}
+		for {
+			delta, err := stream.Next(context.Background())
+			if err != nil {
+				t.Fatalf("Next error: %v", err)
+			}
+			if delta.Done {
+				break
+			}
+		}
+		stream.Close()
+	}
+
+	if len(bodies) != 4 {
+		t.Fatalf("expected 4 requests, got %d", len(bodies))
+	}
+	for i, body := range bodies {
+		wantStore := i >= 2
+		if body["store"] != wantStore {
+			t.Fatalf("request %d: store = %v, want %v", i, body["store"], wantStore)
+		}
+		metadata, _ := body["metadata"].(map[string]any)
+		if wantMetadata := i < 2; wantMetadata != (metadata["tenant"] == "acme") {
+			t.Fatalf("request %d: unexpected metadata %v", i, body["metadata"])
+		}
+	}
+}
+
+func TestChatModel_InvalidMetadata(t *testing.T) {
+	client, err := NewClient(provider.ClientOptions{BaseURL: "http://127.0.0.1:0", APIKey: "test"})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	model := client.ChatModel("gpt-test")
+
+	tooMany := map[string]string{}
+	for i := 0; i <= maxMetadataPairs; i++ {
+		tooMany[fmt.Sprintf("k%d", i)] = "v"
+	}
+	cases := []struct {
+		name     string
+		metadata map[string]string
+		key      string
+	}{
+		{"pairs", tooMany, ""},
+		{"key", map[string]string{strings.Repeat("k", maxMetadataKeyLen+1): "v"}, strings.Repeat("k", maxMetadataKeyLen+1)},
+		{"value", map[string]string{"note": strings.Repeat("é", maxMetadataValueLen+1)}, "note"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &provider.LanguageModelRequest{
+				Messages: []provider.Message{{Role: "user", Content: "go"}},
+				Metadata: tc.metadata,
+			}
+			_, err := model.Generate(context.Background(), req)
+			var metaErr *provider.InvalidMetadataError
+			if !errors.As(err, &metaErr) || metaErr.Key != tc.key {
+				t.Fatalf("expected *InvalidMetadataError for %q, got %T: %v", tc.key, err, err)
+			}
+		})
+	}
+
+	// Values are measured in characters, not bytes.
+	ok := map[string]string{"note": strings.Repeat("é", maxMetadataValueLen)}
+	if err := validateMetadata(ok); err != nil {
+		t.Fatalf("expected %d characters to be accepted, got %v", maxMetadataValueLen, err)
+	}
+}
+
+func TestChatModel_PenaltiesOmittedWhenNil(t *testing.T) {
+	var bodies []map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"finish_reason":"stop","message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	model := client.ChatModel("gpt-test")
+	frequency, presence := 0.5, -1.0
+	reqs := []*provider.LanguageModelRequest{
+		{},
+		{FrequencyPenalty: &frequency, PresencePenalty: &presence},
+	}
+	for _, req := range reqs {
+		req.Messages = []provider.Message{{Role: "user", Content: "go"}}
+		if _, err := model.Generate(context.Background(), req); err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+	}
+
+	for _, key := range []string{"frequency_penalty", "presence_penalty"} {
+		if v, ok := bodies[0][key]; ok {
+			t.Fatalf("expected %s to be omitted when nil, got %v", key, v)
+		}
+	}
+	if bodies[1]["frequency_penalty"] != 0.5 || bodies[1]["presence_penalty"] != -1.0 {
+		t.Fatalf("unexpected penalties in %v", bodies[1])
+	}
+}
+
+func TestChatModel_CacheUsage(t *testing.T) {
+	var bodies []map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		usage := `{"prompt_tokens":2006,"completion_tokens":300,"total_tokens":2306,"prompt_tokens_details":{"cached_tokens":1920}}`
+		if body["stream"] == true {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"ok\"}}]}\n\n")
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n")
+			fmt.Fprintf(w, "data: {\"choices\":[],\"usage\":%s}\n\ndata: [DONE]\n\n", usage)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"choices":[{"finish_reason":"stop","message":{"role":"assistant","content":"ok"}}],"usage":%s}`, usage)
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	model := client.ChatModel("gpt-test")
+	req := &provider.LanguageModelRequest{Messages: []provider.Message{{Role: "user", Content: "go"}}}
+	want := &provider.Usage{
+		PromptTokens: 2006, CompletionTokens: 300, TotalTokens: 2306,
+		Cache: &provider.CacheUsage{CachedInputTokens: 1920},
+	}
+
+	res, err := model.Generate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	if !reflect.DeepEqual(res.Usage, want) {
+		t.Fatalf("Generate usage = %+v, want %+v", res.Usage, want)
+	}
+
+	stream, err := model.Stream(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Stream error: %v", err)
+	}
+	defer stream.Close()
+	var text string
+	for {
+		delta, err := stream.Next(context.Background())
+		if err != nil {
+			t.Fatalf("Next error: %v", err)
+		}
+		text += delta.Text
+		if delta.Done {
+			if !reflect.DeepEqual(delta.Usage, want) {
+				t.Fatalf("stream usage = %+v, want %+v", delta.Usage, want)
+			}
+			break
+		}
+	}
+	if text != "ok" {
+		t.Fatalf("unexpected text %q", text)
+	}
+	if opts, _ := bodies[1]["stream_options"].(map[string]any); opts["include_usage"] != true {
+		t.Fatalf("expected stream_options.include_usage, got %v", bodies[1]["stream_options"])
+	}
+	if _, ok := bodies[0]["stream_options"]; ok {
+		t.Fatalf("stream_options must not be sent with Generate")
+	}
+}
+
+func TestChatModelBuildBody_ResponseFormat(t *testing.T) {
+	m := &chatModel{client: &Client{}, model: "gpt-test"}
+	schema := []byte(`{"type":"object","properties":{"name":{"type":"string"}}}`)
+	for _, tc := range []struct {
+		name   string
+		req    provider.LanguageModelRequest
+		format string
+	}{
+		{"none", provider.LanguageModelRequest{}, ""},
+		{"json_object", provider.LanguageModelRequest{ResponseFormat: provider.ResponseFormatJSON}, `{"type":"json_object"}`},
+		{"json_object with schema", provider.LanguageModelRequest{ResponseFormat: provider.ResponseFormatJSON, JSONSchema: schema}, `{"type":"json_object"}`},
+		{"json_schema", provider.LanguageModelRequest{JSONSchema: schema}, `{"type":"json_schema","json_schema":{"name":"response","schema":` + string(schema) + `}}`},
+		{"named json_schema", provider.LanguageModelRequest{JSONSchema: schema, JSONSchemaName: "person"}, `{"type":"json_schema","json_schema":{"name":"person","schema":` + string(schema) + `}}`},
+		{"strict json_schema", provider.LanguageModelRequest{JSONSchema: schema, JSONSchemaStrict: true}, `{"type":"json_schema","json_schema":{"name":"response","strict":true,"schema":` + string(schema) + `}}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var body struct {
+				ResponseFormat json.RawMessage `json:"response_format"`
+			}
+			buf, _ := json.Marshal(m.buildBody(&tc.req, false))
+			if err := json.Unmarshal(buf, &body); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if string(body.ResponseFormat) != tc.format {
+				t.Fatalf("response_format = %s, want %s", body.ResponseFormat, tc.format)
+			}
+		})
+	}
+}
+
+func TestChatModel_ProviderOptions(t *testing.T) {
+	var bodies []map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		if body["stream"] == true {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"finish_reason":"stop","message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	defer ts.Close()
+
+	var warnings []string
+	client, err := NewClient(provider.ClientOptions{
+		BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client(),
+		OnLintWarnings: func(source string, w []string) { warnings = append(warnings, w...) },
+	})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	model := client.ChatModel("gpt-test")
+	temperature := 0.5
+	req := &provider.LanguageModelRequest{
+		Messages:    []provider.Message{{Role: "user", Content: "go"}},
+		Temperature: &temperature,
+		ProviderOptions: map[string]any{
+			"service_tier": "flex",
+			"prediction":   map[string]any{"type": "content", "content": "x"},
+			"temperature":  1.5,
+			"model":        "other",
+		},
+	}
+	if _, err := model.Generate(context.Background(), req); err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	stream, err := model.Stream(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Stream error: %v", err)
+	}
+	for {
+		delta, err := stream.Next(context.Background())
+		if err != nil {
+			t.Fatalf("Next error: %v", err)
+		}
+		if delta.Done {
+			break
+		}
+	}
+	stream.Close()
+
+	if len(bodies) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(bodies))
+	}
+	for i, body := range bodies {
+		if body["service_tier"] != "flex" {
+			t.Fatalf("request %d: service_tier = %v", i, body["service_tier"])
+		}
+		if prediction, _ := body["prediction"].(map[string]any); prediction["content"] != "x" {
+			t.Fatalf("request %d: prediction = %v", i, body["prediction"])
+		}
+		if body["temperature"] != 0.5 || body["model"] != "gpt-test" {
+			t.Fatalf("request %d: typed fields were overwritten: temperature %v, model %v", i, body["temperature"], body["model"])
+		}
+	}
+	if len(warnings) != 4 || !strings.Contains(warnings[0], `"model"`) || !strings.Contains(warnings[1], `"temperature"`) {
+		t.Fatalf("expected collision warnings for model and temperature on each call, got %q", warnings)
+	}
+}
+
+func TestChatModel_ProviderOptionsUnmarshalable(t *testing.T) {
+	m := &chatModel{client: &Client{}, model: "gpt-test"}
+	_, _, err := m.BuildRequest(context.Background(), &provider.LanguageModelRequest{
+		Messages:        []provider.Message{{Role: "user", Content: "go"}},
+		ProviderOptions: map[string]any{"bad": func() {}},
+	})
+	if err == nil || !strings.Contains(err.Error(), `"bad"`) {
+		t.Fatalf("expected an error naming the option, got %v", err)
+	}
+}
+
+func TestChatModel_ProviderOptionsLintsUnknownKeys(t *testing.T) {
+	var warnings []string
+	m := &chatModel{client: &Client{onLint: func(source string, w []string) { warnings = append(warnings, w...) }}, model: "gpt-test"}
+	_, _, err := m.BuildRequest(context.Background(), &provider.LanguageModelRequest{
+		Messages:        []provider.Message{{Role: "user", Content: "go"}},
+		ProviderOptions: map[string]any{"reasonig_effort": "low", "service_tier": "flex", "frobnicate": true},
+	})
+	if err != nil {
+		t.Fatalf("BuildRequest error: %v", err)
+	}
+	want := []string{
+		`provider option "frobnicate" is not a known chat completions field`,
+		`extra key "reasonig_effort" looks like a misspelling of "reasoning_effort"`,
+	}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Fatalf("warnings = %q, want %q", warnings, want)
+	}
+}
+
+func TestClient_ContextHeaders(t *testing.T) {
+	tenants := map[string]string{}
+	var auth []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenants[r.URL.Path] = r.Header.Get("X-Tenant-ID")
+		auth = append(auth, r.Header.Get("Authorization"))
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/chat/completions"):
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"choices":[{"finish_reason":"stop","message":{"role":"assistant","content":"ok"}}]}`)
+		case strings.HasSuffix(r.URL.Path, "/embeddings"):
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"data":[{"embedding":[0.5]}]}`)
+		case strings.HasSuffix(r.URL.Path, "/images/generations"):
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"data":[{"b64_json":"aGk="}]}`)
+		case strings.HasSuffix(r.URL.Path, "/audio/speech"):
+			w.Header().Set("Content-Type", "audio/mpeg")
+			fmt.Fprint(w, "ID3")
+		case strings.HasSuffix(r.URL.Path, "/audio/transcriptions"):
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"text":"hi"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(provider.ClientOptions{
+		BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client(),
+		Headers: http.Header{"X-Tenant-Id": {"default"}},
+	})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	ctx := provider.WithHeaders(context.Background(), http.Header{
+		"X-Tenant-ID":   {"acme"},
+		"Authorization": {"Bearer stolen"},
+	})
+
+	if _, err := client.ChatModel("gpt-test").Generate(ctx, &provider.LanguageModelRequest{Messages: []provider.Message{{Role: "user", Content: "hi"}}}); err != nil {
+		t.Fatalf("chat error: %v", err)
+	}
+	if _, err := client.EmbeddingModel("embed").Generate(ctx, &provider.EmbeddingRequest{Input: []string{"hi"}}); err != nil {
+		t.Fatalf("embeddings error: %v", err)
+	}
+	if _, err := client.ImageModel("image").Generate(ctx, &provider.ImageRequest{Prompt: "a cat"}); err != nil {
+		t.Fatalf("images error: %v", err)
+	}
+	if _, err := client.SpeechModel("tts").Generate(ctx, &provider.SpeechRequest{Input: "hi", Voice: "alloy"}); err != nil {
+		t.Fatalf("speech error: %v", err)
+	}
+	if _, err := client.TranscriptionModel("whisper").Generate(ctx, &provider.TranscriptionRequest{Audio: []byte("RIFF"), FileName: "a.wav"}); err != nil {
+		t.Fatalf("transcription error: %v", err)
+	}
+	if _, err := client.ChatModel("gpt-test").Generate(context.Background(), &provider.LanguageModelRequest{Messages: []provider.Message{{Role: "user", Content: "hi"}}}); err != nil {
+		t.Fatalf("chat error: %v", err)
+	}
+
+	if len(tenants) != 5 {
+		t.Fatalf("expected 5 endpoints, got %v", tenants)
+	}
+	for path, tenant := range tenants {
+		want := "acme"
+		if strings.HasSuffix(path, "/chat/completions") {
+			want = "default" // the last call had no per-request headers
+		}
+		if tenant != want {
+			t.Fatalf("%s: X-Tenant-ID = %q, want %q", path, tenant, want)
+		}
+	}
+	for i, a := range auth {
+		if a != "Bearer test" {
+			t.Fatalf("request %d: Authorization = %q, want the client's key", i, a)
+		}
+	}
+}
+
+func TestChatModel_DroppedOptions(t *testing.T) {
+	var bodies []map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		if body["stream"] == true {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"finish_reason":"stop","message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	defer ts.Close()
+
+	if _, err := NewClient(provider.ClientOptions{APIKey: "test", UnsupportedOptions: []string{"Seed"}}); err == nil {
+		t.Fatal("expected an unknown UnsupportedOptions field to fail")
+	}
+
+	type report struct {
+		source  string
+		dropped []provider.DroppedOption
+	}
+	var reports []report
+	opts := provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client(),
+		// A compatible backend without JSON mode or logit bias.
+		UnsupportedOptions: []string{"ResponseFormat", "LogitBias", "Store"},
+		OnDroppedOptions: func(source string, dropped []provider.DroppedOption) {
+			reports = append(reports, report{source, dropped})
+		},
+	}
+	client, err := NewClient(opts)
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	model := client.ChatModel("gpt-test")
+
+	penalty := 0.5
+	req := &provider.LanguageModelRequest{
+		Messages:         []provider.Message{{Role: "user", Content: "hi"}},
+		ResponseFormat:   provider.ResponseFormatJSON,
+		LogitBias:        map[string]float64{"50256": -100},
+		FrequencyPenalty: &penalty,
+		Reasoning:        &provider.ReasoningOptions{Effort: "low", BudgetTokens: 2048},
+		WebSearch:        &provider.WebSearchOptions{},
+	}
+	want := []string{"WebSearch", "Reasoning.BudgetTokens", "ResponseFormat", "LogitBias"}
+	fields := func(dropped []provider.DroppedOption) []string {
+		var out []string
+		for _, d := range dropped {
+			if d.Reason == "" {
+				t.Fatalf("%s dropped without a reason", d.Field)
+			}
+			out = append(out, d.Field)
+		}
+		return out
+	}
+
+	res, err := model.Generate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	if got := fields(res.Metadata.DroppedOptions); !reflect.DeepEqual(got, want) {
+		t.Fatalf("DroppedOptions = %v, want %v", got, want)
+	}
+	stream, err := model.Stream(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Stream error: %v", err)
+	}
+	stream.Close()
+	if len(reports) != 2 || reports[0].source != "openai.chat" || !reflect.DeepEqual(fields(reports[1].dropped), want) {
+		t.Fatalf("unexpected reports %+v", reports)
+	}
+	for _, key := range []string{"response_format", "logit_bias"} {
+		if v, ok := bodies[0][key]; ok {
+			t.Fatalf("expected %s to be left out, got %v", key, v)
+		}
+	}
+	if bodies[0]["frequency_penalty"] != 0.5 || bodies[0]["reasoning_effort"] != "low" {
+		t.Fatalf("expected the supported options to be sent, got %v", bodies[0])
+	}
+
+	// Requests without dropped options report nothing.
+	reports = nil
+	res, err = model.Generate(context.Background(), &provider.LanguageModelRequest{Messages: req.Messages, FrequencyPenalty: &penalty})
+	if err != nil || res.Metadata.DroppedOptions != nil || reports != nil {
+		t.Fatalf("unexpected report %v, %+v (err %v)", res.Metadata.DroppedOptions, reports, err)
+	}
+
+	// Strict clients reject the request before sending it, and dry
+	// runs show the same error.
+	opts.StrictOptions = true
+	strict, err := NewClient(opts)
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	sent := len(bodies)
+	_, err = strict.ChatModel("gpt-test").Generate(context.Background(), req)
+	var unsupported *provider.UnsupportedFunctionalityError
+	if !errors.As(err, &unsupported) || !reflect.DeepEqual(fields(unsupported.Dropped), want) || !strings.Contains(err.Error(), "WebSearch") {
+		t.Fatalf("expected *UnsupportedFunctionalityError, got %v", err)
+	}
+	if _, _, err := strict.ChatModel("gpt-test").(provider.RequestBuilder).BuildRequest(context.Background(), req); !errors.As(err, &unsupported) {
+		t.Fatalf("expected BuildRequest to fail, got %v", err)
+	}
+	if len(bodies) != sent {
+		t.Fatal("strict client sent the request")
+	}
+}
+
+func TestBatchModel_SubmitsAndRetrievesBatch(t *testing.T) {
+	ctx := context.Background()
+
+	var polls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/files":
+			if r.FormValue("purpose") != "batch" {
+				t.Errorf("purpose = %q, want batch", r.FormValue("purpose"))
+			}
+			file, _, err := r.FormFile("file")
+			if err != nil {
+				t.Fatalf("FormFile: %v", err)
+			}
+			var line openAIBatchInput
+			if err := json.NewDecoder(file).Decode(&line); err != nil {
+				t.Fatalf("decoding batch input: %v", err)
+			}
+			if line.URL != "/v1/chat/completions" || !strings.Contains(string(line.Body), `"model":"gpt-4o-mini"`) {
+				t.Errorf("unexpected batch input: %+v", line)
+			}
+			fmt.Fprint(w, `{"id":"file-in"}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/batches":
+			var body map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Fatalf("decoding batch create: %v", err)
+			}
+			if body["input_file_id"] != "file-in" || body["completion_window"] != "24h" {
+				t.Errorf("unexpected batch create: %v", body)
+			}
+			fmt.Fprint(w, `{"id":"batch-1","status":"validating"}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/batches/batch-1":
+			polls++
+			if polls == 1 {
+				fmt.Fprint(w, `{"id":"batch-1","status":"in_progress"}`)
+				return
+			}
+			fmt.Fprint(w, `{"id":"batch-1","status":"completed","output_file_id":"file-out"}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/files/file-out/content":
+			fmt.Fprint(w, `{"custom_id":"request-1","response":{"status_code":200,"body":{"choices":[{"finish_reason":"stop","message":{"role":"assistant","content":"batched"}}]}}}`+"\n")
+		default:
+			t.Fatalf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test-key", HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	model, ok := client.BatchModel("gpt-4o-mini").(provider.DeferredLanguageModel)
+	if !ok {
+		t.Fatal("BatchModel does not implement provider.DeferredLanguageModel")
+	}
+
+	id, err := model.SubmitDeferred(ctx, &provider.LanguageModelRequest{
+		Messages: []provider.Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("SubmitDeferred error: %v", err)
+	}
+	if id != "batch-1" {
+		t.Fatalf("job ID = %q, want batch-1", id)
+	}
+
+	if _, done, err := model.RetrieveDeferred(ctx, id); err != nil || done {
+		t.Fatalf("first RetrieveDeferred = done %v, err %v; want pending", done, err)
+	}
+	resp, done, err := model.RetrieveDeferred(ctx, id)
+	if err != nil || !done {
+		t.Fatalf("second RetrieveDeferred = done %v, err %v; want done", done, err)
+	}
+	if resp.Text != "batched" {
+		t.Fatalf("Text = %q, want batched", resp.Text)
+	}
+}
+
+func TestBatchModel_FailedBatch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"batch-1","status":"failed","errors":{"data":[{"code":"invalid","message":"bad input"}]}}`)
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test-key", HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	model := client.BatchModel("gpt-4o-mini").(provider.DeferredLanguageModel)
+	_, _, err = model.RetrieveDeferred(context.Background(), "batch-1")
+	if err == nil || !strings.Contains(err.Error(), "bad input") {
+		t.Fatalf("RetrieveDeferred error = %v, want failed batch error", err)
+	}
+}
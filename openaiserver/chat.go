@@ -0,0 +1,449 @@
+package openaiserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// chatRequest is the subset of the chat completions request that maps
+// onto provider.LanguageModelRequest.
+type chatRequest struct {
+	Model               string             `json:"model"`
+	Messages            []chatMessage      `json:"messages"`
+	Temperature         *float64           `json:"temperature"`
+	TopP                *float64           `json:"top_p"`
+	MaxTokens           *int               `json:"max_tokens"`
+	MaxCompletionTokens *int               `json:"max_completion_tokens"`
+	Stop                json.RawMessage    `json:"stop"`
+	N                   int                `json:"n"`
+	ResponseFormat      *responseFormat    `json:"response_format"`
+	Tools               []chatTool         `json:"tools"`
+	ToolChoice          json.RawMessage    `json:"tool_choice"`
+	ParallelToolCalls   *bool              `json:"parallel_tool_calls"`
+	LogitBias           map[string]float64 `json:"logit_bias"`
+	FrequencyPenalty    *float64           `json:"frequency_penalty"`
+	PresencePenalty     *float64           `json:"presence_penalty"`
+	ReasoningEffort     string             `json:"reasoning_effort"`
+	Metadata            map[string]string  `json:"metadata"`
+	User                string             `json:"user"`
+	Store               *bool              `json:"store"`
+	StreamOptions       *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+	Stream bool `json:"stream"`
+}
+
+type chatMessage struct {
+	Role string `json:"role"`
+	// Content is a string, an array of content parts, or null.
+	Content    json.RawMessage `json:"content"`
+	ToolCalls  []toolCallWire  `json:"tool_calls"`
+	ToolCallID string          `json:"tool_call_id"`
+}
+
+type contentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	ImageURL *struct {
+		URL string `json:"url"`
+	} `json:"image_url,omitempty"`
+}
+
+type responseFormat struct {
+	Type       string `json:"type"`
+	JSONSchema *struct {
+		Name   string          `json:"name"`
+		Strict bool            `json:"strict"`
+		Schema json.RawMessage `json:"schema"`
+	} `json:"json_schema"`
+}
+
+type chatTool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description"`
+		Parameters  json.RawMessage `json:"parameters"`
+	} `json:"function"`
+}
+
+// toProviderRequest translates req into a provider request.
+func (req *chatRequest) toProviderRequest() (*provider.LanguageModelRequest, error) {
+	if req.N > 1 {
+		return nil, errors.New("n > 1 is not supported")
+	}
+	out := &provider.LanguageModelRequest{
+		Model:            req.Model,
+		Temperature:      req.Temperature,
+		TopP:             req.TopP,
+		MaxTokens:        req.MaxTokens,
+		FrequencyPenalty: req.FrequencyPenalty,
+		PresencePenalty:  req.PresencePenalty,
+		LogitBias:        req.LogitBias,
+		Metadata:         req.Metadata,
+		UserID:           req.User,
+		Store:            req.Store,
+	}
+	if req.MaxCompletionTokens != nil {
+		out.MaxTokens = req.MaxCompletionTokens
+	}
+	if req.ReasoningEffort != "" {
+		out.Reasoning = &provider.ReasoningOptions{Effort: req.ReasoningEffort}
+	}
+
+	if len(req.Stop) > 0 && string(req.Stop) != "null" {
+		var stop string
+		if err := json.Unmarshal(req.Stop, &stop); err == nil {
+			out.Stop = []string{stop}
+		} else if err := json.Unmarshal(req.Stop, &out.Stop); err != nil {
+			return nil, errors.New("stop must be a string or an array of strings")
+		}
+	}
+
+	if f := req.ResponseFormat; f != nil {
+		switch f.Type {
+		case "json_schema":
+			if f.JSONSchema == nil || len(f.JSONSchema.Schema) == 0 {
+				return nil, errors.New("response_format.json_schema.schema is required")
+			}
+			out.JSONSchema = f.JSONSchema.Schema
+			out.JSONSchemaName = f.JSONSchema.Name
+			out.JSONSchemaStrict = f.JSONSchema.Strict
+		case "json_object":
+			out.ResponseFormat = provider.ResponseFormatJSON
+		case "", "text":
+		default:
+			return nil, fmt.Errorf("unsupported response_format type %q", f.Type)
+		}
+	}
+
+	for _, t := range req.Tools {
+		if t.Type != "function" {
+			return nil, fmt.Errorf("unsupported tool type %q", t.Type)
+		}
+		out.Tools = append(out.Tools, provider.ToolDefinition{
+			Name:        t.Function.Name,
+			Description: t.Function.Description,
+			Parameters:  t.Function.Parameters,
+		})
+	}
+
+	choice, err := toolChoice(req.ToolChoice)
+	if err != nil {
+		return nil, err
+	}
+	out.ToolChoice = choice
+	out.ParallelToolCalls = req.ParallelToolCalls
+
+	for i, m := range req.Messages {
+		msg, err := m.toProviderMessage()
+		if err != nil {
+			return nil, fmt.Errorf("messages[%d]: %w", i, err)
+		}
+		out.Messages = append(out.Messages, msg)
+	}
+	return out, nil
+}
+
+// toolChoice parses tool_choice: "auto", "none", "required", or
+// {"type":"function","function":{"name":...}}.
+func toolChoice(raw json.RawMessage) (provider.ToolChoice, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+	var mode string
+	if json.Unmarshal(raw, &mode) == nil {
+		switch choice := provider.ToolChoice(mode); choice {
+		case provider.ToolChoiceAuto, provider.ToolChoiceNone, provider.ToolChoiceRequired:
+			return choice, nil
+		}
+		return "", fmt.Errorf("unsupported tool_choice %q", mode)
+	}
+	var named struct {
+		Type     string `json:"type"`
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if json.Unmarshal(raw, &named) != nil || named.Type != "function" || named.Function.Name == "" {
+		return "", errors.New(`tool_choice must be "auto", "none", "required", or a function object`)
+	}
+	return provider.ToolChoiceTool(named.Function.Name), nil
+}
+
+func (m chatMessage) toProviderMessage() (provider.Message, error) {
+	role := m.Role
+	if role == "developer" {
+		role = "system"
+	}
+	msg := provider.Message{Role: role, ToolCallID: m.ToolCallID}
+	for _, tc := range m.ToolCalls {
+		msg.ToolCalls = append(msg.ToolCalls, provider.ToolCall{ID: tc.ID, Name: tc.Function.Name, RawArguments: []byte(tc.Function.Arguments)})
+	}
+	if len(m.Content) == 0 || string(m.Content) == "null" {
+		return msg, nil
+	}
+	if err := json.Unmarshal(m.Content, &msg.Content); err == nil {
+		return msg, nil
+	}
+	var parts []contentPart
+	if err := json.Unmarshal(m.Content, &parts); err != nil {
+		return provider.Message{}, errors.New("content must be a string or an array of content parts")
+	}
+	var text []string
+	for _, p := range parts {
+		switch p.Type {
+		case "text":
+			msg.Parts = append(msg.Parts, provider.ContentPart{Type: provider.ContentPartText, Text: p.Text})
+			text = append(text, p.Text)
+		case "image_url":
+			if p.ImageURL == nil {
+				return provider.Message{}, errors.New("image_url part without a url")
+			}
+			msg.Parts = append(msg.Parts, provider.ContentPart{Type: provider.ContentPartImage, ImageURL: p.ImageURL.URL})
+		default:
+			return provider.Message{}, fmt.Errorf("unsupported content part type %q", p.Type)
+		}
+	}
+	msg.Content = strings.Join(text, "\n")
+	return msg, nil
+}
+
+// chatCompletion is a non-streaming chat completions response.
+type chatCompletion struct {
+	ID      string       `json:"id"`
+	Object  string       `json:"object"`
+	Created int64        `json:"created"`
+	Model   string       `json:"model"`
+	Choices []chatChoice `json:"choices"`
+	// Usage is omitted when the model did not report usage.
+	Usage *usageWire `json:"usage,omitempty"`
+}
+
+// usageWire is the OpenAI usage object.
+type usageWire struct {
+	PromptTokens        int                  `json:"prompt_tokens"`
+	CompletionTokens    int                  `json:"completion_tokens"`
+	TotalTokens         int                  `json:"total_tokens"`
+	PromptTokensDetails *promptTokensDetails `json:"prompt_tokens_details,omitempty"`
+}
+
+type promptTokensDetails struct {
+	CachedTokens int `json:"cached_tokens"`
+}
+
+// toUsageWire renders u, which may be nil, in the OpenAI format. Cache
+// writes have no OpenAI equivalent and are only counted in
+// prompt_tokens.
+func toUsageWire(u *provider.Usage) *usageWire {
+	if u == nil {
+		return nil
+	}
+	out := &usageWire{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens, TotalTokens: u.TotalTokens}
+	if u.Cache != nil {
+		out.PromptTokensDetails = &promptTokensDetails{CachedTokens: u.Cache.CachedInputTokens}
+	}
+	return out
+}
+
+type chatChoice struct {
+	Index        int             `json:"index"`
+	Message      responseMessage `json:"message"`
+	FinishReason string          `json:"finish_reason"`
+}
+
+type responseMessage struct {
+	Role             string         `json:"role"`
+	Content          *string        `json:"content"`
+	ReasoningContent string         `json:"reasoning_content,omitempty"`
+	ToolCalls        []toolCallWire `json:"tool_calls,omitempty"`
+}
+
+type toolCallWire struct {
+	Index    *int   `json:"index,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// chatChunk is one server-sent event of a streaming response.
+type chatChunk struct {
+	ID      string            `json:"id"`
+	Object  string            `json:"object"`
+	Created int64             `json:"created"`
+	Model   string            `json:"model"`
+	Choices []chatChunkChoice `json:"choices"`
+	// Usage is only sent in the last chunk, which has no choices, when
+	// the request set stream_options.include_usage.
+	Usage *usageWire `json:"usage,omitempty"`
+}
+
+type chatChunkChoice struct {
+	Index        int        `json:"index"`
+	Delta        chunkDelta `json:"delta"`
+	FinishReason *string    `json:"finish_reason"`
+}
+
+type chunkDelta struct {
+	Role             string         `json:"role,omitempty"`
+	Content          string         `json:"content,omitempty"`
+	ReasoningContent string         `json:"reasoning_content,omitempty"`
+	ToolCalls        []toolCallWire `json:"tool_calls,omitempty"`
+}
+
+func (s *Server) serveChat(w http.ResponseWriter, r *http.Request, req *chatRequest) {
+	lmReq, err := req.toProviderRequest()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", err.Error())
+		return
+	}
+	model, err := s.Registry.LanguageModel(req.Model)
+	if err != nil {
+		writeModelError(w, err)
+		return
+	}
+	id := "chatcmpl-" + provider.NewTraceID()
+	created := time.Now().Unix()
+
+	if req.Stream {
+		includeUsage := req.StreamOptions != nil && req.StreamOptions.IncludeUsage
+		s.streamChat(w, r, model, lmReq, chatChunk{ID: id, Object: "chat.completion.chunk", Created: created, Model: req.Model}, includeUsage)
+		return
+	}
+
+	res, err := model.Generate(r.Context(), lmReq)
+	if err != nil {
+		writeModelError(w, err)
+		return
+	}
+	msg := responseMessage{Role: "assistant", ReasoningContent: res.Reasoning}
+	if res.Text != "" || len(res.ToolCalls) == 0 {
+		msg.Content = &res.Text
+	}
+	for _, tc := range res.ToolCalls {
+		msg.ToolCalls = append(msg.ToolCalls, toWireToolCall(tc, nil))
+	}
+	writeJSON(w, http.StatusOK, chatCompletion{
+		ID:      id,
+		Object:  "chat.completion",
+		Created: created,
+		Model:   req.Model,
+		Choices: []chatChoice{{
+			Message:      msg,
+			FinishReason: finishReason(res.FinishReason, res.StopReason, len(res.ToolCalls) > 0),
+		}},
+		Usage: toUsageWire(res.Usage),
+	})
+}
+
+// streamChat writes the model's stream as chat.completion.chunk events
+// followed by "data: [DONE]". With includeUsage, a chunk without
+// choices carrying the usage reported on the final delta, if any,
+// precedes [DONE]. Errors
+// after the first event are sent as an error event, since the status
+// has already been written.
+func (s *Server) streamChat(w http.ResponseWriter, r *http.Request, model provider.LanguageModel, lmReq *provider.LanguageModelRequest, chunk chatChunk, includeUsage bool) {
+	ctx := r.Context()
+	stream, err := model.Stream(ctx, lmReq)
+	if err != nil {
+		writeModelError(w, err)
+		return
+	}
+	defer stream.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	flusher, _ := w.(http.Flusher)
+	send := func(v any) {
+		data, _ := json.Marshal(v)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	emit := func(delta chunkDelta, finish *string) {
+		chunk.Choices = []chatChunkChoice{{Delta: delta, FinishReason: finish}}
+		send(chunk)
+	}
+
+	emit(chunkDelta{Role: "assistant"}, nil)
+	calls := 0
+	var final *provider.LanguageModelDelta
+	for {
+		delta, err := stream.Next(ctx)
+		if err != nil {
+			send(errorBody{Error: errorDetail{Message: err.Error(), Type: "server_error"}})
+			return
+		}
+		out := chunkDelta{Content: delta.Text, ReasoningContent: delta.Reasoning}
+		for _, tc := range delta.ToolCalls {
+			index := calls
+			calls++
+			out.ToolCalls = append(out.ToolCalls, toWireToolCall(tc, &index))
+		}
+		if out.Content != "" || out.ReasoningContent != "" || len(out.ToolCalls) > 0 {
+			emit(out, nil)
+		}
+		if delta.Done {
+			final = delta
+			break
+		}
+	}
+	reason := finishReason(final.FinishReason, final.StopReason, calls > 0)
+	emit(chunkDelta{}, &reason)
+	if includeUsage && final.Usage != nil {
+		chunk.Choices = []chatChunkChoice{}
+		chunk.Usage = toUsageWire(final.Usage)
+		send(chunk)
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+// toWireToolCall renders tc with its arguments as a JSON string, as the
+// OpenAI wire format expects. Arguments that are already a JSON string
+// (as returned by the SDK's OpenAI client) are not quoted again.
+func toWireToolCall(tc provider.ToolCall, index *int) toolCallWire {
+	wire := toolCallWire{Index: index, ID: tc.ID}
+	if tc.ID != "" || tc.Name != "" {
+		wire.Type = "function"
+	}
+	wire.Function.Name = tc.Name
+	wire.Function.Arguments = string(tc.RawArguments)
+	var s string
+	if json.Unmarshal(tc.RawArguments, &s) == nil {
+		wire.Function.Arguments = s
+	}
+	return wire
+}
+
+// finishReason maps a finish reason onto the OpenAI values, deriving it
+// from the provider's stop reason for models that do not set one. Stop
+// reasons without a normalized equivalent are passed through.
+func finishReason(reason provider.FinishReason, stop string, toolCalls bool) string {
+	if toolCalls {
+		return string(provider.FinishReasonToolCalls)
+	}
+	if reason == "" {
+		reason = provider.ParseFinishReason(stop)
+	}
+	switch reason {
+	case "", provider.FinishReasonToolCalls:
+		return string(provider.FinishReasonStop)
+	case provider.FinishReasonOther:
+		return stop
+	}
+	return string(reason)
+}
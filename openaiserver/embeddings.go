@@ -0,0 +1,73 @@
+package openaiserver
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"net/http"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+type embeddingRequest struct {
+	Model string `json:"model"`
+	// Input is a string or an array of strings.
+	Input          json.RawMessage `json:"input"`
+	User           string          `json:"user"`
+	EncodingFormat string          `json:"encoding_format"`
+}
+
+type embeddingList struct {
+	Object string          `json:"object"`
+	Data   []embeddingData `json:"data"`
+	Model  string          `json:"model"`
+}
+
+type embeddingData struct {
+	Object string `json:"object"`
+	Index  int    `json:"index"`
+	// Embedding is a []float32, or a base64 string of little-endian
+	// float32 values when encoding_format is "base64".
+	Embedding any `json:"embedding"`
+}
+
+func (s *Server) serveEmbeddings(w http.ResponseWriter, r *http.Request, req *embeddingRequest) {
+	var input []string
+	var single string
+	if err := json.Unmarshal(req.Input, &single); err == nil {
+		input = []string{single}
+	} else if err := json.Unmarshal(req.Input, &input); err != nil || len(input) == 0 {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", "input must be a string or a non-empty array of strings")
+		return
+	}
+	if req.EncodingFormat != "" && req.EncodingFormat != "float" && req.EncodingFormat != "base64" {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", "encoding_format must be \"float\" or \"base64\"")
+		return
+	}
+
+	model, err := s.Registry.EmbeddingModel(req.Model)
+	if err != nil {
+		writeModelError(w, err)
+		return
+	}
+	res, err := model.Generate(r.Context(), &provider.EmbeddingRequest{Model: req.Model, Input: input, UserID: req.User})
+	if err != nil {
+		writeModelError(w, err)
+		return
+	}
+
+	out := embeddingList{Object: "list", Model: req.Model, Data: make([]embeddingData, len(res.Embeddings))}
+	for i, e := range res.Embeddings {
+		var v any = e
+		if req.EncodingFormat == "base64" {
+			buf := make([]byte, 4*len(e))
+			for j, f := range e {
+				binary.LittleEndian.PutUint32(buf[4*j:], math.Float32bits(f))
+			}
+			v = base64.StdEncoding.EncodeToString(buf)
+		}
+		out.Data[i] = embeddingData{Object: "embedding", Index: i, Embedding: v}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,323 @@
+package openaiserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/openai"
+	"github.com/ncecere/ai-sdk/provider"
+	"github.com/ncecere/ai-sdk/registry"
+)
+
+// fakeModel answers with a fixed response and records the requests it
+// receives. Stream replays deltas.
+type fakeModel struct {
+	res      *provider.LanguageModelResponse
+	deltas   []provider.LanguageModelDelta
+	err      error
+	requests []*provider.LanguageModelRequest
+}
+
+func (m *fakeModel) Generate(ctx context.Context, req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
+	m.requests = append(m.requests, req)
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.res, nil
+}
+
+func (m *fakeModel) Stream(ctx context.Context, req *provider.LanguageModelRequest) (provider.LanguageModelStream, error) {
+	m.requests = append(m.requests, req)
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &fakeStream{deltas: m.deltas}, nil
+}
+
+type fakeStream struct {
+	deltas []provider.LanguageModelDelta
+}
+
+func (s *fakeStream) Next(ctx context.Context) (*provider.LanguageModelDelta, error) {
+	if len(s.deltas) == 0 {
+		return &provider.LanguageModelDelta{Done: true}, nil
+	}
+	d := s.deltas[0]
+	s.deltas = s.deltas[1:]
+	return &d, nil
+}
+
+func (s *fakeStream) Close() error { return nil }
+
+type fakeEmbeddingModel struct{}
+
+func (fakeEmbeddingModel) Generate(ctx context.Context, req *provider.EmbeddingRequest) (*provider.EmbeddingResponse, error) {
+	res := &provider.EmbeddingResponse{}
+	for i := range req.Input {
+		res.Embeddings = append(res.Embeddings, []float32{float32(i), 0.5, -1})
+	}
+	return res, nil
+}
+
+// newTestClient serves reg behind an httptest server that requires the
+// API key "secret" and returns an SDK OpenAI client for it.
+func newTestClient(t *testing.T, reg registry.Registry, apiKey string) *openai.Client {
+	t.Helper()
+	srv := &Server{
+		Registry: reg,
+		Authenticate: func(r *http.Request) error {
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				return errors.New("invalid API key")
+			}
+			return nil
+		},
+	}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	client, err := openai.NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: apiKey, HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	return client
+}
+
+func TestServer_ChatCompletion(t *testing.T) {
+	model := &fakeModel{res: &provider.LanguageModelResponse{
+		Text:       "let me search",
+		StopReason: "tool_use",
+		ToolCalls:  []provider.ToolCall{{ID: "call_1", Name: "search", RawArguments: []byte(`{"q":"x"}`)}},
+		Usage:      &provider.Usage{PromptTokens: 20, CompletionTokens: 4, TotalTokens: 24},
+	}}
+	reg := registry.NewInMemoryRegistry()
+	reg.RegisterLanguageModel("assistant", model)
+	client := newTestClient(t, reg, "secret")
+
+	temp := 0.2
+	res, err := client.ChatModel("assistant").Generate(context.Background(), &provider.LanguageModelRequest{
+		Messages: []provider.Message{
+			{Role: "system", Content: "be brief"},
+			{Role: "user", Parts: []provider.ContentPart{
+				{Type: provider.ContentPartText, Text: "what is this?"},
+				{Type: provider.ContentPartImage, ImageURL: "https://example.com/cat.png"},
+			}},
+			{Role: "assistant", ToolCalls: []provider.ToolCall{{ID: "call_0", Name: "search", RawArguments: []byte(`{"q":"cat"}`)}}},
+			{Role: "tool", Content: "a cat", ToolCallID: "call_0"},
+		},
+		Temperature: &temp,
+		Stop:        []string{"END"},
+		JSONSchema:  []byte(`{"type":"object"}`),
+		Tools:       []provider.ToolDefinition{{Name: "search", Description: "search the web", Parameters: []byte(`{"type":"object"}`)}},
+		ToolChoice:  provider.ToolChoiceTool("search"),
+		// Disabled, so the false value must survive the round trip.
+		ParallelToolCalls: new(bool),
+	})
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+
+	got := model.requests[0]
+	if len(got.Messages) != 4 || got.Messages[0].Content != "be brief" || len(got.Messages[1].Parts) != 2 || got.Messages[1].Parts[1].ImageURL != "https://example.com/cat.png" || got.Messages[3].ToolCallID != "call_0" {
+		t.Fatalf("unexpected messages %+v", got.Messages)
+	}
+	if calls := got.Messages[2].ToolCalls; len(calls) != 1 || calls[0].ID != "call_0" || calls[0].Name != "search" || string(calls[0].RawArguments) != `{"q":"cat"}` {
+		t.Fatalf("assistant tool calls not translated: %+v", got.Messages[2])
+	}
+	if got.Temperature == nil || *got.Temperature != temp || len(got.Stop) != 1 || string(got.JSONSchema) != `{"type":"object"}` {
+		t.Fatalf("settings not translated: %+v", got)
+	}
+	if len(got.Tools) != 1 || got.Tools[0].Name != "search" || string(got.Tools[0].Parameters) != `{"type":"object"}` || got.ToolChoice != provider.ToolChoiceTool("search") || got.ParallelToolCalls == nil || *got.ParallelToolCalls {
+		t.Fatalf("tools not translated: %+v", got.Tools)
+	}
+
+	if res.Text != "let me search" || res.StopReason != "tool_calls" || res.Usage == nil || *res.Usage != *model.res.Usage {
+		t.Fatalf("unexpected response %+v", res)
+	}
+	if len(res.ToolCalls) != 1 || res.ToolCalls[0].ID != "call_1" || res.ToolCalls[0].Name != "search" {
+		t.Fatalf("unexpected tool calls %+v", res.ToolCalls)
+	}
+	var args string
+	if err := json.Unmarshal(res.ToolCalls[0].RawArguments, &args); err != nil || args != `{"q":"x"}` {
+		t.Fatalf("expected arguments as a JSON string, got %s", res.ToolCalls[0].RawArguments)
+	}
+}
+
+func TestServer_StreamingChatCompletion(t *testing.T) {
+	model := &fakeModel{deltas: []provider.LanguageModelDelta{
+		{Text: "Hel"},
+		{Reasoning: "thinking"},
+		{Text: "lo"},
+		{ToolCalls: []provider.ToolCall{{ID: "call_1", Name: "search", RawArguments: []byte(`{"q":"x"}`)}}, Done: true},
+	}}
+	reg := registry.NewInMemoryRegistry()
+	reg.RegisterLanguageModel("assistant", model)
+	client := newTestClient(t, reg, "secret")
+
+	ctx := context.Background()
+	stream, err := client.ChatModel("assistant").Stream(ctx, &provider.LanguageModelRequest{
+		Messages: []provider.Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("Stream error: %v", err)
+	}
+	defer stream.Close()
+
+	var text, reasoning string
+	var calls []provider.ToolCall
+	for {
+		delta, err := stream.Next(ctx)
+		if err != nil {
+			t.Fatalf("Next error: %v", err)
+		}
+		text += delta.Text
+		reasoning += delta.Reasoning
+		calls = append(calls, delta.ToolCalls...)
+		if delta.Done {
+			break
+		}
+	}
+	if text != "Hello" || reasoning != "thinking" {
+		t.Fatalf("unexpected stream text %q reasoning %q", text, reasoning)
+	}
+	if len(calls) != 1 || calls[0].ID != "call_1" || calls[0].Name != "search" {
+		t.Fatalf("unexpected streamed tool calls %+v", calls)
+	}
+}
+
+func TestServer_StreamWireFormat(t *testing.T) {
+	// Some backends return calls without IDs; each is still a
+	// complete call of its own.
+	model := &fakeModel{deltas: []provider.LanguageModelDelta{
+		{ToolCalls: []provider.ToolCall{
+			{ID: "call_1", Name: "search", RawArguments: []byte(`{"q":"x"}`)},
+			{Name: "search", RawArguments: []byte(`{"q":"y"}`)},
+			{Name: "fetch", RawArguments: []byte(`{}`)},
+		}},
+	}}
+	reg := registry.NewInMemoryRegistry()
+	reg.RegisterLanguageModel("assistant", model)
+	ts := httptest.NewServer(NewServer(reg))
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/v1/chat/completions", "application/json", strings.NewReader(`{"model":"assistant","stream":true,"messages":[{"role":"user","content":"hi"}]}`))
+	if err != nil {
+		t.Fatalf("POST error: %v", err)
+	}
+	defer resp.Body.Close()
+	var chunks []chatChunk
+	var done bool
+	for _, line := range strings.Split(readAll(t, resp), "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			done = true
+			continue
+		}
+		var c chatChunk
+		if err := json.Unmarshal([]byte(data), &c); err != nil {
+			t.Fatalf("decode chunk %q: %v", data, err)
+		}
+		chunks = append(chunks, c)
+	}
+	if !done || len(chunks) != 3 {
+		t.Fatalf("expected a role chunk, a tool call chunk, a finish chunk, and [DONE]; got %d chunks, done=%v", len(chunks), done)
+	}
+	var indexes []int
+	for _, tc := range chunks[1].Choices[0].Delta.ToolCalls {
+		indexes = append(indexes, *tc.Index)
+	}
+	if !reflect.DeepEqual(indexes, []int{0, 1, 2}) {
+		t.Fatalf("unexpected tool call indexes %v", indexes)
+	}
+	if f := chunks[2].Choices[0].FinishReason; f == nil || *f != "tool_calls" {
+		t.Fatalf("unexpected finish reason %v", f)
+	}
+}
+
+func TestServer_StreamFinishReason(t *testing.T) {
+	model := &fakeModel{deltas: []provider.LanguageModelDelta{
+		{Text: "cut"},
+		{Done: true, StopReason: "max_tokens", FinishReason: provider.FinishReasonLength},
+	}}
+	reg := registry.NewInMemoryRegistry()
+	reg.RegisterLanguageModel("assistant", model)
+	ts := httptest.NewServer(NewServer(reg))
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/v1/chat/completions", "application/json", strings.NewReader(`{"model":"assistant","stream":true,"messages":[{"role":"user","content":"hi"}]}`))
+	if err != nil {
+		t.Fatalf("POST error: %v", err)
+	}
+	defer resp.Body.Close()
+	if body := readAll(t, resp); !strings.Contains(body, `"finish_reason":"length"`) {
+		t.Fatalf("expected finish_reason length, got %s", body)
+	}
+}
+
+func TestServer_Embeddings(t *testing.T) {
+	reg := registry.NewInMemoryRegistry()
+	reg.RegisterEmbeddingModel("embedder", fakeEmbeddingModel{})
+	client := newTestClient(t, reg, "secret")
+
+	res, err := client.EmbeddingModel("embedder").Generate(context.Background(), &provider.EmbeddingRequest{Input: []string{"a", "b"}})
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	if len(res.Embeddings) != 2 || res.Embeddings[1][0] != 1 || res.Embeddings[1][2] != -1 {
+		t.Fatalf("unexpected embeddings %v", res.Embeddings)
+	}
+
+	ts := httptest.NewServer(NewServer(reg))
+	defer ts.Close()
+	resp, err := http.Post(ts.URL+"/v1/embeddings", "application/json", strings.NewReader(`{"model":"embedder","input":"a","encoding_format":"base64"}`))
+	if err != nil {
+		t.Fatalf("POST error: %v", err)
+	}
+	defer resp.Body.Close()
+	// 0, 0.5, -1 as little-endian float32.
+	if body := readAll(t, resp); !strings.Contains(body, `"embedding":"AAAAAAAAAD8AAIC/"`) {
+		t.Fatalf("unexpected base64 response %s", body)
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	model := &fakeModel{err: &provider.APIError{Provider: "anthropic", StatusCode: 429, Type: "rate_limit_error", Message: "slow down"}}
+	reg := registry.NewInMemoryRegistry()
+	reg.RegisterLanguageModel("limited", model)
+	req := &provider.LanguageModelRequest{Messages: []provider.Message{{Role: "user", Content: "hi"}}}
+
+	cases := []struct {
+		name, apiKey, model string
+		status              int
+		typ, code           string
+	}{
+		{"unauthorized", "wrong", "limited", http.StatusUnauthorized, "invalid_request_error", "invalid_api_key"},
+		{"unknown model", "secret", "missing", http.StatusNotFound, "invalid_request_error", "model_not_found"},
+		{"upstream error", "secret", "limited", http.StatusTooManyRequests, "rate_limit_error", ""},
+	}
+	for _, tc := range cases {
+		client := newTestClient(t, reg, tc.apiKey)
+		_, err := client.ChatModel(tc.model).Generate(context.Background(), req)
+		var apiErr *provider.APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != tc.status || apiErr.Type != tc.typ || apiErr.Code != tc.code {
+			t.Fatalf("%s: unexpected error %#v", tc.name, err)
+		}
+	}
+}
+
+func readAll(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	return string(data)
+}
@@ -0,0 +1,159 @@
+// Package openaiserver exposes registry models behind an HTTP API
+// compatible with the OpenAI chat completions and embeddings endpoints,
+// so that existing OpenAI clients and tools can use models composed
+// with this SDK (fallbacks, middleware, local providers).
+//
+// The model field of each request is resolved by name in the server's
+// Registry:
+//
+//	reg := registry.NewInMemoryRegistry()
+//	reg.RegisterLanguageModel("assistant", model)
+//	http.Handle("/v1/", openaiserver.NewServer(reg))
+package openaiserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ncecere/ai-sdk/provider"
+	"github.com/ncecere/ai-sdk/registry"
+)
+
+// maxRequestBytes limits the size of request bodies.
+const maxRequestBytes = 32 << 20
+
+// Server is an http.Handler serving POST requests to
+// <Prefix>chat/completions (streaming and non-streaming) and
+// <Prefix>embeddings in the OpenAI wire format.
+//
+// Errors are reported in the OpenAI error envelope
+// {"error":{"message":...,"type":...,"code":...}}: 401 when
+// Authenticate rejects the request, 404 for unknown models, 400 for
+// malformed requests, 429 for a *provider.BudgetExceededError, and the
+// upstream status for provider API errors.
+type Server struct {
+	// Registry resolves the model field of requests.
+	Registry registry.Registry
+	// Prefix is the path the server is mounted at. Defaults to "/v1/".
+	Prefix string
+	// Authenticate, if set, is called before each request is served.
+	// A non-nil error rejects the request with 401 Unauthorized and the
+	// error's message.
+	Authenticate func(r *http.Request) error
+}
+
+// NewServer returns a Server for reg mounted at "/v1/" that accepts
+// every request.
+func NewServer(reg registry.Registry) *Server {
+	return &Server{Registry: reg}
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	prefix := s.Prefix
+	if prefix == "" {
+		prefix = "/v1/"
+	}
+	endpoint, ok := strings.CutPrefix(r.URL.Path, prefix)
+	if !ok || (endpoint != "chat/completions" && endpoint != "embeddings") {
+		writeError(w, http.StatusNotFound, "invalid_request_error", "unknown_url", "unknown endpoint "+r.URL.Path)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method_not_allowed", "only POST is supported")
+		return
+	}
+	if s.Authenticate != nil {
+		if err := s.Authenticate(r); err != nil {
+			writeError(w, http.StatusUnauthorized, "invalid_request_error", "invalid_api_key", err.Error())
+			return
+		}
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxRequestBytes)
+	switch endpoint {
+	case "chat/completions":
+		var req chatRequest
+		if err := json.NewDecoder(body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request_error", "", "invalid request body: "+err.Error())
+			return
+		}
+		s.serveChat(w, r, &req)
+	case "embeddings":
+		var req embeddingRequest
+		if err := json.NewDecoder(body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request_error", "", "invalid request body: "+err.Error())
+			return
+		}
+		s.serveEmbeddings(w, r, &req)
+	}
+}
+
+// errorBody is the OpenAI error envelope.
+type errorBody struct {
+	Error errorDetail `json:"error"`
+}
+
+type errorDetail struct {
+	Message string  `json:"message"`
+	Type    string  `json:"type"`
+	Param   *string `json:"param"`
+	Code    *string `json:"code"`
+}
+
+func writeError(w http.ResponseWriter, status int, typ, code, message string) {
+	detail := errorDetail{Message: message, Type: typ}
+	if code != "" {
+		detail.Code = &code
+	}
+	writeJSON(w, status, errorBody{Error: detail})
+}
+
+// writeModelError reports an error returned while resolving or calling
+// a model, keeping the status and details of provider API errors.
+func writeModelError(w http.ResponseWriter, err error) {
+	var noModel *registry.NoSuchModelError
+	if errors.As(err, &noModel) {
+		writeError(w, http.StatusNotFound, "invalid_request_error", "model_not_found", err.Error())
+		return
+	}
+	var apiErr *provider.APIError
+	if errors.As(err, &apiErr) {
+		detail := errorDetail{Message: apiErr.Message, Type: apiErr.Type}
+		if detail.Message == "" {
+			detail.Message = err.Error()
+		}
+		if detail.Type == "" {
+			detail.Type = "api_error"
+		}
+		if apiErr.Param != "" {
+			detail.Param = &apiErr.Param
+		}
+		if apiErr.Code != "" {
+			detail.Code = &apiErr.Code
+		}
+		writeJSON(w, apiErr.StatusCode, errorBody{Error: detail})
+		return
+	}
+	var budget *provider.BudgetExceededError
+	if errors.As(err, &budget) {
+		writeError(w, http.StatusTooManyRequests, "insufficient_quota", "insufficient_quota", err.Error())
+		return
+	}
+	var noConversation *provider.NoConversationMessageError
+	var orphanTool *provider.OrphanToolMessageError
+	if errors.As(err, &noConversation) || errors.As(err, &orphanTool) {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", err.Error())
+		return
+	}
+	writeError(w, http.StatusInternalServerError, "server_error", "", err.Error())
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
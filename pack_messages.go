@@ -0,0 +1,118 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// MetadataPackedMessages is the Message.Metadata key PackMessages sets
+// on the consolidated message to the number of messages it replaces.
+const MetadataPackedMessages = "packed_messages"
+
+// PackMessages fits messages into maxMessages by consolidating the
+// oldest turns into a single user message. Leading system messages and
+// the most recent messages are kept verbatim; everything between them
+// is rendered as text, one line per message and tool call, under an
+// "Earlier conversation" heading. Messages that already fit, and a
+// maxMessages <= 0, are returned unchanged.
+//
+// An assistant message with tool calls and the tool messages answering
+// it are never split: the verbatim tail never starts with a tool
+// message, so a tool call is either kept with its results or
+// consolidated together with them.
+//
+// Errors:
+//   - InvalidArgumentError if maxMessages leaves no room for the
+//     consolidated message and at least one recent message, or if the
+//     recent messages that fit are all tool results whose call would be
+//     separated from them.
+func PackMessages(messages []Message, maxMessages int) ([]Message, error) {
+	if maxMessages <= 0 || len(messages) <= maxMessages {
+		return messages, nil
+	}
+	head := 0
+	for head < len(messages) && messages[head].Role == RoleSystem {
+		head++
+	}
+	keep := maxMessages - head - 1
+	if keep < 1 {
+		return nil, &InvalidArgumentError{Parameter: "maxMessages", Value: maxMessages, Message: fmt.Sprintf("must leave room for a consolidated message and a recent message after %d system messages", head)}
+	}
+	start := len(messages) - keep
+	for start < len(messages) && messages[start].Role == RoleTool {
+		start++
+	}
+	if start == len(messages) {
+		return nil, &InvalidArgumentError{Parameter: "maxMessages", Value: maxMessages, Message: "the recent messages that fit are tool results separated from their tool call"}
+	}
+
+	packed := make([]Message, 0, head+1+len(messages)-start)
+	packed = append(packed, messages[:head]...)
+	packed = append(packed, consolidatedMessage(messages[head:start]))
+	return append(packed, messages[start:]...), nil
+}
+
+// consolidatedMessage renders messages as a single user message.
+func consolidatedMessage(messages []Message) Message {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Earlier conversation (%d messages):\n", len(messages))
+	for _, m := range messages {
+		switch {
+		case m.Role == RoleTool:
+			fmt.Fprintf(&b, "\n%s result (%s): %s", m.Role, m.ToolCallID, messageText(m))
+		case messageText(m) != "":
+			fmt.Fprintf(&b, "\n%s: %s", m.Role, messageText(m))
+		}
+		for _, tc := range m.ToolCalls {
+			fmt.Fprintf(&b, "\n%s called %s (%s) with %s", m.Role, tc.Name, tc.ID, tc.RawArguments)
+		}
+	}
+	return Message{
+		Role:     RoleUser,
+		Content:  b.String(),
+		Metadata: map[string]any{MetadataPackedMessages: len(messages)},
+	}
+}
+
+// messageText returns the text of m, with "[image]" standing in for
+// image parts of multi-part messages.
+func messageText(m Message) string {
+	if len(m.Parts) == 0 {
+		return m.Content
+	}
+	parts := make([]string, 0, len(m.Parts))
+	for _, p := range m.Parts {
+		switch p.Type {
+		case ContentPartText:
+			parts = append(parts, p.Text)
+		case ContentPartImage:
+			parts = append(parts, "[image]")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// fitMessageLimit packs req.Messages with PackMessages when req.Model
+// reports a message cap (provider.MessageLimiter). req.System counts
+// toward the cap.
+func (req GenerateTextRequest) fitMessageLimit() (GenerateTextRequest, error) {
+	limiter, ok := req.Model.(provider.MessageLimiter)
+	if !ok || limiter.MaxMessages() <= 0 {
+		return req, nil
+	}
+	limit := limiter.MaxMessages()
+	if req.System != "" {
+		limit--
+		if limit == 0 {
+			return req, &InvalidArgumentError{Parameter: "Model", Value: limiter.MaxMessages(), Message: "message cap leaves no room after the system prompt"}
+		}
+	}
+	messages, err := PackMessages(req.Messages, limit)
+	if err != nil {
+		return req, err
+	}
+	req.Messages = messages
+	return req, nil
+}
@@ -0,0 +1,140 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// toolHistory is a conversation with two tool rounds:
+//
+//	0 system, 1 user, 2 assistant calls c1+c2, 3 tool c1, 4 tool c2,
+//	5 assistant, 6 user, 7 assistant calls c3, 8 tool c3, 9 assistant
+func toolHistory() []Message {
+	return []Message{
+		SystemMessage("be brief"),
+		UserMessage("weather in Paris and Rome?"),
+		AssistantToolCallMessage("", []ToolCall{
+			{ID: "c1", Name: "weather", RawArguments: []byte(`{"city":"Paris"}`)},
+			{ID: "c2", Name: "weather", RawArguments: []byte(`{"city":"Rome"}`)},
+		}),
+		{Role: RoleTool, ToolCallID: "c1", Content: "21C"},
+		{Role: RoleTool, ToolCallID: "c2", Content: "25C"},
+		AssistantMessage("Paris 21C, Rome 25C."),
+		UserMessage("and Oslo?"),
+		AssistantToolCallMessage("Checking.", []ToolCall{{ID: "c3", Name: "weather", RawArguments: []byte(`{"city":"Oslo"}`)}}),
+		{Role: RoleTool, ToolCallID: "c3", Content: "9C"},
+		AssistantMessage("Oslo 9C."),
+	}
+}
+
+func TestPackMessages_FitsUnchanged(t *testing.T) {
+	history := toolHistory()
+	for _, max := range []int{0, len(history)} {
+		got, err := PackMessages(history, max)
+		if err != nil || len(got) != len(history) {
+			t.Fatalf("max %d: got %d messages, err %v", max, len(got), err)
+		}
+	}
+}
+
+func TestPackMessages_KeepsToolPairs(t *testing.T) {
+	cases := []struct {
+		max       int
+		tailStart int // index in toolHistory of the first verbatim message
+	}{
+		{max: 6, tailStart: 6},
+		// The tail would start at the c3 result: c3's call is consolidated
+		// with it instead of being separated.
+		{max: 4, tailStart: 9},
+		// The tail would start at the c2 result, the second of a pair.
+		{max: 8, tailStart: 5},
+	}
+	for _, tc := range cases {
+		history := toolHistory()
+		got, err := PackMessages(history, tc.max)
+		if err != nil {
+			t.Fatalf("max %d: PackMessages error: %v", tc.max, err)
+		}
+		if len(got) > tc.max {
+			t.Fatalf("max %d: got %d messages", tc.max, len(got))
+		}
+		if got[0].Content != "be brief" || got[1].Role != RoleUser || got[1].Metadata[MetadataPackedMessages] != tc.tailStart-1 {
+			t.Fatalf("max %d: unexpected head %+v", tc.max, got[:2])
+		}
+		tail := got[2:]
+		if len(tail) != len(history)-tc.tailStart || tail[0].Content != history[tc.tailStart].Content {
+			t.Fatalf("max %d: tail %+v, want from message %d", tc.max, tail, tc.tailStart)
+		}
+		for _, m := range tail {
+			if m.Role == RoleTool && !strings.HasPrefix(m.ToolCallID, "c") {
+				t.Fatalf("max %d: unexpected tool message %+v", tc.max, m)
+			}
+		}
+	}
+
+	got, err := PackMessages(toolHistory(), 4)
+	if err != nil {
+		t.Fatalf("PackMessages error: %v", err)
+	}
+	packed := got[1].Content
+	for _, want := range []string{
+		"Earlier conversation (8 messages):",
+		"user: weather in Paris and Rome?",
+		`assistant called weather (c2) with {"city":"Rome"}`,
+		"tool result (c1): 21C",
+		"assistant: Checking.",
+		"tool result (c3): 9C",
+	} {
+		if !strings.Contains(packed, want) {
+			t.Fatalf("consolidated message misses %q:\n%s", want, packed)
+		}
+	}
+}
+
+func TestPackMessages_Errors(t *testing.T) {
+	var argErr *InvalidArgumentError
+	if _, err := PackMessages(toolHistory(), 2); !errors.As(err, &argErr) {
+		t.Fatalf("expected InvalidArgumentError for a cap without room, got %v", err)
+	}
+
+	// The only message that fits is a tool result.
+	history := toolHistory()[:9]
+	if _, err := PackMessages(history, 3); !errors.As(err, &argErr) {
+		t.Fatalf("expected InvalidArgumentError for a split pair, got %v", err)
+	}
+}
+
+// limitedModel is a scriptedModel with a message cap.
+type limitedModel struct {
+	scriptedModel
+	max int
+}
+
+func (m *limitedModel) MaxMessages() int { return m.max }
+
+var _ provider.MessageLimiter = (*limitedModel)(nil)
+
+func TestGenerateText_PacksForMessageLimit(t *testing.T) {
+	model := &limitedModel{max: 5}
+	model.responses = []*provider.LanguageModelResponse{{Text: "ok"}}
+
+	history := toolHistory()[1:]
+	if _, err := GenerateText(context.Background(), GenerateTextRequest{
+		Model:    model,
+		System:   "be brief",
+		Messages: history,
+	}); err != nil {
+		t.Fatalf("GenerateText error: %v", err)
+	}
+	sent := model.requests[0].Messages
+	if len(sent) != 5 || sent[0].Role != RoleSystem || sent[1].Metadata[MetadataPackedMessages] != 6 {
+		t.Fatalf("unexpected packed request %+v", sent)
+	}
+	if len(history) != 9 {
+		t.Fatalf("caller history modified: %d messages", len(history))
+	}
+}
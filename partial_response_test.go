@@ -0,0 +1,72 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// cutServer streams body and then drops the connection mid-response.
+func cutServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, body)
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestWriteTextStreamAsSSE_PartialResponseOnCut(t *testing.T) {
+	for _, p := range streamProviders {
+		t.Run(p.name, func(t *testing.T) {
+			srv := cutServer(t, p.chunk("hel")+p.chunk("lo"))
+			model, err := p.newModel(provider.ClientOptions{BaseURL: srv.URL, APIKey: "test", HTTPClient: srv.Client()})
+			if err != nil {
+				t.Fatalf("NewClient error: %v", err)
+			}
+			stream, err := StreamText(context.Background(), GenerateTextRequest{Model: model, Messages: []Message{UserMessage("hi")}})
+			if err != nil {
+				t.Fatalf("StreamText error: %v", err)
+			}
+
+			rec := httptest.NewRecorder()
+			err = WriteTextStreamAsSSE(context.Background(), rec, stream)
+			var partial *PartialResponseError
+			if !errors.As(err, &partial) || partial.Partial.Text != "hello" {
+				t.Fatalf("expected PartialResponseError with the streamed text, got %v", err)
+			}
+			if partial.Err == nil {
+				t.Fatal("expected the transport error to be wrapped")
+			}
+			if !strings.Contains(rec.Body.String(), "data: lo\n\n") {
+				t.Fatalf("expected the partial text to be sent, got %q", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestWriteTextStreamAsSSE_NoPartialBeforeContent(t *testing.T) {
+	srv := cutServer(t, "")
+	model, err := streamProviders[0].newModel(provider.ClientOptions{BaseURL: srv.URL, APIKey: "test", HTTPClient: srv.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	stream, err := StreamText(context.Background(), GenerateTextRequest{Model: model, Messages: []Message{UserMessage("hi")}})
+	if err != nil {
+		t.Fatalf("StreamText error: %v", err)
+	}
+	err = WriteTextStreamAsSSE(context.Background(), httptest.NewRecorder(), stream)
+	var partial *PartialResponseError
+	if err == nil || errors.As(err, &partial) {
+		t.Fatalf("expected a plain error without content, got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// DefaultProgressInterval is the interval GenerateTextWithProgress uses
+// when none is given.
+const DefaultProgressInterval = time.Second
+
+// ProgressOptions configures GenerateTextWithProgress.
+type ProgressOptions struct {
+	// Interval is the time between OnProgress calls. Values <= 0 use
+	// DefaultProgressInterval.
+	Interval time.Duration
+	// OnProgress, if set, is called every Interval with the time
+	// elapsed and the number of characters (runes) of text and
+	// reasoning received so far.
+	OnProgress func(elapsed time.Duration, chars int)
+}
+
+// GenerateTextWithProgress returns a single final response like
+// GenerateText, with the same fields, but streams from the provider
+// internally and calls opts.OnProgress as the response arrives. It
+// suits environments that break SSE but allow long requests: the
+// callback can write keep-alive bytes or update a job record.
+//
+// OnProgress runs on the calling goroutine, never concurrently with
+// itself, and is not called after GenerateTextWithProgress returns.
+// Models that cannot stream are called with GenerateText; progress then
+// reports 0 characters until the response arrives.
+//
+// Errors:
+//   - Any error returned by StreamText or GenerateText.
+//   - *PartialResponseError if the stream fails after content was
+//     received, holding the text and reasoning received so far.
+//   - An error describing the panic if OnProgress panics. The call is
+//     cancelled and the panic does not propagate.
+func GenerateTextWithProgress(ctx context.Context, req GenerateTextRequest, opts ProgressOptions) (GenerateTextResponse, error) {
+	interval, onProgress := opts.Interval, opts.OnProgress
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+	ctx, traceID := provider.EnsureTraceID(ctx)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		res GenerateTextResponse
+		err error
+	}
+	var chars atomic.Int64
+	done := make(chan result, 1)
+	start := time.Now()
+	go func() {
+		res, err := generateCounting(ctx, req, &chars)
+		done <- result{res, err}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case r := <-done:
+			if r.err != nil {
+				return GenerateTextResponse{}, r.err
+			}
+			r.res.TraceID = traceID
+			return r.res, nil
+		case <-ticker.C:
+			if onProgress == nil {
+				continue
+			}
+			if err := callProgress(onProgress, time.Since(start), int(chars.Load())); err != nil {
+				cancel()
+				<-done
+				return GenerateTextResponse{}, err
+			}
+		}
+	}
+}
+
+// callProgress calls onProgress, turning a panic into an error.
+func callProgress(onProgress func(time.Duration, int), elapsed time.Duration, chars int) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("ai: progress callback panicked: %v", r)
+		}
+	}()
+	onProgress(elapsed, chars)
+	return nil
+}
+
+// generateCounting streams req into a single response, adding the runes
+// of each delta to chars. Models that cannot stream fall back to
+// GenerateText.
+func generateCounting(ctx context.Context, req GenerateTextRequest, chars *atomic.Int64) (GenerateTextResponse, error) {
+	stream, err := StreamText(ctx, req)
+	if errors.Is(err, ErrStreamingUnsupported) {
+		res, err := GenerateText(ctx, req)
+		if err == nil {
+			chars.Store(int64(utf8.RuneCountInString(res.Text) + utf8.RuneCountInString(res.Reasoning)))
+		}
+		return res, err
+	}
+	if err != nil {
+		return GenerateTextResponse{}, err
+	}
+	return TeeStream(ctx, stream, func(delta *TextDelta) error {
+		chars.Add(int64(utf8.RuneCountInString(delta.Text) + utf8.RuneCountInString(delta.Reasoning)))
+		return nil
+	})
+}
@@ -0,0 +1,115 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ncecere/ai-sdk/openai"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+func TestGenerateTextWithProgress(t *testing.T) {
+	stream := &scriptStream{texts: []string{"Hello", ", ", "wörld"}, delay: 20 * time.Millisecond}
+	var calls []int
+	var lastElapsed time.Duration
+	res, err := GenerateTextWithProgress(context.Background(), GenerateTextRequest{
+		Model:    &streamOnlyModel{stream: stream},
+		Messages: []Message{UserMessage("hi")},
+	}, ProgressOptions{Interval: 5 * time.Millisecond, OnProgress: func(elapsed time.Duration, chars int) {
+		if elapsed < lastElapsed {
+			t.Errorf("elapsed went back from %v to %v", lastElapsed, elapsed)
+		}
+		lastElapsed = elapsed
+		calls = append(calls, chars)
+	}})
+	if err != nil {
+		t.Fatalf("GenerateTextWithProgress error: %v", err)
+	}
+	if res.Text != "Hello, wörld" || res.TraceID == "" || res.FinishReason != "" {
+		t.Fatalf("unexpected response %+v", res)
+	}
+	if len(calls) < 3 {
+		t.Fatalf("expected several progress calls, got %v", calls)
+	}
+	for i := 1; i < len(calls); i++ {
+		if calls[i] < calls[i-1] || calls[i] > 12 {
+			t.Fatalf("progress counts not monotonic runes: %v", calls)
+		}
+	}
+	if !stream.closed.Load() {
+		t.Fatal("stream not closed")
+	}
+}
+
+func TestGenerateTextWithProgress_KeepsResponseFields(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("X-Request-Id", "req-1")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"cut\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"length\"}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer ts.Close()
+	client, err := openai.NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	res, err := GenerateTextWithProgress(context.Background(), GenerateTextRequest{
+		Model:    client.ChatModel("gpt-test"),
+		Messages: []Message{UserMessage("hi")},
+	}, ProgressOptions{})
+	if err != nil {
+		t.Fatalf("GenerateTextWithProgress error: %v", err)
+	}
+	if res.Text != "cut" || res.StopReason != "length" || res.FinishReason != FinishReasonLength {
+		t.Fatalf("unexpected response %+v", res)
+	}
+	if res.Metadata == nil || res.Metadata.RequestID != "req-1" {
+		t.Fatalf("Metadata = %+v, want request ID req-1", res.Metadata)
+	}
+}
+
+func TestGenerateTextWithProgress_CallbackPanic(t *testing.T) {
+	stream := &scriptStream{texts: []string{"x"}, forever: true, delay: time.Millisecond}
+	_, err := GenerateTextWithProgress(context.Background(), GenerateTextRequest{
+		Model:    &streamOnlyModel{stream: stream},
+		Messages: []Message{UserMessage("hi")},
+	}, ProgressOptions{Interval: time.Millisecond, OnProgress: func(time.Duration, int) { panic("boom") }})
+	if err == nil || !strings.Contains(err.Error(), "panicked: boom") {
+		t.Fatalf("expected panic error, got %v", err)
+	}
+	if !stream.closed.Load() {
+		t.Fatal("stream not closed after panic")
+	}
+}
+
+func TestGenerateTextWithProgress_Errors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	stream := &scriptStream{texts: []string{"x"}, forever: true, delay: time.Millisecond}
+	_, err := GenerateTextWithProgress(ctx, GenerateTextRequest{
+		Model:    &streamOnlyModel{stream: stream},
+		Messages: []Message{UserMessage("hi")},
+	}, ProgressOptions{Interval: time.Millisecond})
+	var partial *PartialResponseError
+	if !errors.As(err, &partial) || !errors.Is(err, context.DeadlineExceeded) || partial.Partial.Text == "" {
+		t.Fatalf("expected PartialResponseError after the deadline, got %v", err)
+	}
+
+	// Models that cannot stream fall back to GenerateText.
+	model := &scriptedModel{responses: []*provider.LanguageModelResponse{{Text: "done", StopReason: "stop"}}}
+	res, err := GenerateTextWithProgress(context.Background(), GenerateTextRequest{
+		Model:    model,
+		Messages: []Message{UserMessage("hi")},
+	}, ProgressOptions{Interval: time.Millisecond, OnProgress: func(time.Duration, int) {}})
+	if err != nil || res.Text != "done" || res.FinishReason != FinishReasonStop {
+		t.Fatalf("fallback = %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,116 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type attemptRecordKey struct{}
+
+// AttemptRecord collects the attempts made for one logical call by the
+// retrying components that share a context, such as stacked retry
+// middleware or fallbacks. Retrying components record each retry;
+// telemetry and logging read the record to report calls that succeeded
+// only after retries. A nil *AttemptRecord ignores records and reports
+// a single attempt.
+type AttemptRecord struct {
+	mu        sync.Mutex
+	errs      []error
+	wait      time.Duration
+	lastStart time.Time
+	last      time.Duration
+	ended     bool
+}
+
+// RetryStats summarizes the attempts of a call.
+type RetryStats struct {
+	// Attempts is the number of calls made to the underlying model,
+	// including the first.
+	Attempts int
+	// Errors holds the error of each failed attempt that was retried,
+	// in order. The error of a final failed attempt is the call's
+	// error and is not included.
+	Errors []error
+	// Wait is the total time spent waiting between attempts.
+	Wait time.Duration
+	// LastAttempt is the latency of the final attempt alone. It is zero
+	// until the final attempt completes.
+	LastAttempt time.Duration
+}
+
+// WithAttemptRecord returns a context carrying an attempt record and
+// the record. If ctx already carries one, ctx and that record are
+// returned unchanged, so every layer of a call shares the record of the
+// outermost layer. Create the record before the call to read it
+// afterwards.
+func WithAttemptRecord(ctx context.Context) (context.Context, *AttemptRecord) {
+	if r := AttemptRecordFromContext(ctx); r != nil {
+		return ctx, r
+	}
+	r := &AttemptRecord{}
+	return context.WithValue(ctx, attemptRecordKey{}, r), r
+}
+
+// AttemptRecordFromContext returns the attempt record carried by ctx,
+// or nil if there is none.
+func AttemptRecordFromContext(ctx context.Context) *AttemptRecord {
+	r, _ := ctx.Value(attemptRecordKey{}).(*AttemptRecord)
+	return r
+}
+
+// StartAttempt marks the start of an attempt. Stacked layers may all
+// call it; the innermost call, made last, marks the actual attempt.
+func (r *AttemptRecord) StartAttempt() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastStart = time.Now()
+	r.ended = false
+}
+
+// EndAttempt marks the end of the attempt last started. Only the first
+// call after StartAttempt counts, so outer layers ending later do not
+// overwrite the latency measured by the innermost one.
+func (r *AttemptRecord) EndAttempt() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ended || r.lastStart.IsZero() {
+		return
+	}
+	r.last = time.Since(r.lastStart)
+	r.ended = true
+}
+
+// RecordRetry records that the attempt that failed with err is retried
+// after waiting wait. Each retry must be recorded by exactly one layer:
+// the one that decides to retry.
+func (r *AttemptRecord) RecordRetry(err error, wait time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errs = append(r.errs, err)
+	r.wait += wait
+}
+
+// Stats returns a snapshot of the record.
+func (r *AttemptRecord) Stats() RetryStats {
+	if r == nil {
+		return RetryStats{Attempts: 1}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return RetryStats{
+		Attempts:    len(r.errs) + 1,
+		Errors:      append([]error(nil), r.errs...),
+		Wait:        r.wait,
+		LastAttempt: r.last,
+	}
+}
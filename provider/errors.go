@@ -0,0 +1,357 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrStreamingUnsupported is returned, possibly wrapped, by the Stream
+// method of LanguageModel implementations that cannot stream, such as
+// batch-only models or wrappers that replay stored responses, instead
+// of faking a stream. Callers that want streaming UX regardless can
+// fall back to Generate explicitly; see ai.StreamOrSimulate.
+var ErrStreamingUnsupported = errors.New("provider: streaming is not supported")
+
+// EmptyResponseError indicates that a provider answered with a 2xx HTTP
+// status but the response body did not contain any result (for example
+// an OpenAI-style response without a "choices" array).
+//
+// This is distinct from a legitimate empty response where the model
+// produced no text but the provider still reported a result and a
+// finish reason. Gateways such as LiteLLM occasionally return
+// malformed bodies like this under failure conditions.
+type EmptyResponseError struct {
+	// Provider is the name of the provider that returned the response.
+	Provider string
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Body is a truncated snippet of the raw response body.
+	Body string
+	// TraceID is the client-generated trace ID of the failed call.
+	TraceID string
+}
+
+func (e *EmptyResponseError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("%s: empty response (http status %d): %s%s", e.Provider, e.StatusCode, e.Body, traceSuffix(e.TraceID))
+}
+
+// APIError is returned for non-2xx provider responses and for error
+// events sent in-band on a stream that started successfully. Fields
+// parsed from the provider's error envelope are empty when the body is
+// not in a recognized format; Body always holds a truncated copy.
+type APIError struct {
+	// Provider is the name of the provider that returned the response.
+	Provider string
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Type is the error type, e.g. "invalid_request_error".
+	Type string
+	// Code is the provider's error code, e.g. "unknown_parameter".
+	Code string
+	// Param is the request parameter the error refers to, as a JSON path
+	// such as "reasoning_effort" or "messages.0.content". OpenAI reports
+	// it in the param field; for Anthropic it is taken from the
+	// "<path>: <message>" form of validation messages.
+	Param string
+	// Message is the provider's error message.
+	Message string
+	// Body is a truncated snippet of the raw response body, or of the
+	// error event for in-band stream errors.
+	Body string
+	// InStream reports that the error was sent as an event of a
+	// streaming response, such as Anthropic's overloaded_error.
+	// StatusCode is then zero, as the stream itself started with a 2xx
+	// status.
+	InStream bool
+	// RequestID is the provider's ID for the failed HTTP request, if
+	// the response carried one (see ResponseMetadata.RequestID).
+	RequestID string
+}
+
+func (e *APIError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	name := e.Provider
+	if name == "" {
+		name = "provider"
+	}
+	msg := fmt.Sprintf("%s: http status %d", name, e.StatusCode)
+	if e.InStream {
+		msg = name + ": stream error"
+	}
+	if e.Param != "" {
+		msg += fmt.Sprintf(" (param %q)", e.Param)
+	}
+	if e.Message != "" {
+		return msg + ": " + e.Message
+	}
+	return msg + ": " + e.Body
+}
+
+// IncompleteStreamError indicates that a streaming response ended
+// before the provider's completion marker ("data: [DONE]" or a finish
+// reason for OpenAI, message_stop for Anthropic), typically because a
+// gateway or the server cut the connection. Content received before
+// the cut was delivered; the stream then returns this error instead of
+// a Done delta, so callers can tell an interrupted response from a
+// complete one.
+type IncompleteStreamError struct {
+	// Provider is the name of the provider the stream came from.
+	Provider string
+	// Err is the read error that ended the stream, or
+	// io.ErrUnexpectedEOF when the body ended cleanly but early.
+	Err error
+}
+
+func (e *IncompleteStreamError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("%s: stream ended before completion: %v", e.Provider, e.Err)
+}
+
+func (e *IncompleteStreamError) Unwrap() error {
+	if e == nil {
+		return nil
+	}
+	return e.Err
+}
+
+// ResponseTooLargeError indicates that a response body exceeded
+// ClientOptions.MaxResponseBytes. The body is not read further.
+type ResponseTooLargeError struct {
+	// Provider is the name of the provider that sent the response.
+	Provider string
+	// Limit is the exceeded limit in bytes.
+	Limit int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("%s: response body exceeds %d bytes", e.Provider, e.Limit)
+}
+
+// StreamTruncatedError indicates that a language-model stream exceeded
+// ClientOptions.MaxStreamBytes or MaxStreamDeltas. The stream is closed;
+// Text and Reasoning hold what it delivered before the limit.
+type StreamTruncatedError struct {
+	// Provider is the name of the provider the stream came from.
+	Provider string
+	// Limit names the exceeded limit: "bytes" or "deltas".
+	Limit string
+	// Max is the value of the exceeded limit.
+	Max int64
+	// Text is the text delivered before the limit.
+	Text string
+	// Reasoning is the reasoning delivered before the limit.
+	Reasoning string
+}
+
+func (e *StreamTruncatedError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("%s: stream truncated after %d bytes of text: exceeds %d %s", e.Provider, len(e.Text), e.Max, e.Limit)
+}
+
+// InvalidMetadataError indicates that request metadata exceeds the
+// provider's limits, such as OpenAI's 16 pairs with keys of at most 64
+// and values of at most 512 characters. It is returned before the
+// request is sent.
+type InvalidMetadataError struct {
+	// Provider is the name of the provider whose limits were exceeded.
+	Provider string
+	// Key is the offending key, or empty for too many pairs.
+	Key string
+	// Message describes the exceeded limit.
+	Message string
+}
+
+func (e *InvalidMetadataError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	if e.Key == "" {
+		return fmt.Sprintf("%s: invalid metadata: %s", e.Provider, e.Message)
+	}
+	return fmt.Sprintf("%s: invalid metadata key %q: %s", e.Provider, e.Key, e.Message)
+}
+
+// RemoteImageError indicates that an image part referenced by URL could
+// not be downloaded and inlined for ClientOptions.FetchRemoteImages.
+type RemoteImageError struct {
+	// Provider is the name of the provider the request was for.
+	Provider string
+	// Message is the index of the message in the request.
+	Message int
+	// Part is the index of the image part within the message's Parts.
+	Part int
+	// URL is the image URL.
+	URL string
+	// Err is the underlying download or validation error.
+	Err error
+}
+
+func (e *RemoteImageError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("%s: fetching image for message %d part %d from %s: %v", e.Provider, e.Message, e.Part, redactQuery(e.URL), e.Err)
+}
+
+func (e *RemoteImageError) Unwrap() error {
+	if e == nil {
+		return nil
+	}
+	return e.Err
+}
+
+// redactQuery drops the query of rawURL, which for presigned links
+// carries the signature.
+func redactQuery(rawURL string) string {
+	if i := strings.IndexByte(rawURL, '?'); i >= 0 {
+		return rawURL[:i] + "?…"
+	}
+	return rawURL
+}
+
+// OrphanToolMessageError indicates that a request contains a tool
+// message without the tool-call context the provider requires. OpenAI
+// only accepts role "tool" messages that answer a preceding assistant
+// message with tool_calls, in a request that declares the tools.
+type OrphanToolMessageError struct {
+	// Provider is the name of the provider that rejected the request.
+	Provider string
+	// Index is the position of the orphaned message in the request.
+	Index int
+	// TraceID is the client-generated trace ID of the failed call.
+	TraceID string
+}
+
+func (e *OrphanToolMessageError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("%s: tool message at index %d has no matching tool call; declare the tools on the request so the preceding assistant tool_calls are valid, or use OrphanToolDowngrade to send it as a user message%s", e.Provider, e.Index, traceSuffix(e.TraceID))
+}
+
+// UnsupportedFunctionalityError indicates that a requested feature is
+// not supported by the current implementation.
+type UnsupportedFunctionalityError struct {
+	// Feature describes the unsupported feature, e.g. "image generation".
+	Feature string
+	// Message is an optional explanatory message.
+	Message string
+	// Dropped lists the request options a provider would have left out
+	// when ClientOptions.StrictOptions rejected the request.
+	Dropped []DroppedOption
+}
+
+func (e *UnsupportedFunctionalityError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	if e.Message != "" {
+		return "ai: unsupported functionality (" + e.Feature + "): " + e.Message
+	}
+	return "ai: unsupported functionality (" + e.Feature + ")"
+}
+
+// NoConversationMessageError indicates that a request contains no
+// user, assistant, or tool message. Providers reject such requests with
+// errors that rarely mention the cause, so they are caught up front.
+type NoConversationMessageError struct {
+	// Provider is the name of the provider the request was meant for.
+	Provider string
+	// TraceID is the client-generated trace ID of the failed call.
+	TraceID string
+}
+
+func (e *NoConversationMessageError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("%s: request has no user or assistant messages; add a user message or use SystemOnlyInsertUser for system-only prompts%s", e.Provider, traceSuffix(e.TraceID))
+}
+
+// ContentBlockedError indicates that the provider refused to produce a
+// response because the prompt or the candidate output was blocked by
+// its safety filters. Providers return it instead of an empty response
+// so blocked requests are distinguishable from empty answers.
+type ContentBlockedError struct {
+	// Provider is the name of the provider that blocked the content.
+	Provider string
+	// Reason is the provider's block reason, e.g. "SAFETY".
+	Reason string
+	// PromptBlocked reports whether the prompt itself was blocked, as
+	// opposed to the generated candidate.
+	PromptBlocked bool
+	// SafetyRatings contains the per-category ratings that led to the
+	// block, when reported.
+	SafetyRatings []SafetyRating
+	// TraceID is the client-generated trace ID of the failed call.
+	TraceID string
+}
+
+func (e *ContentBlockedError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	what := "response"
+	if e.PromptBlocked {
+		what = "prompt"
+	}
+	return fmt.Sprintf("%s: %s blocked (reason %s)%s", e.Provider, what, e.Reason, traceSuffix(e.TraceID))
+}
+
+// BudgetExceededError is returned by budget enforcement (see
+// middleware.Budget) when a call would exceed a per-user or per-key
+// limit. Used is the usage already recorded in the current window, not
+// including the rejected call.
+type BudgetExceededError struct {
+	// Key is the budget key the call was charged to, usually a user ID.
+	Key string
+	// Resource is the exceeded limit: "requests", "tokens", or "dollars".
+	Resource string
+	// Limit is the configured limit for Resource.
+	Limit float64
+	// Used is the amount of Resource used in the current window.
+	Used float64
+	// Requested is the estimated amount of Resource the call needed.
+	Requested float64
+	// ResetAt is when the current window ends and usage resets.
+	ResetAt time.Time
+}
+
+func (e *BudgetExceededError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("budget exceeded for %q: %s used %s of %s (call needs %s), resets at %s",
+		e.Key, e.Resource, formatAmount(e.Used), formatAmount(e.Limit), formatAmount(e.Requested), e.ResetAt.UTC().Format(time.RFC3339))
+}
+
+// formatAmount formats a budget amount with at most six decimals and
+// no trailing zeros.
+func formatAmount(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 6, 64)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// traceSuffix formats a trace ID for inclusion in error messages.
+func traceSuffix(id string) string {
+	if id == "" {
+		return ""
+	}
+	return " (trace_id=" + id + ")"
+}
@@ -0,0 +1,50 @@
+package provider
+
+// Provider is the minimal interface implemented by provider clients.
+// Clients that support additional model kinds also implement the
+// optional interfaces below, such as EmbeddingProvider.
+type Provider interface {
+	// ChatModel returns a LanguageModel for the given model ID.
+	ChatModel(model string) LanguageModel
+}
+
+// EmbeddingProvider is implemented by provider clients that expose
+// embedding models.
+type EmbeddingProvider interface {
+	EmbeddingModel(model string) EmbeddingModel
+}
+
+// CompletionProvider is implemented by provider clients that expose
+// completion-style models.
+type CompletionProvider interface {
+	CompletionModel(model string) CompletionModel
+}
+
+// ImageProvider is implemented by provider clients that expose image
+// generation models.
+type ImageProvider interface {
+	ImageModel(model string) ImageModel
+}
+
+// SpeechProvider is implemented by provider clients that expose
+// text-to-speech models.
+type SpeechProvider interface {
+	SpeechModel(model string) SpeechModel
+}
+
+// TranscriptionProvider is implemented by provider clients that expose
+// transcription models.
+type TranscriptionProvider interface {
+	TranscriptionModel(model string) TranscriptionModel
+}
+
+// Factory constructs provider clients by name. Provider packages,
+// including third-party ones, expose a Factory so that applications can
+// register it with registry.RegisterProviderFactory and resolve models
+// from "provider:model" references.
+type Factory interface {
+	// Name is the provider name used as the reference prefix, e.g. "openai".
+	Name() string
+	// NewClient creates a provider client from the given options.
+	NewClient(opts ClientOptions) (Provider, error)
+}
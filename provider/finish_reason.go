@@ -0,0 +1,47 @@
+package provider
+
+import "strings"
+
+// FinishReason is the provider-independent reason a model stopped
+// generating. LanguageModelResponse.StopReason keeps the provider's own
+// value alongside it.
+type FinishReason string
+
+const (
+	// FinishReasonStop means the model finished its answer or hit a
+	// stop sequence.
+	FinishReasonStop FinishReason = "stop"
+	// FinishReasonLength means the output reached the token limit.
+	FinishReasonLength FinishReason = "length"
+	// FinishReasonToolCalls means the model called tools. It is set if
+	// and only if the response has tool calls.
+	FinishReasonToolCalls FinishReason = "tool_calls"
+	// FinishReasonContentFilter means the provider's safety filters
+	// stopped or refused the output. The OpenAI and Anthropic providers
+	// return a *ContentBlockedError instead.
+	FinishReasonContentFilter FinishReason = "content_filter"
+	// FinishReasonOther is any other provider value, such as
+	// Anthropic's pause_turn; StopReason holds the raw value.
+	FinishReasonOther FinishReason = "other"
+)
+
+// ParseFinishReason maps a stop reason from any of the common provider
+// vocabularies (OpenAI, Anthropic, and Gemini) onto a FinishReason, for
+// language models that do not set LanguageModelResponse.FinishReason
+// themselves. It returns "" for an empty stop reason and
+// FinishReasonOther for an unknown one.
+func ParseFinishReason(stop string) FinishReason {
+	switch strings.ToLower(stop) {
+	case "":
+		return ""
+	case "stop", "end_turn", "stop_sequence":
+		return FinishReasonStop
+	case "length", "max_tokens":
+		return FinishReasonLength
+	case "tool_calls", "function_call", "tool_use":
+		return FinishReasonToolCalls
+	case "content_filter", "refusal", "safety":
+		return FinishReasonContentFilter
+	}
+	return FinishReasonOther
+}
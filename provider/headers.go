@@ -0,0 +1,32 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+)
+
+type headersKey struct{}
+
+// WithHeaders returns a context carrying extra HTTP headers for the
+// provider requests made with it, such as a per-tenant header required
+// by a gateway. They are added to ClientOptions.Headers, replacing
+// values of the same name. Headers a provider requires, such as
+// Authorization or anthropic-version, are never replaced. Headers from
+// an enclosing WithHeaders are kept unless h sets the same name.
+func WithHeaders(ctx context.Context, h http.Header) context.Context {
+	merged := HeadersFromContext(ctx).Clone()
+	if merged == nil {
+		merged = make(http.Header, len(h))
+	}
+	for k, vs := range h {
+		merged[http.CanonicalHeaderKey(k)] = append([]string(nil), vs...)
+	}
+	return context.WithValue(ctx, headersKey{}, merged)
+}
+
+// HeadersFromContext returns the headers carried by ctx, or nil if
+// there are none. The result must not be modified.
+func HeadersFromContext(ctx context.Context) http.Header {
+	h, _ := ctx.Value(headersKey{}).(http.Header)
+	return h
+}
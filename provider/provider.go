@@ -3,6 +3,8 @@ package provider
 import (
 	"context"
 	"net/http"
+	"strings"
+	"time"
 )
 
 // HTTPClient is the minimal interface required from an HTTP client.
@@ -22,12 +24,214 @@ type ClientOptions struct {
 	// HTTPClient is the underlying HTTP client. If nil, a default
 	// client should be used by the provider.
 	HTTPClient HTTPClient
+	// CACertPEM holds PEM-encoded CA certificates to trust in addition
+	// to the system pool, for gateways behind an internal CA. With
+	// ClientCertPEM and ClientKeyPEM (a client certificate and its key
+	// for mutual TLS), it makes the client build its HTTP client with
+	// providerutil.NewHTTPClient. They require HTTPClient to be nil;
+	// for other TLS settings, pass a client built by NewHTTPClient.
+	// Invalid PEM fails client construction.
+	CACertPEM     []byte
+	ClientCertPEM []byte
+	ClientKeyPEM  []byte
 	// Headers contains additional HTTP headers that providers should
 	// attach to every outbound request. Provider implementations
 	// decide how these interact with their own required headers.
 	Headers http.Header
+	// HeadersFromEnv opts in to default headers from the environment,
+	// for example proxy credentials every request must carry. Clients
+	// then read AI_SDK_DEFAULT_HEADERS and their provider's
+	// <PROVIDER>_EXTRA_HEADERS variable (such as OPENAI_EXTRA_HEADERS)
+	// as comma-separated name=value pairs; see
+	// providerutil.ParseHeaderList. Headers takes precedence over both,
+	// and the provider variable over AI_SDK_DEFAULT_HEADERS.
+	HeadersFromEnv bool
+	// Betas lists provider beta features to enable on every request.
+	// Anthropic sends them in the anthropic-beta header and OpenAI in the
+	// OpenAI-Beta header, comma-joined with any value already present in
+	// Headers.
+	Betas []string
+	// OrphanToolMessages controls how chat providers that require tool
+	// messages to follow a declared tool call handle tool messages in a
+	// request that declares no tools. The zero value downgrades them.
+	OrphanToolMessages OrphanToolPolicy
+	// SystemSeparator joins multiple system messages on providers that
+	// accept a single system prompt (such as Anthropic). Empty means
+	// DefaultSystemSeparator.
+	SystemSeparator string
+	// SystemOnly controls how requests without any non-system message
+	// are handled. The zero value rejects them.
+	SystemOnly SystemOnlyPolicy
+	// OnUnknownFields, if set, is called with the paths of response keys
+	// that the provider did not decode (for example
+	// "choices[].message.annotations"). Source identifies the endpoint,
+	// such as "openai.chat". Detection re-parses each response, so it is
+	// meant for development and staging; leave it nil in production.
+	OnUnknownFields func(source string, fields []string)
+	// OnDroppedOptions, if set, is called before each chat request with
+	// the request options the provider cannot honor and leaves out, such
+	// as FrequencyPenalty on Anthropic. Source identifies the endpoint,
+	// such as "anthropic.messages". Generate also lists them in
+	// ResponseMetadata.DroppedOptions.
+	OnDroppedOptions func(source string, dropped []DroppedOption)
+	// StrictOptions rejects chat requests with options the provider
+	// would drop with an *UnsupportedFunctionalityError listing them,
+	// before anything is sent.
+	StrictOptions bool
+	// UnsupportedOptions names LanguageModelRequest fields that an
+	// OpenAI-compatible backend does not accept, such as
+	// "ResponseFormat" for a backend without response_format. The
+	// OpenAI client leaves them out of chat requests and reports them
+	// as dropped; see openai.NewClient for the names it recognizes.
+	UnsupportedOptions []string
+	// Debug enables development checks that cost extra work per request.
+	// It turns on the lint pass over pass-through request options (see
+	// OnLintWarnings), logging warnings with the standard logger.
+	Debug bool
+	// OnLintWarnings, if set, is called with warnings about pass-through
+	// request options such as TranscriptionRequest.ExtraFields: keys that
+	// collide with typed fields or look like misspelled parameter names.
+	// Source identifies the endpoint. Setting it enables the lint pass
+	// without Debug.
+	OnLintWarnings func(source string, warnings []string)
+	// FetchRemoteImages downloads image parts that only carry a URL and
+	// sends them inline as base64 on providers whose chat API does not
+	// accept URL image sources (see providerutil.ImageURLSupport), such
+	// as Anthropic behind gateways that reject them. It has no effect on
+	// providers that accept URLs.
+	FetchRemoteImages bool
+	// RemoteImages limits the downloads made for FetchRemoteImages.
+	RemoteImages RemoteImageOptions
+	// MaxResponseBytes bounds the size of a response body: chat,
+	// completion, embedding, image, speech, and transcription responses
+	// and image streams. Reading past it fails with a
+	// *ResponseTooLargeError. Zero means DefaultMaxResponseBytes; a
+	// negative value disables the limit.
+	MaxResponseBytes int64
+	// MaxStreamBytes bounds the text, reasoning, and tool-call argument
+	// bytes a language-model stream delivers. A stream exceeding it fails
+	// with a *StreamTruncatedError carrying what was delivered. Zero
+	// means DefaultMaxStreamBytes; a negative value disables the limit.
+	MaxStreamBytes int64
+	// MaxStreamDeltas bounds the number of deltas a language-model
+	// stream delivers, failing like MaxStreamBytes. Zero means
+	// DefaultMaxStreamDeltas; a negative value disables the limit.
+	MaxStreamDeltas int
+	// DefaultStore sets LanguageModelRequest.Store for requests that
+	// leave it nil, such as store:false on every OpenAI request for
+	// compliance. Nil leaves the provider default.
+	DefaultStore *bool
+	// MaxMessages caps the number of messages per chat request for
+	// backends that limit it independently of tokens. Chat models
+	// report it through MessageLimiter, and ai.GenerateText and
+	// ai.StreamText pack longer histories with ai.PackMessages. Zero
+	// means no cap.
+	MaxMessages int
+	// DefaultUserID is the end-user ID sent with requests that leave
+	// UserID empty and whose ctx carries none (see WithUserID), for
+	// example a per-deployment ID for abuse tracking.
+	DefaultUserID string
+	// DeadlineHint, if set, sends the time left before the ctx deadline
+	// of each chat request to gateways that accept a timeout hint, so
+	// they stop server-side work once the caller has given up. Nil
+	// sends no hint.
+	DeadlineHint *DeadlineHintOptions
 }
 
+// DefaultDeadlineHintMargin is the DeadlineHintOptions.Margin used
+// when none is set.
+const DefaultDeadlineHintMargin = time.Second
+
+// DeadlineHintOptions configures ClientOptions.DeadlineHint. The hint
+// is the remaining time minus Margin, in seconds with millisecond
+// precision, such as 29.5. Requests whose ctx has no deadline carry no
+// hint, and requests with no time left after Margin fail before they
+// are sent.
+type DeadlineHintOptions struct {
+	// Header names a request header carrying the hint, such as
+	// "X-Request-Timeout". Empty sends no header.
+	Header string
+	// BodyField names a top-level body field carrying the hint, such as
+	// LiteLLM's "timeout". Empty sends no field. A field set through
+	// ProviderOptions takes precedence.
+	BodyField string
+	// Margin is subtracted from the remaining time to leave room for
+	// the response to reach the caller. Zero means
+	// DefaultDeadlineHintMargin; a negative value means none.
+	Margin time.Duration
+}
+
+// Defaults for the response limits of ClientOptions. They are generous
+// guardrails against misbehaving backends, well above what legitimate
+// responses reach.
+const (
+	DefaultMaxResponseBytes = 50 << 20
+	DefaultMaxStreamBytes   = 10 << 20
+	DefaultMaxStreamDeltas  = 1 << 20
+)
+
+// RemoteImageOptions limits the image downloads made for
+// ClientOptions.FetchRemoteImages. Zero values use the defaults.
+type RemoteImageOptions struct {
+	// MaxBytes is the largest image that is downloaded. Zero means
+	// DefaultRemoteImageMaxBytes.
+	MaxBytes int64
+	// AllowedTypes lists the accepted image content types. Empty means
+	// DefaultRemoteImageTypes.
+	AllowedTypes []string
+	// Timeout bounds each download. Zero means
+	// DefaultRemoteImageTimeout.
+	Timeout time.Duration
+	// HTTPClient downloads the images. If nil, a default client is
+	// used rather than ClientOptions.HTTPClient, so that image hosts
+	// never see transports configured for the provider API.
+	HTTPClient HTTPClient
+}
+
+// Defaults for RemoteImageOptions. The size cap matches Anthropic's
+// per-image limit.
+const (
+	DefaultRemoteImageMaxBytes = 5 << 20
+	DefaultRemoteImageTimeout  = 30 * time.Second
+)
+
+// DefaultRemoteImageTypes are the image content types accepted by
+// default, the formats supported by both OpenAI and Anthropic.
+var DefaultRemoteImageTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+// OrphanToolPolicy controls how orphaned tool messages are handled.
+type OrphanToolPolicy string
+
+const (
+	// OrphanToolDowngrade rewrites orphaned tool messages as user
+	// messages prefixed with "[tool result]". It is the default.
+	OrphanToolDowngrade OrphanToolPolicy = "downgrade"
+	// OrphanToolError rejects requests containing orphaned tool
+	// messages with an *OrphanToolMessageError.
+	OrphanToolError OrphanToolPolicy = "error"
+)
+
+// DefaultSystemSeparator is the separator used to join multiple system
+// messages. A blank line keeps markdown paragraphs and lists intact.
+const DefaultSystemSeparator = "\n\n"
+
+// SystemOnlyPolicy controls how requests containing only system
+// messages (or no messages at all) are handled.
+type SystemOnlyPolicy string
+
+const (
+	// SystemOnlyError rejects the request with a
+	// *NoConversationMessageError before it is sent. It is the default.
+	SystemOnlyError SystemOnlyPolicy = "error"
+	// SystemOnlyInsertUser appends SystemOnlyUserMessage as a user
+	// message, for priming calls that only carry instructions.
+	SystemOnlyInsertUser SystemOnlyPolicy = "insert_user"
+)
+
+// SystemOnlyUserMessage is the user message inserted by
+// SystemOnlyInsertUser.
+const SystemOnlyUserMessage = "Begin."
+
 // LanguageModel is the low-level provider-facing interface for chat models.
 // Implementations are responsible for mapping LanguageModelRequest values
 // to the provider's chat/completions API.
@@ -36,6 +240,42 @@ type LanguageModel interface {
 	Stream(ctx context.Context, req *LanguageModelRequest) (LanguageModelStream, error)
 }
 
+// RequestBuilder is an optional interface implemented by language
+// models that can render the HTTP request they would send for a
+// non-streaming Generate call without actually sending it.
+//
+// Implementations must use the same code path for BuildRequest and
+// Generate so the rendered request matches what is sent on the wire.
+type RequestBuilder interface {
+	BuildRequest(ctx context.Context, req *LanguageModelRequest) (*http.Request, []byte, error)
+}
+
+// StreamRequestBuilder is an optional interface implemented by language
+// models that can also render the HTTP request they would send for a
+// Stream call. As with RequestBuilder, it must share Stream's code path.
+type StreamRequestBuilder interface {
+	BuildStreamRequest(ctx context.Context, req *LanguageModelRequest) (*http.Request, []byte, error)
+}
+
+// TokenCounter is an optional interface implemented by language models
+// whose provider can report the exact number of input tokens a request
+// would use, including system prompt and tools.
+type TokenCounter interface {
+	CountTokens(ctx context.Context, req *LanguageModelRequest) (int, error)
+}
+
+// DeferredLanguageModel is an optional interface implemented by
+// language models whose provider supports server-side deferred
+// completions: a request is submitted, an ID is returned immediately,
+// and the result is retrieved later.
+type DeferredLanguageModel interface {
+	// SubmitDeferred submits req and returns the provider's job ID.
+	SubmitDeferred(ctx context.Context, req *LanguageModelRequest) (string, error)
+	// RetrieveDeferred fetches the result for a job. done is false
+	// while the job is still running.
+	RetrieveDeferred(ctx context.Context, jobID string) (res *LanguageModelResponse, done bool, err error)
+}
+
 // LanguageModelRequest is a provider-level request structure close to
 // the wire format used by chat APIs.
 type LanguageModelRequest struct {
@@ -46,7 +286,147 @@ type LanguageModelRequest struct {
 	MaxTokens   *int
 	Stop        []string
 	JSONSchema  []byte
-	Tools       []ToolDefinition
+	// JSONSchemaName names JSONSchema where the provider requires a
+	// name, such as OpenAI's json_schema.name. Empty means "response".
+	JSONSchemaName string
+	// JSONSchemaStrict requests strict structured outputs, such as
+	// OpenAI's json_schema "strict": true. JSONSchema already meets the
+	// strict requirements.
+	JSONSchemaStrict bool
+	// ResponseFormat selects JSON mode without a schema. Empty requests
+	// structured output when JSONSchema is set and text otherwise.
+	ResponseFormat ResponseFormat
+	Tools          []ToolDefinition
+	// ToolChoice controls whether and which of Tools the model calls.
+	// It is not sent when Tools is empty. Empty leaves the provider
+	// default.
+	ToolChoice ToolChoice
+	// ParallelToolCalls, if set, allows or forbids several tool calls
+	// in one response. OpenAI sends it as parallel_tool_calls and
+	// Anthropic as the inverse disable_parallel_tool_use. It is not
+	// sent when Tools is empty; providers without the flag ignore it.
+	ParallelToolCalls *bool
+	// LogitBias maps token IDs (as decimal strings) to a bias between
+	// -100 and 100. Providers without logit bias support ignore it.
+	LogitBias map[string]float64
+	// FrequencyPenalty and PresencePenalty, between -2 and 2, discourage
+	// repetition. Providers without penalties ignore them.
+	FrequencyPenalty *float64
+	PresencePenalty  *float64
+	// Logprobs requests the log probability of each generated token,
+	// returned in LanguageModelResponse.Logprobs. TopLogprobs also
+	// requests that many (OpenAI: 0 to 20) most likely alternatives per
+	// token and implies Logprobs. Providers without logprobs ignore
+	// both; streams do not report them.
+	Logprobs    *bool
+	TopLogprobs *int
+	// Betas lists additional provider beta features to enable for this
+	// request, merged with ClientOptions.Betas.
+	Betas []string
+	// OrphanToolMessages overrides ClientOptions.OrphanToolMessages for
+	// this request when non-empty.
+	OrphanToolMessages OrphanToolPolicy
+	// SystemOnly overrides ClientOptions.SystemOnly for this request
+	// when non-empty.
+	SystemOnly SystemOnlyPolicy
+	// Reasoning requests (and optionally limits) model reasoning output
+	// on providers that support it. Nil leaves the provider default.
+	Reasoning *ReasoningOptions
+	// Metadata is request-level metadata for providers that accept it.
+	// OpenAI sends it as the metadata object; Anthropic only supports
+	// the MetadataUserID key, sent as metadata.user_id.
+	Metadata map[string]string
+	// UserID is an opaque end-user identifier for abuse tracking. OpenAI
+	// sends it as user and Anthropic as metadata.user_id, where it takes
+	// precedence over Metadata. Empty uses the ID carried by the ctx
+	// (see WithUserID), then ClientOptions.DefaultUserID.
+	UserID string
+	// Store controls whether the provider retains the request and
+	// response, for example for OpenAI evals and distillation. Nil uses
+	// ClientOptions.DefaultStore. Providers without retention controls
+	// ignore it.
+	Store *bool
+	// WebSearch enables the provider's server-side web search tool,
+	// which the model may call during generation; the provider runs
+	// the searches and returns their results with the response.
+	// Anthropic sends it as the web_search_20250305 server tool.
+	// Providers without server-side search ignore it.
+	WebSearch *WebSearchOptions
+	// ProviderOptions holds provider-specific body fields, such as
+	// OpenAI's service_tier, merged into the top level of the outbound
+	// JSON body. Fields the request already sets from typed fields take
+	// precedence and are not overwritten.
+	ProviderOptions map[string]any
+}
+
+// WebSearchOptions configures the server-side web search tool. See
+// LanguageModelRequest.WebSearch.
+type WebSearchOptions struct {
+	// MaxUses limits the number of searches in one call. Zero leaves
+	// the provider default.
+	MaxUses int
+	// AllowedDomains restricts results to these domains. BlockedDomains
+	// excludes domains instead; set at most one of them.
+	AllowedDomains []string
+	BlockedDomains []string
+}
+
+// ToolChoice controls whether and which tools a model calls: one of
+// the ToolChoice constants or a value built by ToolChoiceTool. OpenAI
+// receives it as tool_choice "auto", "none", "required", or a function
+// object; Anthropic as a tool_choice object of type "auto", "none",
+// "any", or "tool".
+type ToolChoice string
+
+const (
+	// ToolChoiceAuto lets the model decide whether to call tools.
+	ToolChoiceAuto ToolChoice = "auto"
+	// ToolChoiceNone forbids tool calls.
+	ToolChoiceNone ToolChoice = "none"
+	// ToolChoiceRequired makes the model call at least one tool.
+	ToolChoiceRequired ToolChoice = "required"
+)
+
+// ResponseFormat selects the output format of a language-model call.
+type ResponseFormat string
+
+const (
+	// ResponseFormatJSON requests a JSON object without enforcing a
+	// schema, for backends that support OpenAI's json_object mode but
+	// not json_schema. OpenAI requires the messages to mention JSON.
+	// Providers without JSON mode, such as Anthropic, use JSONSchema if
+	// set and a permissive object schema otherwise.
+	ResponseFormatJSON ResponseFormat = "json"
+)
+
+// toolChoicePrefix prefixes the tool name of a ToolChoiceTool value.
+const toolChoicePrefix = "tool:"
+
+// ToolChoiceTool makes the model call the named tool.
+func ToolChoiceTool(name string) ToolChoice {
+	return ToolChoice(toolChoicePrefix + name)
+}
+
+// Tool returns the tool name of a ToolChoiceTool value, and false for
+// any other value.
+func (c ToolChoice) Tool() (string, bool) {
+	return strings.CutPrefix(string(c), toolChoicePrefix)
+}
+
+// MetadataUserID is the request metadata key for an opaque end-user
+// identifier.
+const MetadataUserID = "user_id"
+
+// ReasoningOptions controls reasoning ("thinking") output. Providers
+// map the fields they support and ignore the rest.
+type ReasoningOptions struct {
+	// Effort is a coarse reasoning effort: "low", "medium", or "high".
+	// OpenAI sends it as reasoning_effort; Anthropic maps it to a
+	// thinking budget when BudgetTokens is zero.
+	Effort string
+	// BudgetTokens caps the tokens spent on reasoning (Anthropic
+	// thinking budget_tokens).
+	BudgetTokens int
 }
 
 // Message is a provider-level chat message.
@@ -55,6 +435,55 @@ type LanguageModelRequest struct {
 type Message struct {
 	Role    string
 	Content string
+	// Parts optionally carries multi-part content such as text and
+	// images. When non-empty, providers that support multi-part content
+	// use Parts instead of Content; Content should then hold a plain-text
+	// rendering for providers that do not.
+	Parts []ContentPart
+	// ToolCalls are the tool calls made by an assistant message, as
+	// returned in LanguageModelResponse.ToolCalls. OpenAI requires them
+	// ahead of the tool messages that answer them, and Anthropic sends
+	// them as tool_use blocks.
+	ToolCalls []ToolCall `json:",omitempty"`
+	// ToolCallID is the ID of the tool call a RoleTool message answers
+	// (ToolCall.ID). OpenAI requires it on tool messages, and Anthropic
+	// sends the message as a tool_result block for that call.
+	ToolCallID string `json:",omitempty"`
+	// ReasoningBlocks are the signed reasoning blocks of an assistant
+	// message with ToolCalls, as returned in
+	// LanguageModelResponse.ReasoningBlocks. Anthropic requires them
+	// ahead of the tool_use blocks when thinking is enabled; other
+	// providers ignore them.
+	ReasoningBlocks []ReasoningBlock `json:",omitempty"`
+	// Metadata carries application data such as message IDs or source
+	// references through histories and persistence. It is never sent to
+	// providers; see LanguageModelRequest.Metadata for request-level
+	// metadata that is.
+	Metadata map[string]any `json:",omitempty"`
+}
+
+// ContentPartType identifies the kind of a ContentPart.
+type ContentPartType string
+
+const (
+	// ContentPartText is a plain text part.
+	ContentPartText ContentPartType = "text"
+	// ContentPartImage is an image part referenced by URL or inline data.
+	ContentPartImage ContentPartType = "image"
+)
+
+// ContentPart is a single part of a multi-part message.
+type ContentPart struct {
+	// Type is the kind of part.
+	Type ContentPartType
+	// Text is the text content for text parts.
+	Text string
+	// ImageURL is a remote URL (or data URI) for image parts.
+	ImageURL string
+	// Data contains raw bytes for inline image parts.
+	Data []byte
+	// MimeType is the content type of Data, e.g. "image/png".
+	MimeType string
 }
 
 // ToolDefinition describes a tool with JSON schema parameters.
@@ -73,15 +502,252 @@ type ToolCall struct {
 	RawArguments []byte
 }
 
+// ToolCallDelta is a fragment of a streamed tool call. Fragments with
+// the same Index belong to the same call; ID and Name are usually set
+// on the first fragment only, and Arguments are appended in order to
+// form the JSON arguments object.
+type ToolCallDelta struct {
+	Index     int
+	ID        string
+	Name      string
+	Arguments string
+}
+
+// ReasoningBlock is one block of reasoning output as the provider
+// returned it, to be sent back unchanged in a tool loop.
+type ReasoningBlock struct {
+	// Text is the reasoning text. It is empty for a redacted block.
+	Text string `json:",omitempty"`
+	// Signature verifies Text to the provider.
+	Signature string `json:",omitempty"`
+	// Redacted is the encrypted content of a block the provider did
+	// not return as text, such as Anthropic redacted_thinking.
+	Redacted string `json:",omitempty"`
+}
+
 // LanguageModelResponse is a provider-level response from a chat model.
+//
+// A response may carry both Text and ToolCalls. ToolCalls take
+// precedence: a response with tool calls is never a final answer, even
+// if it has text, its FinishReason is FinishReasonToolCalls, and its
+// StopReason is the provider's tool-calls value ("tool_calls" for
+// OpenAI, "tool_use" for Anthropic) whatever the backend reported.
 type LanguageModelResponse struct {
-	Text       string
+	Text string
+	// Reasoning is the model's reasoning output (Anthropic thinking,
+	// DeepSeek reasoning_content, and similar), if the provider
+	// returned any. It is not part of Text and is never echoed back into
+	// the message history by this SDK; only ReasoningBlocks are, with
+	// tool calls.
+	Reasoning string
+	// ReasoningBlocks are the reasoning blocks with the signatures that
+	// must accompany them when they are sent back, such as Anthropic
+	// thinking blocks. Tool loops keep them on the assistant message
+	// with the tool calls (Message.ReasoningBlocks).
+	ReasoningBlocks []ReasoningBlock
+	// StopReason is the provider's own stop reason, such as "length"
+	// (OpenAI) or "max_tokens" (Anthropic).
 	StopReason string
-	ToolCalls  []ToolCall
+	// FinishReason is StopReason mapped onto the provider-independent
+	// values. Empty means the provider reported none.
+	FinishReason FinishReason
+	ToolCalls    []ToolCall
+	// Warnings contains non-fatal validation notes about the request,
+	// such as a feature used without the beta it requires.
+	Warnings []string
+	// ToolDiagnostics is set when tools were sent but the response
+	// contains no tool calls, to help distinguish a model that chose not
+	// to call a tool from a backend that ignored the tools field.
+	ToolDiagnostics *ToolDiagnostics
+	// Citations lists sources the provider attributed parts of Text to,
+	// for example when search grounding is enabled.
+	Citations []Citation
+	// SafetyRatings contains the provider's per-category safety
+	// assessment of the response, when reported.
+	SafetyRatings []SafetyRating
+	// Usage is the token usage of the call. It is nil when the provider
+	// did not report usage.
+	Usage *Usage
+	// Metadata describes how the response was produced. It is nil when
+	// there is nothing to report.
+	Metadata *ResponseMetadata
+	// Logprobs holds the log probability of each generated token when
+	// the request set Logprobs or TopLogprobs and the provider returned
+	// them.
+	Logprobs []TokenLogprob
+}
+
+// TokenLogprob is the log probability of one generated token.
+type TokenLogprob struct {
+	Token   string
+	Logprob float64
+	// TopAlternatives are the most likely tokens at this position, most
+	// likely first, when TopLogprobs was requested. They may include
+	// Token itself.
+	TopAlternatives []TokenAlternative
+}
+
+// TokenAlternative is a candidate token and its log probability.
+type TokenAlternative struct {
+	Token   string
+	Logprob float64
+}
+
+// ResponseMetadata describes how a response was produced, beyond its
+// content.
+type ResponseMetadata struct {
+	// Model names the model that served the call when a wrapper such as
+	// a router chose among several.
+	Model string
+	// RequestID is the provider's ID for the HTTP request, from the
+	// x-request-id (OpenAI) or request-id (Anthropic) response header,
+	// for correlating calls with the provider's dashboard and support.
+	RequestID string
+	// SearchResults lists the pages returned by server-side web
+	// searches (see LanguageModelRequest.WebSearch), whether or not the
+	// text cites them. Their spans are zero.
+	SearchResults []Citation
+	// Headers is a copy of the response headers useful for diagnostics:
+	// the request ID, rate-limit headers such as
+	// x-ratelimit-remaining-requests or
+	// anthropic-ratelimit-requests-remaining, retry-after, and
+	// openai-processing-ms.
+	Headers http.Header
+	// Retry reports the attempts of a call that middleware such as
+	// middleware.RetryLanguageModel retried. It is nil when the call
+	// was not retried.
+	Retry *RetryStats
+	// DroppedOptions lists the request options the provider could not
+	// honor and left out of the request; see
+	// ClientOptions.OnDroppedOptions.
+	DroppedOptions []DroppedOption
+}
+
+// DroppedOption is a request option a provider could not honor, such
+// as FrequencyPenalty on Anthropic, which has no penalties. The request
+// is sent without it.
+type DroppedOption struct {
+	// Field names the LanguageModelRequest field, such as
+	// "FrequencyPenalty" or "Reasoning.BudgetTokens".
+	Field string
+	// Reason explains why the provider left it out.
+	Reason string
+}
+
+// Usage reports the tokens consumed by a language-model call.
+type Usage struct {
+	// PromptTokens is the number of input tokens, including tokens read
+	// from or written to a prompt cache.
+	PromptTokens int `json:"prompt_tokens"`
+	// CompletionTokens is the number of generated tokens, including
+	// reasoning tokens.
+	CompletionTokens int `json:"completion_tokens"`
+	// TotalTokens is PromptTokens plus CompletionTokens.
+	TotalTokens int `json:"total_tokens"`
+	// Cache reports the part of PromptTokens served from or written to
+	// a prompt cache. It is nil when the provider reported no cache
+	// information.
+	Cache *CacheUsage `json:"cache,omitempty"`
+	// WebSearchRequests is the number of server-side web searches the
+	// call ran (Anthropic server_tool_use.web_search_requests), which
+	// providers bill separately from tokens.
+	WebSearchRequests int `json:"web_search_requests,omitempty"`
+}
+
+// CacheUsage reports prompt-cache activity. Both counts are included in
+// Usage.PromptTokens.
+type CacheUsage struct {
+	// CachedInputTokens is the number of input tokens read from the
+	// cache (OpenAI cached_tokens, Anthropic cache_read_input_tokens).
+	CachedInputTokens int `json:"cached_input_tokens"`
+	// CacheWriteTokens is the number of input tokens written to the
+	// cache (Anthropic cache_creation_input_tokens). OpenAI caches
+	// automatically and does not report writes.
+	CacheWriteTokens int `json:"cache_write_tokens"`
+}
+
+// Add returns the sum of u and other. A nil operand counts as zero;
+// the result is nil only when both are nil.
+func (u *Usage) Add(other *Usage) *Usage {
+	if u == nil && other == nil {
+		return nil
+	}
+	var sum Usage
+	for _, v := range []*Usage{u, other} {
+		if v != nil {
+			sum.PromptTokens += v.PromptTokens
+			sum.CompletionTokens += v.CompletionTokens
+			sum.TotalTokens += v.TotalTokens
+			sum.WebSearchRequests += v.WebSearchRequests
+			if v.Cache != nil {
+				if sum.Cache == nil {
+					sum.Cache = &CacheUsage{}
+				}
+				sum.Cache.CachedInputTokens += v.Cache.CachedInputTokens
+				sum.Cache.CacheWriteTokens += v.Cache.CacheWriteTokens
+			}
+		}
+	}
+	return &sum
+}
+
+// Citation attributes a span of the response text to a source.
+type Citation struct {
+	// URL is the source location, if any.
+	URL string `json:"url,omitempty"`
+	// Title is the source title, if any.
+	Title string `json:"title,omitempty"`
+	// Text is the cited passage from the source, if provided.
+	Text string `json:"text,omitempty"`
+	// StartIndex and EndIndex are byte offsets into the response text
+	// of the span supported by this source. Both are zero when the
+	// provider does not report a span.
+	StartIndex int `json:"start_index,omitempty"`
+	EndIndex   int `json:"end_index,omitempty"`
+}
+
+// SafetyRating is a provider's assessment of one harm category.
+type SafetyRating struct {
+	// Category is the provider's harm category name, e.g.
+	// "HARM_CATEGORY_HARASSMENT".
+	Category string `json:"category"`
+	// Probability is the provider's likelihood label, e.g. "LOW".
+	Probability string `json:"probability,omitempty"`
+	// Blocked reports whether this category caused content to be
+	// blocked.
+	Blocked bool `json:"blocked,omitempty"`
+}
+
+// ToolDiagnostics describes what a provider's response revealed about
+// its handling of the tools sent with a request.
+type ToolDiagnostics struct {
+	// FinishReason is the provider's raw finish or stop reason.
+	FinishReason string
+	// ToolCallsKeyPresent reports whether the response included a
+	// tool-calls field at all (even an empty one). Backends that ignore
+	// tools typically omit it.
+	ToolCallsKeyPresent bool
+	// TextPresent reports whether the response contained any text.
+	// A response with neither text nor tool calls is a strong hint that
+	// the tools field was dropped.
+	TextPresent bool
 }
 
 // LanguageModelStream represents an incremental streaming interface.
 // Next should block until a new delta is available or the stream ends.
+//
+// Implementations follow this lifetime contract (providerutil.LineReader
+// implements it for line-based HTTP streams):
+//
+//   - The stream's lifetime is governed by the ctx passed to Stream.
+//     When it is done, the connection is released and Next returns its
+//     error.
+//   - The ctx passed to Next only bounds that call. When it is done, Next
+//     returns its error and the stream remains usable.
+//   - Close releases the connection promptly and may be called more than
+//     once. Callers should always Close streams; an abandoned stream is
+//     otherwise only released when its Stream ctx is done (or, for the
+//     built-in providers, when it is garbage collected).
 type LanguageModelStream interface {
 	Next(ctx context.Context) (*LanguageModelDelta, error)
 	Close() error
@@ -89,9 +755,28 @@ type LanguageModelStream interface {
 
 // LanguageModelDelta is a single streamed update from a chat model.
 type LanguageModelDelta struct {
-	Text      string
+	Text string
+	// Reasoning is an incremental piece of reasoning output.
+	Reasoning string
+	// ToolCalls are complete tool calls. The built-in providers deliver
+	// them only on the final (Done) delta, after all text deltas, with
+	// the same ID, name and arguments Generate would return.
 	ToolCalls []ToolCall
-	Done      bool
+	// ToolCallDeltas are fragments of tool calls as they stream in, for
+	// callers that show arguments while they are generated. They are
+	// informational: the complete calls still arrive in ToolCalls.
+	// ai.ToolCallAccumulator assembles either form.
+	ToolCallDeltas []ToolCallDelta
+	// Usage is the token usage of the call, set on the final (Done)
+	// delta when the provider reported it.
+	Usage *Usage
+	// StopReason, FinishReason, Warnings, and Metadata are set on the
+	// final (Done) delta, as on LanguageModelResponse.
+	StopReason   string
+	FinishReason FinishReason
+	Warnings     []string
+	Metadata     *ResponseMetadata
+	Done         bool
 }
 
 // EmbeddingModel is the provider-level interface for embeddings.
@@ -105,11 +790,36 @@ type EmbeddingRequest struct {
 	Model  string
 	Input  []string
 	UserID string
+	// OutputDType requests vectors of this type from providers that
+	// produce them natively. Others return float32 Embeddings, which
+	// the caller converts.
+	OutputDType EmbeddingDType
 }
 
-// EmbeddingResponse contains embedding vectors.
+// EmbeddingDType is the numeric type of embedding vectors.
+type EmbeddingDType string
+
+// Embedding types for EmbeddingRequest.OutputDType.
+const (
+	// EmbeddingFloat32 is the default.
+	EmbeddingFloat32 EmbeddingDType = "float32"
+	EmbeddingFloat64 EmbeddingDType = "float64"
+	// EmbeddingInt8 is one signed byte per dimension.
+	EmbeddingInt8 EmbeddingDType = "int8"
+	// EmbeddingBinary is one bit per dimension, set for positive
+	// components, packed most significant bit first.
+	EmbeddingBinary EmbeddingDType = "binary"
+)
+
+// EmbeddingResponse contains embedding vectors. A provider that
+// produced the requested OutputDType natively sets the matching field
+// instead of Embeddings.
 type EmbeddingResponse struct {
 	Embeddings [][]float32
+	Float64    [][]float64
+	Int8       [][]int8
+	// Binary holds packed bits; see EmbeddingBinary.
+	Binary [][]byte
 }
 
 // ImageModel is the provider-level interface for image generation.
@@ -132,6 +842,65 @@ type ImageRequest struct {
 	ResponseFormat string
 	// UserID is an optional identifier used for provider-side logging.
 	UserID string
+	// PartialImages is the number of partial (preview) images to emit
+	// while streaming. It is ignored by non-streaming calls.
+	PartialImages int
+}
+
+// ImageCapabilities describes the request values an image model
+// accepts. Empty or zero fields mean "no restriction".
+type ImageCapabilities struct {
+	// Sizes lists the supported Size values.
+	Sizes []string
+	// MaxImages is the largest supported NumberOfImages.
+	MaxImages int
+}
+
+// ImageCapabilityReporter is an optional interface implemented by image
+// models that know the limits of their underlying model. ok is false
+// for models with unknown limits, which skips validation.
+type ImageCapabilityReporter interface {
+	ImageCapabilities() (caps ImageCapabilities, ok bool)
+}
+
+// MessageLimiter is an optional interface implemented by language
+// models whose backend caps the number of messages per request.
+// MaxMessages returns the cap, or 0 if there is none.
+type MessageLimiter interface {
+	MaxMessages() int
+}
+
+// ImageStreamer is an optional interface implemented by image models
+// that can stream partial images while a generation is in progress.
+type ImageStreamer interface {
+	Stream(ctx context.Context, req *ImageRequest) (ImageModelStream, error)
+}
+
+// ImageModelStream represents an incremental image generation stream.
+// Next should block until a new delta is available or the stream ends.
+// It follows the same lifetime contract as LanguageModelStream.
+type ImageModelStream interface {
+	Next(ctx context.Context) (*ImageDelta, error)
+	Close() error
+}
+
+// ImageDelta is a single streamed update from an image model. Exactly
+// one of Partial or Image is set unless Done is true.
+type ImageDelta struct {
+	// Partial is an in-progress preview of an image.
+	Partial *PartialImage
+	// Image is a complete generated image.
+	Image *Image
+	// Done reports that the stream has ended.
+	Done bool
+}
+
+// PartialImage is a preview emitted while an image is being generated.
+type PartialImage struct {
+	// Index is the zero-based sequence number of the partial image.
+	Index int
+	// Data contains the decoded image bytes.
+	Data []byte
 }
 
 // Image contains a single generated image.
@@ -162,8 +931,9 @@ type SpeechRequest struct {
 	Input string
 	// Voice is an optional voice selection (provider-specific).
 	Voice string
-	// Format is the desired audio container/codec (e.g. "mp3", "wav").
-	Format string
+	// Format is the desired audio container/codec (e.g. FormatMP3).
+	// Empty means the provider default.
+	Format AudioFormat
 	// Language is an optional BCP-47 language tag.
 	Language string
 	// UserID is an optional identifier used for provider-side logging.
@@ -178,6 +948,58 @@ type SpeechResponse struct {
 	MimeType string
 }
 
+// AudioFormat names the audio container or codec of generated speech.
+// It is an alias of string so that SpeechRequest.Format accepts plain
+// strings as it always has.
+type AudioFormat = string
+
+// Audio formats for SpeechRequest.Format.
+const (
+	FormatMP3  = "mp3"
+	FormatWAV  = "wav"
+	FormatOpus = "opus"
+	FormatAAC  = "aac"
+	FormatFLAC = "flac"
+	// FormatPCM is raw 16-bit little-endian samples without a header.
+	FormatPCM = "pcm"
+)
+
+// AudioMimeType returns the content type of audio in format f, or ""
+// for unknown formats.
+func AudioMimeType(f AudioFormat) string {
+	switch f {
+	case FormatMP3:
+		return "audio/mpeg"
+	case FormatWAV:
+		return "audio/wav"
+	case FormatOpus:
+		return "audio/opus"
+	case FormatAAC:
+		return "audio/aac"
+	case FormatFLAC:
+		return "audio/flac"
+	case FormatPCM:
+		return "audio/pcm"
+	}
+	return ""
+}
+
+// SpeechCapabilities describes the request values a speech model
+// accepts. Empty fields mean "no restriction".
+type SpeechCapabilities struct {
+	// Formats lists the supported Format values.
+	Formats []AudioFormat
+	// DefaultFormat is the format produced when Format is empty.
+	DefaultFormat AudioFormat
+}
+
+// SpeechCapabilityReporter is an optional interface implemented by
+// speech models that know the limits of their underlying model. ok is
+// false for models with unknown limits, which skips validation.
+type SpeechCapabilityReporter interface {
+	SpeechCapabilities() (caps SpeechCapabilities, ok bool)
+}
+
 // TranscriptionModel is the provider-level interface for speech-to-text transcription.
 // Implementations map TranscriptionRequest values to the provider's transcription API.
 type TranscriptionModel interface {
@@ -202,11 +1024,34 @@ type TranscriptionRequest struct {
 	Temperature *float64
 	// UserID is an optional identifier used for provider-side logging.
 	UserID string
+	// ExtraFields contains additional form fields passed through as-is,
+	// for backends that accept options beyond the OpenAI API (for
+	// example "vad_filter" on self-hosted Whisper servers).
+	ExtraFields map[string]string
+	// ExtraArrayFields contains additional array-valued form fields.
+	// Each value is sent as a repeated "name[]" field, e.g.
+	// "timestamp_granularities": {"word", "segment"}.
+	ExtraArrayFields map[string][]string
+	// Segments requests segment-level timestamps in the response. Not
+	// every model supports them; OpenAI only returns segments from
+	// whisper-1.
+	Segments bool
 }
 
 // TranscriptionResponse contains the transcription text.
 type TranscriptionResponse struct {
 	Text string
+	// Segments holds timestamped segments when they were requested and
+	// the model returned them.
+	Segments []TranscriptionSegment
+}
+
+// TranscriptionSegment is a timestamped span of a transcription.
+// Start and End are offsets into the audio in seconds.
+type TranscriptionSegment struct {
+	Start float64
+	End   float64
+	Text  string
 }
 
 // RerankModel is the provider-level interface for reranking documents.
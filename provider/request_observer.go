@@ -0,0 +1,24 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+)
+
+type requestObserverKey struct{}
+
+// WithRequestObserver returns a context whose chat calls pass each
+// HTTP request they send, with its body, to observe before sending it.
+// It lets tests record the exact payload of a call without building the
+// request a second time. The built-in chat models support it.
+func WithRequestObserver(ctx context.Context, observe func(req *http.Request, body []byte)) context.Context {
+	return context.WithValue(ctx, requestObserverKey{}, observe)
+}
+
+// ObserveRequest passes req and body to the observer carried by ctx, if
+// any. Providers call it once per request, right before sending it.
+func ObserveRequest(ctx context.Context, req *http.Request, body []byte) {
+	if observe, _ := ctx.Value(requestObserverKey{}).(func(*http.Request, []byte)); observe != nil {
+		observe(req, body)
+	}
+}
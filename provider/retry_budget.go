@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+type retryBudgetKey struct{}
+
+// RetryBudget caps the total number of retries performed across all
+// retrying components that share a context, such as stacked retry
+// middleware, fallbacks, and agent loops. A nil *RetryBudget allows
+// unlimited retries.
+type RetryBudget struct {
+	mu        sync.Mutex
+	remaining int
+	skipped   int
+}
+
+// WithRetryBudget returns a context carrying a retry budget of n
+// retries. If ctx already carries a budget it is returned unchanged, so
+// inner components cannot raise a cap imposed by an outer caller.
+func WithRetryBudget(ctx context.Context, n int) context.Context {
+	if RetryBudgetFromContext(ctx) != nil {
+		return ctx
+	}
+	if n < 0 {
+		n = 0
+	}
+	return context.WithValue(ctx, retryBudgetKey{}, &RetryBudget{remaining: n})
+}
+
+// RetryBudgetFromContext returns the retry budget carried by ctx, or
+// nil if there is none.
+func RetryBudgetFromContext(ctx context.Context) *RetryBudget {
+	b, _ := ctx.Value(retryBudgetKey{}).(*RetryBudget)
+	return b
+}
+
+// TryAcquire consumes one retry from the budget. It reports false, and
+// records a skipped retry, when the budget is exhausted.
+func (b *RetryBudget) TryAcquire() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.remaining <= 0 {
+		b.skipped++
+		return false
+	}
+	b.remaining--
+	return true
+}
+
+// Remaining returns the number of retries left in the budget.
+func (b *RetryBudget) Remaining() int {
+	if b == nil {
+		return -1
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.remaining
+}
+
+// Skipped returns the number of retries that were skipped because the
+// budget was exhausted.
+func (b *RetryBudget) Skipped() int {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.skipped
+}
+
+// RetryBudgetExhaustedError is returned by retrying components that
+// would have retried Err but skipped the retry because the shared
+// retry budget was exhausted.
+type RetryBudgetExhaustedError struct {
+	// Err is the last error encountered before the retry was skipped.
+	Err error
+}
+
+func (e *RetryBudgetExhaustedError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("retry budget exhausted: %v", e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *RetryBudgetExhaustedError) Unwrap() error {
+	if e == nil {
+		return nil
+	}
+	return e.Err
+}
@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+)
+
+// TraceHeader is the outbound header carrying the client-generated
+// trace ID. OpenAI echoes it in its own request logs.
+const TraceHeader = "X-Client-Request-Id"
+
+type traceIDKey struct{}
+
+// WithTraceID returns a context carrying the trace ID id. Calls made
+// with the returned context reuse id instead of generating a new one.
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, id)
+}
+
+// TraceIDFromContext returns the trace ID carried by ctx, or "" if
+// there is none.
+func TraceIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(traceIDKey{}).(string)
+	return id
+}
+
+// EnsureTraceID returns ctx and its trace ID, attaching a newly
+// generated ID when ctx does not carry one.
+func EnsureTraceID(ctx context.Context) (context.Context, string) {
+	if id := TraceIDFromContext(ctx); id != "" {
+		return ctx, id
+	}
+	id := NewTraceID()
+	return WithTraceID(ctx, id), id
+}
+
+// NewTraceID returns a random 128-bit trace ID as 32 hex characters.
+func NewTraceID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// StampTraceID sets the TraceID field of the typed errors in err's
+// chain that do not have one yet, and reports whether any typed error
+// was found.
+func StampTraceID(err error, id string) bool {
+	found := false
+	stamp := func(dst *string) {
+		found = true
+		if *dst == "" {
+			*dst = id
+		}
+	}
+	var empty *EmptyResponseError
+	if errors.As(err, &empty) && empty != nil {
+		stamp(&empty.TraceID)
+	}
+	var orphan *OrphanToolMessageError
+	if errors.As(err, &orphan) && orphan != nil {
+		stamp(&orphan.TraceID)
+	}
+	var noConv *NoConversationMessageError
+	if errors.As(err, &noConv) && noConv != nil {
+		stamp(&noConv.TraceID)
+	}
+	var blocked *ContentBlockedError
+	if errors.As(err, &blocked) && blocked != nil {
+		stamp(&blocked.TraceID)
+	}
+	return found
+}
@@ -0,0 +1,20 @@
+package provider
+
+import "context"
+
+type userIDKey struct{}
+
+// WithUserID returns a context carrying the end-user ID id for the
+// provider requests made with it, such as the signed-in user of an
+// HTTP handler. It fills the UserID of requests that leave it empty and
+// takes precedence over ClientOptions.DefaultUserID.
+func WithUserID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, userIDKey{}, id)
+}
+
+// UserIDFromContext returns the end-user ID carried by ctx, or "" if
+// there is none.
+func UserIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(userIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,38 @@
+package providerutil
+
+import (
+	"net/http"
+	"strings"
+)
+
+// SetBetaHeader merges beta feature names into the comma-separated
+// header name on h (for example "anthropic-beta" or "OpenAI-Beta").
+// Values already present on h are kept first, duplicates and empty
+// names are dropped, and the header is left untouched when there is
+// nothing to set.
+func SetBetaHeader(h http.Header, name string, betas ...[]string) {
+	var out []string
+	seen := make(map[string]bool)
+	add := func(b string) {
+		b = strings.TrimSpace(b)
+		if b == "" || seen[b] {
+			return
+		}
+		seen[b] = true
+		out = append(out, b)
+	}
+	for _, v := range h.Values(name) {
+		for _, b := range strings.Split(v, ",") {
+			add(b)
+		}
+	}
+	for _, list := range betas {
+		for _, b := range list {
+			add(b)
+		}
+	}
+	if len(out) == 0 {
+		return
+	}
+	h.Set(name, strings.Join(out, ","))
+}
@@ -0,0 +1,39 @@
+package providerutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// MergeBodyFields adds the fields of extra to the top level of the JSON
+// object body. Keys body already sets keep body's value and are
+// returned, sorted, as collisions. With no extra fields body is
+// returned unchanged.
+func MergeBodyFields(body []byte, extra map[string]any) ([]byte, []string, error) {
+	if len(extra) == 0 {
+		return body, nil, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, nil, err
+	}
+	var collisions []string
+	for k, v := range extra {
+		if _, ok := fields[k]; ok {
+			collisions = append(collisions, k)
+			continue
+		}
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("provider option %q: %w", k, err)
+		}
+		fields[k] = raw
+	}
+	sort.Strings(collisions)
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, collisions, nil
+}
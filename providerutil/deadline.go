@@ -0,0 +1,72 @@
+package providerutil
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// DeadlineHint is the timeout hint of one request; see
+// provider.DeadlineHintOptions. A nil *DeadlineHint sends no hint.
+type DeadlineHint struct {
+	opts *provider.DeadlineHintOptions
+	// Headroom is the time left before the deadline, minus the margin.
+	Headroom time.Duration
+}
+
+// NewDeadlineHint computes the hint for a request made at now with ctx.
+// It returns nil when opts is nil or ctx has no deadline.
+//
+// Errors:
+//   - An error wrapping context.DeadlineExceeded if no time is left
+//     after the margin; the request should not be sent.
+func NewDeadlineHint(ctx context.Context, opts *provider.DeadlineHintOptions, now time.Time) (*DeadlineHint, error) {
+	if opts == nil {
+		return nil, nil
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil, nil
+	}
+	margin := opts.Margin
+	if margin == 0 {
+		margin = provider.DefaultDeadlineHintMargin
+	}
+	margin = max(margin, 0)
+	headroom := deadline.Sub(now) - margin
+	if headroom.Truncate(time.Millisecond) <= 0 {
+		return nil, fmt.Errorf("deadline leaves no time after the %v margin: %w", margin, context.DeadlineExceeded)
+	}
+	return &DeadlineHint{opts: opts, Headroom: headroom}, nil
+}
+
+// Seconds formats the headroom in seconds, truncated to milliseconds.
+func (h *DeadlineHint) Seconds() string {
+	if h == nil {
+		return ""
+	}
+	return strconv.FormatFloat(h.Headroom.Truncate(time.Millisecond).Seconds(), 'f', -1, 64)
+}
+
+// MergeBody adds the hint to the JSON object body when a body field is
+// configured. A field body already sets is kept.
+func (h *DeadlineHint) MergeBody(body []byte) ([]byte, error) {
+	if h == nil || h.opts.BodyField == "" {
+		return body, nil
+	}
+	seconds, _ := strconv.ParseFloat(h.Seconds(), 64)
+	body, _, err := MergeBodyFields(body, map[string]any{h.opts.BodyField: seconds})
+	return body, err
+}
+
+// SetHeader sets the hint header when one is configured.
+func (h *DeadlineHint) SetHeader(header http.Header) {
+	if h == nil || h.opts.Header == "" {
+		return
+	}
+	header.Set(h.opts.Header, h.Seconds())
+}
@@ -0,0 +1,28 @@
+package providerutil
+
+import (
+	"strings"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// CheckDroppedOptions returns an *provider.UnsupportedFunctionalityError
+// listing dropped when strict is set (see
+// provider.ClientOptions.StrictOptions) and the provider named
+// providerName would drop any request option.
+func CheckDroppedOptions(providerName string, dropped []provider.DroppedOption, strict bool) error {
+	if !strict || len(dropped) == 0 {
+		return nil
+	}
+	fields := make([]string, len(dropped))
+	reasons := make([]string, len(dropped))
+	for i, d := range dropped {
+		fields[i] = d.Field
+		reasons[i] = d.Field + ": " + d.Reason
+	}
+	return &provider.UnsupportedFunctionalityError{
+		Feature: strings.Join(fields, ", "),
+		Message: providerName + " would drop them (" + strings.Join(reasons, "; ") + ")",
+		Dropped: dropped,
+	}
+}
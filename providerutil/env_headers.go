@@ -0,0 +1,187 @@
+package providerutil
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// DefaultHeadersEnv names the environment variable holding default
+// headers for every provider client, in ParseHeaderList syntax. It is
+// read only when ClientOptions.HeadersFromEnv is set.
+const DefaultHeadersEnv = "AI_SDK_DEFAULT_HEADERS"
+
+// ClientHeaders returns the headers a client should send on every
+// request. Without opts.HeadersFromEnv it returns opts.Headers. With
+// it, headers are read from DefaultHeadersEnv and then from the
+// provider's own variable providerEnv (for example
+// OPENAI_EXTRA_HEADERS), and opts.Headers is merged on top; each
+// source replaces the values of headers named by the previous ones.
+// The result is a new header map.
+//
+// Errors:
+//   - An error naming the variable if either one does not parse.
+func ClientHeaders(opts provider.ClientOptions, providerEnv string) (http.Header, error) {
+	if !opts.HeadersFromEnv {
+		return opts.Headers, nil
+	}
+	merged := make(http.Header)
+	for _, name := range []string{DefaultHeadersEnv, providerEnv} {
+		h, err := ParseHeaderList(os.Getenv(name))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		replaceHeaders(merged, h)
+	}
+	replaceHeaders(merged, opts.Headers)
+	return merged, nil
+}
+
+// replaceHeaders sets every header of src on dst, replacing the values
+// dst had for it.
+func replaceHeaders(dst, src http.Header) {
+	for k, vs := range src {
+		k = http.CanonicalHeaderKey(k)
+		dst[k] = append([]string(nil), vs...)
+	}
+}
+
+// ParseHeaderList parses comma-separated name=value pairs, such as
+//
+//	Proxy-Authorization=Basic dXNlcjpwYXNz,X-Egress-Token="a,b=c"
+//
+// Whitespace around names and values is ignored. A value runs to the
+// next comma and may contain '='. A value containing commas, quotes, or
+// leading or trailing spaces is written in double quotes, in which \"
+// and \\ stand for a quote and a backslash. A repeated name adds a
+// value. FormatHeaderList produces this syntax.
+func ParseHeaderList(s string) (http.Header, error) {
+	h := make(http.Header)
+	rest := s
+	for {
+		rest = strings.TrimLeft(rest, " \t,")
+		if rest == "" {
+			return h, nil
+		}
+		eq := strings.IndexByte(rest, '=')
+		if comma := strings.IndexByte(rest, ','); eq < 0 || (comma >= 0 && comma < eq) {
+			entry, _, _ := strings.Cut(rest, ",")
+			return nil, fmt.Errorf("header entry %q has no '='", strings.TrimSpace(entry))
+		}
+		name := strings.TrimSpace(rest[:eq])
+		if !validHeaderName(name) {
+			return nil, fmt.Errorf("invalid header name %q", name)
+		}
+		rest = strings.TrimLeft(rest[eq+1:], " \t")
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			var err error
+			value, rest, err = unquoteHeaderValue(rest)
+			if err != nil {
+				return nil, fmt.Errorf("header %s: %w", name, err)
+			}
+			rest = strings.TrimLeft(rest, " \t")
+			if rest != "" && rest[0] != ',' {
+				return nil, fmt.Errorf("header %s: unexpected text after quoted value", name)
+			}
+		} else {
+			end := strings.IndexByte(rest, ',')
+			if end < 0 {
+				end = len(rest)
+			}
+			value, rest = strings.TrimSpace(rest[:end]), rest[end:]
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("header %s: value contains a line break", name)
+		}
+		h.Add(name, value)
+	}
+}
+
+// unquoteHeaderValue decodes the quoted value at the start of s and
+// returns it with the text after the closing quote.
+func unquoteHeaderValue(s string) (value, rest string, err error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"':
+			return b.String(), s[i+1:], nil
+		case '\\':
+			i++
+			if i == len(s) {
+				return "", "", fmt.Errorf("unterminated quoted value")
+			}
+			b.WriteByte(s[i])
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", fmt.Errorf("unterminated quoted value")
+}
+
+// FormatHeaderList renders h in ParseHeaderList syntax, sorted by
+// name, quoting the values that need it.
+func FormatHeaderList(h http.Header) string {
+	names := make([]string, 0, len(h))
+	for k := range h {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+	var entries []string
+	for _, k := range names {
+		for _, v := range h[k] {
+			if v == "" || strings.ContainsAny(v, `,"\`) || strings.TrimSpace(v) != v {
+				v = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v) + `"`
+			}
+			entries = append(entries, k+"="+v)
+		}
+	}
+	return strings.Join(entries, ",")
+}
+
+// validHeaderName reports whether name is a non-empty HTTP token.
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// RedactHeaders returns a copy of h with the values of headers that
+// may carry credentials replaced by "REDACTED": authorization and
+// cookie headers, and any header whose name mentions a key, token,
+// secret, or password, such as X-Api-Key or X-Egress-Token. Use it
+// before logging or displaying request headers.
+func RedactHeaders(h http.Header) http.Header {
+	out := h.Clone()
+	for k, vs := range out {
+		if len(vs) > 0 && sensitiveHeader(k) {
+			out[k] = []string{"REDACTED"}
+		}
+	}
+	return out
+}
+
+func sensitiveHeader(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range []string{"auth", "cookie", "key", "token", "secret", "password"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,30 +2,179 @@ package providerutil
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"io"
 	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/ncecere/ai-sdk/provider"
 )
 
 // ReadJSON decodes a JSON response body into v and closes the body.
 //
 // If the response status code is not in the 2xx range, ReadJSON
-// returns an error of the form:
-//
-//	provider: http status <code>: <truncated-body>
-//
-// Callers can inspect this error string or wrap it in higher-level
-// errors as needed.
+// returns a *provider.APIError with the fields of the error envelope
+// parsed (see ParseAPIError). Providers name themselves in it with
+// TagProvider.
 func ReadJSON(resp *http.Response, v any) error {
 	defer resp.Body.Close()
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		b, _ := io.ReadAll(io.LimitReader(resp.Body, 8*1024))
-		return fmt.Errorf("provider: http status %d: %s", resp.StatusCode, string(b))
+	if err := statusError(resp); err != nil {
+		return err
 	}
 	dec := json.NewDecoder(resp.Body)
 	return dec.Decode(v)
 }
 
+// ReadJSONWithBody is like ReadJSON but also returns the raw response
+// body bytes so callers can include a snippet of the body in errors
+// when the decoded value is unexpectedly empty.
+func ReadJSONWithBody(resp *http.Response, v any) ([]byte, error) {
+	defer resp.Body.Close()
+	if err := statusError(resp); err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return body, err
+	}
+	return body, nil
+}
+
+// CheckStatus returns the same error as ReadJSON if the response
+// status code is not in the 2xx range, closing the body in that case.
+// Streaming endpoints call it before handing the body to a stream so
+// error responses are neither parsed as events nor left open.
+func CheckStatus(resp *http.Response) error {
+	err := statusError(resp)
+	if err != nil {
+		resp.Body.Close()
+	}
+	return err
+}
+
+func statusError(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 8*1024))
+	apiErr := ParseAPIError(resp.StatusCode, b)
+	apiErr.RequestID = requestID(resp.Header)
+	return apiErr
+}
+
+// requestIDHeaders lists the response headers providers return their
+// request ID in, in order of preference.
+var requestIDHeaders = []string{"X-Request-Id", "Request-Id"}
+
+// metadataHeaderPrefixes lists the prefixes of the response headers
+// ResponseMetadata copies, in canonical form.
+var metadataHeaderPrefixes = []string{"X-Ratelimit-", "Anthropic-Ratelimit-"}
+
+// metadataHeaders lists other response headers ResponseMetadata copies.
+var metadataHeaders = []string{"X-Request-Id", "Request-Id", "Retry-After", "Openai-Processing-Ms"}
+
+func requestID(h http.Header) string {
+	for _, name := range requestIDHeaders {
+		if id := h.Get(name); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// ResponseMetadata returns the request ID and diagnostic headers of
+// resp (see provider.ResponseMetadata). It only reads headers, so it
+// can be called before or after the body is consumed.
+func ResponseMetadata(resp *http.Response) *provider.ResponseMetadata {
+	md := &provider.ResponseMetadata{RequestID: requestID(resp.Header)}
+	for name, values := range resp.Header {
+		if !slices.Contains(metadataHeaders, name) && !slices.ContainsFunc(metadataHeaderPrefixes, func(p string) bool { return strings.HasPrefix(name, p) }) {
+			continue
+		}
+		if md.Headers == nil {
+			md.Headers = make(http.Header)
+		}
+		md.Headers[name] = slices.Clone(values)
+	}
+	return md
+}
+
+// ParseAPIError builds a *provider.APIError from an error response
+// body. It understands the OpenAI envelope
+// {"error":{"message","type","param","code"}} and the Anthropic
+// envelope {"type":"error","error":{"type","message"}}, whose
+// validation messages name the parameter as "<path>: <message>".
+func ParseAPIError(status int, body []byte) *provider.APIError {
+	apiErr := &provider.APIError{StatusCode: status, Body: string(body)}
+
+	var env struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(body, &env) != nil || len(env.Error) == 0 {
+		return apiErr
+	}
+	if json.Unmarshal(env.Error, &apiErr.Message) == nil {
+		// Gateways sometimes send {"error":"message"}.
+		return apiErr
+	}
+	var detail struct {
+		Message string          `json:"message"`
+		Type    string          `json:"type"`
+		Param   *string         `json:"param"`
+		Code    json.RawMessage `json:"code"`
+	}
+	if json.Unmarshal(env.Error, &detail) != nil {
+		return apiErr
+	}
+	apiErr.Message = detail.Message
+	apiErr.Type = detail.Type
+	if detail.Param != nil {
+		apiErr.Param = *detail.Param
+	} else if arg, ok := strings.CutPrefix(detail.Message, "Unrecognized request argument supplied: "); ok {
+		// Older OpenAI-compatible servers report unknown parameters only
+		// in the message.
+		apiErr.Param = strings.TrimSpace(arg)
+	} else if path, _, ok := strings.Cut(detail.Message, ": "); ok && paramPath.MatchString(path) {
+		apiErr.Param = path
+	}
+	if len(detail.Code) > 0 && string(detail.Code) != "null" {
+		var code string
+		if json.Unmarshal(detail.Code, &code) != nil {
+			code = string(detail.Code)
+		}
+		apiErr.Code = code
+	}
+	return apiErr
+}
+
+// paramPath matches the JSON paths Anthropic prefixes validation
+// messages with, such as "max_tokens" or "messages.0.content.1.source".
+var paramPath = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z0-9_]+)*$`)
+
+// TagProvider sets the Provider of a *provider.APIError in err's chain
+// to name, if it is not set yet, and returns err.
+func TagProvider(err error, name string) error {
+	var apiErr *provider.APIError
+	if errors.As(err, &apiErr) && apiErr != nil && apiErr.Provider == "" {
+		apiErr.Provider = name
+	}
+	return err
+}
+
+// Snippet returns at most n bytes of b as a string, appending an
+// ellipsis when the input was truncated.
+func Snippet(b []byte, n int) string {
+	if len(b) <= n {
+		return string(b)
+	}
+	return string(b[:n]) + "..."
+}
+
 // DefaultHTTPClient returns the default HTTP client used when none is provided.
 func DefaultHTTPClient() *http.Client {
 	return http.DefaultClient
@@ -0,0 +1,158 @@
+package providerutil
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// ImageURLSupport records whether a provider's chat API accepts image
+// parts referenced by URL. InlineRemoteImages only downloads images for
+// providers listed as false, so provider.ClientOptions.FetchRemoteImages
+// can be set on every client without branching on the provider.
+// Providers that are not listed are assumed to accept URLs.
+var ImageURLSupport = map[string]bool{
+	"openai":    true,
+	"anthropic": false,
+}
+
+// AcceptsImageURLs reports whether providerName accepts image URLs
+// according to ImageURLSupport.
+func AcceptsImageURLs(providerName string) bool {
+	ok, listed := ImageURLSupport[providerName]
+	return ok || !listed
+}
+
+// InlineRemoteImages returns msgs with every image part that only has
+// an ImageURL replaced by its downloaded bytes, unless providerName
+// accepts image URLs. Data URIs are decoded without a download. Each
+// URL is fetched once per call. msgs is never modified.
+//
+// Errors:
+//   - *provider.RemoteImageError naming the message and part index for
+//     failed downloads, disallowed content types, or oversized images.
+func InlineRemoteImages(ctx context.Context, providerName string, msgs []provider.Message, opts provider.RemoteImageOptions) ([]provider.Message, error) {
+	if AcceptsImageURLs(providerName) {
+		return msgs, nil
+	}
+	var (
+		out     []provider.Message
+		fetched map[string]provider.ContentPart
+	)
+	for i, msg := range msgs {
+		var parts []provider.ContentPart
+		for j, p := range msg.Parts {
+			if p.Type != provider.ContentPartImage || len(p.Data) > 0 || p.ImageURL == "" {
+				continue
+			}
+			inline, ok := fetched[p.ImageURL]
+			if !ok {
+				var err error
+				inline, err = fetchImage(ctx, p.ImageURL, opts)
+				if err != nil {
+					return nil, &provider.RemoteImageError{Provider: providerName, Message: i, Part: j, URL: p.ImageURL, Err: err}
+				}
+				if fetched == nil {
+					fetched = make(map[string]provider.ContentPart)
+				}
+				fetched[p.ImageURL] = inline
+			}
+			if parts == nil {
+				parts = slices.Clone(msg.Parts)
+			}
+			parts[j] = inline
+		}
+		if parts == nil {
+			continue
+		}
+		if out == nil {
+			out = slices.Clone(msgs)
+		}
+		out[i].Parts = parts
+	}
+	if out == nil {
+		return msgs, nil
+	}
+	return out, nil
+}
+
+// fetchImage downloads or decodes rawURL into an inline image part.
+func fetchImage(ctx context.Context, rawURL string, opts provider.RemoteImageOptions) (provider.ContentPart, error) {
+	maxBytes := opts.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = provider.DefaultRemoteImageMaxBytes
+	}
+
+	if rest, ok := strings.CutPrefix(rawURL, "data:"); ok {
+		meta, payload, ok := strings.Cut(rest, ",")
+		if !ok || !strings.HasSuffix(meta, ";base64") {
+			return provider.ContentPart{}, errors.New("unsupported data URI")
+		}
+		data, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			return provider.ContentPart{}, fmt.Errorf("decoding data URI: %w", err)
+		}
+		return checkImage(strings.TrimSuffix(meta, ";base64"), data, maxBytes, opts.AllowedTypes)
+	}
+	if !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
+		return provider.ContentPart{}, errors.New("unsupported URL scheme")
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = provider.DefaultRemoteImageTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return provider.ContentPart{}, err
+	}
+	hc := opts.HTTPClient
+	if hc == nil {
+		hc = DefaultHTTPClient()
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return provider.ContentPart{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return provider.ContentPart{}, fmt.Errorf("http status %d", resp.StatusCode)
+	}
+	if resp.ContentLength > maxBytes {
+		return provider.ContentPart{}, fmt.Errorf("image is %d bytes, limit is %d", resp.ContentLength, maxBytes)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return provider.ContentPart{}, err
+	}
+	return checkImage(resp.Header.Get("Content-Type"), data, maxBytes, opts.AllowedTypes)
+}
+
+// checkImage validates data against the size cap and the allowed
+// content types. Missing or generic content types are sniffed.
+func checkImage(contentType string, data []byte, maxBytes int64, allowed []string) (provider.ContentPart, error) {
+	if int64(len(data)) > maxBytes {
+		return provider.ContentPart{}, fmt.Errorf("image exceeds %d bytes", maxBytes)
+	}
+	mt, _, _ := mime.ParseMediaType(contentType)
+	if mt == "" || mt == "application/octet-stream" {
+		mt, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+	if len(allowed) == 0 {
+		allowed = provider.DefaultRemoteImageTypes
+	}
+	if !slices.Contains(allowed, mt) {
+		return provider.ContentPart{}, fmt.Errorf("content type %q is not allowed", mt)
+	}
+	return provider.ContentPart{Type: provider.ContentPartImage, Data: data, MimeType: mt}, nil
+}
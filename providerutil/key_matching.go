@@ -0,0 +1,165 @@
+package providerutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// NormalizeKeys rewrites the object keys of the JSON document raw to
+// the JSON field names of v's type, so that keys in another naming
+// convention decode into the intended fields. Each key is matched
+// exactly, then case-insensitively, then by snake_case, camelCase, and
+// kebab-case equivalence ("user_name", "userName", and "User-Name" are
+// equivalent). Keys matching no field are kept unchanged. Nested
+// objects and arrays are rewritten according to the types of the
+// fields that hold them; values decoded into maps keep their keys but
+// have their values rewritten. Numbers are copied verbatim.
+//
+// Errors:
+//   - An error if raw is not valid JSON.
+//   - An error naming the keys if two keys of one object match the
+//     same field, or if a key matches several fields at its first
+//     matching stage. Keys are never merged.
+func NormalizeKeys(raw []byte, v any) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var data any
+	if err := dec.Decode(&data); err != nil {
+		return nil, err
+	}
+	data, err := normalizeKeys(data, reflect.TypeOf(v), "")
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(data)
+}
+
+func normalizeKeys(data any, t reflect.Type, path string) (any, error) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil {
+		return data, nil
+	}
+	if pt := reflect.PointerTo(t); pt.Implements(jsonUnmarshalerType) || pt.Implements(textUnmarshalerType) {
+		return data, nil
+	}
+
+	switch d := data.(type) {
+	case map[string]any:
+		switch t.Kind() {
+		case reflect.Map:
+			for k, val := range d {
+				nv, err := normalizeKeys(val, t.Elem(), joinPath(path, k))
+				if err != nil {
+					return nil, err
+				}
+				d[k] = nv
+			}
+			return d, nil
+		case reflect.Struct:
+		default:
+			return d, nil
+		}
+		fields := jsonFields(t)
+		keys := make([]string, 0, len(d))
+		for k := range d {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		out := make(map[string]any, len(d))
+		source := make(map[string]string, len(d))
+		for _, k := range keys {
+			name, err := matchField(k, fields)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", joinPath(path, k), err)
+			}
+			if name == "" {
+				name = k
+			}
+			if prev, ok := source[name]; ok {
+				return nil, fmt.Errorf("keys %q and %q both match field %q", joinPath(path, prev), joinPath(path, k), joinPath(path, name))
+			}
+			source[name] = k
+			nv, err := normalizeKeys(d[k], fields[name], joinPath(path, name))
+			if err != nil {
+				return nil, err
+			}
+			out[name] = nv
+		}
+		return out, nil
+	case []any:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return d, nil
+		}
+		for i, e := range d {
+			ne, err := normalizeKeys(e, t.Elem(), path+"[]")
+			if err != nil {
+				return nil, err
+			}
+			d[i] = ne
+		}
+		return d, nil
+	}
+	return data, nil
+}
+
+// matchField returns the field name key matches, or "" if it matches
+// none.
+func matchField(key string, fields map[string]reflect.Type) (string, error) {
+	if _, ok := fields[key]; ok {
+		return key, nil
+	}
+	stages := []func(name string) bool{
+		func(name string) bool { return strings.EqualFold(name, key) },
+		func(name string) bool { return foldConvention(name) == foldConvention(key) },
+	}
+	for _, match := range stages {
+		var found []string
+		for name := range fields {
+			if match(name) {
+				found = append(found, name)
+			}
+		}
+		switch len(found) {
+		case 0:
+			continue
+		case 1:
+			return found[0], nil
+		}
+		sort.Strings(found)
+		return "", fmt.Errorf("matches several fields: %s", strings.Join(found, ", "))
+	}
+	return "", nil
+}
+
+// foldConvention maps a name to a form shared by its snake_case,
+// camelCase, PascalCase, and kebab-case spellings.
+func foldConvention(name string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(name))
+}
+
+// UnmatchedKeys is like UnknownFields but without encoding/json's
+// case-insensitive fallback: it returns the sorted paths of keys in raw
+// that do not exactly match a field name of v's type.
+func UnmatchedKeys(raw []byte, v any) []string {
+	var data any
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	collectFields(data, reflect.TypeOf(v), "", seen, false)
+	return sortedKeys(seen)
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
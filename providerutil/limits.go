@@ -0,0 +1,136 @@
+package providerutil
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// Limits are a client's response limits, resolved from
+// provider.ClientOptions. Zero fields mean no limit.
+type Limits struct {
+	ResponseBytes int64
+	StreamBytes   int64
+	StreamDeltas  int
+}
+
+// NewLimits resolves the response limits of opts, applying the
+// provider defaults to zero values and disabling negative ones.
+func NewLimits(opts provider.ClientOptions) Limits {
+	return Limits{
+		ResponseBytes: resolveLimit(opts.MaxResponseBytes, provider.DefaultMaxResponseBytes),
+		StreamBytes:   resolveLimit(opts.MaxStreamBytes, provider.DefaultMaxStreamBytes),
+		StreamDeltas:  int(resolveLimit(int64(opts.MaxStreamDeltas), provider.DefaultMaxStreamDeltas)),
+	}
+}
+
+func resolveLimit(v, def int64) int64 {
+	switch {
+	case v == 0:
+		return def
+	case v < 0:
+		return 0
+	}
+	return v
+}
+
+// LimitBody replaces the body of a successful resp with one that fails
+// with a *provider.ResponseTooLargeError naming the provider once more
+// than l.ResponseBytes are read. Error responses are left alone, since
+// CheckStatus and ReadJSON read only a bounded snippet of them.
+func (l Limits) LimitBody(resp *http.Response, name string) {
+	if l.ResponseBytes <= 0 || resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return
+	}
+	resp.Body = &limitedBody{ReadCloser: resp.Body, name: name, limit: l.ResponseBytes, remaining: l.ResponseBytes}
+}
+
+type limitedBody struct {
+	io.ReadCloser
+	name      string
+	limit     int64
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// Probe for a byte past the limit; a body of exactly limit
+		// bytes is accepted.
+		var probe [1]byte
+		n, err := b.ReadCloser.Read(probe[:])
+		if n > 0 {
+			return 0, &provider.ResponseTooLargeError{Provider: b.name, Limit: b.limit}
+		}
+		return 0, err
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// LimitStream wraps a language-model stream so that it fails with a
+// *provider.StreamTruncatedError naming the provider, and is closed,
+// once it delivers more than l.StreamDeltas deltas (not counting the
+// final Done delta) or more than
+// l.StreamBytes bytes of text, reasoning, and tool-call arguments. The
+// delta that would exceed a limit is not delivered.
+func (l Limits) LimitStream(stream provider.LanguageModelStream, name string) provider.LanguageModelStream {
+	if l.StreamBytes <= 0 && l.StreamDeltas <= 0 {
+		return stream
+	}
+	return &limitedStream{LanguageModelStream: stream, limits: l, name: name}
+}
+
+type limitedStream struct {
+	provider.LanguageModelStream
+	limits          Limits
+	name            string
+	bytes           int64
+	deltas          int
+	text, reasoning strings.Builder
+	err             error
+}
+
+func (s *limitedStream) Next(ctx context.Context) (*provider.LanguageModelDelta, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	delta, err := s.LanguageModelStream.Next(ctx)
+	if err != nil {
+		return nil, err
+	}
+	size := int64(len(delta.Text) + len(delta.Reasoning))
+	for _, tc := range delta.ToolCalls {
+		size += int64(len(tc.RawArguments))
+	}
+	switch {
+	case !delta.Done && s.limits.StreamDeltas > 0 && s.deltas >= s.limits.StreamDeltas:
+		return nil, s.truncate("deltas", int64(s.limits.StreamDeltas))
+	case s.limits.StreamBytes > 0 && s.bytes+size > s.limits.StreamBytes:
+		return nil, s.truncate("bytes", s.limits.StreamBytes)
+	}
+	s.deltas++
+	s.bytes += size
+	s.text.WriteString(delta.Text)
+	s.reasoning.WriteString(delta.Reasoning)
+	return delta, nil
+}
+
+// truncate records the truncation error and closes the stream.
+func (s *limitedStream) truncate(limit string, max int64) error {
+	s.err = &provider.StreamTruncatedError{
+		Provider:  s.name,
+		Limit:     limit,
+		Max:       max,
+		Text:      s.text.String(),
+		Reasoning: s.reasoning.String(),
+	}
+	s.LanguageModelStream.Close()
+	return s.err
+}
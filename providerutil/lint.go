@@ -0,0 +1,118 @@
+package providerutil
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// misspelledParams maps common misspellings of request parameters to
+// the intended name.
+var misspelledParams = map[string]string{
+	"reasonig_effort":       "reasoning_effort",
+	"reasoning_efort":       "reasoning_effort",
+	"reasoningEffort":       "reasoning_effort",
+	"max_token":             "max_tokens",
+	"maxTokens":             "max_tokens",
+	"max_completion_token":  "max_completion_tokens",
+	"temprature":            "temperature",
+	"temperture":            "temperature",
+	"top-p":                 "top_p",
+	"topP":                  "top_p",
+	"frequency_penality":    "frequency_penalty",
+	"presense_penalty":      "presence_penalty",
+	"responseFormat":        "response_format",
+	"response-format":       "response_format",
+	"stop_sequence":         "stop_sequences",
+	"timestamp_granularity": "timestamp_granularities",
+	"langauge":              "language",
+}
+
+// LintExtraKeys checks pass-through request keys and returns a warning
+// for each key that duplicates a parameter the request already sets
+// from a typed field (typed maps parameter names to field names), or
+// that looks like a misspelling of a known parameter. Keys are checked
+// in sorted order.
+func LintExtraKeys(keys []string, typed map[string]string, known []string) []string {
+	keys = append([]string(nil), keys...)
+	sort.Strings(keys)
+	var warnings []string
+	for _, key := range keys {
+		name := strings.TrimSuffix(key, "[]")
+		if field, ok := typed[name]; ok {
+			warnings = append(warnings, fmt.Sprintf("extra key %q collides with the typed field %s", key, field))
+			continue
+		}
+		if want, ok := misspelledParams[name]; ok {
+			warnings = append(warnings, fmt.Sprintf("extra key %q looks like a misspelling of %q", key, want))
+			continue
+		}
+		if want := nearestParam(name, typed, known); want != "" {
+			warnings = append(warnings, fmt.Sprintf("extra key %q looks like a misspelling of %q", key, want))
+		}
+	}
+	return warnings
+}
+
+// nearestParam returns the typed or known parameter within a small edit
+// distance of name, or "" if name is itself known or nothing is close.
+func nearestParam(name string, typed map[string]string, known []string) string {
+	candidates := append([]string(nil), known...)
+	for k := range typed {
+		candidates = append(candidates, k)
+	}
+	sort.Strings(candidates)
+	best, bestDist := "", 3
+	for _, c := range candidates {
+		if c == name {
+			return ""
+		}
+		limit := 2
+		if len(c) < 6 {
+			limit = 1
+		}
+		if d := editDistance(strings.ToLower(name), c); d <= limit && d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// LintReporter returns the function providers call with lint warnings:
+// onLint if set, otherwise a logger when debug is true, otherwise nil
+// (lint disabled).
+func LintReporter(debug bool, onLint func(source string, warnings []string)) func(source string, warnings []string) {
+	if onLint != nil {
+		return onLint
+	}
+	if !debug {
+		return nil
+	}
+	return func(source string, warnings []string) {
+		for _, w := range warnings {
+			log.Printf("%s: %s", source, w)
+		}
+	}
+}
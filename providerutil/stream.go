@@ -0,0 +1,175 @@
+package providerutil
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// ErrStreamClosed is returned by LineReader.Next after Close.
+var ErrStreamClosed = errors.New("providerutil: stream closed")
+
+// LineReader reads a streaming response body line by line and
+// implements the provider.LanguageModelStream lifetime contract:
+//
+//   - The stream lives as long as the context passed to NewLineReader,
+//     which should be the context the HTTP request was made with.
+//   - The context passed to Next only bounds that call. Cancelling it
+//     returns its error without ending the stream.
+//   - Close releases the connection immediately. The connection is also
+//     released as soon as the body ends or fails, and a LineReader that
+//     is abandoned without Close is released once it is garbage
+//     collected or its context is done, whichever comes first.
+type LineReader struct {
+	st *lineState
+}
+
+// lineState is shared with the read goroutine. It must not reference
+// the LineReader so the reader can be collected when abandoned.
+type lineState struct {
+	lines   chan string
+	stop    chan struct{}
+	once    sync.Once
+	release sync.Once
+	body    io.ReadCloser
+	cancel  context.CancelFunc
+	// err is the terminal read error; it is written before lines is
+	// closed and read only after.
+	err error
+}
+
+// NewLineReader starts reading body in the background. ctx governs the
+// lifetime of the stream and cancel, if non-nil, cancels the context
+// of the underlying HTTP request; it is called by Close. maxLine is the
+// maximum accepted line length in bytes.
+func NewLineReader(ctx context.Context, body io.ReadCloser, cancel context.CancelFunc, maxLine int) *LineReader {
+	st := &lineState{
+		lines:  make(chan string),
+		stop:   make(chan struct{}),
+		body:   body,
+		cancel: cancel,
+	}
+	go st.read(ctx, maxLine)
+	r := &LineReader{st: st}
+	runtime.AddCleanup(r, func(st *lineState) { st.close() }, st)
+	return r
+}
+
+func (st *lineState) read(ctx context.Context, maxLine int) {
+	defer close(st.lines)
+	defer st.releaseBody()
+	scanner := bufio.NewScanner(st.body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLine)
+	for scanner.Scan() {
+		select {
+		case st.lines <- scanner.Text():
+		case <-st.stop:
+			st.err = ErrStreamClosed
+			return
+		case <-ctx.Done():
+			st.err = ctx.Err()
+			return
+		}
+	}
+	switch {
+	case isClosed(st.stop):
+		st.err = ErrStreamClosed
+	case ctx.Err() != nil:
+		st.err = ctx.Err()
+	case scanner.Err() != nil:
+		st.err = scanner.Err()
+	default:
+		st.err = io.EOF
+	}
+}
+
+// close stops the stream and releases the connection.
+func (st *lineState) close() error {
+	st.once.Do(func() { close(st.stop) })
+	return st.releaseBody()
+}
+
+// releaseBody closes the body and cancels the request context without
+// stopping the stream, so lines already read can still be consumed.
+func (st *lineState) releaseBody() error {
+	var err error
+	st.release.Do(func() {
+		if st.cancel != nil {
+			st.cancel()
+		}
+		err = st.body.Close()
+	})
+	return err
+}
+
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// Next returns the next line. It returns io.EOF at the end of the body,
+// the stream context's error if it was cancelled, ctx's error if ctx is
+// done first, and ErrStreamClosed after Close.
+func (r *LineReader) Next(ctx context.Context) (string, error) {
+	if isClosed(r.st.stop) {
+		return "", ErrStreamClosed
+	}
+	select {
+	case line, ok := <-r.st.lines:
+		if !ok {
+			return "", r.st.err
+		}
+		return line, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// Close stops reading and closes the body. It is safe to call more
+// than once.
+func (r *LineReader) Close() error {
+	return r.st.close()
+}
+
+// IncompleteStream maps the error that ended a stream before the
+// provider's completion marker. Errors from Close or from cancelling a
+// context are returned as-is, since the caller ended the stream; io.EOF
+// and read failures become a *provider.IncompleteStreamError naming
+// the provider.
+func IncompleteStream(name string, err error) error {
+	if errors.Is(err, ErrStreamClosed) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return &provider.IncompleteStreamError{Provider: name, Err: err}
+}
+
+// StreamEventError parses an in-band stream error event, such as
+// OpenAI's {"error":{...}} chunk or Anthropic's "error" event, into a
+// *provider.APIError with InStream set. It returns nil if data carries
+// no error envelope.
+func StreamEventError(name string, data []byte) *provider.APIError {
+	var env struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(data, &env) != nil || len(env.Error) == 0 || string(env.Error) == "null" {
+		return nil
+	}
+	apiErr := ParseAPIError(0, data)
+	apiErr.Provider = name
+	apiErr.Body = Snippet(data, 1024)
+	apiErr.InStream = true
+	return apiErr
+}
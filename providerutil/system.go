@@ -0,0 +1,42 @@
+package providerutil
+
+import (
+	"strings"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// JoinSystem joins system prompt parts with sep, or with
+// provider.DefaultSystemSeparator when sep is empty. Empty parts are
+// skipped so they do not produce stray separators.
+func JoinSystem(parts []string, sep string) string {
+	if sep == "" {
+		sep = provider.DefaultSystemSeparator
+	}
+	kept := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p != "" {
+			kept = append(kept, p)
+		}
+	}
+	return strings.Join(kept, sep)
+}
+
+// EnsureConversation checks that msgs contains at least one non-system
+// message. Otherwise it returns a *provider.NoConversationMessageError
+// for providerName or, under provider.SystemOnlyInsertUser, a copy of
+// msgs with provider.SystemOnlyUserMessage appended. msgs is never
+// modified.
+func EnsureConversation(providerName string, msgs []provider.Message, policy provider.SystemOnlyPolicy) ([]provider.Message, error) {
+	for _, msg := range msgs {
+		if msg.Role != "system" {
+			return msgs, nil
+		}
+	}
+	if policy != provider.SystemOnlyInsertUser {
+		return nil, &provider.NoConversationMessageError{Provider: providerName}
+	}
+	out := make([]provider.Message, 0, len(msgs)+1)
+	out = append(out, msgs...)
+	return append(out, provider.Message{Role: "user", Content: provider.SystemOnlyUserMessage}), nil
+}
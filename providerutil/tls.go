@@ -0,0 +1,165 @@
+package providerutil
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// HTTPClientOptions configures the TLS settings of NewHTTPClient, for
+// gateways that use an internal CA or require client certificates.
+type HTTPClientOptions struct {
+	// RootCAs is the pool of CAs trusted for server certificates. Nil
+	// means the system pool.
+	RootCAs *x509.CertPool
+	// CACertPEM holds PEM-encoded CA certificates trusted in addition
+	// to RootCAs (or the system pool).
+	CACertPEM []byte
+	// Certificates are client certificates presented for mutual TLS.
+	Certificates []tls.Certificate
+	// ClientCertPEM and ClientKeyPEM are a PEM-encoded client
+	// certificate (chain) and its private key, added to Certificates.
+	// They must be set together.
+	ClientCertPEM []byte
+	ClientKeyPEM  []byte
+	// ServerNames overrides the TLS server name verified, and sent as
+	// SNI, per host: a request to a host in the map (without port)
+	// expects a certificate for the mapped name. Use it to reach a
+	// gateway by IP address or an alias its certificate does not name.
+	// It only applies to direct connections: HTTPS requests sent through
+	// a proxy (HTTPS_PROXY) verify the request's own host name, so the
+	// gateway's host should be listed in NO_PROXY.
+	ServerNames map[string]string
+	// MinVersion is the minimum TLS version accepted. Zero means TLS
+	// 1.2.
+	MinVersion uint16
+	// InsecureSkipVerify disables server certificate verification,
+	// making connections open to interception. It exists for local
+	// experiments only; never set it in production. Prefer CACertPEM or
+	// ServerNames. NewHTTPClient logs a warning when it is set.
+	InsecureSkipVerify bool
+}
+
+// NewHTTPClient returns an HTTP client with the given TLS settings. Its
+// transport is a clone of http.DefaultTransport, keeping its proxy,
+// timeout, connection-pooling, and HTTP/2 settings. Misconfiguration
+// fails here rather than on the first request.
+//
+// Errors:
+//   - An error if CACertPEM holds no certificate.
+//   - An error if only one of ClientCertPEM and ClientKeyPEM is set,
+//     or if they do not parse or do not match.
+func NewHTTPClient(opts HTTPClientOptions) (*http.Client, error) {
+	cfg := &tls.Config{
+		RootCAs:            opts.RootCAs,
+		Certificates:       append([]tls.Certificate(nil), opts.Certificates...),
+		MinVersion:         opts.MinVersion,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+	if len(opts.CACertPEM) > 0 {
+		pool := opts.RootCAs
+		if pool == nil {
+			var err error
+			if pool, err = x509.SystemCertPool(); err != nil {
+				pool = x509.NewCertPool()
+			}
+		} else {
+			pool = pool.Clone()
+		}
+		if !pool.AppendCertsFromPEM(opts.CACertPEM) {
+			return nil, errors.New("tls: CACertPEM contains no PEM certificate")
+		}
+		cfg.RootCAs = pool
+	}
+	if len(opts.ClientCertPEM) > 0 || len(opts.ClientKeyPEM) > 0 {
+		if len(opts.ClientCertPEM) == 0 || len(opts.ClientKeyPEM) == 0 {
+			return nil, errors.New("tls: ClientCertPEM and ClientKeyPEM must be set together")
+		}
+		cert, err := tls.X509KeyPair(opts.ClientCertPEM, opts.ClientKeyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("tls: client certificate: %w", err)
+		}
+		cfg.Certificates = append(cfg.Certificates, cert)
+	}
+	if opts.InsecureSkipVerify {
+		log.Printf("ai-sdk: WARNING: TLS certificate verification is disabled (InsecureSkipVerify); connections can be intercepted")
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = cfg
+	if len(opts.ServerNames) > 0 {
+		names := make(map[string]string, len(opts.ServerNames))
+		for host, name := range opts.ServerNames {
+			names[host] = name
+		}
+		t.DialTLSContext = serverNameDialer(t, names)
+	}
+	return &http.Client{Transport: t}, nil
+}
+
+// serverNameDialer returns a DialTLSContext function for t that
+// verifies the server name names maps the dialed host to. It dials with
+// the timeouts of http.DefaultTransport. The transport does not call it
+// for requests through a proxy, which tunnel with CONNECT and run TLS
+// over the tunnel itself.
+func serverNameDialer(t *http.Transport, names map[string]string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		cfg := t.TLSClientConfig.Clone()
+		cfg.ServerName = host
+		if name, ok := names[host]; ok {
+			cfg.ServerName = name
+		}
+		raw, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		conn := tls.Client(raw, cfg)
+		if err := conn.HandshakeContext(ctx); err != nil {
+			raw.Close()
+			return nil, err
+		}
+		return conn, nil
+	}
+}
+
+// ClientHTTPClient returns the HTTP client a provider client should
+// use: opts.HTTPClient if set, otherwise a client built by
+// NewHTTPClient from opts.CACertPEM, ClientCertPEM, and ClientKeyPEM
+// if any is set, otherwise DefaultHTTPClient.
+//
+// Errors:
+//   - An error if the TLS fields are set together with HTTPClient,
+//     which they would not apply to.
+//   - Any error returned by NewHTTPClient.
+func ClientHTTPClient(opts provider.ClientOptions) (provider.HTTPClient, error) {
+	tlsSet := len(opts.CACertPEM) > 0 || len(opts.ClientCertPEM) > 0 || len(opts.ClientKeyPEM) > 0
+	switch {
+	case opts.HTTPClient != nil && tlsSet:
+		return nil, errors.New("CACertPEM, ClientCertPEM, and ClientKeyPEM cannot be combined with HTTPClient; configure its transport instead")
+	case opts.HTTPClient != nil:
+		return opts.HTTPClient, nil
+	case tlsSet:
+		return NewHTTPClient(HTTPClientOptions{
+			CACertPEM:     opts.CACertPEM,
+			ClientCertPEM: opts.ClientCertPEM,
+			ClientKeyPEM:  opts.ClientKeyPEM,
+		})
+	}
+	return DefaultHTTPClient(), nil
+}
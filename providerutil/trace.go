@@ -0,0 +1,45 @@
+package providerutil
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// SetTraceHeader sets provider.TraceHeader on h to the trace ID carried
+// by ctx. A header already configured by the caller is kept.
+func SetTraceHeader(ctx context.Context, h http.Header) {
+	id := provider.TraceIDFromContext(ctx)
+	if id == "" || h.Get(provider.TraceHeader) != "" {
+		return
+	}
+	h.Set(provider.TraceHeader, id)
+}
+
+// SetContextHeaders sets the headers carried by ctx (see
+// provider.WithHeaders) on h, replacing values of the same name. The
+// protected headers, which the provider sets itself, are skipped.
+func SetContextHeaders(ctx context.Context, h http.Header, protected ...string) {
+	for k, vs := range provider.HeadersFromContext(ctx) {
+		if isProtectedHeader(k, protected) {
+			continue
+		}
+		h.Del(k)
+		for _, v := range vs {
+			if v != "" {
+				h.Add(k, v)
+			}
+		}
+	}
+}
+
+func isProtectedHeader(name string, protected []string) bool {
+	for _, p := range protected {
+		if strings.EqualFold(name, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package providerutil
+
+import (
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ReadJSONReporting is like ReadJSON but, when report is non-nil, also
+// passes the paths of response keys that v has no field for to report
+// (see UnknownFields). With a nil report it behaves exactly like
+// ReadJSON and adds no overhead.
+func ReadJSONReporting(resp *http.Response, v any, report func(fields []string)) error {
+	if report == nil {
+		return ReadJSON(resp, v)
+	}
+	raw, err := ReadJSONWithBody(resp, v)
+	if err != nil {
+		return err
+	}
+	ReportUnknownFields(raw, v, report)
+	return nil
+}
+
+// ReportUnknownFields calls report with the unknown fields of raw
+// relative to v, if report is non-nil and any were found.
+func ReportUnknownFields(raw []byte, v any, report func(fields []string)) {
+	if report == nil {
+		return
+	}
+	if fields := UnknownFields(raw, v); len(fields) > 0 {
+		report(fields)
+	}
+}
+
+// UnknownFields returns the sorted, de-duplicated paths of keys in the
+// JSON document raw that would be dropped when decoding into v. Paths
+// use dots for nested objects and "[]" for array elements, for example
+// "choices[].message.annotations". Values decoded into maps, interfaces,
+// json.RawMessage, or types with custom unmarshalers are not inspected.
+func UnknownFields(raw []byte, v any) []string {
+	var data any
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	collectFields(data, reflect.TypeOf(v), "", seen, true)
+	return sortedKeys(seen)
+}
+
+// sortedKeys returns the keys of seen in order, or nil if it is empty.
+func sortedKeys(seen map[string]bool) []string {
+	if len(seen) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(seen))
+	for p := range seen {
+		out = append(out, p)
+	}
+	sort.Strings(out)
+	return out
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// collectFields records in seen the paths of keys in data that have no
+// field in t, matching names case-insensitively as encoding/json does
+// when fold is set and exactly otherwise.
+func collectFields(data any, t reflect.Type, path string, seen map[string]bool, fold bool) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil {
+		return
+	}
+	if pt := reflect.PointerTo(t); pt.Implements(jsonUnmarshalerType) || pt.Implements(textUnmarshalerType) {
+		return
+	}
+
+	switch d := data.(type) {
+	case map[string]any:
+		if t.Kind() != reflect.Struct {
+			return
+		}
+		fields := jsonFields(t)
+		for k, val := range d {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			ft, ok := fields[k]
+			if !ok && fold {
+				// encoding/json falls back to a case-insensitive match.
+				for name, f := range fields {
+					if strings.EqualFold(name, k) {
+						ft, ok = f, true
+						break
+					}
+				}
+			}
+			if !ok {
+				seen[p] = true
+				continue
+			}
+			collectFields(val, ft, p, seen, fold)
+		}
+	case []any:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return
+		}
+		for _, e := range d {
+			collectFields(e, t.Elem(), path+"[]", seen, fold)
+		}
+	}
+}
+
+// jsonFields maps JSON key names to field types for struct type t,
+// following encoding/json rules for tags and embedded structs.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for k, v := range jsonFields(ft) {
+					if _, ok := fields[k]; !ok {
+						fields[k] = v
+					}
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
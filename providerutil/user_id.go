@@ -0,0 +1,20 @@
+package providerutil
+
+import (
+	"context"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// UserID resolves the end-user ID sent with a request: id when set,
+// else the ID carried by ctx (see provider.WithUserID), else fallback,
+// typically ClientOptions.DefaultUserID.
+func UserID(ctx context.Context, id, fallback string) string {
+	if id != "" {
+		return id
+	}
+	if id := provider.UserIDFromContext(ctx); id != "" {
+		return id
+	}
+	return fallback
+}
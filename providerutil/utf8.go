@@ -0,0 +1,185 @@
+package providerutil
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// utf8Repairs counts the runes UTF8Joiner reassembled across deltas.
+var utf8Repairs atomic.Int64
+
+// UTF8Repairs returns the number of multi-byte characters that arrived
+// split across stream deltas and were reassembled, across all streams
+// in the process. A non-zero value points at a provider or proxy that
+// chunks responses on byte rather than character boundaries.
+func UTF8Repairs() int64 {
+	return utf8Repairs.Load()
+}
+
+// UTF8Joiner repairs streamed text whose multi-byte UTF-8 characters
+// are split across deltas. Join holds back an incomplete character at
+// the end of a delta and prepends it to the next one, so every piece
+// it returns is valid UTF-8. Use one UTF8Joiner per text channel (for
+// example text and reasoning) of a stream. The zero value is ready to
+// use.
+type UTF8Joiner struct {
+	pending []byte
+}
+
+// Join returns the valid UTF-8 text of s, with the bytes held back from
+// the previous call prepended and a trailing incomplete character held
+// back for the next. Bytes that cannot start or continue a character
+// are replaced with U+FFFD.
+func (j *UTF8Joiner) Join(s string) string {
+	if len(j.pending) == 0 && utf8.ValidString(s) {
+		return s
+	}
+	repaired := len(j.pending) > 0
+	b := append(j.pending, s...)
+	j.pending = nil
+	if n := incompleteSuffix(b); n > 0 {
+		j.pending = append([]byte(nil), b[len(b)-n:]...)
+		b = b[:len(b)-n]
+	}
+	if repaired && len(b) > 0 {
+		utf8Repairs.Add(1)
+	}
+	return strings.ToValidUTF8(string(b), "�")
+}
+
+// Flush returns U+FFFD if an incomplete character is still held back,
+// which happens when a stream ends mid-character, and "" otherwise.
+func (j *UTF8Joiner) Flush() string {
+	if len(j.pending) == 0 {
+		return ""
+	}
+	j.pending = nil
+	return "�"
+}
+
+// incompleteSuffix returns the length of the incomplete character at the
+// end of b, or 0 if b ends on a character boundary.
+func incompleteSuffix(b []byte) int {
+	for n := 1; n <= utf8.UTFMax-1 && n <= len(b); n++ {
+		c := b[len(b)-n]
+		if utf8.RuneStart(c) {
+			if c >= utf8.RuneSelf && !utf8.FullRune(b[len(b)-n:]) {
+				return n
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// RawString is a JSON string that keeps invalid UTF-8 bytes instead of
+// replacing them with U+FFFD as encoding/json does, so a UTF8Joiner can
+// reassemble characters split across stream chunks. Use it for the text
+// fields of streamed deltas.
+type RawString string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *RawString) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if utf8.Valid(data) {
+		var v string
+		if err := json.Unmarshal(data, &v); err != nil {
+			return err
+		}
+		*s = RawString(v)
+		return nil
+	}
+	v, err := unquoteRaw(data)
+	if err != nil {
+		return err
+	}
+	*s = RawString(v)
+	return nil
+}
+
+var errBadString = errors.New("providerutil: invalid JSON string")
+
+// unquoteRaw decodes the JSON string literal data, copying bytes outside
+// escapes through unchanged.
+func unquoteRaw(data []byte) (string, error) {
+	if len(data) < 2 || data[0] != '"' || data[len(data)-1] != '"' {
+		return "", errBadString
+	}
+	data = data[1 : len(data)-1]
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if c != '\\' {
+			out = append(out, c)
+			continue
+		}
+		i++
+		if i == len(data) {
+			return "", errBadString
+		}
+		switch data[i] {
+		case '"', '\\', '/':
+			out = append(out, data[i])
+		case 'b':
+			out = append(out, '\b')
+		case 'f':
+			out = append(out, '\f')
+		case 'n':
+			out = append(out, '\n')
+		case 'r':
+			out = append(out, '\r')
+		case 't':
+			out = append(out, '\t')
+		case 'u':
+			r, ok := hex4(data[i+1:])
+			if !ok {
+				return "", errBadString
+			}
+			i += 4
+			if utf16.IsSurrogate(r) {
+				r2, ok := rune(utf8.RuneError), false
+				if i+6 < len(data) && data[i+1] == '\\' && data[i+2] == 'u' {
+					r2, ok = hex4(data[i+3:])
+				}
+				if dec := utf16.DecodeRune(r, r2); ok && dec != utf8.RuneError {
+					r = dec
+					i += 6
+				} else {
+					r = utf8.RuneError
+				}
+			}
+			out = utf8.AppendRune(out, r)
+		default:
+			return "", errBadString
+		}
+	}
+	return string(out), nil
+}
+
+// hex4 parses the four hex digits at the start of b.
+func hex4(b []byte) (rune, bool) {
+	if len(b) < 4 {
+		return 0, false
+	}
+	var r rune
+	for _, c := range b[:4] {
+		switch {
+		case '0' <= c && c <= '9':
+			c -= '0'
+		case 'a' <= c && c <= 'f':
+			c = c - 'a' + 10
+		case 'A' <= c && c <= 'F':
+			c = c - 'A' + 10
+		default:
+			return 0, false
+		}
+		r = r<<4 | rune(c)
+	}
+	return r, true
+}
@@ -0,0 +1,187 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/anthropic"
+	"github.com/ncecere/ai-sdk/openai"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// reasoningCase describes how one provider maps reasoning. Each case
+// replays recorded fixtures from testdata/reasoning.
+type reasoningCase struct {
+	name      string
+	newModel  func(opts provider.ClientOptions) (LanguageModel, error)
+	generate  string
+	stream    string
+	checkBody func(t *testing.T, body map[string]any)
+}
+
+var reasoningCases = []reasoningCase{
+	{
+		name: "openai",
+		newModel: func(opts provider.ClientOptions) (LanguageModel, error) {
+			c, err := openai.NewClient(opts)
+			if err != nil {
+				return nil, err
+			}
+			return c.ChatModel("reasoner"), nil
+		},
+		generate: "reasoning/openai_generate.json",
+		stream:   "reasoning/openai_stream.sse",
+		checkBody: func(t *testing.T, body map[string]any) {
+			if body["reasoning_effort"] != "high" {
+				t.Fatalf("expected reasoning_effort high, got %v", body["reasoning_effort"])
+			}
+		},
+	},
+	{
+		name: "anthropic",
+		newModel: func(opts provider.ClientOptions) (LanguageModel, error) {
+			c, err := anthropic.NewClient(opts)
+			if err != nil {
+				return nil, err
+			}
+			return c.ChatModel("claude-test"), nil
+		},
+		generate: "reasoning/anthropic_generate.json",
+		stream:   "reasoning/anthropic_stream.sse",
+		checkBody: func(t *testing.T, body map[string]any) {
+			thinking, _ := body["thinking"].(map[string]any)
+			if thinking["type"] != "enabled" || thinking["budget_tokens"] != float64(16384) {
+				t.Fatalf("unexpected thinking block: %v", body["thinking"])
+			}
+			if maxTokens, _ := body["max_tokens"].(float64); maxTokens <= 16384 {
+				t.Fatalf("expected max_tokens above the thinking budget, got %v", body["max_tokens"])
+			}
+		},
+	},
+}
+
+// fixtureServer serves the fixture at the given path under testdata and
+// records request bodies.
+func fixtureServer(t *testing.T, fixture string, bodies *[]map[string]any) *httptest.Server {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", filepath.FromSlash(fixture)))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		*bodies = append(*bodies, body)
+		if strings.HasSuffix(fixture, ".sse") {
+			w.Header().Set("Content-Type", "text/event-stream")
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.Write(data)
+	}))
+}
+
+func TestReasoning_GenerateMatrix(t *testing.T) {
+	for _, tc := range reasoningCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var bodies []map[string]any
+			ts := fixtureServer(t, tc.generate, &bodies)
+			defer ts.Close()
+
+			model, err := tc.newModel(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+			if err != nil {
+				t.Fatalf("NewClient error: %v", err)
+			}
+
+			res, err := GenerateText(context.Background(), GenerateTextRequest{
+				Model:     model,
+				Messages:  []Message{UserMessage("What is 6 times 7?")},
+				Reasoning: &ReasoningOptions{Effort: "high"},
+			})
+			if err != nil {
+				t.Fatalf("GenerateText error: %v", err)
+			}
+			if res.Text != "42" || res.Reasoning != "6 times 7 is 42." {
+				t.Fatalf("unexpected response: text=%q reasoning=%q", res.Text, res.Reasoning)
+			}
+			if len(bodies) != 1 {
+				t.Fatalf("expected 1 request, got %d", len(bodies))
+			}
+			tc.checkBody(t, bodies[0])
+		})
+	}
+}
+
+func TestReasoning_StreamMatrix(t *testing.T) {
+	for _, tc := range reasoningCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var bodies []map[string]any
+			ts := fixtureServer(t, tc.stream, &bodies)
+			defer ts.Close()
+
+			model, err := tc.newModel(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+			if err != nil {
+				t.Fatalf("NewClient error: %v", err)
+			}
+
+			stream, err := StreamText(context.Background(), GenerateTextRequest{
+				Model:     model,
+				Messages:  []Message{UserMessage("What is 6 times 7?")},
+				Reasoning: &ReasoningOptions{Effort: "high"},
+			})
+			if err != nil {
+				t.Fatalf("StreamText error: %v", err)
+			}
+
+			rec := httptest.NewRecorder()
+			if err := WriteTextStreamAsSSE(context.Background(), rec, stream); err != nil {
+				t.Fatalf("WriteTextStreamAsSSE error: %v", err)
+			}
+
+			want := "event: reasoning\ndata: 6 times 7 \n\n" +
+				"event: reasoning\ndata: is 42.\n\n" +
+				"data: 42\n\n" +
+				"data: [DONE]\n\n"
+			if got := rec.Body.String(); got != want {
+				t.Fatalf("unexpected SSE output:\n%q\nwant:\n%q", got, want)
+			}
+			tc.checkBody(t, bodies[0])
+		})
+	}
+}
+
+func TestReasoning_NotEchoedIntoHistory(t *testing.T) {
+	for _, tc := range reasoningCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var bodies []map[string]any
+			ts := fixtureServer(t, tc.generate, &bodies)
+			defer ts.Close()
+
+			model, err := tc.newModel(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+			if err != nil {
+				t.Fatalf("NewClient error: %v", err)
+			}
+
+			session := NewSession(model, "")
+			if _, err := session.Ask(context.Background(), "What is 6 times 7?"); err != nil {
+				t.Fatalf("first Ask error: %v", err)
+			}
+			if _, err := session.Ask(context.Background(), "And doubled?"); err != nil {
+				t.Fatalf("second Ask error: %v", err)
+			}
+
+			raw, _ := json.Marshal(bodies[1])
+			if strings.Contains(string(raw), "6 times 7 is 42.") {
+				t.Fatalf("reasoning was echoed back to the provider: %s", raw)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// Provider factories are registered explicitly by applications (there
+// is no registration in init functions), typically once at startup:
+//
+//	registry.RegisterProviderFactory(openai.Factory{})
+//	registry.RegisterProviderFactory(anthropic.Factory{})
+//	lm, err := registry.LanguageModelFromRef("anthropic:claude-3-5-sonnet-latest", provider.ClientOptions{})
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]provider.Factory)
+)
+
+// DuplicateProviderError is returned when a provider factory is
+// registered under a name that is already in use.
+type DuplicateProviderError struct {
+	// Name is the provider name that was already registered.
+	Name string
+}
+
+func (e *DuplicateProviderError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("registry: provider %q already registered", e.Name)
+}
+
+// UnknownProviderError is returned when no provider factory is
+// registered under the requested name.
+type UnknownProviderError struct {
+	// Name is the provider name that was requested.
+	Name string
+}
+
+func (e *UnknownProviderError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("registry: unknown provider %q", e.Name)
+}
+
+// RegisterProviderFactory registers f under f.Name(). Registering a
+// second factory with the same name returns a *DuplicateProviderError;
+// call UnregisterProviderFactory first to replace a factory.
+func RegisterProviderFactory(f provider.Factory) error {
+	if f == nil {
+		return fmt.Errorf("registry: nil provider factory")
+	}
+	name := f.Name()
+	if name == "" {
+		return fmt.Errorf("registry: provider factory has empty name")
+	}
+
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	if _, ok := factories[name]; ok {
+		return &DuplicateProviderError{Name: name}
+	}
+	factories[name] = f
+	return nil
+}
+
+// UnregisterProviderFactory removes the factory registered under name,
+// if any.
+func UnregisterProviderFactory(name string) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	delete(factories, name)
+}
+
+// ProviderFactory returns the factory registered under name or an
+// *UnknownProviderError.
+func ProviderFactory(name string) (provider.Factory, error) {
+	factoriesMu.RLock()
+	f, ok := factories[name]
+	factoriesMu.RUnlock()
+	if !ok {
+		return nil, &UnknownProviderError{Name: name}
+	}
+	return f, nil
+}
+
+// ProviderFactoryNames returns the names of all registered factories in
+// sorted order.
+func ProviderFactoryNames() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SplitModelRef splits a "provider:model" reference into its provider
+// name and model ID. It returns ok=false if ref has no provider prefix.
+func SplitModelRef(ref string) (providerName, modelID string, ok bool) {
+	providerName, modelID, ok = strings.Cut(ref, ":")
+	if !ok || providerName == "" || modelID == "" {
+		return "", "", false
+	}
+	return providerName, modelID, true
+}
+
+// NewProviderClient creates a client for the named provider using its
+// registered factory.
+func NewProviderClient(name string, opts provider.ClientOptions) (provider.Provider, error) {
+	f, err := ProviderFactory(name)
+	if err != nil {
+		return nil, err
+	}
+	return f.NewClient(opts)
+}
+
+// LanguageModelFromRef resolves a "provider:model" reference to a
+// language model using the registered provider factories. A new client
+// is created from opts for each call, so callers that resolve many
+// models should create the client once with NewProviderClient.
+//
+// Errors:
+//   - *NoSuchModelError if ref has no provider prefix.
+//   - *UnknownProviderError if no factory is registered for the prefix.
+//   - Any error returned by the factory.
+func LanguageModelFromRef(ref string, opts provider.ClientOptions) (provider.LanguageModel, error) {
+	name, modelID, ok := SplitModelRef(ref)
+	if !ok {
+		return nil, &NoSuchModelError{Name: ref, Kind: "language"}
+	}
+	client, err := NewProviderClient(name, opts)
+	if err != nil {
+		return nil, err
+	}
+	return client.ChatModel(modelID), nil
+}
+
+// EmbeddingModelFromRef resolves a "provider:model" reference to an
+// embedding model using the registered provider factories.
+//
+// Errors:
+//   - *NoSuchModelError if ref has no provider prefix or the provider
+//     does not expose embedding models.
+//   - *UnknownProviderError if no factory is registered for the prefix.
+//   - Any error returned by the factory.
+func EmbeddingModelFromRef(ref string, opts provider.ClientOptions) (provider.EmbeddingModel, error) {
+	name, modelID, ok := SplitModelRef(ref)
+	if !ok {
+		return nil, &NoSuchModelError{Name: ref, Kind: "embedding"}
+	}
+	client, err := NewProviderClient(name, opts)
+	if err != nil {
+		return nil, err
+	}
+	ep, ok := client.(provider.EmbeddingProvider)
+	if !ok {
+		return nil, &NoSuchModelError{Name: ref, Kind: "embedding"}
+	}
+	return ep.EmbeddingModel(modelID), nil
+}
@@ -0,0 +1,75 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+type stubModel struct{ id string }
+
+func (m *stubModel) Generate(ctx context.Context, req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
+	return &provider.LanguageModelResponse{Text: m.id}, nil
+}
+
+func (m *stubModel) Stream(ctx context.Context, req *provider.LanguageModelRequest) (provider.LanguageModelStream, error) {
+	return nil, errors.New("not implemented")
+}
+
+type stubClient struct{}
+
+func (stubClient) ChatModel(model string) provider.LanguageModel { return &stubModel{id: model} }
+
+type stubFactory struct{ name string }
+
+func (f stubFactory) Name() string { return f.name }
+
+func (f stubFactory) NewClient(opts provider.ClientOptions) (provider.Provider, error) {
+	return stubClient{}, nil
+}
+
+func TestRegisterProviderFactory_DuplicateAndUnknown(t *testing.T) {
+	const name = "stub-dup"
+	defer UnregisterProviderFactory(name)
+
+	if err := RegisterProviderFactory(stubFactory{name: name}); err != nil {
+		t.Fatalf("first registration failed: %v", err)
+	}
+	var dupErr *DuplicateProviderError
+	if err := RegisterProviderFactory(stubFactory{name: name}); !errors.As(err, &dupErr) {
+		t.Fatalf("expected DuplicateProviderError, got %v", err)
+	}
+
+	var unknownErr *UnknownProviderError
+	if _, err := LanguageModelFromRef("missing:model", provider.ClientOptions{}); !errors.As(err, &unknownErr) {
+		t.Fatalf("expected UnknownProviderError, got %v", err)
+	}
+}
+
+func TestLanguageModelFromRef_UsesRegisteredFactory(t *testing.T) {
+	const name = "stub-ref"
+	defer UnregisterProviderFactory(name)
+
+	if err := RegisterProviderFactory(stubFactory{name: name}); err != nil {
+		t.Fatalf("registration failed: %v", err)
+	}
+
+	lm, err := LanguageModelFromRef(name+":model-x", provider.ClientOptions{})
+	if err != nil {
+		t.Fatalf("LanguageModelFromRef error: %v", err)
+	}
+	res, err := lm.Generate(context.Background(), &provider.LanguageModelRequest{})
+	if err != nil || res.Text != "model-x" {
+		t.Fatalf("unexpected result: %+v, %v", res, err)
+	}
+
+	var noModel *NoSuchModelError
+	if _, err := LanguageModelFromRef("no-prefix", provider.ClientOptions{}); !errors.As(err, &noModel) {
+		t.Fatalf("expected NoSuchModelError, got %v", err)
+	}
+	if _, err := EmbeddingModelFromRef(name+":embed", provider.ClientOptions{}); !errors.As(err, &noModel) {
+		t.Fatalf("expected NoSuchModelError for missing embedding support, got %v", err)
+	}
+}
@@ -0,0 +1,220 @@
+package ai
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// RerankLargeOptions configures RerankLarge.
+type RerankLargeOptions struct {
+	// ShardSize is the maximum number of documents sent per rerank
+	// call. Defaults to 1000; lower it for providers with smaller
+	// per-request limits.
+	ShardSize int
+	// MaxConcurrency bounds the number of shards scored in parallel.
+	// Defaults to 4.
+	MaxConcurrency int
+	// FinalTopK limits the number of merged results returned. Zero
+	// returns every scored document.
+	FinalTopK int
+	// SecondPass, if true, reranks the top merged candidates together in
+	// a single call so their scores are directly comparable. Scores from
+	// different shards are not guaranteed to be on the same scale.
+	SecondPass bool
+	// SecondPassCandidates is the number of merged candidates rescored
+	// by the second pass. Defaults to FinalTopK, or ShardSize when
+	// FinalTopK is zero, and is capped at ShardSize.
+	SecondPassCandidates int
+	// UserID is forwarded to every rerank call.
+	UserID string
+}
+
+// RerankLarge reranks docs against query with any number of documents by
+// splitting them into shards of at most opts.ShardSize, scoring up to
+// opts.MaxConcurrency shards at a time, and merging the results by
+// descending score. Result indexes refer to docs. Ties are broken by
+// the lower original index so the order is deterministic.
+//
+// A failed shard does not fail the query: the results of the shards
+// that succeeded are returned together with a *PartialRerankError
+// describing the failures. The same applies to a failed second pass,
+// in which case the merged first-pass order is returned.
+//
+// Errors:
+//   - ErrMissingModel if model is nil.
+//   - *PartialRerankError if some, but not all, rerank calls failed.
+//   - The first shard's error if every shard failed, or the context
+//     error if ctx is canceled.
+func RerankLarge(ctx context.Context, model RerankModel, query string, docs []string, opts RerankLargeOptions) (RerankResponse, error) {
+	if model == nil {
+		return RerankResponse{}, ErrMissingModel
+	}
+	if opts.ShardSize <= 0 {
+		opts.ShardSize = 1000
+	}
+	if opts.MaxConcurrency <= 0 {
+		opts.MaxConcurrency = 4
+	}
+	candidates := 0
+	if opts.SecondPass {
+		candidates = opts.SecondPassCandidates
+		if candidates <= 0 {
+			candidates = opts.FinalTopK
+		}
+		if candidates <= 0 || candidates > opts.ShardSize {
+			candidates = opts.ShardSize
+		}
+	}
+
+	// Each shard only needs to return as many results as can make it
+	// into the merged top set.
+	shardTopK := opts.FinalTopK
+	if candidates > shardTopK {
+		shardTopK = candidates
+	}
+
+	type shardResult struct {
+		results []RerankResult
+		err     error
+	}
+	numShards := (len(docs) + opts.ShardSize - 1) / opts.ShardSize
+	shards := make([]shardResult, numShards)
+	sem := make(chan struct{}, opts.MaxConcurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < numShards; i++ {
+		start := i * opts.ShardSize
+		end := min(start+opts.ShardSize, len(docs))
+		wg.Add(1)
+		go func(i, start, end int) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				shards[i].err = ctx.Err()
+				return
+			}
+			topK := shardTopK
+			if topK >= end-start {
+				topK = 0
+			}
+			shards[i].results, shards[i].err = rerankSubset(ctx, model, query, docs, start, end, topK, opts.UserID)
+		}(i, start, end)
+	}
+	wg.Wait()
+
+	var merged []RerankResult
+	var partial PartialRerankError
+	for i, s := range shards {
+		if s.err != nil {
+			start := i * opts.ShardSize
+			partial.Failed = append(partial.Failed, RerankShardError{
+				Start: start,
+				End:   min(start+opts.ShardSize, len(docs)),
+				Err:   s.err,
+			})
+			continue
+		}
+		merged = append(merged, s.results...)
+	}
+	if numShards > 0 && len(partial.Failed) == numShards {
+		if err := ctx.Err(); err != nil {
+			return RerankResponse{}, err
+		}
+		return RerankResponse{}, partial.Failed[0].Err
+	}
+	sortRerankResults(merged)
+
+	if opts.SecondPass && len(merged) > 1 {
+		n := min(candidates, len(merged))
+		rescored, err := rerankCandidates(ctx, model, query, docs, merged[:n], opts.UserID)
+		if err != nil {
+			partial.SecondPass = err
+		} else {
+			merged = append(rescored, merged[n:]...)
+		}
+	}
+
+	if opts.FinalTopK > 0 && len(merged) > opts.FinalTopK {
+		merged = merged[:opts.FinalTopK]
+	}
+	res := RerankResponse{Results: merged}
+	if len(partial.Failed) > 0 || partial.SecondPass != nil {
+		partial.Shards = numShards
+		return res, &partial
+	}
+	return res, nil
+}
+
+// rerankSubset scores docs[start:end] and maps the result indexes back
+// onto docs. Results with out-of-range indexes are dropped.
+func rerankSubset(ctx context.Context, model RerankModel, query string, docs []string, start, end, topK int, userID string) ([]RerankResult, error) {
+	res, err := model.Generate(ctx, &provider.RerankRequest{
+		Query:     query,
+		Documents: docs[start:end],
+		TopK:      topK,
+		UserID:    userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]RerankResult, 0, len(res.Results))
+	for _, r := range res.Results {
+		if r.Index < 0 || r.Index >= end-start {
+			continue
+		}
+		r.Index += start
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// rerankCandidates rescores the given candidates in a single call and
+// returns them sorted by their new scores, with indexes into docs.
+// Candidates the model omits keep their relative order after the
+// rescored ones.
+func rerankCandidates(ctx context.Context, model RerankModel, query string, docs []string, candidates []RerankResult, userID string) ([]RerankResult, error) {
+	subset := make([]string, len(candidates))
+	for i, c := range candidates {
+		subset[i] = docs[c.Index]
+	}
+	res, err := model.Generate(ctx, &provider.RerankRequest{
+		Query:     query,
+		Documents: subset,
+		UserID:    userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make([]bool, len(candidates))
+	out := make([]RerankResult, 0, len(candidates))
+	for _, r := range res.Results {
+		if r.Index < 0 || r.Index >= len(candidates) || seen[r.Index] {
+			continue
+		}
+		seen[r.Index] = true
+		out = append(out, RerankResult{Index: candidates[r.Index].Index, Score: r.Score})
+	}
+	sortRerankResults(out)
+	for i, c := range candidates {
+		if !seen[i] {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// sortRerankResults orders results by descending score, breaking ties
+// by ascending index.
+func sortRerankResults(results []RerankResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Index < results[j].Index
+	})
+}
@@ -0,0 +1,125 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// scoringRerankModel scores "doc-N" as N plus the bias configured for
+// the call's first document, simulating scores that are not comparable
+// across calls. Calls containing a document in fail return an error.
+type scoringRerankModel struct {
+	mu          sync.Mutex
+	calls       int
+	inFlight    int
+	maxInFlight int
+	fail        map[string]bool
+	biasFirst   map[string]float64
+}
+
+func (m *scoringRerankModel) Generate(ctx context.Context, req *provider.RerankRequest) (*provider.RerankResponse, error) {
+	m.mu.Lock()
+	m.calls++
+	m.inFlight++
+	if m.inFlight > m.maxInFlight {
+		m.maxInFlight = m.inFlight
+	}
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		m.inFlight--
+		m.mu.Unlock()
+	}()
+
+	bias := m.biasFirst[req.Documents[0]]
+	var res provider.RerankResponse
+	for i, d := range req.Documents {
+		if m.fail[d] {
+			return nil, errors.New("shard unavailable")
+		}
+		n, _ := strconv.Atoi(strings.TrimPrefix(d, "doc-"))
+		res.Results = append(res.Results, provider.RerankResult{Index: i, Score: float64(n) + bias})
+	}
+	sortRerankResults(res.Results)
+	if req.TopK > 0 && len(res.Results) > req.TopK {
+		res.Results = res.Results[:req.TopK]
+	}
+	return &res, nil
+}
+
+func rerankDocs(n int) []string {
+	docs := make([]string, n)
+	for i := range docs {
+		docs[i] = "doc-" + strconv.Itoa(i)
+	}
+	return docs
+}
+
+func TestRerankLarge_MergesShardsWithOriginalIndexes(t *testing.T) {
+	model := &scoringRerankModel{}
+	docs := rerankDocs(25)
+	docs[3] = "doc-24" // tie with the last document
+
+	res, err := RerankLarge(context.Background(), model, "q", docs, RerankLargeOptions{ShardSize: 10, MaxConcurrency: 2, FinalTopK: 4})
+	if err != nil {
+		t.Fatalf("RerankLarge error: %v", err)
+	}
+	var got []int
+	for _, r := range res.Results {
+		got = append(got, r.Index)
+	}
+	if want := []int{3, 24, 23, 22}; !slices.Equal(got, want) {
+		t.Fatalf("unexpected order: got %v want %v", got, want)
+	}
+	if model.calls != 3 || model.maxInFlight > 2 {
+		t.Fatalf("expected 3 calls with at most 2 in flight, got %d calls, %d in flight", model.calls, model.maxInFlight)
+	}
+}
+
+func TestRerankLarge_PartialFailureDegrades(t *testing.T) {
+	model := &scoringRerankModel{fail: map[string]bool{"doc-15": true}}
+	res, err := RerankLarge(context.Background(), model, "q", rerankDocs(25), RerankLargeOptions{ShardSize: 10})
+
+	var partial *PartialRerankError
+	if !errors.As(err, &partial) || partial.Shards != 3 || len(partial.Failed) != 1 || partial.Failed[0].Start != 10 || partial.Failed[0].End != 20 {
+		t.Fatalf("expected partial rerank error for shard [10,20), got %v", err)
+	}
+	if len(res.Results) != 15 || res.Results[0].Index != 24 {
+		t.Fatalf("expected results from the surviving shards, got %+v", res.Results)
+	}
+
+	model.fail = map[string]bool{"doc-0": true, "doc-10": true, "doc-20": true}
+	if _, err := RerankLarge(context.Background(), model, "q", rerankDocs(25), RerankLargeOptions{ShardSize: 10}); err == nil || errors.As(err, &partial) {
+		t.Fatalf("expected the shard error when every shard fails, got %v", err)
+	}
+}
+
+func TestRerankLarge_SecondPassRescoresCandidates(t *testing.T) {
+	// The first shard's scores are inflated by 15, so without a second
+	// pass its best documents would outrank the true top results.
+	model := &scoringRerankModel{biasFirst: map[string]float64{"doc-0": 15}}
+	docs := rerankDocs(20)
+
+	res, err := RerankLarge(context.Background(), model, "q", docs, RerankLargeOptions{ShardSize: 10, FinalTopK: 2})
+	if err != nil {
+		t.Fatalf("RerankLarge error: %v", err)
+	}
+	if res.Results[0].Index != 9 {
+		t.Fatalf("expected the biased shard to win without a second pass, got %+v", res.Results)
+	}
+
+	res, err = RerankLarge(context.Background(), model, "q", docs, RerankLargeOptions{ShardSize: 10, FinalTopK: 2, SecondPass: true, SecondPassCandidates: 10})
+	if err != nil {
+		t.Fatalf("RerankLarge error: %v", err)
+	}
+	if res.Results[0].Index != 19 || res.Results[1].Index != 18 {
+		t.Fatalf("expected second pass to restore comparable scores, got %+v", res.Results)
+	}
+}
@@ -0,0 +1,102 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/anthropic"
+	"github.com/ncecere/ai-sdk/middleware"
+	"github.com/ncecere/ai-sdk/openai"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+func TestGenerateText_ResponseMetadata(t *testing.T) {
+	cases := []struct {
+		name      string
+		idHeader  string
+		rateLimit string
+		body      string
+		newModel  func(opts provider.ClientOptions) (LanguageModel, error)
+	}{
+		{
+			name: "openai", idHeader: "x-request-id", rateLimit: "x-ratelimit-remaining-requests",
+			body: `{"choices":[{"finish_reason":"stop","message":{"role":"assistant","content":"ok"}}]}`,
+			newModel: func(opts provider.ClientOptions) (LanguageModel, error) {
+				c, err := openai.NewClient(opts)
+				if err != nil {
+					return nil, err
+				}
+				return c.ChatModel("gpt-test"), nil
+			},
+		},
+		{
+			name: "anthropic", idHeader: "request-id", rateLimit: "anthropic-ratelimit-requests-remaining",
+			body: `{"content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn"}`,
+			newModel: func(opts provider.ClientOptions) (LanguageModel, error) {
+				c, err := anthropic.NewClient(opts)
+				if err != nil {
+					return nil, err
+				}
+				return c.ChatModel("claude-test"), nil
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fail := false
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set(tc.idHeader, "req_abc")
+				w.Header().Set(tc.rateLimit, "99")
+				w.Header().Set("Set-Cookie", "session=secret")
+				w.Header().Set("Content-Type", "application/json")
+				if fail {
+					w.WriteHeader(http.StatusBadRequest)
+					fmt.Fprint(w, `{"error":{"type":"invalid_request_error","message":"bad"}}`)
+					return
+				}
+				fmt.Fprint(w, tc.body)
+			}))
+			defer ts.Close()
+
+			base, err := tc.newModel(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+			if err != nil {
+				t.Fatalf("NewClient error: %v", err)
+			}
+			logger := &recordingLogger{}
+			model := middleware.WrapLanguageModel(base,
+				middleware.LoggingLanguageModel(middleware.LoggingOptions{Logger: logger, LogResponse: true, LogErrors: true}),
+			)
+			req := GenerateTextRequest{Model: model, Messages: []Message{UserMessage("hi")}}
+
+			res, err := GenerateText(context.Background(), req)
+			if err != nil {
+				t.Fatalf("GenerateText error: %v", err)
+			}
+			md := res.Metadata
+			if md == nil || md.RequestID != "req_abc" || md.Headers.Get(tc.rateLimit) != "99" {
+				t.Fatalf("unexpected metadata %+v", md)
+			}
+			if md.Headers.Get("Set-Cookie") != "" || md.Headers.Get("Content-Type") != "" {
+				t.Fatalf("metadata copied uninteresting headers: %v", md.Headers)
+			}
+			if !strings.Contains(logger.lines[len(logger.lines)-1], "request_id=req_abc") {
+				t.Fatalf("success log lacks the request ID: %v", logger.lines)
+			}
+
+			fail = true
+			_, err = GenerateText(context.Background(), req)
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.RequestID != "req_abc" {
+				t.Fatalf("expected an APIError with the request ID, got %v", err)
+			}
+			if !strings.Contains(logger.lines[len(logger.lines)-1], "request_id=req_abc") {
+				t.Fatalf("error log lacks the request ID: %v", logger.lines)
+			}
+		})
+	}
+}
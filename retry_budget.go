@@ -0,0 +1,26 @@
+package ai
+
+import (
+	"context"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// WithRetryBudget returns a context that caps the total number of
+// retries performed by all retrying components (such as
+// middleware.RetryLanguageModel, possibly stacked several times) for
+// calls made with it. This prevents retry amplification when retries
+// are composed across layers during an outage.
+//
+// If ctx already carries a budget it is returned unchanged.
+func WithRetryBudget(ctx context.Context, n int) context.Context {
+	return provider.WithRetryBudget(ctx, n)
+}
+
+// RetryBudgetRemaining reports the retries left in ctx's budget and the
+// number of retries skipped because it was exhausted. It returns
+// remaining=-1 when ctx carries no budget.
+func RetryBudgetRemaining(ctx context.Context) (remaining, skipped int) {
+	b := provider.RetryBudgetFromContext(ctx)
+	return b.Remaining(), b.Skipped()
+}
@@ -0,0 +1,121 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// SizeRule routes prompts of up to MaxEstimatedTokens tokens to Model.
+// See RouteBySize.
+type SizeRule struct {
+	// MaxEstimatedTokens is the largest estimated prompt size the rule
+	// accepts. Zero marks the final catch-all rule.
+	MaxEstimatedTokens int
+	// Model serves the prompts the rule accepts.
+	Model LanguageModel
+	// Name identifies Model in ResponseMetadata.Model, for example by
+	// its registry name.
+	Name string
+}
+
+// RouteBySize returns a LanguageModel that forwards each call to the
+// model of the first rule whose MaxEstimatedTokens is at least the
+// estimated size of the prompt, so small prompts can go to a cheap fast
+// model and large ones to a large-context model. The prompt size is
+// estimated from the messages and tool definitions as for CountTokens.
+//
+// Rules must be sorted by strictly increasing MaxEstimatedTokens and end
+// with a catch-all rule whose MaxEstimatedTokens is zero. Register the
+// result in a registry under a logical name such as "chat:auto" to hide
+// the routing from callers.
+//
+// Generate records the chosen rule's Name in the response's
+// ResponseMetadata.Model, and Stream in that of the final delta.
+//
+// Errors:
+//   - *InvalidArgumentError if rules is empty, a rule has no Model, the
+//     limits are not strictly increasing, or the last rule is not a
+//     catch-all.
+func RouteBySize(rules []SizeRule) (LanguageModel, error) {
+	if len(rules) == 0 {
+		return nil, &InvalidArgumentError{Parameter: "rules", Value: rules, Message: "must not be empty"}
+	}
+	last := len(rules) - 1
+	for i, rule := range rules {
+		if rule.Model == nil {
+			return nil, &InvalidArgumentError{Parameter: fmt.Sprintf("rules[%d].Model", i), Value: nil, Message: "must not be nil"}
+		}
+		switch {
+		case i == last && rule.MaxEstimatedTokens != 0:
+			return nil, &InvalidArgumentError{Parameter: fmt.Sprintf("rules[%d].MaxEstimatedTokens", i), Value: rule.MaxEstimatedTokens, Message: "the last rule must be a catch-all with MaxEstimatedTokens 0"}
+		case i < last && rule.MaxEstimatedTokens <= 0:
+			return nil, &InvalidArgumentError{Parameter: fmt.Sprintf("rules[%d].MaxEstimatedTokens", i), Value: rule.MaxEstimatedTokens, Message: "only the last rule may be a catch-all"}
+		case i > 0 && i < last && rule.MaxEstimatedTokens <= rules[i-1].MaxEstimatedTokens:
+			return nil, &InvalidArgumentError{Parameter: fmt.Sprintf("rules[%d].MaxEstimatedTokens", i), Value: rule.MaxEstimatedTokens, Message: fmt.Sprintf("must be greater than the previous rule's %d", rules[i-1].MaxEstimatedTokens)}
+		}
+	}
+	return &sizeRouter{rules: append([]SizeRule(nil), rules...)}, nil
+}
+
+type sizeRouter struct {
+	rules []SizeRule
+}
+
+// route returns the first rule accepting req.
+func (r *sizeRouter) route(req *provider.LanguageModelRequest) SizeRule {
+	n := estimatePromptTokens(req.Messages, req.Tools)
+	for _, rule := range r.rules[:len(r.rules)-1] {
+		if n <= rule.MaxEstimatedTokens {
+			return rule
+		}
+	}
+	return r.rules[len(r.rules)-1]
+}
+
+func (r *sizeRouter) Generate(ctx context.Context, req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
+	rule := r.route(req)
+	res, err := rule.Model.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	metadata := provider.ResponseMetadata{}
+	if res.Metadata != nil {
+		metadata = *res.Metadata
+	}
+	metadata.Model = rule.Name
+	res.Metadata = &metadata
+	return res, nil
+}
+
+func (r *sizeRouter) Stream(ctx context.Context, req *provider.LanguageModelRequest) (provider.LanguageModelStream, error) {
+	rule := r.route(req)
+	stream, err := rule.Model.Stream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &routedStream{LanguageModelStream: stream, name: rule.Name}, nil
+}
+
+// routedStream records the chosen rule's Name as Metadata.Model on the
+// final delta.
+type routedStream struct {
+	provider.LanguageModelStream
+	name string
+}
+
+func (s *routedStream) Next(ctx context.Context) (*provider.LanguageModelDelta, error) {
+	delta, err := s.LanguageModelStream.Next(ctx)
+	if err != nil || !delta.Done {
+		return delta, err
+	}
+	metadata := provider.ResponseMetadata{}
+	if delta.Metadata != nil {
+		metadata = *delta.Metadata
+	}
+	metadata.Model = s.name
+	out := *delta
+	out.Metadata = &metadata
+	return &out, nil
+}
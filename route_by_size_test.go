@@ -0,0 +1,90 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/provider"
+	"github.com/ncecere/ai-sdk/registry"
+)
+
+func TestRouteBySize_RoutesThroughRegistry(t *testing.T) {
+	small := &scriptedModel{responses: []*provider.LanguageModelResponse{{Text: "small"}}}
+	large := &scriptedModel{responses: []*provider.LanguageModelResponse{{Text: "large"}}}
+	router, err := RouteBySize([]SizeRule{
+		{MaxEstimatedTokens: 100, Model: small, Name: "chat:small"},
+		{Model: large, Name: "chat:large"},
+	})
+	if err != nil {
+		t.Fatalf("RouteBySize error: %v", err)
+	}
+	reg := registry.NewInMemoryRegistry()
+	reg.RegisterLanguageModel("chat:auto", router)
+
+	for _, tc := range []struct {
+		prompt, text, model string
+	}{
+		{"hi", "small", "chat:small"},
+		{strings.Repeat("word ", 200), "large", "chat:large"},
+	} {
+		res, err := GenerateTextWithRegistry(context.Background(), reg, "chat:auto", GenerateTextRequest{
+			Messages: []Message{UserMessage(tc.prompt)},
+		})
+		if err != nil {
+			t.Fatalf("GenerateTextWithRegistry error: %v", err)
+		}
+		if res.Text != tc.text || res.Metadata == nil || res.Metadata.Model != tc.model {
+			t.Fatalf("expected %q from %s, got %q with metadata %+v", tc.text, tc.model, res.Text, res.Metadata)
+		}
+	}
+	if len(small.requests) != 1 || len(large.requests) != 1 {
+		t.Fatalf("expected one call per model, got small=%d large=%d", len(small.requests), len(large.requests))
+	}
+}
+
+func TestRouteBySize_StreamRecordsModel(t *testing.T) {
+	small := &streamOnlyModel{stream: &scriptStream{texts: []string{"sm", "all"}}}
+	large := &streamOnlyModel{stream: &scriptStream{texts: []string{"large"}}}
+	router, err := RouteBySize([]SizeRule{
+		{MaxEstimatedTokens: 100, Model: small, Name: "chat:small"},
+		{Model: large, Name: "chat:large"},
+	})
+	if err != nil {
+		t.Fatalf("RouteBySize error: %v", err)
+	}
+
+	stream, err := StreamText(context.Background(), GenerateTextRequest{Model: router, Messages: []Message{UserMessage("hi")}})
+	if err != nil {
+		t.Fatalf("StreamText error: %v", err)
+	}
+	res, err := CollectStream(context.Background(), stream)
+	if err != nil {
+		t.Fatalf("CollectStream error: %v", err)
+	}
+	if res.Text != "small" || res.Metadata == nil || res.Metadata.Model != "chat:small" {
+		t.Fatalf("expected small from chat:small, got %q with metadata %+v", res.Text, res.Metadata)
+	}
+}
+
+func TestRouteBySize_ValidatesRules(t *testing.T) {
+	m := &scriptedModel{}
+	cases := map[string][]SizeRule{
+		"empty":           nil,
+		"nil model":       {{MaxEstimatedTokens: 10}, {Model: m}},
+		"no catch-all":    {{MaxEstimatedTokens: 10, Model: m}, {MaxEstimatedTokens: 20, Model: m}},
+		"early catch-all": {{Model: m}, {Model: m}},
+		"unsorted":        {{MaxEstimatedTokens: 20, Model: m}, {MaxEstimatedTokens: 10, Model: m}, {Model: m}},
+		"overlapping":     {{MaxEstimatedTokens: 10, Model: m}, {MaxEstimatedTokens: 10, Model: m}, {Model: m}},
+	}
+	for name, rules := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := RouteBySize(rules)
+			var invalid *InvalidArgumentError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("expected *InvalidArgumentError, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// Session owns a chat message history and handles the bookkeeping
+// needed between GenerateText calls when the model requests tools.
+//
+// It is a thinner alternative to the agent package for applications
+// that execute tools themselves but want the message protocol handled:
+//
+//	s := NewSession(model, "You are a helpful assistant.")
+//	s.Tools = tools
+//	res, err := s.Ask(ctx, "What's the weather in Paris?")
+//	for _, tc := range s.PendingToolCalls() {
+//	    result := runTool(tc)
+//	    _ = s.ProvideToolResult(tc.ID, result)
+//	}
+//	res, err = s.Continue(ctx)
+//
+// Session is not safe for concurrent use. Its state can be persisted
+// with State and restored with RestoreSession.
+type Session struct {
+	// Model is the language model used for every call.
+	Model LanguageModel
+	// Tools defines tools the model may call during generation.
+	Tools []ToolDefinition
+	// Settings are optional call settings applied to every request.
+	Settings *CallSettings
+
+	messages []Message
+	pending  []ToolCall
+}
+
+// SessionState is the serializable state of a Session.
+type SessionState struct {
+	// Messages is the full chat history.
+	Messages []Message `json:"messages"`
+	// PendingToolCalls are tool calls that have not yet received a result.
+	PendingToolCalls []ToolCall `json:"pending_tool_calls,omitempty"`
+}
+
+// NewSession creates a Session for the given model. If system is
+// non-empty it is added as the first message of the history.
+func NewSession(model LanguageModel, system string) *Session {
+	s := &Session{Model: model}
+	if system != "" {
+		s.messages = append(s.messages, SystemMessage(system))
+	}
+	return s
+}
+
+// RestoreSession creates a Session for the given model from a
+// previously captured SessionState.
+func RestoreSession(model LanguageModel, state SessionState) *Session {
+	return &Session{
+		Model:    model,
+		messages: append([]Message(nil), state.Messages...),
+		pending:  append([]ToolCall(nil), state.PendingToolCalls...),
+	}
+}
+
+// State returns a copy of the session state suitable for persistence.
+func (s *Session) State() SessionState {
+	return SessionState{
+		Messages:         append([]Message(nil), s.messages...),
+		PendingToolCalls: append([]ToolCall(nil), s.pending...),
+	}
+}
+
+// Messages returns a copy of the current message history.
+func (s *Session) Messages() []Message {
+	return append([]Message(nil), s.messages...)
+}
+
+// PendingToolCalls returns the tool calls from the last response that
+// have not yet received a result via ProvideToolResult. Calls the model
+// returned without an ID are given one, as in GenerateTextWithTools.
+func (s *Session) PendingToolCalls() []ToolCall {
+	return append([]ToolCall(nil), s.pending...)
+}
+
+// Ask appends a user message to the history and calls the model.
+//
+// Errors:
+//   - ErrPendingToolCalls if tool calls from the previous response are
+//     still awaiting results.
+//   - Any error returned by GenerateText. On error the user message is
+//     not kept in the history.
+func (s *Session) Ask(ctx context.Context, userText string) (GenerateTextResponse, error) {
+	if len(s.pending) > 0 {
+		return GenerateTextResponse{}, ErrPendingToolCalls
+	}
+	n := len(s.messages)
+	s.messages = append(s.messages, UserMessage(userText))
+	res, err := s.generate(ctx)
+	if err != nil {
+		s.messages = s.messages[:n]
+		return GenerateTextResponse{}, err
+	}
+	return res, nil
+}
+
+// Continue calls the model with the current history, typically after
+// all pending tool calls have been answered with ProvideToolResult.
+//
+// Errors:
+//   - ErrPendingToolCalls if some tool calls are still awaiting results.
+//   - Any error returned by GenerateText.
+func (s *Session) Continue(ctx context.Context) (GenerateTextResponse, error) {
+	if len(s.pending) > 0 {
+		return GenerateTextResponse{}, ErrPendingToolCalls
+	}
+	return s.generate(ctx)
+}
+
+// ProvideToolResult records the result of the pending tool call with
+// the given ID. The result is converted with NewToolResultMessage and
+// appended to the history as a tool message that the next Ask or
+// Continue call includes.
+//
+// Errors:
+//   - InvalidArgumentError if no pending tool call has the given ID.
+//   - Any error returned while JSON-encoding result.
+func (s *Session) ProvideToolResult(id string, result any) error {
+	idx := -1
+	for i, tc := range s.pending {
+		if tc.ID == id {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return &InvalidArgumentError{Parameter: "id", Value: id, Message: "no pending tool call with this ID"}
+	}
+	msg, err := NewToolResultMessage(s.pending[idx], result)
+	if err != nil {
+		return err
+	}
+
+	s.messages = append(s.messages, msg)
+	s.pending = append(s.pending[:idx], s.pending[idx+1:]...)
+	return nil
+}
+
+func (s *Session) generate(ctx context.Context) (GenerateTextResponse, error) {
+	req := NewGenerateTextRequest(s.Model, s.messages, s.Settings)
+	req.Tools = s.Tools
+
+	res, err := GenerateText(ctx, req)
+	if err != nil {
+		return GenerateTextResponse{}, err
+	}
+
+	// Calls without a provider ID get "call_<step>_<index>", as in
+	// GenerateTextWithTools, so that each can be answered. The step is
+	// the number of assistant turns so far, which a restored session
+	// keeps.
+	step := 1
+	for _, m := range s.messages {
+		if m.Role == RoleAssistant {
+			step++
+		}
+	}
+	res.ToolCalls = slices.Clone(res.ToolCalls)
+	for i := range res.ToolCalls {
+		if res.ToolCalls[i].ID == "" {
+			res.ToolCalls[i].ID = fmt.Sprintf("call_%d_%d", step, i)
+		}
+	}
+	if res.Text != "" || len(res.ToolCalls) > 0 {
+		msg := AssistantToolCallMessage(res.Text, res.ToolCalls)
+		msg.ReasoningBlocks = res.ReasoningBlocks
+		s.messages = append(s.messages, msg)
+	}
+	s.pending = append([]ToolCall(nil), res.ToolCalls...)
+	return res, nil
+}
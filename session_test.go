@@ -0,0 +1,113 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// scriptedModel is a fake LanguageModel that returns the scripted
+// responses in order and records every request it receives.
+type scriptedModel struct {
+	responses []*provider.LanguageModelResponse
+	requests  []*provider.LanguageModelRequest
+}
+
+func (m *scriptedModel) Generate(ctx context.Context, req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
+	m.requests = append(m.requests, req)
+	if len(m.responses) == 0 {
+		return nil, errors.New("scriptedModel: no more responses")
+	}
+	res := m.responses[0]
+	m.responses = m.responses[1:]
+	return res, nil
+}
+
+func (m *scriptedModel) Stream(ctx context.Context, req *provider.LanguageModelRequest) (provider.LanguageModelStream, error) {
+	return nil, fmt.Errorf("scriptedModel: %w", ErrStreamingUnsupported)
+}
+
+func TestSession_ToolCallBookkeeping(t *testing.T) {
+	ctx := context.Background()
+	model := &scriptedModel{responses: []*provider.LanguageModelResponse{
+		{ToolCalls: []ToolCall{{ID: "call-1", Name: "weather", RawArguments: []byte(`{"city":"Paris"}`)}}},
+		{Text: "It is sunny in Paris."},
+	}}
+
+	s := NewSession(model, "be brief")
+	res, err := s.Ask(ctx, "weather in Paris?")
+	if err != nil {
+		t.Fatalf("Ask error: %v", err)
+	}
+	if len(res.ToolCalls) != 1 || len(s.PendingToolCalls()) != 1 {
+		t.Fatalf("expected one pending tool call, got %+v", s.PendingToolCalls())
+	}
+
+	if _, err := s.Continue(ctx); !errors.Is(err, ErrPendingToolCalls) {
+		t.Fatalf("expected ErrPendingToolCalls, got %v", err)
+	}
+	if err := s.ProvideToolResult("unknown", "x"); err == nil {
+		t.Fatalf("expected error for unknown tool call ID")
+	}
+	if err := s.ProvideToolResult("call-1", map[string]string{"sky": "clear"}); err != nil {
+		t.Fatalf("ProvideToolResult error: %v", err)
+	}
+
+	res, err = s.Continue(ctx)
+	if err != nil {
+		t.Fatalf("Continue error: %v", err)
+	}
+	if res.Text != "It is sunny in Paris." {
+		t.Fatalf("unexpected text: %q", res.Text)
+	}
+
+	sent := model.requests[1].Messages
+	if len(sent) != 4 {
+		t.Fatalf("expected 4 messages in second request, got %+v", sent)
+	}
+	if sent[2].Role != RoleAssistant || len(sent[2].ToolCalls) != 1 || sent[2].ToolCalls[0].ID != "call-1" || sent[3].Role != RoleTool || !strings.Contains(sent[3].Content, `"call-1"`) {
+		t.Fatalf("unexpected message shape: %+v", sent)
+	}
+
+	restored := RestoreSession(model, s.State())
+	if got := len(restored.Messages()); got != 5 {
+		t.Fatalf("expected 5 restored messages, got %d", got)
+	}
+}
+
+func TestSession_AssignsMissingToolCallIDs(t *testing.T) {
+	ctx := context.Background()
+	model := &scriptedModel{responses: []*provider.LanguageModelResponse{
+		{ToolCalls: []ToolCall{
+			{Name: "weather", RawArguments: []byte(`{"city":"Paris"}`)},
+			{Name: "weather", RawArguments: []byte(`{"city":"Oslo"}`)},
+		}},
+		{Text: "Sunny and cold."},
+	}}
+
+	s := NewSession(model, "")
+	res, err := s.Ask(ctx, "weather in Paris and Oslo?")
+	if err != nil {
+		t.Fatalf("Ask error: %v", err)
+	}
+	if len(res.ToolCalls) != 2 || res.ToolCalls[0].ID != "call_1_0" || res.ToolCalls[1].ID != "call_1_1" {
+		t.Fatalf("unexpected tool call IDs %+v", res.ToolCalls)
+	}
+	for _, tc := range res.ToolCalls {
+		if err := s.ProvideToolResult(tc.ID, "ok"); err != nil {
+			t.Fatalf("ProvideToolResult(%q) error: %v", tc.ID, err)
+		}
+	}
+	if _, err := s.Continue(ctx); err != nil {
+		t.Fatalf("Continue error: %v", err)
+	}
+
+	sent := model.requests[1].Messages
+	if len(sent) != 4 || sent[1].ToolCalls[1].ID != "call_1_1" || sent[2].ToolCallID != "call_1_0" || sent[3].ToolCallID != "call_1_1" {
+		t.Fatalf("unexpected message shape: %+v", sent)
+	}
+}
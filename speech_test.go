@@ -0,0 +1,91 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/openai"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// speechServer answers audio/speech requests without a Content-Type
+// header, like some compatible TTS servers, and records the requested
+// response_format.
+func speechServer(t *testing.T, format *string) func(model string) SpeechModel {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			ResponseFormat string `json:"response_format"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		*format = body.ResponseFormat
+		w.Header()["Content-Type"] = nil
+		w.Write([]byte("audio"))
+	}))
+	t.Cleanup(srv.Close)
+	client, err := openai.NewClient(provider.ClientOptions{BaseURL: srv.URL, APIKey: "test", HTTPClient: srv.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	return client.SpeechModel
+}
+
+func TestGenerateSpeech_FormatMapping(t *testing.T) {
+	var sent string
+	speechModel := speechServer(t, &sent)
+	cases := []struct {
+		model string
+		// format is a plain string: SpeechRequest.Format still accepts
+		// string values.
+		format   string
+		wantSent string
+		wantMime string
+	}{
+		{"tts-1", FormatMP3, "mp3", "audio/mpeg"},
+		{"tts-1", FormatOpus, "opus", "audio/opus"},
+		{"tts-1-hd", FormatAAC, "aac", "audio/aac"},
+		{"gpt-4o-mini-tts", FormatFLAC, "flac", "audio/flac"},
+		{"gpt-4o-mini-tts", FormatPCM, "pcm", "audio/pcm"},
+		{"tts-1", "", "", "audio/mpeg"},
+		{"playai-tts", FormatWAV, "wav", "audio/wav"},
+		{"playai-tts", "", "", "audio/wav"},
+	}
+	for _, tc := range cases {
+		res, err := GenerateSpeech(context.Background(), SpeechRequest{Model: speechModel(tc.model), Input: "hi", Format: tc.format})
+		if err != nil {
+			t.Fatalf("%s/%q: GenerateSpeech error: %v", tc.model, tc.format, err)
+		}
+		if sent != tc.wantSent || res.MimeType != tc.wantMime {
+			t.Fatalf("%s/%q: sent %q with MimeType %q, want %q and %q", tc.model, tc.format, sent, res.MimeType, tc.wantSent, tc.wantMime)
+		}
+	}
+}
+
+func TestGenerateSpeech_RejectsUnsupportedFormat(t *testing.T) {
+	sent := "unset"
+	speechModel := speechServer(t, &sent)
+
+	_, err := GenerateSpeech(context.Background(), SpeechRequest{Model: speechModel("playai-tts"), Input: "hi", Format: FormatOpus})
+	var argErr *InvalidArgumentError
+	if !errors.As(err, &argErr) || argErr.Parameter != "Format" {
+		t.Fatalf("expected InvalidArgumentError for Format, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "supported formats: wav, mp3, flac, ogg, mulaw") {
+		t.Fatalf("expected the supported formats to be listed, got %q", err.Error())
+	}
+	if sent != "unset" {
+		t.Fatalf("expected no provider call for an invalid format")
+	}
+
+	// Models without known capabilities pass any format through.
+	if _, err := GenerateSpeech(context.Background(), SpeechRequest{Model: speechModel("custom-tts"), Input: "hi", Format: "ogg"}); err != nil || sent != "ogg" {
+		t.Fatalf("expected unknown models to skip validation, got %v (sent %q)", err, sent)
+	}
+}
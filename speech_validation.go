@@ -0,0 +1,34 @@
+package ai
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// validate checks Format against the capabilities reported by the
+// model, if any.
+func (req SpeechRequest) validate() error {
+	if req.Format == "" {
+		return nil
+	}
+	reporter, ok := req.Model.(provider.SpeechCapabilityReporter)
+	if !ok {
+		return nil
+	}
+	caps, ok := reporter.SpeechCapabilities()
+	if !ok || len(caps.Formats) == 0 || slices.Contains(caps.Formats, req.Format) {
+		return nil
+	}
+	supported := make([]string, len(caps.Formats))
+	for i, f := range caps.Formats {
+		supported[i] = string(f)
+	}
+	return &InvalidArgumentError{
+		Parameter: "Format",
+		Value:     req.Format,
+		Message:   fmt.Sprintf("unsupported format %q; supported formats: %s", req.Format, strings.Join(supported, ", ")),
+	}
+}
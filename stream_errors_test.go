@@ -0,0 +1,166 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/anthropic"
+	"github.com/ncecere/ai-sdk/openai"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// streamErrorCase replays streams that fail mid-response, recorded in
+// testdata/streamerrors: <name>_error.sse ends with the provider's
+// in-band error event, <name>_cut.sse with a connection cut before the
+// completion marker.
+type streamErrorCase struct {
+	name      string
+	newModel  func(opts provider.ClientOptions) (LanguageModel, error)
+	errorType string
+	message   string
+}
+
+var streamErrorCases = []streamErrorCase{
+	{
+		name: "openai",
+		newModel: func(opts provider.ClientOptions) (LanguageModel, error) {
+			c, err := openai.NewClient(opts)
+			if err != nil {
+				return nil, err
+			}
+			return c.ChatModel("gpt-test"), nil
+		},
+		errorType: "server_error",
+		message:   "The server had an error while processing your request.",
+	},
+	{
+		name: "anthropic",
+		newModel: func(opts provider.ClientOptions) (LanguageModel, error) {
+			c, err := anthropic.NewClient(opts)
+			if err != nil {
+				return nil, err
+			}
+			return c.ChatModel("claude-test"), nil
+		},
+		errorType: "overloaded_error",
+		message:   "Overloaded",
+	},
+}
+
+// streamFixture starts a stream replaying fixture and returns it with a
+// func stopping the fixture server.
+func streamFixture(t *testing.T, tc streamErrorCase, fixture string) (TextStream, func()) {
+	t.Helper()
+	var bodies []map[string]any
+	ts := fixtureServer(t, fixture, &bodies)
+	model, err := tc.newModel(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	stream, err := StreamText(context.Background(), GenerateTextRequest{
+		Model:    model,
+		Messages: []Message{UserMessage("Say hello world.")},
+	})
+	if err != nil {
+		t.Fatalf("StreamText error: %v", err)
+	}
+	return stream, ts.Close
+}
+
+// drain reads stream until it fails or is done.
+func drain(stream TextStream) (string, error) {
+	var text strings.Builder
+	for {
+		delta, err := stream.Next(context.Background())
+		if err != nil {
+			return text.String(), err
+		}
+		text.WriteString(delta.Text)
+		if delta.Done {
+			return text.String(), nil
+		}
+	}
+}
+
+func TestStreamErrors_InBandErrorEvent(t *testing.T) {
+	for _, tc := range streamErrorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stream, done := streamFixture(t, tc, "streamerrors/"+tc.name+"_error.sse")
+			defer done()
+			defer stream.Close()
+
+			text, err := drain(stream)
+			if text != "Hello" {
+				t.Fatalf("expected text before the error, got %q", text)
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected *APIError, got %T: %v", err, err)
+			}
+			if !apiErr.InStream || apiErr.Provider != tc.name || apiErr.Type != tc.errorType || apiErr.Message != tc.message {
+				t.Fatalf("unexpected error %+v", apiErr)
+			}
+			if _, again := stream.Next(context.Background()); again != err {
+				t.Fatalf("expected the error to be returned again, got %v", again)
+			}
+		})
+	}
+}
+
+func TestStreamErrors_ConnectionCut(t *testing.T) {
+	for _, tc := range streamErrorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stream, done := streamFixture(t, tc, "streamerrors/"+tc.name+"_cut.sse")
+			defer done()
+			defer stream.Close()
+
+			text, err := drain(stream)
+			if text != "Hello wor" {
+				t.Fatalf("expected text before the cut, got %q", text)
+			}
+			var incomplete *IncompleteStreamError
+			if !errors.As(err, &incomplete) || incomplete.Provider != tc.name || !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Fatalf("expected *IncompleteStreamError, got %T: %v", err, err)
+			}
+		})
+	}
+}
+
+func TestStreamErrors_SSEReportsReason(t *testing.T) {
+	for _, tc := range streamErrorCases {
+		for fixture, reason := range map[string]string{"error": StreamErrorFailed, "cut": StreamErrorInterrupted} {
+			t.Run(tc.name+"_"+fixture, func(t *testing.T) {
+				stream, done := streamFixture(t, tc, "streamerrors/"+tc.name+"_"+fixture+".sse")
+				defer done()
+
+				rec := httptest.NewRecorder()
+				err := WriteTextStreamAsSSE(context.Background(), rec, stream)
+				var partial *PartialResponseError
+				if !errors.As(err, &partial) || !strings.HasPrefix(partial.Partial.Text, "Hello") {
+					t.Fatalf("expected *PartialResponseError, got %T: %v", err, err)
+				}
+
+				body := rec.Body.String()
+				if strings.Contains(body, "[DONE]") {
+					t.Fatalf("failed stream must not send [DONE]:\n%s", body)
+				}
+				_, event, ok := strings.Cut(body, "event: error\ndata: ")
+				if !ok {
+					t.Fatalf("expected an error event:\n%s", body)
+				}
+				var ev StreamErrorEvent
+				if err := json.Unmarshal([]byte(strings.TrimSpace(event)), &ev); err != nil {
+					t.Fatalf("decode error event: %v", err)
+				}
+				if ev.Reason != reason || ev.Message == "" {
+					t.Fatalf("unexpected error event %+v", ev)
+				}
+			})
+		}
+	}
+}
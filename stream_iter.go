@@ -0,0 +1,68 @@
+package ai
+
+import (
+	"context"
+	"iter"
+)
+
+// StreamToChannel reads stream on a new goroutine and sends each delta,
+// including the final (Done) delta, on the returned delta channel,
+// which is closed afterwards, for use in select loops. If the stream
+// fails, the error is sent on the error channel before both channels
+// are closed; the error channel is buffered, so it may be read after
+// the delta channel is drained.
+//
+// To stop early, cancel ctx: the goroutine stops, closes the stream,
+// and reports ctx's error. The stream is closed in every case once the
+// goroutine exits.
+func StreamToChannel(ctx context.Context, stream TextStream) (<-chan TextDelta, <-chan error) {
+	deltas := make(chan TextDelta)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(deltas)
+		defer stream.Close()
+		for {
+			delta, err := stream.Next(ctx)
+			if err != nil {
+				errs <- err
+				return
+			}
+			select {
+			case deltas <- *delta:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+			if delta.Done {
+				return
+			}
+		}
+	}()
+	return deltas, errs
+}
+
+// DeltaSeq returns an iterator over the deltas of stream, for
+//
+//	for delta, err := range ai.DeltaSeq(ctx, stream) {
+//
+// It yields each delta, including the final (Done) delta, with a nil
+// error. If the stream fails, it yields a zero delta with the error and
+// stops. The stream is closed when the iteration ends, including when
+// the loop breaks early. The iterator reads stream, so it can only be
+// ranged over once.
+func DeltaSeq(ctx context.Context, stream TextStream) iter.Seq2[TextDelta, error] {
+	return func(yield func(TextDelta, error) bool) {
+		defer stream.Close()
+		for {
+			delta, err := stream.Next(ctx)
+			if err != nil {
+				yield(TextDelta{}, err)
+				return
+			}
+			if !yield(*delta, nil) || delta.Done {
+				return
+			}
+		}
+	}
+}
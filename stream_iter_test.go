@@ -0,0 +1,129 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/aitest"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+func TestDeltaSeq(t *testing.T) {
+	source := &scriptStream{texts: []string{"a", "b"}}
+	var text string
+	var done bool
+	for delta, err := range DeltaSeq(context.Background(), source) {
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		text += delta.Text
+		done = delta.Done
+	}
+	if text != "ab" || !done || !source.closed.Load() {
+		t.Fatalf("got %q, done %v, closed %v", text, done, source.closed.Load())
+	}
+
+	source = &scriptStream{texts: []string{"a"}, err: io.ErrUnexpectedEOF}
+	var errs []error
+	for _, err := range DeltaSeq(context.Background(), source) {
+		errs = append(errs, err)
+	}
+	if len(errs) != 2 || errs[0] != nil || errs[1] != io.ErrUnexpectedEOF || !source.closed.Load() {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+
+	source = &scriptStream{texts: []string{"a"}, forever: true}
+	for range DeltaSeq(context.Background(), source) {
+		break
+	}
+	if !source.closed.Load() {
+		t.Fatal("stream not closed after break")
+	}
+}
+
+func TestStreamToChannel(t *testing.T) {
+	source := &scriptStream{texts: []string{"a", "b"}}
+	deltas, errs := StreamToChannel(context.Background(), source)
+	var text string
+	var done bool
+	for delta := range deltas {
+		text += delta.Text
+		done = delta.Done
+	}
+	if err := <-errs; err != nil || text != "ab" || !done || !source.closed.Load() {
+		t.Fatalf("got %q, done %v, closed %v, err %v", text, done, source.closed.Load(), err)
+	}
+
+	deltas, errs = StreamToChannel(context.Background(), &scriptStream{err: io.ErrUnexpectedEOF})
+	for range deltas {
+		t.Fatal("unexpected delta")
+	}
+	if err := <-errs; err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected the stream error, got %v", err)
+	}
+
+	// A consumer that stops reading cancels ctx.
+	ctx, cancel := context.WithCancel(context.Background())
+	source = &scriptStream{texts: []string{"a"}, forever: true}
+	deltas, errs = StreamToChannel(ctx, source)
+	<-deltas
+	cancel()
+	for range deltas {
+	}
+	if err := <-errs; !errors.Is(err, context.Canceled) || !source.closed.Load() {
+		t.Fatalf("expected a cancelled, closed stream, got %v", err)
+	}
+}
+
+func TestLeaks_StreamAdaptersStopEarly(t *testing.T) {
+	// Abandoned streams are also released by a finalizer, so the check
+	// runs while the stream is still reachable.
+	noLeaks := func(name string, before aitest.GoroutineSnapshot, stream TextStream) {
+		t.Helper()
+		if leaked := before.Leaked(aitest.LeakTimeout); len(leaked) > 0 {
+			t.Fatalf("%s: %d leaked goroutine(s):\n\n%s", name, len(leaked), strings.Join(leaked, "\n\n"))
+		}
+		runtime.KeepAlive(stream)
+	}
+	for _, p := range streamProviders {
+		// The server keeps the response open, so the stream only ends
+		// when the consumer closes it.
+		srv := aitest.NewFaultServer(t, aitest.FaultStall, "text/event-stream", p.chunk("hel")+p.chunk("lo"))
+		model, err := p.newModel(provider.ClientOptions{BaseURL: srv.URL, APIKey: "test", HTTPClient: srv.Client()})
+		if err != nil {
+			t.Fatalf("NewClient error: %v", err)
+		}
+		ctx := context.Background()
+
+		before := aitest.Snapshot()
+		stream, err := StreamText(ctx, GenerateTextRequest{Model: model, Messages: []Message{UserMessage("hi")}})
+		if err != nil {
+			t.Fatalf("%s: StreamText error: %v", p.name, err)
+		}
+		for delta, err := range DeltaSeq(ctx, stream) {
+			if err != nil || delta.Text == "" {
+				t.Fatalf("%s: unexpected delta %+v, %v", p.name, delta, err)
+			}
+			break
+		}
+		noLeaks(p.name+"/range", before, stream)
+
+		before = aitest.Snapshot()
+		stream, err = StreamText(ctx, GenerateTextRequest{Model: model, Messages: []Message{UserMessage("hi")}})
+		if err != nil {
+			t.Fatalf("%s: StreamText error: %v", p.name, err)
+		}
+		chanCtx, cancel := context.WithCancel(ctx)
+		deltas, errs := StreamToChannel(chanCtx, stream)
+		<-deltas
+		cancel()
+		if err := <-errs; !errors.Is(err, context.Canceled) {
+			t.Fatalf("%s: expected cancellation, got %v", p.name, err)
+		}
+		noLeaks(p.name+"/channel", before, stream)
+	}
+}
@@ -0,0 +1,204 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ncecere/ai-sdk/anthropic"
+	"github.com/ncecere/ai-sdk/openai"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// streamProvider describes how to build a model for one provider and
+// encode its responses: a text chunk, the end-of-stream event, and a
+// complete non-streaming response.
+type streamProvider struct {
+	name     string
+	newModel func(opts provider.ClientOptions) (LanguageModel, error)
+	chunk    func(text string) string
+	done     string
+	generate string
+}
+
+var streamProviders = []streamProvider{
+	{
+		name: "openai",
+		newModel: func(opts provider.ClientOptions) (LanguageModel, error) {
+			c, err := openai.NewClient(opts)
+			if err != nil {
+				return nil, err
+			}
+			return c.ChatModel("gpt-test"), nil
+		},
+		chunk: func(text string) string {
+			return fmt.Sprintf("data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", text)
+		},
+		done:     "data: [DONE]\n\n",
+		generate: `{"choices":[{"message":{"role":"assistant","content":"hello"}}]}`,
+	},
+	{
+		name: "anthropic",
+		newModel: func(opts provider.ClientOptions) (LanguageModel, error) {
+			c, err := anthropic.NewClient(opts)
+			if err != nil {
+				return nil, err
+			}
+			return c.ChatModel("claude-test"), nil
+		},
+		chunk: func(text string) string {
+			return fmt.Sprintf("data: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":%q}}\n\n", text)
+		},
+		done:     "data: {\"type\":\"message_stop\"}\n\n",
+		generate: `{"content":[{"type":"text","text":"hello"}]}`,
+	},
+}
+
+// stallingServer streams "first", then waits for release before
+// streaming "second". disconnected is closed when the client drops the
+// connection.
+type stallingServer struct {
+	*httptest.Server
+	release      chan struct{}
+	disconnected chan struct{}
+}
+
+func newStallingServer(t *testing.T, p streamProvider) *stallingServer {
+	t.Helper()
+	s := &stallingServer{release: make(chan struct{}), disconnected: make(chan struct{})}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, p.chunk("first"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-s.release:
+			fmt.Fprint(w, p.chunk("second"))
+			w.(http.Flusher).Flush()
+		case <-r.Context().Done():
+			close(s.disconnected)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *stallingServer) stream(t *testing.T, ctx context.Context, p streamProvider) TextStream {
+	t.Helper()
+	model, err := p.newModel(provider.ClientOptions{BaseURL: s.URL, APIKey: "test", HTTPClient: s.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	stream, err := StreamText(ctx, GenerateTextRequest{Model: model, Messages: []Message{UserMessage("hi")}})
+	if err != nil {
+		t.Fatalf("StreamText error: %v", err)
+	}
+	delta, err := stream.Next(context.Background())
+	if err != nil || delta.Text != "first" {
+		t.Fatalf("first Next = %+v, %v", delta, err)
+	}
+	return stream
+}
+
+func (s *stallingServer) waitDisconnect(t *testing.T) {
+	t.Helper()
+	select {
+	case <-s.disconnected:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("connection was not released")
+	}
+}
+
+// streamReaders counts goroutines reading stream bodies.
+func streamReaders() int {
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+	return strings.Count(string(buf), "providerutil.(*lineState).read")
+}
+
+func waitNoStreamReaders(t *testing.T) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for streamReaders() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("leaked %d stream reader goroutines", streamReaders())
+		}
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStreamLifecycle_CancelStreamContextEndsStream(t *testing.T) {
+	for _, p := range streamProviders {
+		t.Run(p.name, func(t *testing.T) {
+			srv := newStallingServer(t, p)
+			ctx, cancel := context.WithCancel(context.Background())
+			stream := srv.stream(t, ctx, p)
+			defer stream.Close()
+
+			cancel()
+			if _, err := stream.Next(context.Background()); !errors.Is(err, context.Canceled) {
+				t.Fatalf("expected context.Canceled from a live read ctx, got %v", err)
+			}
+			srv.waitDisconnect(t)
+			waitNoStreamReaders(t)
+		})
+	}
+}
+
+func TestStreamLifecycle_CancelReadContextKeepsStream(t *testing.T) {
+	for _, p := range streamProviders {
+		t.Run(p.name, func(t *testing.T) {
+			srv := newStallingServer(t, p)
+			stream := srv.stream(t, context.Background(), p)
+			defer stream.Close()
+
+			readCtx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			if _, err := stream.Next(readCtx); !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("expected read deadline error, got %v", err)
+			}
+
+			close(srv.release)
+			delta, err := stream.Next(context.Background())
+			if err != nil || delta.Text != "second" {
+				t.Fatalf("expected stream to continue after read timeout, got %+v, %v", delta, err)
+			}
+		})
+	}
+}
+
+func TestStreamLifecycle_CloseReleasesConnection(t *testing.T) {
+	for _, p := range streamProviders {
+		t.Run(p.name, func(t *testing.T) {
+			srv := newStallingServer(t, p)
+			stream := srv.stream(t, context.Background(), p)
+
+			if err := stream.Close(); err != nil {
+				t.Fatalf("Close error: %v", err)
+			}
+			if err := stream.Close(); err != nil {
+				t.Fatalf("second Close error: %v", err)
+			}
+			srv.waitDisconnect(t)
+			waitNoStreamReaders(t)
+		})
+	}
+}
+
+func TestStreamLifecycle_AbandonedStreamIsReleased(t *testing.T) {
+	for _, p := range streamProviders {
+		t.Run(p.name, func(t *testing.T) {
+			srv := newStallingServer(t, p)
+			srv.stream(t, context.Background(), p) // never closed
+
+			waitNoStreamReaders(t)
+			srv.waitDisconnect(t)
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package ai
+
+import (
+	"context"
+	"strings"
+)
+
+// ObjectStream yields partial objects of type T while a structured
+// response streams in, followed by the final validated object. It is
+// returned by StreamObject and StreamObjectWithRequest.
+type ObjectStream[T any] struct {
+	stream TextStream
+	opts   DecodeOptions
+
+	text    strings.Builder
+	last    string
+	partial T
+	done    bool
+	final   T
+	err     error
+}
+
+// StreamObject is the streaming counterpart of GenerateObject: it
+// starts a StreamText call with the schema inferred from T and returns
+// an ObjectStream that decodes the JSON as it arrives. opts are applied
+// as by GenerateObject.
+func StreamObject[T any](ctx context.Context, model LanguageModel, messages []Message, opts ...GenerateObjectOptions) (*ObjectStream[T], error) {
+	o := objectOptions(opts)
+	return StreamObjectWithRequest[T](ctx, o.request(model, messages), o.Decode)
+}
+
+// StreamObjectWithRequest is like StreamObject but takes a full
+// GenerateTextRequest and DecodeOptions, prepared and applied as by
+// GenerateObjectWithRequest. Partial objects are decoded leniently:
+// DisallowUnknownFields and KeyMatchStrict apply to the final object
+// only.
+//
+// Errors:
+//   - Any error returned while building the schema or by StreamText.
+func StreamObjectWithRequest[T any](ctx context.Context, req GenerateTextRequest, opts DecodeOptions) (*ObjectStream[T], error) {
+	req, err := objectRequest[T](req)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := StreamText(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &ObjectStream[T]{stream: stream, opts: opts}, nil
+}
+
+// Next reads the stream until the object has grown and returns it with
+// done false. Partial objects are best-effort: fields not yet received
+// are zero, a string that is still streaming holds its text so far, and
+// a number, literal, or key cut mid-token is left out until it
+// completes. Fields may arrive in any order. A code fence or prose
+// around the JSON is ignored.
+//
+// When the model finishes, Next returns the final object decoded with
+// the stream's DecodeOptions and done true; later calls return the same
+// result.
+//
+// Errors:
+//   - Any error returned by the underlying stream, with the last
+//     partial object.
+//   - The errors of GenerateObjectWithRequest for the final object,
+//     such as ErrInvalidObjectJSON (wrapped) for JSON truncated by the
+//     end of the stream. The last partial object is returned with done
+//     true.
+func (s *ObjectStream[T]) Next(ctx context.Context) (partial T, done bool, err error) {
+	if s.done {
+		return s.final, true, s.err
+	}
+	for {
+		delta, err := s.stream.Next(ctx)
+		if err != nil {
+			return s.partial, false, err
+		}
+		s.text.WriteString(delta.Text)
+		if delta.Done {
+			s.done = true
+			s.final, s.err = decodeObject[T](s.text.String(), s.opts)
+			if s.err != nil {
+				s.final = s.partial
+			}
+			return s.final, true, s.err
+		}
+		if delta.Text == "" {
+			continue
+		}
+		repaired := completePartialJSON(s.text.String())
+		if repaired == "" || repaired == s.last {
+			continue
+		}
+		raw := []byte(repaired)
+		if isListTarget[T]() {
+			raw = unwrapList(raw)
+		}
+		var v T
+		if DecodeJSON(raw, &v, s.partialOptions()) != nil {
+			continue
+		}
+		s.last, s.partial = repaired, v
+		return v, false, nil
+	}
+}
+
+// partialOptions returns the options for partial objects: opts without
+// the checks that reject keys.
+func (s *ObjectStream[T]) partialOptions() DecodeOptions {
+	opts := DecodeOptions{UseNumber: s.opts.UseNumber}
+	if s.opts.KeyMatching == KeyMatchTolerant {
+		opts.KeyMatching = KeyMatchTolerant
+	}
+	return opts
+}
+
+// Text returns the raw text received so far.
+func (s *ObjectStream[T]) Text() string {
+	return s.text.String()
+}
+
+// Close closes the underlying stream.
+func (s *ObjectStream[T]) Close() error {
+	return s.stream.Close()
+}
+
+// completePartialJSON turns the prefix of a JSON object or array in
+// text into valid JSON: a string value cut short is closed, a trailing
+// incomplete key, number, or literal is dropped (a trailing number may
+// still be growing, so it counts as incomplete), and open containers
+// are closed. Text before the first '{' or '[', such as a code fence,
+// is skipped, as is text after the value completes. It returns "" if
+// text holds no container yet.
+func completePartialJSON(text string) string {
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return ""
+	}
+	s := text[start:]
+
+	var (
+		stack     []byte // open containers
+		expectKey bool   // the next string in the innermost object is a key
+		safe      int    // s[:safe] ends on a complete element
+		safeStack []byte // stack at safe
+	)
+	closeAt := func(prefix string, open []byte) string {
+		var b strings.Builder
+		b.WriteString(prefix)
+		for i := len(open) - 1; i >= 0; i-- {
+			if open[i] == '{' {
+				b.WriteByte('}')
+			} else {
+				b.WriteByte(']')
+			}
+		}
+		return b.String()
+	}
+	markSafe := func(i int) {
+		safe = i
+		safeStack = append(safeStack[:0], stack...)
+	}
+
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case ' ', '\t', '\n', '\r', ':':
+		case ',':
+			expectKey = len(stack) > 0 && stack[len(stack)-1] == '{'
+		case '{', '[':
+			stack = append(stack, c)
+			expectKey = c == '{'
+			markSafe(i + 1)
+		case '}', ']':
+			if len(stack) == 0 {
+				return ""
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return s[:i+1]
+			}
+			markSafe(i + 1)
+		case '"':
+			end, ok := scanJSONString(s, i)
+			if !ok {
+				if expectKey {
+					return closeAt(s[:safe], safeStack)
+				}
+				return closeAt(s[:end]+`"`, stack)
+			}
+			if expectKey {
+				expectKey = false
+			} else {
+				markSafe(end)
+			}
+			i = end - 1
+		default:
+			end := i
+			for end < len(s) && strings.IndexByte(" \t\n\r,:]}", s[end]) < 0 {
+				end++
+			}
+			if tok := s[i:end]; end == len(s) && tok != "true" && tok != "false" && tok != "null" {
+				return closeAt(s[:safe], safeStack)
+			}
+			markSafe(end)
+			i = end - 1
+		}
+	}
+	return closeAt(s[:safe], safeStack)
+}
+
+// scanJSONString scans the string literal starting at the quote s[i].
+// For a complete literal it returns the index after the closing quote
+// and true. For one cut short it returns the end of its complete
+// content, before any partial escape sequence, and false.
+func scanJSONString(s string, i int) (int, bool) {
+	for j := i + 1; j < len(s); j++ {
+		switch s[j] {
+		case '"':
+			return j + 1, true
+		case '\\':
+			n := 2
+			if j+1 < len(s) && s[j+1] == 'u' {
+				n = 6
+			}
+			if j+n > len(s) {
+				return j, false
+			}
+			j += n - 1
+		}
+	}
+	return len(s), false
+}
@@ -0,0 +1,156 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/anthropic"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+func TestCompletePartialJSON(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"", ""},
+		{"Sure, here", ""},
+		{`{`, `{}`},
+		{`{"na`, `{}`},
+		{`{"name"`, `{}`},
+		{`{"name": "Ad`, `{"name": "Ad"}`},
+		{`{"name": "Ada", "age": 3`, `{"name": "Ada"}`},
+		{`{"name": "Ada", "age": 36,`, `{"name": "Ada", "age": 36}`},
+		{`{"tags": ["a", "b`, `{"tags": ["a", "b"]}`},
+		{`{"tags": ["a"], "ok": tr`, `{"tags": ["a"]}`},
+		{`{"q": "say \"hi\`, `{"q": "say \"hi"}`},
+		{`{"q": "\u00e`, `{"q": ""}`},
+		{`[{"a": 1}, {"a`, `[{"a": 1}, {}]`},
+		{"```json\n{\"a\": {\"b\": null", `{"a": {"b": null}}`},
+		{"```json\n{\"a\": 1}\n```", `{"a": 1}`},
+	}
+	for _, tc := range cases {
+		if got := completePartialJSON(tc.in); got != tc.want {
+			t.Errorf("completePartialJSON(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+type streamedPerson struct {
+	Name string   `json:"name"`
+	Age  int      `json:"age"`
+	Tags []string `json:"tags"`
+}
+
+func TestStreamObject_Partials(t *testing.T) {
+	// Fields arrive out of declaration order, inside a code fence.
+	stream := &scriptStream{texts: []string{
+		"```json\n{\"tags\": [\"ma",
+		"th\"], \"age\": 3",
+		"6, \"name\": \"Ad",
+		"a\"}\n```",
+	}}
+	objects, err := StreamObject[streamedPerson](context.Background(), &streamOnlyModel{stream: stream}, []Message{UserMessage("who?")})
+	if err != nil {
+		t.Fatalf("StreamObject error: %v", err)
+	}
+	defer objects.Close()
+
+	want := []streamedPerson{
+		{Tags: []string{"ma"}},
+		{Tags: []string{"math"}},
+		{Tags: []string{"math"}, Age: 36, Name: "Ad"},
+		{Tags: []string{"math"}, Age: 36, Name: "Ada"},
+	}
+	for i, w := range want {
+		got, done, err := objects.Next(context.Background())
+		if err != nil || done || !reflect.DeepEqual(got, w) {
+			t.Fatalf("partial %d = %+v done=%v err=%v, want %+v", i, got, done, err, w)
+		}
+	}
+	final := streamedPerson{Name: "Ada", Age: 36, Tags: []string{"math"}}
+	for range 2 {
+		got, done, err := objects.Next(context.Background())
+		if err != nil || !done || !reflect.DeepEqual(got, final) {
+			t.Fatalf("final = %+v done=%v err=%v", got, done, err)
+		}
+	}
+}
+
+func TestStreamObject_Truncated(t *testing.T) {
+	stream := &scriptStream{texts: []string{`{"name": "Ada", "age": 3`}}
+	objects, err := StreamObject[streamedPerson](context.Background(), &streamOnlyModel{stream: stream}, nil)
+	if err != nil {
+		t.Fatalf("StreamObject error: %v", err)
+	}
+	partial, done, err := objects.Next(context.Background())
+	if err != nil || done || partial.Name != "Ada" || partial.Age != 0 {
+		t.Fatalf("partial = %+v done=%v err=%v", partial, done, err)
+	}
+	got, done, err := objects.Next(context.Background())
+	if !done || !errors.Is(err, ErrInvalidObjectJSON) || got.Name != "Ada" {
+		t.Fatalf("final = %+v done=%v err=%v, want ErrInvalidObjectJSON with the last partial", got, done, err)
+	}
+}
+
+func TestStreamObject_StreamError(t *testing.T) {
+	boom := errors.New("boom")
+	stream := &scriptStream{texts: []string{`{"name": "A`}, err: boom}
+	objects, err := StreamObject[streamedPerson](context.Background(), &streamOnlyModel{stream: stream}, nil)
+	if err != nil {
+		t.Fatalf("StreamObject error: %v", err)
+	}
+	if _, _, err := objects.Next(context.Background()); err != nil {
+		t.Fatalf("first Next error: %v", err)
+	}
+	got, done, err := objects.Next(context.Background())
+	if !errors.Is(err, boom) || done || got.Name != "A" {
+		t.Fatalf("got %+v done=%v err=%v, want boom with the last partial", got, done, err)
+	}
+}
+
+func TestStreamObject_Anthropic(t *testing.T) {
+	// Anthropic returns structured output as the input of a forced
+	// "json" tool, streamed as input_json_delta fragments.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, data := range []string{
+			`{"type":"message_start","message":{"usage":{"input_tokens":5}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"json","input":{}}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"name\": \"Ad"}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"a\", \"age\": 36}"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":9}}`,
+			`{"type":"message_stop"}`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+	}))
+	defer ts.Close()
+
+	client, err := anthropic.NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	objects, err := StreamObject[streamedPerson](context.Background(), client.ChatModel("claude-test"), []Message{UserMessage("who?")})
+	if err != nil {
+		t.Fatalf("StreamObject error: %v", err)
+	}
+	defer objects.Close()
+
+	partial, done, err := objects.Next(context.Background())
+	if err != nil || done || partial.Name != "Ad" {
+		t.Fatalf("partial = %+v done=%v err=%v", partial, done, err)
+	}
+	var got streamedPerson
+	for !done {
+		if got, done, err = objects.Next(context.Background()); err != nil {
+			t.Fatalf("Next error: %v", err)
+		}
+	}
+	if want := (streamedPerson{Name: "Ada", Age: 36}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("final = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,102 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// StreamOrSimulate is like StreamText, but when the model cannot stream
+// (its Stream returns ErrStreamingUnsupported) it calls GenerateText
+// and replays the response as a stream: the reasoning and then the text
+// in word-sized deltas, followed by a final Done delta carrying the tool
+// calls and usage. Handlers can then offer streaming UX for every model,
+// while IsSimulatedStream reports which streams were simulated, for
+// example for logging.
+//
+// Errors:
+//   - Any error returned by StreamText other than
+//     ErrStreamingUnsupported.
+//   - Any error returned by GenerateText during the fallback.
+func StreamOrSimulate(ctx context.Context, req GenerateTextRequest) (TextStream, error) {
+	stream, err := StreamText(ctx, req)
+	if err == nil || !errors.Is(err, ErrStreamingUnsupported) {
+		return stream, err
+	}
+	res, err := GenerateText(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return newSimulatedStream(res), nil
+}
+
+// IsSimulatedStream reports whether stream was synthesized by
+// StreamOrSimulate from a non-streaming response.
+func IsSimulatedStream(stream TextStream) bool {
+	_, ok := stream.(*simulatedStream)
+	return ok
+}
+
+type simulatedStream struct {
+	deltas []*TextDelta
+	closed bool
+}
+
+func newSimulatedStream(res GenerateTextResponse) *simulatedStream {
+	s := &simulatedStream{}
+	for _, chunk := range simulatedChunks(res.Reasoning) {
+		s.deltas = append(s.deltas, &TextDelta{Reasoning: chunk})
+	}
+	for _, chunk := range simulatedChunks(res.Text) {
+		s.deltas = append(s.deltas, &TextDelta{Text: chunk})
+	}
+	s.deltas = append(s.deltas, &TextDelta{
+		ToolCalls:    res.ToolCalls,
+		Usage:        res.Usage,
+		StopReason:   res.StopReason,
+		FinishReason: res.FinishReason,
+		Warnings:     res.Warnings,
+		Metadata:     res.Metadata,
+		Done:         true,
+	})
+	return s
+}
+
+// simulatedChunks splits text after each run of whitespace, so that
+// joining the chunks restores text exactly.
+func simulatedChunks(text string) []string {
+	var chunks []string
+	for text != "" {
+		i := strings.IndexAny(text, " \t\n")
+		if i < 0 {
+			chunks = append(chunks, text)
+			break
+		}
+		for i < len(text) && strings.ContainsRune(" \t\n", rune(text[i])) {
+			i++
+		}
+		chunks = append(chunks, text[:i])
+		text = text[i:]
+	}
+	return chunks
+}
+
+func (s *simulatedStream) Next(ctx context.Context) (*TextDelta, error) {
+	if s.closed {
+		return nil, ErrStreamClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(s.deltas) == 0 {
+		return &TextDelta{Done: true}, nil
+	}
+	delta := s.deltas[0]
+	s.deltas = s.deltas[1:]
+	return delta, nil
+}
+
+func (s *simulatedStream) Close() error {
+	s.closed = true
+	return nil
+}
@@ -0,0 +1,92 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+func TestStreamOrSimulate_FallsBackToGenerate(t *testing.T) {
+	model := &scriptedModel{responses: []*provider.LanguageModelResponse{{
+		Text:      "Hello there,  world!",
+		Reasoning: "Greet them.",
+		ToolCalls: []ToolCall{{ID: "c1", Name: "wave", RawArguments: []byte(`{}`)}},
+		Usage:     &Usage{PromptTokens: 3, CompletionTokens: 4, TotalTokens: 7},
+	}}}
+	req := GenerateTextRequest{Model: model, Messages: []Message{UserMessage("hi")}}
+
+	if _, err := StreamText(context.Background(), req); !errors.Is(err, ErrStreamingUnsupported) {
+		t.Fatalf("expected StreamText to report ErrStreamingUnsupported, got %v", err)
+	}
+
+	stream, err := StreamOrSimulate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("StreamOrSimulate error: %v", err)
+	}
+	defer stream.Close()
+	if !IsSimulatedStream(stream) {
+		t.Fatalf("expected a simulated stream")
+	}
+
+	var text, reasoning []string
+	var final *TextDelta
+	for {
+		delta, err := stream.Next(context.Background())
+		if err != nil {
+			t.Fatalf("Next error: %v", err)
+		}
+		if delta.Done {
+			final = delta
+			break
+		}
+		if delta.Text != "" {
+			text = append(text, delta.Text)
+		}
+		if delta.Reasoning != "" {
+			if len(text) > 0 {
+				t.Fatalf("reasoning delta after text")
+			}
+			reasoning = append(reasoning, delta.Reasoning)
+		}
+	}
+	if want := []string{"Hello ", "there,  ", "world!"}; !reflect.DeepEqual(text, want) {
+		t.Fatalf("text deltas = %q, want %q", text, want)
+	}
+	if want := []string{"Greet ", "them."}; !reflect.DeepEqual(reasoning, want) {
+		t.Fatalf("reasoning deltas = %q, want %q", reasoning, want)
+	}
+	if len(final.ToolCalls) != 1 || final.ToolCalls[0].Name != "wave" || final.Usage.TotalTokens != 7 {
+		t.Fatalf("unexpected final delta %+v", final)
+	}
+	if again, err := stream.Next(context.Background()); err != nil || !again.Done || len(again.ToolCalls) != 0 {
+		t.Fatalf("expected an empty Done delta after the end, got %+v, %v", again, err)
+	}
+}
+
+func TestStreamOrSimulate_StreamsWhenSupported(t *testing.T) {
+	stream, err := StreamOrSimulate(context.Background(), GenerateTextRequest{
+		Model:    &streamOnlyModel{stream: &scriptStream{texts: []string{"a"}}},
+		Messages: []Message{UserMessage("hi")},
+	})
+	if err != nil {
+		t.Fatalf("StreamOrSimulate error: %v", err)
+	}
+	defer stream.Close()
+	if IsSimulatedStream(stream) {
+		t.Fatalf("expected the model's own stream")
+	}
+}
+
+// streamOnlyModel streams its stream and fails Generate.
+type streamOnlyModel struct{ stream TextStream }
+
+func (m *streamOnlyModel) Generate(ctx context.Context, req *provider.LanguageModelRequest) (*provider.LanguageModelResponse, error) {
+	return nil, errors.New("streamOnlyModel: Generate called")
+}
+
+func (m *streamOnlyModel) Stream(ctx context.Context, req *provider.LanguageModelRequest) (provider.LanguageModelStream, error) {
+	return m.stream, nil
+}
@@ -0,0 +1,160 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/anthropic"
+	"github.com/ncecere/ai-sdk/openai"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// renderedSystem is the provider-neutral view of a rendered request
+// body: the top-level system prompt (Anthropic) and the role/content
+// sequence of the messages array.
+type renderedSystem struct {
+	System   string
+	Messages []string
+}
+
+func renderSystem(t *testing.T, providerName string, sep string, req GenerateTextRequest) (renderedSystem, error) {
+	t.Helper()
+	opts := provider.ClientOptions{BaseURL: "https://example.test", APIKey: "test", SystemSeparator: sep}
+	var model LanguageModel
+	switch providerName {
+	case "openai":
+		c, err := openai.NewClient(opts)
+		if err != nil {
+			t.Fatalf("NewClient error: %v", err)
+		}
+		model = c.ChatModel("gpt-test")
+	case "anthropic":
+		c, err := anthropic.NewClient(opts)
+		if err != nil {
+			t.Fatalf("NewClient error: %v", err)
+		}
+		model = c.ChatModel("claude-test")
+	}
+
+	res, err := DryRunText(context.Background(), model, req)
+	if err != nil {
+		return renderedSystem{}, err
+	}
+	var body struct {
+		System   string `json:"system"`
+		Messages []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(res.Body, &body); err != nil {
+		t.Fatalf("invalid body: %v", err)
+	}
+	out := renderedSystem{System: body.System}
+	for _, m := range body.Messages {
+		var text string
+		if err := json.Unmarshal(m.Content, &text); err != nil {
+			var blocks []struct {
+				Text string `json:"text"`
+			}
+			if err := json.Unmarshal(m.Content, &blocks); err != nil || len(blocks) == 0 {
+				t.Fatalf("unexpected content: %s", m.Content)
+			}
+			text = blocks[0].Text
+		}
+		out.Messages = append(out.Messages, m.Role+":"+text)
+	}
+	return out, nil
+}
+
+func TestSystemMessages_Matrix(t *testing.T) {
+	cases := []struct {
+		name string
+		sep  string
+		req  GenerateTextRequest
+		want map[string]renderedSystem
+	}{
+		{
+			name: "single system",
+			req:  GenerateTextRequest{Messages: []Message{SystemMessage("Be brief."), UserMessage("hi")}},
+			want: map[string]renderedSystem{
+				"openai":    {Messages: []string{"system:Be brief.", "user:hi"}},
+				"anthropic": {System: "Be brief.", Messages: []string{"user:hi"}},
+			},
+		},
+		{
+			name: "interleaved systems keep order",
+			req: GenerateTextRequest{Messages: []Message{
+				SystemMessage("# Rules\n- one"),
+				UserMessage("hi"),
+				AssistantMessage("hello"),
+				SystemMessage("- two"),
+				UserMessage("again"),
+			}},
+			want: map[string]renderedSystem{
+				"openai":    {Messages: []string{"system:# Rules\n- one", "user:hi", "assistant:hello", "system:- two", "user:again"}},
+				"anthropic": {System: "# Rules\n- one\n\n- two", Messages: []string{"user:hi", "assistant:hello", "user:again"}},
+			},
+		},
+		{
+			name: "System field precedes messages",
+			req:  GenerateTextRequest{System: "Top.", Messages: []Message{SystemMessage("Inner."), UserMessage("hi")}},
+			want: map[string]renderedSystem{
+				"openai":    {Messages: []string{"system:Top.", "system:Inner.", "user:hi"}},
+				"anthropic": {System: "Top.\n\nInner.", Messages: []string{"user:hi"}},
+			},
+		},
+		{
+			name: "custom separator",
+			sep:  "\n---\n",
+			req:  GenerateTextRequest{Messages: []Message{SystemMessage("A"), SystemMessage("B"), UserMessage("hi")}},
+			want: map[string]renderedSystem{
+				"openai":    {Messages: []string{"system:A", "system:B", "user:hi"}},
+				"anthropic": {System: "A\n---\nB", Messages: []string{"user:hi"}},
+			},
+		},
+		{
+			name: "system only with inserted user",
+			req:  GenerateTextRequest{System: "Prime.", SystemOnly: SystemOnlyInsertUser},
+			want: map[string]renderedSystem{
+				"openai":    {Messages: []string{"system:Prime.", "user:" + provider.SystemOnlyUserMessage}},
+				"anthropic": {System: "Prime.", Messages: []string{"user:" + provider.SystemOnlyUserMessage}},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		for _, p := range []string{"openai", "anthropic"} {
+			t.Run(tc.name+"/"+p, func(t *testing.T) {
+				got, err := renderSystem(t, p, tc.sep, tc.req)
+				if err != nil {
+					t.Fatalf("DryRunText error: %v", err)
+				}
+				if !reflect.DeepEqual(got, tc.want[p]) {
+					t.Fatalf("unexpected rendering:\n got %#v\nwant %#v", got, tc.want[p])
+				}
+			})
+		}
+	}
+}
+
+func TestSystemMessages_RejectsRequestsWithoutConversation(t *testing.T) {
+	reqs := map[string]GenerateTextRequest{
+		"system only": {Messages: []Message{SystemMessage("Be brief.")}},
+		"empty":       {},
+	}
+	for name, req := range reqs {
+		for _, p := range []string{"openai", "anthropic"} {
+			t.Run(name+"/"+p, func(t *testing.T) {
+				_, err := renderSystem(t, p, "", req)
+				var noConv *NoConversationMessageError
+				if !errors.As(err, &noConv) || noConv.Provider != p {
+					t.Fatalf("expected NoConversationMessageError, got %v", err)
+				}
+			})
+		}
+	}
+}
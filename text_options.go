@@ -0,0 +1,64 @@
+package ai
+
+import "github.com/ncecere/ai-sdk/registry"
+
+// TextOption configures the request built by GenerateSimpleText.
+// Options are applied in order, so a later option overrides what an
+// earlier one set.
+type TextOption func(*textOptions)
+
+// textOptions collects the effect of TextOptions.
+type textOptions struct {
+	req GenerateTextRequest
+	// reg and modelName are set by WithRegistryModel.
+	reg       registry.Registry
+	modelName string
+	useReg    bool
+}
+
+// WithSystem sets the system prompt.
+func WithSystem(system string) TextOption {
+	return func(o *textOptions) { o.req.System = system }
+}
+
+// WithSettings applies the non-nil fields of settings, as
+// CallSettings.ApplyTo does. Fields settings leaves unset keep the
+// values of earlier options.
+func WithSettings(settings *CallSettings) TextOption {
+	return func(o *textOptions) { settings.ApplyTo(&o.req) }
+}
+
+// WithTools adds tool definitions the model may call. The response text
+// is empty when the model calls a tool instead of answering; use
+// GenerateText to read the tool calls.
+func WithTools(tools ...ToolDefinition) TextOption {
+	return func(o *textOptions) { o.req.Tools = append(o.req.Tools, tools...) }
+}
+
+// WithHistory sets the messages sent before the prompt, such as earlier
+// turns of a conversation.
+func WithHistory(messages ...Message) TextOption {
+	return func(o *textOptions) { o.req.Messages = messages }
+}
+
+// WithRegistryModel resolves the model by name in reg when the call is
+// made, replacing the model passed to the helper, which may then be
+// nil.
+func WithRegistryModel(reg registry.Registry, name string) TextOption {
+	return func(o *textOptions) {
+		o.reg, o.modelName, o.useReg = reg, name, true
+	}
+}
+
+// buildTextRequest applies opts and appends prompt as the final user
+// message.
+func buildTextRequest(model LanguageModel, prompt string, opts []TextOption) textOptions {
+	o := textOptions{req: GenerateTextRequest{Model: model}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	messages := make([]Message, 0, len(o.req.Messages)+1)
+	messages = append(messages, o.req.Messages...)
+	o.req.Messages = append(messages, UserMessage(prompt))
+	return o
+}
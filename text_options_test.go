@@ -0,0 +1,90 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/provider"
+	"github.com/ncecere/ai-sdk/registry"
+)
+
+func TestGenerateSimpleText_OptionPrecedence(t *testing.T) {
+	model := &scriptedModel{responses: []*provider.LanguageModelResponse{{Text: "ok"}}}
+	low, high, tokens := 0.2, 0.9, 64
+	text, err := GenerateSimpleText(context.Background(), model, "question",
+		WithSystem("first"),
+		WithSettings(&CallSettings{Temperature: &low, MaxTokens: &tokens}),
+		WithHistory(UserMessage("earlier"), AssistantMessage("answer")),
+		WithTools(ToolDefinition{Name: "a"}),
+		WithSystem("second"),
+		WithSettings(&CallSettings{Temperature: &high}),
+		WithTools(ToolDefinition{Name: "b"}),
+	)
+	if err != nil || text != "ok" {
+		t.Fatalf("GenerateSimpleText = %q, %v", text, err)
+	}
+
+	req := model.requests[0]
+	if *req.Temperature != high || req.MaxTokens == nil || *req.MaxTokens != tokens {
+		t.Fatalf("expected the later temperature and the earlier max tokens, got %v and %v", *req.Temperature, req.MaxTokens)
+	}
+	if len(req.Tools) != 2 || req.Tools[0].Name != "a" || req.Tools[1].Name != "b" {
+		t.Fatalf("expected tools from both options, got %+v", req.Tools)
+	}
+	var got []string
+	for _, m := range req.Messages {
+		got = append(got, string(m.Role)+":"+m.Content)
+	}
+	want := []string{"system:second", "user:earlier", "assistant:answer", "user:question"}
+	if len(got) != len(want) {
+		t.Fatalf("messages = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("messages = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestGenerateSimpleText_RegistryModel(t *testing.T) {
+	direct := &scriptedModel{responses: []*provider.LanguageModelResponse{{Text: "direct"}}}
+	first := &scriptedModel{responses: []*provider.LanguageModelResponse{{Text: "first"}}}
+	second := &scriptedModel{responses: []*provider.LanguageModelResponse{{Text: "second"}, {Text: "second again"}}}
+	reg := registry.NewInMemoryRegistry()
+	reg.RegisterLanguageModel("first", first)
+	reg.RegisterLanguageModel("second", second)
+	ctx := context.Background()
+
+	// The registry model replaces the model argument, and the last
+	// WithRegistryModel wins.
+	text, err := GenerateSimpleText(ctx, direct, "hi", WithRegistryModel(reg, "first"), WithRegistryModel(reg, "second"))
+	if err != nil || text != "second" {
+		t.Fatalf("GenerateSimpleText = %q, %v", text, err)
+	}
+	if len(direct.requests) != 0 || len(first.requests) != 0 {
+		t.Fatal("expected only the last registry model to be called")
+	}
+
+	// The legacy helper is GenerateSimpleText with WithRegistryModel
+	// first, so its options still apply.
+	text, err = GenerateSimpleTextWithRegistry(ctx, reg, "second", "hi", WithSystem("be brief"))
+	if err != nil || text != "second again" {
+		t.Fatalf("GenerateSimpleTextWithRegistry = %q, %v", text, err)
+	}
+	if sys := second.requests[1].Messages[0]; sys.Role != RoleSystem || sys.Content != "be brief" {
+		t.Fatalf("expected the system option to apply, got %+v", sys)
+	}
+
+	var noModel *registry.NoSuchModelError
+	if _, err := GenerateSimpleText(ctx, direct, "hi", WithRegistryModel(reg, "missing")); !errors.As(err, &noModel) {
+		t.Fatalf("expected *NoSuchModelError, got %v", err)
+	}
+	var invalid *InvalidArgumentError
+	if _, err := GenerateSimpleText(ctx, nil, "hi", WithRegistryModel(nil, "first")); !errors.As(err, &invalid) {
+		t.Fatalf("expected *InvalidArgumentError for a nil registry, got %v", err)
+	}
+	if _, err := GenerateSimpleText(ctx, nil, "hi"); !errors.Is(err, ErrMissingModel) {
+		t.Fatalf("expected ErrMissingModel, got %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package textsplit
+
+import "strings"
+
+// markdownBlock is a paragraph or fenced code block within a section.
+type markdownBlock struct {
+	span
+	code bool
+}
+
+// markdownSections splits text at ATX headings ("# Title"). Each
+// section starts at its heading line; text before the first heading
+// forms its own section. Headings inside code fences are ignored.
+func markdownSections(text string) []span {
+	var out []span
+	start := 0
+	fence := ""
+	for _, line := range splitLines(text, span{0, len(text)}) {
+		l := strings.TrimSpace(text[line.start:line.end])
+		if f := fenceMarker(l); f != "" {
+			if fence == "" {
+				fence = f
+			} else if strings.HasPrefix(l, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if fence == "" && isHeading(l) && line.start > start {
+			out = append(out, span{start, line.start})
+			start = line.start
+		}
+	}
+	if start < len(text) {
+		out = append(out, span{start, len(text)})
+	}
+	return out
+}
+
+// markdownBlocks splits a section into paragraphs separated by blank
+// lines and fenced code blocks, which are kept whole. A section's
+// heading line stays attached to the block that follows it.
+func markdownBlocks(text string, s span) []markdownBlock {
+	var out []markdownBlock
+	start := s.start
+	fence := ""
+	flush := func(end int, code bool) {
+		if end > start {
+			out = append(out, markdownBlock{span: span{start, end}, code: code})
+		}
+		start = end
+	}
+	for _, line := range splitLines(text, s) {
+		l := strings.TrimSpace(text[line.start:line.end])
+		if fence != "" {
+			if strings.HasPrefix(l, fence) && fenceMarker(l) != "" {
+				fence = ""
+				flush(line.end, true)
+			}
+			continue
+		}
+		if f := fenceMarker(l); f != "" {
+			if !headingOnly(text[start:line.start]) {
+				flush(line.start, false)
+			}
+			fence = f
+			continue
+		}
+		if l == "" && !headingOnly(text[start:line.start]) {
+			flush(line.end, false)
+		}
+	}
+	flush(s.end, fence != "")
+	return out
+}
+
+// fenceMarker returns the fence characters opening or closing a code
+// block on line, or "" if line is not a fence.
+func fenceMarker(line string) string {
+	for _, f := range []string{"```", "~~~"} {
+		if strings.HasPrefix(line, f) {
+			return f
+		}
+	}
+	return ""
+}
+
+// headingOnly reports whether s consists of a single heading line.
+func headingOnly(s string) bool {
+	s = strings.TrimSpace(s)
+	return !strings.Contains(s, "\n") && isHeading(s)
+}
+
+// isHeading reports whether line is an ATX heading.
+func isHeading(line string) bool {
+	n := 0
+	for n < len(line) && line[n] == '#' {
+		n++
+	}
+	return n >= 1 && n <= 6 && (n == len(line) || line[n] == ' ' || line[n] == '\t')
+}
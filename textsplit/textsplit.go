@@ -0,0 +1,252 @@
+// Package textsplit splits long documents into token-bounded chunks for
+// embedding and retrieval pipelines.
+//
+// Every Chunk records byte offsets into the original text, so
+// text[c.Start:c.End] == c.Text and retrieval hits can be highlighted in
+// the source document. Chunks never split a UTF-8 sequence and have
+// leading and trailing whitespace trimmed.
+package textsplit
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Chunk is a contiguous slice of the original text.
+type Chunk struct {
+	// Text is the chunk content.
+	Text string `json:"text"`
+	// Start is the byte offset of the chunk in the original text.
+	Start int `json:"start"`
+	// End is the byte offset just past the chunk in the original text.
+	End int `json:"end"`
+}
+
+// Estimator returns the number of tokens in text. ai.EstimateTokens
+// and exact tokenizer-backed counters both satisfy it.
+type Estimator func(text string) int
+
+// EstimateTokens is the default Estimator, using the common heuristic
+// of about four characters per token.
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// SplitByTokens splits text into chunks of at most maxTokens tokens,
+// breaking between words. Consecutive chunks share up to overlap tokens
+// of trailing context. A single word longer than maxTokens is split
+// between runes. If est is nil, EstimateTokens is used.
+func SplitByTokens(text string, maxTokens, overlap int, est Estimator) []Chunk {
+	est = defaultEstimator(est)
+	units := refine(text, []span{{0, len(text)}}, maxTokens, est, splitWords)
+	return pack(text, units, maxTokens, overlap, est)
+}
+
+// SplitBySentences splits text into chunks of at most maxTokens tokens,
+// breaking between sentences where possible and between words for
+// sentences longer than maxTokens. Consecutive chunks share up to
+// overlap tokens of trailing sentences. If est is nil, EstimateTokens
+// is used.
+func SplitBySentences(text string, maxTokens, overlap int, est Estimator) []Chunk {
+	est = defaultEstimator(est)
+	units := refine(text, splitSentences(text, span{0, len(text)}), maxTokens, est, splitWords)
+	return pack(text, units, maxTokens, overlap, est)
+}
+
+// SplitMarkdown splits a Markdown document into chunks of at most
+// maxTokens tokens. Chunks never cross a heading, so each chunk belongs
+// to a single section, and fenced code blocks are kept whole unless
+// they alone exceed maxTokens, in which case they are split between
+// lines. Paragraphs are split between sentences, then words, as needed.
+// Overlap applies within a section. If est is nil, EstimateTokens is
+// used.
+func SplitMarkdown(text string, maxTokens, overlap int, est Estimator) []Chunk {
+	est = defaultEstimator(est)
+	var out []Chunk
+	for _, section := range markdownSections(text) {
+		var units []span
+		for _, b := range markdownBlocks(text, section) {
+			if b.code {
+				units = append(units, refine(text, []span{b.span}, maxTokens, est, splitLines, splitWords)...)
+			} else {
+				units = append(units, refine(text, []span{b.span}, maxTokens, est, splitSentences, splitWords)...)
+			}
+		}
+		out = append(out, pack(text, units, maxTokens, overlap, est)...)
+	}
+	return out
+}
+
+func defaultEstimator(est Estimator) Estimator {
+	if est == nil {
+		return EstimateTokens
+	}
+	return est
+}
+
+// span is a half-open byte range [start, end) of the original text.
+type span struct {
+	start, end int
+}
+
+// splitter breaks a span into smaller consecutive spans covering it.
+type splitter func(text string, s span) []span
+
+// refine replaces every span whose estimate exceeds maxTokens with the
+// result of the first splitter, recursing through the remaining
+// splitters and finally splitting between runes.
+func refine(text string, spans []span, maxTokens int, est Estimator, levels ...splitter) []span {
+	var out []span
+	for _, s := range spans {
+		if est(text[s.start:s.end]) <= maxTokens {
+			out = append(out, s)
+			continue
+		}
+		if len(levels) == 0 {
+			out = append(out, splitRunes(text, s, maxTokens, est)...)
+			continue
+		}
+		out = append(out, refine(text, levels[0](text, s), maxTokens, est, levels[1:]...)...)
+	}
+	return out
+}
+
+// pack greedily groups consecutive units into chunks of at most
+// maxTokens, starting each new chunk with the trailing units of the
+// previous one that fit within overlap.
+func pack(text string, units []span, maxTokens, overlap int, est Estimator) []Chunk {
+	var out []Chunk
+	for i := 0; i < len(units); {
+		j := i
+		for j+1 < len(units) && est(text[units[i].start:units[j+1].end]) <= maxTokens {
+			j++
+		}
+		if c, ok := trimmed(text, units[i].start, units[j].end); ok {
+			out = append(out, c)
+		}
+		if j+1 >= len(units) {
+			break
+		}
+
+		next := j + 1
+		if overlap > 0 {
+			for k := j; k > i && est(text[units[k].start:units[j].end]) <= overlap; k-- {
+				next = k
+			}
+		}
+		i = next
+	}
+	return out
+}
+
+// trimmed returns the chunk for text[start:end] with surrounding
+// whitespace removed, reporting false if nothing remains.
+func trimmed(text string, start, end int) (Chunk, bool) {
+	for start < end {
+		r, size := utf8.DecodeRuneInString(text[start:end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		start += size
+	}
+	for end > start {
+		r, size := utf8.DecodeLastRuneInString(text[start:end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		end -= size
+	}
+	if start == end {
+		return Chunk{}, false
+	}
+	return Chunk{Text: text[start:end], Start: start, End: end}, true
+}
+
+// splitWords splits s into units of a word plus its trailing
+// whitespace.
+func splitWords(text string, s span) []span {
+	var out []span
+	start := s.start
+	inSpace := false
+	for i, r := range text[s.start:s.end] {
+		pos := s.start + i
+		space := unicode.IsSpace(r)
+		if inSpace && !space && pos > start {
+			out = append(out, span{start, pos})
+			start = pos
+		}
+		inSpace = space
+	}
+	if start < s.end {
+		out = append(out, span{start, s.end})
+	}
+	return out
+}
+
+// splitLines splits s into lines, each including its newline.
+func splitLines(text string, s span) []span {
+	var out []span
+	start := s.start
+	for start < s.end {
+		i := strings.IndexByte(text[start:s.end], '\n')
+		if i < 0 {
+			out = append(out, span{start, s.end})
+			break
+		}
+		out = append(out, span{start, start + i + 1})
+		start += i + 1
+	}
+	return out
+}
+
+// splitSentences splits s after sentence-ending punctuation followed by
+// whitespace and at paragraph breaks. Each unit includes its trailing
+// whitespace.
+func splitSentences(text string, s span) []span {
+	var out []span
+	start := s.start
+	seg := text[s.start:s.end]
+	boundary := false
+	for i, r := range seg {
+		pos := s.start + i
+		if boundary && !unicode.IsSpace(r) {
+			out = append(out, span{start, pos})
+			start = pos
+			boundary = false
+		}
+		switch {
+		case r == '.' || r == '!' || r == '?' || r == '。':
+			next, _ := utf8.DecodeRuneInString(seg[i+utf8.RuneLen(r):])
+			if unicode.IsSpace(next) || r == '。' {
+				boundary = true
+			}
+		case r == '\n' && strings.HasPrefix(seg[i+1:], "\n"):
+			boundary = true
+		}
+	}
+	if start < s.end {
+		out = append(out, span{start, s.end})
+	}
+	return out
+}
+
+// splitRunes greedily splits s between runes into spans of at most
+// maxTokens (and at least one rune each).
+func splitRunes(text string, s span, maxTokens int, est Estimator) []span {
+	var out []span
+	start := s.start
+	end := start
+	for end < s.end {
+		_, size := utf8.DecodeRuneInString(text[end:s.end])
+		if end > start && est(text[start:end+size]) > maxTokens {
+			out = append(out, span{start, end})
+			start = end
+		}
+		end += size
+	}
+	if start < s.end {
+		out = append(out, span{start, s.end})
+	}
+	return out
+}
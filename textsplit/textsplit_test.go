@@ -0,0 +1,118 @@
+package textsplit
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// words counts whitespace-separated words, an exact estimator that
+// keeps test expectations readable.
+func words(s string) int { return len(strings.Fields(s)) }
+
+func checkOffsets(t *testing.T, text string, chunks []Chunk) {
+	t.Helper()
+	for i, c := range chunks {
+		if c.Start < 0 || c.End > len(text) || c.Start >= c.End {
+			t.Fatalf("chunk %d has invalid offsets [%d,%d)", i, c.Start, c.End)
+		}
+		if text[c.Start:c.End] != c.Text {
+			t.Fatalf("chunk %d text does not match offsets: %q vs %q", i, c.Text, text[c.Start:c.End])
+		}
+		if !utf8.ValidString(c.Text) {
+			t.Fatalf("chunk %d splits a UTF-8 sequence: %q", i, c.Text)
+		}
+	}
+}
+
+func texts(chunks []Chunk) []string {
+	out := make([]string, len(chunks))
+	for i, c := range chunks {
+		out[i] = c.Text
+	}
+	return out
+}
+
+func TestSplitByTokens_Overlap(t *testing.T) {
+	text := "one two three four five six seven"
+	chunks := SplitByTokens(text, 3, 1, words)
+	checkOffsets(t, text, chunks)
+
+	want := []string{"one two three", "three four five", "five six seven"}
+	if got := texts(chunks); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("unexpected chunks: %q", got)
+	}
+}
+
+func TestSplitByTokens_UnicodeAndLongWords(t *testing.T) {
+	text := "  héllo wörld 日本語のテキスト ✓✓✓✓✓✓✓✓  "
+	chunks := SplitByTokens(text, 2, 0, nil)
+	checkOffsets(t, text, chunks)
+	if len(chunks) < 4 {
+		t.Fatalf("expected long words to be split, got %q", texts(chunks))
+	}
+	for _, c := range chunks {
+		if EstimateTokens(c.Text) > 2 {
+			t.Fatalf("chunk exceeds max tokens: %q", c.Text)
+		}
+	}
+}
+
+func TestSplitBySentences(t *testing.T) {
+	text := "First sentence here. Second one! Is this third? Fourth sentence is a bit longer than the others.\n\nNew paragraph"
+	chunks := SplitBySentences(text, 6, 0, words)
+	checkOffsets(t, text, chunks)
+
+	want := []string{
+		"First sentence here. Second one!",
+		"Is this third? Fourth sentence is",
+		"a bit longer than the others.",
+		"New paragraph",
+	}
+	if got := texts(chunks); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("unexpected chunks: %q", got)
+	}
+}
+
+func TestSplitMarkdown_RespectsHeadingsAndFences(t *testing.T) {
+	text := "Intro text.\n\n# Install\n\nRun the installer.\n\n```sh\n# not a heading\nmake install\n```\n\n## Usage\n\nCall it. Then stop.\n"
+	chunks := SplitMarkdown(text, 50, 0, words)
+	checkOffsets(t, text, chunks)
+
+	want := []string{
+		"Intro text.",
+		"# Install\n\nRun the installer.\n\n```sh\n# not a heading\nmake install\n```",
+		"## Usage\n\nCall it. Then stop.",
+	}
+	if got := texts(chunks); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("unexpected chunks: %q", got)
+	}
+
+	// With a small budget the code block is kept whole while it fits,
+	// and the heading stays attached to the paragraph after it.
+	chunks = SplitMarkdown(text, 8, 0, words)
+	checkOffsets(t, text, chunks)
+	want = []string{
+		"Intro text.",
+		"# Install\n\nRun the installer.",
+		"```sh\n# not a heading\nmake install\n```",
+		"## Usage\n\nCall it. Then stop.",
+	}
+	if got := texts(chunks); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("unexpected chunks: %q", got)
+	}
+}
+
+func TestSplitMarkdown_LargeCodeBlockSplitsByLine(t *testing.T) {
+	text := "```\na b\nc d\ne f\n```"
+	chunks := SplitMarkdown(text, 3, 0, words)
+	checkOffsets(t, text, chunks)
+	for _, c := range chunks {
+		if words(c.Text) > 3 {
+			t.Fatalf("chunk exceeds max tokens: %q", c.Text)
+		}
+	}
+	if len(chunks) < 2 {
+		t.Fatalf("expected code block to be split, got %q", texts(chunks))
+	}
+}
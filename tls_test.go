@@ -0,0 +1,215 @@
+package ai
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ncecere/ai-sdk/openai"
+	"github.com/ncecere/ai-sdk/provider"
+	"github.com/ncecere/ai-sdk/providerutil"
+)
+
+// testPKI is a locally generated CA with server certificates for
+// gateway.internal and for 127.0.0.1, and a client certificate.
+type testPKI struct {
+	caPEM                 []byte
+	pool                  *x509.CertPool
+	gateway, loopback     tls.Certificate
+	clientCert, clientKey []byte
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	caKey := newTestKey(t)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Internal CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issue := func(serial int64, usage x509.ExtKeyUsage, dnsNames []string, ips []net.IP) (certPEM, keyPEM []byte) {
+		key := newTestKey(t)
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "test"},
+			DNSNames:     dnsNames,
+			IPAddresses:  ips,
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	}
+
+	p := &testPKI{caPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), pool: x509.NewCertPool()}
+	p.pool.AddCert(ca)
+	if p.gateway, err = tls.X509KeyPair(issue(2, x509.ExtKeyUsageServerAuth, []string{"gateway.internal"}, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if p.loopback, err = tls.X509KeyPair(issue(3, x509.ExtKeyUsageServerAuth, nil, []net.IP{net.IPv4(127, 0, 0, 1)})); err != nil {
+		t.Fatal(err)
+	}
+	p.clientCert, p.clientKey = issue(4, x509.ExtKeyUsageClientAuth, nil, nil)
+	return p
+}
+
+func newTestKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// mtlsServer starts a TLS server presenting cert that requires a client
+// certificate issued by the test CA and answers every request with a
+// chat completion.
+func mtlsServer(t *testing.T, pki *testPKI, cert tls.Certificate) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hello over mTLS"},"finish_reason":"stop"}]}`))
+	}))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pki.pool,
+	}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestNewHTTPClient_CustomCAAndClientCertificates(t *testing.T) {
+	pki := newTestPKI(t)
+	srv := mtlsServer(t, pki, pki.gateway)
+	u, _ := url.Parse(srv.URL)
+	host, _, _ := net.SplitHostPort(u.Host)
+	gateway := map[string]string{host: "gateway.internal"}
+
+	get := func(opts providerutil.HTTPClientOptions) error {
+		hc, err := providerutil.NewHTTPClient(opts)
+		if err != nil {
+			t.Fatalf("NewHTTPClient error: %v", err)
+		}
+		resp, err := hc.Get(srv.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := get(providerutil.HTTPClientOptions{
+		CACertPEM: pki.caPEM, ClientCertPEM: pki.clientCert, ClientKeyPEM: pki.clientKey, ServerNames: gateway,
+	}); err != nil {
+		t.Fatalf("mTLS request failed: %v", err)
+	}
+
+	failures := []struct {
+		name string
+		opts providerutil.HTTPClientOptions
+		want string
+	}{
+		{"unknown CA", providerutil.HTTPClientOptions{ClientCertPEM: pki.clientCert, ClientKeyPEM: pki.clientKey, ServerNames: gateway}, "certificate"},
+		{"no server name override", providerutil.HTTPClientOptions{RootCAs: pki.pool, ClientCertPEM: pki.clientCert, ClientKeyPEM: pki.clientKey}, "certificate"},
+		{"no client certificate", providerutil.HTTPClientOptions{CACertPEM: pki.caPEM, ServerNames: gateway}, ""},
+	}
+	for _, tc := range failures {
+		err := get(tc.opts)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected a TLS error mentioning %q, got %v", tc.name, tc.want, err)
+		}
+	}
+
+	if err := get(providerutil.HTTPClientOptions{
+		InsecureSkipVerify: true, ClientCertPEM: pki.clientCert, ClientKeyPEM: pki.clientKey,
+	}); err != nil {
+		t.Fatalf("InsecureSkipVerify request failed: %v", err)
+	}
+}
+
+func TestNewHTTPClient_RejectsBadPEM(t *testing.T) {
+	pki := newTestPKI(t)
+	cases := []struct {
+		name string
+		opts providerutil.HTTPClientOptions
+		want string
+	}{
+		{"CA", providerutil.HTTPClientOptions{CACertPEM: []byte("not a certificate")}, "CACertPEM"},
+		{"cert without key", providerutil.HTTPClientOptions{ClientCertPEM: pki.clientCert}, "set together"},
+		{"mismatched key", providerutil.HTTPClientOptions{ClientCertPEM: pki.clientCert, ClientKeyPEM: pki.caPEM}, "client certificate"},
+	}
+	for _, tc := range cases {
+		if _, err := providerutil.NewHTTPClient(tc.opts); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected error mentioning %q, got %v", tc.name, tc.want, err)
+		}
+	}
+}
+
+func TestClientOptions_TLSFields(t *testing.T) {
+	pki := newTestPKI(t)
+	srv := mtlsServer(t, pki, pki.loopback)
+
+	client, err := openai.NewClient(provider.ClientOptions{
+		APIKey:        "sk-test",
+		BaseURL:       srv.URL,
+		CACertPEM:     pki.caPEM,
+		ClientCertPEM: pki.clientCert,
+		ClientKeyPEM:  pki.clientKey,
+	})
+	if err != nil {
+		t.Fatalf("openai.NewClient error: %v", err)
+	}
+	res, err := GenerateText(context.Background(), GenerateTextRequest{
+		Model:    client.ChatModel("gpt-test"),
+		Messages: []Message{UserMessage("hi")},
+	})
+	if err != nil || res.Text != "hello over mTLS" {
+		t.Fatalf("GenerateText = %q, %v", res.Text, err)
+	}
+
+	// Misconfiguration fails at construction.
+	if _, err := openai.NewClient(provider.ClientOptions{APIKey: "sk-test", CACertPEM: []byte("junk")}); err == nil || !strings.Contains(err.Error(), "CACertPEM") {
+		t.Fatalf("expected a construction error for bad PEM, got %v", err)
+	}
+	if _, err := openai.NewClient(provider.ClientOptions{APIKey: "sk-test", CACertPEM: pki.caPEM, HTTPClient: http.DefaultClient}); err == nil {
+		t.Fatalf("expected an error for CACertPEM combined with HTTPClient")
+	}
+}
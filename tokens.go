@@ -0,0 +1,102 @@
+package ai
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// EstimateTokens returns a rough token estimate for text using the
+// common heuristic of about four characters per token. It is intended
+// for budgeting decisions where an exact tokenizer is not available and
+// deliberately errs on the high side for short strings.
+func EstimateTokens(text string) int {
+	n := utf8.RuneCountInString(text)
+	if n == 0 {
+		return 0
+	}
+	return (n + 3) / 4
+}
+
+// EstimateMessageTokens returns a rough token estimate for a message
+// history, including a small per-message overhead for role and
+// framing tokens.
+func EstimateMessageTokens(messages []Message) int {
+	const perMessageOverhead = 4
+	total := 0
+	for _, m := range messages {
+		total += perMessageOverhead + EstimateTokens(m.Content)
+	}
+	return total
+}
+
+// CountTokens returns the number of input tokens req would use with
+// req.Model. Models implementing provider.TokenCounter (such as
+// Anthropic chat models) report exact counts and exact is true;
+// otherwise the count is estimated from the messages and tool
+// definitions with EstimateTokens.
+//
+// Errors:
+//   - ErrMissingModel if req.Model is nil.
+//   - Any error returned by the provider's token counting endpoint.
+func CountTokens(ctx context.Context, req GenerateTextRequest) (n int, exact bool, err error) {
+	if req.Model == nil {
+		return 0, false, ErrMissingModel
+	}
+	if counter, ok := req.Model.(provider.TokenCounter); ok {
+		n, err := counter.CountTokens(ctx, req.languageModelRequest())
+		if err != nil {
+			return 0, false, err
+		}
+		return n, true, nil
+	}
+
+	return estimatePromptTokens(req.Messages, req.Tools), false, nil
+}
+
+// estimatePromptTokens estimates the input tokens of messages and tool
+// definitions with EstimateTokens.
+func estimatePromptTokens(messages []Message, tools []ToolDefinition) int {
+	n := EstimateMessageTokens(messages)
+	for _, t := range tools {
+		n += EstimateTokens(t.Name) + EstimateTokens(t.Description) + EstimateTokens(string(t.Parameters))
+	}
+	return n
+}
+
+// Tokenizer encodes text into model token IDs. Implementations are
+// model-specific (for example a tiktoken encoder for OpenAI models).
+type Tokenizer interface {
+	Encode(text string) []int
+}
+
+// LogitBiasFor builds a logit bias map for use with
+// GenerateTextRequest.LogitBias. Every token of each banned string is
+// given a bias of -100, and every token of each boosted string is given
+// the associated bias, clamped to [-100, 100]. Boosts are applied after
+// bans, so a token that appears in both uses the boost value.
+//
+// Errors:
+//   - InvalidArgumentError if tok is nil.
+func LogitBiasFor(tok Tokenizer, banned []string, boosted map[string]float64) (map[string]float64, error) {
+	if tok == nil {
+		return nil, &InvalidArgumentError{Parameter: "tok", Value: nil, Message: "tokenizer must not be nil"}
+	}
+
+	bias := make(map[string]float64)
+	for _, s := range banned {
+		for _, id := range tok.Encode(s) {
+			bias[strconv.Itoa(id)] = -100
+		}
+	}
+	for s, v := range boosted {
+		v = math.Max(-100, math.Min(100, v))
+		for _, id := range tok.Encode(s) {
+			bias[strconv.Itoa(id)] = v
+		}
+	}
+	return bias, nil
+}
@@ -0,0 +1,90 @@
+package ai
+
+// ToolCallAccumulator assembles the tool calls of a stream from its
+// deltas. Providers deliver tool calls in one of two forms, and the
+// accumulator takes both:
+//
+//   - Fragments in TextDelta.ToolCallDeltas, keyed by stream index,
+//     whose arguments are appended in order (OpenAI and compatible
+//     backends).
+//   - Complete calls in TextDelta.ToolCalls (Anthropic, and every
+//     built-in provider on the final delta).
+//
+// A complete call replaces the fragments it was assembled from, so a
+// stream that sends both yields each call once. The zero value is ready
+// to use. A ToolCallAccumulator is not safe for concurrent use.
+type ToolCallAccumulator struct {
+	calls []accumulatedCall
+	// byIndex maps a fragment's stream index to its position in calls.
+	byIndex map[int]int
+}
+
+// accumulatedCall is one call, either complete or still being
+// assembled from fragments.
+type accumulatedCall struct {
+	call     ToolCall
+	complete bool
+}
+
+// Add adds the tool-call fragments and complete tool calls of delta.
+// Fragments for a call that is already complete are ignored.
+func (a *ToolCallAccumulator) Add(delta TextDelta) {
+	for _, f := range delta.ToolCallDeltas {
+		i, ok := a.byIndex[f.Index]
+		if !ok {
+			if a.byIndex == nil {
+				a.byIndex = map[int]int{}
+			}
+			i = len(a.calls)
+			a.byIndex[f.Index] = i
+			a.calls = append(a.calls, accumulatedCall{})
+		}
+		c := &a.calls[i]
+		if c.complete {
+			continue
+		}
+		if f.ID != "" {
+			c.call.ID = f.ID
+		}
+		if f.Name != "" {
+			c.call.Name = f.Name
+		}
+		c.call.RawArguments = append(c.call.RawArguments, f.Arguments...)
+	}
+	for _, call := range delta.ToolCalls {
+		if i := a.pending(call.ID); i >= 0 {
+			a.calls[i] = accumulatedCall{call: call, complete: true}
+			continue
+		}
+		a.calls = append(a.calls, accumulatedCall{call: call, complete: true})
+	}
+}
+
+// pending returns the position of the first call still assembled from
+// fragments with the given ID, or -1. Backends that send no IDs match
+// the first such call without one.
+func (a *ToolCallAccumulator) pending(id string) int {
+	for i, c := range a.calls {
+		if !c.complete && c.call.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Calls returns the tool calls seen so far, in the order they started.
+// The arguments of a call still being assembled are the fragments
+// received so far and may not be valid JSON yet; once the stream is
+// done, every call is complete. Complete calls are returned as the
+// provider delivered them. The returned slice is a copy.
+func (a *ToolCallAccumulator) Calls() []ToolCall {
+	if len(a.calls) == 0 {
+		return nil
+	}
+	out := make([]ToolCall, len(a.calls))
+	for i, c := range a.calls {
+		out[i] = c.call
+		out[i].RawArguments = append([]byte(nil), c.call.RawArguments...)
+	}
+	return out
+}
@@ -0,0 +1,114 @@
+package ai
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/openai"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+func TestToolCallAccumulator_Fragments(t *testing.T) {
+	var acc ToolCallAccumulator
+	if calls := acc.Calls(); calls != nil {
+		t.Fatalf("zero value Calls = %+v, want nil", calls)
+	}
+	// Two calls interleaved, keyed by stream index rather than order.
+	acc.Add(TextDelta{ToolCallDeltas: []ToolCallDelta{{Index: 1, ID: "b", Name: "time"}}})
+	acc.Add(TextDelta{ToolCallDeltas: []ToolCallDelta{{Index: 0, ID: "a", Name: "weather", Arguments: `{"ci`}}})
+	acc.Add(TextDelta{Text: "hi", ToolCallDeltas: []ToolCallDelta{{Index: 1, Arguments: `{}`}, {Index: 0, Arguments: `ty":"Paris"}`}}})
+	want := []ToolCall{
+		{ID: "b", Name: "time", RawArguments: []byte(`{}`)},
+		{ID: "a", Name: "weather", RawArguments: []byte(`{"city":"Paris"}`)},
+	}
+	got := acc.Calls()
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Calls = %+v, want %+v", got, want)
+	}
+	got[0].RawArguments[0] = 'x'
+	if string(acc.Calls()[0].RawArguments) != `{}` {
+		t.Fatal("Calls returned the accumulator's own arguments")
+	}
+
+	// The complete calls of the final delta replace the fragments.
+	acc.Add(TextDelta{Done: true, ToolCalls: []ToolCall{
+		{ID: "a", Name: "weather", RawArguments: []byte(`"{\"city\":\"Paris\"}"`)},
+		{ID: "b", Name: "time", RawArguments: []byte(`"{}"`)},
+	}})
+	acc.Add(TextDelta{ToolCallDeltas: []ToolCallDelta{{Index: 0, Arguments: "late"}}})
+	want = []ToolCall{
+		{ID: "b", Name: "time", RawArguments: []byte(`"{}"`)},
+		{ID: "a", Name: "weather", RawArguments: []byte(`"{\"city\":\"Paris\"}"`)},
+	}
+	if got := acc.Calls(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Calls after Done = %+v, want %+v", got, want)
+	}
+}
+
+func TestToolCallAccumulator_WholeCalls(t *testing.T) {
+	var acc ToolCallAccumulator
+	acc.Add(TextDelta{Text: "Let me check."})
+	acc.Add(TextDelta{ToolCalls: []ToolCall{{ID: "a", Name: "weather", RawArguments: []byte(`{"city":"Paris"}`)}}})
+	acc.Add(TextDelta{Done: true, ToolCalls: []ToolCall{{ID: "b", Name: "time", RawArguments: []byte(`{}`)}}})
+	got := acc.Calls()
+	if len(got) != 2 || got[0].ID != "a" || got[1].ID != "b" {
+		t.Fatalf("Calls = %+v", got)
+	}
+}
+
+func TestToolCallAccumulator_NoIDs(t *testing.T) {
+	// Some compatible backends send neither IDs nor, on the final
+	// delta, anything to tell the calls apart but their order.
+	var acc ToolCallAccumulator
+	acc.Add(TextDelta{ToolCallDeltas: []ToolCallDelta{{Index: 0, Name: "weather", Arguments: `{}`}}})
+	acc.Add(TextDelta{ToolCallDeltas: []ToolCallDelta{{Index: 1, Name: "time", Arguments: `{}`}}})
+	acc.Add(TextDelta{Done: true, ToolCalls: []ToolCall{
+		{Name: "weather", RawArguments: []byte(`"{}"`)},
+		{Name: "time", RawArguments: []byte(`"{}"`)},
+	}})
+	got := acc.Calls()
+	if len(got) != 2 || got[0].Name != "weather" || got[1].Name != "time" || string(got[1].RawArguments) != `"{}"` {
+		t.Fatalf("Calls = %+v", got)
+	}
+}
+
+func TestToolCallAccumulator_OpenAIStream(t *testing.T) {
+	var bodies []map[string]any
+	ts := fixtureServer(t, "toolcalls/openai_stream.sse", &bodies)
+	defer ts.Close()
+	client, err := openai.NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	ctx := context.Background()
+	stream, err := StreamText(ctx, GenerateTextRequest{
+		Model:    client.ChatModel("gpt-test"),
+		Messages: []Message{UserMessage("Weather in Paris?")},
+		Tools:    []ToolDefinition{{Name: "weather", Parameters: []byte(`{"type":"object"}`)}},
+	})
+	if err != nil {
+		t.Fatalf("StreamText error: %v", err)
+	}
+	defer stream.Close()
+
+	// The arguments are complete from the fragments alone, before the
+	// final delta delivers the whole call.
+	var acc ToolCallAccumulator
+	for {
+		delta, err := stream.Next(ctx)
+		if err != nil {
+			t.Fatalf("Next error: %v", err)
+		}
+		if delta.Done {
+			calls := acc.Calls()
+			if len(calls) != 1 || string(calls[0].RawArguments) != `{"city":"Paris"}` {
+				t.Fatalf("calls before the final delta = %+v", calls)
+			}
+			acc.Add(*delta)
+			break
+		}
+		acc.Add(*delta)
+	}
+	checkWeatherCall(t, acc.Calls())
+}
@@ -0,0 +1,166 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// ToolExecutor runs a tool call with the model's JSON arguments. The
+// result is converted with NewToolResultMessage, so it may be any
+// JSON-encodable value or a ToolResult.
+type ToolExecutor func(ctx context.Context, args json.RawMessage) (any, error)
+
+// GenerateTextWithToolsResponse is the result of GenerateTextWithTools.
+type GenerateTextWithToolsResponse struct {
+	// Text is the final assistant text.
+	Text string
+	// Response is the final model response, the one without tool calls.
+	Response GenerateTextResponse
+	// Messages is the full message trace: the request's Messages
+	// followed by every assistant and tool message of the loop. It
+	// can be used as the history of a follow-up request.
+	Messages []Message
+	// Rounds is the number of model calls made.
+	Rounds int
+	// Usage is the summed token usage of all model calls, or nil if no
+	// call reported usage.
+	Usage *Usage
+}
+
+// ToolRoundsExceededError is returned by GenerateTextWithTools when the
+// model still requests tools after the maximum number of rounds.
+type ToolRoundsExceededError struct {
+	// MaxRounds is the limit that was reached.
+	MaxRounds int
+	// Messages is the message trace up to and including the last
+	// assistant message, whose tool calls were not executed.
+	Messages []Message
+	// Usage is the summed token usage of all model calls.
+	Usage *Usage
+}
+
+func (e *ToolRoundsExceededError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("ai: model still requested tools after %d rounds", e.MaxRounds)
+}
+
+// ToolExecutionError is returned by GenerateTextWithTools when a tool
+// executor fails.
+type ToolExecutionError struct {
+	// Tool is the name of the tool that failed.
+	Tool string
+	// ToolCallID is the ID of the failed call.
+	ToolCallID string
+	// Err is the error returned by the executor.
+	Err error
+}
+
+func (e *ToolExecutionError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("ai: tool %q (call %s) failed: %v", e.Tool, e.ToolCallID, e.Err)
+}
+
+// Unwrap returns the executor's error.
+func (e *ToolExecutionError) Unwrap() error {
+	if e == nil {
+		return nil
+	}
+	return e.Err
+}
+
+// defaultMaxToolRounds is the round limit GenerateTextWithTools uses
+// when maxRounds is not positive.
+const defaultMaxToolRounds = 8
+
+// GenerateTextWithTools runs the tool loop for req: it calls the model,
+// executes any requested tools with executors, appends the assistant
+// message and one tool message per call to the history, and calls the
+// model again until it answers without tools. It is a lighter
+// alternative to the agent package that needs neither a registry nor
+// agent.Tool values.
+//
+// req.Tools declares the tools offered to the model; executors maps
+// each tool name to the function that runs it. All other request
+// fields, such as MaxTokens and Temperature, apply to every call.
+// Calls within a round run sequentially in the order the model returned
+// them. Calls without a provider ID get "call_<round>_<index>", shared
+// by the assistant message and the tool message that answers it.
+//
+// maxRounds limits the number of model calls; values <= 0 use a
+// default of 8. A ToolChoice of ToolChoiceRequired or ToolChoiceTool
+// applies to every round, so such a loop only ends at the limit.
+//
+// Errors:
+//   - InvalidArgumentError if a tool in req.Tools has no executor, or
+//     the model calls a tool without one.
+//   - ToolExecutionError if an executor fails.
+//   - ToolRoundsExceededError if the model still requests tools after
+//     maxRounds calls.
+//   - Any error returned by GenerateText or NewToolResultMessage.
+func GenerateTextWithTools(ctx context.Context, req GenerateTextRequest, executors map[string]ToolExecutor, maxRounds int) (GenerateTextWithToolsResponse, error) {
+	for _, def := range req.Tools {
+		if _, ok := executors[def.Name]; !ok {
+			return GenerateTextWithToolsResponse{}, &InvalidArgumentError{Parameter: "executors", Value: def.Name, Message: fmt.Sprintf("no executor for tool %q", def.Name)}
+		}
+	}
+	if maxRounds <= 0 {
+		maxRounds = defaultMaxToolRounds
+	}
+
+	messages := slices.Clone(req.Messages)
+	var usage *Usage
+	for round := 1; ; round++ {
+		req.Messages = messages
+		res, err := GenerateText(ctx, req)
+		if err != nil {
+			return GenerateTextWithToolsResponse{}, err
+		}
+		usage = usage.Add(res.Usage)
+
+		calls := slices.Clone(res.ToolCalls)
+		for i := range calls {
+			if calls[i].ID == "" {
+				calls[i].ID = fmt.Sprintf("call_%d_%d", round, i)
+			}
+		}
+		if res.Text != "" || len(calls) > 0 {
+			msg := AssistantToolCallMessage(res.Text, calls)
+			msg.ReasoningBlocks = res.ReasoningBlocks
+			messages = append(messages, msg)
+		}
+		if res.FinishReason != FinishReasonToolCalls {
+			return GenerateTextWithToolsResponse{
+				Text:     res.Text,
+				Response: res,
+				Messages: messages,
+				Rounds:   round,
+				Usage:    usage,
+			}, nil
+		}
+		if round >= maxRounds {
+			return GenerateTextWithToolsResponse{}, &ToolRoundsExceededError{MaxRounds: maxRounds, Messages: messages, Usage: usage}
+		}
+
+		for _, tc := range calls {
+			exec, ok := executors[tc.Name]
+			if !ok {
+				return GenerateTextWithToolsResponse{}, &InvalidArgumentError{Parameter: "executors", Value: tc.Name, Message: fmt.Sprintf("model called tool %q, which has no executor", tc.Name)}
+			}
+			result, err := exec(ctx, json.RawMessage(tc.RawArguments))
+			if err != nil {
+				return GenerateTextWithToolsResponse{}, &ToolExecutionError{Tool: tc.Name, ToolCallID: tc.ID, Err: err}
+			}
+			msg, err := NewToolResultMessage(tc, result)
+			if err != nil {
+				return GenerateTextWithToolsResponse{}, err
+			}
+			messages = append(messages, msg)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+func TestGenerateTextWithTools_MultiRound(t *testing.T) {
+	model := &scriptedModel{responses: []*provider.LanguageModelResponse{
+		{ToolCalls: []provider.ToolCall{{ID: "call_a", Name: "weather", RawArguments: []byte(`{"city":"Paris"}`)}}, Usage: &provider.Usage{TotalTokens: 10}},
+		{Text: "Converting.", ToolCalls: []provider.ToolCall{{Name: "convert", RawArguments: []byte(`{"celsius":21}`)}}, Usage: &provider.Usage{TotalTokens: 20}},
+		{Text: "It is 70F in Paris.", Usage: &provider.Usage{TotalTokens: 30}},
+	}}
+	maxTokens := 64
+	temperature := 0.2
+	var cities []string
+	executors := map[string]ToolExecutor{
+		"weather": func(ctx context.Context, args json.RawMessage) (any, error) {
+			var a struct{ City string }
+			if err := json.Unmarshal(args, &a); err != nil {
+				return nil, err
+			}
+			cities = append(cities, a.City)
+			return map[string]int{"celsius": 21}, nil
+		},
+		"convert": func(ctx context.Context, args json.RawMessage) (any, error) {
+			return 70, nil
+		},
+	}
+
+	res, err := GenerateTextWithTools(context.Background(), GenerateTextRequest{
+		Model:       model,
+		Messages:    []Message{UserMessage("Weather in Paris, in F?")},
+		Tools:       []ToolDefinition{{Name: "weather"}, {Name: "convert"}},
+		MaxTokens:   &maxTokens,
+		Temperature: &temperature,
+	}, executors, 5)
+	if err != nil {
+		t.Fatalf("GenerateTextWithTools error: %v", err)
+	}
+	if res.Text != "It is 70F in Paris." || res.Rounds != 3 || res.Usage == nil || res.Usage.TotalTokens != 60 {
+		t.Fatalf("unexpected result text=%q rounds=%d usage=%+v", res.Text, res.Rounds, res.Usage)
+	}
+	if len(cities) != 1 || cities[0] != "Paris" {
+		t.Fatalf("weather executed with %v", cities)
+	}
+
+	roles := []string{RoleUser, RoleAssistant, RoleTool, RoleAssistant, RoleTool, RoleAssistant}
+	if len(res.Messages) != len(roles) {
+		t.Fatalf("got %d messages, want %d: %+v", len(res.Messages), len(roles), res.Messages)
+	}
+	for i, role := range roles {
+		if res.Messages[i].Role != role {
+			t.Fatalf("message %d role = %q, want %q", i, res.Messages[i].Role, role)
+		}
+	}
+	if id := res.Messages[2].ToolCallID; id != "call_a" {
+		t.Fatalf("first tool message ToolCallID = %q", id)
+	}
+	if call, id := res.Messages[3].ToolCalls[0].ID, res.Messages[4].ToolCallID; call != "call_2_0" || id != call {
+		t.Fatalf("generated call ID %q answered by %q", call, id)
+	}
+
+	if len(model.requests) != 3 {
+		t.Fatalf("got %d model calls", len(model.requests))
+	}
+	for i, req := range model.requests {
+		if req.MaxTokens == nil || *req.MaxTokens != 64 || req.Temperature == nil || *req.Temperature != 0.2 || len(req.Tools) != 2 {
+			t.Fatalf("request %d lost settings: %+v", i, req)
+		}
+	}
+	if n := len(model.requests[2].Messages); n != 5 {
+		t.Fatalf("last request has %d messages, want 5", n)
+	}
+}
+
+func TestGenerateTextWithTools_MaxRounds(t *testing.T) {
+	call := &provider.LanguageModelResponse{ToolCalls: []provider.ToolCall{{ID: "c", Name: "loop", RawArguments: []byte(`{}`)}}}
+	model := &scriptedModel{responses: []*provider.LanguageModelResponse{call, call, call}}
+	var runs int
+	executors := map[string]ToolExecutor{
+		"loop": func(ctx context.Context, args json.RawMessage) (any, error) { runs++; return "again", nil },
+	}
+
+	_, err := GenerateTextWithTools(context.Background(), GenerateTextRequest{
+		Model:    model,
+		Messages: []Message{UserMessage("go")},
+		Tools:    []ToolDefinition{{Name: "loop"}},
+	}, executors, 2)
+	var roundsErr *ToolRoundsExceededError
+	if !errors.As(err, &roundsErr) || roundsErr.MaxRounds != 2 {
+		t.Fatalf("expected ToolRoundsExceededError, got %v", err)
+	}
+	if runs != 1 || len(model.requests) != 2 {
+		t.Fatalf("runs=%d calls=%d, want 1 and 2", runs, len(model.requests))
+	}
+	if n := len(roundsErr.Messages); n != 4 {
+		t.Fatalf("trace has %d messages, want 4", n)
+	}
+}
+
+func TestGenerateTextWithTools_Errors(t *testing.T) {
+	boom := errors.New("boom")
+	executors := map[string]ToolExecutor{
+		"fail": func(ctx context.Context, args json.RawMessage) (any, error) { return nil, boom },
+	}
+
+	_, err := GenerateTextWithTools(context.Background(), GenerateTextRequest{
+		Model:    &scriptedModel{},
+		Messages: []Message{UserMessage("go")},
+		Tools:    []ToolDefinition{{Name: "fail"}, {Name: "missing"}},
+	}, executors, 0)
+	var argErr *InvalidArgumentError
+	if !errors.As(err, &argErr) || argErr.Value != "missing" {
+		t.Fatalf("expected InvalidArgumentError for missing executor, got %v", err)
+	}
+
+	model := &scriptedModel{responses: []*provider.LanguageModelResponse{
+		{ToolCalls: []provider.ToolCall{{ID: "c1", Name: "fail", RawArguments: []byte(`{}`)}}},
+	}}
+	_, err = GenerateTextWithTools(context.Background(), GenerateTextRequest{
+		Model:    model,
+		Messages: []Message{UserMessage("go")},
+		Tools:    []ToolDefinition{{Name: "fail"}},
+	}, executors, 0)
+	var execErr *ToolExecutionError
+	if !errors.As(err, &execErr) || execErr.Tool != "fail" || execErr.ToolCallID != "c1" || !errors.Is(err, boom) {
+		t.Fatalf("expected ToolExecutionError wrapping boom, got %v", err)
+	}
+}
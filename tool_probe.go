@@ -0,0 +1,82 @@
+package ai
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// probeToolName is the tool the model is asked to call by
+// ProbeToolSupport.
+const probeToolName = "report_probe"
+
+// ToolSupport is the outcome of ProbeToolSupport.
+type ToolSupport struct {
+	// Supported reports whether the model returned a tool call for the
+	// probe request.
+	Supported bool
+	// Diagnostics describes the provider's response when no tool call
+	// was returned.
+	Diagnostics *ToolDiagnostics
+}
+
+// ProbeToolSupport sends a small canned request that forces the model
+// to call a single tool and reports whether a tool call came
+// back. Some OpenAI-compatible backends silently ignore the tools field;
+// on those the probe reports Supported=false, typically with
+// Diagnostics.ToolCallsKeyPresent=false.
+//
+// The probe costs one model call. Use ProbeToolSupportCached to run it
+// at most once per model value.
+//
+// Errors:
+//   - ErrMissingModel if model is nil.
+//   - Any error returned by GenerateText.
+func ProbeToolSupport(ctx context.Context, model LanguageModel) (ToolSupport, error) {
+	res, err := GenerateText(ctx, GenerateTextRequest{
+		Model: model,
+		Messages: []Message{
+			SystemMessage("You are a connectivity check. Always respond by calling the " + probeToolName + " tool."),
+			UserMessage("Call the " + probeToolName + " tool with ok set to true."),
+		},
+		Tools: []ToolDefinition{{
+			Name:        probeToolName,
+			Description: "Reports that tool calling works. Always call this tool.",
+			Parameters:  []byte(`{"type":"object","properties":{"ok":{"type":"boolean"}},"required":["ok"]}`),
+		}},
+		ToolChoice: ToolChoiceTool(probeToolName),
+	})
+	if err != nil {
+		return ToolSupport{}, err
+	}
+	return ToolSupport{
+		Supported:   len(res.ToolCalls) > 0,
+		Diagnostics: res.ToolDiagnostics,
+	}, nil
+}
+
+var toolSupportCache sync.Map // LanguageModel -> ToolSupport
+
+// ProbeToolSupportCached is like ProbeToolSupport but caches successful
+// results per model value for the lifetime of the process. Models must
+// be comparable (pointer receivers are); non-comparable models are
+// probed on every call.
+func ProbeToolSupportCached(ctx context.Context, model LanguageModel) (ToolSupport, error) {
+	if model != nil && isComparable(model) {
+		if v, ok := toolSupportCache.Load(model); ok {
+			return v.(ToolSupport), nil
+		}
+	}
+	support, err := ProbeToolSupport(ctx, model)
+	if err != nil {
+		return ToolSupport{}, err
+	}
+	if model != nil && isComparable(model) {
+		toolSupportCache.Store(model, support)
+	}
+	return support, nil
+}
+
+func isComparable(v any) bool {
+	return reflect.TypeOf(v).Comparable()
+}
@@ -0,0 +1,25 @@
+package ai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+func TestProbeToolSupport_ForcesProbeTool(t *testing.T) {
+	model := &scriptedModel{responses: []*provider.LanguageModelResponse{
+		{ToolCalls: []ToolCall{{ID: "call-1", Name: probeToolName, RawArguments: []byte(`{"ok":true}`)}}},
+	}}
+
+	support, err := ProbeToolSupport(context.Background(), model)
+	if err != nil {
+		t.Fatalf("ProbeToolSupport error: %v", err)
+	}
+	if !support.Supported {
+		t.Fatal("Supported = false, want true")
+	}
+	if got, want := model.requests[0].ToolChoice, ToolChoiceTool(probeToolName); got != want {
+		t.Fatalf("ToolChoice = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,170 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/anthropic"
+	"github.com/ncecere/ai-sdk/openai"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// mixedCase replays a response holding both text and a tool call,
+// recorded in testdata/toolcalls.
+type mixedCase struct {
+	name       string
+	newModel   func(opts provider.ClientOptions) (LanguageModel, error)
+	stopReason string
+}
+
+var mixedCases = []mixedCase{
+	{
+		name: "openai",
+		newModel: func(opts provider.ClientOptions) (LanguageModel, error) {
+			c, err := openai.NewClient(opts)
+			if err != nil {
+				return nil, err
+			}
+			return c.ChatModel("gpt-test"), nil
+		},
+		stopReason: "tool_calls",
+	},
+	{
+		name: "anthropic",
+		newModel: func(opts provider.ClientOptions) (LanguageModel, error) {
+			c, err := anthropic.NewClient(opts)
+			if err != nil {
+				return nil, err
+			}
+			return c.ChatModel("claude-test"), nil
+		},
+		stopReason: "tool_use",
+	},
+}
+
+// checkWeatherCall checks calls hold the single recorded weather call.
+// Arguments may be the JSON object or a JSON string holding it.
+func checkWeatherCall(t *testing.T, calls []ToolCall) {
+	t.Helper()
+	if len(calls) != 1 || calls[0].ID != "call_1" || calls[0].Name != "weather" {
+		t.Fatalf("unexpected tool calls %+v", calls)
+	}
+	raw := calls[0].RawArguments
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		raw = []byte(s)
+	}
+	var args struct{ City string }
+	if err := json.Unmarshal(raw, &args); err != nil || args.City != "Paris" {
+		t.Fatalf("unexpected arguments %s", calls[0].RawArguments)
+	}
+}
+
+func TestMixedTextAndToolCalls_Generate(t *testing.T) {
+	for _, tc := range mixedCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var bodies []map[string]any
+			ts := fixtureServer(t, "toolcalls/"+tc.name+"_generate.json", &bodies)
+			defer ts.Close()
+			model, err := tc.newModel(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+			if err != nil {
+				t.Fatalf("NewClient error: %v", err)
+			}
+
+			res, err := GenerateText(context.Background(), GenerateTextRequest{
+				Model:    model,
+				Messages: []Message{UserMessage("Weather in Paris?")},
+				Tools:    []ToolDefinition{{Name: "weather", Parameters: []byte(`{"type":"object"}`)}},
+			})
+			if err != nil {
+				t.Fatalf("GenerateText error: %v", err)
+			}
+			if res.Text != "Let me check the weather." || res.StopReason != tc.stopReason {
+				t.Fatalf("unexpected response text=%q stop=%q", res.Text, res.StopReason)
+			}
+			if res.FinishReason != FinishReasonToolCalls {
+				t.Fatalf("FinishReason = %q, want %q", res.FinishReason, FinishReasonToolCalls)
+			}
+			checkWeatherCall(t, res.ToolCalls)
+		})
+	}
+}
+
+func TestMixedTextAndToolCalls_Stream(t *testing.T) {
+	for _, tc := range mixedCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var bodies []map[string]any
+			ts := fixtureServer(t, "toolcalls/"+tc.name+"_stream.sse", &bodies)
+			defer ts.Close()
+			model, err := tc.newModel(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+			if err != nil {
+				t.Fatalf("NewClient error: %v", err)
+			}
+
+			ctx := context.Background()
+			stream, err := StreamText(ctx, GenerateTextRequest{
+				Model:    model,
+				Messages: []Message{UserMessage("Weather in Paris?")},
+				Tools:    []ToolDefinition{{Name: "weather", Parameters: []byte(`{"type":"object"}`)}},
+			})
+			if err != nil {
+				t.Fatalf("StreamText error: %v", err)
+			}
+			defer stream.Close()
+
+			// All text arrives before the tool calls, which come whole on
+			// the final delta.
+			var text string
+			for {
+				delta, err := stream.Next(ctx)
+				if err != nil {
+					t.Fatalf("Next error: %v", err)
+				}
+				if !delta.Done {
+					if len(delta.ToolCalls) > 0 {
+						t.Fatalf("tool calls before the final delta: %+v", delta.ToolCalls)
+					}
+					text += delta.Text
+					continue
+				}
+				if delta.Text != "" {
+					t.Fatalf("text on the final delta: %q", delta.Text)
+				}
+				checkWeatherCall(t, delta.ToolCalls)
+				break
+			}
+			if text != "Let me check the weather." {
+				t.Fatalf("unexpected streamed text %q", text)
+			}
+		})
+	}
+}
+
+func TestGenerateText_ToolChoice(t *testing.T) {
+	tools := []ToolDefinition{{Name: "weather", Parameters: []byte(`{"type":"object"}`)}}
+	model := &scriptedModel{responses: []*provider.LanguageModelResponse{{Text: "ok"}}}
+	if _, err := GenerateText(context.Background(), GenerateTextRequest{
+		Model:      model,
+		Messages:   []Message{UserMessage("Weather in Paris?")},
+		Tools:      tools,
+		ToolChoice: ToolChoiceTool("weather"),
+	}); err != nil {
+		t.Fatalf("GenerateText error: %v", err)
+	}
+	if got := model.requests[0].ToolChoice; got != ToolChoiceTool("weather") {
+		t.Fatalf("ToolChoice = %q", got)
+	}
+
+	for _, choice := range []ToolChoice{"sometimes", ToolChoiceTool("search")} {
+		_, err := GenerateText(context.Background(), GenerateTextRequest{Model: model, Tools: tools, ToolChoice: choice})
+		var invalid *InvalidArgumentError
+		if !errors.As(err, &invalid) || invalid.Parameter != "ToolChoice" {
+			t.Fatalf("expected InvalidArgumentError for %q, got %v", choice, err)
+		}
+		if _, err := StreamText(context.Background(), GenerateTextRequest{Model: model, Tools: tools, ToolChoice: choice}); !errors.As(err, &invalid) {
+			t.Fatalf("expected StreamText to reject %q, got %v", choice, err)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package ai
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// TraceHeader is the outbound header carrying the trace ID.
+const TraceHeader = provider.TraceHeader
+
+// WithTraceID returns a context carrying the trace ID id. GenerateText,
+// StreamText, and agent runs made with the returned context use id
+// instead of generating one, so it can match an ID already in your
+// request logs.
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return provider.WithTraceID(ctx, id)
+}
+
+// TraceIDFromContext returns the trace ID carried by ctx, or "".
+func TraceIDFromContext(ctx context.Context) string {
+	return provider.TraceIDFromContext(ctx)
+}
+
+// TraceIDFromError returns the trace ID recorded on err by GenerateText
+// or StreamText, or "" if there is none.
+func TraceIDFromError(err error) string {
+	var traced *TracedError
+	if errors.As(err, &traced) && traced != nil {
+		return traced.TraceID
+	}
+	var empty *EmptyResponseError
+	if errors.As(err, &empty) && empty != nil {
+		return empty.TraceID
+	}
+	var orphan *OrphanToolMessageError
+	if errors.As(err, &orphan) && orphan != nil {
+		return orphan.TraceID
+	}
+	var noConv *NoConversationMessageError
+	if errors.As(err, &noConv) && noConv != nil {
+		return noConv.TraceID
+	}
+	var blocked *ContentBlockedError
+	if errors.As(err, &blocked) && blocked != nil {
+		return blocked.TraceID
+	}
+	return ""
+}
+
+// traceError records id on err. Typed provider errors carry it in their
+// TraceID field; other errors are wrapped in a *TracedError.
+func traceError(err error, id string) error {
+	if err == nil || provider.StampTraceID(err, id) {
+		return err
+	}
+	var traced *TracedError
+	if errors.As(err, &traced) {
+		return err
+	}
+	return &TracedError{TraceID: id, Err: err}
+}
@@ -0,0 +1,86 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/middleware"
+	"github.com/ncecere/ai-sdk/openai"
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+type recordingLogger struct{ lines []string }
+
+func (l *recordingLogger) Printf(format string, v ...any) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func TestTraceID_SameIDAtEveryObservationPoint(t *testing.T) {
+	var headers []string
+	fail := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Get(TraceHeader))
+		if fail {
+			http.Error(w, `{"error":"boom"}`, http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"finish_reason":"stop","message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	defer ts.Close()
+
+	client, err := openai.NewClient(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	logger := &recordingLogger{}
+	var telemetry []string
+	model := middleware.WrapLanguageModel(client.ChatModel("gpt-test"),
+		middleware.LoggingLanguageModel(middleware.LoggingOptions{Logger: logger}),
+		middleware.TelemetryLanguageModel(middleware.TelemetryHooks{
+			OnLanguageModelCall: func(ctx context.Context, info middleware.LanguageModelCallInfo) {
+				telemetry = append(telemetry, info.TraceID)
+			},
+		}),
+	)
+	req := GenerateTextRequest{Model: model, Messages: []Message{UserMessage("hi")}}
+
+	res, err := GenerateText(context.Background(), req)
+	if err != nil {
+		t.Fatalf("GenerateText error: %v", err)
+	}
+	id := res.TraceID
+	if len(id) != 32 || headers[0] != id || telemetry[0] != id || !strings.Contains(logger.lines[0], "trace_id="+id) {
+		t.Fatalf("trace ID %q not propagated: headers=%v telemetry=%v logs=%v", id, headers, telemetry, logger.lines)
+	}
+
+	fail = true
+	_, err = GenerateText(WithTraceID(context.Background(), "req-123"), req)
+	var traced *TracedError
+	if !errors.As(err, &traced) || TraceIDFromError(err) != "req-123" || !strings.Contains(err.Error(), "trace_id=req-123") {
+		t.Fatalf("expected traced error, got %v", err)
+	}
+	if headers[1] != "req-123" || telemetry[1] != "req-123" || !strings.Contains(logger.lines[len(logger.lines)-1], "trace_id=req-123") {
+		t.Fatalf("context trace ID not reused: headers=%v telemetry=%v logs=%v", headers, telemetry, logger.lines)
+	}
+}
+
+func TestTraceID_StampedOnTypedErrors(t *testing.T) {
+	client, err := openai.NewClient(provider.ClientOptions{BaseURL: "https://example.test", APIKey: "test"})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	_, err = GenerateText(WithTraceID(context.Background(), "req-456"), GenerateTextRequest{
+		Model:    client.ChatModel("gpt-test"),
+		Messages: []Message{SystemMessage("only system")},
+	})
+	var noConv *NoConversationMessageError
+	if !errors.As(err, &noConv) || noConv.TraceID != "req-456" || TraceIDFromError(err) != "req-456" {
+		t.Fatalf("expected trace ID on typed error, got %v", err)
+	}
+}
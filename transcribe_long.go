@@ -0,0 +1,236 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TranscribeLongOptions configures TranscribeLong.
+type TranscribeLongOptions struct {
+	// ChunkSeconds is the target duration of each chunk. Defaults to
+	// 600 (ten minutes). It is lowered when needed so that a chunk,
+	// including its overlap, stays under MaxChunkBytes.
+	ChunkSeconds float64
+	// OverlapSeconds is the audio repeated at the start of every chunk
+	// after the first, so words cut at a boundary are heard whole. Text
+	// transcribed twice is dropped when merging. Zero disables overlap;
+	// it must be less than half of ChunkSeconds.
+	OverlapSeconds float64
+	// Format is the audio container, "wav" or "mp3". Empty detects it
+	// from the data.
+	Format string
+	// MaxChunkBytes caps the size of each uploaded chunk. Defaults to
+	// 24 MB, below the 25 MB limit of Whisper-based APIs.
+	MaxChunkBytes int
+	// Request is a template for each chunk's transcription request, for
+	// fields such as Language, Temperature, or Segments. Model, Audio,
+	// FileName, and MimeType are set per chunk. Prompt is only sent with
+	// the first chunk; later chunks are prompted with the tail of the
+	// previous chunk's text for continuity.
+	Request TranscriptionRequest
+	// OnProgress, if set, is called after each chunk is transcribed.
+	OnProgress func(TranscribeLongProgress)
+}
+
+// TranscribeLongProgress reports a transcribed TranscribeLong chunk.
+type TranscribeLongProgress struct {
+	// Chunk is the zero-based index of the chunk.
+	Chunk int
+	// Chunks is the total number of chunks.
+	Chunks int
+	// Start and End are the chunk's boundaries in seconds, excluding
+	// the overlap shared with the previous chunk.
+	Start float64
+	End   float64
+	// Text is the chunk's text after overlap removal.
+	Text string
+}
+
+// transcribePromptTail is the maximum length, in bytes, of the previous
+// chunk's text used as the prompt for the next chunk. Whisper only
+// considers the last 224 prompt tokens.
+const transcribePromptTail = 500
+
+// TranscribeLong transcribes audio of any length by splitting it into
+// chunks that fit the provider's upload limit, transcribing them in
+// order, and merging the results.
+//
+// WAV and MP3 files are split on sample and frame boundaries
+// respectively. For 16-bit PCM WAV files each cut is moved to the
+// quietest point in the last tenth of the chunk, so it is less likely
+// to fall inside a word. Segment timestamps returned by the model are
+// shifted to be relative to the whole recording; chunks transcribed
+// without segments contribute one segment spanning the chunk. With
+// OverlapSeconds set, segments starting in the overlap are dropped, or
+// for text-only responses, words repeated across the boundary (at
+// least two) are removed.
+//
+// If a chunk fails, the text merged so far is returned together with a
+// *TranscribeChunkError.
+//
+// Errors:
+//   - ErrMissingModel if model is nil.
+//   - *InvalidArgumentError if the audio cannot be parsed or the options
+//     are out of range.
+//   - *TranscribeChunkError if a chunk's transcription fails.
+//   - The context error if ctx is canceled between chunks.
+func TranscribeLong(ctx context.Context, model TranscriptionModel, audio []byte, opts TranscribeLongOptions) (TranscriptionResponse, error) {
+	if model == nil {
+		return TranscriptionResponse{}, ErrMissingModel
+	}
+	if opts.ChunkSeconds == 0 {
+		opts.ChunkSeconds = 600
+	}
+	if opts.MaxChunkBytes == 0 {
+		opts.MaxChunkBytes = 24 << 20
+	}
+	if opts.ChunkSeconds < 0 {
+		return TranscriptionResponse{}, &InvalidArgumentError{Parameter: "ChunkSeconds", Value: opts.ChunkSeconds, Message: "must be positive"}
+	}
+	if opts.OverlapSeconds < 0 || opts.OverlapSeconds >= opts.ChunkSeconds/2 {
+		return TranscriptionResponse{}, &InvalidArgumentError{Parameter: "OverlapSeconds", Value: opts.OverlapSeconds, Message: "must be at least 0 and less than half of ChunkSeconds"}
+	}
+
+	track, err := parseAudioTrack(audio, opts.Format)
+	if err != nil {
+		return TranscriptionResponse{}, &InvalidArgumentError{Parameter: "audio", Message: err.Error()}
+	}
+	chunk := min(opts.ChunkSeconds, float64(opts.MaxChunkBytes)/track.BytesPerSecond()-opts.OverlapSeconds)
+	if chunk < 1 {
+		return TranscriptionResponse{}, &InvalidArgumentError{Parameter: "MaxChunkBytes", Value: opts.MaxChunkBytes, Message: "too small to hold one second of audio plus overlap"}
+	}
+	cuts := planAudioCuts(track, chunk)
+
+	var (
+		res      TranscriptionResponse
+		texts    []string
+		prevText string
+	)
+	for i := 0; i+1 < len(cuts); i++ {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		ownStart, ownEnd := cuts[i], cuts[i+1]
+		data, offset := track.Slice(max(ownStart-opts.OverlapSeconds, 0), ownEnd)
+
+		req := opts.Request
+		req.Model = model
+		req.Audio = data
+		req.FileName = fmt.Sprintf("chunk-%03d%s", i, track.Ext())
+		req.MimeType = track.MimeType()
+		if i > 0 {
+			req.Prompt = promptTail(prevText, transcribePromptTail)
+		}
+		out, err := Transcribe(ctx, req)
+		if err != nil {
+			return res, &TranscribeChunkError{Chunk: i, Start: ownStart, End: ownEnd, Err: err}
+		}
+		prevText = out.Text
+
+		var text string
+		if len(out.Segments) > 0 {
+			var parts []string
+			for _, seg := range out.Segments {
+				seg.Start += offset
+				seg.End += offset
+				seg.Text = strings.TrimSpace(seg.Text)
+				if i > 0 && (seg.Start+seg.End)/2 < ownStart {
+					continue
+				}
+				res.Segments = append(res.Segments, seg)
+				if seg.Text != "" {
+					parts = append(parts, seg.Text)
+				}
+			}
+			text = strings.Join(parts, " ")
+		} else {
+			text = strings.TrimSpace(out.Text)
+			if i > 0 && opts.OverlapSeconds > 0 {
+				text = dropRepeatedWords(strings.Join(texts, " "), text)
+			}
+			res.Segments = append(res.Segments, TranscriptionSegment{Start: ownStart, End: ownEnd, Text: text})
+		}
+		if text != "" {
+			texts = append(texts, text)
+		}
+		res.Text = strings.Join(texts, " ")
+
+		if opts.OnProgress != nil {
+			opts.OnProgress(TranscribeLongProgress{Chunk: i, Chunks: len(cuts) - 1, Start: ownStart, End: ownEnd, Text: text})
+		}
+	}
+	return res, nil
+}
+
+// planAudioCuts returns the chunk boundaries in seconds, starting at 0
+// and ending at the track's duration. Each cut is moved to the
+// quietest point in the last tenth of its chunk when the track
+// supports it.
+func planAudioCuts(track audioTrack, chunk float64) []float64 {
+	total := track.Duration()
+	cuts := []float64{0}
+	for last := 0.0; total-last > chunk; {
+		cut := last + chunk
+		if quiet, ok := track.Quietest(cut-chunk/10, cut); ok {
+			cut = quiet
+		}
+		cuts = append(cuts, cut)
+		last = cut
+	}
+	return append(cuts, total)
+}
+
+// promptTail returns at most n bytes from the end of text, starting at
+// a word boundary.
+func promptTail(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	tail := text[len(text)-n:]
+	if i := strings.IndexFunc(tail, unicode.IsSpace); i >= 0 {
+		return strings.TrimLeftFunc(tail[i:], unicode.IsSpace)
+	}
+	for !utf8.ValidString(tail) {
+		tail = tail[1:]
+	}
+	return tail
+}
+
+// dropRepeatedWords removes the longest run of at least two words at
+// the start of next that repeats the end of prev, comparing words
+// case-insensitively and ignoring punctuation.
+func dropRepeatedWords(prev, next string) string {
+	const maxWords = 30
+	prevWords := strings.Fields(prev)
+	prevWords = prevWords[max(len(prevWords)-maxWords, 0):]
+	nextWords := strings.Fields(next)
+
+	for k := min(len(prevWords), len(nextWords)); k >= 2; k-- {
+		match := true
+		for j := 0; j < k; j++ {
+			if normalizeWord(prevWords[len(prevWords)-k+j]) != normalizeWord(nextWords[j]) {
+				match = false
+				break
+			}
+		}
+		if !match {
+			continue
+		}
+		rest := next
+		for j := 0; j < k; j++ {
+			rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+			rest = rest[len(nextWords[j]):]
+		}
+		return strings.TrimLeftFunc(rest, unicode.IsSpace)
+	}
+	return next
+}
+
+func normalizeWord(w string) string {
+	return strings.ToLower(strings.TrimFunc(w, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSymbol(r)
+	}))
+}
@@ -0,0 +1,241 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+const testSampleRate = 1000
+
+// speechWAV builds a 16-bit mono WAV where second k holds the constant
+// sample value 100*(k+1), standing in for the word "wk". Seconds listed
+// in silent are zero.
+func speechWAV(seconds int, silent ...int) []byte {
+	pcm := make([]byte, 0, seconds*testSampleRate*2)
+	for k := range seconds {
+		v := int16(100 * (k + 1))
+		for _, s := range silent {
+			if s == k {
+				v = 0
+			}
+		}
+		for range testSampleRate {
+			pcm = binary.LittleEndian.AppendUint16(pcm, uint16(v))
+		}
+	}
+	fmtChunk := binary.LittleEndian.AppendUint16(nil, 1) // PCM
+	fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, 1)
+	fmtChunk = binary.LittleEndian.AppendUint32(fmtChunk, testSampleRate)
+	fmtChunk = binary.LittleEndian.AppendUint32(fmtChunk, testSampleRate*2)
+	fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, 2)
+	fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, 16)
+
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(4+8+len(fmtChunk)+8+len(pcm)))
+	b.WriteString("WAVEfmt ")
+	binary.Write(&b, binary.LittleEndian, uint32(len(fmtChunk)))
+	b.Write(fmtChunk)
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(len(pcm)))
+	b.Write(pcm)
+	return b.Bytes()
+}
+
+// wordModel "hears" each run of constant samples in a speechWAV chunk
+// as one word, returning segments relative to the chunk when requested.
+type wordModel struct {
+	failChunk int
+	requests  []*provider.TranscriptionRequest
+}
+
+func (m *wordModel) Generate(ctx context.Context, req *provider.TranscriptionRequest) (*provider.TranscriptionResponse, error) {
+	m.requests = append(m.requests, req)
+	if m.failChunk > 0 && len(m.requests) == m.failChunk+1 {
+		return nil, errors.New("upstream unavailable")
+	}
+	track, err := parseWAV(req.Audio)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		res   provider.TranscriptionResponse
+		words []string
+	)
+	samples := track.frames()
+	for start := 0; start < samples; {
+		v := int16(binary.LittleEndian.Uint16(track.data[start*2:]))
+		end := start + 1
+		for end < samples && int16(binary.LittleEndian.Uint16(track.data[end*2:])) == v {
+			end++
+		}
+		if v != 0 {
+			word := fmt.Sprintf("w%d", int(v)/100-1)
+			words = append(words, word)
+			if req.Segments {
+				res.Segments = append(res.Segments, provider.TranscriptionSegment{
+					Start: float64(start) / testSampleRate,
+					End:   float64(end) / testSampleRate,
+					Text:  " " + word,
+				})
+			}
+		}
+		start = end
+	}
+	res.Text = strings.Join(words, " ")
+	return &res, nil
+}
+
+func expectedWords(seconds int, silent ...int) string {
+	var words []string
+	for k := range seconds {
+		if !slices.Contains(silent, k) {
+			words = append(words, fmt.Sprintf("w%d", k))
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func TestTranscribeLong_SegmentsCutOnSilence(t *testing.T) {
+	model := &wordModel{}
+	var progress []TranscribeLongProgress
+	res, err := TranscribeLong(context.Background(), model, speechWAV(28, 9, 19), TranscribeLongOptions{
+		ChunkSeconds:   10,
+		OverlapSeconds: 1,
+		Request:        TranscriptionRequest{Segments: true, Prompt: "Glossary: w"},
+		OnProgress:     func(p TranscribeLongProgress) { progress = append(progress, p) },
+	})
+	if err != nil {
+		t.Fatalf("TranscribeLong error: %v", err)
+	}
+	if want := expectedWords(28, 9, 19); res.Text != want {
+		t.Fatalf("unexpected text:\n got %q\nwant %q", res.Text, want)
+	}
+
+	if len(progress) != 3 || progress[2].Chunks != 3 {
+		t.Fatalf("expected 3 progress reports, got %+v", progress)
+	}
+	for i, silence := range []float64{9, 19} {
+		if cut := progress[i+1].Start; cut < silence || cut > silence+1 {
+			t.Fatalf("cut %d at %.3fs is outside the silence at %vs", i+1, cut, silence)
+		}
+	}
+
+	for _, seg := range res.Segments {
+		var k int
+		fmt.Sscanf(seg.Text, "w%d", &k)
+		if math.Abs(seg.Start-float64(k)) > 0.02 || math.Abs(seg.End-float64(k+1)) > 0.02 {
+			t.Fatalf("segment %q has uncorrected timestamps [%.3f, %.3f]", seg.Text, seg.Start, seg.End)
+		}
+	}
+
+	if model.requests[0].Prompt != "Glossary: w" {
+		t.Fatalf("first chunk should use the template prompt, got %q", model.requests[0].Prompt)
+	}
+	if !strings.HasSuffix(model.requests[1].Prompt, "w7 w8") {
+		t.Fatalf("second chunk should be prompted with the first chunk's tail, got %q", model.requests[1].Prompt)
+	}
+	if model.requests[1].MimeType != "audio/wav" || model.requests[1].FileName != "chunk-001.wav" {
+		t.Fatalf("unexpected chunk file: %q %q", model.requests[1].FileName, model.requests[1].MimeType)
+	}
+}
+
+func TestTranscribeLong_TextOverlapDeduplicated(t *testing.T) {
+	model := &wordModel{}
+	res, err := TranscribeLong(context.Background(), model, speechWAV(28, 9, 19), TranscribeLongOptions{
+		ChunkSeconds:   10,
+		OverlapSeconds: 2,
+	})
+	if err != nil {
+		t.Fatalf("TranscribeLong error: %v", err)
+	}
+	if want := expectedWords(9); model.requests[1].Prompt != want {
+		t.Fatalf("expected the first chunk's text as prompt, got %q", model.requests[1].Prompt)
+	}
+	if want := expectedWords(28, 9, 19); res.Text != want {
+		t.Fatalf("unexpected text:\n got %q\nwant %q", res.Text, want)
+	}
+	if len(res.Segments) != 3 || res.Segments[2].End != 28 {
+		t.Fatalf("expected one segment per chunk, got %+v", res.Segments)
+	}
+}
+
+func TestTranscribeLong_MaxChunkBytesAndFailure(t *testing.T) {
+	model := &wordModel{failChunk: 2}
+	res, err := TranscribeLong(context.Background(), model, speechWAV(20), TranscribeLongOptions{MaxChunkBytes: 12000})
+
+	var chunkErr *TranscribeChunkError
+	if !errors.As(err, &chunkErr) || chunkErr.Chunk != 2 {
+		t.Fatalf("expected TranscribeChunkError for chunk 2, got %v", err)
+	}
+	for _, req := range model.requests {
+		if len(req.Audio) > 12000+44 {
+			t.Fatalf("chunk of %d bytes exceeds MaxChunkBytes", len(req.Audio))
+		}
+	}
+	if !strings.HasPrefix(res.Text, "w0 w1") || strings.Contains(res.Text, "w19") {
+		t.Fatalf("expected text of the chunks before the failure, got %q", res.Text)
+	}
+}
+
+func TestTranscribeLong_MP3SplitsOnFrames(t *testing.T) {
+	var audio []byte
+	audio = append(audio, "ID3\x04\x00\x00\x00\x00\x00\x14"...)
+	audio = append(audio, make([]byte, 20)...)
+	frame := append([]byte{0xFF, 0xFB, 0x90, 0x00}, make([]byte, 413)...) // MPEG-1 Layer III, 128 kbps, 44.1 kHz
+	for range 1000 {
+		audio = append(audio, frame...)
+	}
+	audio = append(audio, "TAG"...)
+	audio = append(audio, make([]byte, 125)...)
+
+	var frames int
+	model := transcriptionFunc(func(req *provider.TranscriptionRequest) (*provider.TranscriptionResponse, error) {
+		track, err := parseMP3(req.Audio)
+		if err != nil {
+			return nil, err
+		}
+		if len(track.frames)*len(frame) != len(req.Audio) || req.MimeType != "audio/mpeg" {
+			t.Fatalf("chunk is not a clean run of frames (%d bytes, %s)", len(req.Audio), req.MimeType)
+		}
+		if track.Duration() > 10+track.frames[1].start {
+			t.Fatalf("chunk of %.2fs exceeds ChunkSeconds", track.Duration())
+		}
+		frames += len(track.frames)
+		return &provider.TranscriptionResponse{Text: fmt.Sprintf("%d frames", len(track.frames))}, nil
+	})
+
+	res, err := TranscribeLong(context.Background(), model, audio, TranscribeLongOptions{ChunkSeconds: 10})
+	if err != nil {
+		t.Fatalf("TranscribeLong error: %v", err)
+	}
+	if frames != 1000 || len(res.Segments) != 3 {
+		t.Fatalf("expected 1000 frames in 3 chunks, got %d frames, %+v", frames, res.Segments)
+	}
+	if end := res.Segments[2].End; math.Abs(end-1000*1152.0/44100) > 1e-6 {
+		t.Fatalf("unexpected total duration %.4f", end)
+	}
+}
+
+func TestTranscribeLong_RejectsUnknownFormat(t *testing.T) {
+	_, err := TranscribeLong(context.Background(), &wordModel{}, []byte("OggS...."), TranscribeLongOptions{})
+	var invalid *InvalidArgumentError
+	if !errors.As(err, &invalid) || invalid.Parameter != "audio" {
+		t.Fatalf("expected InvalidArgumentError for audio, got %v", err)
+	}
+}
+
+type transcriptionFunc func(req *provider.TranscriptionRequest) (*provider.TranscriptionResponse, error)
+
+func (f transcriptionFunc) Generate(ctx context.Context, req *provider.TranscriptionRequest) (*provider.TranscriptionResponse, error) {
+	return f(req)
+}
@@ -0,0 +1,54 @@
+package ai
+
+import "context"
+
+// TextTransform rewrites streamed text incrementally. Implementations
+// may hold back text whose rewrite depends on what follows, such as a
+// marker split across deltas, and emit it from a later call.
+type TextTransform interface {
+	// Transform consumes the next piece of text and returns the
+	// rewritten text that is final so far.
+	Transform(text string) string
+	// Flush returns the text still held back once the input ends.
+	Flush() string
+}
+
+// TransformStream returns a stream whose delta text is rewritten by
+// transforms, applied in order. Reasoning, tool calls, and usage pass
+// through unchanged. Text held back by a transform is flushed into the
+// final Done delta. Deltas whose text is held back entirely are
+// skipped. Closing the returned stream closes stream.
+func TransformStream(stream TextStream, transforms ...TextTransform) TextStream {
+	return &transformedStream{stream: stream, transforms: transforms}
+}
+
+type transformedStream struct {
+	stream     TextStream
+	transforms []TextTransform
+}
+
+func (s *transformedStream) Next(ctx context.Context) (*TextDelta, error) {
+	for {
+		delta, err := s.stream.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		text := delta.Text
+		for _, t := range s.transforms {
+			text = t.Transform(text)
+			if delta.Done {
+				text += t.Flush()
+			}
+		}
+		if text == "" && delta.Text != "" && delta.Reasoning == "" && len(delta.ToolCalls) == 0 && len(delta.ToolCallDeltas) == 0 && delta.Usage == nil && !delta.Done {
+			continue
+		}
+		out := *delta
+		out.Text = text
+		return &out, nil
+	}
+}
+
+func (s *transformedStream) Close() error {
+	return s.stream.Close()
+}
@@ -0,0 +1,17 @@
+package ai
+
+import (
+	"context"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// WithUserID returns a context carrying an end-user ID for every
+// provider request made with it that leaves UserID empty: chat,
+// completions, embeddings, images, and transcription. Providers use it
+// for abuse tracking; OpenAI receives it as user and Anthropic as
+// metadata.user_id. It takes precedence over
+// ClientOptions.DefaultUserID.
+func WithUserID(ctx context.Context, id string) context.Context {
+	return provider.WithUserID(ctx, id)
+}
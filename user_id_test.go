@@ -0,0 +1,95 @@
+package ai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ncecere/ai-sdk/provider"
+)
+
+// sentUserID returns the end-user ID of a recorded chat request body.
+func sentUserID(provider string, body map[string]any) any {
+	if provider == "anthropic" {
+		metadata, _ := body["metadata"].(map[string]any)
+		return metadata["user_id"]
+	}
+	return body["user"]
+}
+
+func TestUserID_Chat(t *testing.T) {
+	cases := []struct {
+		name          string
+		reqID, ctxID  string
+		defaultUserID string
+		want          any
+	}{
+		{"request", "u-req", "u-ctx", "u-default", "u-req"},
+		{"context", "", "u-ctx", "u-default", "u-ctx"},
+		{"client default", "", "", "u-default", "u-default"},
+		{"none", "", "", "", nil},
+	}
+	for _, mc := range mixedCases {
+		for _, tc := range cases {
+			t.Run(mc.name+"/"+tc.name, func(t *testing.T) {
+				ctx := context.Background()
+				if tc.ctxID != "" {
+					ctx = WithUserID(ctx, tc.ctxID)
+				}
+				req := GenerateTextRequest{Messages: []Message{UserMessage("Hi")}, UserID: tc.reqID}
+
+				for _, fixture := range []string{"_generate.json", "_stream.sse"} {
+					var bodies []map[string]any
+					ts := fixtureServer(t, "toolcalls/"+mc.name+fixture, &bodies)
+					defer ts.Close()
+					model, err := mc.newModel(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client(), DefaultUserID: tc.defaultUserID})
+					if err != nil {
+						t.Fatalf("NewClient error: %v", err)
+					}
+					req.Model = model
+					if fixture == "_generate.json" {
+						_, err = GenerateText(ctx, req)
+					} else {
+						var stream TextStream
+						if stream, err = StreamText(ctx, req); err == nil {
+							stream.Close()
+						}
+					}
+					if err != nil {
+						t.Fatalf("%s error: %v", fixture, err)
+					}
+					if got := sentUserID(mc.name, bodies[0]); got != tc.want {
+						t.Fatalf("%s: sent user ID %v, want %v", fixture, got, tc.want)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestUserID_AnthropicMetadata(t *testing.T) {
+	var bodies []map[string]any
+	ts := fixtureServer(t, "toolcalls/anthropic_generate.json", &bodies)
+	defer ts.Close()
+	model, err := mixedCases[1].newModel(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client(), DefaultUserID: "u-default"})
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	// Metadata's user_id still applies and beats the client default,
+	// while UserID beats both.
+	for _, tc := range []struct {
+		userID, want string
+	}{{"", "u-meta"}, {"u-req", "u-req"}} {
+		_, err := GenerateText(context.Background(), GenerateTextRequest{
+			Model:    model,
+			Messages: []Message{UserMessage("Hi")},
+			Metadata: map[string]string{MetadataUserID: "u-meta"},
+			UserID:   tc.userID,
+		})
+		if err != nil {
+			t.Fatalf("GenerateText error: %v", err)
+		}
+		if got := sentUserID("anthropic", bodies[len(bodies)-1]); got != tc.want {
+			t.Fatalf("sent user ID %v, want %s", got, tc.want)
+		}
+	}
+}
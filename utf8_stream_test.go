@@ -0,0 +1,97 @@
+package ai
+
+import (
+	"context"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/ncecere/ai-sdk/anthropic"
+	"github.com/ncecere/ai-sdk/openai"
+	"github.com/ncecere/ai-sdk/provider"
+	"github.com/ncecere/ai-sdk/providerutil"
+)
+
+// The testdata/utf8 fixtures split "😀" and both characters of "世界"
+// across chunks mid-character.
+func TestStreamText_RepairsSplitUTF8(t *testing.T) {
+	cases := []struct {
+		name     string
+		fixture  string
+		newModel func(opts provider.ClientOptions) (LanguageModel, error)
+	}{
+		{"openai", "utf8/openai_stream.sse", func(opts provider.ClientOptions) (LanguageModel, error) {
+			c, err := openai.NewClient(opts)
+			if err != nil {
+				return nil, err
+			}
+			return c.ChatModel("llama"), nil
+		}},
+		{"anthropic", "utf8/anthropic_stream.sse", func(opts provider.ClientOptions) (LanguageModel, error) {
+			c, err := anthropic.NewClient(opts)
+			if err != nil {
+				return nil, err
+			}
+			return c.ChatModel("claude-test"), nil
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var bodies []map[string]any
+			ts := fixtureServer(t, tc.fixture, &bodies)
+			defer ts.Close()
+
+			model, err := tc.newModel(provider.ClientOptions{BaseURL: ts.URL, APIKey: "test", HTTPClient: ts.Client()})
+			if err != nil {
+				t.Fatalf("NewClient error: %v", err)
+			}
+			before := providerutil.UTF8Repairs()
+			stream, err := StreamText(context.Background(), GenerateTextRequest{
+				Model:    model,
+				Messages: []Message{UserMessage("Say hi")},
+			})
+			if err != nil {
+				t.Fatalf("StreamText error: %v", err)
+			}
+			defer stream.Close()
+
+			var deltas []string
+			for {
+				delta, err := stream.Next(context.Background())
+				if err != nil {
+					t.Fatalf("Next error: %v", err)
+				}
+				if delta.Text != "" {
+					deltas = append(deltas, delta.Text)
+				}
+				if delta.Done {
+					break
+				}
+			}
+			want := []string{"Hi ", "😀 ", "世", "界!"}
+			if len(deltas) != len(want) {
+				t.Fatalf("deltas = %q, want %q", deltas, want)
+			}
+			for i, d := range deltas {
+				if !utf8.ValidString(d) || d != want[i] {
+					t.Fatalf("delta %d = %q, want %q", i, d, want[i])
+				}
+			}
+			if got := providerutil.UTF8Repairs() - before; got != 3 {
+				t.Fatalf("UTF8Repairs grew by %d, want 3", got)
+			}
+		})
+	}
+}
+
+func TestUTF8Joiner_StreamEndsMidCharacter(t *testing.T) {
+	var j providerutil.UTF8Joiner
+	if got := j.Join("ok \xe4\xb8"); got != "ok " {
+		t.Fatalf("Join = %q, want %q", got, "ok ")
+	}
+	if got := j.Flush(); got != "�" {
+		t.Fatalf("Flush = %q, want U+FFFD", got)
+	}
+	if got := j.Join("\x96x"); got != "�x" {
+		t.Fatalf("Join after Flush = %q, want a replaced stray byte", got)
+	}
+}